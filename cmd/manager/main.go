@@ -4,32 +4,64 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 
 	apis "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	"github.com/kubeflow/kfctl/v3/pkg/audit"
+	"github.com/kubeflow/kfctl/v3/pkg/cachefilter"
+	"github.com/kubeflow/kfctl/v3/pkg/clusterstatus"
 	"github.com/kubeflow/kfctl/v3/pkg/controller"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/dataconnection"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/datasciencecluster"
 	kfdefcontroller "github.com/kubeflow/kfctl/v3/pkg/controller/kfdef"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/modelregistry"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/notebook"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/pipelineserver"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	"github.com/kubeflow/kfctl/v3/pkg/healthz"
+	"github.com/kubeflow/kfctl/v3/pkg/janitor"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	opmetrics "github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/profiling"
+	"github.com/kubeflow/kfctl/v3/pkg/reconcilecontext"
+	"github.com/kubeflow/kfctl/v3/pkg/telemetry"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/kubeflow/kfctl/v3/pkg/webhook"
+	kfdefwebhook "github.com/kubeflow/kfctl/v3/pkg/webhook/kfdef"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
-	"github.com/operator-framework/operator-sdk/pkg/leader"
-	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/restmapper"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 )
@@ -39,13 +71,56 @@ var (
 	Version string = "1.1.0"
 )
 
-// Change below variables to serve metrics on different host or port.
+// watchNamespaceSelectorEnvVar is the environment variable holding the label selector of the namespaces
+// whose KfDef instances are reconciled by the operator
+const watchNamespaceSelectorEnvVar = "WATCH_NAMESPACE_SELECTOR"
+
+// Change below variables to serve metrics on different host or port. The metrics are served on every IPv4 and IPv6
+// address when metricsHost is empty.
 var (
-	metricsHost               = "0.0.0.0"
+	metricsHost               = ""
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
 )
 
+// Flags controlling the logs, the controllers and the admission webhook server.
+var (
+	logFormat string
+	logLevel  string
+
+	healthProbeAddr string
+	statusAddr      string
+
+	leaderElect             bool
+	leaderElectionID        string
+	leaderElectionNamespace string
+	leaseDuration           time.Duration
+	renewDeadline           time.Duration
+	retryPeriod             time.Duration
+
+	enablePprof bool
+	pprofPort   int
+
+	maxConcurrentReconciles int
+	syncPeriod              time.Duration
+
+	enableWebhooks bool
+	webhookHost    string
+	webhookPort    int
+	webhookCertDir string
+
+	enableNotebookCuller bool
+	enableJanitor        bool
+
+	upgradeChecksFile string
+
+	cosignPublicKey string
+	fulcioRoots     string
+	rekorPublicKey  string
+
+	runLocal bool
+)
+
 func printVersion() {
 	log.Infof("Go Version: %s", runtime.Version())
 	log.Infof("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
@@ -54,17 +129,355 @@ func printVersion() {
 }
 
 func main() {
-	// Add the zap logger flag set to the CLI. The flag set must
-	// be added before calling pflag.Parse().
-	pflag.CommandLine.AddFlagSet(zap.FlagSet())
-
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.StringVar(&metricsHost, "metrics-host", metricsHost,
+		"Address the metrics of the operator are served on, e.g. :: or 0.0.0.0. They are served on every IPv4 and IPv6 address when empty.")
+	pflag.StringVar(&logFormat, "log-format", kfutils.TextLogFormat, "Log format, one of json or text.")
+	pflag.StringVar(&logLevel, "log-level", "info",
+		"Log level, one of panic, fatal, error, warn, info, debug or trace. Send SIGUSR1 to toggle debug logs at runtime.")
+	pflag.StringVar(&healthProbeAddr, "health-probe-bind-address", ":8081",
+		"Address the /healthz and /readyz probe endpoints bind to.")
+	pflag.StringVar(&statusAddr, "status-bind-address", ":8082",
+		"Address the /status endpoint serving the aggregated status of the DataScienceClusters and the KfDefs binds to, empty to disable it.")
+	pflag.BoolVar(&leaderElect, "leader-elect", true,
+		"Elect a leader among the replicas of the operator, only the leader reconciles the KfDef instances.")
+	pflag.StringVar(&leaderElectionID, "leader-election-id", "kfctl-leader",
+		"Name of the ConfigMap holding the leader election lease.")
+	pflag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election ConfigMap. Defaults to the namespace of the operator.")
+	pflag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long the other replicas wait after the last renewal of the lease before taking over.")
+	pflag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader retries to renew the lease before giving up the leadership.")
+	pflag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How long the replicas wait between two attempts to acquire or renew the lease.")
+	pflag.BoolVar(&enablePprof, "enable-pprof", false,
+		"Serve the net/http/pprof endpoints on localhost to capture CPU and heap profiles.")
+	pflag.IntVar(&pprofPort, "pprof-port", 6060, "Port of localhost the pprof endpoints are served on.")
+	pflag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Maximum number of objects reconciled concurrently by each controller.")
+	pflag.DurationVar(&syncPeriod, "sync-period", 10*time.Hour,
+		"Minimum frequency at which all watched resources are reconciled again.")
+	pflag.DurationVar(&ratelimiter.BaseDelay, "reconcile-base-delay", ratelimiter.BaseDelay,
+		"Delay before retrying a failed reconciliation. The delay doubles on every consecutive failure.")
+	pflag.DurationVar(&ratelimiter.MaxDelay, "reconcile-max-delay", ratelimiter.MaxDelay,
+		"Maximum delay before retrying a reconciliation that keeps failing.")
+	pflag.BoolVar(&kfdefcontroller.DryRun, "dry-run", false,
+		"Validate the manifests of every KfDef with a server-side dry run and record the changes in a ConfigMap instead of applying them.")
+	pflag.StringVar(&kfdefcontroller.RelatedImagesConfigMap, "related-images-configmap", "",
+		"<namespace>/<name> of the ConfigMap holding the image overrides of every KfDef, e.g. mirror registry digests.")
+	pflag.StringVar(&operatorconfig.Defaults.TrustedCABundle.ConfigMap, "trusted-ca-bundle-configmap", "",
+		"<namespace>/<name> of the ConfigMap holding the CA bundle mounted in the Deployments instead of the OpenShift trusted CA bundle.")
+	pflag.StringVar(&operatorconfig.Defaults.ApplicationsNamespace, "applications-namespace", operatorconfig.Defaults.ApplicationsNamespace,
+		"Namespace of the Open Data Hub applications, allowed by the NetworkPolicies of the data science projects.")
+	pflag.StringVar((*string)(&operatorconfig.Defaults.NetworkPolicy), "network-policy", string(operatorconfig.Defaults.NetworkPolicy),
+		"Managed to create and update the NetworkPolicies of the data science projects, Unmanaged to leave them to the administrators.")
+	pflag.StringVar(&kustomize.CertManagerIssuer, "cert-manager-issuer", "",
+		"cert-manager ClusterIssuer of the serving certificates of the Services. They are issued by the OpenShift service CA when empty.")
+	pflag.DurationVar(&kustomize.CertificateRenewBefore, "certificate-renew-before", kustomize.CertificateRenewBefore,
+		"How long before their expiry the serving certificates of the Services are issued again.")
+	pflag.BoolVar(&kfdefcontroller.BlockUpgrades, "block-upgrade-on-preflight-failure", false,
+		"Leave the applications of a KfDef as they are after an operator upgrade until the upgrade pre-flight checks pass.")
+	pflag.StringVar(&upgradeChecksFile, "upgrade-checks-file", "",
+		"YAML file of the deprecatedComponents and the requiredCRDs of the applications checked before an operator upgrade.")
+	pflag.DurationVar(&kfconfig.GitFetchInterval, "git-fetch-interval", kfconfig.GitFetchInterval,
+		"How often the git repos of the manifests are fetched again.")
+	pflag.BoolVar(&kustomize.PruneResources, "prune-resources", kustomize.PruneResources,
+		"Delete the resources applied for a KfDef that are no longer in the manifests of its applications.")
+	pflag.IntVar(&kustomize.MaxConcurrentApplies, "max-concurrent-applies", kustomize.MaxConcurrentApplies,
+		"Maximum number of applications of a KfDef applied at the same time once the applications they depend on are applied.")
+	pflag.IntVar(&kustomize.ApplyRetries, "apply-retries", kustomize.ApplyRetries,
+		"How many times the resources of an application that failed to apply are applied again, within 10 minutes.")
+	pflag.DurationVar(&reconcilecontext.Timeout, "reconcile-timeout", reconcilecontext.Timeout,
+		"How long a reconcile of a KfDef may download and apply its manifests before they are cancelled, 0 for no limit.")
+	pflag.DurationVar(&kustomize.ReadinessTimeout, "readiness-timeout", kustomize.ReadinessTimeout,
+		"How long the applications depending on an application wait for its Deployments to be available and its Jobs to complete, unless it sets its readinessTimeout.")
+	pflag.DurationVar(&kustomize.HookJobTimeout, "hook-timeout", kustomize.HookJobTimeout,
+		"How long a pre-apply or post-apply hook Job of the manifests may run, unless it is annotated with kfctl.kubeflow.io/hook-timeout.")
+	pflag.IntVar(&kustomize.HookJobRetries, "hook-retries", kustomize.HookJobRetries,
+		"How many times a failed hook Job of the manifests is run again, unless it is annotated with kfctl.kubeflow.io/hook-retries.")
+	pflag.DurationVar(&kustomize.RenderCacheTTL, "render-cache-ttl", kustomize.RenderCacheTTL,
+		"How long the applications whose manifests are unchanged are not rendered nor applied again, 0 to always apply them.")
+	pflag.BoolVar(&kfdefcontroller.DeleteClusterScopedResources, "delete-cluster-scoped-resources", false,
+		"Delete the cluster-scoped resources of a KfDef, such as ClusterRoles and CRDs, when the KfDef is deleted.")
+	pflag.StringVar(&kfdefcontroller.ServiceMeshControlPlane, "service-mesh-control-plane", kfdefcontroller.ServiceMeshControlPlane,
+		"<namespace>/<name> of the ServiceMeshControlPlane of the KfDef instances whose serviceMesh is Managed.")
+	pflag.StringVar(&kustomize.OAuthProxyImage, "oauth-proxy-image", kustomize.OAuthProxyImage,
+		"Image of the oauth-proxy sidecars put in front of the Routes of the KfDef instances whose auth is OAuthProxy.")
+	pflag.StringVar(&kustomize.AuthorinoProvider, "authorino-provider", kustomize.AuthorinoProvider,
+		"Name of the extension provider of the Service Mesh sending the requests to Authorino, for the KfDef instances whose auth is Authorino.")
+	pflag.StringVar(&kustomize.IngressClass, "ingress-class", "",
+		"Ingress class of the Ingresses the Routes are converted to on Kubernetes. Defaults to the default class of the cluster.")
+	pflag.StringVar(&kustomize.IngressTLSSecret, "ingress-tls-secret", "",
+		"Secret of the certificate of the Ingresses the TLS Routes are converted to on Kubernetes.")
+	pflag.StringVar(&kustomize.IngressDomain, "ingress-domain", "",
+		"Domain of the hosts of the Ingresses the Routes without a host are converted to on Kubernetes.")
+	pflag.StringVar(&kustomize.ArgoCDMode, "argocd-mode", "",
+		"What to do with the resources tracked by Argo CD: ignore leaves them to Argo CD, adopt takes them over. They are applied as any other resource when empty.")
+	pflag.StringVar(&kustomize.ArgoCDTrackingLabel, "argocd-tracking-label", "",
+		"Label of the resources tracked by Argo CD with the label tracking method, e.g. app.kubernetes.io/instance. Only the argocd.argoproj.io/tracking-id annotation is looked up when empty.")
+	pflag.StringVar(&kustomize.FIPSMode, "fips-mode", kustomize.FIPSMode,
+		"Deploy the FIPS-compliant variants of the components and refuse the incompatible ones: auto when the cluster was installed with FIPS enabled, enabled or disabled.")
+	pflag.StringVar(&kfutils.FieldManager, "field-manager", kfutils.FieldManager,
+		"Field manager the manifests are server-side applied with. The fields applied with the default kfctl field manager are taken over when it is changed.")
+	pflag.StringVar(&kfutils.ConflictPolicy, "apply-conflict-policy", kfutils.ConflictPolicy,
+		"What to do with the fields of the manifests managed by other field managers: fail reports them without applying their resources, force takes them over, ignore-fields leaves the --apply-ignore-fields to the other field managers.")
+	pflag.StringSliceVar(&kfutils.IgnoredFields, "apply-ignore-fields", nil,
+		"Paths of the fields left to other field managers with the ignore-fields conflict policy, e.g. .spec.replicas.")
+	pflag.BoolVar(&enableNotebookCuller, "enable-notebook-culler", false,
+		"Stop the notebooks of the notebook controller whose kernels are idle. Requires the Notebook CRD.")
+	pflag.DurationVar(&notebook.CullIdleTime, "cull-idle-time", notebook.CullIdleTime,
+		"How long the kernels of a notebook are idle before it is stopped, unless its namespace overrides it.")
+	pflag.DurationVar(&notebook.IdlenessCheckPeriod, "idleness-check-period", notebook.IdlenessCheckPeriod,
+		"How often the activity of the kernels of the running notebooks is probed.")
+	pflag.StringVar(&notebook.ClusterDomain, "cluster-domain", notebook.ClusterDomain,
+		"Domain of the cluster the Services of the notebooks are resolved in.")
+	pflag.StringVar(&datasciencecluster.RayImage, "ray-image", datasciencecluster.RayImage,
+		"Image of the RayCluster templates of the workloads component that do not set theirs.")
+	pflag.StringVar(&pipelineserver.APIServerImage, "pipeline-server-image", pipelineserver.APIServerImage,
+		"Image of the API servers of the PipelineServers that do not set theirs.")
+	pflag.StringVar(&pipelineserver.MariaDBImage, "pipeline-server-mariadb-image", pipelineserver.MariaDBImage,
+		"Image of the MariaDB databases of the PipelineServers that do not set theirs.")
+	pflag.StringVar(&modelregistry.RegistryImage, "model-registry-image", modelregistry.RegistryImage,
+		"Image of the registry servers of the ModelRegistries that do not set theirs.")
+	pflag.StringVar(&modelregistry.MySQLImage, "model-registry-mysql-image", modelregistry.MySQLImage,
+		"Image of the MySQL databases of the ModelRegistries that do not set theirs.")
+	pflag.StringVar(&modelregistry.PostgreSQLImage, "model-registry-postgresql-image", modelregistry.PostgreSQLImage,
+		"Image of the PostgreSQL databases of the ModelRegistries that do not set theirs.")
+	pflag.StringVar(&dataconnection.ProbeImage, "data-connection-probe-image", dataconnection.ProbeImage,
+		"Image of the AWS CLI the Jobs probing the buckets of the DataConnections run.")
+	pflag.StringVar(&telemetry.Endpoint, "telemetry-endpoint", "",
+		"URL the usage reports of the DataScienceClusters opting in to the telemetry are posted to. They are only exposed as metrics when empty.")
+	pflag.DurationVar(&telemetry.Interval, "telemetry-interval", telemetry.Interval,
+		"How often the usage of the DataScienceClusters opting in to the telemetry is reported.")
+	pflag.BoolVar(&enableJanitor, "enable-janitor", false,
+		"Garbage collect the completed pipeline run pods, the stale ReplicaSets and the orphaned notebook claims of the data science projects and of the applications namespace.")
+	pflag.DurationVar(&janitor.Interval, "janitor-interval", janitor.Interval,
+		"How often the janitor garbage collects the resources.")
+	pflag.DurationVar(&janitor.PipelineRunPodTTL, "janitor-pipeline-run-pod-ttl", janitor.PipelineRunPodTTL,
+		"How long the pods of the pipeline runs are kept once completed. They are never deleted when 0.")
+	pflag.DurationVar(&janitor.ReplicaSetTTL, "janitor-replicaset-ttl", janitor.ReplicaSetTTL,
+		"How long the scaled down ReplicaSets of the previous revisions of the Deployments of the operator are kept once superseded. They are never deleted when 0.")
+	pflag.DurationVar(&janitor.OrphanedClaimTTL, "janitor-orphaned-claim-ttl", janitor.OrphanedClaimTTL,
+		"How long the PersistentVolumeClaims of the dashboard mounted by no notebook are kept. They are never deleted when 0.")
+	pflag.BoolVar(&audit.Enabled, "enable-audit", false,
+		"Write a JSON audit record of every create, update, patch and delete of the operator to the standard output.")
+	pflag.StringVar(&audit.WebhookURL, "audit-webhook-url", "",
+		"URL the audit records are also posted to as JSON, one per request.")
+	pflag.StringVar(&audit.KafkaURL, "audit-kafka-rest-url", "",
+		"URL of the Kafka REST Proxy the audit records are also produced to --audit-kafka-topic with.")
+	pflag.StringVar(&audit.KafkaTopic, "audit-kafka-topic", "",
+		"Kafka topic the audit records are produced to.")
+	pflag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the admission webhooks validating and defaulting KfDef specs.")
+	pflag.StringVar(&webhookHost, "webhook-host", "",
+		"Address the admission webhook server listens on, e.g. :: or 0.0.0.0. It listens on every IPv4 and IPv6 address when empty.")
+	pflag.IntVar(&webhookPort, "webhook-port", 9443, "Port the admission webhook server listens on.")
+	pflag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"Directory containing the tls.crt and tls.key used by the admission webhook server.")
+	pflag.BoolVar(&webhook.ManageCertificates, "manage-webhook-certs", false,
+		"Generate the certificate of the admission webhook server, inject its CA into the webhook configurations and the converted CRDs, and rotate it before it expires.")
+	pflag.StringVar(&webhook.ServiceName, "webhook-service", webhook.ServiceName,
+		"Service of the admission webhook server, in the namespace of the operator, the managed certificate is issued for.")
+	pflag.StringVar(&webhook.CertificateSecret, "webhook-cert-secret", webhook.CertificateSecret,
+		"Secret of the namespace of the operator the managed webhook certificate is kept in.")
+	pflag.DurationVar(&webhook.CertificateValidity, "webhook-cert-validity", webhook.CertificateValidity,
+		"How long the managed webhook certificate and its CA are valid for.")
+	pflag.DurationVar(&webhook.CertificateRotateBefore, "webhook-cert-rotate-before", webhook.CertificateRotateBefore,
+		"How long before its expiry the managed webhook certificate is rotated.")
+	pflag.StringVar(&kfdefwebhook.DefaultManifestsURI, "default-manifests-uri",
+		"https://github.com/opendatahub-io/odh-manifests/tarball/v"+Version,
+		"URI of the manifests repo the defaulting webhook adds to the KfDef specs referring to it without declaring it.")
+	pflag.StringSliceVar(&kfdefwebhook.DefaultOpenShiftOverlays, "default-openshift-overlays", nil,
+		"Overlays the defaulting webhook sets on the kustomize applications without overlays on OpenShift.")
+	pflag.StringSliceVar(&kfdefwebhook.DefaultKubernetesOverlays, "default-kubernetes-overlays", nil,
+		"Overlays the defaulting webhook sets on the kustomize applications without overlays on other clusters.")
+	pflag.StringSliceVar(&kfdefwebhook.KnownApplications, "known-applications", nil,
+		"Names the validating webhook allows the applications of the KfDefs to have, any name when empty.")
+	pflag.StringVar(&cosignPublicKey, "cosign-public-key", "",
+		"File containing the PEM public key the signatures of the manifests of the repos not setting their own verification are checked with.")
+	pflag.StringVar(&fulcioRoots, "fulcio-roots", "",
+		"File containing the PEM root certificates the certificates of keyless manifest signatures must chain up to.")
+	pflag.StringVar(&rekorPublicKey, "rekor-public-key", "",
+		"File containing the PEM public key of the Rekor transparency log keyless manifest signatures must be recorded in.")
+	pflag.StringVar(&tracing.Endpoint, "otlp-endpoint", "",
+		"Base URL of the OTLP/HTTP receiver the spans of the reconciliations are exported to, e.g. http://otel-collector:4318. Tracing is disabled when empty.")
+	pflag.Float32Var(&kfutils.APIQPS, "kube-api-qps", kfutils.APIQPS,
+		"Requests per second the operator sends to the API server. The clients applying the manifests share this rate, the other clients of the operator are each limited to it.")
+	pflag.IntVar(&kfutils.APIBurst, "kube-api-burst", kfutils.APIBurst,
+		"Requests the operator sends to the API server in bursts above --kube-api-qps.")
+	pflag.BoolVar(&cachefilter.ManagedResourcesOnly, "cache-managed-resources-only", cachefilter.ManagedResourcesOnly,
+		"Only cache the resources labelled opendatahub.io/managed-by among the resources watched to restore the resources deployed by the KfDef instances.")
+	pflag.IntVar(&kfutils.UpdateFailurePercent, "inject-update-failures", 0,
+		"Testing only: percentage of the creates, updates and patches applying the manifests that fail with an injected ServiceUnavailable error.")
+	pflag.DurationVar(&kfutils.ListDelay, "inject-list-delay", 0,
+		"Testing only: delay injected in the lists of the resources applied for the manifests.")
+	pflag.BoolVar(&runLocal, "run-local", false,
+		"Run the operator outside of the cluster, e.g. from a development machine with a kubeconfig, without the metrics Service and ServiceMonitor nor leader election, unless --leader-election-namespace is set.")
+	pflag.StringVar(&kfconfig.ManifestsDir, "manifests-dir", "",
+		"Local checkout of the manifests the manifests repo of every KfDef is read from in place instead of being fetched, e.g. to try changes to the manifests.")
+	pflag.Var(featuregates.Gates, "feature-gates",
+		"Comma-separated <feature>=<true|false> pairs enabling or disabling the experimental features, overridden by the featureGates of the DataScienceClusters and the KfDefs. Features:\n"+featuregates.Describe())
+
 	pflag.Parse()
 
+	// Use logrus for the operator and for the libraries logging through logr (controller-runtime, operator-sdk)
+	if err := kfutils.ConfigureLogging(logFormat, logLevel); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	logf.SetLogger(kfutils.NewLogrLogger(log.NewEntry(log.StandardLogger())))
+	kfutils.ToggleDebugOnSignal(syscall.SIGUSR1)
+
 	printVersion()
+	kfdefcontroller.OperatorVersion = Version
+	if gates := featuregates.Gates.String(); gates != "" {
+		log.Infof("Feature gates: %v", gates)
+	}
+	telemetry.OperatorVersion = Version
+	audit.OperatorVersion = Version
+
+	if err := kustomize.ValidateArgoCDMode(kustomize.ArgoCDMode); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	if err := kustomize.ValidateFIPSMode(kustomize.FIPSMode); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	if err := kfutils.ValidateConflictPolicy(kfutils.ConflictPolicy, kfutils.IgnoredFields); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	if kustomize.ReadinessTimeout <= 0 {
+		log.Errorf("Error: --readiness-timeout must be positive, got %v.", kustomize.ReadinessTimeout)
+		os.Exit(1)
+	}
+	if kustomize.ApplyRetries < 0 {
+		log.Errorf("Error: --apply-retries must not be negative, got %v.", kustomize.ApplyRetries)
+		os.Exit(1)
+	}
+	if reconcilecontext.Timeout < 0 {
+		log.Errorf("Error: --reconcile-timeout must not be negative, got %v.", reconcilecontext.Timeout)
+		os.Exit(1)
+	}
+	if kustomize.HookJobTimeout <= 0 || kustomize.HookJobRetries < 0 {
+		log.Errorf("Error: --hook-timeout must be positive and --hook-retries must not be negative, got %v and %v.",
+			kustomize.HookJobTimeout, kustomize.HookJobRetries)
+		os.Exit(1)
+	}
+	if err := kfutils.ValidateRateLimits(kfutils.APIQPS, kfutils.APIBurst); err != nil {
+		log.Errorf("Error: --kube-api-qps and --kube-api-burst are invalid: %v.", err)
+		os.Exit(1)
+	}
+	if err := kfutils.ValidateFaults(kfutils.UpdateFailurePercent, kfutils.ListDelay); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	if kfutils.FaultsEnabled() {
+		log.Warnf("Injecting faults in the apply clients: %v%% of the updates fail, the lists are delayed by %v.",
+			kfutils.UpdateFailurePercent, kfutils.ListDelay)
+	}
+	if err := audit.ValidateSinks(); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+	if err := operatorconfig.Validate(operatorconfig.Defaults); err != nil {
+		log.Errorf("Error: invalid operator settings: %v.", err)
+		os.Exit(1)
+	}
+	if kfutils.FieldManager == "" {
+		log.Errorf("Error: --field-manager must not be empty.")
+		os.Exit(1)
+	}
+	if kfutils.FieldManager != kfutils.DefaultFieldManager {
+		// The fields applied by the previous releases are taken over instead of reported as conflicts
+		kfutils.LegacyFieldManagers = append(kfutils.LegacyFieldManagers, kfutils.DefaultFieldManager)
+	}
+	if kustomize.ArgoCDMode == kustomize.ArgoCDAdopt {
+		// The fields Argo CD applied are taken over instead of reported as conflicts
+		kfutils.LegacyFieldManagers = append(kfutils.LegacyFieldManagers, kustomize.ArgoCDFieldManagers...)
+	}
+
+	if upgradeChecksFile != "" {
+		data, err := ioutil.ReadFile(upgradeChecksFile)
+		if err == nil {
+			err = kfdefcontroller.LoadUpgradeChecks(data)
+		}
+		if err != nil {
+			log.Errorf("Error: invalid --upgrade-checks-file: %v.", err)
+			os.Exit(1)
+		}
+	}
+	if cosignPublicKey != "" {
+		data, err := ioutil.ReadFile(cosignPublicKey)
+		if err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+		kfconfig.CosignPublicKey = data
+	}
+	if fulcioRoots != "" {
+		data, err := ioutil.ReadFile(fulcioRoots)
+		if err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+		kfconfig.FulcioRoots = data
+	}
+	if rekorPublicKey != "" {
+		data, err := ioutil.ReadFile(rekorPublicKey)
+		if err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+		kfconfig.RekorPublicKey = data
+	}
+	if kfconfig.ManifestsDir != "" {
+		dir, err := filepath.Abs(kfconfig.ManifestsDir)
+		if err == nil {
+			var fi os.FileInfo
+			if fi, err = os.Stat(dir); err == nil && !fi.IsDir() {
+				err = fmt.Errorf("%v is not a directory", dir)
+			}
+		}
+		if err != nil {
+			log.Errorf("Error: invalid --manifests-dir: %v.", err)
+			os.Exit(1)
+		}
+		kfconfig.ManifestsDir = dir
+		log.Infof("Reading the manifests repos from %v.", dir)
+	}
+	if runLocal {
+		// The operator has no namespace outside of the cluster, the resources created in it are skipped
+		os.Setenv(k8sutil.ForceRunModeEnv, string(k8sutil.LocalRunMode))
+		if leaderElect && leaderElectionNamespace == "" {
+			log.Infof("Running locally without --leader-election-namespace, leader election disabled.")
+			leaderElect = false
+		}
+	}
+
+	// Serve the probes first so that the operator is reported alive while it waits to become the leader.
+	// The replicas that are not the leader are ready too, they serve the webhooks and take over from the leader
+	// as soon as its lease expires. Only the leader passes the readiness checks of /readyz/leader.
+	schemeRegistered := healthz.NewFlag("waiting for the scheme registration")
+	cacheSynced := healthz.NewFlag("waiting for the caches to sync")
+	leaderElected := healthz.NewFlag("waiting to become the leader")
+	liveness := healthz.NewHandler()
+	liveness.AddCheck("ping", healthz.Ping)
+	readiness := healthz.NewHandler()
+	readiness.AddCheck("scheme", schemeRegistered.Check)
+	readiness.AddCheck("cache", cacheSynced.Check)
+	leaderReadiness := healthz.NewHandler()
+	leaderReadiness.AddCheck("scheme", schemeRegistered.Check)
+	leaderReadiness.AddCheck("cache", cacheSynced.Check)
+	leaderReadiness.AddCheck("leader", leaderElected.Check)
+	healthz.Serve(healthProbeAddr, liveness, readiness, leaderReadiness)
+	if enablePprof {
+		profiling.Serve(pprofPort)
+	}
 
 	watchNamespace, err := k8sutil.GetWatchNamespace()
 	if err != nil {
@@ -79,19 +492,46 @@ func main() {
 		log.Errorf("Error: %v.", err)
 		os.Exit(1)
 	}
+	cfg.QPS, cfg.Burst = kfutils.APIQPS, kfutils.APIBurst
 
 	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "kfctl-lock")
-	if err != nil {
-		log.Errorf("Error: %v.", err)
+	if leaseDuration <= renewDeadline || renewDeadline <= retryPeriod || retryPeriod <= 0 {
+		log.Errorf("Error: --leader-election-lease-duration must be greater than --leader-election-renew-deadline, "+
+			"itself greater than the positive --leader-election-retry-period, got %v, %v and %v.", leaseDuration, renewDeadline, retryPeriod)
 		os.Exit(1)
 	}
 
+	// Select the namespaces by label if WATCH_NAMESPACE_SELECTOR is set (e.g opendatahub.io/watched=true)
+	if selector, found := os.LookupEnv(watchNamespaceSelectorEnvVar); found && selector != "" {
+		kfdefcontroller.NamespaceSelector, err = labels.Parse(selector)
+		if err != nil {
+			log.Errorf("Error: invalid %v %q: %v.", watchNamespaceSelectorEnvVar, selector, err)
+			os.Exit(1)
+		}
+		// The operator watches all namespaces to follow their labels, a restriction to some of them would be lost
+		if watchNamespace != "" {
+			log.Errorf("Error: WATCH_NAMESPACE %v and %v %q cannot be set together.", watchNamespace,
+				watchNamespaceSelectorEnvVar, selector)
+			os.Exit(1)
+		}
+	}
+
 	options := manager.Options{
 		Namespace:          watchNamespace, //"" will watch all namespaces
 		MapperProvider:     restmapper.NewDynamicRESTMapper,
-		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		MetricsBindAddress: net.JoinHostPort(metricsHost, strconv.Itoa(int(metricsPort))),
+		SyncPeriod:         &syncPeriod,
+		// The lease expires when the leader stops renewing it, e.g. once OOM-killed, for another replica to take over
+		LeaderElection:          leaderElect,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaseDuration:           &leaseDuration,
+		RenewDeadline:           &renewDeadline,
+		RetryPeriod:             &retryPeriod,
+	}
+
+	if audit.Enabled {
+		options.NewClient = audit.NewClient
 	}
 
 	// MultiNamespace set in WATCH_NAMESPACE (e.g ns1,ns2)
@@ -102,6 +542,13 @@ func main() {
 		options.NewCache = cache.MultiNamespacedCacheBuilder(strings.Split(watchNamespace, ","))
 	}
 
+	// Drop the managed fields of the cached objects, and cache the managed resources apart when they are selected
+	newCache := options.NewCache
+	if newCache == nil {
+		newCache = cache.New
+	}
+	options.NewCache = cachefilter.NewCacheFunc(newCache)
+
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, options)
 	if err != nil {
@@ -109,6 +556,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The controllers are started along with the runnables of the leader
+	opmetrics.SetLeader(false)
+	err = mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		log.Infof("Became the leader.")
+		opmetrics.SetLeader(true)
+		leaderElected.Set()
+		<-stop
+		return nil
+	}))
+	if err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+
+	// The manager starts the runnables of every replica once the informers of the watched kinds are synced
+	if err := mgr.Add(cacheSynced.SetOnStart()); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+
 	log.Info("Registering Components.")
 
 	// Setup Scheme for all resources
@@ -116,14 +583,115 @@ func main() {
 		log.Errorf("Error: %v.", err)
 		os.Exit(1)
 	}
+	schemeRegistered.Set()
+
+	// The settings of the OperatorConfig override the flags from the start, the controller keeps them up to date
+	operatorconfig.Load(mgr.GetAPIReader())
+	// The FIPS mode is resolved once, a cluster cannot be switched to FIPS after its installation
+	kustomize.ConfigureFIPS(mgr.GetAPIReader())
 
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr); err != nil {
+	if maxConcurrentReconciles < 1 {
+		log.Errorf("Error: --max-concurrent-reconciles must be at least 1, got %v.", maxConcurrentReconciles)
+		os.Exit(1)
+	}
+	if ratelimiter.BaseDelay <= 0 || ratelimiter.MaxDelay < ratelimiter.BaseDelay {
+		log.Errorf("Error: --reconcile-base-delay must be positive and at most --reconcile-max-delay, got %v and %v.",
+			ratelimiter.BaseDelay, ratelimiter.MaxDelay)
+		os.Exit(1)
+	}
+	if err := controller.AddToManager(mgr, crcontroller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+
+	if enableNotebookCuller {
+		if notebook.CullIdleTime <= 0 || notebook.IdlenessCheckPeriod <= 0 {
+			log.Errorf("Error: --cull-idle-time and --idleness-check-period must be positive, got %v and %v.",
+				notebook.CullIdleTime, notebook.IdlenessCheckPeriod)
+			os.Exit(1)
+		}
+		if err := notebook.AddToManager(mgr, crcontroller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}); err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+	}
+
+	if telemetry.Interval <= 0 {
+		log.Errorf("Error: --telemetry-interval must be positive, got %v.", telemetry.Interval)
+		os.Exit(1)
+	}
+	if err := telemetry.AddToManager(mgr); err != nil {
+		log.Errorf("Error: %v.", err)
+		os.Exit(1)
+	}
+
+	if err := audit.AddToManager(mgr); err != nil {
 		log.Errorf("Error: %v.", err)
 		os.Exit(1)
 	}
 
-	if err = serveCRMetrics(cfg); err != nil {
+	if enableJanitor {
+		if janitor.Interval <= 0 {
+			log.Errorf("Error: --janitor-interval must be positive, got %v.", janitor.Interval)
+			os.Exit(1)
+		}
+		if janitor.PipelineRunPodTTL < 0 || janitor.ReplicaSetTTL < 0 || janitor.OrphanedClaimTTL < 0 {
+			log.Errorf("Error: --janitor-pipeline-run-pod-ttl, --janitor-replicaset-ttl and --janitor-orphaned-claim-ttl must not be negative, got %v, %v and %v.",
+				janitor.PipelineRunPodTTL, janitor.ReplicaSetTTL, janitor.OrphanedClaimTTL)
+			os.Exit(1)
+		}
+		if err := janitor.AddToManager(mgr); err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+	}
+
+	// Setup all Webhooks
+	if enableWebhooks {
+		if webhook.CertificateRotateBefore <= 0 || webhook.CertificateRotateBefore >= webhook.CertificateValidity {
+			log.Errorf("Error: --webhook-cert-rotate-before must be positive and shorter than --webhook-cert-validity, got %v and %v.",
+				webhook.CertificateRotateBefore, webhook.CertificateValidity)
+			os.Exit(1)
+		}
+		if err := webhook.AddToManager(mgr, webhookHost, webhookPort, webhookCertDir); err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+	}
+
+	if statusAddr != "" {
+		if err := clusterstatus.AddToManager(mgr, statusAddr); err != nil {
+			log.Errorf("Error: %v.", err)
+			os.Exit(1)
+		}
+	}
+
+	if runLocal {
+		log.Infof("Running locally, not serving the custom resource metrics nor creating the metrics Service and ServiceMonitor.")
+	} else {
+		exposeMetrics(ctx, cfg)
+	}
+
+	log.Infof("Starting the Cmd.")
+
+	stopCh := signals.SetupSignalHandler()
+	// The manifest downloads and the applies in flight are cancelled on shutdown
+	reconcilecontext.CancelOnStop(stopCh)
+
+	// Start the Cmd
+	err = mgr.Start(stopCh)
+	tracing.Flush()
+	if err != nil {
+		log.Errorf("Manager exited non-zero. Error: %v.", err)
+		os.Exit(1)
+	}
+}
+
+// exposeMetrics serves the custom resource metrics and creates the Service and the ServiceMonitor scraping the
+// metrics of the operator. They require the operator to run in the cluster.
+func exposeMetrics(ctx context.Context, cfg *rest.Config) {
+	if err := serveCRMetrics(cfg); err != nil {
 		log.Errorf("Could not generate and serve custom resource metrics. Error: %v.", err.Error())
 	}
 
@@ -136,6 +704,8 @@ func main() {
 	service, err := metrics.CreateMetricsService(ctx, cfg, servicePorts)
 	if err != nil {
 		log.Errorf("Could not create metrics Service. Error: %v.", err.Error())
+	} else if err := preferDualStack(cfg, service); err != nil {
+		log.Warnf("Could not make the metrics Service dual-stack. Error: %v.", err)
 	}
 
 	// CreateServiceMonitors will automatically create the prometheus-operator ServiceMonitor resources
@@ -151,14 +721,17 @@ func main() {
 			log.Errorf("Install prometheus-operator in your cluster to create ServiceMonitor objects. Error: %v.", err.Error())
 		}
 	}
+}
 
-	log.Infof("Starting the Cmd.")
-
-	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
-		log.Errorf("Manager exited non-zero. Error: %v.", err)
-		os.Exit(1)
+// preferDualStack sets the PreferDualStack IP family policy on service, for it to get an IPv4 and an IPv6 address on
+// dual-stack clusters. The policy is not part of the Service API the operator is built with, so it is patched in.
+func preferDualStack(cfg *rest.Config, service *v1.Service) error {
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
 	}
+	patch := []byte(`{"spec":{"ipFamilyPolicy":"PreferDualStack"}}`)
+	return c.Patch(context.TODO(), service, client.ConstantPatch(types.MergePatchType, patch))
 }
 
 // serveCRMetrics gets the Operator/CustomResource GVKs and generates metrics based on those types.
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	diagnosticsDestDir string
+	diagnosticsTimeout time.Duration
+)
+
+// diagnosticsCmd represents the diagnostics command
+var diagnosticsCmd = &cobra.Command{
+	Use:   "diagnostics <kfdef>",
+	Short: "Make the operator collect its diagnostics for a KfDef.",
+	Long: `Make the operator collect the dump of its goroutines, the last errors of the applications of a KfDef and the
+versions of their manifests into the <kfdef>-diagnostics ConfigMap, by annotating the KfDef, and show them once
+collected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		data, err := c.collectDiagnostics(args[0], diagnosticsTimeout)
+		if err != nil {
+			return err
+		}
+		if diagnosticsDestDir != "" {
+			for key, value := range data {
+				if err := ioutil.WriteFile(path.Join(diagnosticsDestDir, key), []byte(value), 0644); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(os.Stdout, "Diagnostics of KfDef %v written to %v\n", args[0], diagnosticsDestDir)
+			return nil
+		}
+		keys := []string{}
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(os.Stdout, "# %v\n%v\n", key, strings.TrimSuffix(data[key], "\n"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diagnosticsCmd)
+	diagnosticsCmd.Flags().StringVar(&diagnosticsDestDir, "dest-dir", "",
+		"Existing directory to write the diagnostics into, one file per key, instead of showing them.")
+	diagnosticsCmd.Flags().DurationVar(&diagnosticsTimeout, "timeout", 2*time.Minute,
+		"How long to wait for the operator to collect the diagnostics.")
+}
+
+// collectDiagnostics requests the operator to collect the diagnostics of the KfDef and returns them once collected
+func (c *clients) collectDiagnostics(name string, timeout time.Duration) (map[string]string, error) {
+	ann := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.CollectDiagnostics}, "/")
+	request := time.Now().UTC().Format(time.RFC3339Nano)
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, ann, request)
+	kfdef := &kfdefv1.KfDef{}
+	kfdef.Namespace = c.namespace
+	kfdef.Name = name
+	if err := c.client.Patch(context.TODO(), kfdef, client.ConstantPatch(types.MergePatchType, []byte(patch))); err != nil {
+		return nil, fmt.Errorf("failed to annotate KfDef %v: %v", name, err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: c.namespace, Name: kfutils.DiagnosticsConfigMapName(name)}
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		if err := c.client.Get(context.TODO(), key, cm); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return cm.Annotations[ann] == request, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("the operator did not collect the diagnostics of KfDef %v in %v", name, timeout)
+	}
+	return cm.Data, err
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var diffApplication string
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <kfdef>",
+	Short: "Show how the cluster differs from the manifests the operator applied.",
+	Long: `Show the changes applying the manifests the operator applied for the applications of a KfDef would make to
+the cluster, validated with a server-side dry run. Nothing is changed in the cluster.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		kfdefs, err := c.kfDefs(args[0])
+		if err != nil {
+			return err
+		}
+		diffs, err := c.diffs(&kfdefs[0], diffApplication)
+		if err != nil {
+			return err
+		}
+		for _, app := range applications(&kfdefs[0], diffApplication) {
+			if diff, ok := diffs[app]; ok {
+				fmt.Fprintf(os.Stdout, "# Application %v\n%v", app, diff)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&diffApplication, "application", "", "Only show the changes of this application.")
+}
+
+// diffs returns the changes applying the manifests of the applications of the KfDef would make to the cluster
+func (c *clients) diffs(kfdef *kfdefv1.KfDef, application string) (map[string]string, error) {
+	manifests, err := c.renderedManifests(kfdef, application)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := apiutil.NewDiscoveryRESTMapper(c.config)
+	if err != nil {
+		return nil, err
+	}
+	diffs := map[string]string{}
+	for app, data := range manifests {
+		diff, err := kustomize.DiffManifests(c.client, mapper, data, kfdef.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff the manifests of %v: %v", app, err)
+		}
+		if diff == "" {
+			diff = "No changes.\n"
+		}
+		diffs[app] = diff
+	}
+	return diffs, nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	eventsSince    time.Duration
+	eventsWarnings bool
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the recent events of the namespace and of the operator.",
+	Long: `Show the events of the namespace of the DataScienceClusters and the KfDefs, and of the namespace of the
+operator, from the oldest to the most recent.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		events, err := c.recentEvents(time.Now().Add(-eventsSince), eventsWarnings)
+		if err != nil {
+			return err
+		}
+		writeEvents(os.Stdout, events)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+	eventsCmd.Flags().DurationVar(&eventsSince, "since", time.Hour, "Only show the events more recent than this.")
+	eventsCmd.Flags().BoolVar(&eventsWarnings, "warnings", false, "Only show the warnings.")
+}
+
+// recentEvents returns the events of the namespace and of the namespace of the operator seen after since, from the
+// oldest to the most recent
+func (c *clients) recentEvents(since time.Time, warnings bool) ([]corev1.Event, error) {
+	namespaces := []string{c.namespace}
+	if pods, err := c.operatorPods(); err == nil && pods[0].Namespace != c.namespace {
+		namespaces = append(namespaces, pods[0].Namespace)
+	}
+	events := []corev1.Event{}
+	for _, ns := range namespaces {
+		list, err := c.clientset.CoreV1().Events(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the events of namespace %v: %v", ns, err)
+		}
+		events = append(events, filterEvents(list.Items, since, warnings)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).Before(eventTime(events[j])) })
+	return events, nil
+}
+
+// filterEvents returns the events seen after since, only the warnings if warnings is set
+func filterEvents(events []corev1.Event, since time.Time, warnings bool) []corev1.Event {
+	filtered := []corev1.Event{}
+	for _, event := range events {
+		if eventTime(event).Before(since) || (warnings && event.Type != corev1.EventTypeWarning) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
+// eventTime returns the last time the event was seen
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// writeEvents writes a table of the events
+func writeEvents(out io.Writer, events []corev1.Event) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+	for _, event := range events {
+		object := event.InvolvedObject
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v %v/%v\t%v\n", eventTime(event).Format(time.RFC3339), event.Type, event.Reason,
+			object.Kind, object.Namespace, object.Name, event.Message)
+	}
+	w.Flush()
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/clusterstatus"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	gatherDestDir string
+	gatherSince   time.Duration
+)
+
+// gatherCmd represents the gather command
+var gatherCmd = &cobra.Command{
+	Use:   "gather",
+	Short: "Gather the state of the deployment for a support case.",
+	Long: `Gather the status, the DataScienceClusters and KfDefs, the recent events, the logs of the operator, the
+diagnostics it last collected, the manifests it applied and how the cluster differs from them into a directory
+to attach to a support case.
+The parts that cannot be gathered are listed in the errors.txt file of the directory.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		dir := gatherDestDir
+		if dir == "" {
+			dir = "odh-must-gather." + time.Now().Format("20060102150405")
+		}
+		g := &gatherer{clients: c, dir: dir}
+		if err := g.gather(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Gathered the state of namespace %v into %v\n", c.namespace, dir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gatherCmd)
+	gatherCmd.Flags().StringVar(&gatherDestDir, "dest-dir", "",
+		"Directory to gather into, defaults to odh-must-gather.<timestamp> in the current directory.")
+	gatherCmd.Flags().DurationVar(&gatherSince, "since", 24*time.Hour, "Only gather the events and logs more recent than this.")
+}
+
+// gatherer writes the state of the deployment into dir, recording the parts it could not gather
+type gatherer struct {
+	*clients
+	dir    string
+	errors []string
+}
+
+func (g *gatherer) gather() error {
+	if err := os.MkdirAll(g.dir, 0755); err != nil {
+		return err
+	}
+	clusters, kfdefs, err := g.instances()
+	if err != nil {
+		g.fail("instances", err)
+	} else {
+		var status bytes.Buffer
+		g.fail("status", writeJSON(&status, clusterstatus.Aggregate(clusters, kfdefs)))
+		g.write("status.json", status.Bytes())
+		g.writeYAML("datascienceclusters.yaml", clusters)
+		g.writeYAML("kfdefs.yaml", kfdefs)
+	}
+
+	since := time.Now().Add(-gatherSince)
+	if events, err := g.recentEvents(since, false); err != nil {
+		g.fail("events", err)
+	} else {
+		var out bytes.Buffer
+		writeEvents(&out, events)
+		g.write("events.txt", out.Bytes())
+	}
+
+	if pods, err := g.operatorPods(); err != nil {
+		g.fail("operator", err)
+	} else {
+		g.writeYAML("operator/pods.yaml", pods)
+		for i := range pods {
+			g.gatherLogs(&pods[i], since)
+		}
+	}
+
+	for i := range kfdefs {
+		kfdef := &kfdefs[i]
+		g.gatherDiagnostics(kfdef)
+		manifests, err := g.renderedManifests(kfdef, "")
+		if err != nil {
+			g.fail("manifests of "+kfdef.Name, err)
+			continue
+		}
+		for app, data := range manifests {
+			g.write(path.Join("manifests", kfdef.Name, app+".yaml"), data)
+		}
+		diffs, err := g.diffs(kfdef, "")
+		if err != nil {
+			g.fail("diffs of "+kfdef.Name, err)
+			continue
+		}
+		for app, diff := range diffs {
+			g.write(path.Join("diffs", kfdef.Name, app+".diff"), []byte(diff))
+		}
+	}
+
+	if len(g.errors) == 0 {
+		return nil
+	}
+	var errors bytes.Buffer
+	for _, e := range g.errors {
+		errors.WriteString(e + "\n")
+	}
+	fmt.Fprintf(os.Stderr, "%v parts could not be gathered, see %v\n", len(g.errors), path.Join(g.dir, "errors.txt"))
+	return ioutil.WriteFile(path.Join(g.dir, "errors.txt"), errors.Bytes(), 0644)
+}
+
+// gatherLogs writes the logs of the containers of the pod, and of their previous run when they restarted
+func (g *gatherer) gatherLogs(pod *corev1.Pod, since time.Time) {
+	for _, status := range pod.Status.ContainerStatuses {
+		previous := []bool{false}
+		if status.RestartCount > 0 {
+			previous = append(previous, true)
+		}
+		for _, p := range previous {
+			name := path.Join("operator", pod.Name, status.Name+".log")
+			if p {
+				name = path.Join("operator", pod.Name, status.Name+".previous.log")
+			}
+			logs, err := g.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: status.Name,
+				Previous:  p,
+				SinceTime: &metav1.Time{Time: since},
+			}).DoRaw()
+			if err != nil {
+				g.fail("logs of "+pod.Name+"/"+status.Name, err)
+				continue
+			}
+			g.write(name, logs)
+		}
+	}
+}
+
+// gatherDiagnostics writes the diagnostics of the KfDef the operator collected last, see the diagnostics command
+func (g *gatherer) gatherDiagnostics(kfdef *kfdefv1.KfDef) {
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: kfdef.Namespace, Name: kfutils.DiagnosticsConfigMapName(kfdef.Name)}
+	if err := g.client.Get(context.TODO(), key, cm); err != nil {
+		g.fail("diagnostics of "+kfdef.Name, client.IgnoreNotFound(err))
+		return
+	}
+	for name, data := range cm.Data {
+		g.write(path.Join("diagnostics", kfdef.Name, name), []byte(data))
+	}
+}
+
+// write writes the file under the directory
+func (g *gatherer) write(name string, data []byte) {
+	file := path.Join(g.dir, name)
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		g.fail(name, err)
+		return
+	}
+	g.fail(name, ioutil.WriteFile(file, data, 0644))
+}
+
+// writeYAML writes v as YAML under the directory
+func (g *gatherer) writeYAML(name string, v interface{}) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		g.fail(name, err)
+		return
+	}
+	g.write(name, data)
+}
+
+// fail records that the part could not be gathered, unless err is nil
+func (g *gatherer) fail(part string, err error) {
+	if err != nil {
+		g.errors = append(g.errors, fmt.Sprintf("%v: %v", part, err))
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+var manifestsApplication string
+
+// manifestsCmd represents the manifests command
+var manifestsCmd = &cobra.Command{
+	Use:   "manifests <kfdef>",
+	Short: "Show the manifests the operator applied for the applications of a KfDef.",
+	Long: `Show the rendered manifests the operator applied for the applications of a KfDef, as kept by the leader
+of the operator.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		kfdefs, err := c.kfDefs(args[0])
+		if err != nil {
+			return err
+		}
+		manifests, err := c.renderedManifests(&kfdefs[0], manifestsApplication)
+		if err != nil {
+			return err
+		}
+		for _, app := range applications(&kfdefs[0], manifestsApplication) {
+			if data, ok := manifests[app]; ok {
+				fmt.Fprintf(os.Stdout, "# Application %v\n%s", app, data)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manifestsCmd)
+	manifestsCmd.Flags().StringVar(&manifestsApplication, "application", "", "Only show the manifests of this application.")
+}
+
+// applications returns the names of the applications of the KfDef, or only application if it is set
+func applications(kfdef *kfdefv1.KfDef, application string) []string {
+	apps := []string{}
+	for _, app := range kfdef.Spec.Applications {
+		if application == "" || app.Name == application {
+			apps = append(apps, app.Name)
+		}
+	}
+	return apps
+}
+
+// renderedManifests reads the manifests applied for the applications of the KfDef from the leader of the operator.
+// The applications the operator did not apply yet are left out.
+func (c *clients) renderedManifests(kfdef *kfdefv1.KfDef, application string) (map[string][]byte, error) {
+	apps := applications(kfdef, application)
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("KfDef %v has no application %v", kfdef.Name, application)
+	}
+	pods, err := c.operatorPods()
+	if err != nil {
+		return nil, err
+	}
+	manifests := map[string][]byte{}
+	for _, app := range apps {
+		data, err := c.readFile(&pods[0], kustomize.RenderedManifestsPath(appDir(kfdef), app))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "No manifests applied for %v: %v\n", app, err)
+			continue
+		}
+		manifests[app] = data
+	}
+	return manifests, nil
+}
+
+// readFile reads the file from the first container of the pod
+func (c *clients) readFile(pod *corev1.Pod, file string) ([]byte, error) {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: pod.Spec.Containers[0].Name,
+			Command:   []string{"cat", file},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return nil, fmt.Errorf("%v %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	apis "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rootCmd represents the base command when called without any subcommands
+var rootCmd = &cobra.Command{
+	Use:   "kubectl-odh",
+	Short: "Diagnose an Open Data Hub deployment",
+	Long: `Diagnose an Open Data Hub deployment: show the status of its components, the recent events, the manifests
+the operator applied and how the cluster differs from them, or gather all of them for a support case.`,
+	SilenceUsage: true,
+}
+
+var (
+	// VERSION is set during build
+	VERSION string
+
+	kubeconfig        string
+	namespace         string
+	operatorNamespace string
+	operatorSelector  string
+	leaderElectionID  string
+)
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute(version string) {
+	VERSION = version
+	rootCmd.Version = version
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig file, defaults to $KUBECONFIG or ~/.kube/config.")
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "",
+		"Namespace of the DataScienceClusters and the KfDefs, defaults to the namespace of the current context.")
+	rootCmd.PersistentFlags().StringVar(&operatorNamespace, "operator-namespace", "",
+		"Namespace of the operator, it is looked up in all the namespaces when empty.")
+	rootCmd.PersistentFlags().StringVar(&operatorSelector, "operator-selector", "name=kubeflow-operator",
+		"Label selector of the pods of the operator.")
+	rootCmd.PersistentFlags().StringVar(&leaderElectionID, "leader-election-id", "kfctl-leader",
+		"Name of the leader election ConfigMap of the operator, the manifests are read from its leader.")
+}
+
+// clients are the clients of the cluster the commands read from
+type clients struct {
+	config    *rest.Config
+	client    client.Client
+	clientset kubernetes.Interface
+	namespace string
+}
+
+// newClients connects to the cluster of the kubeconfig, and defaults the namespace to the one of its context
+func newClients() (*clients, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %v", err)
+	}
+	ns := namespace
+	if ns == "" {
+		if ns, _, err = clientConfig.Namespace(); err != nil {
+			return nil, err
+		}
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	kubeclient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &clients{config: config, client: kubeclient, clientset: clientset, namespace: ns}, nil
+}
+
+// kfDefs returns the KfDefs of the namespace, or the one named name
+func (c *clients) kfDefs(name string) ([]kfdefv1.KfDef, error) {
+	if name != "" {
+		kfdef := &kfdefv1.KfDef{}
+		if err := c.client.Get(context.TODO(), client.ObjectKey{Namespace: c.namespace, Name: name}, kfdef); err != nil {
+			return nil, err
+		}
+		return []kfdefv1.KfDef{*kfdef}, nil
+	}
+	kfdefs := &kfdefv1.KfDefList{}
+	if err := c.client.List(context.TODO(), kfdefs, client.InNamespace(c.namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(kfdefs.Items, func(i, j int) bool { return kfdefs.Items[i].Name < kfdefs.Items[j].Name })
+	return kfdefs.Items, nil
+}
+
+// operatorPods returns the pods of the operator, the leader first
+func (c *clients) operatorPods() ([]corev1.Pod, error) {
+	pods, err := c.clientset.CoreV1().Pods(operatorNamespace).List(metav1.ListOptions{LabelSelector: operatorSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pods of the operator: %v", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no pod of the operator matches %v", operatorSelector)
+	}
+	leader := ""
+	cm, err := c.clientset.CoreV1().ConfigMaps(pods.Items[0].Namespace).Get(leaderElectionID, metav1.GetOptions{})
+	if err == nil {
+		leader = leaderPod(cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey])
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name == leader && pods.Items[j].Name != leader
+	})
+	return pods.Items, nil
+}
+
+// leaderPod returns the name of the pod holding the leader election record, whose identity is the host name of the
+// pod followed by a unique id
+func leaderPod(record string) string {
+	if record == "" {
+		return ""
+	}
+	leader := resourcelock.LeaderElectionRecord{}
+	if err := json.Unmarshal([]byte(record), &leader); err != nil {
+		return ""
+	}
+	if i := strings.LastIndex(leader.HolderIdentity, "_"); i > 0 {
+		return leader.HolderIdentity[:i]
+	}
+	return leader.HolderIdentity
+}
+
+// appDir returns the directory of the KfDef in the pods of the operator
+func appDir(kfdef *kfdefv1.KfDef) string {
+	return path.Join("/tmp", kfdef.Namespace, kfdef.Name)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLeaderPod(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{
+			name: "no record",
+		},
+		{
+			name:   "invalid record",
+			record: "{",
+		},
+		{
+			name:   "host name and id",
+			record: `{"holderIdentity":"kubeflow-operator-7d9f8-x2v4k_0a1b2c3d-1111-2222-3333-444455556666"}`,
+			want:   "kubeflow-operator-7d9f8-x2v4k",
+		},
+		{
+			name:   "host name only",
+			record: `{"holderIdentity":"kubeflow-operator-7d9f8-x2v4k"}`,
+			want:   "kubeflow-operator-7d9f8-x2v4k",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leaderPod(tt.record); got != tt.want {
+				t.Errorf("leaderPod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	now := time.Now()
+	event := func(name string, eventType string, last time.Time) corev1.Event {
+		return corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-48 * time.Hour))},
+			Type:          eventType,
+			LastTimestamp: metav1.NewTime(last),
+		}
+	}
+	events := []corev1.Event{
+		event("old", corev1.EventTypeWarning, now.Add(-2*time.Hour)),
+		event("normal", corev1.EventTypeNormal, now.Add(-time.Minute)),
+		event("warning", corev1.EventTypeWarning, now.Add(-time.Minute)),
+		{ObjectMeta: metav1.ObjectMeta{Name: "created", CreationTimestamp: metav1.NewTime(now)}, Type: corev1.EventTypeNormal},
+	}
+	tests := []struct {
+		name     string
+		warnings bool
+		want     []string
+	}{
+		{
+			name: "recent",
+			want: []string{"normal", "warning", "created"},
+		},
+		{
+			name:     "recent warnings",
+			warnings: true,
+			want:     []string{"warning"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, e := range filterEvents(events, now.Add(-time.Hour), tt.warnings) {
+				got = append(got, e.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("filterEvents() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("filterEvents() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
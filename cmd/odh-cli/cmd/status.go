@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/clusterstatus"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var statusOutput string
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the components and the applications.",
+	Long: `Show the status of the components of the DataScienceClusters, of the operators they depend on, and of the
+applications of the KfDefs of the namespace.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusOutput != "" && statusOutput != "json" {
+			return fmt.Errorf("invalid output format %q, must be json", statusOutput)
+		}
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		clusters, kfdefs, err := c.instances()
+		if err != nil {
+			return err
+		}
+		if statusOutput == "json" {
+			return writeJSON(os.Stdout, clusterstatus.Aggregate(clusters, kfdefs))
+		}
+		writeStatus(os.Stdout, clusters, kfdefs)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "", "Output format, json for the aggregated status.")
+}
+
+// instances returns the DataScienceClusters and the KfDefs of the namespace
+func (c *clients) instances() ([]dscv1alpha1.DataScienceCluster, []kfdefv1.KfDef, error) {
+	clusters := &dscv1alpha1.DataScienceClusterList{}
+	if err := c.client.List(context.TODO(), clusters, client.InNamespace(c.namespace)); err != nil {
+		return nil, nil, fmt.Errorf("failed to list the DataScienceClusters: %v", err)
+	}
+	kfdefs, err := c.kfDefs("")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the KfDefs: %v", err)
+	}
+	return clusters.Items, kfdefs, nil
+}
+
+// writeStatus writes a table of the components of every DataScienceCluster and of the applications of every KfDef
+func writeStatus(out io.Writer, clusters []dscv1alpha1.DataScienceCluster, kfdefs []kfdefv1.KfDef) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, cluster := range clusters {
+		fmt.Fprintf(w, "DataScienceCluster %v/%v: %v, %v components ready\n", cluster.Namespace, cluster.Name,
+			cluster.Status.Phase, cluster.Status.ReadyComponents)
+		fmt.Fprintln(w, "COMPONENT\tENABLED\tPHASE\tMESSAGE")
+		for _, component := range cluster.Status.Components {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", component.Name, component.Enabled, component.Phase, component.Message)
+		}
+		if len(cluster.Status.Dependencies) > 0 {
+			fmt.Fprintln(w, "DEPENDENCY\tPACKAGE\tPHASE\tMESSAGE")
+			for _, dependency := range cluster.Status.Dependencies {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", dependency.Name, dependency.Package, dependency.Phase, dependency.Message)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	for _, kfdef := range kfdefs {
+		fmt.Fprintf(w, "KfDef %v/%v\n", kfdef.Namespace, kfdef.Name)
+		fmt.Fprintln(w, "APPLICATION\tCONDITION\tREASON\tMESSAGE")
+		for _, cond := range kfdef.Status.ApplicationConditions {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", cond.Application, cond.Type, cond.Reason, cond.Message)
+		}
+		for _, failure := range kfdef.Status.HookFailures {
+			fmt.Fprintf(w, "Hook Job %v of %v failed %v times: %v\n", failure.Job, failure.Application, failure.Attempts,
+				failure.Message)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(clusters) == 0 && len(kfdefs) == 0 {
+		fmt.Fprintln(w, "No DataScienceCluster nor KfDef found.")
+	}
+	w.Flush()
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
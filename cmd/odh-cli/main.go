@@ -0,0 +1,16 @@
+// odh-cli is the kubectl and oc plugin collecting the state of an Open Data Hub deployment for support cases.
+// Installed on the PATH as kubectl-odh, it runs as kubectl odh or oc odh.
+package main
+
+import (
+	"github.com/kubeflow/kfctl/v3/cmd/odh-cli/cmd"
+)
+
+var (
+	// VERSION is set during build
+	VERSION = "0.0.1"
+)
+
+func main() {
+	cmd.Execute(VERSION)
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DiscoveredLabel is the label of the AcceleratorProfiles created by the operator for the accelerators it
+// discovers on the nodes.
+const DiscoveredLabel = "accelerator.opendatahub.io/discovered"
+
+// AcceleratorProfileSpec defines how the workloads request an accelerator. The operator sets it when it creates
+// the profile, it can be edited afterwards.
+type AcceleratorProfileSpec struct {
+	// DisplayName is the name of the accelerator shown to the users.
+	DisplayName string `json:"displayName"`
+
+	// Enabled offers the accelerator to the users when true.
+	Enabled bool `json:"enabled"`
+
+	// Identifier is the extended resource the workloads request the accelerator with, e.g. nvidia.com/gpu.
+	Identifier string `json:"identifier"`
+
+	// Description of the accelerator.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Tolerations are added to the workloads requesting the accelerator so they can run on the tainted
+	// accelerator nodes.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// AcceleratorProfileStatus is the accelerator discovered on the nodes
+type AcceleratorProfileStatus struct {
+	// Vendor of the accelerator.
+	Vendor string `json:"vendor,omitempty"`
+
+	// Product is the model of the accelerator, as labeled by the GPU feature discovery.
+	Product string `json:"product,omitempty"`
+
+	// Count is the number of accelerators allocatable on all the nodes.
+	Count int64 `json:"count"`
+
+	// Nodes are the nodes having the accelerator.
+	Nodes []AcceleratorNode `json:"nodes,omitempty"`
+
+	// MIGProfiles are the Multi-Instance GPU partitions of the accelerator exposed by the nodes.
+	MIGProfiles []MIGProfile `json:"migProfiles,omitempty"`
+}
+
+// AcceleratorNode is a node having the accelerator.
+type AcceleratorNode struct {
+	// Name of the node.
+	Name string `json:"name"`
+	// Count is the number of accelerators allocatable on the node.
+	Count int64 `json:"count"`
+}
+
+// MIGProfile is a Multi-Instance GPU partition.
+type MIGProfile struct {
+	// Name of the partition, e.g. 1g.5gb.
+	Name string `json:"name"`
+	// Identifier is the extended resource the workloads request the partition with, e.g. nvidia.com/mig-1g.5gb.
+	Identifier string `json:"identifier"`
+	// Count is the number of partitions allocatable on all the nodes.
+	Count int64 `json:"count"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AcceleratorProfile is the Schema for the acceleratorprofiles API
+// +k8s:openapi-gen=true
+type AcceleratorProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AcceleratorProfileSpec   `json:"spec,omitempty"`
+	Status AcceleratorProfileStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AcceleratorProfileList contains a list of AcceleratorProfile
+type AcceleratorProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AcceleratorProfile `json:"items"`
+}
@@ -0,0 +1,10 @@
+package apps
+
+import (
+	"github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1beta1"
+)
+
+func init() {
+	// Register the v1beta1 types for the conversion webhook to convert them to the v1 ones
+	AddToSchemes = append(AddToSchemes, v1beta1.SchemeBuilder.AddToScheme)
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DataConnectionSpec defines an S3 compatible bucket of a project
+type DataConnectionSpec struct {
+	// DisplayName is the name of the connection shown by the dashboard. Defaults to the name of the connection.
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Endpoint is the URL of the S3 endpoint, e.g. https://s3.amazonaws.com or http://minio.example.com:9000.
+	Endpoint string `json:"endpoint"`
+
+	// Region of the bucket. Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Bucket of the connection.
+	Bucket string `json:"bucket"`
+
+	// CredentialsSecret is the Secret of the namespace of the DataConnection holding the access keys of the bucket.
+	CredentialsSecret CredentialsSecret `json:"credentialsSecret"`
+}
+
+// CredentialsSecret is a Secret holding the access keys of a bucket.
+type CredentialsSecret struct {
+	// SecretName is the name of the Secret.
+	SecretName string `json:"secretName"`
+
+	// AccessKey is the key of the access key ID. Defaults to AWS_ACCESS_KEY_ID.
+	// +optional
+	AccessKey string `json:"accessKey,omitempty"`
+
+	// SecretKey is the key of the secret access key. Defaults to AWS_SECRET_ACCESS_KEY.
+	// +optional
+	SecretKey string `json:"secretKey,omitempty"`
+}
+
+// DataConnectionConditionType is the type of a condition of a DataConnection.
+type DataConnectionConditionType string
+
+const (
+	// DataConnectionReady is true once the bucket is reachable with the credentials of the connection.
+	DataConnectionReady DataConnectionConditionType = "Ready"
+)
+
+// DataConnectionCondition is an observation of the state of a DataConnection.
+type DataConnectionCondition struct {
+	// Type of the condition.
+	Type DataConnectionConditionType `json:"type"`
+
+	// Status of the condition, True, False or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The reason for the last transition of the condition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time the status of the condition changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// DataConnectionStatus defines the observed state of DataConnection
+type DataConnectionStatus struct {
+	// ObservedGeneration is the generation of the spec the connection was verified from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions of the connection.
+	Conditions []DataConnectionCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// SecretName is the name of the Secret of the connection listed by the dashboard.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataConnection is the Schema for the dataconnections API
+// +k8s:openapi-gen=true
+type DataConnection struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DataConnectionSpec   `json:"spec,omitempty"`
+	Status DataConnectionStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataConnectionList contains a list of DataConnection
+type DataConnectionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DataConnection `json:"items"`
+}
@@ -0,0 +1,416 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DataScienceClusterSpec defines the desired state of DataScienceCluster
+type DataScienceClusterSpec struct {
+	// Components selects the Open Data Hub components to deploy.
+	Components Components `json:"components,omitempty"`
+
+	// ManifestsURI is the location of the odh-manifests tarball the components are deployed from.
+	// Can use any URI understood by go-getter.
+	// +optional
+	ManifestsURI string `json:"manifestsUri,omitempty"`
+
+	// Telemetry opts in to the reporting of usage data.
+	// +optional
+	Telemetry Telemetry `json:"telemetry,omitempty"`
+
+	// Groups are the user groups granted access to the dashboard, the notebooks and the pipelines.
+	// +optional
+	Groups Groups `json:"groups,omitempty"`
+
+	// InstallDependencies subscribes to the OperatorHub operators the enabled components depend on when they are
+	// not installed, e.g. OpenShift Serverless and Service Mesh for KServe. They are only reported otherwise.
+	// +optional
+	InstallDependencies bool `json:"installDependencies,omitempty"`
+
+	// GPUSupport requires the Node Feature Discovery and NVIDIA GPU operators, which label the nodes with their
+	// GPUs and expose them to the workloads.
+	// +optional
+	GPUSupport bool `json:"gpuSupport,omitempty"`
+
+	// FeatureGates enables or disables the experimental features of the operator for the components, overriding
+	// the --feature-gates flag of the operator, e.g. KServeRawDeployment: false. They are copied to the KfDefs of
+	// the components.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// Groups holds the user groups synchronized into the RoleBindings and the ClusterRoleBindings of the Open Data Hub
+// applications.
+type Groups struct {
+	// AdminGroups administer the dashboard, and the notebooks and the pipelines of the namespace of the
+	// DataScienceCluster and of all the data science projects. Defaults to odh-admins.
+	// +optional
+	AdminGroups []string `json:"adminGroups,omitempty"`
+
+	// UserGroups are allowed to use the dashboard and to read its settings. Defaults to system:authenticated, all
+	// the users of the cluster.
+	// +optional
+	UserGroups []string `json:"userGroups,omitempty"`
+}
+
+// Telemetry holds the settings of the usage telemetry. The data reported is the version of the operator, the
+// enabled components and the size bucket of the cluster, along with a random ID of the installation, stable across
+// the reports.
+type Telemetry struct {
+	// Enabled reports the usage data periodically. Defaults to false.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MetricsOnly exposes the usage data as metrics of the operator only, without sending it to the telemetry
+	// endpoint.
+	// +optional
+	MetricsOnly bool `json:"metricsOnly,omitempty"`
+}
+
+// Components lists the Open Data Hub components that can be toggled individually.
+type Components struct {
+	// Dashboard is the Open Data Hub dashboard.
+	Dashboard Component `json:"dashboard,omitempty"`
+
+	// Workbenches is the notebook controller and notebook images.
+	Workbenches Component `json:"workbenches,omitempty"`
+
+	// DataSciencePipelines is the pipelines operator and API server.
+	DataSciencePipelines Component `json:"datasciencepipelines,omitempty"`
+
+	// ModelServing is the model serving stack, served with ModelMesh or KServe.
+	ModelServing ModelServing `json:"modelserving,omitempty"`
+
+	// TrustyAI is the TrustyAI operator, monitoring the bias and the drift of the models served by the
+	// modelserving component from their inference payloads.
+	TrustyAI Component `json:"trustyai,omitempty"`
+
+	// Workloads is the KubeRay and CodeFlare operators, running the distributed workloads launched from the
+	// notebooks on Ray clusters.
+	Workloads Workloads `json:"workloads,omitempty"`
+
+	// Kueue is the Kueue quota management of the workloads of the data science projects.
+	Kueue Kueue `json:"kueue,omitempty"`
+
+	// Monitoring is the Prometheus rules, the ServiceMonitors and the Grafana dashboards of the enabled components.
+	Monitoring Monitoring `json:"monitoring,omitempty"`
+}
+
+// Component holds the settings shared by all components.
+type Component struct {
+	// Enabled deploys the component when true and removes it when false.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// DevFlags are the settings for developing the component.
+	// +optional
+	DevFlags *DevFlags `json:"devFlags,omitempty"`
+}
+
+// DevFlags holds the settings for developing a component against the manifests of a development branch, while the
+// other components keep using the release manifests.
+type DevFlags struct {
+	// Manifests override the source of the manifests of the applications of the component. The first one matching
+	// an application is used.
+	// +optional
+	Manifests []ManifestsConfig `json:"manifests,omitempty"`
+}
+
+// ManifestsConfig is a source of manifests overriding the release manifests of applications of a component.
+type ManifestsConfig struct {
+	// URI of the manifests, e.g. https://github.com/example/odh-manifests/tarball/my-branch, or a git URI such as
+	// https://github.com/example/odh-manifests.git?ref=my-branch. Can use any URI understood by go-getter.
+	URI string `json:"uri"`
+
+	// ContextDir is the directory of the URI the odh-manifests paths of the applications are looked up in, e.g.
+	// manifests. Defaults to the root of the URI.
+	// +optional
+	ContextDir string `json:"contextDir,omitempty"`
+
+	// SourcePath is the odh-manifests path of the application the manifests are used for, e.g. odh-dashboard,
+	// including the paths under it. They are used for every application of the component when it is empty.
+	// +optional
+	SourcePath string `json:"sourcePath,omitempty"`
+}
+
+// ServingPlatform is the platform the models are served with.
+type ServingPlatform string
+
+const (
+	// ModelMesh serves many models in shared pods. It is the default platform.
+	ModelMesh ServingPlatform = "ModelMesh"
+
+	// KServe serves every model with its own Knative service. It requires the OpenShift Serverless and
+	// Service Mesh operators.
+	KServe ServingPlatform = "KServe"
+)
+
+// ModelServing holds the settings of the model serving component.
+type ModelServing struct {
+	Component `json:",inline"`
+
+	// Platform the models are served with, ModelMesh or KServe. Defaults to ModelMesh.
+	// +optional
+	Platform ServingPlatform `json:"platform,omitempty"`
+
+	// ServingRuntimes are the names of the ServingRuntime templates installed for the users to serve their models
+	// with, e.g. ovms. The templates of the platform are installed.
+	// +optional
+	ServingRuntimes []string `json:"servingRuntimes,omitempty"`
+
+	// RawDeployment serves the KServe models with Deployments instead of Knative services, without the OpenShift
+	// Serverless and Service Mesh operators.
+	// +optional
+	RawDeployment bool `json:"rawDeployment,omitempty"`
+
+	// Serving configures the KnativeServing the KServe models are served with, unless RawDeployment is set.
+	// +optional
+	Serving KnativeServing `json:"serving,omitempty"`
+}
+
+// Workloads holds the settings of the distributed workloads component.
+type Workloads struct {
+	Component `json:",inline"`
+
+	// RayClusterTemplates are the RayClusters offered to the users launching distributed workloads from their
+	// notebooks. A small, a medium and a large template are offered when it is empty.
+	// +optional
+	RayClusterTemplates []RayClusterTemplate `json:"rayClusterTemplates,omitempty"`
+
+	// LocalQueue is the name of the Kueue LocalQueue of the namespaces of the users the RayClusters of the
+	// templates are queued in, until Kueue admits them. Defaults to the LocalQueue of the kueue component when it
+	// is enabled. They are started right away when it is empty.
+	// +optional
+	LocalQueue string `json:"localQueue,omitempty"`
+}
+
+// RayClusterTemplate is a RayCluster offered to the users.
+type RayClusterTemplate struct {
+	// Name of the template.
+	Name string `json:"name"`
+
+	// Image of the head and the workers. Defaults to the Ray image the operator is configured with.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Workers is the number of workers of the cluster.
+	Workers int32 `json:"workers"`
+
+	// HeadResources are the resources of the head of the cluster.
+	// +optional
+	HeadResources corev1.ResourceRequirements `json:"headResources,omitempty"`
+
+	// WorkerResources are the resources of every worker of the cluster.
+	// +optional
+	WorkerResources corev1.ResourceRequirements `json:"workerResources,omitempty"`
+}
+
+// Kueue holds the settings of the Kueue integration.
+type Kueue struct {
+	// ManagementState is Managed for the operator to install Kueue and create its queues, Unmanaged to create the
+	// queues of an existing installation of Kueue, or Removed to leave Kueue alone. Defaults to Removed.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// ClusterQueue is the name of the ClusterQueue the workloads of the data science projects share. Defaults to
+	// odh-default.
+	// +optional
+	ClusterQueue string `json:"clusterQueue,omitempty"`
+
+	// Quotas are the nominal quotas of the resources of the ClusterQueue, e.g. cpu, memory and nvidia.com/gpu.
+	// Defaults to 64 CPUs and 256Gi of memory.
+	// +optional
+	Quotas corev1.ResourceList `json:"quotas,omitempty"`
+
+	// LocalQueue is the name of the LocalQueue created in every data science project, which the pipelines and
+	// the Ray workloads are queued in. Defaults to default.
+	// +optional
+	LocalQueue string `json:"localQueue,omitempty"`
+
+	// DevFlags are the settings for developing the component.
+	// +optional
+	DevFlags *DevFlags `json:"devFlags,omitempty"`
+}
+
+// ManagementState tells whether the operator manages a resource the components depend on.
+type ManagementState string
+
+const (
+	// Managed resources are created and configured by the operator.
+	Managed ManagementState = "Managed"
+
+	// Unmanaged resources are created and configured by the administrator, the operator only requires them.
+	Unmanaged ManagementState = "Unmanaged"
+
+	// Removed resources are deleted by the operator.
+	Removed ManagementState = "Removed"
+)
+
+// KnativeServing holds the settings of the KnativeServing of the OpenShift Serverless operator.
+type KnativeServing struct {
+	// ManagementState is Managed for the operator to create and configure the KnativeServing, or Unmanaged to use
+	// an existing one as it is. Defaults to Managed.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// Domain of the URLs of the models, e.g. models.apps.example.com. Defaults to the domain of the cluster.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// AutoTLS issues the certificates of the URLs of the models with the cert-manager integration of Knative and
+	// redirects HTTP requests to HTTPS.
+	// +optional
+	AutoTLS bool `json:"autoTls,omitempty"`
+}
+
+// Monitoring holds the settings of the monitoring component.
+type Monitoring struct {
+	// ManagementState is Managed for the operator to deploy the monitoring of the enabled components, or Removed to
+	// remove it. Defaults to Removed.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// GrafanaOperator deploys the Grafana dashboards as GrafanaDashboard resources of the Grafana operator instead
+	// of ConfigMaps picked up by the dashboard sidecar of Grafana.
+	// +optional
+	GrafanaOperator bool `json:"grafanaOperator,omitempty"`
+
+	// AlertmanagerConfigSecret is the name of a Secret of the namespace of the DataScienceCluster whose
+	// alertmanager.yaml key holds the route and the receivers of the alerts, as in the spec of an AlertmanagerConfig.
+	// +optional
+	AlertmanagerConfigSecret string `json:"alertmanagerConfigSecret,omitempty"`
+
+	// DisabledAlerts are the names of the default alerts that are not deployed, e.g. ODHCertificateExpiring.
+	// +optional
+	DisabledAlerts []string `json:"disabledAlerts,omitempty"`
+
+	// DevFlags are the settings for developing the component.
+	// +optional
+	DevFlags *DevFlags `json:"devFlags,omitempty"`
+}
+
+// DataScienceClusterStatus defines the observed state of DataScienceCluster
+type DataScienceClusterStatus struct {
+	// Phase summarizes the state of all enabled components.
+	Phase ComponentPhase `json:"phase,omitempty"`
+
+	// Components reports the state of each component.
+	Components []ComponentStatus `json:"components,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+
+	// Dependencies reports the state of the operators the components depend on.
+	Dependencies []DependencyStatus `json:"dependencies,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+
+	// InstalledComponents reports whether each enabled component is deployed and ready, by name.
+	InstalledComponents map[string]bool `json:"installedComponents,omitempty"`
+
+	// ReadyComponents summarizes the enabled components that are ready, e.g. 3/4.
+	ReadyComponents string `json:"readyComponents,omitempty"`
+
+	// ComponentCounts counts the enabled components by phase.
+	ComponentCounts ComponentCounts `json:"componentCounts,omitempty"`
+}
+
+// ComponentCounts counts the enabled components of a DataScienceCluster by phase.
+type ComponentCounts struct {
+	// Ready components.
+	Ready int `json:"ready"`
+	// Progressing components.
+	Progressing int `json:"progressing"`
+	// Degraded components, which failed to be deployed.
+	Degraded int `json:"degraded"`
+	// Blocked components, waiting for the operators or resources they depend on.
+	Blocked int `json:"blocked"`
+}
+
+type ComponentPhase string
+
+const (
+	// ComponentReady means the component was deployed successfully.
+	ComponentReady ComponentPhase = "Ready"
+
+	// ComponentProgressing means the component is being deployed.
+	ComponentProgressing ComponentPhase = "Progressing"
+
+	// ComponentFailed means the component could not be deployed.
+	ComponentFailed ComponentPhase = "Failed"
+
+	// ComponentBlocked means the component waits for the operators or resources it depends on.
+	ComponentBlocked ComponentPhase = "Blocked"
+
+	// ComponentDisabled means the component is not deployed.
+	ComponentDisabled ComponentPhase = "Disabled"
+)
+
+// ComponentStatus is the observed state of a single component.
+type ComponentStatus struct {
+	// Name of the component.
+	Name string `json:"name"`
+	// Enabled mirrors the spec setting the status was computed for.
+	Enabled bool `json:"enabled"`
+	// Phase of the component.
+	Phase ComponentPhase `json:"phase,omitempty"`
+	// The last time the phase was updated.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// A human readable message indicating details about the phase.
+	Message string `json:"message,omitempty"`
+}
+
+type DependencyPhase string
+
+const (
+	// DependencyInstalled means the operator is installed.
+	DependencyInstalled DependencyPhase = "Installed"
+
+	// DependencyInstalling means the operator was subscribed to and is being installed by OLM.
+	DependencyInstalling DependencyPhase = "Installing"
+
+	// DependencyMissing means the operator is not installed, the components depending on it are blocked.
+	DependencyMissing DependencyPhase = "Missing"
+)
+
+// DependencyStatus is the observed state of an operator the components depend on.
+type DependencyStatus struct {
+	// Name of the operator.
+	Name string `json:"name"`
+	// Package of the operator in OperatorHub.
+	Package string `json:"package"`
+	// Phase of the operator.
+	Phase DependencyPhase `json:"phase"`
+	// A human readable message indicating how to install the operator, or details about its installation.
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataScienceCluster is the Schema for the datascienceclusters API
+// +k8s:openapi-gen=true
+type DataScienceCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DataScienceClusterSpec   `json:"spec,omitempty"`
+	Status DataScienceClusterStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataScienceClusterList contains a list of DataScienceCluster
+type DataScienceClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DataScienceCluster `json:"items"`
+}
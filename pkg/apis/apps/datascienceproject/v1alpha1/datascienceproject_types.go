@@ -0,0 +1,122 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DataScienceProjectSpec defines the namespace of a data science project and its settings
+type DataScienceProjectSpec struct {
+	// DisplayName is the name of the project shown by the dashboard. Defaults to the name of the project.
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Description of the project shown by the dashboard.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Groups are the user groups granted access to the namespace of the project.
+	// +optional
+	Groups []GroupBinding `json:"groups,omitempty"`
+
+	// Quota is the hard limits of the ResourceQuota of the namespace, e.g. requests.cpu, requests.memory,
+	// requests.nvidia.com/gpu and persistentvolumeclaims. Defaults to 8 requested CPUs, 32Gi of requested memory,
+	// 10 PersistentVolumeClaims and 100Gi of requested storage.
+	// +optional
+	Quota corev1.ResourceList `json:"quota,omitempty"`
+
+	// AllowedNamespaces are the namespaces, besides the OpenShift router, monitoring and the namespace of the Open
+	// Data Hub applications, allowed to connect to the pods of the project by its NetworkPolicy.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// ServiceMesh enrolls the namespace into the ServiceMeshMemberRoll of the control plane the operator is
+	// configured with.
+	// +optional
+	ServiceMesh bool `json:"serviceMesh,omitempty"`
+}
+
+// GroupBinding grants a role of the namespace of the project to a user group.
+type GroupBinding struct {
+	// Name of the group.
+	Name string `json:"name"`
+
+	// Role of the group, admin, edit or view. Defaults to edit.
+	// +optional
+	Role ProjectRole `json:"role,omitempty"`
+}
+
+// ProjectRole is the ClusterRole bound to a group in the namespace of a project.
+type ProjectRole string
+
+const (
+	// ProjectAdmin manages the project, including the access of the other users.
+	ProjectAdmin ProjectRole = "admin"
+
+	// ProjectEdit creates and edits the workbenches, the pipelines and the models of the project.
+	ProjectEdit ProjectRole = "edit"
+
+	// ProjectView lists the resources of the project.
+	ProjectView ProjectRole = "view"
+)
+
+// DataScienceProjectPhase summarizes the state of the namespace of a project.
+type DataScienceProjectPhase string
+
+const (
+	// DataScienceProjectReady means the namespace and its settings are provisioned.
+	DataScienceProjectReady DataScienceProjectPhase = "Ready"
+
+	// DataScienceProjectFailed means the spec is invalid or the namespace could not be provisioned.
+	DataScienceProjectFailed DataScienceProjectPhase = "Failed"
+)
+
+// DataScienceProjectStatus defines the observed state of DataScienceProject
+type DataScienceProjectStatus struct {
+	// ObservedGeneration is the generation of the spec the namespace was provisioned from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the project.
+	Phase DataScienceProjectPhase `json:"phase,omitempty"`
+
+	// A human readable message indicating details about the phase.
+	Message string `json:"message,omitempty"`
+
+	// Namespace of the project.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataScienceProject is the Schema for the datascienceprojects API
+// +k8s:openapi-gen=true
+type DataScienceProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DataScienceProjectSpec   `json:"spec,omitempty"`
+	Status DataScienceProjectStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DataScienceProjectList contains a list of DataScienceProject
+type DataScienceProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DataScienceProject `json:"items"`
+}
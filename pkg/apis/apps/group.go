@@ -16,6 +16,7 @@
 package apps
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -120,6 +121,13 @@ type KfApp interface {
 	Init(resources ResourceEnum) error
 }
 
+// KfAppContext is implemented by the KfApps that are applied and deleted with a context, e.g. the one of a
+// reconciliation of the operator, cancelling their downloads and their requests to the API server once it is done
+type KfAppContext interface {
+	ApplyContext(ctx context.Context, resources ResourceEnum) error
+	DeleteContext(ctx context.Context, resources ResourceEnum) error
+}
+
 //
 // Platform provides a common
 // API for platforms like gcp or minikube
@@ -23,6 +23,7 @@ import (
 	valid "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"os"
 	"strings"
 )
@@ -54,20 +55,247 @@ type KfDefSpec struct {
 	Plugins      []Plugin      `json:"plugins,omitempty"`
 	Secrets      []Secret      `json:"secrets,omitempty"`
 	Repos        []Repo        `json:"repos,omitempty"`
+	// Images overrides the container images of the applications
+	Images []Image `json:"images,omitempty"`
+	// Scheduling constrains the nodes the pods of every application are scheduled on
+	Scheduling *Scheduling `json:"scheduling,omitempty"`
+	// Availability adds PodDisruptionBudgets and topology spread constraints to the workloads of every application
+	// running several replicas
+	Availability *Availability `json:"availability,omitempty"`
+	// ServiceMesh is Managed for the operator to set up an OpenShift Service Mesh, enroll the namespace of the KfDef
+	// in it and inject the sidecar of the mesh in the pods of every application. The mesh is left alone when it is
+	// Unmanaged, the default.
+	ServiceMesh ManagementState `json:"serviceMesh,omitempty"`
+	// Auth puts an authentication layer in front of the Routes of every application, so that only the users
+	// allowed to get their Services can reach them. The Routes are left as they are when it is empty.
+	Auth AuthProvider `json:"auth,omitempty"`
+	// PodSecurity is the Pod Security Standard the workloads of every application comply with. The namespace of the
+	// KfDef and the namespaces of the manifests are labelled with the least restrictive level of the applications,
+	// and the workloads of the restricted applications are hardened to satisfy it. The namespaces and the workloads
+	// are left alone when it is empty.
+	PodSecurity PodSecurityLevel `json:"podSecurity,omitempty"`
+	// IPFamilyPolicy is set on the Services of every application that do not set theirs, replacing the IP families
+	// they list, e.g. PreferDualStack for the Services to get an IPv4 and an IPv6 address on dual-stack clusters.
+	// The Services are left as they are when it is empty.
+	IPFamilyPolicy IPFamilyPolicy `json:"ipFamilyPolicy,omitempty"`
+	// GitOps pushes the rendered manifests of the applications to a git repository instead of applying them, for a
+	// GitOps tool such as Argo CD to apply them
+	GitOps *GitOps `json:"gitOps,omitempty"`
+	// FeatureGates enables or disables the experimental features of the operator for the applications, overriding
+	// the --feature-gates flag of the operator
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// Image overrides a container image referenced by the rendered manifests, e.g. to pull it from a mirror registry
+type Image struct {
+	// Name of the image to override, without tag or digest, e.g. quay.io/opendatahub/odh-dashboard
+	Name string `json:"name,omitempty"`
+	// NewName replaces the name of the image, e.g. with the repository of a mirror registry
+	NewName string `json:"newName,omitempty"`
+	// NewTag replaces the tag of the image
+	NewTag string `json:"newTag,omitempty"`
+	// Digest pins the image to a digest, e.g. sha256:<hex>. NewTag is ignored when it is set.
+	Digest string `json:"digest,omitempty"`
 }
 
 // Application defines an application to install
 type Application struct {
 	Name            string           `json:"name,omitempty"`
 	KustomizeConfig *KustomizeConfig `json:"kustomizeConfig,omitempty"`
+	// HelmConfig deploys the application from a Helm chart instead of kustomize manifests
+	HelmConfig *HelmConfig `json:"helmConfig,omitempty"`
+	// DeletionPolicy tells whether the resources of the application are deleted along with the KfDef.
+	// Defaults to Delete.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// DependsOn are the names of the applications that must be applied before the application, e.g. istio
+	// before kserve. The other applications may be applied at the same time.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// ReadinessTimeout is how long the applications depending on the application wait for its Deployments to be
+	// available and its Jobs to complete before it is reported Degraded. Defaults to the --readiness-timeout of
+	// the operator.
+	ReadinessTimeout *metav1.Duration `json:"readinessTimeout,omitempty"`
+	// Overrides change the replicas and the resources of Deployments and StatefulSets of the rendered manifests,
+	// to right-size the application without changing its manifests.
+	Overrides []WorkloadOverride `json:"overrides,omitempty"`
+	// Scheduling constrains the nodes the pods of the application are scheduled on. The fields it sets replace
+	// the ones of the scheduling of the spec.
+	Scheduling *Scheduling `json:"scheduling,omitempty"`
+	// Availability replaces the availability of the spec for the workloads of the application
+	Availability *Availability `json:"availability,omitempty"`
+	// PodSecurity replaces the pod security of the spec for the application, e.g. privileged for an application
+	// running privileged pods
+	PodSecurity PodSecurityLevel `json:"podSecurity,omitempty"`
+	// Autoscaling generates a HorizontalPodAutoscaler for Deployments of the application, the replicas of the
+	// Deployments being left to it.
+	Autoscaling *Autoscaling `json:"autoscaling,omitempty"`
+}
+
+// Scheduling is injected into the pod templates of the rendered manifests, e.g. to pin the workloads to infra or
+// GPU nodes
+type Scheduling struct {
+	// NodeSelector is added to the node selector of the pods, its labels replace the ones the manifests set
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are added to the tolerations of the pods
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// Affinity replaces the node affinity, the pod affinity and the pod anti-affinity of the pods it sets
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+}
+
+// Availability keeps the workloads running several replicas available during voluntary disruptions, e.g. the node
+// drains of cluster upgrades
+type Availability struct {
+	// MaxUnavailable is the number or the percentage of the pods of a workload that can be evicted at the same time.
+	// Defaults to 1.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// TopologyKey is the label of the nodes the pods of a workload are spread across, e.g.
+	// topology.kubernetes.io/zone. Defaults to kubernetes.io/hostname.
+	TopologyKey string `json:"topologyKey,omitempty"`
+}
+
+// GitOps is the git repository the rendered manifests are pushed to
+type GitOps struct {
+	// URI of the repository, e.g. https://github.com/example/odh-deployment.git or
+	// git@github.com:example/odh-deployment.git
+	URI string `json:"uri"`
+	// Branch the manifests are pushed to, created if it does not exist. Defaults to main.
+	Branch string `json:"branch,omitempty"`
+	// Path of the directory of the manifests in the repository, each application being written to
+	// <path>/<application>.yaml. Defaults to <namespace>/<name> of the KfDef.
+	Path string `json:"path,omitempty"`
+	// SecretName is the Secret of the namespace of the KfDef holding the credentials of the repository, as for the
+	// repos. The commits are signed with the ASCII-armored OpenPGP private key of its signing-key entry, if any,
+	// decrypted with its signing-key-passphrase entry.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// Autoscaling scales Deployments of the rendered manifests with their load
+type Autoscaling struct {
+	// Deployments are the names of the Deployments scaled, all the Deployments of the application when empty
+	Deployments []string `json:"deployments,omitempty"`
+	// MinReplicas is the number of replicas the Deployments are scaled down to. Defaults to 1.
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the number of replicas the Deployments are scaled up to
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilization is the average CPU usage of the pods, in percent of their CPU requests, the Deployments
+	// are scaled to keep. Defaults to 80 when no other target is set.
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+	// TargetMemoryUtilization is the average memory usage of the pods, in percent of their memory requests, the
+	// Deployments are scaled to keep
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+	// Metrics are the custom metrics of the pods the Deployments are scaled on
+	Metrics []AutoscalingMetric `json:"metrics,omitempty"`
+}
+
+// AutoscalingMetric is a metric of the pods, served by the custom metrics API, e.g. requests_per_second
+type AutoscalingMetric struct {
+	// Name of the metric
+	Name string `json:"name"`
+	// TargetAverageValue is the quantity the average of the metric over the pods is kept at, e.g. 100 or 500m
+	TargetAverageValue string `json:"targetAverageValue"`
+}
+
+// WorkloadOverride is patched into a Deployment or a StatefulSet of the manifests of an application
+type WorkloadOverride struct {
+	// Kind of the workload, Deployment or StatefulSet. Defaults to Deployment.
+	Kind string `json:"kind,omitempty"`
+	// Name of the workload
+	Name string `json:"name"`
+	// Replicas replaces the number of replicas of the workload
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Containers override the resources of the containers of the workload
+	Containers []ContainerOverride `json:"containers,omitempty"`
+}
+
+// ContainerOverride overrides the requests and limits of a container of a workload. The requests and limits it
+// does not set are left as they are in the manifests.
+type ContainerOverride struct {
+	// Name of the container
+	Name string `json:"name"`
+	// Resources are the requests and limits of the container
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 }
 
+// ManagementState tells whether the operator manages an integration
+type ManagementState string
+
+const (
+	// Managed integrations are set up by the operator.
+	Managed ManagementState = "Managed"
+	// Unmanaged integrations are left alone by the operator.
+	Unmanaged ManagementState = "Unmanaged"
+)
+
+// PodSecurityLevel is a level of the Pod Security Standards
+type PodSecurityLevel string
+
+const (
+	// PodSecurityPrivileged allows the pods to escalate their privileges.
+	PodSecurityPrivileged PodSecurityLevel = "privileged"
+	// PodSecurityBaseline prevents the known privilege escalations.
+	PodSecurityBaseline PodSecurityLevel = "baseline"
+	// PodSecurityRestricted enforces the pod hardening best practices.
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// IPFamilyPolicy is the IP family policy of the Services of the applications
+type IPFamilyPolicy string
+
+const (
+	// IPFamilyPolicySingleStack gives the Services an address of the primary IP family of the cluster.
+	IPFamilyPolicySingleStack IPFamilyPolicy = "SingleStack"
+	// IPFamilyPolicyPreferDualStack gives the Services an address of both IP families on dual-stack clusters, and of
+	// their only IP family on the other clusters.
+	IPFamilyPolicyPreferDualStack IPFamilyPolicy = "PreferDualStack"
+	// IPFamilyPolicyRequireDualStack gives the Services an address of both IP families, failing on the single-stack
+	// clusters.
+	IPFamilyPolicyRequireDualStack IPFamilyPolicy = "RequireDualStack"
+)
+
+// AuthProvider is the authentication layer put in front of the Routes of the applications
+type AuthProvider string
+
+const (
+	// OAuthProxy adds an OpenShift oauth-proxy sidecar to the pods behind the Routes.
+	OAuthProxy AuthProvider = "OAuthProxy"
+	// Authorino authorizes the requests with Authorino AuthConfigs. It requires a Managed ServiceMesh.
+	Authorino AuthProvider = "Authorino"
+)
+
+// DeletionPolicy is what happens to the resources of an application when its KfDef is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the resources of the application.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the resources of the application, e.g. to preserve the data of its volumes.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
 type KustomizeConfig struct {
 	RepoRef    *RepoRef    `json:"repoRef,omitempty"`
 	Overlays   []string    `json:"overlays,omitempty"`
 	Parameters []NameValue `json:"parameters,omitempty"`
 }
 
+// HelmConfig is the Helm chart of an application and the values it is rendered with. The chart is either
+// in a directory of a repo of the spec, or fetched from a chart repository or an OCI registry.
+type HelmConfig struct {
+	// RepoRef is the directory of the chart in a repo of the spec
+	RepoRef *RepoRef `json:"repoRef,omitempty"`
+	// Repo is the URL of a chart repository, e.g. https://charts.example.com, or of an OCI registry,
+	// e.g. oci://quay.io/opendatahub/charts
+	Repo string `json:"repo,omitempty"`
+	// Chart is the name of the chart in Repo
+	Chart string `json:"chart,omitempty"`
+	// Version is the version of the chart in Repo, or a constraint such as ">= 1.0, < 2.0".
+	// Defaults to the latest version.
+	Version string `json:"version,omitempty"`
+	// ReleaseName is the name of the release the chart is rendered for. Defaults to the name of the application.
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Values override the default values of the chart
+	Values *runtime.RawExtension `json:"values,omitempty"`
+}
+
 type RepoRef struct {
 	Name string `json:"name,omitempty"`
 	Path string `json:"path,omitempty"`
@@ -121,13 +349,46 @@ type Repo struct {
 	// Can use any URI understood by go-getter:
 	// https://github.com/hashicorp/go-getter/blob/master/README.md#installation-and-usage
 	URI string `json:"uri,omitempty"`
+	// Ref is the branch, tag or commit to fetch of a git repo URI. It overrides the ref query parameter of the URI.
+	// For an oci:// URI, it is the tag or the digest of the artifact.
+	Ref string `json:"ref,omitempty"`
+	// SecretName is the Secret, in the namespace of the KfDef, holding the credentials of a private git repo:
+	// username and password for https URIs, or ssh-privatekey and optionally known_hosts for ssh URIs.
+	// For an oci:// URI, it is a pull Secret of type kubernetes.io/dockerconfigjson.
+	SecretName string `json:"secretName,omitempty"`
+	// Verify requires the cosign signature of the tarball or of the OCI artifact of the repo to be verified
+	// before its manifests are applied.
+	Verify *Verification `json:"verify,omitempty"`
+}
+
+// Verification is the key, or the identity of the keyless signatures, the manifests of a repo are signed with
+type Verification struct {
+	// PublicKey is the PEM public key of the key pair the manifests are signed with, as generated by
+	// cosign generate-key-pair
+	PublicKey string `json:"publicKey,omitempty"`
+	// Identity is the email or the URI the certificate of the keyless signatures is issued to
+	Identity string `json:"identity,omitempty"`
+	// Issuer is the OIDC issuer of the identity of the keyless signatures, e.g. https://token.actions.githubusercontent.com
+	Issuer string `json:"issuer,omitempty"`
 }
 
 // KfDefStatus defines the observed state of KfDef
 type KfDefStatus struct {
 	Conditions []KfDefCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// ApplicationConditions holds one condition per application describing whether it is
+	// Available, Progressing or Degraded.
+	ApplicationConditions []ApplicationCondition `json:"applicationConditions,omitempty" patchStrategy:"merge" patchMergeKey:"application"`
 	// ReposCache is used to cache information about local caching of the URIs.
 	ReposCache []RepoCache `json:"reposCache,omitempty"`
+	// OperatorVersion is the version of the operator the applications were last applied with.
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// ObservedGeneration is the generation of the spec the applications were last applied from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// HookFailures holds the hook Jobs of the applications that failed all their attempts.
+	HookFailures []HookFailure `json:"hookFailures,omitempty"`
+	// SecurityContextConstraints holds the SecurityContextConstraints granted to the ServiceAccounts of the
+	// applications, on OpenShift.
+	SecurityContextConstraints []SCCGrant `json:"securityContextConstraints,omitempty"`
 }
 
 type RepoCache struct {
@@ -146,6 +407,32 @@ const (
 
 	// Pending means Kubeflow services is being updated.
 	Pending KfDefConditionType = "Pending"
+
+	// KfProgressing means the application is being deployed.
+	KfProgressing KfDefConditionType = "Progressing"
+
+	// KfConflict means resources of the KfDef, or some of their fields, are managed by another KfDef or field manager.
+	KfConflict KfDefConditionType = "Conflict"
+
+	// KfDryRun means the manifests of the KfDef were validated but not applied.
+	KfDryRun KfDefConditionType = "DryRun"
+
+	// KfUpgradeable means the pre-flight checks of an operator upgrade passed and the applications can be upgraded.
+	KfUpgradeable KfDefConditionType = "Upgradeable"
+
+	// KfSignatureUnverified means the signature of the manifests of a repo couldn't be verified, so nothing was applied.
+	KfSignatureUnverified KfDefConditionType = "SignatureUnverified"
+
+	// KfCRDUpgradeRefused means the manifests were not applied as they upgrade CRDs in a way that would lose the
+	// data of their custom resources.
+	KfCRDUpgradeRefused KfDefConditionType = "CRDUpgradeRefused"
+
+	// KfPaused means the reconciliation of the KfDef is paused, its applications are neither applied nor restored.
+	KfPaused KfDefConditionType = "Paused"
+
+	// KfFIPSMode means the applications are deployed in FIPS mode: with their FIPS-compliant variants, the ones
+	// known to be incompatible being refused.
+	KfFIPSMode KfDefConditionType = "FIPSMode"
 )
 
 type KfDefCondition struct {
@@ -163,6 +450,56 @@ type KfDefCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
+// ApplicationCondition describes the state of a single application of the KfDef.
+type ApplicationCondition struct {
+	// Name of the application.
+	Application string `json:"application"`
+	// Type of the condition, one of Available, Progressing or Degraded.
+	Type KfDefConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// The last time this condition was updated.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+	// Digest of the manifests the application was last applied from.
+	ManifestsDigest string `json:"manifestsDigest,omitempty"`
+}
+
+// SCCGrant describes a SecurityContextConstraints granted to a ServiceAccount of an application.
+type SCCGrant struct {
+	// Name of the application.
+	Application string `json:"application"`
+	// <namespace>/<name> of the ServiceAccount.
+	ServiceAccount string `json:"serviceAccount"`
+	// Profile of the SecurityContextConstraints, anyuid or nonroot.
+	Profile string `json:"profile"`
+	// Name of the SecurityContextConstraints.
+	Name string `json:"name"`
+}
+
+// HookFailure describes a hook Job of an application that failed all its attempts.
+type HookFailure struct {
+	// Name of the application.
+	Application string `json:"application"`
+	// <namespace>/<name> of the Job.
+	Job string `json:"job"`
+	// When the Job runs, pre-apply or post-apply.
+	Hook string `json:"hook"`
+	// Number of times the Job was run.
+	Attempts int `json:"attempts"`
+	// The last time the Job failed.
+	LastFailureTime metav1.Time `json:"lastFailureTime,omitempty"`
+	// A human readable message indicating why the last attempt failed.
+	Message string `json:"message,omitempty"`
+	// The last lines of the logs of the pod of the last attempt.
+	Logs string `json:"logs,omitempty"`
+}
+
 // GetPluginSpec will try to unmarshal the spec for the specified plugin to the supplied
 // interface. Returns an error if the plugin isn't defined or if there is a problem
 // unmarshaling it.
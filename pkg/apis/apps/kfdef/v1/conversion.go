@@ -0,0 +1,4 @@
+package v1
+
+// Hub marks v1 as the version the other versions of KfDef are converted to and from
+func (*KfDef) Hub() {}
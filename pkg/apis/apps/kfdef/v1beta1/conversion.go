@@ -0,0 +1,161 @@
+package v1beta1
+
+import (
+	"encoding/json"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// V1SpecAnnotation holds the fields of the v1 spec that v1beta1 lacks, so that a KfDef read and written
+// back with v1beta1 keeps them.
+const V1SpecAnnotation = "kfdef.apps.kubeflow.org/v1-spec"
+
+// v1Spec are the fields of the v1 spec that v1beta1 lacks
+type v1Spec struct {
+	Images           []kfdefv1.Image                   `json:"images,omitempty"`
+	Scheduling       *kfdefv1.Scheduling               `json:"scheduling,omitempty"`
+	Availability     *kfdefv1.Availability             `json:"availability,omitempty"`
+	ServiceMesh      kfdefv1.ManagementState           `json:"serviceMesh,omitempty"`
+	Auth             kfdefv1.AuthProvider              `json:"auth,omitempty"`
+	GitOps           *kfdefv1.GitOps                   `json:"gitOps,omitempty"`
+	DeletionPolicies map[string]kfdefv1.DeletionPolicy `json:"deletionPolicies,omitempty"`
+	// Repos holds the git ref, the credentials and the signature verification of the repos, by name
+	Repos map[string]kfdefv1.Repo `json:"repos,omitempty"`
+	// HelmConfigs holds the charts of the applications deployed with Helm, by name
+	HelmConfigs map[string]*kfdefv1.HelmConfig `json:"helmConfigs,omitempty"`
+	// DependsOn holds the applications each application depends on, by name
+	DependsOn map[string][]string `json:"dependsOn,omitempty"`
+	// ReadinessTimeouts holds the readiness timeout of each application, by name
+	ReadinessTimeouts map[string]*metav1.Duration `json:"readinessTimeouts,omitempty"`
+	// Overrides holds the workload overrides of each application, by name
+	Overrides map[string][]kfdefv1.WorkloadOverride `json:"overrides,omitempty"`
+	// ApplicationScheduling holds the scheduling of each application, by name
+	ApplicationScheduling map[string]*kfdefv1.Scheduling `json:"applicationScheduling,omitempty"`
+	// ApplicationAvailability holds the availability of each application, by name
+	ApplicationAvailability map[string]*kfdefv1.Availability `json:"applicationAvailability,omitempty"`
+}
+
+// ConvertTo converts the v1beta1 KfDef to v1. The fields are the same, except for the ones v1 adds
+// which are restored from the V1SpecAnnotation.
+func (src *KfDef) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*kfdefv1.KfDef)
+	if err := convert(src, dst); err != nil {
+		return err
+	}
+	dst.APIVersion = kfdefv1.SchemeGroupVersion.String()
+
+	data, ok := dst.Annotations[V1SpecAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(dst.Annotations, V1SpecAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+	spec := v1Spec{}
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return err
+	}
+	dst.Spec.Images = spec.Images
+	dst.Spec.Scheduling = spec.Scheduling
+	dst.Spec.Availability = spec.Availability
+	dst.Spec.ServiceMesh = spec.ServiceMesh
+	dst.Spec.Auth = spec.Auth
+	dst.Spec.GitOps = spec.GitOps
+	for i, app := range dst.Spec.Applications {
+		dst.Spec.Applications[i].DeletionPolicy = spec.DeletionPolicies[app.Name]
+		dst.Spec.Applications[i].HelmConfig = spec.HelmConfigs[app.Name]
+		dst.Spec.Applications[i].DependsOn = spec.DependsOn[app.Name]
+		dst.Spec.Applications[i].ReadinessTimeout = spec.ReadinessTimeouts[app.Name]
+		dst.Spec.Applications[i].Overrides = spec.Overrides[app.Name]
+		dst.Spec.Applications[i].Scheduling = spec.ApplicationScheduling[app.Name]
+		dst.Spec.Applications[i].Availability = spec.ApplicationAvailability[app.Name]
+	}
+	for i, repo := range dst.Spec.Repos {
+		dst.Spec.Repos[i].Ref = spec.Repos[repo.Name].Ref
+		dst.Spec.Repos[i].SecretName = spec.Repos[repo.Name].SecretName
+		dst.Spec.Repos[i].Verify = spec.Repos[repo.Name].Verify
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1 KfDef to v1beta1. The fields v1 adds to the spec are kept in the V1SpecAnnotation.
+// The application conditions of the status are dropped, they are reported again by the operator.
+func (dst *KfDef) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*kfdefv1.KfDef)
+	if err := convert(src, dst); err != nil {
+		return err
+	}
+	dst.APIVersion = SchemeGroupVersion.String()
+
+	spec := v1Spec{
+		Images:                  src.Spec.Images,
+		DeletionPolicies:        map[string]kfdefv1.DeletionPolicy{},
+		Repos:                   map[string]kfdefv1.Repo{},
+		HelmConfigs:             map[string]*kfdefv1.HelmConfig{},
+		DependsOn:               map[string][]string{},
+		ReadinessTimeouts:       map[string]*metav1.Duration{},
+		Overrides:               map[string][]kfdefv1.WorkloadOverride{},
+		Scheduling:              src.Spec.Scheduling,
+		Availability:            src.Spec.Availability,
+		ServiceMesh:             src.Spec.ServiceMesh,
+		Auth:                    src.Spec.Auth,
+		GitOps:                  src.Spec.GitOps,
+		ApplicationScheduling:   map[string]*kfdefv1.Scheduling{},
+		ApplicationAvailability: map[string]*kfdefv1.Availability{},
+	}
+	for _, app := range src.Spec.Applications {
+		if app.DeletionPolicy != "" {
+			spec.DeletionPolicies[app.Name] = app.DeletionPolicy
+		}
+		if app.HelmConfig != nil {
+			spec.HelmConfigs[app.Name] = app.HelmConfig
+		}
+		if len(app.DependsOn) > 0 {
+			spec.DependsOn[app.Name] = app.DependsOn
+		}
+		if app.ReadinessTimeout != nil {
+			spec.ReadinessTimeouts[app.Name] = app.ReadinessTimeout
+		}
+		if len(app.Overrides) > 0 {
+			spec.Overrides[app.Name] = app.Overrides
+		}
+		if app.Scheduling != nil {
+			spec.ApplicationScheduling[app.Name] = app.Scheduling
+		}
+		if app.Availability != nil {
+			spec.ApplicationAvailability[app.Name] = app.Availability
+		}
+	}
+	for _, repo := range src.Spec.Repos {
+		if repo.Ref != "" || repo.SecretName != "" || repo.Verify != nil {
+			spec.Repos[repo.Name] = kfdefv1.Repo{Ref: repo.Ref, SecretName: repo.SecretName, Verify: repo.Verify}
+		}
+	}
+	if len(spec.Images) == 0 && len(spec.DeletionPolicies) == 0 && len(spec.Repos) == 0 && len(spec.HelmConfigs) == 0 &&
+		len(spec.DependsOn) == 0 && len(spec.ReadinessTimeouts) == 0 && len(spec.Overrides) == 0 && spec.Scheduling == nil && len(spec.ApplicationScheduling) == 0 &&
+		spec.Availability == nil && len(spec.ApplicationAvailability) == 0 && spec.ServiceMesh == "" && spec.Auth == "" &&
+		spec.GitOps == nil {
+		return nil
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[V1SpecAnnotation] = string(data)
+	return nil
+}
+
+// convert copies the fields of src to the fields of dst with the same JSON name
+func convert(src interface{}, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
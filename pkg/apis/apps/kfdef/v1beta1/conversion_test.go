@@ -0,0 +1,88 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestConversion(t *testing.T) {
+	replicas := int32(1)
+	maxUnavailable := intstr.FromString("50%")
+	v1 := &kfdefv1.KfDef{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "kfdef.apps.kubeflow.org/v1", Kind: "KfDef"},
+		ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "opendatahub"},
+		Spec: kfdefv1.KfDefSpec{
+			Applications: []kfdefv1.Application{
+				{
+					Name: "odh-common",
+					KustomizeConfig: &kfdefv1.KustomizeConfig{
+						RepoRef: &kfdefv1.RepoRef{Name: "manifests", Path: "odh-common"},
+					},
+					DeletionPolicy: kfdefv1.DeletionPolicyRetain,
+				},
+				{
+					Name:      "odh-dashboard",
+					DependsOn: []string{"odh-common"},
+					Overrides: []kfdefv1.WorkloadOverride{{
+						Name:     "odh-dashboard",
+						Replicas: &replicas,
+						Containers: []kfdefv1.ContainerOverride{{
+							Name: "odh-dashboard",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+							},
+						}},
+					}},
+					Scheduling:   &kfdefv1.Scheduling{NodeSelector: map[string]string{"nvidia.com/gpu.present": "true"}},
+					Availability: &kfdefv1.Availability{TopologyKey: "topology.kubernetes.io/zone"},
+				},
+				{
+					Name: "odh-model-serving",
+					HelmConfig: &kfdefv1.HelmConfig{
+						Repo:    "oci://quay.io/opendatahub/charts",
+						Chart:   "odh-model-serving",
+						Version: "1.0.0",
+						Values:  &runtime.RawExtension{Raw: []byte(`{"replicas":2}`)},
+					},
+				},
+			},
+			Repos: []kfdefv1.Repo{{Name: "manifests", URI: "git::https://github.com/opendatahub-io/odh-manifests", Ref: "v1.1.0", SecretName: "odh-manifests-credentials",
+				Verify: &kfdefv1.Verification{Identity: "release@opendatahub.io", Issuer: "https://accounts.google.com"}}},
+			Images: []kfdefv1.Image{{Name: "quay.io/opendatahub/odh-dashboard", NewName: "registry.example.com/odh-dashboard"}},
+			Scheduling: &kfdefv1.Scheduling{
+				NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+				Tolerations:  []corev1.Toleration{{Key: "node-role.kubernetes.io/infra", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			Availability: &kfdefv1.Availability{MaxUnavailable: &maxUnavailable},
+			ServiceMesh:  kfdefv1.Managed,
+			Auth:         kfdefv1.Authorino,
+			GitOps:       &kfdefv1.GitOps{URI: "git@github.com:example/odh-deployment.git", SecretName: "odh-deployment"},
+		},
+	}
+
+	v1beta1 := &KfDef{}
+	if err := v1beta1.ConvertFrom(v1); err != nil {
+		t.Fatalf("Failed to convert from v1: %v", err)
+	}
+	if v1beta1.APIVersion != "kfdef.apps.kubeflow.org/v1beta1" || len(v1beta1.Spec.Applications) != 3 {
+		t.Errorf("Unexpected v1beta1 KfDef: %+v", v1beta1)
+	}
+	if _, ok := v1beta1.Annotations[V1SpecAnnotation]; !ok {
+		t.Errorf("Expected the v1 fields to be kept in annotation %v", V1SpecAnnotation)
+	}
+
+	converted := &kfdefv1.KfDef{}
+	if err := v1beta1.ConvertTo(converted); err != nil {
+		t.Fatalf("Failed to convert to v1: %v", err)
+	}
+	if diff := cmp.Diff(v1, converted); diff != "" {
+		t.Errorf("Round trip conversion is different from the original (-want, +got):\n%s", diff)
+	}
+}
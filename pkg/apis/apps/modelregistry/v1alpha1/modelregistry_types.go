@@ -0,0 +1,148 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelRegistrySpec defines the model registry of a project
+type ModelRegistrySpec struct {
+	// Image of the registry server. Defaults to the image the operator is configured with.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources of the registry server container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Database stores the registered models, their versions and their artifacts. A database is deployed with the
+	// registry unless ConnectionSecret is set.
+	// +optional
+	Database Database `json:"database,omitempty"`
+}
+
+// DatabaseType is the engine of the database of the registry.
+type DatabaseType string
+
+const (
+	// MySQL is a MySQL or MariaDB database. It is the default type.
+	MySQL DatabaseType = "MySQL"
+
+	// PostgreSQL is a PostgreSQL database.
+	PostgreSQL DatabaseType = "PostgreSQL"
+)
+
+// Database holds the settings of the database of the registry.
+type Database struct {
+	// Type of the database, MySQL or PostgreSQL. Defaults to MySQL.
+	// +optional
+	Type DatabaseType `json:"type,omitempty"`
+
+	// ConnectionSecret is the name of a Secret of the namespace of the ModelRegistry holding the host, port,
+	// username, password and database keys of an existing database. The port defaults to the one of the type.
+	// +optional
+	ConnectionSecret string `json:"connectionSecret,omitempty"`
+
+	// ExternalSecret creates the connection Secret from an external secret store instead of reading an existing
+	// one. It requires ConnectionSecret.
+	// +optional
+	ExternalSecret *ExternalSecret `json:"externalSecret,omitempty"`
+
+	// Image of the database deployed with the registry. Defaults to the image of the type the operator is
+	// configured with.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// StorageSize is the size of the PersistentVolumeClaim of the database deployed with the registry. Defaults to
+	// 10Gi.
+	// +optional
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+}
+
+// ExternalSecret is a secret of an external secret store, e.g. a path of a Vault, read through the External Secrets
+// Operator.
+type ExternalSecret struct {
+	// StoreName is the name of the SecretStore or the ClusterSecretStore of the external secret store.
+	StoreName string `json:"storeName"`
+
+	// StoreKind is the kind of the store, SecretStore or ClusterSecretStore. Defaults to SecretStore.
+	// +optional
+	StoreKind string `json:"storeKind,omitempty"`
+
+	// Key of the secret in the store, e.g. its path in a Vault. Every property of the secret is a key of the Secret.
+	Key string `json:"key"`
+
+	// RefreshInterval is the interval the Secret is read from the store again at. Defaults to 1h.
+	// +optional
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
+// ModelRegistryPhase summarizes the state of the registry.
+type ModelRegistryPhase string
+
+const (
+	// ModelRegistryReady means the schema of the database is migrated and the registry server is available.
+	ModelRegistryReady ModelRegistryPhase = "Ready"
+
+	// ModelRegistryProgressing means the database, the migration of its schema or the registry server are being
+	// deployed.
+	ModelRegistryProgressing ModelRegistryPhase = "Progressing"
+
+	// ModelRegistryFailed means the spec is invalid, the schema could not be migrated or the registry could not be
+	// deployed.
+	ModelRegistryFailed ModelRegistryPhase = "Failed"
+)
+
+// ModelRegistryStatus defines the observed state of ModelRegistry
+type ModelRegistryStatus struct {
+	// ObservedGeneration is the generation of the spec the registry was deployed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the registry.
+	Phase ModelRegistryPhase `json:"phase,omitempty"`
+
+	// A human readable message indicating details about the phase.
+	Message string `json:"message,omitempty"`
+
+	// URL is the URL of the REST API of the registry inside the cluster.
+	URL string `json:"url,omitempty"`
+
+	// MigratedImage is the image of the registry the schema of the database was last migrated with.
+	MigratedImage string `json:"migratedImage,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ModelRegistry is the Schema for the modelregistries API
+// +k8s:openapi-gen=true
+type ModelRegistry struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ModelRegistrySpec   `json:"spec,omitempty"`
+	Status ModelRegistryStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ModelRegistryList contains a list of ModelRegistry
+type ModelRegistryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelRegistry `json:"items"`
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OdhDashboardConfigSpec defines the settings of the Open Data Hub dashboard
+type OdhDashboardConfigSpec struct {
+	// EnabledTiles are the names of the application tiles shown by the dashboard. All the tiles are shown when
+	// it is empty.
+	// +optional
+	EnabledTiles []string `json:"enabledTiles,omitempty"`
+
+	// NotebookSizes are the sizes offered to the users starting a notebook. The Small, Medium and Large sizes are
+	// offered when it is empty.
+	// +optional
+	NotebookSizes []NotebookSize `json:"notebookSizes,omitempty"`
+
+	// NotebookIdleTimeout is how long a notebook is idle before the dashboard stops it. The notebooks are never
+	// stopped when it is not set.
+	// +optional
+	NotebookIdleTimeout *metav1.Duration `json:"notebookIdleTimeout,omitempty"`
+}
+
+// NotebookSize is a size offered to the users starting a notebook.
+type NotebookSize struct {
+	// Name of the size shown to the users.
+	Name string `json:"name"`
+	// Resources of the notebook container.
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// OdhDashboardConfigStatus defines the observed state of OdhDashboardConfig
+type OdhDashboardConfigStatus struct {
+	// ObservedGeneration is the generation of the spec rendered in the ConfigMap.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ConfigMap is the name of the ConfigMap the settings are rendered in.
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OdhDashboardConfig is the Schema for the odhdashboardconfigs API
+// +k8s:openapi-gen=true
+type OdhDashboardConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OdhDashboardConfigSpec   `json:"spec,omitempty"`
+	Status OdhDashboardConfigStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OdhDashboardConfigList contains a list of OdhDashboardConfig
+type OdhDashboardConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OdhDashboardConfig `json:"items"`
+}
@@ -0,0 +1,198 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SingletonName is the name of the only OperatorConfig the operator is configured with, the others are ignored.
+const SingletonName = "default"
+
+// OperatorConfigSpec defines the cluster-wide settings of the operator. The settings that are not set default to
+// the flags of the operator.
+type OperatorConfigSpec struct {
+	// ApplicationsNamespace is the namespace of the Open Data Hub applications. The operator creates it, and the
+	// NetworkPolicies of the data science projects allow the connections from it.
+	// +optional
+	ApplicationsNamespace string `json:"applicationsNamespace,omitempty"`
+
+	// PodSecurity is the Pod Security Standard enforced in the applications namespace, privileged, baseline or
+	// restricted. Defaults to baseline.
+	// +optional
+	PodSecurity PodSecurityLevel `json:"podSecurity,omitempty"`
+
+	// ServiceMesh is Managed for the applications namespace to be enrolled into the service mesh the operator is
+	// configured with and labelled for sidecar injection, Removed for it to be removed from the mesh, Unmanaged, the
+	// default, for its membership to be left alone.
+	// +optional
+	ServiceMesh ManagementState `json:"serviceMesh,omitempty"`
+
+	// Monitoring of the applications and of the data science projects by the cluster monitoring stack.
+	// +optional
+	Monitoring Monitoring `json:"monitoring,omitempty"`
+
+	// TrustedCABundle mounted in the Deployments of every KfDef.
+	// +optional
+	TrustedCABundle TrustedCABundle `json:"trustedCABundle,omitempty"`
+
+	// NetworkPolicy is Managed when the operator creates and updates the NetworkPolicies of the data science
+	// projects, Unmanaged when they are left to the administrators.
+	// +optional
+	NetworkPolicy NetworkPolicyMode `json:"networkPolicy,omitempty"`
+
+	// PriorityClasses of the pods of the control plane and of the user workloads, so that the workloads are
+	// preempted and evicted before the control plane under cluster pressure.
+	// +optional
+	PriorityClasses PriorityClasses `json:"priorityClasses,omitempty"`
+}
+
+// ManagementState tells whether the operator manages a setting
+type ManagementState string
+
+const (
+	// Managed settings are reconciled by the operator.
+	Managed ManagementState = "Managed"
+
+	// Removed settings are removed by the operator.
+	Removed ManagementState = "Removed"
+
+	// Unmanaged settings are left alone by the operator.
+	Unmanaged ManagementState = "Unmanaged"
+)
+
+// PodSecurityLevel is a level of the Pod Security Standards
+type PodSecurityLevel string
+
+const (
+	// PodSecurityPrivileged allows the pods to escalate their privileges.
+	PodSecurityPrivileged PodSecurityLevel = "privileged"
+
+	// PodSecurityBaseline prevents the known privilege escalations.
+	PodSecurityBaseline PodSecurityLevel = "baseline"
+
+	// PodSecurityRestricted enforces the pod hardening best practices.
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// Monitoring defines how the cluster monitoring stack reaches the applications and the data science projects.
+type Monitoring struct {
+	// ManagementState is Managed when the applications namespace is labelled for the cluster monitoring stack
+	// to scrape it, and the NetworkPolicies of the projects allow the monitoring stack. Defaults to Managed.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// Namespace of a monitoring stack, besides the OpenShift one, allowed to scrape the data science projects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TrustedCABundle defines the CA bundle trusted by the applications.
+type TrustedCABundle struct {
+	// ConfigMap is the <namespace>/<name> ConfigMap holding the CA bundle. The OpenShift cluster-wide trusted CA
+	// bundle is mounted when it is empty. The ConfigMap a KfDef is annotated with takes precedence.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// NetworkPolicyMode tells whether the operator manages the NetworkPolicies of the data science projects.
+type NetworkPolicyMode string
+
+const (
+	// NetworkPolicyManaged makes the operator create and update the NetworkPolicies of the projects.
+	NetworkPolicyManaged NetworkPolicyMode = "Managed"
+
+	// NetworkPolicyUnmanaged leaves the NetworkPolicies of the projects to the administrators.
+	NetworkPolicyUnmanaged NetworkPolicyMode = "Unmanaged"
+)
+
+// PriorityClasses defines the PriorityClasses assigned to the pods of the control plane and of the user workloads.
+type PriorityClasses struct {
+	// ControlPlane is the PriorityClass of the pods of the KfDef applications and of the servers the operator
+	// deploys, such as the pipelines API servers, the model registries and their databases.
+	// +optional
+	ControlPlane PriorityClass `json:"controlPlane,omitempty"`
+
+	// Workloads is the PriorityClass of the pods of the notebooks created in the data science projects.
+	// +optional
+	Workloads PriorityClass `json:"workloads,omitempty"`
+}
+
+// PriorityClass defines a PriorityClass assigned to pods.
+type PriorityClass struct {
+	// Name of the PriorityClass. No PriorityClass is assigned when it is empty, and the pods that name one are
+	// left alone.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// ManagementState is Managed when the operator creates the PriorityClass with Value, Unmanaged, the default,
+	// when it is created by the administrators.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// Value of the PriorityClass created by the operator, at most 1000000000.
+	// +optional
+	Value int32 `json:"value,omitempty"`
+}
+
+// OperatorConfigPhase summarizes whether the settings are applied.
+type OperatorConfigPhase string
+
+const (
+	// OperatorConfigReady means the operator runs with the settings.
+	OperatorConfigReady OperatorConfigPhase = "Ready"
+
+	// OperatorConfigFailed means the settings are invalid or could not be applied.
+	OperatorConfigFailed OperatorConfigPhase = "Failed"
+
+	// OperatorConfigIgnored means the OperatorConfig is not the singleton the operator is configured with.
+	OperatorConfigIgnored OperatorConfigPhase = "Ignored"
+)
+
+// OperatorConfigStatus defines the observed state of OperatorConfig
+type OperatorConfigStatus struct {
+	// ObservedGeneration is the generation of the spec the operator runs with.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the settings.
+	Phase OperatorConfigPhase `json:"phase,omitempty"`
+
+	// A human readable message indicating details about the phase.
+	Message string `json:"message,omitempty"`
+
+	// ApplicationsNamespace is the namespace of the applications the operator runs with.
+	ApplicationsNamespace string `json:"applicationsNamespace,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OperatorConfig is the Schema for the operatorconfigs API
+// +k8s:openapi-gen=true
+type OperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorConfigSpec   `json:"spec,omitempty"`
+	Status OperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OperatorConfigList contains a list of OperatorConfig
+type OperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorConfig `json:"items"`
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The Kubeflow Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PipelineServerSpec defines the pipelines stack of a project
+type PipelineServerSpec struct {
+	// APIServer is the pipelines API server.
+	// +optional
+	APIServer APIServer `json:"apiServer,omitempty"`
+
+	// Database stores the pipelines, their runs and their metadata. A MariaDB database is deployed with the API
+	// server unless ExternalDB is set.
+	// +optional
+	Database Database `json:"database,omitempty"`
+
+	// ObjectStorage is the S3 compatible bucket the artifacts of the runs are stored in.
+	ObjectStorage ObjectStorage `json:"objectStorage"`
+}
+
+// APIServer holds the settings of the pipelines API server.
+type APIServer struct {
+	// Image of the API server. Defaults to the image the operator is configured with.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources of the API server container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Database holds the settings of the database of the pipelines.
+type Database struct {
+	// MariaDB configures the MariaDB database deployed with the API server.
+	// +optional
+	MariaDB MariaDB `json:"mariaDB,omitempty"`
+
+	// ExternalDB connects the API server to an existing MySQL or MariaDB database instead of deploying one.
+	// +optional
+	ExternalDB *ExternalDB `json:"externalDB,omitempty"`
+}
+
+// MariaDB holds the settings of the MariaDB database deployed with the API server.
+type MariaDB struct {
+	// Image of the database. Defaults to the image the operator is configured with.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// StorageSize is the size of the PersistentVolumeClaim of the database. Defaults to 10Gi.
+	// +optional
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+}
+
+// ExternalDB is an existing MySQL or MariaDB database.
+type ExternalDB struct {
+	// Host of the database.
+	Host string `json:"host"`
+
+	// Port of the database. Defaults to 3306.
+	// +optional
+	Port string `json:"port,omitempty"`
+
+	// Username the API server connects with. Defaults to mlpipeline.
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// DBName is the name of the database. Defaults to mlpipeline.
+	// +optional
+	DBName string `json:"dbName,omitempty"`
+
+	// PasswordSecret is the key of a Secret of the namespace of the PipelineServer holding the password.
+	PasswordSecret SecretKey `json:"passwordSecret"`
+}
+
+// ObjectStorage is an S3 compatible bucket.
+type ObjectStorage struct {
+	// Host of the S3 endpoint, e.g. s3.amazonaws.com or minio.example.com:9000.
+	Host string `json:"host"`
+
+	// Scheme of the S3 endpoint, http or https. Defaults to https.
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+
+	// Bucket the artifacts are stored in.
+	Bucket string `json:"bucket"`
+
+	// CredentialsSecret is the Secret of the namespace of the PipelineServer holding the access keys of the bucket.
+	CredentialsSecret S3CredentialsSecret `json:"credentialsSecret"`
+}
+
+// SecretKey selects a key of a Secret.
+type SecretKey struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+	// Key of the Secret.
+	Key string `json:"key"`
+	// ExternalSecret creates the Secret from an external secret store instead of reading an existing one.
+	// +optional
+	ExternalSecret *ExternalSecret `json:"externalSecret,omitempty"`
+}
+
+// S3CredentialsSecret is a Secret holding the access keys of a bucket.
+type S3CredentialsSecret struct {
+	// SecretName is the name of the Secret.
+	SecretName string `json:"secretName"`
+
+	// AccessKey is the key of the access key ID. Defaults to AWS_ACCESS_KEY_ID.
+	// +optional
+	AccessKey string `json:"accessKey,omitempty"`
+
+	// SecretKey is the key of the secret access key. Defaults to AWS_SECRET_ACCESS_KEY.
+	// +optional
+	SecretKey string `json:"secretKey,omitempty"`
+
+	// ExternalSecret creates the Secret from an external secret store instead of reading an existing one.
+	// +optional
+	ExternalSecret *ExternalSecret `json:"externalSecret,omitempty"`
+}
+
+// ExternalSecret is a secret of an external secret store, e.g. a path of a Vault, read through the External Secrets
+// Operator.
+type ExternalSecret struct {
+	// StoreName is the name of the SecretStore or the ClusterSecretStore of the external secret store.
+	StoreName string `json:"storeName"`
+
+	// StoreKind is the kind of the store, SecretStore or ClusterSecretStore. Defaults to SecretStore.
+	// +optional
+	StoreKind string `json:"storeKind,omitempty"`
+
+	// Key of the secret in the store, e.g. its path in a Vault. Every property of the secret is a key of the Secret.
+	Key string `json:"key"`
+
+	// RefreshInterval is the interval the Secret is read from the store again at. Defaults to 1h.
+	// +optional
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
+// PipelineServerPhase summarizes the state of the pipelines stack.
+type PipelineServerPhase string
+
+const (
+	// PipelineServerReady means the API server and the database are available.
+	PipelineServerReady PipelineServerPhase = "Ready"
+
+	// PipelineServerProgressing means the stack is being deployed.
+	PipelineServerProgressing PipelineServerPhase = "Progressing"
+
+	// PipelineServerFailed means the spec is invalid or the stack could not be deployed.
+	PipelineServerFailed PipelineServerPhase = "Failed"
+)
+
+// PipelineServerStatus defines the observed state of PipelineServer
+type PipelineServerStatus struct {
+	// ObservedGeneration is the generation of the spec the stack was deployed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the stack.
+	Phase PipelineServerPhase `json:"phase,omitempty"`
+
+	// A human readable message indicating details about the phase.
+	Message string `json:"message,omitempty"`
+
+	// APIServerURL is the URL of the API server inside the cluster.
+	APIServerURL string `json:"apiServerURL,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PipelineServer is the Schema for the pipelineservers API
+// +k8s:openapi-gen=true
+type PipelineServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PipelineServerSpec   `json:"spec,omitempty"`
+	Status PipelineServerStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PipelineServerList contains a list of PipelineServer
+type PipelineServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PipelineServer `json:"items"`
+}
@@ -24,7 +24,10 @@ type StatusCode int
 const (
 	OK               StatusCode = 200
 	INVALID_ARGUMENT StatusCode = 400
+	UNVERIFIED       StatusCode = 403
 	NOT_FOUND        StatusCode = 404
+	CONFLICT         StatusCode = 409
+	UNSAFE_UPGRADE   StatusCode = 412
 	INTERNAL_ERROR   StatusCode = 500
 	UNKNOWN          StatusCode = 520
 )
@@ -47,6 +50,23 @@ func IsNotFound(e error) bool {
 	return ok && kfError.Code == int(NOT_FOUND)
 }
 
+func IsConflict(e error) bool {
+	kfError, ok := e.(*KfError)
+	return ok && kfError.Code == int(CONFLICT)
+}
+
+// IsUnverified returns true when the signature of manifests could not be verified
+func IsUnverified(e error) bool {
+	kfError, ok := e.(*KfError)
+	return ok && kfError.Code == int(UNVERIFIED)
+}
+
+// IsUnsafeUpgrade returns true when an upgrade of the manifests was refused as it would lose data
+func IsUnsafeUpgrade(e error) bool {
+	kfError, ok := e.(*KfError)
+	return ok && kfError.Code == int(UNSAFE_UPGRADE)
+}
+
 // NewKfErrorWithMessage will propogate the error with the given message.
 //
 // TODO(jlewi): Not sure this is the best way to propogate the error messages and turn them
@@ -0,0 +1,373 @@
+// Package audit records the changes the operator makes to the cluster, for the clusters with change-control
+// requirements. Every create, update, patch and delete of the audited clients is written as a JSON record to Output,
+// one per line, and sent to the webhook and to the Kafka topic when they are set. Dry runs and the updates that
+// leave the resources unchanged are not recorded.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// queueSize is the number of records waiting to be sent to the sinks before new ones are dropped
+	queueSize = 1000
+	// kafkaContentType is the content type of the records produced with the Kafka REST Proxy
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+)
+
+var (
+	// Enabled makes the audited clients record the changes they make
+	Enabled = false
+	// OperatorVersion is the version of the operator recorded as the author of the changes
+	OperatorVersion = ""
+	// WebhookURL is the URL the records are posted to as JSON, one per request. They are not sent when it is empty.
+	WebhookURL = ""
+	// KafkaURL is the URL of the Kafka REST Proxy the records are produced to KafkaTopic with. They are not
+	// produced when it is empty.
+	KafkaURL = ""
+	// KafkaTopic is the Kafka topic the records are produced to
+	KafkaTopic = ""
+	// Output is where the records are written, one JSON record per line
+	Output io.Writer = os.Stdout
+)
+
+// Record describes a change made by the operator
+type Record struct {
+	Time time.Time `json:"time"`
+	// Operator is the operator that made the change
+	Operator Operator `json:"operator"`
+	// Action is create, update, patch, apply, delete or deletecollection
+	Action     string `json:"action"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// DiffHash is the sha256 digest of the content sent: the resource created or updated, or the patch
+	DiffHash string `json:"diffHash,omitempty"`
+}
+
+// Operator identifies the operator that made a change
+type Operator struct {
+	Version string `json:"version"`
+	// Pod is the replica of the operator that made the change
+	Pod string `json:"pod,omitempty"`
+}
+
+var (
+	// outputMu serializes the writes to Output
+	outputMu sync.Mutex
+	// queue holds the records waiting to be sent to the sinks
+	queue = make(chan Record, queueSize)
+
+	// versionsMu guards versions
+	versionsMu sync.Mutex
+	// versions are the resource versions of the resources last written, to tell the updates that changed them
+	versions = map[types.UID]string{}
+)
+
+// ValidateSinks returns an error if the sinks of the records are not valid
+func ValidateSinks() error {
+	for _, sink := range []struct{ name, url string }{{"webhook", WebhookURL}, {"Kafka REST Proxy", KafkaURL}} {
+		if sink.url != "" && !strings.HasPrefix(sink.url, "http://") && !strings.HasPrefix(sink.url, "https://") {
+			return fmt.Errorf("the URL of the %v must be an http or https URL, got %v", sink.name, sink.url)
+		}
+	}
+	if (KafkaURL == "") != (KafkaTopic == "") {
+		return fmt.Errorf("the Kafka REST Proxy and the Kafka topic must be set together")
+	}
+	return nil
+}
+
+// WithAudit returns a client recording the changes made by c, or c itself when the audit is disabled
+func WithAudit(c client.Client) client.Client {
+	if !Enabled {
+		return c
+	}
+	return &auditClient{Client: c}
+}
+
+// NewClient creates the client of the manager recording the changes it makes: the default client of the manager,
+// reading from the cache and writing to the API server, wrapped by WithAudit
+func NewClient(cache cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return WithAudit(&client.DelegatingClient{
+		Reader:       &client.DelegatingReader{CacheReader: cache, ClientReader: c},
+		Writer:       c,
+		StatusClient: c,
+	}), nil
+}
+
+// auditClient records the writes of the client it wraps that succeed
+type auditClient struct {
+	client.Client
+}
+
+func (c *auditClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	hash := digestObject(obj)
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if len((&client.CreateOptions{}).ApplyOptions(opts).DryRun) == 0 && written(obj, "") {
+		record("create", obj, hash)
+	}
+	return nil
+}
+
+func (c *auditClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	hash := digestObject(obj)
+	before := resourceVersion(obj)
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if len((&client.UpdateOptions{}).ApplyOptions(opts).DryRun) == 0 && written(obj, before) {
+		record("update", obj, hash)
+	}
+	return nil
+}
+
+func (c *auditClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	hash := ""
+	if data, err := patch.Data(obj); err == nil {
+		hash = digest(data)
+	}
+	before := resourceVersion(obj)
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	if len((&client.PatchOptions{}).ApplyOptions(opts).DryRun) > 0 || !written(obj, before) {
+		return nil
+	}
+	action := "patch"
+	if patch.Type() == types.ApplyPatchType {
+		action = "apply"
+	}
+	record(action, obj, hash)
+	return nil
+}
+
+func (c *auditClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if len((&client.DeleteOptions{}).ApplyOptions(opts).DryRun) > 0 {
+		return nil
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		versionsMu.Lock()
+		delete(versions, accessor.GetUID())
+		versionsMu.Unlock()
+	}
+	record("delete", obj, "")
+	return nil
+}
+
+func (c *auditClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	if err := c.Client.DeleteAllOf(ctx, obj, opts...); err != nil {
+		return err
+	}
+	options := (&client.DeleteAllOfOptions{}).ApplyOptions(opts)
+	if len(options.DryRun) > 0 {
+		return nil
+	}
+	r := newRecord("deletecollection", obj, "")
+	r.Namespace, r.Name = options.Namespace, ""
+	emit(r)
+	return nil
+}
+
+// written returns true if obj was changed by the write it was returned by, i.e. if its resource version is no
+// longer before, or, when the version before the write is unknown as for server-side applies, the one it was last
+// written with
+func written(obj runtime.Object, before string) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+	after := accessor.GetResourceVersion()
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	if before == "" {
+		before = versions[accessor.GetUID()]
+	}
+	if accessor.GetUID() != "" {
+		versions[accessor.GetUID()] = after
+	}
+	return after == "" || after != before
+}
+
+// resourceVersion returns the resource version of obj, empty if it has none
+func resourceVersion(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
+
+// digestObject returns the digest of obj as it is sent to the API server
+func digestObject(obj runtime.Object) string {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return digest(data)
+}
+
+func digest(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// newRecord returns the record of action on obj, whose content sent has hash as digest
+func newRecord(action string, obj runtime.Object, hash string) Record {
+	r := Record{
+		Time:     time.Now().UTC(),
+		Operator: Operator{Version: OperatorVersion, Pod: os.Getenv("POD_NAME")},
+		Action:   action,
+		DiffHash: hash,
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		// The typed objects usually do not set their kind
+		if kind, err := apiutil.GVKForObject(obj, scheme.Scheme); err == nil {
+			gvk = kind
+		}
+	}
+	r.APIVersion, r.Kind = gvk.GroupVersion().String(), gvk.Kind
+	if accessor, err := meta.Accessor(obj); err == nil {
+		r.Namespace, r.Name = accessor.GetNamespace(), accessor.GetName()
+	}
+	return r
+}
+
+// record emits the record of action on obj
+func record(action string, obj runtime.Object, hash string) {
+	emit(newRecord(action, obj, hash))
+}
+
+// emit writes r to Output and queues it to be sent to the sinks. The records are dropped when the queue is full,
+// rather than delaying the changes of the operator.
+func emit(r Record) {
+	line, err := json.Marshal(r)
+	if err != nil {
+		log.Warnf("Failed to marshal the audit record of %v %v: %v", r.Kind, r.Name, err)
+		return
+	}
+	outputMu.Lock()
+	_, err = Output.Write(append(line, '\n'))
+	outputMu.Unlock()
+	if err != nil {
+		log.Warnf("Failed to write the audit record of %v %v: %v", r.Kind, r.Name, err)
+	}
+	if WebhookURL == "" && KafkaURL == "" {
+		return
+	}
+	select {
+	case queue <- r:
+	default:
+		log.Warnf("Dropping the audit record of %v %v: %v records are waiting to be sent", r.Kind, r.Name, queueSize)
+		for _, sink := range sinks() {
+			metrics.AuditRecordsDropped.WithLabelValues(sink).Inc()
+		}
+	}
+}
+
+// sinks returns the sinks the records are sent to
+func sinks() []string {
+	names := []string{}
+	if WebhookURL != "" {
+		names = append(names, "webhook")
+	}
+	if KafkaURL != "" {
+		names = append(names, "kafka")
+	}
+	return names
+}
+
+// AddToManager adds the sender of the records to the sinks to the manager. It does nothing when the audit is
+// disabled or when no sink is set.
+func AddToManager(mgr manager.Manager) error {
+	if !Enabled || len(sinks()) == 0 {
+		return nil
+	}
+	s := &sender{client: &http.Client{Timeout: 10 * time.Second}}
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		for {
+			select {
+			case <-stop:
+				return nil
+			case r := <-queue:
+				s.send(r)
+			}
+		}
+	}))
+}
+
+// sender sends the records to the sinks
+type sender struct {
+	client *http.Client
+}
+
+// send sends r to every sink, counting it as dropped by the sinks it could not be delivered to
+func (s *sender) send(r Record) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if WebhookURL != "" {
+		if err := s.post(WebhookURL, "application/json", body); err != nil {
+			log.Warnf("Failed to send the audit record of %v %v to the webhook: %v", r.Kind, r.Name, err)
+			metrics.AuditRecordsDropped.WithLabelValues("webhook").Inc()
+		}
+	}
+	if KafkaURL != "" {
+		key := strings.Join([]string{r.Kind, r.Namespace, r.Name}, "/")
+		records, err := json.Marshal(map[string]interface{}{
+			"records": []interface{}{map[string]interface{}{"key": key, "value": json.RawMessage(body)}},
+		})
+		if err == nil {
+			err = s.post(strings.TrimSuffix(KafkaURL, "/")+"/topics/"+KafkaTopic, kafkaContentType, records)
+		}
+		if err != nil {
+			log.Warnf("Failed to produce the audit record of %v %v to Kafka topic %v: %v", r.Kind, r.Name, KafkaTopic, err)
+			metrics.AuditRecordsDropped.WithLabelValues("kafka").Inc()
+		}
+	}
+}
+
+// post posts body to url
+func (s *sender) post(url string, contentType string, body []byte) error {
+	resp, err := s.client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
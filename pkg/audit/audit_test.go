@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// records returns the records written to output
+func records(t *testing.T, output *bytes.Buffer) []Record {
+	records := []Record{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		r := Record{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid audit record %v: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAuditClient(t *testing.T) {
+	output := &bytes.Buffer{}
+	defer func() { Enabled, OperatorVersion, Output = false, "", os.Stdout }()
+	Enabled, OperatorVersion, Output = true, "1.1.0", output
+
+	c := WithAudit(fake.NewFakeClient())
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "odh-dashboard-config", Namespace: "opendatahub"},
+		Data:       map[string]string{"enabled": "true"},
+	}
+	if err := c.Create(context.TODO(), cm); err != nil {
+		t.Fatal(err)
+	}
+	cm.Data["enabled"] = "false"
+	if err := c.Update(context.TODO(), cm, client.DryRunAll); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(context.TODO(), cm); err != nil {
+		t.Fatal(err)
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	cm.Labels = map[string]string{"app": "odh-dashboard"}
+	if err := c.Patch(context.TODO(), cm, patch); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(context.TODO(), cm); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []string{}
+	for _, r := range records(t, output) {
+		actions = append(actions, r.Action)
+		if r.Operator.Version != "1.1.0" || r.APIVersion != "v1" || r.Kind != "ConfigMap" ||
+			r.Namespace != "opendatahub" || r.Name != "odh-dashboard-config" || r.Time.IsZero() {
+			t.Errorf("Unexpected audit record %+v", r)
+		}
+		if (r.DiffHash == "") != (r.Action == "delete") {
+			t.Errorf("Unexpected digest of the %v audit record: %q", r.Action, r.DiffHash)
+		}
+	}
+	if expected := []string{"create", "update", "patch", "delete"}; !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Expected the audit records of %v, got %v", expected, actions)
+	}
+}
+
+func TestWritten(t *testing.T) {
+	cm := func(uid, version string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid), ResourceVersion: version}}
+	}
+	tests := []struct {
+		name    string
+		obj     *corev1.ConfigMap
+		before  string
+		written bool
+	}{
+		{name: "updated", obj: cm("updated", "2"), before: "1", written: true},
+		{name: "unchanged", obj: cm("unchanged", "1"), before: "1", written: false},
+		{name: "applied first", obj: cm("applied", "1"), written: true},
+		{name: "applied unchanged", obj: cm("applied", "1"), written: false},
+		{name: "applied changed", obj: cm("applied", "2"), written: true},
+		{name: "no version", obj: cm("", ""), written: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if written := written(tt.obj, tt.before); written != tt.written {
+				t.Errorf("Expected the resource to be written (%v), got %v", tt.written, written)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	type request struct {
+		path        string
+		contentType string
+		body        map[string]interface{}
+	}
+	requests := []request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body := map[string]interface{}{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Invalid body %s: %v", data, err)
+		}
+		requests = append(requests, request{r.URL.Path, r.Header.Get("Content-Type"), body})
+	}))
+	defer server.Close()
+	defer func() { WebhookURL, KafkaURL, KafkaTopic = "", "", "" }()
+	WebhookURL, KafkaURL, KafkaTopic = server.URL+"/audit", server.URL+"/", "odh-audit"
+
+	s := &sender{client: server.Client()}
+	s.send(Record{Action: "apply", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "opendatahub", Name: "odh-dashboard"})
+	if len(requests) != 2 {
+		t.Fatalf("Expected the record to be sent to the webhook and to Kafka, got %v requests", len(requests))
+	}
+	if requests[0].path != "/audit" || requests[0].contentType != "application/json" || requests[0].body["name"] != "odh-dashboard" {
+		t.Errorf("Unexpected webhook request %+v", requests[0])
+	}
+	if requests[1].path != "/topics/odh-audit" || requests[1].contentType != kafkaContentType {
+		t.Errorf("Unexpected Kafka request %+v", requests[1])
+	}
+	produced, _ := requests[1].body["records"].([]interface{})
+	if len(produced) != 1 {
+		t.Fatalf("Expected one record to be produced, got %v", requests[1].body)
+	}
+	produced0, _ := produced[0].(map[string]interface{})
+	value, _ := produced0["value"].(map[string]interface{})
+	if produced0["key"] != "Deployment/opendatahub/odh-dashboard" || value["action"] != "apply" {
+		t.Errorf("Unexpected Kafka record %v", produced0)
+	}
+
+	metrics.AuditRecordsDropped.Reset()
+	WebhookURL, KafkaURL = server.URL+"/audit", ""
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	s.send(Record{Action: "delete", APIVersion: "v1", Kind: "ConfigMap", Name: "removed"})
+	if dropped := testutil.ToFloat64(metrics.AuditRecordsDropped.WithLabelValues("webhook")); dropped != 1 {
+		t.Errorf("Expected 1 record to be dropped by the webhook, got %v", dropped)
+	}
+}
+
+func TestValidateSinks(t *testing.T) {
+	defer func() { WebhookURL, KafkaURL, KafkaTopic = "", "", "" }()
+	tests := []struct {
+		name    string
+		webhook string
+		kafka   string
+		topic   string
+		valid   bool
+	}{
+		{name: "no sink", valid: true},
+		{name: "webhook", webhook: "https://audit.example.com/records", valid: true},
+		{name: "kafka", kafka: "http://kafka-rest:8082", topic: "odh-audit", valid: true},
+		{name: "webhook not http", webhook: "audit.example.com", valid: false},
+		{name: "kafka without topic", kafka: "http://kafka-rest:8082", valid: false},
+		{name: "topic without kafka", topic: "odh-audit", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			WebhookURL, KafkaURL, KafkaTopic = tt.webhook, tt.kafka, tt.topic
+			if err := ValidateSinks(); (err == nil) != tt.valid {
+				t.Errorf("Expected the sinks to be valid (%v), got %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package cachefilter reduces the memory the caches of the operator take on large clusters. The objects listed and
+// watched by the informers are transformed before they are cached: their managed fields, which the operator never
+// reads, are dropped. The resources the operator only watches to restore the ones it deployed can be cached in a
+// separate cache, whose informers only list and watch the resources labelled as deployed by a KfDef, and which also
+// drops their last applied configuration.
+package cachefilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// lastAppliedAnnotation is the annotation kubectl apply records the last applied configuration of a resource in
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ManagedResourcesOnly makes the informers of the resources watched to restore the resources deployed by the
+// KfDef instances only list and watch the resources labelled with utils.ManagedLabel
+var ManagedResourcesOnly = false
+
+// NewCacheFunc returns a function creating the cache of the manager with newCache, dropping the managed fields of
+// the cached objects. When ManagedResourcesOnly is set, it also creates the cache of the managed resources with
+// newCache, started along with the cache of the manager.
+func NewCacheFunc(newCache cache.NewCacheFunc) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		c, err := newCache(withTransform(config, "", false), opts)
+		if err != nil || !ManagedResourcesOnly {
+			return c, err
+		}
+		managed, err := newCache(withTransform(config, utils.ManagedSelector(), true), opts)
+		if err != nil {
+			return nil, err
+		}
+		return &managerCache{Cache: c, managed: managed}, nil
+	}
+}
+
+// managerCache is the cache of the manager along with the cache of the managed resources
+type managerCache struct {
+	cache.Cache
+	managed cache.Cache
+}
+
+func (c *managerCache) Start(stop <-chan struct{}) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.managed.Start(stop)
+	}()
+	if err := c.Cache.Start(stop); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+func (c *managerCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	return c.Cache.WaitForCacheSync(stop) && c.managed.WaitForCacheSync(stop)
+}
+
+// Managed returns the cache of the managed resources created along with the cache of the manager c, or c itself
+// when the informers are not limited to the managed resources. The objects of the cache of the managed resources
+// lack their last applied configuration, they must only be watched and never be updated.
+func Managed(c cache.Cache) cache.Cache {
+	if m, ok := c.(*managerCache); ok {
+		return m.managed
+	}
+	return c
+}
+
+// Kind returns the source of the events of the objects of the kind of obj cached by c
+func Kind(c cache.Cache, obj runtime.Object) source.Source {
+	src := &source.Kind{Type: obj}
+	// The cache of the controller is only injected into the sources without a cache
+	_ = src.InjectCache(c)
+	return src
+}
+
+// withTransform returns a copy of config whose lists and watches transform the objects they return, only listing
+// and watching the objects matching selector when it is set
+func withTransform(config *rest.Config, selector string, dropLastApplied bool) *rest.Config {
+	transformed := rest.CopyConfig(config)
+	transformed.WrapTransport = transport.Wrappers(config.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &transformer{RoundTripper: rt, selector: selector, dropLastApplied: dropLastApplied}
+	})
+	return transformed
+}
+
+// transformer transforms the objects of the responses of the lists and watches sent with it
+type transformer struct {
+	http.RoundTripper
+	selector        string
+	dropLastApplied bool
+}
+
+func (t *transformer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	if t.selector != "" {
+		// The request must not be modified by the round tripper
+		selected := new(http.Request)
+		*selected = *req
+		u := *req.URL
+		query := u.Query()
+		if current := query.Get("labelSelector"); current != "" {
+			query.Set("labelSelector", current+","+t.selector)
+		} else {
+			query.Set("labelSelector", t.selector)
+		}
+		u.RawQuery = query.Encode()
+		selected.URL = &u
+		req = selected
+	}
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return resp, err
+	}
+	if req.URL.Query().Get("watch") == "true" {
+		resp.Body = t.transformEvents(resp.Body)
+		return resp, nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if transformed, err := t.transformList(data); err == nil {
+		data = transformed
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// transformList transforms the items of a list, or the object returned by a get
+func (t *transformer) transformList(data []byte) ([]byte, error) {
+	list := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// The numbers are kept as they are rather than converted to floats
+	decoder.UseNumber()
+	if err := decoder.Decode(&list); err != nil {
+		return nil, err
+	}
+	items, isList := list["items"].([]interface{})
+	if !isList {
+		t.transform(list)
+	}
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			t.transform(obj)
+		}
+	}
+	return json.Marshal(list)
+}
+
+// transformEvents returns the stream of the watch events of body with their objects transformed
+func (t *transformer) transformEvents(body io.ReadCloser) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		decoder := json.NewDecoder(body)
+		decoder.UseNumber()
+		encoder := json.NewEncoder(writer)
+		for {
+			event := map[string]interface{}{}
+			if err := decoder.Decode(&event); err != nil {
+				// The reader gets io.EOF once the watch ends
+				writer.CloseWithError(err)
+				return
+			}
+			if obj, ok := event["object"].(map[string]interface{}); ok {
+				t.transform(obj)
+			}
+			if err := encoder.Encode(event); err != nil {
+				body.Close()
+				return
+			}
+		}
+	}()
+	return &events{PipeReader: reader, body: body}
+}
+
+// events is the stream of transformed watch events read from body
+type events struct {
+	*io.PipeReader
+	body io.ReadCloser
+}
+
+func (e *events) Close() error {
+	e.PipeReader.Close()
+	return e.body.Close()
+}
+
+// transform drops the managed fields of obj, and its last applied configuration if dropLastApplied is set
+func (t *transformer) transform(obj map[string]interface{}) {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(metadata, "managedFields")
+	if !t.dropLastApplied {
+		return
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		delete(annotations, lastAppliedAnnotation)
+	}
+}
@@ -0,0 +1,124 @@
+package cachefilter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	"k8s.io/client-go/rest"
+)
+
+// object returns the JSON of a ConfigMap with managed fields and a last applied configuration
+func object(name string) string {
+	return `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","resourceVersion":"12345678901234567890",` +
+		`"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}","kfctl.kubeflow.io/kfdef-instance":"opendatahub"},` +
+		`"managedFields":[{"manager":"kubectl","operation":"Apply"}]},"data":{"key":"value"}}`
+}
+
+// expected returns the metadata of the ConfigMap of object once transformed
+func expected(name string, dropLastApplied bool) map[string]interface{} {
+	annotations := map[string]interface{}{"kfctl.kubeflow.io/kfdef-instance": "opendatahub"}
+	if !dropLastApplied {
+		annotations[lastAppliedAnnotation] = "{}"
+	}
+	return map[string]interface{}{"name": name, "resourceVersion": "12345678901234567890", "annotations": annotations}
+}
+
+// metadata returns the metadata of the object of data
+func metadata(t *testing.T, data []byte) map[string]interface{} {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatalf("Invalid object %s: %v", data, err)
+	}
+	m, _ := obj["metadata"].(map[string]interface{})
+	return m
+}
+
+func TestTransformer(t *testing.T) {
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("labelSelector"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			w.Write([]byte(`{"type":"ADDED","object":` + object("added") + "}\n"))
+			w.Write([]byte(`{"type":"MODIFIED","object":` + object("modified") + "}\n"))
+			return
+		}
+		w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMapList","items":[` + object("first") + "," + object("second") + "]}"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		query           string
+		selector        string
+		dropLastApplied bool
+		expectedQuery   string
+	}{
+		{name: "all resources"},
+		{name: "selector", selector: utils.ManagedSelector(), dropLastApplied: true, expectedQuery: "opendatahub.io/managed-by=opendatahub-operator"},
+		{
+			name:            "selector merged",
+			query:           "labelSelector=app%3Dodh-dashboard",
+			selector:        utils.ManagedSelector(),
+			dropLastApplied: true,
+			expectedQuery:   "app=odh-dashboard,opendatahub.io/managed-by=opendatahub-operator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := withTransform(&rest.Config{Host: server.URL}, tt.selector, tt.dropLastApplied)
+			rt, err := rest.TransportFor(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &http.Client{Transport: rt}
+			queries = nil
+
+			resp, err := c.Get(server.URL + "/api/v1/configmaps?" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			list := struct{ Items []json.RawMessage }{}
+			if err := json.Unmarshal(data, &list); err != nil || len(list.Items) != 2 {
+				t.Fatalf("Expected a list of 2 items, got %s: %v", data, err)
+			}
+			for i, name := range []string{"first", "second"} {
+				if m := metadata(t, list.Items[i]); !reflect.DeepEqual(m, expected(name, tt.dropLastApplied)) {
+					t.Errorf("Expected the metadata %v, got %v", expected(name, tt.dropLastApplied), m)
+				}
+			}
+
+			resp, err = c.Get(server.URL + "/api/v1/configmaps?watch=true&" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("Expected 2 watch events, got %s", data)
+			}
+			for i, name := range []string{"added", "modified"} {
+				event := struct{ Object json.RawMessage }{}
+				if err := json.Unmarshal([]byte(lines[i]), &event); err != nil {
+					t.Fatalf("Invalid watch event %v: %v", lines[i], err)
+				}
+				if m := metadata(t, event.Object); !reflect.DeepEqual(m, expected(name, tt.dropLastApplied)) {
+					t.Errorf("Expected the metadata %v, got %v", expected(name, tt.dropLastApplied), m)
+				}
+			}
+
+			if expectedQueries := []string{tt.expectedQuery, tt.expectedQuery}; !reflect.DeepEqual(queries, expectedQueries) {
+				t.Errorf("Expected the label selectors %q, got %q", expectedQueries, queries)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package clusterstatus serves the aggregated status of the DataScienceClusters and the KfDefs of the operator as
+// JSON on /status, for the dashboard and external tooling to show the overall health of Open Data Hub without
+// reading every component.
+package clusterstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Status is the aggregated status served on /status
+type Status struct {
+	// Ready is true when all the enabled components and all the applications are ready
+	Ready               bool            `json:"ready"`
+	DataScienceClusters []ClusterStatus `json:"dataScienceClusters"`
+	KfDefs              []KfDefStatus   `json:"kfDefs"`
+}
+
+// ClusterStatus summarizes the status of a DataScienceCluster
+type ClusterStatus struct {
+	Namespace           string                      `json:"namespace"`
+	Name                string                      `json:"name"`
+	Phase               dscv1alpha1.ComponentPhase  `json:"phase,omitempty"`
+	ReadyComponents     string                      `json:"readyComponents,omitempty"`
+	ComponentCounts     dscv1alpha1.ComponentCounts `json:"componentCounts"`
+	InstalledComponents map[string]bool             `json:"installedComponents,omitempty"`
+	// NotReady holds the message of the enabled components that are not ready, by name
+	NotReady map[string]string `json:"notReady,omitempty"`
+}
+
+// KfDefStatus summarizes the status of a KfDef
+type KfDefStatus struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	ReadyApplications string `json:"readyApplications"`
+	// Degraded holds the message of the Degraded applications, by name
+	Degraded map[string]string `json:"degraded,omitempty"`
+}
+
+// Aggregate summarizes the status of the DataScienceClusters and the KfDefs
+func Aggregate(clusters []dscv1alpha1.DataScienceCluster, kfdefs []kfdefv1.KfDef) Status {
+	status := Status{Ready: true, DataScienceClusters: []ClusterStatus{}, KfDefs: []KfDefStatus{}}
+	for _, cluster := range clusters {
+		summary := ClusterStatus{
+			Namespace:           cluster.Namespace,
+			Name:                cluster.Name,
+			Phase:               cluster.Status.Phase,
+			ReadyComponents:     cluster.Status.ReadyComponents,
+			ComponentCounts:     cluster.Status.ComponentCounts,
+			InstalledComponents: cluster.Status.InstalledComponents,
+		}
+		for _, component := range cluster.Status.Components {
+			if !component.Enabled || component.Phase == dscv1alpha1.ComponentReady {
+				continue
+			}
+			if summary.NotReady == nil {
+				summary.NotReady = map[string]string{}
+			}
+			summary.NotReady[component.Name] = component.Message
+		}
+		if cluster.Status.Phase != dscv1alpha1.ComponentReady {
+			status.Ready = false
+		}
+		status.DataScienceClusters = append(status.DataScienceClusters, summary)
+	}
+	for _, kfdef := range kfdefs {
+		summary := KfDefStatus{Namespace: kfdef.Namespace, Name: kfdef.Name}
+		ready := 0
+		for _, cond := range kfdef.Status.ApplicationConditions {
+			switch {
+			case cond.Type == kfdefv1.KfAvailable && cond.Status == corev1.ConditionTrue:
+				ready++
+			case cond.Type == kfdefv1.KfDegraded:
+				if summary.Degraded == nil {
+					summary.Degraded = map[string]string{}
+				}
+				summary.Degraded[cond.Application] = cond.Message
+			}
+		}
+		summary.ReadyApplications = fmt.Sprintf("%d/%d", ready, len(kfdef.Spec.Applications))
+		if ready < len(kfdef.Spec.Applications) {
+			status.Ready = false
+		}
+		status.KfDefs = append(status.KfDefs, summary)
+	}
+	sort.Slice(status.DataScienceClusters, func(i, j int) bool {
+		a, b := status.DataScienceClusters[i], status.DataScienceClusters[j]
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	sort.Slice(status.KfDefs, func(i, j int) bool {
+		a, b := status.KfDefs[i], status.KfDefs[j]
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	return status
+}
+
+// NewHandler returns the handler of the /status endpoint, reading the DataScienceClusters and the KfDefs with
+// reader
+func NewHandler(reader client.Reader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		clusters := &dscv1alpha1.DataScienceClusterList{}
+		if err := reader.List(context.TODO(), clusters); err != nil {
+			log.Warnf("Failed to list the DataScienceClusters: %v", err)
+			http.Error(w, "failed to list the DataScienceClusters", http.StatusInternalServerError)
+			return
+		}
+		kfdefs := &kfdefv1.KfDefList{}
+		if err := reader.List(context.TODO(), kfdefs); err != nil {
+			log.Warnf("Failed to list the KfDefs: %v", err)
+			http.Error(w, "failed to list the KfDefs", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(Aggregate(clusters.Items, kfdefs.Items)); err != nil {
+			log.Warnf("Failed to write the status: %v", err)
+		}
+	})
+}
+
+// server serves /status on every replica of the operator once the caches of the manager are synced
+type server struct {
+	addr   string
+	reader client.Reader
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the replicas that are not the leader serve the
+// status too
+func (s *server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves /status until stop is closed
+func (s *server) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/status", NewHandler(s.reader))
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-stop
+		srv.Close()
+	}()
+	log.Infof("Serving the aggregated status on %v.", s.addr)
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// AddToManager serves the aggregated status on /status at addr, reading the DataScienceClusters and the KfDefs
+// from the cache of the manager
+func AddToManager(mgr manager.Manager, addr string) error {
+	return mgr.Add(&server{addr: addr, reader: mgr.GetClient()})
+}
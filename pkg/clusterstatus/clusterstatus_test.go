@@ -0,0 +1,91 @@
+package clusterstatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStatus(t *testing.T) {
+	cluster := &dscv1alpha1.DataScienceCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "opendatahub", Name: "default"},
+		Status: dscv1alpha1.DataScienceClusterStatus{
+			Phase: dscv1alpha1.ComponentFailed,
+			Components: []dscv1alpha1.ComponentStatus{
+				{Name: "dashboard", Enabled: true, Phase: dscv1alpha1.ComponentReady},
+				{Name: "modelserving", Enabled: true, Phase: dscv1alpha1.ComponentFailed, Message: "application kserve failed"},
+				{Name: "workbenches", Enabled: false, Phase: dscv1alpha1.ComponentDisabled},
+			},
+			InstalledComponents: map[string]bool{"dashboard": true, "modelserving": false},
+			ReadyComponents:     "1/2",
+			ComponentCounts:     dscv1alpha1.ComponentCounts{Ready: 1, Degraded: 1},
+		},
+	}
+	kfdef := &kfdefv1.KfDef{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "opendatahub", Name: "default-modelserving"},
+		Spec:       kfdefv1.KfDefSpec{Applications: []kfdefv1.Application{{Name: "odh-model-controller"}, {Name: "kserve"}}},
+		Status: kfdefv1.KfDefStatus{ApplicationConditions: []kfdefv1.ApplicationCondition{
+			{Application: "odh-model-controller", Type: kfdefv1.KfAvailable, Status: corev1.ConditionTrue},
+			{Application: "kserve", Type: kfdefv1.KfDegraded, Status: corev1.ConditionTrue, Message: "application not ready"},
+		}},
+	}
+	s := runtime.NewScheme()
+	if err := dscv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := kfdefv1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(fake.NewFakeClientWithScheme(s, cluster, kfdef))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v: %v", recorder.Code, recorder.Body.String())
+	}
+	status := Status{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	expected := Status{
+		Ready: false,
+		DataScienceClusters: []ClusterStatus{{
+			Namespace:           "opendatahub",
+			Name:                "default",
+			Phase:               dscv1alpha1.ComponentFailed,
+			ReadyComponents:     "1/2",
+			ComponentCounts:     dscv1alpha1.ComponentCounts{Ready: 1, Degraded: 1},
+			InstalledComponents: map[string]bool{"dashboard": true, "modelserving": false},
+			NotReady:            map[string]string{"modelserving": "application kserve failed"},
+		}},
+		KfDefs: []KfDefStatus{{
+			Namespace:         "opendatahub",
+			Name:              "default-modelserving",
+			ReadyApplications: "1/2",
+			Degraded:          map[string]string{"kserve": "application not ready"},
+		}},
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected the status %+v, got %+v", expected, status)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for a POST, got %v", recorder.Code)
+	}
+
+	ready := Aggregate([]dscv1alpha1.DataScienceCluster{{Status: dscv1alpha1.DataScienceClusterStatus{Phase: dscv1alpha1.ComponentReady}}}, nil)
+	if !ready.Ready {
+		t.Errorf("Expected ready when all the components are ready, got %+v", ready)
+	}
+}
@@ -0,0 +1,164 @@
+package acceleratorprofile
+
+import (
+	"context"
+	"reflect"
+
+	acceleratorv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/acceleratorprofile/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// discoveryRequest is the request every event is mapped to: the accelerators are discovered on all the nodes at once
+var discoveryRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: "accelerators"}}
+
+// AddToManager adds the AcceleratorProfile controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("acceleratorprofile-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileAcceleratorProfile{client: mgr.GetClient()}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for acceleratorprofile.")
+	c, err := controller.New("acceleratorprofile-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	toDiscoveryRequest := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(_ handler.MapObject) []reconcile.Request {
+			return []reconcile.Request{discoveryRequest}
+		}),
+	}
+
+	// Watch for the nodes whose labels or allocatable resources change
+	if err := c.Watch(&source.Kind{Type: &corev1.Node{}}, toDiscoveryRequest, nodePredicates); err != nil {
+		return err
+	}
+
+	// Watch for the discovered AcceleratorProfiles being deleted to create them again
+	return c.Watch(&source.Kind{Type: &acceleratorv1alpha1.AcceleratorProfile{}}, toDiscoveryRequest, profilePredicates)
+}
+
+var nodePredicates = predicate.Funcs{
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, ok := e.ObjectOld.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		newNode, ok := e.ObjectNew.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+			!reflect.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable)
+	},
+}
+
+var profilePredicates = predicate.Funcs{
+	CreateFunc: func(_ event.CreateEvent) bool {
+		return false
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return e.Meta.GetLabels()[acceleratorv1alpha1.DiscoveredLabel] == "true"
+	},
+	UpdateFunc: func(_ event.UpdateEvent) bool {
+		return false
+	},
+}
+
+// blank assignment to verify that ReconcileAcceleratorProfile implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileAcceleratorProfile{}
+
+// ReconcileAcceleratorProfile reconciles the AcceleratorProfiles with the accelerators discovered on the nodes
+type ReconcileAcceleratorProfile struct {
+	client client.Client
+}
+
+// Reconcile discovers the accelerators of the nodes, creates an AcceleratorProfile for each new one and reports
+// their counts, nodes and MIG profiles in the status of the profiles. The profiles of the accelerators no longer
+// found on any node are kept with a count of 0, so the changes made to their spec survive the nodes being
+// scaled down.
+func (r *ReconcileAcceleratorProfile) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Discovering the accelerators of the nodes.")
+
+	nodes := &corev1.NodeList{}
+	if err := r.client.List(context.TODO(), nodes); err != nil {
+		return reconcile.Result{}, err
+	}
+	profiles := &acceleratorv1alpha1.AcceleratorProfileList{}
+	if err := r.client.List(context.TODO(), profiles,
+		client.MatchingLabels{acceleratorv1alpha1.DiscoveredLabel: "true"}); err != nil {
+		return reconcile.Result{}, err
+	}
+	discovered := map[string]*acceleratorv1alpha1.AcceleratorProfile{}
+	for i := range profiles.Items {
+		discovered[profiles.Items[i].Name] = &profiles.Items[i]
+	}
+
+	var reconcileErr error
+	for _, status := range discoverAccelerators(nodes.Items) {
+		name := profileName(status.Product)
+		profile, ok := discovered[name]
+		if ok {
+			delete(discovered, name)
+		} else {
+			profile = newProfile(status)
+			log.Infof("Discovered accelerator %v, creating AcceleratorProfile %v.", status.Product, name)
+			if err := r.client.Create(context.TODO(), profile); err != nil {
+				if errors.IsAlreadyExists(err) {
+					log.Warnf("AcceleratorProfile %v was not created by the operator, skipping accelerator %v.", name, status.Product)
+					continue
+				}
+				reconcileErr = err
+				continue
+			}
+		}
+		if err := r.setStatus(profile, status); err != nil {
+			reconcileErr = err
+		}
+	}
+
+	for _, profile := range discovered {
+		if err := r.setStatus(profile, acceleratorv1alpha1.AcceleratorProfileStatus{
+			Vendor:  profile.Status.Vendor,
+			Product: profile.Status.Product,
+		}); err != nil {
+			reconcileErr = err
+		}
+	}
+	return reconcile.Result{}, reconcileErr
+}
+
+// setStatus records the discovered accelerator in the status of the profile
+func (r *ReconcileAcceleratorProfile) setStatus(profile *acceleratorv1alpha1.AcceleratorProfile, status acceleratorv1alpha1.AcceleratorProfileStatus) error {
+	if reflect.DeepEqual(profile.Status, status) {
+		return nil
+	}
+	log.Infof("Found %v %v on %v nodes.", status.Count, status.Product, len(status.Nodes))
+	profile.Status = status
+	return r.client.Status().Update(context.TODO(), profile)
+}
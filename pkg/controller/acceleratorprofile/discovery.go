@@ -0,0 +1,152 @@
+package acceleratorprofile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	acceleratorv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/acceleratorprofile/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	nvidiaVendor = "NVIDIA"
+	// nvidiaGPU is the extended resource of the NVIDIA GPUs advertised by the NVIDIA device plugin
+	nvidiaGPU = corev1.ResourceName("nvidia.com/gpu")
+	// gpuProductLabel and gpuCountLabel are set by the GPU feature discovery of the NVIDIA GPU operator
+	gpuProductLabel = "nvidia.com/gpu.product"
+	gpuCountLabel   = "nvidia.com/gpu.count"
+	// migLabelPrefix and migCountLabelSuffix enclose the MIG profiles in the labels set by the GPU feature
+	// discovery with the mixed MIG strategy, e.g. nvidia.com/mig-1g.5gb.count
+	migLabelPrefix      = "nvidia.com/mig-"
+	migCountLabelSuffix = ".count"
+	// nfdNVIDIALabel is set by the Node Feature Discovery on the nodes having an NVIDIA PCI device
+	nfdNVIDIALabel = "feature.node.kubernetes.io/pci-10de.present"
+	// genericNVIDIAProduct is the product of the NVIDIA GPUs whose model is not labeled yet
+	genericNVIDIAProduct = "NVIDIA-GPU"
+)
+
+// discoverAccelerators returns the accelerators of the nodes, one per product, sorted by product
+func discoverAccelerators(nodes []corev1.Node) []acceleratorv1alpha1.AcceleratorProfileStatus {
+	byProduct := map[string]*acceleratorv1alpha1.AcceleratorProfileStatus{}
+	for i := range nodes {
+		node := &nodes[i]
+		product, found := gpuProduct(node)
+		if !found {
+			continue
+		}
+		status, ok := byProduct[product]
+		if !ok {
+			status = &acceleratorv1alpha1.AcceleratorProfileStatus{Vendor: nvidiaVendor, Product: product}
+			byProduct[product] = status
+		}
+		count := allocatable(node, nvidiaGPU, node.Labels[gpuCountLabel])
+		status.Count += count
+		status.Nodes = append(status.Nodes, acceleratorv1alpha1.AcceleratorNode{Name: node.Name, Count: count})
+		for _, mig := range migProfiles(node) {
+			merged := false
+			for j := range status.MIGProfiles {
+				if status.MIGProfiles[j].Name == mig.Name {
+					status.MIGProfiles[j].Count += mig.Count
+					merged = true
+				}
+			}
+			if !merged {
+				status.MIGProfiles = append(status.MIGProfiles, mig)
+			}
+		}
+	}
+
+	accelerators := []acceleratorv1alpha1.AcceleratorProfileStatus{}
+	for _, status := range byProduct {
+		sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Name < status.Nodes[j].Name })
+		sort.Slice(status.MIGProfiles, func(i, j int) bool { return status.MIGProfiles[i].Name < status.MIGProfiles[j].Name })
+		accelerators = append(accelerators, *status)
+	}
+	sort.Slice(accelerators, func(i, j int) bool { return accelerators[i].Product < accelerators[j].Product })
+	return accelerators
+}
+
+// gpuProduct returns the product of the NVIDIA GPUs of the node, and false if it has none. The nodes found by
+// the Node Feature Discovery or advertising GPUs before the GPU feature discovery labels them have a generic product.
+func gpuProduct(node *corev1.Node) (string, bool) {
+	if product := node.Labels[gpuProductLabel]; product != "" {
+		return product, true
+	}
+	if node.Labels[nfdNVIDIALabel] == "true" {
+		return genericNVIDIAProduct, true
+	}
+	if _, ok := node.Status.Allocatable[nvidiaGPU]; ok {
+		return genericNVIDIAProduct, true
+	}
+	return "", false
+}
+
+// migProfiles returns the MIG profiles labeled on the node
+func migProfiles(node *corev1.Node) []acceleratorv1alpha1.MIGProfile {
+	profiles := []acceleratorv1alpha1.MIGProfile{}
+	for label, value := range node.Labels {
+		if !strings.HasPrefix(label, migLabelPrefix) || !strings.HasSuffix(label, migCountLabelSuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(label, migLabelPrefix), migCountLabelSuffix)
+		if name == "" {
+			continue
+		}
+		identifier := migLabelPrefix + name
+		profiles = append(profiles, acceleratorv1alpha1.MIGProfile{
+			Name:       name,
+			Identifier: identifier,
+			Count:      allocatable(node, corev1.ResourceName(identifier), value),
+		})
+	}
+	return profiles
+}
+
+// allocatable returns the quantity of the resource allocatable on the node. The count labeled on the node is
+// returned when the device plugin does not advertise the resource yet.
+func allocatable(node *corev1.Node, resource corev1.ResourceName, label string) int64 {
+	if quantity, ok := node.Status.Allocatable[resource]; ok {
+		return quantity.Value()
+	}
+	count, err := strconv.ParseInt(label, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// profileName returns the name of the AcceleratorProfile of the product: the product in lower case, with the
+// characters not allowed in names replaced by dashes
+func profileName(product string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(product))
+	return strings.Trim(name, "-.")
+}
+
+// newProfile returns the AcceleratorProfile of a discovered accelerator. Its workloads tolerate the taint the
+// GPU nodes usually have.
+func newProfile(status acceleratorv1alpha1.AcceleratorProfileStatus) *acceleratorv1alpha1.AcceleratorProfile {
+	return &acceleratorv1alpha1.AcceleratorProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   profileName(status.Product),
+			Labels: kfutils.WithManagedLabel(map[string]string{acceleratorv1alpha1.DiscoveredLabel: "true"}),
+		},
+		Spec: acceleratorv1alpha1.AcceleratorProfileSpec{
+			DisplayName: strings.Replace(status.Product, "-", " ", -1),
+			Enabled:     true,
+			Identifier:  string(nvidiaGPU),
+			Description: fmt.Sprintf("%v discovered on the nodes of the cluster", strings.Replace(status.Product, "-", " ", -1)),
+			Tolerations: []corev1.Toleration{
+				{Key: string(nvidiaGPU), Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package acceleratorprofile
+
+import (
+	"reflect"
+	"testing"
+
+	acceleratorv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/acceleratorprofile/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func node(name string, labels map[string]string, allocatable corev1.ResourceList) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     corev1.NodeStatus{Allocatable: allocatable},
+	}
+}
+
+func TestDiscoverAccelerators(t *testing.T) {
+	nodes := []corev1.Node{
+		node("worker-0", map[string]string{"kubernetes.io/os": "linux"}, corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("8"),
+		}),
+		node("gpu-1", map[string]string{
+			gpuProductLabel: "Tesla-T4",
+			gpuCountLabel:   "1",
+		}, corev1.ResourceList{nvidiaGPU: resource.MustParse("1")}),
+		node("gpu-0", map[string]string{
+			gpuProductLabel: "Tesla-T4",
+			gpuCountLabel:   "4",
+		}, corev1.ResourceList{}),
+		node("a100-0", map[string]string{
+			gpuProductLabel:                "NVIDIA-A100-SXM4-40GB",
+			gpuCountLabel:                  "8",
+			"nvidia.com/mig.strategy":      "mixed",
+			"nvidia.com/mig-1g.5gb.count":  "7",
+			"nvidia.com/mig-1g.5gb.memory": "4864",
+			"nvidia.com/mig-3g.20gb.count": "2",
+		}, corev1.ResourceList{
+			nvidiaGPU:               resource.MustParse("6"),
+			"nvidia.com/mig-1g.5gb": resource.MustParse("7"),
+		}),
+		node("nfd-0", map[string]string{nfdNVIDIALabel: "true"}, corev1.ResourceList{}),
+	}
+
+	expected := []acceleratorv1alpha1.AcceleratorProfileStatus{
+		{
+			Vendor:  nvidiaVendor,
+			Product: "NVIDIA-A100-SXM4-40GB",
+			Count:   6,
+			Nodes:   []acceleratorv1alpha1.AcceleratorNode{{Name: "a100-0", Count: 6}},
+			MIGProfiles: []acceleratorv1alpha1.MIGProfile{
+				{Name: "1g.5gb", Identifier: "nvidia.com/mig-1g.5gb", Count: 7},
+				{Name: "3g.20gb", Identifier: "nvidia.com/mig-3g.20gb", Count: 2},
+			},
+		},
+		{
+			Vendor:  nvidiaVendor,
+			Product: genericNVIDIAProduct,
+			Nodes:   []acceleratorv1alpha1.AcceleratorNode{{Name: "nfd-0"}},
+		},
+		{
+			Vendor:  nvidiaVendor,
+			Product: "Tesla-T4",
+			Count:   5,
+			Nodes:   []acceleratorv1alpha1.AcceleratorNode{{Name: "gpu-0", Count: 4}, {Name: "gpu-1", Count: 1}},
+		},
+	}
+	accelerators := discoverAccelerators(nodes)
+	if !reflect.DeepEqual(accelerators, expected) {
+		t.Errorf("Expected the accelerators %+v, got %+v", expected, accelerators)
+	}
+}
+
+func TestProfileName(t *testing.T) {
+	names := map[string]string{
+		"NVIDIA-A100-SXM4-40GB":            "nvidia-a100-sxm4-40gb",
+		"NVIDIA-A100-SXM4-40GB-MIG-1g.5gb": "nvidia-a100-sxm4-40gb-mig-1g.5gb",
+		"Tesla T4":                         "tesla-t4",
+		"_NVIDIA_GPU_":                     "nvidia-gpu",
+	}
+	for product, expected := range names {
+		if name := profileName(product); name != expected {
+			t.Errorf("Expected the profile of %v to be named %v, got %v", product, expected, name)
+		}
+	}
+}
+
+func TestProfilePassesPredicate(t *testing.T) {
+	profile := newProfile(acceleratorv1alpha1.AcceleratorProfileStatus{Product: "NVIDIA-A100-SXM4-40GB"})
+	if !profilePredicates.Delete(event.DeleteEvent{Meta: profile, Object: profile}) {
+		t.Errorf("Expected the deletion of a discovered profile to pass the predicate of the watches, got labels %v", profile.Labels)
+	}
+	if !kfutils.IsManaged(profile) {
+		t.Errorf("Expected the discovered profile to be labelled %v, got %v", kfutils.ManagedLabel, profile.Labels)
+	}
+}
@@ -1,12 +1,54 @@
 package controller
 
 import (
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/kubeflow/kfctl/v3/pkg/controller/acceleratorprofile"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/dataconnection"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/datasciencecluster"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/datascienceproject"
 	"github.com/kubeflow/kfctl/v3/pkg/controller/kfdef"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/modelregistry"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/odhdashboardconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/pipelineserver"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/secretgenerator"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/usergroups"
 )
 
-// AddToManager adds all Controllers to the Manager
-func AddToManager(m manager.Manager) error {
-	return kfdef.AddToManager(m)
+// AddToManager adds all Controllers to the Manager. The options are shared by all controllers,
+// each controller sets its own Reconciler.
+func AddToManager(m manager.Manager, options controller.Options) error {
+	if err := operatorconfig.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := kfdef.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := datasciencecluster.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := acceleratorprofile.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := odhdashboardconfig.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := pipelineserver.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := modelregistry.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := datascienceproject.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := usergroups.AddToManager(m, options); err != nil {
+		return err
+	}
+	if err := secretgenerator.AddToManager(m, options); err != nil {
+		return err
+	}
+	return dataconnection.AddToManager(m, options)
 }
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Engine is the engine of a database
+type Engine string
+
+const (
+	// MariaDB is a MariaDB database
+	MariaDB Engine = "mariadb"
+	// MySQL is a MySQL database
+	MySQL Engine = "mysql"
+	// PostgreSQL is a PostgreSQL database
+	PostgreSQL Engine = "postgresql"
+)
+
+const (
+	// The keys of the connection Secret of a database
+	HostKey     = "host"
+	PortKey     = "port"
+	UsernameKey = "username"
+	PasswordKey = "password"
+	DatabaseKey = "database"
+
+	mySQLPort      = 3306
+	postgreSQLPort = 5432
+)
+
+// DialTimeout is the time a connection to an existing database has to be established in
+var DialTimeout = 5 * time.Second
+
+// Database is a database deployed along with a component, with its data kept in a PersistentVolumeClaim and its
+// connection in a Secret holding the host, port, username, password and database keys
+type Database struct {
+	// Name of the Deployment, the Service and the PersistentVolumeClaim of the database
+	Name string
+	// SecretName is the name of the connection Secret
+	SecretName string
+	Namespace  string
+	// Labels of the resources of the database, selecting its pods
+	Labels      map[string]string
+	Engine      Engine
+	Image       string
+	StorageSize resource.Quantity
+	User        string
+	DBName      string
+}
+
+// Port returns the default port of the engine of db
+func (db *Database) Port() int32 {
+	return EnginePort(db.Engine)
+}
+
+// EnginePort returns the default port of an engine
+func EnginePort(engine Engine) int32 {
+	if engine == PostgreSQL {
+		return postgreSQLPort
+	}
+	return mySQLPort
+}
+
+// Host returns the host of the Service of db
+func (db *Database) Host() string {
+	return fmt.Sprintf("%v.%v.svc", db.Name, db.Namespace)
+}
+
+// ConnectionData returns the connection Secret data of db, without the password, which is generated once by
+// GeneratePassword
+func (db *Database) ConnectionData() map[string]string {
+	return map[string]string{
+		HostKey:     db.Host(),
+		PortKey:     fmt.Sprint(db.Port()),
+		UsernameKey: db.User,
+		DatabaseKey: db.DBName,
+	}
+}
+
+// GeneratePassword returns a random password of a database
+func GeneratePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Claim returns the PersistentVolumeClaim of the data of db
+func (db *Database) Claim() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: db.objectMeta(db.Name),
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources:   corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: db.StorageSize}},
+		},
+	}
+}
+
+// Deployment returns the Deployment of db, running the sclorg image of its engine. The database is dumped to its
+// volume before it is backed up by Velero.
+func (db *Database) Deployment() (*appsv1.Deployment, error) {
+	container := corev1.Container{
+		Name:  string(db.Engine),
+		Image: db.Image,
+		Ports: []corev1.ContainerPort{{Name: "mysql", ContainerPort: mySQLPort, Protocol: corev1.ProtocolTCP}},
+		Env: []corev1.EnvVar{
+			utils.SecretEnv("MYSQL_USER", db.SecretName, UsernameKey),
+			utils.SecretEnv("MYSQL_PASSWORD", db.SecretName, PasswordKey),
+			utils.SecretEnv("MYSQL_DATABASE", db.SecretName, DatabaseKey),
+		},
+		ReadinessProbe: &corev1.Probe{
+			Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{
+				"/bin/sh", "-i", "-c",
+				`MYSQL_PWD=$MYSQL_PASSWORD mysql -h 127.0.0.1 -u $MYSQL_USER -D $MYSQL_DATABASE -e 'SELECT 1'`,
+			}}},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/var/lib/mysql/data"}},
+	}
+	switch db.Engine {
+	case MariaDB:
+		// The MariaDB databases of the pipelines have always kept their data at the root of the volume
+		container.VolumeMounts[0].MountPath = "/var/lib/mysql"
+	case PostgreSQL:
+		container.Ports = []corev1.ContainerPort{{Name: "postgresql", ContainerPort: postgreSQLPort, Protocol: corev1.ProtocolTCP}}
+		container.Env = []corev1.EnvVar{
+			utils.SecretEnv("POSTGRESQL_USER", db.SecretName, UsernameKey),
+			utils.SecretEnv("POSTGRESQL_PASSWORD", db.SecretName, PasswordKey),
+			utils.SecretEnv("POSTGRESQL_DATABASE", db.SecretName, DatabaseKey),
+		}
+		container.ReadinessProbe.Handler.Exec.Command = []string{
+			"/bin/sh", "-i", "-c", `pg_isready -h 127.0.0.1 -U $POSTGRESQL_USER -d $POSTGRESQL_DATABASE`,
+		}
+		container.VolumeMounts[0].MountPath = "/var/lib/pgsql/data"
+	}
+	podSpec := corev1.PodSpec{
+		PriorityClassName: operatorconfig.Current().PriorityClasses.ControlPlane.Name,
+		Containers:        []corev1.Container{container},
+		Volumes: []corev1.Volume{{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: db.Name},
+			},
+		}},
+	}
+	hooks, err := backupHooks(&podSpec, db.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := int32(1)
+	meta := db.objectMeta(db.Name)
+	meta.Annotations = map[string]string{strings.Join([]string{utils.KfDefAnnotation, utils.Backup}, "/"): string(db.Engine)}
+	return &appsv1.Deployment{
+		ObjectMeta: meta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: db.Labels},
+			// The volume of the database cannot be mounted by two pods
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: db.Labels, Annotations: hooks},
+				Spec:       podSpec,
+			},
+		},
+	}, nil
+}
+
+// Service returns the Service of db
+func (db *Database) Service() *corev1.Service {
+	port := db.Port()
+	name := "mysql"
+	if db.Engine == PostgreSQL {
+		name = "postgresql"
+	}
+	return &corev1.Service{
+		ObjectMeta: db.objectMeta(db.Name),
+		Spec: corev1.ServiceSpec{
+			Selector: db.Labels,
+			Ports: []corev1.ServicePort{{
+				Name: name, Port: port, TargetPort: intstr.FromInt(int(port)), Protocol: corev1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// CheckConnection returns an error if no TCP connection can be established to the database at host and port
+func CheckConnection(host string, port string) error {
+	address := net.JoinHostPort(host, port)
+	conn, err := net.DialTimeout("tcp", address, DialTimeout)
+	if err != nil {
+		return fmt.Errorf("database %v is unreachable: %v", address, err)
+	}
+	return conn.Close()
+}
+
+// objectMeta returns the metadata of a resource of db named name
+func (db *Database) objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: db.Namespace, Labels: utils.WithManagedLabel(db.Labels)}
+}
+
+// backupHooks returns the Velero annotations dumping the database of the engine run by podSpec
+func backupHooks(podSpec *corev1.PodSpec, engine Engine) (map[string]string, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(podSpec)
+	if err != nil {
+		return nil, err
+	}
+	return kustomize.BackupHooks(obj, string(engine))
+}
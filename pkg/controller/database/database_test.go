@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestDeployment(t *testing.T) {
+	type testCase struct {
+		Engine    Engine
+		Port      int32
+		MountPath string
+		Env       string
+	}
+	testCases := []testCase{
+		{Engine: MariaDB, Port: 3306, MountPath: "/var/lib/mysql", Env: "MYSQL_PASSWORD"},
+		{Engine: MySQL, Port: 3306, MountPath: "/var/lib/mysql/data", Env: "MYSQL_PASSWORD"},
+		{Engine: PostgreSQL, Port: 5432, MountPath: "/var/lib/pgsql/data", Env: "POSTGRESQL_PASSWORD"},
+	}
+	for _, c := range testCases {
+		db := &Database{
+			Name:        "db-sample",
+			SecretName:  "db-sample-connection",
+			Namespace:   "project",
+			Labels:      map[string]string{"component": "database"},
+			Engine:      c.Engine,
+			Image:       "image",
+			StorageSize: resource.MustParse("5Gi"),
+		}
+		deployment, err := db.Deployment()
+		if err != nil {
+			t.Fatalf("Failed to build the Deployment of %v: %v", c.Engine, err)
+		}
+		container := deployment.Spec.Template.Spec.Containers[0]
+		if container.Ports[0].ContainerPort != c.Port || db.Service().Spec.Ports[0].Port != c.Port {
+			t.Errorf("Expected %v to listen on %v, got %v", c.Engine, c.Port, container.Ports[0].ContainerPort)
+		}
+		if container.VolumeMounts[0].MountPath != c.MountPath {
+			t.Errorf("Expected %v to mount its volume at %v, got %v", c.Engine, c.MountPath, container.VolumeMounts[0].MountPath)
+		}
+		found := false
+		for _, e := range container.Env {
+			found = found || (e.Name == c.Env && e.ValueFrom.SecretKeyRef.Name == "db-sample-connection")
+		}
+		if !found {
+			t.Errorf("Expected %v to read %v from the connection Secret", c.Engine, c.Env)
+		}
+		if deployment.Annotations["kfctl.kubeflow.io/backup"] != string(c.Engine) {
+			t.Errorf("Expected the Deployment of %v to be annotated for backups, got %v", c.Engine, deployment.Annotations)
+		}
+		hooks := deployment.Spec.Template.Annotations
+		if hooks["backup.velero.io/backup-volumes"] != "data" ||
+			!strings.Contains(hooks["pre.hook.backup.velero.io/command"], c.MountPath+"/velero-backup.sql") {
+			t.Errorf("Expected %v to be dumped to its volume before the backups, got %v", c.Engine, hooks)
+		}
+		if size := db.Claim().Spec.Resources.Requests["storage"]; size.String() != "5Gi" {
+			t.Errorf("Expected a claim of 5Gi, got %v", size.String())
+		}
+	}
+}
+
+func TestCheckConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if err := CheckConnection(host, port); err != nil {
+		t.Errorf("Expected the database to be reachable, got %v", err)
+	}
+	listener.Close()
+	if err := CheckConnection(host, port); err == nil {
+		t.Errorf("Expected a closed port to be unreachable")
+	}
+}
+
+func TestProvisionPassesPredicate(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "project", UID: "uid"}}
+	db := &Database{
+		Name:        "db-sample",
+		SecretName:  "db-sample-connection",
+		Namespace:   "project",
+		Labels:      map[string]string{"component": "database"},
+		Engine:      MariaDB,
+		Image:       "image",
+		StorageSize: resource.MustParse("5Gi"),
+	}
+	if err := Provision(c, scheme.Scheme, owner, db); err != nil {
+		t.Fatalf("Failed to provision the database: %v", err)
+	}
+
+	key := client.ObjectKey{Namespace: "project", Name: "db-sample"}
+	provisioned := []struct {
+		key client.ObjectKey
+		obj runtime.Object
+	}{
+		{key: client.ObjectKey{Namespace: "project", Name: "db-sample-connection"}, obj: &corev1.Secret{}},
+		{key: key, obj: &corev1.PersistentVolumeClaim{}},
+		{key: key, obj: &appsv1.Deployment{}},
+		{key: key, obj: &corev1.Service{}},
+	}
+	for _, p := range provisioned {
+		if err := c.Get(context.TODO(), p.key, p.obj); err != nil {
+			t.Fatalf("Failed to get %T %v: %v", p.obj, p.key, err)
+		}
+		meta := p.obj.(metav1.Object)
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: meta, Object: p.obj}) {
+			t.Errorf("Expected the events of %T %v to pass the predicate of the watches, got labels %v", p.obj, p.key, meta.GetLabels())
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Provision creates or updates the connection Secret, the volume, the Deployment and the Service of db, controlled
+// by owner
+func Provision(c client.Client, scheme *runtime.Scheme, owner metav1.Object, db *Database) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: db.SecretName, Namespace: db.Namespace}}
+	err := createOrUpdate(c, scheme, owner, secret, func() error {
+		secret.Labels = utils.WithManagedLabel(db.Labels)
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for k, v := range db.ConnectionData() {
+			secret.Data[k] = []byte(v)
+		}
+		// The password is generated once, it is kept by the database in its volume
+		if len(secret.Data[PasswordKey]) > 0 {
+			return nil
+		}
+		password, err := GeneratePassword()
+		if err != nil {
+			return err
+		}
+		secret.Data[PasswordKey] = []byte(password)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	desiredClaim := db.Claim()
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: db.Name, Namespace: db.Namespace}}
+	err = createOrUpdate(c, scheme, owner, claim, func() error {
+		claim.Labels = desiredClaim.Labels
+		// The spec of a claim is immutable once it is bound
+		if claim.CreationTimestamp.IsZero() {
+			claim.Spec = desiredClaim.Spec
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	desiredDeployment, err := db.Deployment()
+	if err != nil {
+		return err
+	}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: db.Name, Namespace: db.Namespace}}
+	err = createOrUpdate(c, scheme, owner, deployment, func() error {
+		deployment.Labels, deployment.Annotations = desiredDeployment.Labels, desiredDeployment.Annotations
+		// The selector of a Deployment is immutable
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = desiredDeployment.Spec.Selector
+		}
+		deployment.Spec.Replicas = desiredDeployment.Spec.Replicas
+		deployment.Spec.Strategy = desiredDeployment.Spec.Strategy
+		deployment.Spec.Template = desiredDeployment.Spec.Template
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	desiredService := db.Service()
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: db.Name, Namespace: db.Namespace}}
+	return createOrUpdate(c, scheme, owner, service, func() error {
+		service.Labels = desiredService.Labels
+		service.Spec.Selector = desiredService.Spec.Selector
+		service.Spec.Ports = desiredService.Spec.Ports
+		return nil
+	})
+}
+
+// Remove deletes the Deployment and the Service of db, once its owner uses an existing database. Its Secret and its
+// volume are kept with their data until the owner is deleted.
+func Remove(c client.Client, db *Database) error {
+	meta := metav1.ObjectMeta{Name: db.Name, Namespace: db.Namespace}
+	for _, obj := range []runtime.Object{&appsv1.Deployment{ObjectMeta: meta}, &corev1.Service{ObjectMeta: meta}} {
+		if err := c.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %T %v: %v", obj, meta.Name, err)
+		}
+	}
+	return nil
+}
+
+// Available returns true if the Deployment of db has an available replica
+func Available(c client.Client, db *Database) (bool, error) {
+	deployment := &appsv1.Deployment{}
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: db.Namespace, Name: db.Name}, deployment)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return deployment.Status.AvailableReplicas > 0, nil
+}
+
+// createOrUpdate creates obj, or updates the live object, after mutating it with mutate and making owner its
+// controller
+func createOrUpdate(c client.Client, scheme *runtime.Scheme, owner metav1.Object, obj runtime.Object, mutate func() error) error {
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), c, obj, func() error {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(owner, obj.(metav1.Object), scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision %T %v: %v", obj, obj.(metav1.Object).GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("%T %v/%v of the database of %v %v.", obj, owner.GetNamespace(), obj.(metav1.Object).GetName(),
+			owner.GetName(), result)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package dataconnection
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	dcv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/dataconnection/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddToManager adds the DataConnection controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("dataconnection-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileDataConnection{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("dataconnection-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for dataconnection.")
+	c, err := controller.New("dataconnection-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource DataConnection
+	err = c.Watch(&source.Kind{Type: &dcv1alpha1.DataConnection{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the Secrets and the probe Jobs and requeue the owner DataConnection, so that the Secrets
+	// edited or deleted by hand are restored and the Ready condition follows the probes
+	for _, t := range []runtime.Object{&corev1.Secret{}, &batchv1.Job{}} {
+		err := c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &dcv1alpha1.DataConnection{},
+		}, kfutils.ManagedPredicate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to the credentials Secrets, which are not owned by the connections
+	kubeclient := mgr.GetClient()
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			connections := &dcv1alpha1.DataConnectionList{}
+			if err := kubeclient.List(context.TODO(), connections, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+				log.Warnf("Failed to list the DataConnections of namespace %v: %v", a.Meta.GetNamespace(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, connection := range connections.Items {
+				if connection.Spec.CredentialsSecret.SecretName == a.Meta.GetName() {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: connection.Name, Namespace: connection.Namespace},
+					})
+				}
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileDataConnection implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDataConnection{}
+
+// ReconcileDataConnection reconciles a DataConnection object by verifying its bucket
+type ReconcileDataConnection struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile creates the Secret of a DataConnection from its credentials Secret, and probes its bucket with a Job
+// whenever the connection or its credentials change. The Ready condition is true, and the Secret listed by the
+// dashboard, once the bucket is reachable. The Secret and the Jobs are owned by the DataConnection and garbage
+// collected with it.
+func (r *ReconcileDataConnection) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling DataConnection. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &dcv1alpha1.DataConnection{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// Invalid specs and credentials are not retried, they are reconciled again once they are fixed
+	if err := validate(&instance.Spec); err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "InvalidSpec", "%v", err)
+		return reconcile.Result{}, r.updateStatus(instance, "", corev1.ConditionFalse, "InvalidSpec", err.Error())
+	}
+	credentials := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: instance.Namespace, Name: instance.Spec.CredentialsSecret.SecretName}
+	if err := r.client.Get(context.TODO(), key, credentials); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		message := fmt.Sprintf("credentials Secret %v not found", key.Name)
+		return reconcile.Result{}, r.updateStatus(instance, "", corev1.ConditionFalse, "CredentialsNotFound", message)
+	}
+	data, err := connectionData(instance, credentials)
+	if err != nil {
+		return reconcile.Result{}, r.updateStatus(instance, "", corev1.ConditionFalse, "InvalidCredentials", err.Error())
+	}
+
+	name := probeJobName(instance, data)
+	job := &batchv1.Job{}
+	err = r.client.Get(context.TODO(), client.ObjectKey{Namespace: instance.Namespace, Name: name}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	probed := err == nil
+	finished, failed, message := kfutils.JobFinished(job)
+
+	// The Secret is created before the Job, which reads the access keys from it
+	if err := r.applySecret(instance, connectionSecret(instance, data, finished && !failed)); err != nil {
+		return reconcile.Result{}, err
+	}
+	if !probed {
+		job = probeJob(instance, name)
+		if err := controllerutil.SetControllerReference(instance, job, r.scheme); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Infof("Probing the bucket of DataConnection %v with Job %v.", request.NamespacedName, name)
+		if err := r.client.Create(context.TODO(), job); err != nil && !errors.IsAlreadyExists(err) {
+			return reconcile.Result{}, fmt.Errorf("failed to create the probe Job %v: %v", name, err)
+		}
+	}
+
+	switch {
+	case failed:
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "ProbeFailed", "Job %v failed: %v", name, message)
+		return reconcile.Result{}, r.updateStatus(instance, secretName(instance), corev1.ConditionFalse, "ProbeFailed",
+			fmt.Sprintf("probe Job %v failed: %v, delete it to retry", name, message))
+	case !finished:
+		return reconcile.Result{}, r.updateStatus(instance, secretName(instance), corev1.ConditionFalse, "Probing",
+			fmt.Sprintf("probing the bucket with Job %v", name))
+	}
+
+	// The Job of the current probe is kept, so that it does not run again
+	jobs := &batchv1.JobList{}
+	err = r.client.List(context.TODO(), jobs, client.InNamespace(instance.Namespace),
+		client.MatchingLabels(labels(instance, "probe")))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	propagation := metav1.DeletePropagationBackground
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == name {
+			continue
+		}
+		err := r.client.Delete(context.TODO(), &jobs.Items[i], client.PropagationPolicy(propagation))
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("failed to delete the probe Job %v: %v", jobs.Items[i].Name, err)
+		}
+	}
+	return reconcile.Result{}, r.updateStatus(instance, secretName(instance), corev1.ConditionTrue, "Verified",
+		"the bucket is reachable with the credentials")
+}
+
+// applySecret creates the desired Secret of the connection, or updates the live one
+func (r *ReconcileDataConnection) applySecret(instance *dcv1alpha1.DataConnection, desired *corev1.Secret) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, secret, func() error {
+		secret.Labels, secret.Annotations = desired.Labels, desired.Annotations
+		secret.Type, secret.Data = desired.Type, desired.Data
+		return controllerutil.SetControllerReference(instance, secret, r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision Secret %v: %v", desired.Name, err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("Secret %v/%v of DataConnection %v %v.", instance.Namespace, desired.Name, instance.Name, result)
+	}
+	return nil
+}
+
+// updateStatus updates the Ready condition and the Secret of the status of instance if they changed
+func (r *ReconcileDataConnection) updateStatus(instance *dcv1alpha1.DataConnection, secret string, status corev1.ConditionStatus, reason string, message string) error {
+	desired := instance.Status.DeepCopy()
+	desired.ObservedGeneration = instance.Generation
+	desired.SecretName = secret
+	desired.Conditions = setCondition(desired.Conditions, dcv1alpha1.DataConnectionCondition{
+		Type:               dcv1alpha1.DataConnectionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+	if reflect.DeepEqual(instance.Status, *desired) {
+		return nil
+	}
+	instance.Status = *desired
+	return r.client.Status().Update(context.TODO(), instance)
+}
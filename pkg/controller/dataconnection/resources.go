@@ -0,0 +1,181 @@
+package dataconnection
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"sort"
+
+	dcv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/dataconnection/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultRegion       = "us-east-1"
+	defaultAccessKeyKey = "AWS_ACCESS_KEY_ID"
+	defaultSecretKeyKey = "AWS_SECRET_ACCESS_KEY"
+	probeBackoff        = 2
+	probeDeadline       = 120
+
+	// The keys of the Secret of a connection, as read by the dashboard and the workbenches
+	accessKeyKey = "AWS_ACCESS_KEY_ID"
+	secretKeyKey = "AWS_SECRET_ACCESS_KEY"
+	endpointKey  = "AWS_S3_ENDPOINT"
+	regionKey    = "AWS_DEFAULT_REGION"
+	bucketKey    = "AWS_S3_BUCKET"
+
+	// The labels and the annotations the dashboard lists the connections of a project with
+	dashboardLabel           = "opendatahub.io/dashboard"
+	managedLabel             = "opendatahub.io/managed"
+	connectionTypeAnnotation = "opendatahub.io/connection-type"
+	displayNameAnnotation    = "openshift.io/display-name"
+
+	dataConnectionNameLabel = "dataconnection.opendatahub.io/dataconnection"
+	componentLabel          = "component"
+)
+
+// ProbeImage is the image of the AWS CLI the Jobs probing the buckets run
+var ProbeImage = "docker.io/amazon/aws-cli:2.15.0"
+
+// validate returns an error describing the first invalid field of the spec
+func validate(spec *dcv1alpha1.DataConnectionSpec) error {
+	switch {
+	case spec.Endpoint == "":
+		return fmt.Errorf("endpoint is required")
+	case spec.Bucket == "":
+		return fmt.Errorf("bucket is required")
+	case spec.CredentialsSecret.SecretName == "":
+		return fmt.Errorf("credentialsSecret.secretName is required")
+	}
+	endpoint, err := url.Parse(spec.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("endpoint must be an http or https URL, got %q", spec.Endpoint)
+	}
+	return nil
+}
+
+// secretName returns the name of the Secret of the connection listed by the dashboard
+func secretName(instance *dcv1alpha1.DataConnection) string {
+	return "aws-connection-" + instance.Name
+}
+
+// labels returns the labels of the resources of component
+func labels(instance *dcv1alpha1.DataConnection, component string) map[string]string {
+	return map[string]string{dataConnectionNameLabel: instance.Name, componentLabel: component}
+}
+
+// connectionData returns the data of the Secret of the connection, with the access keys read from the credentials
+// Secret
+func connectionData(instance *dcv1alpha1.DataConnection, credentials *corev1.Secret) (map[string][]byte, error) {
+	accessKey, secretKey := instance.Spec.CredentialsSecret.AccessKey, instance.Spec.CredentialsSecret.SecretKey
+	if accessKey == "" {
+		accessKey = defaultAccessKeyKey
+	}
+	if secretKey == "" {
+		secretKey = defaultSecretKeyKey
+	}
+	for _, key := range []string{accessKey, secretKey} {
+		if len(credentials.Data[key]) == 0 {
+			return nil, fmt.Errorf("credentials Secret %v has no %v", credentials.Name, key)
+		}
+	}
+	region := instance.Spec.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	return map[string][]byte{
+		accessKeyKey: credentials.Data[accessKey],
+		secretKeyKey: credentials.Data[secretKey],
+		endpointKey:  []byte(instance.Spec.Endpoint),
+		regionKey:    []byte(region),
+		bucketKey:    []byte(instance.Spec.Bucket),
+	}, nil
+}
+
+// connectionSecret returns the Secret of the connection holding data. It is only listed by the dashboard once the
+// bucket is verified.
+func connectionSecret(instance *dcv1alpha1.DataConnection, data map[string][]byte, verified bool) *corev1.Secret {
+	secretLabels := kfutils.WithManagedLabel(labels(instance, "connection"))
+	secretLabels[dashboardLabel] = fmt.Sprint(verified)
+	secretLabels[managedLabel] = "true"
+	displayName := instance.Spec.DisplayName
+	if displayName == "" {
+		displayName = instance.Name
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(instance),
+			Namespace: instance.Namespace,
+			Labels:    secretLabels,
+			Annotations: map[string]string{
+				connectionTypeAnnotation: "s3",
+				displayNameAnnotation:    displayName,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// probeJobName returns the name of the Job probing the bucket of instance. It changes with the data of the
+// connection and the image of the probe, so that the bucket is probed again when either changes.
+func probeJobName(instance *dcv1alpha1.DataConnection, data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	hash.Write([]byte(ProbeImage))
+	for _, k := range keys {
+		fmt.Fprintf(hash, "\n%v=%x", k, data[k])
+	}
+	return fmt.Sprintf("data-connection-%v-probe-%x", instance.Name, hash.Sum(nil)[:5])
+}
+
+// probeJob returns the Job checking that the bucket of the connection exists and is readable with its access keys
+func probeJob(instance *dcv1alpha1.DataConnection, name string) *batchv1.Job {
+	backoff, deadline := int32(probeBackoff), int64(probeDeadline)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace, Labels: kfutils.WithManagedLabel(labels(instance, "probe"))},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoff,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels(instance, "probe")},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "probe",
+						Image:   ProbeImage,
+						Command: []string{"aws"},
+						Args: []string{"s3api", "head-bucket", "--bucket", "$(" + bucketKey + ")",
+							"--endpoint-url", "$(" + endpointKey + ")"},
+						EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: secretName(instance)},
+						}}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// setCondition sets the condition of its type in conditions, keeping its last transition time unless its status
+// changes
+func setCondition(conditions []dcv1alpha1.DataConnectionCondition, condition dcv1alpha1.DataConnectionCondition) []dcv1alpha1.DataConnectionCondition {
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		if conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
@@ -0,0 +1,126 @@
+package dataconnection
+
+import (
+	"testing"
+	"time"
+
+	dcv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/dataconnection/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestValidate(t *testing.T) {
+	credentials := dcv1alpha1.CredentialsSecret{SecretName: "minio"}
+	type testCase struct {
+		Name  string
+		Spec  dcv1alpha1.DataConnectionSpec
+		Valid bool
+	}
+	testCases := []testCase{
+		{
+			Name:  "minio",
+			Spec:  dcv1alpha1.DataConnectionSpec{Endpoint: "http://minio.example.com:9000", Bucket: "models", CredentialsSecret: credentials},
+			Valid: true,
+		},
+		{
+			Name: "no bucket",
+			Spec: dcv1alpha1.DataConnectionSpec{Endpoint: "https://s3.amazonaws.com", CredentialsSecret: credentials},
+		},
+		{
+			Name: "endpoint without scheme",
+			Spec: dcv1alpha1.DataConnectionSpec{Endpoint: "s3.amazonaws.com", Bucket: "models", CredentialsSecret: credentials},
+		},
+		{
+			Name: "no credentials",
+			Spec: dcv1alpha1.DataConnectionSpec{Endpoint: "https://s3.amazonaws.com", Bucket: "models"},
+		},
+	}
+
+	for _, c := range testCases {
+		err := validate(&c.Spec)
+		if c.Valid && err != nil {
+			t.Errorf("Case %v: expected the spec to be valid, got %v", c.Name, err)
+		}
+		if !c.Valid && err == nil {
+			t.Errorf("Case %v: expected the spec to be invalid", c.Name)
+		}
+	}
+}
+
+func TestConnectionData(t *testing.T) {
+	instance := &dcv1alpha1.DataConnection{
+		ObjectMeta: metav1.ObjectMeta{Name: "models", Namespace: "project"},
+		Spec: dcv1alpha1.DataConnectionSpec{
+			Endpoint:          "https://s3.amazonaws.com",
+			Bucket:            "models",
+			CredentialsSecret: dcv1alpha1.CredentialsSecret{SecretName: "aws", AccessKey: "id", SecretKey: "key"},
+		},
+	}
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws"},
+		Data:       map[string][]byte{"id": []byte("AKIA"), "key": []byte("secret")},
+	}
+	data, err := connectionData(instance, credentials)
+	if err != nil {
+		t.Fatalf("Failed to read the credentials: %v", err)
+	}
+	if string(data[accessKeyKey]) != "AKIA" || string(data[secretKeyKey]) != "secret" || string(data[regionKey]) != defaultRegion {
+		t.Errorf("Unexpected data of the connection %v", data)
+	}
+
+	name := probeJobName(instance, data)
+	if name != probeJobName(instance, data) {
+		t.Errorf("Expected the name of the probe Job to be stable")
+	}
+	rotated, _ := connectionData(instance, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws"},
+		Data:       map[string][]byte{"id": []byte("AKIA"), "key": []byte("rotated")},
+	})
+	if probeJobName(instance, rotated) == name {
+		t.Errorf("Expected a new probe Job for new credentials")
+	}
+
+	secret := connectionSecret(instance, data, false)
+	if secret.Labels[dashboardLabel] != "false" || secret.Annotations[connectionTypeAnnotation] != "s3" {
+		t.Errorf("Expected an unverified S3 connection, got %v %v", secret.Labels, secret.Annotations)
+	}
+	if secret := connectionSecret(instance, data, true); secret.Labels[dashboardLabel] != "true" {
+		t.Errorf("Expected the verified connection to be listed by the dashboard, got %v", secret.Labels)
+	}
+
+	delete(credentials.Data, "key")
+	if _, err := connectionData(instance, credentials); err == nil {
+		t.Errorf("Expected credentials without secret access key to be invalid")
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	conditions := []dcv1alpha1.DataConnectionCondition{{
+		Type: dcv1alpha1.DataConnectionReady, Status: corev1.ConditionFalse, Reason: "Probing", LastTransitionTime: past,
+	}}
+	conditions = setCondition(conditions, dcv1alpha1.DataConnectionCondition{
+		Type: dcv1alpha1.DataConnectionReady, Status: corev1.ConditionFalse, Reason: "ProbeFailed", LastTransitionTime: metav1.Now(),
+	})
+	if len(conditions) != 1 || conditions[0].Reason != "ProbeFailed" || !conditions[0].LastTransitionTime.Equal(&past) {
+		t.Errorf("Expected the reason to change without transition, got %v", conditions)
+	}
+	conditions = setCondition(conditions, dcv1alpha1.DataConnectionCondition{
+		Type: dcv1alpha1.DataConnectionReady, Status: corev1.ConditionTrue, Reason: "Verified", LastTransitionTime: metav1.Now(),
+	})
+	if conditions[0].LastTransitionTime.Equal(&past) {
+		t.Errorf("Expected a transition to Ready")
+	}
+}
+
+func TestResourcesPassPredicate(t *testing.T) {
+	instance := &dcv1alpha1.DataConnection{ObjectMeta: metav1.ObjectMeta{Name: "models", Namespace: "project"}}
+	objects := []metav1.Object{connectionSecret(instance, map[string][]byte{}, false), probeJob(instance, "models-probe")}
+	for _, obj := range objects {
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: obj}) {
+			t.Errorf("Expected the events of %v to pass the predicate of the watches, got labels %v", obj.GetName(), obj.GetLabels())
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// alertmanagerConfigCRD is installed by the Prometheus operator
+	alertmanagerConfigCRD = "alertmanagerconfigs.monitoring.coreos.com"
+	// alertmanagerConfigKey is the key of the Secret of the monitoring holding the routes and the receivers
+	alertmanagerConfigKey = "alertmanager.yaml"
+)
+
+var (
+	// PrometheusRuleGVK is the kind of the alerting rules of the Prometheus operator
+	PrometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+	// AlertmanagerConfigGVK is the kind of the routes and receivers of the Alertmanager of the Prometheus operator
+	AlertmanagerConfigGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1alpha1", Kind: "AlertmanagerConfig"}
+)
+
+// alert is a default alert on the metrics of the KfDefs generated for a DataScienceCluster. Its expression is
+// formatted with the regular expression matching their names.
+type alert struct {
+	name     string
+	expr     string
+	duration string
+	severity string
+	summary  string
+}
+
+// defaultAlerts are deployed with the monitoring unless disabled
+var defaultAlerts = []alert{
+	{
+		name:     "ODHComponentDown",
+		expr:     `kfdef_component_ready{kfdef=~"%v"} == 0`,
+		duration: "10m",
+		severity: "critical",
+		summary:  "Component {{ $labels.component }} of KfDef {{ $labels.kfdef }} is not available.",
+	},
+	{
+		name:     "ODHReconcileFailing",
+		expr:     `increase(kfdef_component_reconcile_errors_total{kfdef=~"%v"}[5m]) > 0`,
+		duration: "15m",
+		severity: "warning",
+		summary:  "Component {{ $labels.component }} of KfDef {{ $labels.kfdef }} fails to be applied.",
+	},
+	{
+		name:     "ODHCertificateExpiring",
+		expr:     `kfdef_certificate_expiry_timestamp_seconds{kfdef=~"%v"} - time() < 7 * 24 * 3600`,
+		duration: "1h",
+		severity: "warning",
+		summary:  "Serving certificate {{ $labels.secret }} of KfDef {{ $labels.kfdef }} expires within 7 days.",
+	},
+}
+
+// alertRules returns the rules of the default alerts on the KfDefs of the DataScienceCluster, except the disabled
+// ones
+func alertRules(instance *dscv1alpha1.DataScienceCluster) []interface{} {
+	disabled := map[string]bool{}
+	for _, name := range instance.Spec.Components.Monitoring.DisabledAlerts {
+		disabled[name] = true
+	}
+	kfdefs := instance.Name + "-.+"
+	rules := []interface{}{}
+	for _, a := range defaultAlerts {
+		if disabled[a.name] {
+			delete(disabled, a.name)
+			continue
+		}
+		rules = append(rules, map[string]interface{}{
+			"alert":       a.name,
+			"expr":        fmt.Sprintf(a.expr, kfdefs),
+			"for":         a.duration,
+			"labels":      map[string]interface{}{"severity": a.severity},
+			"annotations": map[string]interface{}{"summary": a.summary},
+		})
+	}
+	unknown := []string{}
+	for name := range disabled {
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		log.Warnf("DataScienceCluster %v disables the unknown alerts %v.", instance.Name, unknown)
+	}
+	return rules
+}
+
+// alertmanagerConfigSpec returns the spec of the AlertmanagerConfig from the content of the alertmanager.yaml key
+// of the Secret of the monitoring
+func alertmanagerConfigSpec(data []byte) (map[string]interface{}, error) {
+	spec := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid %v: %v", alertmanagerConfigKey, err)
+	}
+	for key := range spec {
+		if key != "route" && key != "receivers" && key != "inhibitRules" {
+			return nil, fmt.Errorf("invalid %v: unknown field %v, only route, receivers and inhibitRules are allowed", alertmanagerConfigKey, key)
+		}
+	}
+	if _, ok := spec["receivers"]; !ok {
+		return nil, fmt.Errorf("invalid %v: it has no receivers", alertmanagerConfigKey)
+	}
+	return spec, nil
+}
+
+// reconcileAlerting creates the PrometheusRule of the default alerts that are not disabled, and the
+// AlertmanagerConfig routing them from the Secret of the monitoring if it is set
+func reconcileAlerting(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	rule := alertingResource(instance, PrometheusRuleGVK, "-alerts")
+	rules := alertRules(instance)
+	if len(rules) == 0 {
+		if err := deleteAlertingResource(c, rule); err != nil {
+			return err
+		}
+	} else {
+		spec := map[string]interface{}{
+			"groups": []interface{}{
+				map[string]interface{}{"name": "open-data-hub", "rules": rules},
+			},
+		}
+		if err := applyAlertingResource(c, instance, rule, spec); err != nil {
+			return err
+		}
+	}
+
+	config := alertingResource(instance, AlertmanagerConfigGVK, "-alertmanager")
+	name := instance.Spec.Components.Monitoring.AlertmanagerConfigSecret
+	if name == "" {
+		return deleteAlertingResource(c, config)
+	}
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: instance.Namespace, Name: name}
+	if err := c.Get(context.TODO(), key, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return &blockedError{message: fmt.Sprintf("Secret %v of the Alertmanager configuration must be created first", key)}
+		}
+		return fmt.Errorf("failed to get Secret %v: %v", key, err)
+	}
+	spec, err := alertmanagerConfigSpec(secret.Data[alertmanagerConfigKey])
+	if err != nil {
+		return fmt.Errorf("Secret %v: %v", key, err)
+	}
+	return applyAlertingResource(c, instance, config, spec)
+}
+
+// removeAlerting deletes the PrometheusRule and the AlertmanagerConfig once the monitoring is removed
+func removeAlerting(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	if err := deleteAlertingResource(c, alertingResource(instance, PrometheusRuleGVK, "-alerts")); err != nil {
+		return err
+	}
+	return deleteAlertingResource(c, alertingResource(instance, AlertmanagerConfigGVK, "-alertmanager"))
+}
+
+// alertingResource returns the resource of the kind, named after the DataScienceCluster with the suffix
+func alertingResource(instance *dscv1alpha1.DataScienceCluster, gvk schema.GroupVersionKind, suffix string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(instance.Namespace)
+	obj.SetName(instance.Name + suffix)
+	return obj
+}
+
+// applyAlertingResource creates obj with the spec, owned by the DataScienceCluster, or updates its spec
+func applyAlertingResource(c client.Client, instance *dscv1alpha1.DataScienceCluster, obj *unstructured.Unstructured, spec map[string]interface{}) error {
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	err := c.Get(context.TODO(), key, obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %v %v: %v", obj.GetKind(), key, err)
+	}
+	if errors.IsNotFound(err) {
+		obj.Object["spec"] = spec
+		obj.SetOwnerReferences([]metav1.OwnerReference{
+			*metav1.NewControllerRef(instance, dscv1alpha1.SchemeGroupVersion.WithKind("DataScienceCluster")),
+		})
+		log.Infof("Creating %v %v.", obj.GetKind(), key)
+		return c.Create(context.TODO(), obj)
+	}
+	if reflect.DeepEqual(obj.Object["spec"], spec) {
+		return nil
+	}
+	obj.Object["spec"] = spec
+	log.Infof("Updating %v %v.", obj.GetKind(), key)
+	return c.Update(context.TODO(), obj)
+}
+
+// deleteAlertingResource deletes obj if it exists. It is a no-op when its CRD is not installed.
+func deleteAlertingResource(c client.Client, obj *unstructured.Unstructured) error {
+	err := c.Delete(context.TODO(), obj)
+	if err == nil {
+		log.Infof("Deleted %v %v/%v.", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		return nil
+	}
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	return fmt.Errorf("failed to delete %v %v/%v: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+}
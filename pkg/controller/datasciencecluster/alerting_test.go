@@ -0,0 +1,92 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAlertRules(t *testing.T) {
+	instance := &dscv1alpha1.DataScienceCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "opendatahub"},
+		Spec: dscv1alpha1.DataScienceClusterSpec{
+			Components: dscv1alpha1.Components{
+				Monitoring: dscv1alpha1.Monitoring{
+					ManagementState: dscv1alpha1.Managed,
+					DisabledAlerts:  []string{"ODHCertificateExpiring", "ODHUnknown"},
+				},
+			},
+		},
+	}
+	rules := alertRules(instance)
+	names := []string{}
+	for _, rule := range rules {
+		names = append(names, rule.(map[string]interface{})["alert"].(string))
+	}
+	if !reflect.DeepEqual(names, []string{"ODHComponentDown", "ODHReconcileFailing"}) {
+		t.Errorf("Expected the certificate alert to be disabled, got %v", names)
+	}
+	expr := rules[0].(map[string]interface{})["expr"]
+	if expr != `kfdef_component_ready{kfdef=~"default-.+"} == 0` {
+		t.Errorf("Expected the alert to select the KfDefs of the DataScienceCluster, got %v", expr)
+	}
+}
+
+func TestAlertmanagerConfigSpec(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Data     string
+		Expected map[string]interface{}
+		Error    bool
+	}
+	testCases := []testCase{
+		{
+			Name: "valid",
+			Data: `
+route:
+  receiver: oncall
+  groupBy: [alertname]
+receivers:
+- name: oncall
+  emailConfigs:
+  - to: oncall@example.com
+`,
+			Expected: map[string]interface{}{
+				"route": map[string]interface{}{
+					"receiver": "oncall",
+					"groupBy":  []interface{}{"alertname"},
+				},
+				"receivers": []interface{}{
+					map[string]interface{}{
+						"name":         "oncall",
+						"emailConfigs": []interface{}{map[string]interface{}{"to": "oncall@example.com"}},
+					},
+				},
+			},
+		},
+		{
+			Name:  "no receivers",
+			Data:  "route:\n  receiver: oncall\n",
+			Error: true,
+		},
+		{
+			Name:  "alertmanager.yaml format",
+			Data:  "global:\n  resolve_timeout: 5m\nreceivers:\n- name: oncall\n",
+			Error: true,
+		},
+	}
+	for _, c := range testCases {
+		spec, err := alertmanagerConfigSpec([]byte(c.Data))
+		if c.Error {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %+v", c.Name, spec)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(spec, c.Expected) {
+			t.Errorf("%v: expected the spec %+v, got %+v, %v", c.Name, c.Expected, spec, err)
+		}
+	}
+}
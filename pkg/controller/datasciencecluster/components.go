@@ -0,0 +1,204 @@
+package datasciencecluster
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// manifestsRepoName is the name of the repo every generated KfDef refers to
+	manifestsRepoName = "manifests"
+	// defaultManifestsURI is used when the DataScienceCluster does not set spec.manifestsUri
+	defaultManifestsURI = "https://github.com/opendatahub-io/odh-manifests/tarball/master"
+)
+
+// component maps a DataScienceCluster component to the odh-manifests applications it deploys
+type component struct {
+	name         string
+	enabled      func(c *dscv1alpha1.Components) bool
+	applications func(c *dscv1alpha1.Components) []kfdefv1.Application
+	// requiredCRDs returns the CRDs that have to be installed, e.g. by other operators, before the component is
+	// deployed. It is nil when the component requires none.
+	requiredCRDs func(c *dscv1alpha1.Components) []string
+	// dependencies creates or checks the resources of the operators the component depends on, once their CRDs
+	// are installed. It is nil when the component has none.
+	dependencies func(c client.Client, instance *dscv1alpha1.DataScienceCluster) error
+	// cleanup deletes the resources created by dependencies for the component only, once it is disabled. It is nil
+	// when the component creates none, or shares them with other operators.
+	cleanup func(c client.Client, instance *dscv1alpha1.DataScienceCluster) error
+	// features returns the feature gates the settings of the component rely on. It is nil when the component
+	// relies on none.
+	features func(c *dscv1alpha1.Components) []featuregates.Feature
+	// devFlags returns the dev flags of the component, overriding the source of its manifests
+	devFlags func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags
+}
+
+// components lists the known components in the order they are reconciled
+var components = []component{
+	{
+		name:         "dashboard",
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.Dashboard.Enabled },
+		applications: manifests("odh-common", "odh-dashboard"),
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.Dashboard.DevFlags },
+	},
+	{
+		name:         "workbenches",
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.Workbenches.Enabled },
+		applications: manifests("odh-notebook-controller", "notebooks"),
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.Workbenches.DevFlags },
+	},
+	{
+		name:         "datasciencepipelines",
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.DataSciencePipelines.Enabled },
+		applications: manifests("data-science-pipelines-operator"),
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.DataSciencePipelines.DevFlags },
+	},
+	{
+		name:         "modelserving",
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.ModelServing.Enabled },
+		applications: modelServingApplications,
+		requiredCRDs: modelServingCRDs,
+		dependencies: reconcileKnativeServing,
+		features:     modelServingFeatures,
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.ModelServing.DevFlags },
+	},
+	{
+		name:         trustyAIName,
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.TrustyAI.Enabled },
+		applications: manifests("trustyai-service-operator"),
+		dependencies: checkPayloadLogging,
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.TrustyAI.DevFlags },
+	},
+	{
+		name:         kueueName,
+		enabled:      kueueEnabled,
+		applications: kueueApplications,
+		requiredCRDs: kueueCRDs,
+		dependencies: reconcileKueue,
+		cleanup:      removeKueue,
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.Kueue.DevFlags },
+	},
+	{
+		name:         workloadsName,
+		enabled:      func(c *dscv1alpha1.Components) bool { return c.Workloads.Enabled },
+		applications: workloadsApplications,
+		requiredCRDs: workloadsCRDs,
+		dependencies: reconcileRayClusterTemplates,
+		cleanup:      removeRayClusterTemplates,
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.Workloads.DevFlags },
+	},
+}
+
+// manifests returns the applications deploying the odh-manifests paths of the same names
+func manifests(paths ...string) func(c *dscv1alpha1.Components) []kfdefv1.Application {
+	return func(_ *dscv1alpha1.Components) []kfdefv1.Application {
+		apps := []kfdefv1.Application{}
+		for _, path := range paths {
+			apps = append(apps, application(path, path))
+		}
+		return apps
+	}
+}
+
+// application returns the application deploying an odh-manifests path with the overlays
+func application(name, path string, overlays ...string) kfdefv1.Application {
+	return kfdefv1.Application{
+		Name: name,
+		KustomizeConfig: &kfdefv1.KustomizeConfig{
+			RepoRef:  &kfdefv1.RepoRef{Name: manifestsRepoName, Path: path},
+			Overlays: overlays,
+		},
+	}
+}
+
+// kfDefName returns the name of the KfDef generated for the component
+func (c component) kfDefName(instance *dscv1alpha1.DataScienceCluster) string {
+	return instance.Name + "-" + c.name
+}
+
+// kfDefSpec returns the KfDef spec that deploys the component
+func (c component) kfDefSpec(instance *dscv1alpha1.DataScienceCluster) kfdefv1.KfDefSpec {
+	uri := instance.Spec.ManifestsURI
+	if uri == "" {
+		uri = defaultManifestsURI
+	}
+	spec := kfdefv1.KfDefSpec{
+		Applications: c.applications(&instance.Spec.Components),
+		Repos:        []kfdefv1.Repo{{Name: manifestsRepoName, URI: uri}},
+		FeatureGates: instance.Spec.FeatureGates,
+	}
+	if c.devFlags != nil {
+		overrideManifests(&spec, c.devFlags(&instance.Spec.Components))
+	}
+	return spec
+}
+
+// overrideManifests points the applications of the spec matching the sourcePath of a manifests entry of the dev
+// flags at the contextDir of its URI, the first matching entry wins. A repo is added for every entry in use.
+func overrideManifests(spec *kfdefv1.KfDefSpec, flags *dscv1alpha1.DevFlags) {
+	if flags == nil {
+		return
+	}
+	for i, m := range flags.Manifests {
+		repo := fmt.Sprintf("%v-devflags-%d", manifestsRepoName, i)
+		used := false
+		for j := range spec.Applications {
+			app := &spec.Applications[j]
+			if app.KustomizeConfig == nil || app.KustomizeConfig.RepoRef == nil ||
+				app.KustomizeConfig.RepoRef.Name != manifestsRepoName {
+				continue
+			}
+			appPath := app.KustomizeConfig.RepoRef.Path
+			if m.SourcePath != "" && appPath != m.SourcePath && !strings.HasPrefix(appPath, m.SourcePath+"/") {
+				continue
+			}
+			app.KustomizeConfig = app.KustomizeConfig.DeepCopy()
+			app.KustomizeConfig.RepoRef.Name = repo
+			app.KustomizeConfig.RepoRef.Path = path.Join(m.ContextDir, appPath)
+			used = true
+		}
+		if used {
+			spec.Repos = append(spec.Repos, kfdefv1.Repo{Name: repo, URI: m.URI})
+		}
+	}
+}
+
+// disabledFeatures returns the feature gates the settings of the component rely on that are disabled
+func (c component) disabledFeatures(gates *featuregates.FeatureGates, components *dscv1alpha1.Components) []string {
+	if c.features == nil {
+		return nil
+	}
+	disabled := []string{}
+	for _, f := range c.features(components) {
+		if !gates.Enabled(f) {
+			disabled = append(disabled, string(f))
+		}
+	}
+	return disabled
+}
+
+// missingCRDs returns the CRDs required by the component that are not installed
+func (c component) missingCRDs(crdClient crdclientset.CustomResourceDefinitionsGetter, components *dscv1alpha1.Components) ([]string, error) {
+	if c.requiredCRDs == nil {
+		return nil, nil
+	}
+	missing := []string{}
+	for _, name := range c.requiredCRDs(components) {
+		if _, err := crdClient.CustomResourceDefinitions().Get(name, metav1.GetOptions{}); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get CRD %v: %v", name, err)
+			}
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,86 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDevFlags(t *testing.T) {
+	devRepo := func(name, path string) *kfdefv1.KustomizeConfig {
+		return &kfdefv1.KustomizeConfig{RepoRef: &kfdefv1.RepoRef{Name: name, Path: path}}
+	}
+	type testCase struct {
+		Name     string
+		DevFlags *dscv1alpha1.DevFlags
+		Repos    []kfdefv1.Repo
+		Expected map[string]*kfdefv1.KustomizeConfig
+	}
+	testCases := []testCase{
+		{
+			Name: "release manifests",
+			Repos: []kfdefv1.Repo{
+				{Name: manifestsRepoName, URI: defaultManifestsURI},
+			},
+			Expected: map[string]*kfdefv1.KustomizeConfig{
+				"odh-common":    devRepo(manifestsRepoName, "odh-common"),
+				"odh-dashboard": devRepo(manifestsRepoName, "odh-dashboard"),
+			},
+		},
+		{
+			Name: "single application",
+			DevFlags: &dscv1alpha1.DevFlags{Manifests: []dscv1alpha1.ManifestsConfig{{
+				URI:        "https://github.com/me/odh-dashboard/tarball/my-branch",
+				ContextDir: "manifests",
+				SourcePath: "odh-dashboard",
+			}}},
+			Repos: []kfdefv1.Repo{
+				{Name: manifestsRepoName, URI: defaultManifestsURI},
+				{Name: "manifests-devflags-0", URI: "https://github.com/me/odh-dashboard/tarball/my-branch"},
+			},
+			Expected: map[string]*kfdefv1.KustomizeConfig{
+				"odh-common":    devRepo(manifestsRepoName, "odh-common"),
+				"odh-dashboard": devRepo("manifests-devflags-0", "manifests/odh-dashboard"),
+			},
+		},
+		{
+			Name: "first match wins",
+			DevFlags: &dscv1alpha1.DevFlags{Manifests: []dscv1alpha1.ManifestsConfig{
+				{URI: "https://example.com/common.tar.gz", SourcePath: "odh-common"},
+				{URI: "https://example.com/all.tar.gz"},
+				{URI: "https://example.com/unused.tar.gz", SourcePath: "odh-dashboard"},
+			}},
+			Repos: []kfdefv1.Repo{
+				{Name: manifestsRepoName, URI: defaultManifestsURI},
+				{Name: "manifests-devflags-0", URI: "https://example.com/common.tar.gz"},
+				{Name: "manifests-devflags-1", URI: "https://example.com/all.tar.gz"},
+			},
+			Expected: map[string]*kfdefv1.KustomizeConfig{
+				"odh-common":    devRepo("manifests-devflags-0", "odh-common"),
+				"odh-dashboard": devRepo("manifests-devflags-1", "odh-dashboard"),
+			},
+		},
+	}
+	dashboard := componentNamed(t, "dashboard")
+	for _, c := range testCases {
+		instance := &dscv1alpha1.DataScienceCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: dscv1alpha1.DataScienceClusterSpec{Components: dscv1alpha1.Components{
+				Dashboard: dscv1alpha1.Component{Enabled: true, DevFlags: c.DevFlags},
+			}},
+		}
+		spec := dashboard.kfDefSpec(instance)
+		if !reflect.DeepEqual(spec.Repos, c.Repos) {
+			t.Errorf("%v: expected the repos %+v, got %+v", c.Name, c.Repos, spec.Repos)
+		}
+		for _, app := range spec.Applications {
+			if !reflect.DeepEqual(app.KustomizeConfig, c.Expected[app.Name]) {
+				t.Errorf("%v: expected application %v to refer to %+v, got %+v", c.Name, app.Name,
+					c.Expected[app.Name].RepoRef, app.KustomizeConfig.RepoRef)
+			}
+		}
+	}
+}
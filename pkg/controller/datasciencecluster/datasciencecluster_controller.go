@@ -0,0 +1,352 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddToManager adds the DataScienceCluster controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("datasciencecluster-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileDataScienceCluster{
+		client:    mgr.GetClient(),
+		crdClient: crdclientset.NewForConfigOrDie(mgr.GetConfig()),
+		scheme:    mgr.GetScheme(),
+		recorder:  mgr.GetEventRecorderFor("datasciencecluster-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for datasciencecluster.")
+	c, err := controller.New("datasciencecluster-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource DataScienceCluster
+	err = c.Watch(&source.Kind{Type: &dscv1alpha1.DataScienceCluster{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the KfDefs generated for each component and requeue the owner DataScienceCluster
+	err = c.Watch(&source.Kind{Type: &kfdefv1.KfDef{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &dscv1alpha1.DataScienceCluster{},
+	}, kfutils.ManagedPredicate)
+	if err != nil {
+		return err
+	}
+
+	// Watch for the data science projects and requeue all the DataScienceClusters, creating their LocalQueues
+	kubeclient := mgr.GetClient()
+	return c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			if a.Meta.GetLabels()[dataScienceProjectLabel] != "true" {
+				return nil
+			}
+			instances := &dscv1alpha1.DataScienceClusterList{}
+			if err := kubeclient.List(context.TODO(), instances); err != nil {
+				log.Warnf("Failed to list the DataScienceClusters for namespace %v: %v", a.Meta.GetName(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, instance := range instances.Items {
+				if !kueueEnabled(&instance.Spec.Components) {
+					continue
+				}
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: instance.Namespace,
+					Name:      instance.Name,
+				}})
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileDataScienceCluster implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDataScienceCluster{}
+
+// ReconcileDataScienceCluster reconciles a DataScienceCluster object by maintaining one KfDef per enabled component
+type ReconcileDataScienceCluster struct {
+	client client.Client
+	// crdClient checks that the CRDs required by the components are installed
+	crdClient crdclientset.CustomResourceDefinitionsGetter
+	scheme    *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a DataScienceCluster object and makes changes based on the
+// components enabled in DataScienceCluster.Spec. Each component is reconciled independently so that a failure
+// in one component does not block the others.
+func (r *ReconcileDataScienceCluster) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling DataScienceCluster. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &dscv1alpha1.DataScienceCluster{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The generated KfDefs are owned by the DataScienceCluster and garbage collected with it.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// The missing operators are reported, or subscribed to, before the components depending on them are blocked
+	dependencies, reconcileErr := reconcileDependencies(r.client, r.crdClient, instance)
+	if reconcileErr != nil {
+		log.Errorf("Failed to reconcile the dependencies of DataScienceCluster %v. Error: %v.", request.NamespacedName, reconcileErr)
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "DependencyInstallFailed",
+			"Error installing the operators the components depend on: %v", reconcileErr)
+	}
+	gates, unknown := featuregates.Gates.With(instance.Spec.FeatureGates)
+	if len(unknown) > 0 {
+		log.Warnf("Ignoring the unknown feature gates %v of DataScienceCluster %v.", strings.Join(unknown, ", "), request.NamespacedName)
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "UnknownFeatureGates",
+			"Ignoring the unknown feature gates %s", strings.Join(unknown, ", "))
+	}
+	statuses := []dscv1alpha1.ComponentStatus{}
+	for _, c := range components {
+		status, err := r.reconcileComponent(instance, c, gates)
+		if err != nil {
+			if _, ok := err.(*blockedError); ok {
+				log.Warnf("Component %v is blocked: %v.", c.name, err)
+				r.recorder.Eventf(instance, corev1.EventTypeWarning, "ComponentBlocked",
+					"Component %s is blocked: %v", c.name, err)
+				status.Phase = dscv1alpha1.ComponentBlocked
+			} else {
+				log.Errorf("Failed to reconcile component %v. Error: %v.", c.name, err)
+				r.recorder.Eventf(instance, corev1.EventTypeWarning, "ComponentReconcileFailed",
+					"Error reconciling component %s: %v", c.name, err)
+				status.Phase = dscv1alpha1.ComponentFailed
+			}
+			status.Message = err.Error()
+			reconcileErr = err
+		}
+		statuses = append(statuses, status)
+	}
+
+	if err := r.setStatus(instance, statuses, dependencies); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, reconcileErr
+}
+
+// reconcileComponent creates, updates or deletes the KfDef of a single component and returns its observed status
+func (r *ReconcileDataScienceCluster) reconcileComponent(instance *dscv1alpha1.DataScienceCluster, c component, gates *featuregates.FeatureGates) (dscv1alpha1.ComponentStatus, error) {
+	enabled := c.enabled(&instance.Spec.Components)
+	status := dscv1alpha1.ComponentStatus{
+		Name:    c.name,
+		Enabled: enabled,
+	}
+
+	current := &kfdefv1.KfDef{}
+	key := types.NamespacedName{Name: c.kfDefName(instance), Namespace: instance.Namespace}
+	err := r.client.Get(context.TODO(), key, current)
+	if err != nil && !errors.IsNotFound(err) {
+		return status, err
+	}
+	exists := err == nil
+
+	if !enabled {
+		status.Phase = dscv1alpha1.ComponentDisabled
+		if c.cleanup != nil {
+			if err := c.cleanup(r.client, instance); err != nil {
+				return status, err
+			}
+		}
+		if exists && metav1.IsControlledBy(current, instance) && current.GetDeletionTimestamp() == nil {
+			log.Infof("Component %v disabled, deleting KfDef %v.", c.name, key)
+			if err := r.client.Delete(context.TODO(), current); err != nil && !errors.IsNotFound(err) {
+				return status, err
+			}
+			r.recorder.Eventf(instance, corev1.EventTypeNormal, "ComponentDisabled",
+				"Component %s disabled", c.name)
+		}
+		return status, nil
+	}
+
+	// The component is neither deployed nor updated while its settings rely on disabled features
+	if disabled := c.disabledFeatures(gates, &instance.Spec.Components); len(disabled) > 0 {
+		return status, &blockedError{message: fmt.Sprintf("the feature gates %v are disabled", strings.Join(disabled, ", "))}
+	}
+
+	// The component is neither deployed nor updated until the operators it depends on are installed
+	missing, err := c.missingCRDs(r.crdClient, &instance.Spec.Components)
+	if err != nil {
+		return status, err
+	}
+	if len(missing) > 0 {
+		return status, &blockedError{message: dependencyMessage(missing, instance.Spec.InstallDependencies)}
+	}
+	if c.dependencies != nil {
+		if err := c.dependencies(r.client, instance); err != nil {
+			return status, err
+		}
+	}
+
+	desired := c.kfDefSpec(instance)
+	if !exists {
+		kfdef := &kfdefv1.KfDef{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: kfutils.WithManagedLabel(nil)},
+			Spec:       desired,
+		}
+		if err := controllerutil.SetControllerReference(instance, kfdef, r.scheme); err != nil {
+			return status, err
+		}
+		log.Infof("Component %v enabled, creating KfDef %v.", c.name, key)
+		if err := r.client.Create(context.TODO(), kfdef); err != nil {
+			return status, err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "ComponentEnabled",
+			"Component %s enabled", c.name)
+		status.Phase = dscv1alpha1.ComponentProgressing
+		return status, nil
+	}
+
+	if !metav1.IsControlledBy(current, instance) {
+		return status, fmt.Errorf("KfDef %v already exists and is not owned by DataScienceCluster %v", key, instance.Name)
+	}
+	if !reflect.DeepEqual(current.Spec, desired) || !kfutils.IsManaged(current) {
+		log.Infof("Updating KfDef %v for component %v.", key, c.name)
+		current.Labels = kfutils.WithManagedLabel(current.Labels)
+		current.Spec = desired
+		if err := r.client.Update(context.TODO(), current); err != nil {
+			return status, err
+		}
+		status.Phase = dscv1alpha1.ComponentProgressing
+		return status, nil
+	}
+
+	status.Phase, status.Message = componentPhase(current)
+	return status, nil
+}
+
+// blockedError is returned when a component waits for the operators or resources it depends on
+type blockedError struct {
+	message string
+}
+
+func (e *blockedError) Error() string {
+	return e.message
+}
+
+// componentPhase derives the component phase from the conditions of its KfDef
+func componentPhase(kfdef *kfdefv1.KfDef) (dscv1alpha1.ComponentPhase, string) {
+	phase := dscv1alpha1.ComponentProgressing
+	message := ""
+	for _, cond := range kfdef.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case kfdefv1.KfDegraded:
+			return dscv1alpha1.ComponentFailed, cond.Reason
+		case kfdefv1.KfAvailable:
+			phase = dscv1alpha1.ComponentReady
+			message = cond.Reason
+		}
+	}
+	return phase, message
+}
+
+// setStatus records the component and the dependency statuses and the aggregated phase on the DataScienceCluster
+func (r *ReconcileDataScienceCluster) setStatus(instance *dscv1alpha1.DataScienceCluster, statuses []dscv1alpha1.ComponentStatus, dependencies []dscv1alpha1.DependencyStatus) error {
+	phase := dscv1alpha1.ComponentReady
+	for i := range statuses {
+		for _, old := range instance.Status.Components {
+			if old.Name == statuses[i].Name && old.Phase == statuses[i].Phase && old.Message == statuses[i].Message {
+				statuses[i].LastUpdateTime = old.LastUpdateTime
+			}
+		}
+		if statuses[i].LastUpdateTime.IsZero() {
+			statuses[i].LastUpdateTime = metav1.Now()
+		}
+		switch statuses[i].Phase {
+		case dscv1alpha1.ComponentFailed:
+			phase = dscv1alpha1.ComponentFailed
+		case dscv1alpha1.ComponentBlocked:
+			if phase != dscv1alpha1.ComponentFailed {
+				phase = dscv1alpha1.ComponentBlocked
+			}
+		case dscv1alpha1.ComponentProgressing:
+			if phase == dscv1alpha1.ComponentReady {
+				phase = dscv1alpha1.ComponentProgressing
+			}
+		}
+	}
+
+	status := dscv1alpha1.DataScienceClusterStatus{
+		Phase:        phase,
+		Components:   statuses,
+		Dependencies: dependencies,
+	}
+	summarizeComponents(&status)
+	if reflect.DeepEqual(instance.Status, status) {
+		return nil
+	}
+	instance.Status = status
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// summarizeComponents records which enabled components are installed and how many are in every phase, for the
+// dashboard and external tooling to show the overall health without reading every component
+func summarizeComponents(status *dscv1alpha1.DataScienceClusterStatus) {
+	status.InstalledComponents = nil
+	status.ComponentCounts = dscv1alpha1.ComponentCounts{}
+	enabled := 0
+	for _, component := range status.Components {
+		if !component.Enabled {
+			continue
+		}
+		enabled++
+		if status.InstalledComponents == nil {
+			status.InstalledComponents = map[string]bool{}
+		}
+		status.InstalledComponents[component.Name] = component.Phase == dscv1alpha1.ComponentReady
+		switch component.Phase {
+		case dscv1alpha1.ComponentReady:
+			status.ComponentCounts.Ready++
+		case dscv1alpha1.ComponentFailed:
+			status.ComponentCounts.Degraded++
+		case dscv1alpha1.ComponentBlocked:
+			status.ComponentCounts.Blocked++
+		default:
+			status.ComponentCounts.Progressing++
+		}
+	}
+	status.ReadyComponents = fmt.Sprintf("%d/%d", status.ComponentCounts.Ready, enabled)
+}
@@ -0,0 +1,91 @@
+package datasciencecluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	crdfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSummarizeComponents(t *testing.T) {
+	status := &dscv1alpha1.DataScienceClusterStatus{
+		Components: []dscv1alpha1.ComponentStatus{
+			{Name: "dashboard", Enabled: true, Phase: dscv1alpha1.ComponentReady},
+			{Name: "workbenches", Enabled: true, Phase: dscv1alpha1.ComponentReady},
+			{Name: "modelserving", Enabled: true, Phase: dscv1alpha1.ComponentBlocked},
+			{Name: "datasciencepipelines", Enabled: true, Phase: dscv1alpha1.ComponentFailed},
+			{Name: "kueue", Enabled: true, Phase: dscv1alpha1.ComponentProgressing},
+			{Name: "trustyai", Enabled: false, Phase: dscv1alpha1.ComponentDisabled},
+		},
+	}
+	summarizeComponents(status)
+	installed := map[string]bool{
+		"dashboard":            true,
+		"workbenches":          true,
+		"modelserving":         false,
+		"datasciencepipelines": false,
+		"kueue":                false,
+	}
+	if !reflect.DeepEqual(status.InstalledComponents, installed) {
+		t.Errorf("Expected the installed components %v, got %v", installed, status.InstalledComponents)
+	}
+	counts := dscv1alpha1.ComponentCounts{Ready: 2, Progressing: 1, Degraded: 1, Blocked: 1}
+	if status.ComponentCounts != counts {
+		t.Errorf("Expected the counts %+v, got %+v", counts, status.ComponentCounts)
+	}
+	if status.ReadyComponents != "2/5" {
+		t.Errorf("Expected 2/5 ready components, got %v", status.ReadyComponents)
+	}
+
+	status = &dscv1alpha1.DataScienceClusterStatus{}
+	summarizeComponents(status)
+	if status.InstalledComponents != nil || status.ReadyComponents != "0/0" {
+		t.Errorf("Expected no installed component, got %+v", status)
+	}
+}
+
+func TestKfDefPassesPredicate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, dscv1alpha1.AddToScheme, kfdefv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	instance := &dscv1alpha1.DataScienceCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "opendatahub", UID: "uid"},
+		Spec: dscv1alpha1.DataScienceClusterSpec{
+			Components: dscv1alpha1.Components{Dashboard: dscv1alpha1.Component{Enabled: true}},
+		},
+	}
+	r := &ReconcileDataScienceCluster{
+		client:    fake.NewFakeClientWithScheme(scheme, instance),
+		crdClient: crdfake.NewSimpleClientset().ApiextensionsV1beta1(),
+		scheme:    scheme,
+		recorder:  record.NewFakeRecorder(10),
+	}
+	c := componentNamed(t, "dashboard")
+	if _, err := r.reconcileComponent(instance, c, featuregates.Gates); err != nil {
+		t.Fatalf("Failed to reconcile the dashboard: %v", err)
+	}
+
+	kfdef := &kfdefv1.KfDef{}
+	key := types.NamespacedName{Name: c.kfDefName(instance), Namespace: instance.Namespace}
+	if err := r.client.Get(context.TODO(), key, kfdef); err != nil {
+		t.Fatalf("Expected KfDef %v to be created: %v", key, err)
+	}
+	if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: kfdef, Object: kfdef}) {
+		t.Errorf("Expected the events of the KfDef to pass the predicate of the watches, got labels %v", kfdef.Labels)
+	}
+}
@@ -0,0 +1,275 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// certificateCRD is installed by the cert-manager operator
+	certificateCRD = "certificates.cert-manager.io"
+	// nodeFeatureDiscoveryCRD is installed by the Node Feature Discovery operator
+	nodeFeatureDiscoveryCRD = "nodefeaturediscoveries.nfd.openshift.io"
+	// clusterPolicyCRD is installed by the NVIDIA GPU operator
+	clusterPolicyCRD = "clusterpolicies.nvidia.com"
+
+	// globalOperatorsNamespace is the namespace of the operators watching all the namespaces, which has an
+	// OperatorGroup already
+	globalOperatorsNamespace = "openshift-operators"
+	marketplaceNamespace     = "openshift-marketplace"
+	// dependencyLabel is the label of the Subscriptions, the OperatorGroups and the namespaces created for the
+	// dependencies, naming the DataScienceCluster that required them as <namespace>.<name>
+	dependencyLabel = "datasciencecluster.opendatahub.io/dependency-of"
+)
+
+var (
+	// SubscriptionGVK and OperatorGroupGVK are the kinds OLM installs the operators of OperatorHub with
+	SubscriptionGVK  = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1alpha1", Kind: "Subscription"}
+	OperatorGroupGVK = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1", Kind: "OperatorGroup"}
+)
+
+// operatorDependency is an operator of OperatorHub the components depend on, detected by a CRD it installs
+type operatorDependency struct {
+	name string
+	crd  string
+	// pkg, channel and source are the package of the operator in the catalog source of openshift-marketplace, and
+	// the channel it is subscribed to
+	pkg     string
+	channel string
+	source  string
+	// namespace of the operator. An operator of another namespace than openshift-operators only watches its own
+	// namespace when ownNamespace is set, and all of them otherwise.
+	namespace    string
+	ownNamespace bool
+}
+
+// operatorDependencies lists the operators the components may depend on
+var operatorDependencies = []operatorDependency{
+	{
+		name:      "OpenShift Serverless",
+		crd:       knativeServingCRD,
+		pkg:       "serverless-operator",
+		channel:   "stable",
+		source:    "redhat-operators",
+		namespace: "openshift-serverless",
+	},
+	{
+		name:      "OpenShift Service Mesh",
+		crd:       serviceMeshControlPlaneCRD,
+		pkg:       "servicemeshoperator",
+		channel:   "stable",
+		source:    "redhat-operators",
+		namespace: globalOperatorsNamespace,
+	},
+	{
+		name:      "cert-manager Operator for Red Hat OpenShift",
+		crd:       certificateCRD,
+		pkg:       "openshift-cert-manager-operator",
+		channel:   "stable-v1",
+		source:    "redhat-operators",
+		namespace: "cert-manager-operator",
+	},
+	{
+		name:         "Node Feature Discovery",
+		crd:          nodeFeatureDiscoveryCRD,
+		pkg:          "nfd",
+		channel:      "stable",
+		source:       "redhat-operators",
+		namespace:    "openshift-nfd",
+		ownNamespace: true,
+	},
+	{
+		name:         "NVIDIA GPU Operator",
+		crd:          clusterPolicyCRD,
+		pkg:          "gpu-operator-certified",
+		channel:      "stable",
+		source:       "certified-operators",
+		namespace:    "nvidia-gpu-operator",
+		ownNamespace: true,
+	},
+}
+
+// requiredDependencies returns the operators the enabled components and the GPU support of the spec depend on
+func requiredDependencies(spec *dscv1alpha1.DataScienceClusterSpec) []operatorDependency {
+	crds := map[string]bool{}
+	for _, c := range components {
+		if !c.enabled(&spec.Components) || c.requiredCRDs == nil {
+			continue
+		}
+		for _, crd := range c.requiredCRDs(&spec.Components) {
+			crds[crd] = true
+		}
+	}
+	if spec.GPUSupport {
+		crds[nodeFeatureDiscoveryCRD] = true
+		crds[clusterPolicyCRD] = true
+	}
+	required := []operatorDependency{}
+	for _, d := range operatorDependencies {
+		if crds[d.crd] {
+			required = append(required, d)
+		}
+	}
+	return required
+}
+
+// dependencyNames returns the names of the operators installing the CRDs, and the CRDs installed by no known
+// operator
+func dependencyNames(crds []string) ([]string, []string) {
+	names, unknown := []string{}, []string{}
+	for _, crd := range crds {
+		found := false
+		for _, d := range operatorDependencies {
+			if d.crd == crd {
+				names, found = append(names, d.name), true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, crd)
+		}
+	}
+	return names, unknown
+}
+
+// reconcileDependencies reports whether the operators the DataScienceCluster depends on are installed, and
+// subscribes to the missing ones when spec.installDependencies is set
+func reconcileDependencies(c client.Client, crdClient crdclientset.CustomResourceDefinitionsGetter, instance *dscv1alpha1.DataScienceCluster) ([]dscv1alpha1.DependencyStatus, error) {
+	statuses := []dscv1alpha1.DependencyStatus{}
+	for _, d := range requiredDependencies(&instance.Spec) {
+		status := dscv1alpha1.DependencyStatus{Name: d.name, Package: d.pkg, Phase: dscv1alpha1.DependencyInstalled}
+		_, err := crdClient.CustomResourceDefinitions().Get(d.crd, metav1.GetOptions{})
+		switch {
+		case err == nil:
+		case !errors.IsNotFound(err):
+			return statuses, fmt.Errorf("failed to get CRD %v: %v", d.crd, err)
+		case instance.Spec.InstallDependencies:
+			if err := subscribe(c, instance, d); err != nil {
+				return statuses, err
+			}
+			status.Phase = dscv1alpha1.DependencyInstalling
+			status.Message = fmt.Sprintf("subscribed to the %v channel of package %v of %v in namespace %v",
+				d.channel, d.pkg, d.source, d.namespace)
+		default:
+			status.Phase = dscv1alpha1.DependencyMissing
+			status.Message = fmt.Sprintf("install package %v from the %v channel of %v in OperatorHub, or set "+
+				"spec.installDependencies for the operator to install it", d.pkg, d.channel, d.source)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// subscribe creates the namespace, the OperatorGroup and the Subscription of the operator. The ones that exist
+// are left as they are, so that an installation started by hand is not changed.
+func subscribe(c client.Client, instance *dscv1alpha1.DataScienceCluster, d operatorDependency) error {
+	labels := kfutils.WithManagedLabel(map[string]string{dependencyLabel: instance.Namespace + "." + instance.Name})
+	if d.namespace != globalOperatorsNamespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: d.namespace, Labels: labels}}
+		if err := c.Create(context.TODO(), ns); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create namespace %v: %v", d.namespace, err)
+		}
+		groups := &unstructured.UnstructuredList{}
+		groups.SetGroupVersionKind(OperatorGroupGVK)
+		if err := c.List(context.TODO(), groups, client.InNamespace(d.namespace)); err != nil {
+			return olmError(err, "failed to list the OperatorGroups of namespace "+d.namespace)
+		}
+		// A namespace with several OperatorGroups installs no operator
+		if len(groups.Items) == 0 {
+			if err := create(c, operatorGroup(d, labels)); err != nil {
+				return err
+			}
+		}
+	}
+
+	subscriptions := &unstructured.UnstructuredList{}
+	subscriptions.SetGroupVersionKind(SubscriptionGVK)
+	if err := c.List(context.TODO(), subscriptions, client.InNamespace(d.namespace)); err != nil {
+		return olmError(err, "failed to list the Subscriptions of namespace "+d.namespace)
+	}
+	for _, s := range subscriptions.Items {
+		if name, _, _ := unstructured.NestedString(s.Object, "spec", "name"); name == d.pkg {
+			return nil
+		}
+	}
+	log.Infof("Subscribing to the %v operator for DataScienceCluster %v/%v.", d.name, instance.Namespace, instance.Name)
+	return create(c, subscription(d, labels))
+}
+
+// operatorGroup returns the OperatorGroup of the namespace of the operator
+func operatorGroup(d operatorDependency, labels map[string]string) *unstructured.Unstructured {
+	group := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	group.SetGroupVersionKind(OperatorGroupGVK)
+	group.SetNamespace(d.namespace)
+	group.SetName(d.namespace)
+	group.SetLabels(labels)
+	if d.ownNamespace {
+		group.Object["spec"] = map[string]interface{}{"targetNamespaces": []interface{}{d.namespace}}
+	}
+	return group
+}
+
+// subscription returns the Subscription of the operator, installing its updates automatically
+func subscription(d operatorDependency, labels map[string]string) *unstructured.Unstructured {
+	s := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":                d.pkg,
+			"channel":             d.channel,
+			"source":              d.source,
+			"sourceNamespace":     marketplaceNamespace,
+			"installPlanApproval": "Automatic",
+		},
+	}}
+	s.SetGroupVersionKind(SubscriptionGVK)
+	s.SetNamespace(d.namespace)
+	s.SetName(d.pkg)
+	s.SetLabels(labels)
+	return s
+}
+
+// create creates obj unless it exists
+func create(c client.Client, obj *unstructured.Unstructured) error {
+	if err := c.Create(context.TODO(), obj); err != nil && !errors.IsAlreadyExists(err) {
+		return olmError(err, fmt.Sprintf("failed to create %v %v/%v", obj.GetKind(), obj.GetNamespace(), obj.GetName()))
+	}
+	return nil
+}
+
+// olmError returns the error of a request for the resources of OLM, telling when it is not installed
+func olmError(err error, message string) error {
+	if meta.IsNoMatchError(err) {
+		return &blockedError{message: "the operators cannot be installed without the Operator Lifecycle Manager, " +
+			"install them by hand"}
+	}
+	return fmt.Errorf("%v: %v", message, err)
+}
+
+// dependencyMessage returns the message of a component blocked by the missing CRDs
+func dependencyMessage(missing []string, install bool) string {
+	names, unknown := dependencyNames(missing)
+	parts := []string{}
+	if len(names) > 0 {
+		action := "must be installed first, see status.dependencies"
+		if install {
+			action = "are being installed"
+		}
+		parts = append(parts, fmt.Sprintf("the operators %v %v", strings.Join(names, ", "), action))
+	}
+	if len(unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("the operators providing the CRDs %v must be installed first", strings.Join(unknown, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,125 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	crdfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRequiredDependencies(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Spec     dscv1alpha1.DataScienceClusterSpec
+		Expected []string
+	}
+	kserve := dscv1alpha1.ModelServing{Component: dscv1alpha1.Component{Enabled: true}, Platform: dscv1alpha1.KServe}
+	testCases := []testCase{
+		{
+			Name:     "no dependency",
+			Spec:     dscv1alpha1.DataScienceClusterSpec{Components: dscv1alpha1.Components{Dashboard: dscv1alpha1.Component{Enabled: true}}},
+			Expected: []string{},
+		},
+		{
+			Name:     "kserve",
+			Spec:     dscv1alpha1.DataScienceClusterSpec{Components: dscv1alpha1.Components{ModelServing: kserve}},
+			Expected: []string{"serverless-operator", "servicemeshoperator"},
+		},
+		{
+			Name: "kserve with TLS and GPUs",
+			Spec: dscv1alpha1.DataScienceClusterSpec{
+				Components: dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{
+					Component: kserve.Component,
+					Platform:  dscv1alpha1.KServe,
+					Serving:   dscv1alpha1.KnativeServing{AutoTLS: true},
+				}},
+				GPUSupport: true,
+			},
+			Expected: []string{"serverless-operator", "servicemeshoperator", "openshift-cert-manager-operator", "nfd",
+				"gpu-operator-certified"},
+		},
+		{
+			Name: "disabled kserve",
+			Spec: dscv1alpha1.DataScienceClusterSpec{Components: dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{
+				Platform: dscv1alpha1.KServe,
+			}}},
+			Expected: []string{},
+		},
+	}
+	for _, c := range testCases {
+		packages := []string{}
+		for _, d := range requiredDependencies(&c.Spec) {
+			packages = append(packages, d.pkg)
+		}
+		if !reflect.DeepEqual(packages, c.Expected) {
+			t.Errorf("%v: expected the dependencies %v, got %v", c.Name, c.Expected, packages)
+		}
+	}
+}
+
+func TestReconcileDependencies(t *testing.T) {
+	crdClient := crdfake.NewSimpleClientset(&apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: serviceMeshControlPlaneCRD},
+	}).ApiextensionsV1beta1()
+	instance := &dscv1alpha1.DataScienceCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "opendatahub"},
+		Spec: dscv1alpha1.DataScienceClusterSpec{
+			Components: dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{
+				Component: dscv1alpha1.Component{Enabled: true},
+				Platform:  dscv1alpha1.KServe,
+			}},
+			GPUSupport: true,
+		},
+	}
+
+	statuses, err := reconcileDependencies(fake.NewFakeClientWithScheme(scheme.Scheme), crdClient, instance)
+	if err != nil {
+		t.Fatalf("Failed to reconcile the dependencies: %v", err)
+	}
+	phases := map[string]dscv1alpha1.DependencyPhase{}
+	for _, s := range statuses {
+		phases[s.Package] = s.Phase
+	}
+	expected := map[string]dscv1alpha1.DependencyPhase{
+		"serverless-operator":    dscv1alpha1.DependencyMissing,
+		"servicemeshoperator":    dscv1alpha1.DependencyInstalled,
+		"nfd":                    dscv1alpha1.DependencyMissing,
+		"gpu-operator-certified": dscv1alpha1.DependencyMissing,
+	}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("Expected the dependencies %v, got %v", expected, phases)
+	}
+
+	message := dependencyMessage([]string{knativeServingCRD, "unknown.example.com"}, false)
+	if !strings.Contains(message, "OpenShift Serverless must be installed first") || !strings.Contains(message, "unknown.example.com") {
+		t.Errorf("Expected the missing operators in the message, got %v", message)
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	labels := map[string]string{dependencyLabel: "opendatahub.default"}
+	for _, d := range operatorDependencies {
+		s := subscription(d, labels)
+		if s.GetNamespace() != d.namespace || s.GetName() != d.pkg {
+			t.Errorf("Unexpected Subscription %v/%v of %v", s.GetNamespace(), s.GetName(), d.name)
+		}
+		if source, _, _ := unstructured.NestedString(s.Object, "spec", "source"); source != d.source {
+			t.Errorf("Expected the source %v of %v, got %v", d.source, d.name, source)
+		}
+
+		targets, found, _ := unstructured.NestedStringSlice(operatorGroup(d, labels).Object, "spec", "targetNamespaces")
+		if d.ownNamespace && !reflect.DeepEqual(targets, []string{d.namespace}) {
+			t.Errorf("Expected the OperatorGroup of %v to target its namespace, got %v", d.name, targets)
+		}
+		if !d.ownNamespace && found {
+			t.Errorf("Expected the OperatorGroup of %v to target all the namespaces, got %v", d.name, targets)
+		}
+	}
+}
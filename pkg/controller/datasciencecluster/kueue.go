@@ -0,0 +1,276 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// kueueName is the name of the Kueue component
+	kueueName = "kueue"
+	// clusterQueueCRD and resourceFlavorCRD are installed by Kueue, along with localQueueCRD
+	clusterQueueCRD   = "clusterqueues.kueue.x-k8s.io"
+	resourceFlavorCRD = "resourceflavors.kueue.x-k8s.io"
+	// defaultClusterQueue and defaultLocalQueue are the names of the queues when the component does not set them
+	defaultClusterQueue = "odh-default"
+	defaultLocalQueue   = "default"
+	// defaultResourceFlavor is the flavor of the quotas of the ClusterQueue, matching all the nodes
+	defaultResourceFlavor = "odh-default-flavor"
+	// dataScienceProjectLabel is the label of the namespaces of the data science projects
+	dataScienceProjectLabel = "opendatahub.io/dashboard"
+	// LocalQueueAnnotation is the annotation of the data science projects naming the LocalQueue the queue name
+	// webhook labels the pipelines and the Ray workloads with
+	LocalQueueAnnotation = "kueue.opendatahub.io/local-queue"
+	// kueueOwnerLabel is the label of the Kueue resources created for a DataScienceCluster, <namespace>.<name>
+	kueueOwnerLabel = "opendatahub.io/datasciencecluster"
+)
+
+var (
+	// ClusterQueueGVK, LocalQueueGVK and ResourceFlavorGVK are the kinds of the queues and flavors of Kueue
+	ClusterQueueGVK   = schema.GroupVersionKind{Group: "kueue.x-k8s.io", Version: "v1beta1", Kind: "ClusterQueue"}
+	LocalQueueGVK     = schema.GroupVersionKind{Group: "kueue.x-k8s.io", Version: "v1beta1", Kind: "LocalQueue"}
+	ResourceFlavorGVK = schema.GroupVersionKind{Group: "kueue.x-k8s.io", Version: "v1beta1", Kind: "ResourceFlavor"}
+
+	// defaultQuotas are the quotas of the ClusterQueue when the component does not set any
+	defaultQuotas = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("64"),
+		corev1.ResourceMemory: resource.MustParse("256Gi"),
+	}
+)
+
+// kueueEnabled returns true if the queues of Kueue are created, whether the operator installs Kueue or not
+func kueueEnabled(c *dscv1alpha1.Components) bool {
+	state := c.Kueue.ManagementState
+	return state == dscv1alpha1.Managed || state == dscv1alpha1.Unmanaged
+}
+
+// kueueApplications returns the application installing Kueue when it is Managed
+func kueueApplications(c *dscv1alpha1.Components) []kfdefv1.Application {
+	if c.Kueue.ManagementState != dscv1alpha1.Managed {
+		return []kfdefv1.Application{}
+	}
+	return []kfdefv1.Application{application("kueue", "kueue")}
+}
+
+// kueueCRDs returns the CRDs of an existing installation of Kueue. The CRDs of the Kueue installed by the operator
+// are only waited for by reconcileKueue.
+func kueueCRDs(c *dscv1alpha1.Components) []string {
+	if c.Kueue.ManagementState != dscv1alpha1.Unmanaged {
+		return nil
+	}
+	return []string{clusterQueueCRD, localQueueCRD, resourceFlavorCRD}
+}
+
+// clusterQueueName and localQueueName return the names of the queues of the component
+func clusterQueueName(c *dscv1alpha1.Components) string {
+	if c.Kueue.ClusterQueue != "" {
+		return c.Kueue.ClusterQueue
+	}
+	return defaultClusterQueue
+}
+
+func localQueueName(c *dscv1alpha1.Components) string {
+	if c.Kueue.LocalQueue != "" {
+		return c.Kueue.LocalQueue
+	}
+	return defaultLocalQueue
+}
+
+// clusterQueueSpec returns the spec of the ClusterQueue admitting the workloads of the data science projects
+// within the quotas
+func clusterQueueSpec(c *dscv1alpha1.Components) map[string]interface{} {
+	quotas := c.Kueue.Quotas
+	if len(quotas) == 0 {
+		quotas = defaultQuotas
+	}
+	names := []string{}
+	for name := range quotas {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	covered := []interface{}{}
+	resources := []interface{}{}
+	for _, name := range names {
+		quota := quotas[corev1.ResourceName(name)]
+		covered = append(covered, name)
+		resources = append(resources, map[string]interface{}{"name": name, "nominalQuota": quota.String()})
+	}
+	return map[string]interface{}{
+		"namespaceSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{dataScienceProjectLabel: "true"},
+		},
+		"resourceGroups": []interface{}{
+			map[string]interface{}{
+				"coveredResources": covered,
+				"flavors": []interface{}{
+					map[string]interface{}{"name": defaultResourceFlavor, "resources": resources},
+				},
+			},
+		},
+	}
+}
+
+// reconcileKueue creates the ResourceFlavor and the ClusterQueue of the component, and a LocalQueue of the
+// ClusterQueue in every data science project, whose namespace is annotated with it. The queues of the Kueue
+// installed by the operator are created once its CRDs are installed.
+func reconcileKueue(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	components := &instance.Spec.Components
+	owner := instance.Namespace + "." + instance.Name
+
+	flavor := kueueResource(ResourceFlavorGVK, "", defaultResourceFlavor, owner)
+	err := applyKueueResource(c, flavor, map[string]interface{}{})
+	if meta.IsNoMatchError(err) && components.Kueue.ManagementState == dscv1alpha1.Managed {
+		log.Infof("Waiting for the CRDs of Kueue to create the queues of DataScienceCluster %v.", owner)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	clusterQueue := clusterQueueName(components)
+	err = applyKueueResource(c, kueueResource(ClusterQueueGVK, "", clusterQueue, owner), clusterQueueSpec(components))
+	if err != nil {
+		return err
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(context.TODO(), namespaces, client.MatchingLabels{dataScienceProjectLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the data science projects: %v", err)
+	}
+	localQueue := localQueueName(components)
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if ns.GetDeletionTimestamp() != nil {
+			continue
+		}
+		queue := kueueResource(LocalQueueGVK, ns.Name, localQueue, owner)
+		if err := applyKueueResource(c, queue, map[string]interface{}{"clusterQueue": clusterQueue}); err != nil {
+			return err
+		}
+		if ns.Annotations[LocalQueueAnnotation] == localQueue {
+			continue
+		}
+		if ns.Annotations == nil {
+			ns.Annotations = map[string]string{}
+		}
+		ns.Annotations[LocalQueueAnnotation] = localQueue
+		log.Infof("Annotating data science project %v with LocalQueue %v.", ns.Name, localQueue)
+		if err := c.Update(context.TODO(), ns); err != nil {
+			return fmt.Errorf("failed to annotate namespace %v: %v", ns.Name, err)
+		}
+	}
+	return removeStaleKueueResources(c, instance, clusterQueue, localQueue)
+}
+
+// removeStaleKueueResources deletes the queues of the DataScienceCluster that were renamed
+func removeStaleKueueResources(c client.Client, instance *dscv1alpha1.DataScienceCluster, clusterQueue, localQueue string) error {
+	owner := instance.Namespace + "." + instance.Name
+	for _, gvk := range []schema.GroupVersionKind{LocalQueueGVK, ClusterQueueGVK} {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := c.List(context.TODO(), list, client.MatchingLabels{kueueOwnerLabel: owner}); err != nil {
+			if meta.IsNoMatchError(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to list the %v resources of DataScienceCluster %v: %v", gvk.Kind, owner, err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			if gvk == ClusterQueueGVK && item.GetName() == clusterQueue || gvk == LocalQueueGVK && item.GetName() == localQueue {
+				continue
+			}
+			if err := deleteAlertingResource(c, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeKueue deletes the queues and the flavor created for the DataScienceCluster, and the annotations of the
+// data science projects, once Kueue is Removed
+func removeKueue(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(context.TODO(), namespaces, client.MatchingLabels{dataScienceProjectLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the data science projects: %v", err)
+	}
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if _, found := ns.Annotations[LocalQueueAnnotation]; !found {
+			continue
+		}
+		delete(ns.Annotations, LocalQueueAnnotation)
+		if err := c.Update(context.TODO(), ns); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove the LocalQueue annotation of namespace %v: %v", ns.Name, err)
+		}
+	}
+	if err := removeStaleKueueResources(c, instance, "", ""); err != nil {
+		return err
+	}
+	owner := instance.Namespace + "." + instance.Name
+	return deleteAlertingResource(c, kueueResource(ResourceFlavorGVK, "", defaultResourceFlavor, owner))
+}
+
+// kueueResource returns the Kueue resource of the kind, labeled with the DataScienceCluster creating it. The
+// resources are not owned by the DataScienceCluster since the ClusterQueues and the ResourceFlavors are
+// cluster-scoped.
+func kueueResource(gvk schema.GroupVersionKind, namespace, name, owner string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{kueueOwnerLabel: owner})
+	return obj
+}
+
+// applyKueueResource creates obj with the spec, or sets the fields of the spec on its spec, keeping the ones Kueue
+// defaults
+func applyKueueResource(c client.Client, obj *unstructured.Unstructured, spec map[string]interface{}) error {
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	err := c.Get(context.TODO(), key, current)
+	if meta.IsNoMatchError(err) {
+		return err
+	}
+	if errors.IsNotFound(err) {
+		obj.Object["spec"] = spec
+		log.Infof("Creating %v %v.", obj.GetKind(), key)
+		return c.Create(context.TODO(), obj)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %v %v: %v", obj.GetKind(), key, err)
+	}
+	currentSpec, _, err := unstructured.NestedMap(current.Object, "spec")
+	if err != nil {
+		return fmt.Errorf("invalid spec of %v %v: %v", obj.GetKind(), key, err)
+	}
+	if currentSpec == nil {
+		currentSpec = map[string]interface{}{}
+	}
+	changed := false
+	for k, v := range spec {
+		if !reflect.DeepEqual(currentSpec[k], v) {
+			currentSpec[k] = v
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	current.Object["spec"] = currentSpec
+	log.Infof("Updating %v %v.", obj.GetKind(), key)
+	return c.Update(context.TODO(), current)
+}
@@ -0,0 +1,68 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestClusterQueueSpec(t *testing.T) {
+	components := &dscv1alpha1.Components{Kueue: dscv1alpha1.Kueue{ManagementState: dscv1alpha1.Unmanaged}}
+	spec := clusterQueueSpec(components)
+	group := spec["resourceGroups"].([]interface{})[0].(map[string]interface{})
+	if covered := group["coveredResources"]; !reflect.DeepEqual(covered, []interface{}{"cpu", "memory"}) {
+		t.Errorf("Expected the default quotas to cover cpu and memory, got %v", covered)
+	}
+
+	components.Kueue.Quotas = corev1.ResourceList{
+		"nvidia.com/gpu":   resource.MustParse("4"),
+		corev1.ResourceCPU: resource.MustParse("32"),
+	}
+	spec = clusterQueueSpec(components)
+	group = spec["resourceGroups"].([]interface{})[0].(map[string]interface{})
+	flavor := group["flavors"].([]interface{})[0].(map[string]interface{})
+	expected := []interface{}{
+		map[string]interface{}{"name": "cpu", "nominalQuota": "32"},
+		map[string]interface{}{"name": "nvidia.com/gpu", "nominalQuota": "4"},
+	}
+	if flavor["name"] != defaultResourceFlavor || !reflect.DeepEqual(flavor["resources"], expected) {
+		t.Errorf("Expected the sorted quotas of the component, got %v", flavor)
+	}
+	if !reflect.DeepEqual(spec, clusterQueueSpec(components)) {
+		t.Errorf("Expected the spec to be stable")
+	}
+}
+
+func TestKueueComponent(t *testing.T) {
+	kueue := componentNamed(t, kueueName)
+	components := &dscv1alpha1.Components{}
+	if kueue.enabled(components) {
+		t.Errorf("Expected Kueue to be disabled by default")
+	}
+
+	components.Kueue.ManagementState = dscv1alpha1.Managed
+	if apps := kueue.applications(components); len(apps) != 1 || apps[0].Name != "kueue" {
+		t.Errorf("Expected the operator to install Kueue, got %v", apps)
+	}
+	if crds := kueue.requiredCRDs(components); crds != nil {
+		t.Errorf("Expected the CRDs of the Kueue installed by the operator not to be required, got %v", crds)
+	}
+	if clusterQueueName(components) != defaultClusterQueue || localQueueName(components) != defaultLocalQueue {
+		t.Errorf("Expected the default queues")
+	}
+
+	components.Kueue.ManagementState = dscv1alpha1.Unmanaged
+	components.Kueue.LocalQueue = "team-queue"
+	if apps := kueue.applications(components); len(apps) != 0 {
+		t.Errorf("Expected an existing installation of Kueue to be used, got %v", apps)
+	}
+	if crds := kueue.requiredCRDs(components); len(crds) != 3 {
+		t.Errorf("Expected the CRDs of Kueue to be required, got %v", crds)
+	}
+	if localQueueName(components) != "team-queue" {
+		t.Errorf("Expected the LocalQueue of the component, got %v", localQueueName(components))
+	}
+}
@@ -0,0 +1,168 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// knativeServingCRD is installed by the OpenShift Serverless operator
+	knativeServingCRD = "knativeservings.operator.knative.dev"
+	// serviceMeshControlPlaneCRD is installed by the OpenShift Service Mesh operator
+	serviceMeshControlPlaneCRD = "servicemeshcontrolplanes.maistra.io"
+	// servingRuntimesPath is the odh-manifests path of the ServingRuntime templates, one directory per runtime
+	// with an overlay per platform
+	servingRuntimesPath = "serving-runtimes"
+	// knativeServingNamespace and knativeServingName are the ones the OpenShift Serverless operator requires
+	knativeServingNamespace = "knative-serving"
+	knativeServingName      = "knative-serving"
+)
+
+// KnativeServingGVK is the kind of the Knative Serving installations of the OpenShift Serverless operator
+var KnativeServingGVK = schema.GroupVersionKind{Group: "operator.knative.dev", Version: "v1beta1", Kind: "KnativeServing"}
+
+// servingPlatform returns the platform the models are served with
+func servingPlatform(c *dscv1alpha1.Components) dscv1alpha1.ServingPlatform {
+	if c.ModelServing.Platform == "" {
+		return dscv1alpha1.ModelMesh
+	}
+	return c.ModelServing.Platform
+}
+
+// modelServingApplications returns the applications deploying the serving platform, the model controller and the
+// ServingRuntime templates of the platform. Switching platforms prunes the applications of the previous one.
+func modelServingApplications(c *dscv1alpha1.Components) []kfdefv1.Application {
+	platform := servingPlatform(c)
+	apps := []kfdefv1.Application{}
+	if platform == dscv1alpha1.KServe {
+		apps = append(apps, application("kserve", "kserve"))
+	} else {
+		apps = append(apps, application("model-mesh", "model-mesh"))
+	}
+	apps = append(apps, application("odh-model-controller", "odh-model-controller"))
+	overlay := strings.ToLower(string(platform))
+	for _, runtime := range c.ModelServing.ServingRuntimes {
+		apps = append(apps, application(runtime+"-serving-runtime", servingRuntimesPath+"/"+runtime, overlay))
+	}
+	return apps
+}
+
+// isServerless returns true if the models are served with Knative services
+func isServerless(c *dscv1alpha1.Components) bool {
+	return servingPlatform(c) == dscv1alpha1.KServe && !c.ModelServing.RawDeployment
+}
+
+// modelServingFeatures returns the feature gates the serving platform relies on
+func modelServingFeatures(c *dscv1alpha1.Components) []featuregates.Feature {
+	if servingPlatform(c) == dscv1alpha1.KServe && c.ModelServing.RawDeployment {
+		return []featuregates.Feature{featuregates.KServeRawDeployment}
+	}
+	return nil
+}
+
+// modelServingCRDs returns the CRDs of the operators the serving platform depends on
+func modelServingCRDs(c *dscv1alpha1.Components) []string {
+	if !isServerless(c) {
+		return nil
+	}
+	// The certificates of the URLs of the models are issued by the cert-manager integration of Knative
+	if c.ModelServing.Serving.AutoTLS {
+		return []string{knativeServingCRD, serviceMeshControlPlaneCRD, certificateCRD}
+	}
+	return []string{knativeServingCRD, serviceMeshControlPlaneCRD}
+}
+
+// reconcileKnativeServing makes sure the KnativeServing the models are served with exists. It is created and
+// configured when it is Managed, and required as it is otherwise.
+func reconcileKnativeServing(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	if !isServerless(&instance.Spec.Components) {
+		return nil
+	}
+	settings := instance.Spec.Components.ModelServing.Serving
+	key := client.ObjectKey{Namespace: knativeServingNamespace, Name: knativeServingName}
+	serving := &unstructured.Unstructured{}
+	serving.SetGroupVersionKind(KnativeServingGVK)
+	err := c.Get(context.TODO(), key, serving)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get KnativeServing %v: %v", key, err)
+	}
+	exists := err == nil
+
+	if settings.ManagementState == dscv1alpha1.Unmanaged {
+		if !exists {
+			return &blockedError{message: fmt.Sprintf("KnativeServing %v must be created first", key)}
+		}
+		return nil
+	}
+
+	if !exists {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: knativeServingNamespace, Labels: kfutils.WithManagedLabel(nil)}}
+		if err := c.Create(context.TODO(), ns); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create namespace %v: %v", knativeServingNamespace, err)
+		}
+		serving.SetNamespace(key.Namespace)
+		serving.SetName(key.Name)
+		serving.SetLabels(kfutils.WithManagedLabel(nil))
+	}
+	changed, err := configureKnativeServing(serving, settings)
+	if err != nil {
+		return fmt.Errorf("failed to configure KnativeServing %v: %v", key, err)
+	}
+	if !exists {
+		log.Infof("Creating KnativeServing %v.", key)
+		return c.Create(context.TODO(), serving)
+	}
+	if changed {
+		log.Infof("Updating KnativeServing %v.", key)
+		return c.Update(context.TODO(), serving)
+	}
+	return nil
+}
+
+// configureKnativeServing sets the Istio ingress, the domain and the TLS settings on the spec of the KnativeServing,
+// keeping its other settings. It returns true if the spec was changed.
+func configureKnativeServing(serving *unstructured.Unstructured, settings dscv1alpha1.KnativeServing) (bool, error) {
+	original, _, err := unstructured.NestedMap(serving.Object, "spec")
+	if err != nil {
+		return false, err
+	}
+	if err := unstructured.SetNestedField(serving.Object, true, "spec", "ingress", "istio", "enabled"); err != nil {
+		return false, err
+	}
+	if settings.Domain != "" {
+		domain := map[string]interface{}{settings.Domain: ""}
+		if err := unstructured.SetNestedField(serving.Object, domain, "spec", "config", "domain"); err != nil {
+			return false, err
+		}
+	}
+	if settings.AutoTLS {
+		network, _, err := unstructured.NestedStringMap(serving.Object, "spec", "config", "network")
+		if err != nil {
+			return false, err
+		}
+		if network == nil {
+			network = map[string]string{}
+		}
+		network["auto-tls"] = "Enabled"
+		network["http-protocol"] = "Redirected"
+		if err := unstructured.SetNestedStringMap(serving.Object, network, "spec", "config", "network"); err != nil {
+			return false, err
+		}
+	}
+	spec, _, _ := unstructured.NestedMap(serving.Object, "spec")
+	return !reflect.DeepEqual(original, spec), nil
+}
@@ -0,0 +1,151 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestModelServingApplications(t *testing.T) {
+	type testCase struct {
+		Name         string
+		ModelServing dscv1alpha1.ModelServing
+		Expected     []kfdefv1.Application
+	}
+	testCases := []testCase{
+		{
+			Name:         "default",
+			ModelServing: dscv1alpha1.ModelServing{Component: dscv1alpha1.Component{Enabled: true}},
+			Expected: []kfdefv1.Application{
+				application("model-mesh", "model-mesh"),
+				application("odh-model-controller", "odh-model-controller"),
+			},
+		},
+		{
+			Name: "kserve",
+			ModelServing: dscv1alpha1.ModelServing{
+				Component:       dscv1alpha1.Component{Enabled: true},
+				Platform:        dscv1alpha1.KServe,
+				ServingRuntimes: []string{"caikit-tgis", "ovms"},
+			},
+			Expected: []kfdefv1.Application{
+				application("kserve", "kserve"),
+				application("odh-model-controller", "odh-model-controller"),
+				application("caikit-tgis-serving-runtime", "serving-runtimes/caikit-tgis", "kserve"),
+				application("ovms-serving-runtime", "serving-runtimes/ovms", "kserve"),
+			},
+		},
+	}
+	for _, c := range testCases {
+		apps := modelServingApplications(&dscv1alpha1.Components{ModelServing: c.ModelServing})
+		if !reflect.DeepEqual(apps, c.Expected) {
+			t.Errorf("%v: expected the applications %+v, got %+v", c.Name, c.Expected, apps)
+		}
+	}
+}
+
+func TestMissingCRDs(t *testing.T) {
+	crdClient := fake.NewSimpleClientset(&apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: knativeServingCRD},
+	}).ApiextensionsV1beta1()
+	modelServing := componentNamed(t, "modelserving")
+
+	missing, err := modelServing.missingCRDs(crdClient, &dscv1alpha1.Components{})
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected ModelMesh to require no CRD, got %v, %v", missing, err)
+	}
+	kserve := &dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{Platform: dscv1alpha1.KServe}}
+	missing, err = modelServing.missingCRDs(crdClient, kserve)
+	if err != nil || !reflect.DeepEqual(missing, []string{serviceMeshControlPlaneCRD}) {
+		t.Errorf("Expected KServe to require the Service Mesh CRD, got %v, %v", missing, err)
+	}
+	kserve.ModelServing.RawDeployment = true
+	missing, err = modelServing.missingCRDs(crdClient, kserve)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected KServe raw deployments to require no CRD, got %v, %v", missing, err)
+	}
+	missing, err = components[0].missingCRDs(crdClient, kserve)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected the dashboard to require no CRD, got %v, %v", missing, err)
+	}
+}
+
+func TestConfigureKnativeServing(t *testing.T) {
+	serving := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"config": map[string]interface{}{
+				"network": map[string]interface{}{"ingress-class": "istio.ingress.networking.knative.dev"},
+			},
+			"high-availability": map[string]interface{}{"replicas": int64(2)},
+		},
+	}}
+	settings := dscv1alpha1.KnativeServing{Domain: "models.apps.example.com", AutoTLS: true}
+
+	changed, err := configureKnativeServing(serving, settings)
+	if err != nil || !changed {
+		t.Fatalf("Expected the KnativeServing to be configured, got %v, %v", changed, err)
+	}
+	expected := map[string]interface{}{
+		"config": map[string]interface{}{
+			"domain": map[string]interface{}{"models.apps.example.com": ""},
+			"network": map[string]interface{}{
+				"ingress-class": "istio.ingress.networking.knative.dev",
+				"auto-tls":      "Enabled",
+				"http-protocol": "Redirected",
+			},
+		},
+		"high-availability": map[string]interface{}{"replicas": int64(2)},
+		"ingress":           map[string]interface{}{"istio": map[string]interface{}{"enabled": true}},
+	}
+	if !reflect.DeepEqual(serving.Object["spec"], expected) {
+		t.Errorf("Expected the spec %+v, got %+v", expected, serving.Object["spec"])
+	}
+	changed, err = configureKnativeServing(serving, settings)
+	if err != nil || changed {
+		t.Errorf("Expected configuring a configured KnativeServing to leave it as it is, got %v, %v", changed, err)
+	}
+}
+
+// componentNamed returns the component with the given name
+func componentNamed(t *testing.T, name string) component {
+	for _, c := range components {
+		if c.name == name {
+			return c
+		}
+	}
+	t.Fatalf("Component %v not found", name)
+	return component{}
+}
+
+func TestModelServingFeatureGates(t *testing.T) {
+	modelServing := componentNamed(t, "modelserving")
+	raw := &dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{
+		Component:     dscv1alpha1.Component{Enabled: true},
+		Platform:      dscv1alpha1.KServe,
+		RawDeployment: true,
+	}}
+
+	gates, _ := featuregates.New().With(nil)
+	if disabled := modelServing.disabledFeatures(gates, raw); len(disabled) != 0 {
+		t.Errorf("expected the raw deployments to be enabled by default, got the disabled features %v", disabled)
+	}
+	gates, _ = featuregates.New().With(map[string]bool{string(featuregates.KServeRawDeployment): false})
+	disabled := modelServing.disabledFeatures(gates, raw)
+	if !reflect.DeepEqual(disabled, []string{string(featuregates.KServeRawDeployment)}) {
+		t.Errorf("expected the %v feature to be disabled, got %v", featuregates.KServeRawDeployment, disabled)
+	}
+	serverless := &dscv1alpha1.Components{ModelServing: dscv1alpha1.ModelServing{
+		Component: dscv1alpha1.Component{Enabled: true},
+		Platform:  dscv1alpha1.KServe,
+	}}
+	if disabled := modelServing.disabledFeatures(gates, serverless); len(disabled) != 0 {
+		t.Errorf("expected the serverless platform to rely on no feature, got the disabled features %v", disabled)
+	}
+}
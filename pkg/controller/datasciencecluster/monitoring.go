@@ -0,0 +1,69 @@
+package datasciencecluster
+
+import (
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+)
+
+const (
+	// monitoringName is the name of the monitoring component
+	monitoringName = "monitoring"
+	// prometheusRuleCRD and serviceMonitorCRD are installed by the Prometheus operator, e.g. the one of the
+	// OpenShift monitoring stack
+	prometheusRuleCRD = "prometheusrules.monitoring.coreos.com"
+	serviceMonitorCRD = "servicemonitors.monitoring.coreos.com"
+	// grafanaDashboardCRD is installed by the Grafana operator
+	grafanaDashboardCRD = "grafanadashboards.integreatly.org"
+	// monitoringPath is the odh-manifests path of the monitoring stack, with a directory per component holding its
+	// ServiceMonitors and PrometheusRules
+	monitoringPath = "monitoring"
+	// grafanaOperatorOverlay deploys the Grafana dashboards as GrafanaDashboard resources instead of ConfigMaps
+	grafanaOperatorOverlay = "grafana-operator"
+)
+
+// The monitoring component is reconciled after the components it monitors. It is added to them in init since its
+// applications depend on them.
+func init() {
+	components = append(components, component{
+		name: monitoringName,
+		enabled: func(c *dscv1alpha1.Components) bool {
+			return c.Monitoring.ManagementState == dscv1alpha1.Managed
+		},
+		applications: monitoringApplications,
+		requiredCRDs: monitoringCRDs,
+		dependencies: reconcileAlerting,
+		cleanup:      removeAlerting,
+		devFlags:     func(c *dscv1alpha1.Components) *dscv1alpha1.DevFlags { return c.Monitoring.DevFlags },
+	})
+}
+
+// monitoringApplications returns the application deploying the PrometheusRules and the Grafana dashboards shared by
+// the components, and the applications deploying the ServiceMonitors and the PrometheusRules of every enabled
+// component. Disabling a component prunes its monitoring.
+func monitoringApplications(c *dscv1alpha1.Components) []kfdefv1.Application {
+	var overlays []string
+	if c.Monitoring.GrafanaOperator {
+		overlays = append(overlays, grafanaOperatorOverlay)
+	}
+	apps := []kfdefv1.Application{application(monitoringPath, monitoringPath, overlays...)}
+	for _, monitored := range components {
+		if monitored.name == monitoringName || !monitored.enabled(c) {
+			continue
+		}
+		apps = append(apps, application(monitored.name+"-monitoring", monitoringPath+"/"+monitored.name))
+	}
+	return apps
+}
+
+// monitoringCRDs returns the CRDs of the Prometheus operator, and of the Grafana operator if the dashboards are
+// deployed with it. The AlertmanagerConfigs are required when the alerts are routed.
+func monitoringCRDs(c *dscv1alpha1.Components) []string {
+	crds := []string{prometheusRuleCRD, serviceMonitorCRD}
+	if c.Monitoring.GrafanaOperator {
+		crds = append(crds, grafanaDashboardCRD)
+	}
+	if c.Monitoring.AlertmanagerConfigSecret != "" {
+		crds = append(crds, alertmanagerConfigCRD)
+	}
+	return crds
+}
@@ -0,0 +1,61 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+)
+
+func TestMonitoringApplications(t *testing.T) {
+	type testCase struct {
+		Name       string
+		Components dscv1alpha1.Components
+		Expected   []kfdefv1.Application
+		CRDs       []string
+	}
+	testCases := []testCase{
+		{
+			Name: "no component",
+			Components: dscv1alpha1.Components{
+				Monitoring: dscv1alpha1.Monitoring{ManagementState: dscv1alpha1.Managed},
+			},
+			Expected: []kfdefv1.Application{application("monitoring", "monitoring")},
+			CRDs:     []string{prometheusRuleCRD, serviceMonitorCRD},
+		},
+		{
+			Name: "grafana operator",
+			Components: dscv1alpha1.Components{
+				Dashboard:    dscv1alpha1.Component{Enabled: true},
+				ModelServing: dscv1alpha1.ModelServing{Component: dscv1alpha1.Component{Enabled: true}},
+				TrustyAI:     dscv1alpha1.Component{Enabled: true},
+				Monitoring:   dscv1alpha1.Monitoring{ManagementState: dscv1alpha1.Managed, GrafanaOperator: true},
+			},
+			Expected: []kfdefv1.Application{
+				application("monitoring", "monitoring", "grafana-operator"),
+				application("dashboard-monitoring", "monitoring/dashboard"),
+				application("modelserving-monitoring", "monitoring/modelserving"),
+				application("trustyai-monitoring", "monitoring/trustyai"),
+			},
+			CRDs: []string{prometheusRuleCRD, serviceMonitorCRD, grafanaDashboardCRD},
+		},
+	}
+	monitoring := componentNamed(t, monitoringName)
+	for _, c := range testCases {
+		if !monitoring.enabled(&c.Components) {
+			t.Errorf("%v: expected the monitoring to be enabled", c.Name)
+		}
+		apps := monitoring.applications(&c.Components)
+		if !reflect.DeepEqual(apps, c.Expected) {
+			t.Errorf("%v: expected the applications %+v, got %+v", c.Name, c.Expected, apps)
+		}
+		crds := monitoring.requiredCRDs(&c.Components)
+		if !reflect.DeepEqual(crds, c.CRDs) {
+			t.Errorf("%v: expected the CRDs %v, got %v", c.Name, c.CRDs, crds)
+		}
+	}
+	if monitoring.enabled(&dscv1alpha1.Components{}) {
+		t.Errorf("Expected the monitoring to be removed by default")
+	}
+}
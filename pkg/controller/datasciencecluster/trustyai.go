@@ -0,0 +1,56 @@
+package datasciencecluster
+
+import (
+	"fmt"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// trustyAIName is the name of the TrustyAI component
+const trustyAIName = "trustyai"
+
+// grpcOnlyRuntimes are the ServingRuntimes only serving gRPC inference requests. The logger of the KServe
+// InferenceServices only sends the payloads of the REST requests to TrustyAI, ModelMesh sends the payloads of both.
+var grpcOnlyRuntimes = map[string]bool{
+	"caikit-tgis": true,
+	"tgis":        true,
+}
+
+// unloggedRuntimes returns the ServingRuntimes of the modelserving component whose inference payloads cannot be
+// logged to TrustyAI on its platform
+func unloggedRuntimes(c *dscv1alpha1.Components) []string {
+	if servingPlatform(c) != dscv1alpha1.KServe {
+		return nil
+	}
+	unlogged := []string{}
+	for _, runtime := range c.ModelServing.ServingRuntimes {
+		if grpcOnlyRuntimes[runtime] {
+			unlogged = append(unlogged, runtime)
+		}
+	}
+	return unlogged
+}
+
+// checkPayloadLogging blocks TrustyAI until the models it monitors can send it their inference payloads: the
+// modelserving component must be enabled, with at least one ServingRuntime whose payloads are logged if it
+// installs any. The ServingRuntimes whose payloads are not logged are only reported otherwise.
+func checkPayloadLogging(_ client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	c := &instance.Spec.Components
+	if !c.ModelServing.Enabled {
+		return &blockedError{message: "TrustyAI monitors the models of the modelserving component, which must be enabled"}
+	}
+	unlogged := unloggedRuntimes(c)
+	if len(unlogged) == 0 {
+		return nil
+	}
+	if len(unlogged) == len(c.ModelServing.ServingRuntimes) {
+		return &blockedError{message: fmt.Sprintf("the inference payloads of the ServingRuntimes %v cannot be logged "+
+			"to TrustyAI with %v, which only logs REST requests", strings.Join(unlogged, ", "), dscv1alpha1.KServe)}
+	}
+	log.Warnf("The inference payloads of the ServingRuntimes %v of DataScienceCluster %v/%v cannot be logged to TrustyAI.",
+		strings.Join(unlogged, ", "), instance.Namespace, instance.Name)
+	return nil
+}
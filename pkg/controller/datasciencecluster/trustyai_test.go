@@ -0,0 +1,55 @@
+package datasciencecluster
+
+import (
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+)
+
+func TestCheckPayloadLogging(t *testing.T) {
+	type testCase struct {
+		Name         string
+		ModelServing dscv1alpha1.ModelServing
+		Blocked      bool
+	}
+	testCases := []testCase{
+		{
+			Name:    "modelserving disabled",
+			Blocked: true,
+		},
+		{
+			Name: "modelmesh",
+			ModelServing: dscv1alpha1.ModelServing{
+				Component:       dscv1alpha1.Component{Enabled: true},
+				ServingRuntimes: []string{"caikit-tgis"},
+			},
+		},
+		{
+			Name: "kserve with a REST runtime",
+			ModelServing: dscv1alpha1.ModelServing{
+				Component:       dscv1alpha1.Component{Enabled: true},
+				Platform:        dscv1alpha1.KServe,
+				ServingRuntimes: []string{"caikit-tgis", "ovms"},
+			},
+		},
+		{
+			Name: "kserve with gRPC runtimes only",
+			ModelServing: dscv1alpha1.ModelServing{
+				Component:       dscv1alpha1.Component{Enabled: true},
+				Platform:        dscv1alpha1.KServe,
+				ServingRuntimes: []string{"caikit-tgis", "tgis"},
+			},
+			Blocked: true,
+		},
+	}
+	trustyAI := componentNamed(t, trustyAIName)
+	for _, c := range testCases {
+		instance := &dscv1alpha1.DataScienceCluster{Spec: dscv1alpha1.DataScienceClusterSpec{
+			Components: dscv1alpha1.Components{ModelServing: c.ModelServing},
+		}}
+		err := trustyAI.dependencies(nil, instance)
+		if _, blocked := err.(*blockedError); blocked != c.Blocked {
+			t.Errorf("%v: expected blocked to be %v, got %v", c.Name, c.Blocked, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package datasciencecluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/ghodss/yaml"
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// workloadsName is the name of the distributed workloads component
+	workloadsName = "workloads"
+	// localQueueCRD is installed by Kueue
+	localQueueCRD = "localqueues.kueue.x-k8s.io"
+	// queueNameLabel is the label of the workloads Kueue admits from a LocalQueue
+	queueNameLabel = "kueue.x-k8s.io/queue-name"
+	// rayClusterTemplatesLabel is the label of the ConfigMaps of the RayCluster templates, for the dashboard and the
+	// notebooks to list them
+	rayClusterTemplatesLabel = "opendatahub.io/ray-cluster-templates"
+	// localQueueKey is the key of the ConfigMap of the templates holding the LocalQueue they are queued in
+	localQueueKey = "local-queue"
+)
+
+// RayImage is the image of the RayCluster templates that do not set theirs
+var RayImage = "quay.io/project-codeflare/ray:2.5.0-py38-cu116"
+
+// defaultRayClusterTemplates are offered when the workloads component does not set any
+var defaultRayClusterTemplates = []dscv1alpha1.RayClusterTemplate{
+	rayClusterTemplate("small", 2, "2", "8Gi", "1", "4Gi"),
+	rayClusterTemplate("medium", 4, "2", "8Gi", "4", "16Gi"),
+	rayClusterTemplate("large", 8, "4", "16Gi", "8", "32Gi"),
+}
+
+// rayClusterTemplate returns a template whose containers request and are limited to the resources
+func rayClusterTemplate(name string, workers int32, headCPU, headMemory, workerCPU, workerMemory string) dscv1alpha1.RayClusterTemplate {
+	resources := func(cpu, memory string) corev1.ResourceRequirements {
+		list := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}
+		return corev1.ResourceRequirements{Requests: list, Limits: list}
+	}
+	return dscv1alpha1.RayClusterTemplate{
+		Name:            name,
+		Workers:         workers,
+		HeadResources:   resources(headCPU, headMemory),
+		WorkerResources: resources(workerCPU, workerMemory),
+	}
+}
+
+// workloadsApplications returns the applications deploying the KubeRay operator and the CodeFlare operator
+func workloadsApplications(_ *dscv1alpha1.Components) []kfdefv1.Application {
+	return []kfdefv1.Application{
+		application("kuberay-operator", "ray/operator"),
+		application("codeflare-operator", "codeflare-stack"),
+	}
+}
+
+// workloadsCRDs returns the CRDs of Kueue if the RayClusters are queued in an existing LocalQueue. The CRDs of
+// the Kueue component are checked by the component.
+func workloadsCRDs(c *dscv1alpha1.Components) []string {
+	if c.Workloads.LocalQueue != "" && !kueueEnabled(c) {
+		return []string{localQueueCRD}
+	}
+	return nil
+}
+
+// workloadsLocalQueue returns the LocalQueue the RayClusters are queued in, defaulting to the one the Kueue
+// component creates in the data science projects. It is empty if they are not queued.
+func workloadsLocalQueue(c *dscv1alpha1.Components) string {
+	if c.Workloads.LocalQueue != "" {
+		return c.Workloads.LocalQueue
+	}
+	if kueueEnabled(c) {
+		return localQueueName(c)
+	}
+	return ""
+}
+
+// rayClusterTemplatesName returns the name of the ConfigMap of the RayCluster templates of the DataScienceCluster
+func rayClusterTemplatesName(instance *dscv1alpha1.DataScienceCluster) string {
+	return instance.Name + "-ray-cluster-templates"
+}
+
+// rayClusterTemplatesData returns the RayClusters of the templates of the workloads component by file name, and
+// the LocalQueue they are queued in
+func rayClusterTemplatesData(c *dscv1alpha1.Components) (map[string]string, error) {
+	templates := c.Workloads.RayClusterTemplates
+	if len(templates) == 0 {
+		templates = defaultRayClusterTemplates
+	}
+	localQueue := workloadsLocalQueue(c)
+	data := map[string]string{}
+	if localQueue != "" {
+		data[localQueueKey] = localQueue
+	}
+	for _, t := range templates {
+		if t.Name == "" {
+			return nil, fmt.Errorf("invalid RayCluster template: it has no name")
+		}
+		if t.Workers < 0 {
+			return nil, fmt.Errorf("invalid RayCluster template %v: negative number of workers", t.Name)
+		}
+		if _, found := data[t.Name+".yaml"]; found {
+			return nil, fmt.Errorf("invalid RayCluster template %v: the name is used twice", t.Name)
+		}
+		image := t.Image
+		if image == "" {
+			image = RayImage
+		}
+		metadata := map[string]interface{}{"name": t.Name}
+		if localQueue != "" {
+			metadata["labels"] = map[string]interface{}{queueNameLabel: localQueue}
+		}
+		cluster := map[string]interface{}{
+			"apiVersion": "ray.io/v1alpha1",
+			"kind":       "RayCluster",
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"headGroupSpec": map[string]interface{}{
+					"rayStartParams": map[string]interface{}{"dashboard-host": "0.0.0.0"},
+					"template":       rayPodTemplate("ray-head", image, t.HeadResources),
+				},
+				"workerGroupSpecs": []interface{}{
+					map[string]interface{}{
+						"groupName":      "workers",
+						"replicas":       t.Workers,
+						"minReplicas":    t.Workers,
+						"maxReplicas":    t.Workers,
+						"rayStartParams": map[string]interface{}{},
+						"template":       rayPodTemplate("ray-worker", image, t.WorkerResources),
+					},
+				},
+			},
+		}
+		manifest, err := yaml.Marshal(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render RayCluster template %v: %v", t.Name, err)
+		}
+		data[t.Name+".yaml"] = string(manifest)
+	}
+	return data, nil
+}
+
+// rayPodTemplate returns the pod template of the head or the workers of a RayCluster
+func rayPodTemplate(name string, image string, resources corev1.ResourceRequirements) map[string]interface{} {
+	return map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": name, "image": image, "resources": resources},
+			},
+		},
+	}
+}
+
+// reconcileRayClusterTemplates creates or updates the ConfigMap of the RayCluster templates of the workloads
+// component, owned by the DataScienceCluster
+func reconcileRayClusterTemplates(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	data, err := rayClusterTemplatesData(&instance.Spec.Components)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKey{Namespace: instance.Namespace, Name: rayClusterTemplatesName(instance)}
+	labels := kfutils.WithManagedLabel(map[string]string{rayClusterTemplatesLabel: "true"})
+	cm := &corev1.ConfigMap{}
+	err = c.Get(context.TODO(), key, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get ConfigMap %v: %v", key, err)
+	}
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(instance, dscv1alpha1.SchemeGroupVersion.WithKind("DataScienceCluster")),
+				},
+			},
+			Data: data,
+		}
+		log.Infof("Creating ConfigMap %v.", key)
+		return c.Create(context.TODO(), cm)
+	}
+	if reflect.DeepEqual(cm.Data, data) && cm.Labels[rayClusterTemplatesLabel] == "true" && kfutils.IsManaged(cm) {
+		return nil
+	}
+	cm.Data = data
+	cm.Labels = kfutils.WithManagedLabel(cm.Labels)
+	cm.Labels[rayClusterTemplatesLabel] = "true"
+	log.Infof("Updating ConfigMap %v.", key)
+	return c.Update(context.TODO(), cm)
+}
+
+// removeRayClusterTemplates deletes the ConfigMap of the RayCluster templates once the workloads are disabled
+func removeRayClusterTemplates(c client.Client, instance *dscv1alpha1.DataScienceCluster) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: rayClusterTemplatesName(instance), Namespace: instance.Namespace}}
+	err := c.Delete(context.TODO(), cm)
+	if err == nil {
+		log.Infof("Deleted ConfigMap %v/%v.", cm.Namespace, cm.Name)
+		return nil
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return fmt.Errorf("failed to delete ConfigMap %v/%v: %v", cm.Namespace, cm.Name, err)
+}
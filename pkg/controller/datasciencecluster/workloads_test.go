@@ -0,0 +1,74 @@
+package datasciencecluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+)
+
+func TestRayClusterTemplatesData(t *testing.T) {
+	data, err := rayClusterTemplatesData(&dscv1alpha1.Components{})
+	if err != nil {
+		t.Fatalf("Failed to render the default templates: %v", err)
+	}
+	if len(data) != len(defaultRayClusterTemplates) || data["small.yaml"] == "" {
+		t.Errorf("Expected the default templates, got %v", data)
+	}
+
+	components := &dscv1alpha1.Components{Workloads: dscv1alpha1.Workloads{
+		RayClusterTemplates: []dscv1alpha1.RayClusterTemplate{{Name: "gpu", Image: "quay.io/example/ray:gpu", Workers: 3}},
+		LocalQueue:          "team-queue",
+	}}
+	data, err = rayClusterTemplatesData(components)
+	if err != nil {
+		t.Fatalf("Failed to render the templates: %v", err)
+	}
+	if data[localQueueKey] != "team-queue" {
+		t.Errorf("Expected the LocalQueue to be exposed, got %v", data)
+	}
+	cluster := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(data["gpu.yaml"]), &cluster); err != nil {
+		t.Fatalf("Invalid RayCluster: %v", err)
+	}
+	labels := cluster["metadata"].(map[string]interface{})["labels"]
+	if !reflect.DeepEqual(labels, map[string]interface{}{queueNameLabel: "team-queue"}) {
+		t.Errorf("Expected the RayCluster to be queued, got the labels %v", labels)
+	}
+	workers := cluster["spec"].(map[string]interface{})["workerGroupSpecs"].([]interface{})[0].(map[string]interface{})
+	if workers["replicas"] != float64(3) {
+		t.Errorf("Expected 3 workers, got %v", workers["replicas"])
+	}
+	container := workers["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+	if image := container.(map[string]interface{})["image"]; image != "quay.io/example/ray:gpu" {
+		t.Errorf("Expected the image of the template, got %v", image)
+	}
+
+	if crds := workloadsCRDs(components); !reflect.DeepEqual(crds, []string{localQueueCRD}) {
+		t.Errorf("Expected Kueue to be required, got %v", crds)
+	}
+	workloads := &components.Workloads
+	workloads.RayClusterTemplates = append(workloads.RayClusterTemplates, dscv1alpha1.RayClusterTemplate{Name: "gpu"})
+	if _, err := rayClusterTemplatesData(components); err == nil {
+		t.Errorf("Expected the templates with the same name to be rejected")
+	}
+}
+
+func TestWorkloadsLocalQueue(t *testing.T) {
+	components := &dscv1alpha1.Components{}
+	if queue := workloadsLocalQueue(components); queue != "" {
+		t.Errorf("Expected the RayClusters not to be queued without Kueue, got %v", queue)
+	}
+	components.Kueue.ManagementState = dscv1alpha1.Managed
+	if queue := workloadsLocalQueue(components); queue != defaultLocalQueue {
+		t.Errorf("Expected the LocalQueue of the Kueue component, got %v", queue)
+	}
+	if crds := workloadsCRDs(components); crds != nil {
+		t.Errorf("Expected the CRDs of the Kueue component not to be required, got %v", crds)
+	}
+	components.Workloads.LocalQueue = "team-queue"
+	if queue := workloadsLocalQueue(components); queue != "team-queue" {
+		t.Errorf("Expected the LocalQueue of the workloads, got %v", queue)
+	}
+}
@@ -0,0 +1,310 @@
+package datascienceproject
+
+import (
+	"context"
+	"fmt"
+
+	dspv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datascienceproject/v1alpha1"
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	kfdefcontroller "github.com/kubeflow/kfctl/v3/pkg/controller/kfdef"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// serviceMeshFinalizer removes the namespace of a project enrolled into the service mesh from the
+// ServiceMeshMemberRoll once the project is deleted
+const serviceMeshFinalizer = "datascienceproject.opendatahub.io/service-mesh"
+
+// AddToManager adds the DataScienceProject controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("datascienceproject-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileDataScienceProject{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("datascienceproject-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for datascienceproject.")
+	c, err := controller.New("datascienceproject-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource DataScienceProject
+	err = c.Watch(&source.Kind{Type: &dspv1alpha1.DataScienceProject{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the namespaces and their settings and requeue the owner DataScienceProject, so that
+	// the resources edited or deleted by hand are restored
+	for _, t := range []runtime.Object{&corev1.Namespace{}, &rbacv1.RoleBinding{}, &corev1.ResourceQuota{},
+		&corev1.LimitRange{}, &networkingv1.NetworkPolicy{}} {
+		err := c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &dspv1alpha1.DataScienceProject{},
+		}, kfutils.ManagedPredicate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for the changes of the operator settings the NetworkPolicies depend on and requeue all the
+	// DataScienceProjects
+	kubeclient := mgr.GetClient()
+	events := operatorconfig.Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.NetworkPolicy != next.NetworkPolicy || previous.ApplicationsNamespace != next.ApplicationsNamespace ||
+			previous.Monitoring != next.Monitoring
+	})
+	return c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			instances := &dspv1alpha1.DataScienceProjectList{}
+			if err := kubeclient.List(context.TODO(), instances); err != nil {
+				log.Warnf("Failed to list the DataScienceProjects for OperatorConfig %v: %v", a.Meta.GetName(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, instance := range instances.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name}})
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileDataScienceProject implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDataScienceProject{}
+
+// ReconcileDataScienceProject reconciles a DataScienceProject object by provisioning its namespace
+type ReconcileDataScienceProject struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile provisions the namespace of a DataScienceProject, named after it, along with the RoleBindings of its
+// groups, its ResourceQuota, LimitRange and NetworkPolicy, unless the NetworkPolicies are Unmanaged, and enrolls it
+// into the service mesh. The namespace is owned by the DataScienceProject and garbage collected with it, along with
+// everything it holds.
+func (r *ReconcileDataScienceProject) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling DataScienceProject. Request.Name: %v.", request.Name)
+
+	instance := &dspv1alpha1.DataScienceProject{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	finalizers := sets.NewString(instance.GetFinalizers()...)
+	if instance.GetDeletionTimestamp() != nil {
+		if !finalizers.Has(serviceMeshFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		if err := kfdefcontroller.UnenrollNamespace(r.client, instance.Name); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, r.setServiceMeshFinalizer(instance, false)
+	}
+
+	// Invalid specs are not retried, they are reconciled again once they are fixed
+	if err := validate(instance); err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "InvalidSpec", "%v", err)
+		return reconcile.Result{}, r.updateStatus(instance, dspv1alpha1.DataScienceProjectFailed, err.Error())
+	}
+	if err := r.provision(instance); err != nil {
+		log.Errorf("Failed to provision DataScienceProject %v: %v", request.Name, err)
+		if statusErr := r.updateStatus(instance, dspv1alpha1.DataScienceProjectFailed, err.Error()); statusErr != nil {
+			log.Warnf("Failed to update the status of DataScienceProject %v: %v", request.Name, statusErr)
+		}
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, r.updateStatus(instance, dspv1alpha1.DataScienceProjectReady, "the namespace is provisioned")
+}
+
+// provision creates or updates the namespace of instance and its settings
+func (r *ReconcileDataScienceProject) provision(instance *dspv1alpha1.DataScienceProject) error {
+	// Existing namespaces are not taken over, they would be deleted along with the project
+	desiredNamespace := namespace(instance)
+	ns := &corev1.Namespace{}
+	err := r.client.Get(context.TODO(), client.ObjectKey{Name: instance.Name}, ns)
+	if err == nil && !metav1.IsControlledBy(ns, instance) {
+		return fmt.Errorf("namespace %v exists and is not owned by the DataScienceProject", instance.Name)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get namespace %v: %v", instance.Name, err)
+	}
+	ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: instance.Name}}
+	err = r.createOrUpdate(instance, ns, func() error {
+		ns.Labels = mergeStrings(ns.Labels, desiredNamespace.Labels)
+		ns.Annotations = mergeStrings(ns.Annotations, desiredNamespace.Annotations)
+		if instance.Spec.Description == "" {
+			delete(ns.Annotations, descriptionAnnotation)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for role, desired := range roleBindings(instance) {
+		if desired == nil {
+			binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: roleBindingName(role), Namespace: instance.Name}}
+			if err := r.client.Delete(context.TODO(), binding); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete RoleBinding %v: %v", binding.Name, err)
+			}
+			continue
+		}
+		binding := &rbacv1.RoleBinding{ObjectMeta: metaKey(desired.ObjectMeta)}
+		err := r.createOrUpdate(instance, binding, func() error {
+			binding.Labels, binding.Subjects = desired.Labels, desired.Subjects
+			// The role of a binding is immutable
+			if binding.CreationTimestamp.IsZero() {
+				binding.RoleRef = desired.RoleRef
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	desiredQuota := resourceQuota(instance)
+	quota := &corev1.ResourceQuota{ObjectMeta: metaKey(desiredQuota.ObjectMeta)}
+	err = r.createOrUpdate(instance, quota, func() error {
+		quota.Labels, quota.Spec = desiredQuota.Labels, desiredQuota.Spec
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	desiredLimits := limitRange(instance)
+	limits := &corev1.LimitRange{ObjectMeta: metaKey(desiredLimits.ObjectMeta)}
+	err = r.createOrUpdate(instance, limits, func() error {
+		limits.Labels, limits.Spec = desiredLimits.Labels, desiredLimits.Spec
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The NetworkPolicies left to the administrators are neither created nor restored
+	if settings := operatorconfig.Current(); settings.NetworkPolicy == ocv1alpha1.NetworkPolicyManaged {
+		desiredPolicy := networkPolicy(instance, settings)
+		policy := &networkingv1.NetworkPolicy{ObjectMeta: metaKey(desiredPolicy.ObjectMeta)}
+		err = r.createOrUpdate(instance, policy, func() error {
+			policy.Labels, policy.Spec = desiredPolicy.Labels, desiredPolicy.Spec
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The finalizer is set before enrolling the namespace, so that it is always removed from the mesh
+	if instance.Spec.ServiceMesh {
+		if err := r.setServiceMeshFinalizer(instance, true); err != nil {
+			return err
+		}
+		return kfdefcontroller.EnrollNamespace(r.client, instance.Name)
+	}
+	if sets.NewString(instance.GetFinalizers()...).Has(serviceMeshFinalizer) {
+		if err := kfdefcontroller.UnenrollNamespace(r.client, instance.Name); err != nil {
+			return err
+		}
+		return r.setServiceMeshFinalizer(instance, false)
+	}
+	return nil
+}
+
+// setServiceMeshFinalizer adds or removes the service mesh finalizer of instance
+func (r *ReconcileDataScienceProject) setServiceMeshFinalizer(instance *dspv1alpha1.DataScienceProject, set bool) error {
+	finalizers := sets.NewString(instance.GetFinalizers()...)
+	if finalizers.Has(serviceMeshFinalizer) == set {
+		return nil
+	}
+	if set {
+		finalizers.Insert(serviceMeshFinalizer)
+	} else {
+		finalizers.Delete(serviceMeshFinalizer)
+	}
+	instance.SetFinalizers(finalizers.List())
+	return r.client.Update(context.TODO(), instance)
+}
+
+// createOrUpdate creates obj, or updates the live object, after mutating it with mutate and making instance its
+// controller
+func (r *ReconcileDataScienceProject) createOrUpdate(instance *dspv1alpha1.DataScienceProject, obj runtime.Object, mutate func() error) error {
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, obj, func() error {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(instance, obj.(metav1.Object), r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision %T %v: %v", obj, obj.(metav1.Object).GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("%T %v of DataScienceProject %v %v.", obj, obj.(metav1.Object).GetName(), instance.Name, result)
+	}
+	return nil
+}
+
+// updateStatus updates the status of instance if it changed
+func (r *ReconcileDataScienceProject) updateStatus(instance *dspv1alpha1.DataScienceProject, phase dspv1alpha1.DataScienceProjectPhase, message string) error {
+	status := dspv1alpha1.DataScienceProjectStatus{
+		ObservedGeneration: instance.Generation,
+		Phase:              phase,
+		Message:            message,
+	}
+	if phase == dspv1alpha1.DataScienceProjectReady {
+		status.Namespace = instance.Name
+	}
+	if instance.Status == status {
+		return nil
+	}
+	instance.Status = status
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// mergeStrings returns live with the keys of desired set to their values, keeping the keys set by others
+func mergeStrings(live map[string]string, desired map[string]string) map[string]string {
+	if live == nil {
+		live = map[string]string{}
+	}
+	for k, v := range desired {
+		live[k] = v
+	}
+	return live
+}
+
+// metaKey returns the name and the namespace of meta
+func metaKey(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace}
+}
@@ -0,0 +1,216 @@
+package datascienceproject
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	dspv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datascienceproject/v1alpha1"
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// dashboardLabel makes the namespace a data science project of the dashboard, and of the Kueue component
+	dashboardLabel         = "opendatahub.io/dashboard"
+	projectNameLabel       = "datascienceproject.opendatahub.io/name"
+	displayNameAnnotation  = "openshift.io/display-name"
+	descriptionAnnotation  = "openshift.io/description"
+	resourcesName          = "data-science-project"
+	roleBindingPrefix      = "data-science-project-"
+	namespaceNameLabel     = "kubernetes.io/metadata.name"
+	policyGroupLabel       = "network.openshift.io/policy-group"
+	ingressPolicyGroup     = "ingress"
+	monitoringPolicyGroup  = "monitoring"
+	defaultContainerCPU    = "100m"
+	defaultContainerMemory = "256Mi"
+
+	// ResourceQuotaName is the name of the ResourceQuota of the namespaces of the projects
+	ResourceQuotaName = resourcesName
+)
+
+var (
+	// roles are the ClusterRoles the groups can be bound to, in the order of their RoleBindings
+	roles = []dspv1alpha1.ProjectRole{dspv1alpha1.ProjectAdmin, dspv1alpha1.ProjectEdit, dspv1alpha1.ProjectView}
+
+	// defaultQuota is the hard limits of the ResourceQuota of the projects that do not set theirs
+	defaultQuota = corev1.ResourceList{
+		corev1.ResourceRequestsCPU:            resource.MustParse("8"),
+		corev1.ResourceRequestsMemory:         resource.MustParse("32Gi"),
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("10"),
+		corev1.ResourceRequestsStorage:        resource.MustParse("100Gi"),
+	}
+)
+
+// validate returns an error describing the first invalid field of the project
+func validate(instance *dspv1alpha1.DataScienceProject) error {
+	if errs := validation.IsDNS1123Label(instance.Name); len(errs) > 0 {
+		return fmt.Errorf("the name of the project is not a valid namespace name: %v", strings.Join(errs, ", "))
+	}
+	for i, group := range instance.Spec.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("groups[%v].name is required", i)
+		}
+		if group.Role != "" && !validRole(group.Role) {
+			return fmt.Errorf("groups[%v].role must be admin, edit or view, got %q", i, group.Role)
+		}
+	}
+	for i, ns := range instance.Spec.AllowedNamespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("allowedNamespaces[%v] is not a valid namespace name: %v", i, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// validRole returns true if role is one of the roles the groups can be bound to
+func validRole(role dspv1alpha1.ProjectRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// labels returns the labels of the resources provisioned for a project
+func labels(instance *dspv1alpha1.DataScienceProject) map[string]string {
+	return kfutils.WithManagedLabel(map[string]string{projectNameLabel: instance.Name})
+}
+
+// namespace returns the namespace of a project, labeled as a data science project of the dashboard
+func namespace(instance *dspv1alpha1.DataScienceProject) *corev1.Namespace {
+	nsLabels := labels(instance)
+	nsLabels[dashboardLabel] = "true"
+	displayName := instance.Spec.DisplayName
+	if displayName == "" {
+		displayName = instance.Name
+	}
+	annotations := map[string]string{displayNameAnnotation: displayName}
+	if instance.Spec.Description != "" {
+		annotations[descriptionAnnotation] = instance.Spec.Description
+	}
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: instance.Name, Labels: nsLabels, Annotations: annotations},
+	}
+}
+
+// roleBindingName returns the name of the RoleBinding of the groups of a role
+func roleBindingName(role dspv1alpha1.ProjectRole) string {
+	return roleBindingPrefix + string(role)
+}
+
+// roleBindings returns the RoleBindings of the groups of a project by role. The roles no group is bound to map to
+// nil.
+func roleBindings(instance *dspv1alpha1.DataScienceProject) map[dspv1alpha1.ProjectRole]*rbacv1.RoleBinding {
+	groups := map[dspv1alpha1.ProjectRole][]string{}
+	for _, group := range instance.Spec.Groups {
+		role := group.Role
+		if role == "" {
+			role = dspv1alpha1.ProjectEdit
+		}
+		groups[role] = append(groups[role], group.Name)
+	}
+	bindings := map[dspv1alpha1.ProjectRole]*rbacv1.RoleBinding{}
+	for _, role := range roles {
+		names := groups[role]
+		if len(names) == 0 {
+			bindings[role] = nil
+			continue
+		}
+		sort.Strings(names)
+		binding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: roleBindingName(role), Namespace: instance.Name, Labels: labels(instance)},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: string(role)},
+		}
+		for i, name := range names {
+			if i > 0 && names[i-1] == name {
+				continue
+			}
+			binding.Subjects = append(binding.Subjects, rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: name})
+		}
+		bindings[role] = binding
+	}
+	return bindings
+}
+
+// Quota returns the hard limits of the ResourceQuota of a project
+func Quota(instance *dspv1alpha1.DataScienceProject) corev1.ResourceList {
+	if len(instance.Spec.Quota) == 0 {
+		return defaultQuota.DeepCopy()
+	}
+	return instance.Spec.Quota.DeepCopy()
+}
+
+// DefaultContainerRequests returns the requests of the containers of the projects that do not set theirs, as
+// defaulted by the LimitRange of the projects
+func DefaultContainerRequests() corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(defaultContainerCPU),
+		corev1.ResourceMemory: resource.MustParse(defaultContainerMemory),
+	}
+}
+
+// resourceQuota returns the ResourceQuota of a project
+func resourceQuota(instance *dspv1alpha1.DataScienceProject) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: ResourceQuotaName, Namespace: instance.Name, Labels: labels(instance)},
+		Spec:       corev1.ResourceQuotaSpec{Hard: Quota(instance)},
+	}
+}
+
+// limitRange returns the LimitRange of a project, defaulting the requests of the containers so that the pods
+// that do not set theirs are admitted by the ResourceQuota
+func limitRange(instance *dspv1alpha1.DataScienceProject) *corev1.LimitRange {
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: resourcesName, Namespace: instance.Name, Labels: labels(instance)},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				DefaultRequest: DefaultContainerRequests(),
+			}},
+		},
+	}
+}
+
+// networkPolicy returns the NetworkPolicy of a project, allowing the connections from its own pods, the
+// OpenShift router, the applications namespace and the allowed namespaces of the project, and from monitoring
+// unless the monitoring of the settings is Removed
+func networkPolicy(instance *dspv1alpha1.DataScienceProject, settings ocv1alpha1.OperatorConfigSpec) *networkingv1.NetworkPolicy {
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{}},
+		{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{policyGroupLabel: ingressPolicyGroup}}},
+	}
+	namespaces := []string{settings.ApplicationsNamespace}
+	if settings.Monitoring.ManagementState != ocv1alpha1.Removed {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{policyGroupLabel: monitoringPolicyGroup}},
+		})
+		namespaces = append(namespaces, settings.Monitoring.Namespace)
+	}
+	namespaces = append(namespaces, instance.Spec.AllowedNamespaces...)
+	allowed := map[string]bool{}
+	for _, ns := range namespaces {
+		if ns == "" || allowed[ns] {
+			continue
+		}
+		allowed[ns] = true
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: ns}},
+		})
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: resourcesName, Namespace: instance.Name, Labels: labels(instance)},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package datascienceproject
+
+import (
+	"reflect"
+	"testing"
+
+	dspv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datascienceproject/v1alpha1"
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func project(name string, spec dspv1alpha1.DataScienceProjectSpec) *dspv1alpha1.DataScienceProject {
+	return &dspv1alpha1.DataScienceProject{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Instance *dspv1alpha1.DataScienceProject
+		Valid    bool
+	}
+	testCases := []testCase{
+		{
+			Name:     "defaults",
+			Instance: project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{}),
+			Valid:    true,
+		},
+		{
+			Name: "groups",
+			Instance: project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+				Groups:            []dspv1alpha1.GroupBinding{{Name: "data-scientists"}, {Name: "leads", Role: dspv1alpha1.ProjectAdmin}},
+				AllowedNamespaces: []string{"opendatahub"},
+			}),
+			Valid: true,
+		},
+		{
+			Name:     "invalid namespace name",
+			Instance: project("fraud.detection", dspv1alpha1.DataScienceProjectSpec{}),
+		},
+		{
+			Name: "group without name",
+			Instance: project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+				Groups: []dspv1alpha1.GroupBinding{{Role: dspv1alpha1.ProjectView}},
+			}),
+		},
+		{
+			Name: "invalid role",
+			Instance: project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+				Groups: []dspv1alpha1.GroupBinding{{Name: "data-scientists", Role: "cluster-admin"}},
+			}),
+		},
+		{
+			Name: "invalid allowed namespace",
+			Instance: project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+				AllowedNamespaces: []string{"Open Data Hub"},
+			}),
+		},
+	}
+
+	for _, c := range testCases {
+		err := validate(c.Instance)
+		if c.Valid && err != nil {
+			t.Errorf("Case %v: expected the project to be valid, got %v", c.Name, err)
+		}
+		if !c.Valid && err == nil {
+			t.Errorf("Case %v: expected the project to be invalid", c.Name)
+		}
+	}
+}
+
+func TestRoleBindings(t *testing.T) {
+	instance := project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+		Groups: []dspv1alpha1.GroupBinding{
+			{Name: "interns"},
+			{Name: "data-scientists", Role: dspv1alpha1.ProjectEdit},
+			{Name: "interns"},
+		},
+	})
+	bindings := roleBindings(instance)
+	if bindings[dspv1alpha1.ProjectAdmin] != nil || bindings[dspv1alpha1.ProjectView] != nil {
+		t.Errorf("Expected no binding of the roles without groups, got %v", bindings)
+	}
+	edit := bindings[dspv1alpha1.ProjectEdit]
+	if edit == nil || edit.Name != "data-science-project-edit" || edit.RoleRef.Name != "edit" {
+		t.Fatalf("Expected the edit RoleBinding, got %v", edit)
+	}
+	expected := []rbacv1.Subject{
+		{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "data-scientists"},
+		{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "interns"},
+	}
+	if !reflect.DeepEqual(edit.Subjects, expected) {
+		t.Errorf("Expected the sorted groups once, got %v", edit.Subjects)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	ns := namespace(project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{}))
+	if ns.Labels[dashboardLabel] != "true" || ns.Annotations[displayNameAnnotation] != "fraud-detection" {
+		t.Errorf("Expected a data science project named after the project, got %v %v", ns.Labels, ns.Annotations)
+	}
+	if _, found := ns.Annotations[descriptionAnnotation]; found {
+		t.Errorf("Expected no description, got %v", ns.Annotations)
+	}
+}
+
+func TestResourceQuota(t *testing.T) {
+	instance := project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{})
+	if hard := resourceQuota(instance).Spec.Hard; !reflect.DeepEqual(hard, defaultQuota) {
+		t.Errorf("Expected the default quota, got %v", hard)
+	}
+	instance.Spec.Quota = corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("2")}
+	if hard := resourceQuota(instance).Spec.Hard; !reflect.DeepEqual(hard, instance.Spec.Quota) {
+		t.Errorf("Expected the quota of the project, got %v", hard)
+	}
+}
+
+func TestNetworkPolicy(t *testing.T) {
+	instance := project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{AllowedNamespaces: []string{"opendatahub", "odh-models"}})
+	settings := ocv1alpha1.OperatorConfigSpec{
+		ApplicationsNamespace: "opendatahub",
+		Monitoring:            ocv1alpha1.Monitoring{ManagementState: ocv1alpha1.Managed},
+	}
+	peers := networkPolicy(instance, settings).Spec.Ingress[0].From
+	if len(peers) != 5 {
+		t.Fatalf("Expected the project, the router, monitoring, opendatahub and odh-models to be allowed, got %v", peers)
+	}
+	for i, ns := range []string{"opendatahub", "odh-models"} {
+		if selector := peers[3+i].NamespaceSelector; selector.MatchLabels[namespaceNameLabel] != ns {
+			t.Errorf("Expected the %v namespace to be allowed, got %v", ns, selector)
+		}
+	}
+
+	settings = ocv1alpha1.OperatorConfigSpec{
+		ApplicationsNamespace: "odh-applications",
+		Monitoring:            ocv1alpha1.Monitoring{ManagementState: ocv1alpha1.Removed, Namespace: "prometheus"},
+	}
+	peers = networkPolicy(project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{}), settings).Spec.Ingress[0].From
+	if len(peers) != 3 {
+		t.Fatalf("Expected the project, the router and odh-applications to be allowed, got %v", peers)
+	}
+	if selector := peers[2].NamespaceSelector; selector.MatchLabels[namespaceNameLabel] != "odh-applications" {
+		t.Errorf("Expected the applications namespace to be allowed, got %v", selector)
+	}
+}
+
+func TestResourcesPassPredicate(t *testing.T) {
+	instance := project("fraud-detection", dspv1alpha1.DataScienceProjectSpec{
+		Groups: []dspv1alpha1.GroupBinding{{Name: "data-scientists", Role: dspv1alpha1.ProjectEdit}},
+	})
+	objects := []metav1.Object{namespace(instance), roleBindings(instance)[dspv1alpha1.ProjectEdit], resourceQuota(instance),
+		limitRange(instance), networkPolicy(instance, ocv1alpha1.OperatorConfigSpec{})}
+	for _, obj := range objects {
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: obj}) {
+			t.Errorf("Expected the events of %v to pass the predicate of the watches, got labels %v", obj.GetName(), obj.GetLabels())
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package externalsecret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// SecretStore and ClusterSecretStore are the kinds of the secret stores of the External Secrets Operator
+	SecretStore        = "SecretStore"
+	ClusterSecretStore = "ClusterSecretStore"
+
+	defaultRefreshInterval = "1h"
+)
+
+// GVK is the kind of the ExternalSecrets of the External Secrets Operator
+var GVK = schema.GroupVersionKind{Group: "external-secrets.io", Version: "v1beta1", Kind: "ExternalSecret"}
+
+// Source is a secret of an external secret store, e.g. a path of a Vault, read through the External Secrets
+// Operator
+type Source struct {
+	// StoreName is the name of the SecretStore or the ClusterSecretStore of the external secret store
+	StoreName string
+	// StoreKind is SecretStore or ClusterSecretStore, SecretStore if empty
+	StoreKind string
+	// Key is the key of the secret in the store, e.g. its path in a Vault. Every property of the secret is a key
+	// of the Secret.
+	Key string
+	// RefreshInterval is the interval the Secret is read from the store again at, 1h if empty
+	RefreshInterval string
+}
+
+// Validate returns an error describing the first invalid field of source, prefixed by field
+func Validate(field string, source Source) error {
+	switch {
+	case source.StoreName == "":
+		return fmt.Errorf("%v.storeName is required", field)
+	case source.StoreKind != "" && source.StoreKind != SecretStore && source.StoreKind != ClusterSecretStore:
+		return fmt.Errorf("%v.storeKind must be %v or %v, got %q", field, SecretStore, ClusterSecretStore, source.StoreKind)
+	case source.Key == "":
+		return fmt.Errorf("%v.key is required", field)
+	}
+	return nil
+}
+
+// ExternalSecret returns the ExternalSecret creating the Secret named name in namespace from source. The Secret is
+// owned by the ExternalSecret and deleted along with it.
+func ExternalSecret(namespace string, name string, labels map[string]string, source Source) *unstructured.Unstructured {
+	kind, refresh := source.StoreKind, source.RefreshInterval
+	if kind == "" {
+		kind = SecretStore
+	}
+	if refresh == "" {
+		refresh = defaultRefreshInterval
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"refreshInterval": refresh,
+			"secretStoreRef": map[string]interface{}{
+				"name": source.StoreName,
+				"kind": kind,
+			},
+			"target": map[string]interface{}{
+				"name":           name,
+				"creationPolicy": "Owner",
+			},
+			"dataFrom": []interface{}{
+				map[string]interface{}{"extract": map[string]interface{}{"key": source.Key}},
+			},
+		},
+	}}
+	obj.SetGroupVersionKind(GVK)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+// Apply creates the desired ExternalSecret, or updates the labels and the spec of the live one, making owner its
+// controller. It returns an error naming the External Secrets Operator if its CRDs are not installed.
+func Apply(c client.Client, scheme *runtime.Scheme, owner metav1.Object, desired *unstructured.Unstructured) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GVK)
+	obj.SetNamespace(desired.GetNamespace())
+	obj.SetName(desired.GetName())
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), c, obj, func() error {
+		obj.SetLabels(desired.GetLabels())
+		if !reflect.DeepEqual(obj.Object["spec"], desired.Object["spec"]) {
+			obj.Object["spec"] = desired.Object["spec"]
+		}
+		return controllerutil.SetControllerReference(owner, obj, scheme)
+	})
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("the External Secrets Operator is not installed: %v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to provision ExternalSecret %v: %v", desired.GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("ExternalSecret %v/%v of %v %v.", desired.GetNamespace(), desired.GetName(), owner.GetName(), result)
+	}
+	return nil
+}
+
+// Prune deletes the ExternalSecrets of namespace matching labels that are not named in keep
+func Prune(c client.Client, namespace string, labels map[string]string, keep map[string]bool) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(GVK.GroupVersion().WithKind(GVK.Kind + "List"))
+	err := c.List(context.TODO(), list, client.InNamespace(namespace), client.MatchingLabels(labels))
+	// Nothing was created if the External Secrets Operator is not installed
+	if meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list the ExternalSecrets of namespace %v: %v", namespace, err)
+	}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if keep[item.GetName()] {
+			continue
+		}
+		if err := c.Delete(context.TODO(), item); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ExternalSecret %v: %v", item.GetName(), err)
+		}
+		log.Infof("Deleted ExternalSecret %v/%v.", namespace, item.GetName())
+	}
+	return nil
+}
+
+// Materialized returns true once the Secret of the ExternalSecret named name exists. Otherwise, it returns the
+// message of the Ready condition of the ExternalSecret, if any.
+func Materialized(c client.Client, namespace string, name string) (bool, string, error) {
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	err := c.Get(context.TODO(), key, &corev1.Secret{})
+	if err == nil {
+		return true, "", nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, "", err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GVK)
+	if err := c.Get(context.TODO(), key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	return false, readyMessage(obj), nil
+}
+
+// readyMessage returns the message of the Ready condition of an ExternalSecret that is not ready
+func readyMessage(obj *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" || condition["status"] == "True" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		return message
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package externalsecret
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestExternalSecret(t *testing.T) {
+	obj := ExternalSecret("project", "s3", map[string]string{"component": "external-secret"},
+		Source{StoreName: "vault", Key: "secret/data/pipelines/s3"})
+	if obj.GroupVersionKind() != GVK || obj.GetNamespace() != "project" || obj.GetName() != "s3" {
+		t.Errorf("Unexpected ExternalSecret %v %v/%v", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+	}
+	expected := map[string]interface{}{
+		"refreshInterval": "1h",
+		"secretStoreRef":  map[string]interface{}{"name": "vault", "kind": "SecretStore"},
+		"target":          map[string]interface{}{"name": "s3", "creationPolicy": "Owner"},
+		"dataFrom": []interface{}{
+			map[string]interface{}{"extract": map[string]interface{}{"key": "secret/data/pipelines/s3"}},
+		},
+	}
+	if !reflect.DeepEqual(obj.Object["spec"], expected) {
+		t.Errorf("Expected the spec %v, got %v", expected, obj.Object["spec"])
+	}
+
+	if err := Validate("externalSecret", Source{StoreName: "vault", StoreKind: ClusterSecretStore, Key: "k"}); err != nil {
+		t.Errorf("Expected a ClusterSecretStore to be valid, got %v", err)
+	}
+	for _, source := range []Source{{Key: "k"}, {StoreName: "vault"}, {StoreName: "vault", StoreKind: "Vault", Key: "k"}} {
+		if err := Validate("externalSecret", source); err == nil {
+			t.Errorf("Expected %+v to be invalid", source)
+		}
+	}
+}
+
+func TestReadyMessage(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if message := readyMessage(obj); message != "" {
+		t.Errorf("Expected no message without status, got %v", message)
+	}
+	conditions := []interface{}{map[string]interface{}{
+		"type":    "Ready",
+		"status":  "False",
+		"message": "could not get secret data from provider",
+	}}
+	if err := unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	if message := readyMessage(obj); message != "could not get secret data from provider" {
+		t.Errorf("Expected the message of the Ready condition, got %v", message)
+	}
+}
@@ -0,0 +1,98 @@
+package kfdef
+
+import (
+	"reflect"
+	"strings"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// watchTrustedCABundles reconciles the KfDef instances when a trusted CA bundle they mount changes, so that
+// their Deployments are rolled out with the new bundle. A change of the ConfigMap of the trusted CA bundle of the
+// operator settings reconciles every KfDef instance, a change of the bundle of a namespace reconciles the KfDef
+// instances of the namespace. Every KfDef instance is reconciled too when the settings name another ConfigMap.
+func watchTrustedCABundles(c controller.Controller) error {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"})
+	err := c.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			requests := []reconcile.Request{}
+			for _, k := range kfdefInstances.list() {
+				kfdefCr := strings.Split(k, ".")
+				if a.Meta.GetName() == kustomize.TrustedCABundleConfigMap && kfdefCr[1] != a.Meta.GetNamespace() {
+					continue
+				}
+				log.Infof("Trusted CA bundle %v/%v changed, reconciling KfDef %v.", a.Meta.GetNamespace(), a.Meta.GetName(), k)
+				kustomize.InvalidateRenderCache(kfdefCr[1], kfdefCr[0])
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: kfdefCr[0], Namespace: kfdefCr[1]},
+				})
+			}
+			return requests
+		}),
+	}, trustedCABundlePredicates)
+	if err != nil {
+		return err
+	}
+
+	events := operatorconfig.Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.TrustedCABundle != next.TrustedCABundle
+	})
+	return c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			requests := []reconcile.Request{}
+			for _, k := range kfdefInstances.list() {
+				kfdefCr := strings.Split(k, ".")
+				log.Infof("Trusted CA bundle of OperatorConfig %v changed, reconciling KfDef %v.", a.Meta.GetName(), k)
+				kustomize.InvalidateRenderCache(kfdefCr[1], kfdefCr[0])
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: kfdefCr[0], Namespace: kfdefCr[1]},
+				})
+			}
+			return requests
+		}),
+	})
+}
+
+// isTrustedCABundle returns true for the ConfigMaps holding the trusted CA bundles of the KfDef instances
+func isTrustedCABundle(namespace string, name string) bool {
+	return name == kustomize.TrustedCABundleConfigMap || isOperatorTrustedCABundle(namespace, name)
+}
+
+// isOperatorTrustedCABundle returns true for the ConfigMap of the trusted CA bundle of the operator settings
+func isOperatorTrustedCABundle(namespace string, name string) bool {
+	cm := operatorconfig.Current().TrustedCABundle.ConfigMap
+	return cm != "" && namespace+"/"+name == cm
+}
+
+var trustedCABundlePredicates = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return isOperatorTrustedCABundle(e.Meta.GetNamespace(), e.Meta.GetName())
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return isTrustedCABundle(e.Meta.GetNamespace(), e.Meta.GetName())
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if !isTrustedCABundle(e.MetaNew.GetNamespace(), e.MetaNew.GetName()) {
+			return false
+		}
+		oldData, _, _ := unstructured.NestedFieldNoCopy(e.ObjectOld.(*unstructured.Unstructured).Object, "data")
+		newData, _, _ := unstructured.NestedFieldNoCopy(e.ObjectNew.(*unstructured.Unstructured).Object, "data")
+		return !reflect.DeepEqual(oldData, newData)
+	},
+}
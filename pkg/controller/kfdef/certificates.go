@@ -0,0 +1,126 @@
+package kfdef
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// servingCertificates returns the secrets of the serving certificates requested by the Services of the manifests
+// applied for the KfDef in appDir. Services without a namespace are in the namespace of the KfDef.
+func servingCertificates(instance *kfdefv1.KfDef, appDir string) ([]types.NamespacedName, error) {
+	files, err := filepath.Glob(kustomize.RenderedManifestsPath(appDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	secrets := []types.NamespacedName{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		resources, err := kfutils.SplitYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %v", file, err)
+		}
+		for _, res := range resources {
+			u := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal(res, &u.Object); err != nil {
+				return nil, fmt.Errorf("failed to parse %v: %v", file, err)
+			}
+			secret := kustomize.ServingCertificateSecret(u)
+			if secret == "" {
+				continue
+			}
+			namespace := u.GetNamespace()
+			if namespace == "" {
+				namespace = instance.GetNamespace()
+			}
+			secrets = append(secrets, types.NamespacedName{Name: secret, Namespace: namespace})
+		}
+	}
+	return secrets, nil
+}
+
+// certificateExpiry returns the expiry time of the certificate of a kubernetes.io/tls secret
+func certificateExpiry(secret *v1.Secret) (time.Time, error) {
+	block, _ := pem.Decode(secret.Data[v1.TLSCertKey])
+	if block == nil {
+		return time.Time{}, fmt.Errorf("secret %v/%v has no PEM encoded %v", secret.Namespace, secret.Name, v1.TLSCertKey)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// renewCertificates reports the expiry time of the serving certificates of the KfDef and deletes the secrets of
+// the ones expiring within kustomize.CertificateRenewBefore for the service CA or cert-manager to issue them again.
+// It returns how long until the next certificate has to be issued again, or 0 if there is none.
+func (r *ReconcileKfDef) renewCertificates(instance *kfdefv1.KfDef, appDir string) (time.Duration, error) {
+	secrets, err := servingCertificates(instance, appDir)
+	if err != nil {
+		return 0, err
+	}
+	var next time.Duration
+	for _, key := range secrets {
+		secret := &v1.Secret{}
+		if err := r.client.Get(context.TODO(), key, secret); err != nil {
+			if errors.IsNotFound(err) {
+				// the certificate has not been issued yet
+				continue
+			}
+			return 0, err
+		}
+		expiry, err := certificateExpiry(secret)
+		if err != nil {
+			log.Warnf("Failed to read the serving certificate of KfDef %v: %v", instance.Name, err)
+			continue
+		}
+		metrics.SetCertificateExpiry(key.Namespace, instance.Name, key.Name, expiry)
+
+		renewIn := time.Until(expiry) - kustomize.CertificateRenewBefore
+		if renewIn > 0 {
+			if next == 0 || renewIn < next {
+				next = renewIn
+			}
+			continue
+		}
+		log.Infof("Serving certificate %v expires on %v, issuing it again.", key, expiry)
+		if err := r.client.Delete(context.TODO(), secret); err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+		r.recorder.Eventf(instance, v1.EventTypeNormal, "CertificateReissued",
+			"Serving certificate %s expiring on %s is issued again", key, expiry.Format(time.RFC3339))
+	}
+	return next, nil
+}
+
+// deleteCertificateMetrics stops reporting the expiry time of the serving certificates of the KfDef
+func deleteCertificateMetrics(instance *kfdefv1.KfDef) {
+	secrets, err := servingCertificates(instance, path.Join("/tmp", instance.GetNamespace(), instance.GetName()))
+	if err != nil {
+		log.Warnf("Failed to list the serving certificates of KfDef %v: %v", instance.Name, err)
+		return
+	}
+	for _, key := range secrets {
+		metrics.DeleteCertificate(key.Namespace, instance.Name, key.Name)
+	}
+}
@@ -0,0 +1,129 @@
+package kfdef
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// tlsSecret returns a kubernetes.io/tls secret with a self-signed certificate expiring at notAfter
+func tlsSecret(t *testing.T, namespace string, name string, notAfter time.Time) *v1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})},
+	}
+}
+
+func TestRenewCertificates(t *testing.T) {
+	appDir, err := ioutil.TempDir("", "certificates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(appDir)
+	rendered := kustomize.RenderedManifestsPath(appDir, "odh-dashboard")
+	if err := os.MkdirAll(path.Dir(rendered), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifests := `apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+  annotations:
+    kfctl.kubeflow.io/serving-cert-secret: dashboard-expiring-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard-metrics
+  namespace: monitoring
+  annotations:
+    kfctl.kubeflow.io/serving-cert-secret: dashboard-valid-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard-proxy
+  annotations:
+    kfctl.kubeflow.io/serving-cert-secret: dashboard-missing-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard-internal
+`
+	if err := ioutil.WriteFile(rendered, []byte(manifests), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	kubeclient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme,
+		tlsSecret(t, "odh", "dashboard-expiring-tls", now.Add(10*24*time.Hour)),
+		tlsSecret(t, "monitoring", "dashboard-valid-tls", now.Add(60*24*time.Hour)),
+	)
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileKfDef{client: kubeclient, recorder: recorder}
+	instance := &kfdefv1.KfDef{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh"}}
+
+	next, err := r.renewCertificates(instance, appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the valid certificate is issued again 30 days before its expiry
+	expected := 60*24*time.Hour - kustomize.CertificateRenewBefore
+	if next > expected || next < expected-time.Minute {
+		t.Errorf("Expected the next certificate to be issued again in %v, got %v", expected, next)
+	}
+
+	secret := &v1.Secret{}
+	err = kubeclient.Get(context.TODO(), types.NamespacedName{Namespace: "odh", Name: "dashboard-expiring-tls"}, secret)
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the secret of the certificate expiring within %v to be deleted, got %v",
+			kustomize.CertificateRenewBefore, err)
+	}
+	if err := kubeclient.Get(context.TODO(), types.NamespacedName{Namespace: "monitoring", Name: "dashboard-valid-tls"}, secret); err != nil {
+		t.Errorf("Expected the secret of the valid certificate to be kept, got %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected a single certificate to be issued again, got %v events", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Normal CertificateReissued") {
+		t.Errorf("Expected a CertificateReissued event, got %v", event)
+	}
+}
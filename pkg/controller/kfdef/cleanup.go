@@ -0,0 +1,162 @@
+package kfdef
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	errutil "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// DeleteClusterScopedResources allows the finalizer to delete the cluster-scoped resources of a KfDef,
+// such as ClusterRoles and CRDs. Namespaces created for a KfDef are always deleted.
+var DeleteClusterScopedResources = false
+
+// deleteRemainingResources deletes the resources of WatchedResources kinds annotated as managed by the KfDef.
+// It catches the resources left behind by kfDelete, e.g. those of applications removed from the KfDef
+// or whose manifests can no longer be rendered. The resources of the applications with the Retain deletion policy,
+// and the namespaces holding them, are kept. Nothing is deleted while the manifests of a retained application are not
+// found. Namespaces are deleted last.
+func (r *ReconcileKfDef) deleteRemainingResources(instance *kfdefv1.KfDef) error {
+	kfdefAnn := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.KfDefInstance}, "/")
+	kfdefCr := strings.Join([]string{instance.GetName(), instance.GetNamespace()}, ".")
+
+	retained, retainedNamespaces, err := retainedResources(instance, path.Join("/tmp", instance.GetNamespace(), instance.GetName()))
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	namespaces := []unstructured.Unstructured{}
+	for _, gvk := range WatchedResources {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		list.SetKind(gvk.Kind + "List")
+		if err := r.client.List(context.TODO(), list); err != nil {
+			if _, ok := err.(*meta.NoKindMatchError); ok {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to list %v: %v", gvk.Kind, err))
+			continue
+		}
+		for _, item := range list.Items {
+			if item.GetAnnotations()[kfdefAnn] != kfdefCr || item.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if retained[resourceKey(gvk.Group, gvk.Kind, item.GetNamespace(), item.GetName())] {
+				continue
+			}
+			if gvk.Kind == "Namespace" {
+				if retainedNamespaces[item.GetName()] {
+					continue
+				}
+				namespaces = append(namespaces, item)
+				continue
+			}
+			if item.GetNamespace() == "" && !DeleteClusterScopedResources {
+				log.Infof("Keeping cluster-scoped %v %v of KfDef %v.", gvk.Kind, item.GetName(), kfdefCr)
+				continue
+			}
+			if err := r.deleteResource(&item); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for i := range namespaces {
+		if err := r.deleteResource(&namespaces[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errutil.NewAggregate(errs)
+}
+
+func (r *ReconcileKfDef) deleteResource(obj *unstructured.Unstructured) error {
+	log.Infof("Deleting remaining %v %v/%v.", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	if err := r.client.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %v %v/%v: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// resourceKey identifies a resource by group, kind, namespace and name
+func resourceKey(group, kind, namespace, name string) string {
+	return strings.Join([]string{group, kind, namespace, name}, "/")
+}
+
+// retainedResources returns the keys of the resources of the applications with the Retain deletion policy,
+// along with the namespaces they live in. The manifests last applied by the operator in appDir are preferred to the
+// kustomize packages of the applications. It fails if the manifests of a retained application are not found, since
+// the resources to keep cannot be told apart from the ones to delete.
+func retainedResources(instance *kfdefv1.KfDef, appDir string) (map[string]bool, map[string]bool, error) {
+	resources := map[string]bool{}
+	namespaces := map[string]bool{}
+	for _, app := range instance.Spec.Applications {
+		if app.DeletionPolicy != kfdefv1.DeletionPolicyRetain {
+			continue
+		}
+		data, err := appManifests(appDir, app.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if data == nil {
+			return nil, nil, fmt.Errorf("manifests of the retained application %v not found, set its deletionPolicy to "+
+				"Delete or remove it from the KfDef to delete the remaining resources", app.Name)
+		}
+		manifests, err := kfutils.SplitYAML(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse the manifests of %v: %v", app.Name, err)
+		}
+		for _, manifest := range manifests {
+			u := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal(manifest, &u.Object); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse the manifests of %v: %v", app.Name, err)
+			}
+			gvk := u.GroupVersionKind()
+			if gvk.Kind == "" {
+				continue
+			}
+			resources[resourceKey(gvk.Group, gvk.Kind, u.GetNamespace(), u.GetName())] = true
+			if u.GetNamespace() == "" {
+				// The resource is cluster-scoped or belongs to the namespace of the KfDef
+				resources[resourceKey(gvk.Group, gvk.Kind, instance.GetNamespace(), u.GetName())] = true
+				if gvk.Kind != "Namespace" {
+					namespaces[instance.GetNamespace()] = true
+				}
+			} else {
+				namespaces[u.GetNamespace()] = true
+			}
+		}
+	}
+	return resources, namespaces, nil
+}
+
+// appManifests returns the manifests of the application, or nil if they are not found
+func appManifests(appDir string, app string) ([]byte, error) {
+	data, err := ioutil.ReadFile(kustomize.RenderedManifestsPath(appDir, app))
+	if err == nil {
+		return data, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	kustomizeDir := path.Join(appDir, "kustomize", app)
+	if _, err := os.Stat(kustomizeDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	resMap, err := kustomize.EvaluateKustomizeManifest(kustomizeDir)
+	if err != nil {
+		return nil, err
+	}
+	return resMap.AsYaml()
+}
@@ -0,0 +1,68 @@
+package kfdef
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRetainedResources(t *testing.T) {
+	appDir, err := ioutil.TempDir("", "cleanup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(appDir)
+	rendered := kustomize.RenderedManifestsPath(appDir, "minio")
+	if err := os.MkdirAll(path.Dir(rendered), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifests := `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: minio-data
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: minio-credentials
+  namespace: storage
+`
+	if err := ioutil.WriteFile(rendered, []byte(manifests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	instance := &kfdefv1.KfDef{
+		ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh"},
+		Spec: kfdefv1.KfDefSpec{Applications: []kfdefv1.Application{
+			{Name: "odh-common", DeletionPolicy: kfdefv1.DeletionPolicyDelete},
+			{Name: "minio", DeletionPolicy: kfdefv1.DeletionPolicyRetain},
+		}},
+	}
+
+	resources, namespaces, err := retainedResources(instance, appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{
+		resourceKey("", "PersistentVolumeClaim", "odh", "minio-data"),
+		resourceKey("", "Secret", "storage", "minio-credentials"),
+	} {
+		if !resources[key] {
+			t.Errorf("Expected %v to be retained, got %v", key, resources)
+		}
+	}
+	if !namespaces["odh"] || !namespaces["storage"] {
+		t.Errorf("Expected the namespaces odh and storage to be retained, got %v", namespaces)
+	}
+
+	// The resources to keep are unknown without the manifests of a retained application
+	instance.Spec.Applications = append(instance.Spec.Applications,
+		kfdefv1.Application{Name: "postgres", DeletionPolicy: kfdefv1.DeletionPolicyRetain})
+	if _, _, err := retainedResources(instance, appDir); err == nil {
+		t.Errorf("Expected the missing manifests of a retained application to fail")
+	}
+}
@@ -33,4 +33,10 @@ var (
 		{Group: "apps", Version: "v1", Kind: "StatefulSet"},
 		{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingWebhookConfiguration"},
 	}
+
+	// DriftResources contains the resources restored by the drift controllers as soon as they are changed
+	DriftResources = []schema.GroupVersionKind{
+		{Group: "", Version: "v1", Kind: "ConfigMap"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	}
 )
@@ -0,0 +1,209 @@
+package kfdef
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// goroutinesMaxBytes caps the goroutine dump so that the diagnostics fit in a ConfigMap
+const goroutinesMaxBytes = 512 * 1024
+
+// componentError is the last error an application of a KfDef was reconciled with
+type componentError struct {
+	Application string      `json:"application"`
+	Time        metav1.Time `json:"time"`
+	Reason      string      `json:"reason,omitempty"`
+	Message     string      `json:"message,omitempty"`
+}
+
+// errorRecorder keeps the last error of every application of every KfDef, which the conditions of the KfDef no
+// longer show once the application recovered
+type errorRecorder struct {
+	sync.Mutex
+	errors map[types.NamespacedName]map[string]componentError
+}
+
+// lastErrors keeps the last reconcile errors of the applications of the KfDef instances
+var lastErrors = &errorRecorder{errors: map[types.NamespacedName]map[string]componentError{}}
+
+// record records the errors of the Degraded applications of the KfDef
+func (e *errorRecorder) record(instance *kfdefv1.KfDef) {
+	e.Lock()
+	defer e.Unlock()
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	for _, cond := range instance.Status.ApplicationConditions {
+		if cond.Type != kfdefv1.KfDegraded {
+			continue
+		}
+		if e.errors[key] == nil {
+			e.errors[key] = map[string]componentError{}
+		}
+		e.errors[key][cond.Application] = componentError{
+			Application: cond.Application,
+			Time:        cond.LastUpdateTime,
+			Reason:      cond.Reason,
+			Message:     cond.Message,
+		}
+	}
+}
+
+// forget removes the errors of a deleted KfDef
+func (e *errorRecorder) forget(key types.NamespacedName) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.errors, key)
+}
+
+// list returns the last errors of the applications of the KfDef, sorted by application
+func (e *errorRecorder) list(key types.NamespacedName) []componentError {
+	e.Lock()
+	defer e.Unlock()
+	errs := []componentError{}
+	for _, err := range e.errors[key] {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Application < errs[j].Application })
+	return errs
+}
+
+// manifestVersions describes the manifests the applications of a KfDef were last applied from
+type manifestVersions struct {
+	Version         string              `json:"version,omitempty"`
+	OperatorVersion string              `json:"operatorVersion,omitempty"`
+	Repos           []repoVersion       `json:"repos,omitempty"`
+	Applications    []renderedManifests `json:"applications,omitempty"`
+}
+
+// repoVersion is a repo of the manifests of a KfDef
+type repoVersion struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+	Ref  string `json:"ref,omitempty"`
+}
+
+// renderedManifests are the manifests an application was last applied with, empty when they were not kept
+type renderedManifests struct {
+	Application string `json:"application"`
+	Checksum    string `json:"checksum,omitempty"`
+	RenderedAt  string `json:"renderedAt,omitempty"`
+}
+
+// collectDiagnostics snapshots the goroutines of the operator, the last errors of the applications of the KfDef
+// and the versions of its manifests into the diagnostics ConfigMap of the KfDef, when requested by its
+// collect-diagnostics annotation. They are collected again once the value of the annotation changes.
+func (r *ReconcileKfDef) collectDiagnostics(instance *kfdefv1.KfDef) error {
+	ann := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.CollectDiagnostics}, "/")
+	request, ok := instance.GetAnnotations()[ann]
+	if !ok {
+		return nil
+	}
+	cm := &v1.ConfigMap{}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: kfutils.DiagnosticsConfigMapName(instance.Name)}
+	err := r.client.Get(context.TODO(), key, cm)
+	if err == nil && cm.Annotations[ann] == request {
+		return nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	data, err := diagnosticsData(instance)
+	if err != nil {
+		return err
+	}
+	cm.Name = key.Name
+	cm.Namespace = key.Namespace
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[ann] = request
+	cm.Data = data
+	if err := controllerutil.SetControllerReference(instance, cm, r.scheme); err != nil {
+		return err
+	}
+	if exists {
+		err = r.client.Update(context.TODO(), cm)
+	} else {
+		err = r.client.Create(context.TODO(), cm)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Diagnostics of KfDef %v collected into ConfigMap %v.", instance.Name, key)
+	r.recorder.Eventf(instance, v1.EventTypeNormal, "DiagnosticsCollected",
+		"Diagnostics of KF instance %s collected into ConfigMap %s", instance.Name, key.Name)
+	return nil
+}
+
+// diagnosticsData returns the data of the diagnostics ConfigMap of the KfDef
+func diagnosticsData(instance *kfdefv1.KfDef) (map[string]string, error) {
+	var goroutines bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&goroutines, 2); err != nil {
+		return nil, err
+	}
+	dump := goroutines.String()
+	if len(dump) > goroutinesMaxBytes {
+		dump = dump[:goroutinesMaxBytes] + "\n... truncated\n"
+	}
+
+	errs, err := yaml.Marshal(lastErrors.list(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}))
+	if err != nil {
+		return nil, err
+	}
+	versions, err := yaml.Marshal(getManifestVersions(instance, path.Join("/tmp", instance.Namespace, instance.Name)))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"collectedAt":    time.Now().UTC().Format(time.RFC3339),
+		"goroutines.txt": dump,
+		"errors.yaml":    string(errs),
+		"manifests.yaml": string(versions),
+	}, nil
+}
+
+// getManifestVersions returns the versions of the manifests of the KfDef, with the checksums of the rendered
+// manifests kept in appDir
+func getManifestVersions(instance *kfdefv1.KfDef, appDir string) manifestVersions {
+	versions := manifestVersions{
+		Version:         instance.Spec.Version,
+		OperatorVersion: instance.Status.OperatorVersion,
+	}
+	for _, repo := range instance.Spec.Repos {
+		versions.Repos = append(versions.Repos, repoVersion{Name: repo.Name, URI: repo.URI, Ref: repo.Ref})
+	}
+	for _, app := range instance.Spec.Applications {
+		rendered := renderedManifests{Application: app.Name}
+		file := kustomize.RenderedManifestsPath(appDir, app.Name)
+		if data, err := ioutil.ReadFile(file); err == nil {
+			rendered.Checksum = fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+			if fi, err := os.Stat(file); err == nil {
+				rendered.RenderedAt = fi.ModTime().UTC().Format(time.RFC3339)
+			}
+		}
+		versions.Applications = append(versions.Applications, rendered)
+	}
+	return versions
+}
@@ -0,0 +1,90 @@
+package kfdef
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDiagnostics(t *testing.T) {
+	instance := &kfdefv1.KfDef{
+		ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh"},
+		Spec: kfdefv1.KfDefSpec{
+			Version: "v1.2.0",
+			Repos:   []kfdefv1.Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests.git", Ref: "v1.2"}},
+			Applications: []kfdefv1.Application{
+				{Name: "odh-dashboard"},
+				{Name: "notebooks"},
+			},
+		},
+	}
+	key := types.NamespacedName{Namespace: "odh", Name: "opendatahub"}
+	degraded := func(app string, reason string) kfdefv1.ApplicationCondition {
+		return kfdefv1.ApplicationCondition{Application: app, Type: kfdefv1.KfDegraded, Status: corev1.ConditionTrue, Reason: reason}
+	}
+
+	t.Run("last errors", func(t *testing.T) {
+		recorder := &errorRecorder{errors: map[types.NamespacedName]map[string]componentError{}}
+		instance.Status.ApplicationConditions = []kfdefv1.ApplicationCondition{
+			degraded("odh-dashboard", "ApplyFailed"),
+			degraded("notebooks", "ReadinessTimeout"),
+		}
+		recorder.record(instance)
+		// the errors are kept once the applications recovered
+		instance.Status.ApplicationConditions = []kfdefv1.ApplicationCondition{
+			{Application: "odh-dashboard", Type: kfdefv1.KfAvailable, Status: corev1.ConditionTrue},
+			degraded("notebooks", "HookFailed"),
+		}
+		recorder.record(instance)
+		got := []string{}
+		for _, err := range recorder.list(key) {
+			got = append(got, err.Application+":"+err.Reason)
+		}
+		want := []string{"notebooks:HookFailed", "odh-dashboard:ApplyFailed"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("list() = %v, want %v", got, want)
+		}
+		recorder.forget(key)
+		if errs := recorder.list(key); len(errs) != 0 {
+			t.Errorf("list() = %v after forget, want none", errs)
+		}
+	})
+
+	t.Run("manifest versions", func(t *testing.T) {
+		appDir, err := ioutil.TempDir("", "kfdef-diagnostics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(appDir)
+		rendered := kustomize.RenderedManifestsPath(appDir, "odh-dashboard")
+		if err := os.MkdirAll(path.Dir(rendered), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(rendered, []byte("kind: ConfigMap\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		versions := getManifestVersions(instance, appDir)
+		if versions.Version != "v1.2.0" || len(versions.Repos) != 1 || versions.Repos[0].Ref != "v1.2" {
+			t.Errorf("getManifestVersions() = %+v, want the version and the repos of the KfDef", versions)
+		}
+		if len(versions.Applications) != 2 {
+			t.Fatalf("getManifestVersions() = %+v, want 2 applications", versions.Applications)
+		}
+		if dashboard := versions.Applications[0]; !strings.HasPrefix(dashboard.Checksum, "sha256:") || dashboard.RenderedAt == "" {
+			t.Errorf("odh-dashboard = %+v, want the checksum of its rendered manifests", dashboard)
+		}
+		if notebooks := versions.Applications[1]; notebooks.Checksum != "" {
+			t.Errorf("notebooks = %+v, want no checksum without rendered manifests", notebooks)
+		}
+	})
+}
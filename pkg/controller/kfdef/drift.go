@@ -0,0 +1,236 @@
+package kfdef
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/cachefilter"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/reconcilecontext"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// addDriftControllers adds one controller per kind of DriftResources. Each controller restores the
+// resources deployed by a KfDef to the manifests they were applied from as soon as they are changed or deleted. The
+// resources are watched in the cache of the managed resources, only holding the resources deployed by a KfDef when
+// the informers are limited to them.
+func addDriftControllers(mgr manager.Manager, options controller.Options) error {
+	for _, gvk := range DriftResources {
+		name := "kfdef-drift-controller-" + strings.ToLower(gvk.Kind)
+		options.Reconciler = ratelimiter.Wrap(name, &ReconcileDrift{
+			client:   mgr.GetClient(),
+			gvk:      gvk,
+			recorder: mgr.GetEventRecorderFor("kfdef-drift-controller"),
+		})
+		c, err := controller.New(name, mgr, options)
+		if err != nil {
+			return err
+		}
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		if err := c.Watch(cachefilter.Kind(cachefilter.Managed(mgr.GetCache()), u), &handler.EnqueueRequestForObject{}, driftPredicates); err != nil {
+			return err
+		}
+	}
+	log.Infof("Drift controllers added for %v.", DriftResources)
+	return nil
+}
+
+// isDriftResource returns true if obj is of a kind restored by the drift controllers
+func isDriftResource(obj runtime.Object) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	for _, t := range DriftResources {
+		if t.Group == gvk.Group && t.Kind == gvk.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// isKfDefResource returns true for the resources deployed by a KfDef
+func isKfDefResource(object metav1.Object) bool {
+	kfdefAnn := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.KfDefInstance}, "/")
+	_, found := object.GetAnnotations()[kfdefAnn]
+	return found
+}
+
+// isDriftManaged returns true for the resources deployed by a KfDef which did not opt out of drift remediation, and
+// are not left to Argo CD
+func isDriftManaged(object metav1.Object) bool {
+	return isKfDefResource(object) && !kfutils.IgnoresDrift(object) && !kustomize.IgnoresArgoCDResource(object)
+}
+
+var driftPredicates = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		// resources are created by the KfDef reconciler
+		return false
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return isDriftManaged(e.Meta)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if !isDriftManaged(e.MetaNew) {
+			return false
+		}
+		// Resources with a generation only drift when their spec changes; skip status updates.
+		if e.MetaNew.GetGeneration() > 0 && e.MetaNew.GetGeneration() == e.MetaOld.GetGeneration() {
+			// The metadata written by Argo CD is not drift
+			labels, oldLabels := kustomize.WithoutArgoCDMetadata(e.MetaNew.GetLabels()), kustomize.WithoutArgoCDMetadata(e.MetaOld.GetLabels())
+			annotations, oldAnnotations := kustomize.WithoutArgoCDMetadata(e.MetaNew.GetAnnotations()),
+				kustomize.WithoutArgoCDMetadata(e.MetaOld.GetAnnotations())
+			return !reflect.DeepEqual(labels, oldLabels) || !reflect.DeepEqual(annotations, oldAnnotations)
+		}
+		return true
+	},
+}
+
+// blank assignment to verify that ReconcileDrift implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDrift{}
+
+// ReconcileDrift restores the resources of a single kind deployed by a KfDef
+type ReconcileDrift struct {
+	client client.Client
+	gvk    schema.GroupVersionKind
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile compares the resource with the manifest it was last applied from and applies the manifest
+// again if the resource was deleted or if any field set by the manifest was changed.
+func (r *ReconcileDrift) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	desired, kfdefKey, app := kustomize.FindRenderedResource(r.gvk, request.NamespacedName)
+	if desired == nil {
+		// The resource is not deployed by a KfDef, or its KfDef has not been applied by this operator yet.
+		return reconcile.Result{}, nil
+	}
+	// The resource is restored with the context of the reconciliation, cancelled once it times out
+	ctx, cancel := reconcilecontext.New()
+	defer cancel()
+
+	instance := &kfdefv1.KfDef{}
+	if err := r.client.Get(ctx, kfdefKey, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil || kfutils.IsApplicationPaused(instance, app) {
+		return reconcile.Result{}, nil
+	}
+
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(r.gvk)
+	err := r.client.Get(ctx, request.NamespacedName, current)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if err == nil {
+		if kfutils.IgnoresDrift(current) {
+			return reconcile.Result{}, nil
+		}
+		drifted, err := hasDrifted(desired, current)
+		if err != nil || !drifted {
+			return reconcile.Result{}, err
+		}
+	}
+
+	log.Infof("%v %v drifted from the manifests of KfDef %v, restoring it.", r.gvk.Kind, request.NamespacedName, kfdefKey)
+	err = kfutils.ServerSideApply(ctx, r.client, desired)
+	if kfapis.IsConflict(err) {
+		// The drifted fields are managed by another field manager, e.g. a HorizontalPodAutoscaler
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "DriftConflict",
+			"Not restoring %s %s, fields are managed by another field manager: %v", r.gvk.Kind, request.NamespacedName,
+			err.(*kfapis.KfError).Message)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "DriftRemediationFailed",
+			"Error restoring %s %s: %v", r.gvk.Kind, request.NamespacedName, err)
+		return reconcile.Result{}, err
+	}
+	r.recorder.Eventf(instance, v1.EventTypeNormal, "DriftRemediated",
+		"Restored %s %s to the state defined in the manifests", r.gvk.Kind, request.NamespacedName)
+	return reconcile.Result{}, nil
+}
+
+// hasDrifted returns true if a field set in the desired manifest has a different value in the current resource.
+// Fields only present in the current resource, such as defaults and status, are ignored.
+func hasDrifted(desired *unstructured.Unstructured, current *unstructured.Unstructured) (bool, error) {
+	d, err := normalize(desired)
+	if err != nil {
+		return false, err
+	}
+	c, err := normalize(current)
+	if err != nil {
+		return false, err
+	}
+	return !isSubset(d, c), nil
+}
+
+// normalize round trips obj through JSON so that numbers compare equal regardless of how they were decoded
+func normalize(obj *unstructured.Unstructured) (interface{}, error) {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// isSubset returns true if every field of desired is set to the same value in current
+func isSubset(desired interface{}, current interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			return len(d) == 0 && current == nil
+		}
+		for k, v := range d {
+			if !isSubset(v, c[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok {
+			return len(d) == 0 && current == nil
+		}
+		if len(d) != len(c) {
+			return false
+		}
+		for i := range d {
+			if !isSubset(d[i], c[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return true
+	default:
+		return reflect.DeepEqual(desired, current)
+	}
+}
@@ -0,0 +1,98 @@
+package kfdef
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHasDrifted(t *testing.T) {
+	desired := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  labels:
+    app: odh-dashboard
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard:v2
+`
+	type testCase struct {
+		Name    string
+		Current string
+		Drifted bool
+	}
+	testCases := []testCase{
+		{
+			Name: "defaults and status",
+			Current: desired + `    metadata:
+      creationTimestamp: null
+status:
+  readyReplicas: 2
+`,
+		},
+		{Name: "replicas", Current: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  labels:
+    app: odh-dashboard
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard:v2
+`, Drifted: true},
+		{Name: "label removed", Current: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard:v2
+`, Drifted: true},
+		{Name: "container added", Current: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  labels:
+    app: odh-dashboard
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard:v2
+      - name: debug
+        image: busybox
+`, Drifted: true},
+	}
+	parse := func(manifest string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifest), &u.Object); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	for _, c := range testCases {
+		drifted, err := hasDrifted(parse(desired), parse(c.Current))
+		if err != nil {
+			t.Fatalf("%v: %v", c.Name, err)
+		}
+		if drifted != c.Drifted {
+			t.Errorf("%v: hasDrifted = %v, expected %v", c.Name, drifted, c.Drifted)
+		}
+	}
+}
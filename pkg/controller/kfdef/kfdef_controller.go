@@ -8,14 +8,28 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ghodss/yaml"
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
 	kftypesv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
 	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/cachefilter"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
 	"github.com/kubeflow/kfctl/v3/pkg/kfapp/coordinator"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
 	kfloaders "github.com/kubeflow/kfctl/v3/pkg/kfconfig/loaders"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/reconcilecontext"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
 	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
 	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
 	olmclientset "github.com/operator-framework/operator-lifecycle-manager/pkg/api/client/clientset/versioned/typed/operators/v1alpha1"
@@ -33,6 +47,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -55,30 +70,74 @@ const (
 )
 
 // kfdefInstances keep all KfDef CRs watched by the operator
-var kfdefInstances = map[string]struct{}{}
+var kfdefInstances = &instanceSet{instances: map[string]struct{}{}}
 
-// whether the 2nd controller is added
-var b2ndController = false
+// instanceSet is a set of KfDef keys (<name>.<namespace>) safe for use by concurrent reconciles
+type instanceSet struct {
+	sync.Mutex
+	instances map[string]struct{}
+}
+
+func (s *instanceSet) add(key string) {
+	s.Lock()
+	defer s.Unlock()
+	s.instances[key] = struct{}{}
+}
+
+func (s *instanceSet) remove(key string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.instances, key)
+}
+
+func (s *instanceSet) list() []string {
+	s.Lock()
+	defer s.Unlock()
+	keys := []string{}
+	for k := range s.instances {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// secondController is the 2nd controller, stopped once the last KfDef is deleted
+var secondController = &stoppableController{}
+
+// stoppableController is a controller started with its own stop channel, safe for use by concurrent reconciles
+type stoppableController struct {
+	sync.Mutex
+	started bool
+	stop    chan struct{}
+}
+
+// stopIfLast stops the controller if it is started and instances is the last KfDef. The channel is closed once.
+func (c *stoppableController) stopIfLast(instances int) {
+	c.Lock()
+	defer c.Unlock()
+	if c.started && instances == 1 {
+		close(c.stop)
+		c.started = false
+	}
+}
 
 // the manager
 var kfdefManager manager.Manager
 
-// Flag to to trigger update of alerting configuration
-var addonManagedODHParametersSecretUpdated = false
-
-// the stop channel for the 2nd controller
-var stop chan struct{}
+// addonManagedODHParametersSecretUpdated is set to 1 to trigger the update of the alerting configuration. It is
+// written by the predicates and read by the concurrent reconciles, only through sync/atomic.
+var addonManagedODHParametersSecretUpdated int32
 
 // AddToManager adds all Controllers to the Manager
-func AddToManager(m manager.Manager) error {
+func AddToManager(m manager.Manager, options controller.Options) error {
 	kfdefManager = m
-	return Add(kfdefManager)
+	return Add(kfdefManager, options)
 }
 
 // Add creates a new KfDef Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("kfdef-controller", newReconciler(mgr))
+	return add(mgr, options)
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -90,11 +149,11 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 		recorder:   mgr.GetEventRecorderFor("kfdef-controller")}
 }
 
-// add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
 	log.Infof("Adding controller for kfdef.")
 	// Create a new controller
-	c, err := controller.New("kfdef-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("kfdef-controller", mgr, options)
 	if err != nil {
 		return err
 	}
@@ -105,6 +164,10 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 			namespacedName := types.NamespacedName{Name: a.Meta.GetName(), Namespace: a.Meta.GetNamespace()}
 			finalizers := sets.NewString(a.Meta.GetFinalizers()...)
 			if !finalizers.Has(finalizer) {
+				// leave the KfDef instances of the namespaces that are not selected alone
+				if selected, err := isNamespaceSelected(mgr.GetClient(), a.Meta.GetNamespace()); err != nil || !selected {
+					return nil
+				}
 				// assume this is a CREATE event
 				log.Infof("Adding finalizer %v: %v.", finalizer, namespacedName)
 				finalizers.Insert(finalizer)
@@ -131,16 +194,54 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 
 	// Watch for changes to kfdef resource and requeue the owner KfDef
-	err = watchKubeflowResources(c, mgr.GetClient(), WatchedResources)
+	err = watchKubeflowResources(c, mgr.GetClient(), cachefilter.Managed(mgr.GetCache()), WatchedResources)
+	if err != nil {
+		return err
+	}
+
+	// Watch for namespaces becoming selected by the namespace selector
+	err = watchSelectedNamespaces(c, mgr.GetClient())
+	if err != nil {
+		return err
+	}
+	// Watch for changes to the trusted CA bundles
+	err = watchTrustedCABundles(c)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the cluster-wide proxy configuration
+	err = watchClusterProxy(c)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the PriorityClass of the control plane
+	err = watchControlPlanePriorityClass(c)
 	if err != nil {
 		return err
 	}
 	log.Infof("Controller added to watch on Kubeflow resources with known GVK.")
-	return nil
+
+	// Report to OLM whether the operator can be upgraded
+	if err := operatorUpgradeable.init(mgr.GetConfig()); err != nil {
+		log.Warnf("Failed to report the operator conditions to OLM. Error: %v.", err)
+	}
+
+	// Rewrite the instances stored with a former version of the CRD
+	err = addStorageVersionMigration(mgr)
+	if err != nil {
+		return err
+	}
+
+	// Restore the resources changed outside of the operator without waiting for a full reconcile
+	return addDriftControllers(mgr, options)
 }
 
-// watch is monitoring changes for kfctl resources managed by the operator
-func watchKubeflowResources(c controller.Controller, r client.Client, watchedResources []schema.GroupVersionKind) error {
+// watch is monitoring changes for kfctl resources managed by the operator, cached in managed. The ConfigMaps are
+// cached with the other resources of the manager since the ConfigMap triggering the uninstall is not deployed by a
+// KfDef.
+func watchKubeflowResources(c controller.Controller, r client.Client, managed cache.Cache, watchedResources []schema.GroupVersionKind) error {
 	for _, t := range watchedResources {
 		u := &unstructured.Unstructured{}
 		u.SetGroupVersionKind(schema.GroupVersionKind{
@@ -148,7 +249,11 @@ func watchKubeflowResources(c controller.Controller, r client.Client, watchedRes
 			Group:   t.Group,
 			Version: t.Version,
 		})
-		err := c.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestsFromMapFunc{
+		src := cachefilter.Kind(managed, u)
+		if t.Group == "" && t.Kind == "ConfigMap" {
+			src = &source.Kind{Type: u}
+		}
+		err := c.Watch(src, &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
 				anns := a.Meta.GetAnnotations()
 				kfdefAnn := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.KfDefInstance}, "/")
@@ -168,12 +273,14 @@ func watchKubeflowResources(c controller.Controller, r client.Client, watchedRes
 						return nil
 					}
 					log.Infof("Watch a change for Kubeflow resource: %v.%v.", a.Meta.GetName(), a.Meta.GetNamespace())
+					// apply the applications again to restore the resource
+					kustomize.InvalidateRenderCache(namespacedName.Namespace, namespacedName.Name)
 					return []reconcile.Request{{NamespacedName: namespacedName}}
 				} else if a.Object.GetObjectKind().GroupVersionKind().Kind == "ConfigMap" {
 					labels := a.Meta.GetLabels()
 					if val, ok := labels[deleteConfigMapLabel]; ok {
 						if val == "true" {
-							for k := range kfdefInstances {
+							for _, k := range kfdefInstances.list() {
 								kfdefCr := strings.Split(k, ".")
 								return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: kfdefCr[0], Namespace: kfdefCr[1]}}}
 							}
@@ -207,12 +314,29 @@ var kfdefPredicates = predicate.Funcs{
 		return false
 	},
 	UpdateFunc: func(e event.UpdateEvent) bool {
+		if !kfdefChanged(e.MetaOld, e.MetaNew) {
+			return false
+		}
 		object, _ := meta.Accessor(e.ObjectOld)
 		log.Infof("Got update event for %v.%v.", object.GetName(), object.GetNamespace())
 		return true
 	},
 }
 
+// kfdefChanged returns false for the updates of a KfDef only writing its status, so that the status written at the
+// end of a reconcile does not render and apply the applications again. The periodic resyncs, sent with the same
+// resource version, are kept.
+func kfdefChanged(old, new metav1.Object) bool {
+	if old == nil || new == nil || old.GetResourceVersion() == new.GetResourceVersion() {
+		return true
+	}
+	return old.GetGeneration() != new.GetGeneration() ||
+		!reflect.DeepEqual(old.GetLabels(), new.GetLabels()) ||
+		!reflect.DeepEqual(old.GetAnnotations(), new.GetAnnotations()) ||
+		!reflect.DeepEqual(old.GetFinalizers(), new.GetFinalizers()) ||
+		!reflect.DeepEqual(old.GetDeletionTimestamp(), new.GetDeletionTimestamp())
+}
+
 var ownedResourcePredicates = predicate.Funcs{
 	CreateFunc: func(e event.CreateEvent) bool {
 		// handle create event
@@ -243,11 +367,19 @@ var ownedResourcePredicates = predicate.Funcs{
 		if err != nil {
 			return false
 		}
+		// leave the resources not deployed by the operator alone
+		if !kfutils.IsManaged(object) {
+			return false
+		}
 		log.Infof("Got delete event for %v.%v.", object.GetName(), object.GetNamespace())
 		// if this object has an owner, let the owner handle the appropriate recovery
 		if len(object.GetOwnerReferences()) > 0 {
 			return false
 		}
+		// let the drift controllers restore the object, or keep it deleted if drift is ignored
+		if isDriftResource(e.Object) && isKfDefResource(object) {
+			return false
+		}
 		return true
 	},
 	UpdateFunc: func(e event.UpdateEvent) bool {
@@ -260,16 +392,22 @@ var ownedResourcePredicates = predicate.Funcs{
 		if len(object.GetOwnerReferences()) > 0 {
 			return false
 		}
+		// let the drift controllers restore the object, or keep the change if drift is ignored
+		if isDriftResource(e.ObjectNew) && isKfDefResource(object) {
+			return false
+		}
 		// TODO:  Add update log message when plugin is integrated. We need to only log events for the resources with 'configurable' label
 
 		// Set flag if the object is the addon-managed-odh-parameters secret
 		if e.ObjectNew.GetObjectKind().GroupVersionKind().Kind == "Secret" {
 			if object.GetName() == "addon-managed-odh-parameters" && object.GetNamespace() == "redhat-ods-operator" {
 				log.Infof("%v secret was updated. Enabling update of alertmanager configuration", object.GetName())
-				addonManagedODHParametersSecretUpdated = true
+				atomic.StoreInt32(&addonManagedODHParametersSecretUpdated, 1)
 			}
 		}
-		return true
+		// leave the resources not deployed by the operator alone, such as the Deployments of the users in the
+		// watched namespaces
+		return kfutils.IsManaged(object) || kfutils.IsManaged(e.MetaNew)
 	},
 }
 
@@ -293,10 +431,25 @@ type ReconcileKfDef struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// The reconciliation is the root span of the spans of the KfApp plugins applying the KfDef
+	span := tracing.Start(nil, "Reconcile", "kfdef.namespace", request.Namespace, "kfdef.name", request.Name)
+	key := tracing.Key(request.Namespace, request.Name)
+	tracing.SetActive(key, span)
+	defer tracing.SetActive(key, nil)
+	// The manifests are downloaded and applied with the context of the reconciliation, cancelled once it times out
+	ctx, cancel := reconcilecontext.New()
+	defer cancel()
+	result, err := r.reconcile(ctx, request)
+	span.End(err)
+	return result, err
+}
+
+// reconcile reconciles a KfDef object with ctx, see Reconcile
+func (r *ReconcileKfDef) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	log.Infof("Reconciling KfDef resources. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
 
 	instance := &kfdefv1.KfDef{}
-	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	err := r.client.Get(ctx, request.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -315,39 +468,25 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, err
 	}
 
-	if addonManagedODHParametersSecretUpdated {
-
-		newUserNotificationEmails, err := getNewUserNotificationEmails(r.client)
+	// Only reconcile the KfDef instances of the selected namespaces, but finish the deletion of those
+	// deployed before their namespace was deselected.
+	if instance.GetDeletionTimestamp() == nil {
+		selected, err := isNamespaceSelected(r.client, instance.GetNamespace())
 		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("error getting secret: addon-managed-odh-parameters")
-		}
-
-		config := &v1.ConfigMap{}
-		configParams := client.ObjectKey{
-			Namespace: "redhat-ods-monitoring",
-			Name:      "alertmanager",
-		}
-
-		err = r.client.Get(context.TODO(), configParams, config)
-		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("error getting configmap: alertmanager")
+			return reconcile.Result{}, err
 		}
-
-		config.Data["alertmanager.yml"] = updateUserNotificationsEmails(config.Data["alertmanager.yml"], newUserNotificationEmails)
-
-		err = r.client.Update(context.TODO(), config)
-		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("error updating configmap: alertmanager")
+		if !selected {
+			log.Infof("Namespace %v is not selected, skipping KfDef %v.", instance.GetNamespace(), instance.GetName())
+			return reconcile.Result{}, nil
 		}
+	}
 
-		alertmanagerAnnotationHash := sha256.Sum256([]byte(config.Data["alertmanager.yml"]))
-		base64AlertmanagerAnnotationHash := base64.StdEncoding.EncodeToString(alertmanagerAnnotationHash[:])
-
-		err = updateDeploymentConfigurationAnnotationHash(r.client, "prometheus", "redhat-ods-monitoring", "alertmanager", base64AlertmanagerAnnotationHash)
-		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("error updating Prometheus deployment annotations")
+	// A single reconcile takes the update, it is triggered again if it fails
+	if atomic.CompareAndSwapInt32(&addonManagedODHParametersSecretUpdated, 1, 0) {
+		if err := r.updateAlertingConfiguration(ctx); err != nil {
+			atomic.StoreInt32(&addonManagedODHParametersSecretUpdated, 1)
+			return reconcile.Result{}, err
 		}
-		addonManagedODHParametersSecretUpdated = false
 	}
 
 	deleted := instance.GetDeletionTimestamp() != nil
@@ -364,13 +503,12 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 		log.Infof("Deleting kfdef instance %s.", instance.Name)
 
 		// stop the 2nd controller
-		if len(kfdefInstances) == 1 {
-			close(stop)
-			b2ndController = false
-		}
+		secondController.stopIfLast(len(kfdefInstances.list()))
 
 		// Uninstall Kubeflow
-		err = kfDelete(instance)
+		err = operatorUpgradeable.during("ReconcileInProgress", "KfDef "+request.String()+" is being deleted", func() error {
+			return kfDelete(ctx, instance)
+		})
 		if err == nil {
 			log.Infof("KubeFlow Deployment Deleted.")
 			r.recorder.Eventf(instance, v1.EventTypeNormal, "KfDefDeletionSuccessful",
@@ -383,30 +521,45 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 
 		}
 
+		// Delete the resources the manifests did not account for before letting the KfDef go
+		if err := r.deleteRemainingResources(instance); err != nil {
+			log.Errorf("Failed to delete the remaining resources of KfDef %v. Error: %v.", instance.Name, err)
+			r.recorder.Eventf(instance, v1.EventTypeWarning, "KfDefCleanupFailed",
+				"Error deleting the remaining resources of KF instance %s: %v", instance.Name, err)
+			return reconcile.Result{}, err
+		}
+
 		// Delete the kfapp directory
+		deleteCertificateMetrics(instance)
 		kfAppDir := path.Join("/tmp", instance.GetNamespace(), instance.GetName())
 		if err := os.RemoveAll(kfAppDir); err != nil {
 			log.Errorf("Failed to delete the app directory. Error: %v.", err)
 			return reconcile.Result{}, err
 		}
 		log.Infof("kfAppDir deleted.")
+		kustomize.ForgetRenders(instance.GetNamespace(), instance.GetName())
+		kustomize.ForgetRenderedManifests(instance.GetNamespace(), instance.GetName())
 
 		// Remove this KfDef instance
-		delete(kfdefInstances, strings.Join([]string{instance.GetName(), instance.GetNamespace()}, "."))
+		kfdefInstances.remove(strings.Join([]string{instance.GetName(), instance.GetNamespace()}, "."))
+		for _, app := range instance.Spec.Applications {
+			metrics.DeleteComponent(instance.GetNamespace(), instance.GetName(), app.Name)
+		}
+		lastErrors.forget(request.NamespacedName)
 
 		// Remove finalizer once kfDelete is completed.
 		finalizers.Delete(finalizer)
 		instance.SetFinalizers(finalizers.List())
-		finalizerError := r.client.Update(context.TODO(), instance)
+		finalizerError := r.client.Update(ctx, instance)
 		for retryCount := 0; errors.IsConflict(finalizerError) && retryCount < finalizerMaxRetries; retryCount++ {
 			// Based on Istio operator at https://github.com/istio/istio/blob/master/operator/pkg/controller/istiocontrolplane/istiocontrolplane_controller.go
 			// for finalizer removal errors workaround.
 			log.Info("Conflict during finalizer removal, retrying.")
-			_ = r.client.Get(context.TODO(), request.NamespacedName, instance)
+			_ = r.client.Get(ctx, request.NamespacedName, instance)
 			finalizers = sets.NewString(instance.GetFinalizers()...)
 			finalizers.Delete(finalizer)
 			instance.SetFinalizers(finalizers.List())
-			finalizerError = r.client.Update(context.TODO(), instance)
+			finalizerError = r.client.Update(ctx, instance)
 		}
 		if finalizerError != nil {
 			log.Errorf("Error removing finalizer: %v.", finalizerError)
@@ -420,13 +573,29 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 		log.Infof("Normally this should not happen. Adding finalizer %v: %v.", finalizer, request)
 		finalizers.Insert(finalizer)
 		instance.SetFinalizers(finalizers.List())
-		err = r.client.Update(context.TODO(), instance)
+		err = r.client.Update(ctx, instance)
 		if err != nil {
 			log.Errorf("Failed to update kfdef with finalizer. Error: %v.", err)
 			return reconcile.Result{}, err
 		}
 	}
 
+	// Collect the diagnostics requested by annotation before the app directory is cleaned up
+	if err := r.collectDiagnostics(instance); err != nil {
+		log.Warnf("Failed to collect the diagnostics of KfDef %v: %v", instance.Name, err)
+	}
+
+	// Paused KfDef instances are left as they are, along with the manifests they were last applied from,
+	// unless the operator is being uninstalled
+	if kfutils.IsPaused(instance) && !hasDeleteConfigMap(r.client) {
+		log.Infof("Reconciliation of KfDef %v is paused.", instance.Name)
+		if setPausedStatus(instance) {
+			r.recorder.Eventf(instance, v1.EventTypeNormal, "ReconcilePaused",
+				"Reconciliation of KfDef instance %s paused by the %s/%s annotation", instance.Name, kfutils.KfDefAnnotation, kfutils.Paused)
+		}
+		return reconcile.Result{}, r.reconcileStatus(ctx, instance)
+	}
+
 	// If this is a kfdef change, for now, remove the kfapp config path
 	if request.Name == instance.GetName() && request.Namespace == instance.GetNamespace() {
 		kfAppDir := path.Join("/tmp", instance.GetNamespace(), instance.GetName())
@@ -437,7 +606,7 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 	}
 
 	if hasDeleteConfigMap(r.client) {
-		for key, _ := range kfdefInstances {
+		for _, key := range kfdefInstances.list() {
 			keyVal := strings.Split(key, ".")
 			if len(keyVal) == 2 {
 				instanceName, namespace := keyVal[0], keyVal[1]
@@ -448,7 +617,7 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 					},
 				}
 
-				if err := r.client.Delete(context.TODO(), currentInstance, []client.DeleteOption{}...); err != nil {
+				if err := r.client.Delete(ctx, currentInstance, []client.DeleteOption{}...); err != nil {
 					if !errors.IsNotFound(err) {
 						return reconcile.Result{}, err
 					}
@@ -462,51 +631,181 @@ func (r *ReconcileKfDef) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{Requeue: true}, nil
 	}
 
-	err = getReconcileStatus(instance, kfApply(instance))
+	if DryRun || kfutils.IsDryRun(instance) {
+		err = getDryRunStatus(instance, kfApply(ctx, instance))
+		if err == nil {
+			log.Infof("KubeFlow Deployment dry run completed.")
+			r.recorder.Eventf(instance, v1.EventTypeNormal, "KfDefDryRunSuccessful",
+				"KfDef instance %s validated, the changes are in ConfigMap %s", instance.Name, kustomize.DryRunConfigMapName(instance.Name))
+		} else {
+			r.recorder.Eventf(instance, v1.EventTypeWarning, "KfDefDryRunFailed",
+				"Error validating KfDef instance %s: %v", instance.Name, err)
+		}
+		if err := r.reconcileStatus(ctx, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, err
+	}
+
+	// run the pre-flight checks of an operator upgrade before upgrading the applications
+	upgradeErr := r.checkUpgrade(instance)
+	if upgradeErr != nil {
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "UpgradePreflightFailed",
+			"Pre-flight checks of the upgrade of KfDef instance %s failed: %v", instance.Name, upgradeErr)
+		if BlockUpgrades {
+			log.Warnf("Upgrade of KfDef %v blocked: %v", instance.Name, upgradeErr)
+			setUpgradeableCondition(instance, upgradeErr)
+			if err := r.reconcileStatus(ctx, instance); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{RequeueAfter: preflightRetryPeriod}, nil
+		}
+	}
+
+	if _, unknown := featuregates.Gates.With(instance.Spec.FeatureGates); len(unknown) > 0 {
+		log.Warnf("Ignoring the unknown feature gates %v of KfDef %v.", strings.Join(unknown, ", "), instance.Name)
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "UnknownFeatureGates",
+			"Ignoring the unknown feature gates %s", strings.Join(unknown, ", "))
+	}
+
+	result := reconcile.Result{}
+	r.recorder.Eventf(instance, v1.EventTypeNormal, "ApplyStarted",
+		"Applying %d applications of KfDef instance %s", len(instance.Spec.Applications), instance.Name)
+	err = operatorUpgradeable.during("ReconcileInProgress", "KfDef "+request.String()+" is being applied", func() error {
+		if err := r.reconcilePodSecurity(instance); err != nil {
+			log.Warnf("Failed to set the pod security level of KfDef %v: %v", instance.Name, err)
+			r.recorder.Eventf(instance, v1.EventTypeWarning, "PodSecurityFailed",
+				"Failed to set the pod security level of the namespace of KfDef instance %s: %v", instance.Name, err)
+		}
+		// the service mesh is ready before the applications whose sidecars it injects are applied
+		if err := r.reconcileServiceMesh(instance); err != nil {
+			r.recorder.Eventf(instance, v1.EventTypeWarning, "ServiceMeshFailed",
+				"Failed to set up the service mesh of KfDef instance %s: %v", instance.Name, err)
+			return err
+		}
+		return kfApply(ctx, instance)
+	})
+	err = getReconcileStatus(instance, err)
+	setUpgradeableCondition(instance, upgradeErr)
+	reportComponentReadiness(instance)
+	lastErrors.record(instance)
+	r.recordApplyEvents(instance, err)
 	if err == nil {
 		log.Infof("KubeFlow Deployment Completed.")
 		r.recorder.Eventf(instance, v1.EventTypeNormal, "KfDefCreationSuccessful",
 			"KfDef instance %s created and deployed successfully", instance.Name)
+		instance.Status.OperatorVersion = OperatorVersion
+		instance.Status.ObservedGeneration = instance.Generation
 
 		// add to kfdefInstances if not exists
-		if _, ok := kfdefInstances[strings.Join([]string{instance.GetName(), instance.GetNamespace()}, ".")]; !ok {
-			kfdefInstances[strings.Join([]string{instance.GetName(), instance.GetNamespace()}, ".")] = struct{}{}
+		kfdefInstances.add(strings.Join([]string{instance.GetName(), instance.GetNamespace()}, "."))
+
+		// reconcile again when the next serving certificate has to be issued again
+		renewIn, certErr := r.renewCertificates(instance, path.Join("/tmp", instance.GetNamespace(), instance.GetName()))
+		if certErr != nil {
+			log.Warnf("Failed to renew the serving certificates of KfDef %v: %v", instance.Name, certErr)
 		}
+		result.RequeueAfter = renewIn
 
+		// reconcile again to fetch the git repos of the manifests again
+		if hasGitRepos(instance) && (result.RequeueAfter == 0 || kfconfig.GitFetchInterval < result.RequeueAfter) {
+			result.RequeueAfter = kfconfig.GitFetchInterval
+		}
+	} else if kfapis.IsConflict(err) {
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "KfDefResourceConflict",
+			"Resources of KfDef instance %s are managed by another KfDef or field manager: %v", instance.Name, err.(*kfapis.KfError).Message)
+	} else if kfapis.IsUnsafeUpgrade(err) {
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "CRDUpgradeRefused",
+			"Manifests of KfDef instance %s were not applied: %v", instance.Name, err.(*kfapis.KfError).Message)
 	}
 
 	// set status of the KfDef resource
-	if err := r.reconcileStatus(instance); err != nil {
+	if err := r.reconcileStatus(ctx, instance); err != nil {
 		return reconcile.Result{}, err
 	}
 
 	// If deployment created successfully - don't requeue
-	return reconcile.Result{}, err
+	return result, err
+}
+
+// recordApplyEvents emits an ApplySucceeded or ApplyFailed event for every application of the KfDef
+// according to its condition, and an ApplyFailed event for the KfDef if it failed before any application did.
+func (r *ReconcileKfDef) recordApplyEvents(instance *kfdefv1.KfDef, err error) {
+	failed := false
+	for _, cond := range instance.Status.ApplicationConditions {
+		switch cond.Type {
+		case kfdefv1.KfAvailable:
+			r.recorder.Eventf(instance, v1.EventTypeNormal, "ApplySucceeded",
+				"Application %s applied", cond.Application)
+		case kfdefv1.KfDegraded:
+			failed = true
+			r.recorder.Eventf(instance, v1.EventTypeWarning, "ApplyFailed",
+				"Application %s failed with reason %s: %s", cond.Application, cond.Reason, cond.Message)
+		}
+	}
+	if err != nil && !failed {
+		r.recorder.Eventf(instance, v1.EventTypeWarning, "ApplyFailed",
+			"KfDef instance %s failed: %v", instance.Name, err)
+	}
+}
+
+// DryRun makes the operator validate the manifests of every KfDef with a server-side dry run and record the
+// changes they would make in a ConfigMap instead of applying them.
+var DryRun = false
+
+// RelatedImagesConfigMap is the <namespace>/<name> ConfigMap holding the image overrides of every KfDef,
+// e.g. the mirror registry digests of the images of a disconnected install.
+var RelatedImagesConfigMap = ""
+
+// hasGitRepos returns true if some of the repos of the KfDef are git repos
+func hasGitRepos(instance *kfdefv1.KfDef) bool {
+	for _, repo := range instance.Spec.Repos {
+		if _, _, ok := kfconfig.ParseGitURI(repo.URI); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // kfApply is equivalent of kfctl apply
-func kfApply(instance *kfdefv1.KfDef) error {
+func kfApply(ctx context.Context, instance *kfdefv1.KfDef) error {
 	log.Infof("Creating a new KubeFlow Deployment. KubeFlow.Namespace: %v.", instance.Namespace)
+	span := tracing.Start(tracing.Active(tracing.Key(instance.Namespace, instance.Name)), "Load")
 	kfApp, err := kfLoadConfig(instance, "apply")
+	span.End(err)
 	if err != nil {
 		log.Errorf("Failed to load KfApp. Error: %v.", err)
+		setApplicationConditions(instance, nil, err)
 		return err
 	}
-	// Apply kfApp.
-	err = kfApp.Apply(kftypesv3.K8S)
+	// Apply kfApp with the context of the reconciliation
+	if withContext, ok := kfApp.(kftypesv3.KfAppContext); ok {
+		err = withContext.ApplyContext(ctx, kftypesv3.K8S)
+	} else {
+		err = kfApp.Apply(kftypesv3.K8S)
+	}
+	if getter, ok := kfApp.(coordinator.KfConfigGetter); ok {
+		setApplicationConditions(instance, getter.GetKfConfig().Status.ApplicationConditions, err)
+		setHookFailures(instance, getter.GetKfConfig().Status.HookFailures)
+		setSecurityContextConstraints(instance, getter.GetKfConfig().Status.SecurityContextConstraints)
+	}
 	return err
 }
 
 // kfDelete is equivalent of kfctl delete
-func kfDelete(instance *kfdefv1.KfDef) error {
+func kfDelete(ctx context.Context, instance *kfdefv1.KfDef) error {
 	log.Infof("Uninstall Kubeflow. KubeFlow.Namespace: %v.", instance.Namespace)
 	kfApp, err := kfLoadConfig(instance, "delete")
 	if err != nil {
 		log.Errorf("Failed to load KfApp. Error: %v.", err)
 		return err
 	}
-	// Delete kfApp.
-	err = kfApp.Delete(kftypesv3.K8S)
+	// Delete kfApp with the context of the reconciliation
+	if withContext, ok := kfApp.(kftypesv3.KfAppContext); ok {
+		err = withContext.DeleteContext(ctx, kftypesv3.K8S)
+	} else {
+		err = kfApp.Delete(kftypesv3.K8S)
+	}
 	return err
 }
 
@@ -534,6 +833,26 @@ func kfLoadConfig(instance *kfdefv1.KfDef, action string) (kftypesv3.KfApp, erro
 		setAnnotations(configFilePath, map[string]string{
 			setAnnotationAnn: "true",
 		})
+
+		if DryRun {
+			dryRunAnn := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.DryRun}, "/")
+			setAnnotations(configFilePath, map[string]string{
+				dryRunAnn: "true",
+			})
+		}
+
+		// The ConfigMaps the KfDef is annotated with take precedence
+		for ann, cm := range map[string]string{
+			kfutils.RelatedImages:   RelatedImagesConfigMap,
+			kfutils.TrustedCABundle: operatorconfig.Current().TrustedCABundle.ConfigMap,
+		} {
+			ann = strings.Join([]string{kfutils.KfDefAnnotation, ann}, "/")
+			if _, found := instance.GetAnnotations()[ann]; !found && cm != "" {
+				setAnnotations(configFilePath, map[string]string{
+					ann: cm,
+				})
+			}
+		}
 	}
 
 	if action == "delete" {
@@ -548,6 +867,12 @@ func kfLoadConfig(instance *kfdefv1.KfDef, action string) (kftypesv3.KfApp, erro
 		setAnnotations(configFilePath, map[string]string{
 			byOperatorAnn: "true",
 		})
+
+		// Indicate whether the cluster-scoped resources can be deleted
+		deleteClusterScopedAnn := strings.Join([]string{kfutils.KfDefAnnotation, kfutils.DeleteClusterScoped}, "/")
+		setAnnotations(configFilePath, map[string]string{
+			deleteClusterScopedAnn: strconv.FormatBool(DeleteClusterScopedResources),
+		})
 	}
 
 	kfApp, err := coordinator.NewLoadKfAppFromURI(configFilePath)
@@ -619,7 +944,7 @@ func (r *ReconcileKfDef) operatorUninstall(request reconcile.Request) error {
 	log.Infof("Namespace %s deleted as a part of uninstall.", namespace.Name)
 
 	// Wait until all kfdef instances and corresponding namespaces are deleted
-	if len(kfdefInstances) != 0 {
+	if len(kfdefInstances.list()) != 0 {
 		return fmt.Errorf("waiting for KfDef instances to be deleted")
 	}
 
@@ -708,6 +1033,42 @@ func removeCsv(c client.Client, r *rest.Config) error {
 	return nil
 }
 
+// updateAlertingConfiguration sends the alerts to the notification emails of the addon-managed-odh-parameters Secret
+// and rolls Prometheus out
+func (r *ReconcileKfDef) updateAlertingConfiguration(ctx context.Context) error {
+	newUserNotificationEmails, err := getNewUserNotificationEmails(r.client)
+	if err != nil {
+		return fmt.Errorf("error getting secret: addon-managed-odh-parameters")
+	}
+
+	config := &v1.ConfigMap{}
+	configParams := client.ObjectKey{
+		Namespace: "redhat-ods-monitoring",
+		Name:      "alertmanager",
+	}
+
+	err = r.client.Get(ctx, configParams, config)
+	if err != nil {
+		return fmt.Errorf("error getting configmap: alertmanager")
+	}
+
+	config.Data["alertmanager.yml"] = updateUserNotificationsEmails(config.Data["alertmanager.yml"], newUserNotificationEmails)
+
+	err = r.client.Update(ctx, config)
+	if err != nil {
+		return fmt.Errorf("error updating configmap: alertmanager")
+	}
+
+	alertmanagerAnnotationHash := sha256.Sum256([]byte(config.Data["alertmanager.yml"]))
+	base64AlertmanagerAnnotationHash := base64.StdEncoding.EncodeToString(alertmanagerAnnotationHash[:])
+
+	err = updateDeploymentConfigurationAnnotationHash(r.client, "prometheus", "redhat-ods-monitoring", "alertmanager", base64AlertmanagerAnnotationHash)
+	if err != nil {
+		return fmt.Errorf("error updating Prometheus deployment annotations")
+	}
+	return nil
+}
+
 func getNewUserNotificationEmails(c client.Client) (string, error) {
 
 	secret := &v1.Secret{}
@@ -750,6 +1111,9 @@ func updateDeploymentConfigurationAnnotationHash(c client.Client, deploymentName
 		return err
 	}
 
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
 	deployment.Spec.Template.Annotations[annotationName] = annotationHash
 	err = c.Update(context.TODO(), deployment)
 	if err != nil {
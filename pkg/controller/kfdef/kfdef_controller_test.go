@@ -0,0 +1,184 @@
+package kfdef
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestKfDefChanged(t *testing.T) {
+	old := metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh", Generation: 2, ResourceVersion: "10",
+		Finalizers: []string{finalizer}}
+	type testCase struct {
+		Name    string
+		Update  func(m *metav1.ObjectMeta)
+		Changed bool
+	}
+	testCases := []testCase{
+		{Name: "status", Update: func(m *metav1.ObjectMeta) { m.ResourceVersion = "11" }},
+		{Name: "resync", Update: func(m *metav1.ObjectMeta) {}, Changed: true},
+		{Name: "spec", Update: func(m *metav1.ObjectMeta) { m.ResourceVersion, m.Generation = "11", 3 }, Changed: true},
+		{
+			Name: "annotations",
+			Update: func(m *metav1.ObjectMeta) {
+				m.ResourceVersion, m.Annotations = "11", map[string]string{"kfctl.kubeflow.io/paused": "true"}
+			},
+			Changed: true,
+		},
+		{Name: "finalizers", Update: func(m *metav1.ObjectMeta) { m.ResourceVersion, m.Finalizers = "11", nil }, Changed: true},
+		{
+			Name: "deletion",
+			Update: func(m *metav1.ObjectMeta) {
+				now := metav1.Now()
+				m.ResourceVersion, m.DeletionTimestamp = "11", &now
+			},
+			Changed: true,
+		},
+	}
+	for _, c := range testCases {
+		updated := old.DeepCopy()
+		c.Update(updated)
+		if changed := kfdefChanged(&old, updated); changed != c.Changed {
+			t.Errorf("%v: kfdefChanged = %v, expected %v", c.Name, changed, c.Changed)
+		}
+	}
+}
+
+// TestConcurrentReconciles runs reconciles concurrently with the predicates and the deletions writing the state they
+// share, run it with -race
+func TestConcurrentReconciles(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := kfdefv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := metav1.Now()
+	objects := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "addon-managed-odh-parameters", Namespace: "redhat-ods-operator"},
+			Data:       map[string][]byte{"notification-email": []byte("admin@example.com")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "alertmanager", Namespace: "redhat-ods-monitoring"},
+			Data: map[string]string{"alertmanager.yml": "receivers:\n" +
+				"- name: 'user-notifications'\n  email_configs:\n  - to: 'user@example.com'\n"},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: "redhat-ods-monitoring"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+			}},
+		},
+	}
+	requests := []reconcile.Request{}
+	for i := 0; i < 4; i++ {
+		// The KfDef instances are deleted, their reconciles end once the alerting configuration is updated
+		name := fmt.Sprintf("opendatahub-%d", i)
+		objects = append(objects, &kfdefv1.KfDef{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "odh", DeletionTimestamp: &now},
+		})
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "odh", Name: name}})
+	}
+	r := &ReconcileKfDef{
+		client:   fake.NewFakeClientWithScheme(scheme, objects...),
+		scheme:   scheme,
+		recorder: record.NewFakeRecorder(100),
+	}
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "addon-managed-odh-parameters", Namespace: "redhat-ods-operator"},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		for _, request := range requests {
+			wg.Add(1)
+			go func(request reconcile.Request) {
+				defer wg.Done()
+				_, _ = r.Reconcile(request)
+			}(request)
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ownedResourcePredicates.Update(event.UpdateEvent{MetaOld: secret, ObjectOld: secret, MetaNew: secret, ObjectNew: secret})
+		}()
+		go func() {
+			defer wg.Done()
+			secondController.stopIfLast(1)
+		}()
+	}
+	wg.Wait()
+
+	// The update triggered last is taken by the next reconcile
+	if _, err := r.Reconcile(requests[0]); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&addonManagedODHParametersSecretUpdated) != 0 {
+		t.Errorf("Expected the update of the alerting configuration to be taken by a reconcile")
+	}
+	config := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: "redhat-ods-monitoring", Name: "alertmanager"}, config); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config.Data["alertmanager.yml"], "'admin@example.com'") {
+		t.Errorf("Expected the alerts to be sent to the notification email, got %v", config.Data["alertmanager.yml"])
+	}
+}
+
+func TestStopIfLast(t *testing.T) {
+	c := &stoppableController{started: true, stop: make(chan struct{})}
+	c.stopIfLast(2)
+	select {
+	case <-c.stop:
+		t.Fatalf("Expected the controller to run while other KfDef instances remain")
+	default:
+	}
+	// Stopping twice does not close the channel again
+	c.stopIfLast(1)
+	c.stopIfLast(1)
+	select {
+	case <-c.stop:
+	default:
+		t.Errorf("Expected the controller to stop with the last KfDef instance")
+	}
+}
+
+func TestServiceMeshPassesPredicate(t *testing.T) {
+	r := &ReconcileKfDef{client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme)}
+	if err := r.createServiceMeshControlPlane("istio-system", "data-science-smcp"); err != nil {
+		t.Fatalf("Failed to create the ServiceMeshControlPlane: %v", err)
+	}
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "istio-system"}, ns); err != nil {
+		t.Fatalf("Expected the namespace of the ServiceMeshControlPlane to be created: %v", err)
+	}
+	ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if !ownedResourcePredicates.Delete(event.DeleteEvent{Meta: ns, Object: ns}) {
+		t.Errorf("Expected the deletion of the namespace to pass the predicate of the watches, got labels %v", ns.Labels)
+	}
+	if !ownedResourcePredicates.Update(event.UpdateEvent{MetaOld: ns, ObjectOld: ns, MetaNew: ns, ObjectNew: ns}) {
+		t.Errorf("Expected the updates of the namespace to pass the predicate of the watches, got labels %v", ns.Labels)
+	}
+	if !kfutils.IsManaged(ns) {
+		t.Errorf("Expected the namespace to be labelled %v, got %v", kfutils.ManagedLabel, ns.Labels)
+	}
+}
@@ -0,0 +1,161 @@
+//go:build integration
+// +build integration
+
+package kfdef
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/testenv"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileKfDef(t *testing.T) {
+	if !testenv.Available() {
+		t.Skip("The control plane binaries are not installed, see package testenv.")
+	}
+	env, err := testenv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Stop()
+
+	fixtures, err := ioutil.TempDir("", "kfdef-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fixtures)
+	// writeApplication writes the manifests of the odh-test application, deploying the ConfigMaps
+	writeApplication := func(configMaps ...string) {
+		files := map[string]string{}
+		resources := []string{}
+		for _, name := range configMaps {
+			files["odh-manifests/odh-test/"+name+".yaml"] = fmt.Sprintf(
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %v\ndata:\n  key: value\n", name)
+			resources = append(resources, "- "+name+".yaml\n")
+		}
+		files["odh-manifests/odh-test/kustomization.yaml"] = "resources:\n" + strings.Join(resources, "")
+		if err := testenv.WriteManifests(fixtures, files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeApplication("odh-test-a", "odh-test-b")
+	manifests := testenv.NewManifestServer(fixtures)
+	defer manifests.Close()
+
+	namespace := "odh-integration"
+	if err := env.CreateNamespace(namespace); err != nil {
+		t.Fatal(err)
+	}
+	instance := &kfdefv1.KfDef{
+		ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: namespace},
+		Spec: kfdefv1.KfDefSpec{
+			Repos: []kfdefv1.Repo{{Name: "manifests", URI: manifests.URI("odh-manifests")}},
+			Applications: []kfdefv1.Application{{
+				Name: "odh-test",
+				KustomizeConfig: &kfdefv1.KustomizeConfig{
+					RepoRef: &kfdefv1.RepoRef{Name: "manifests", Path: "odh-test"},
+				},
+			}},
+		},
+	}
+	if err := env.Client.Create(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+	r := &ReconcileKfDef{
+		client:     env.Client,
+		scheme:     env.Scheme,
+		restConfig: env.Config,
+		recorder:   record.NewFakeRecorder(100),
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: instance.Name}
+	configMapExists := func(name string) bool {
+		err := env.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, &corev1.ConfigMap{})
+		if err != nil && !errors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		if _, err := testenv.Reconcile(r, key); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+		for _, name := range []string{"odh-test-a", "odh-test-b"} {
+			if !configMapExists(name) {
+				t.Errorf("Expected ConfigMap %v to be applied", name)
+			}
+		}
+		current := &kfdefv1.KfDef{}
+		if err := env.Client.Get(context.TODO(), key, current); err != nil {
+			t.Fatal(err)
+		}
+		available := false
+		for _, cond := range current.Status.Conditions {
+			if cond.Type == kfdefv1.KfDegraded && cond.Status == corev1.ConditionTrue {
+				t.Errorf("Expected KfDef %v not to be degraded, got %+v", key, cond)
+			}
+			available = available || cond.Type == kfdefv1.KfAvailable && cond.Status == corev1.ConditionTrue
+		}
+		if !available {
+			t.Errorf("Expected KfDef %v to be available, got the conditions %+v", key, current.Status.Conditions)
+		}
+	})
+
+	t.Run("render cache", func(t *testing.T) {
+		// The unchanged application is skipped, the manifests it was applied from are still kept in the app dir
+		rendered := kustomize.RenderedManifestsPath(path.Join("/tmp", namespace, instance.Name), "odh-test")
+		for i := 0; i < 2; i++ {
+			if _, err := testenv.Reconcile(r, key); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+			if _, err := os.Stat(rendered); err != nil {
+				t.Errorf("Expected the rendered manifests to be kept after reconcile %d: %v", i+1, err)
+			}
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		writeApplication("odh-test-a")
+		if _, err := testenv.Reconcile(r, key); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+		if !configMapExists("odh-test-a") {
+			t.Errorf("Expected ConfigMap odh-test-a to be kept")
+		}
+		if configMapExists("odh-test-b") {
+			t.Errorf("Expected ConfigMap odh-test-b to be pruned once removed from the manifests")
+		}
+		if requests := manifests.Requests("odh-manifests"); requests < 2 {
+			t.Errorf("Expected the manifests to be fetched again, got %v requests", requests)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		current := &kfdefv1.KfDef{}
+		if err := env.Client.Get(context.TODO(), key, current); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.Client.Delete(context.TODO(), current); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := testenv.Reconcile(r, key); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+		if err := env.Client.Get(context.TODO(), key, current); !errors.IsNotFound(err) {
+			t.Errorf("Expected KfDef %v to be deleted once its finalizer is removed, got %v", key, err)
+		}
+	})
+}
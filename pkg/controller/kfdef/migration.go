@@ -0,0 +1,85 @@
+package kfdef
+
+import (
+	"context"
+
+	"github.com/cenkalti/backoff"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// kfdefCRD is the name of the KfDef CustomResourceDefinition
+const kfdefCRD = "kfdefs.kfdef.apps.kubeflow.org"
+
+// addStorageVersionMigration migrates the KfDef instances stored with a former version of the CRD, e.g. v1beta1,
+// to the storage version once the manager is started.
+func addStorageVersionMigration(mgr manager.Manager) error {
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		// the API server may need the conversion webhook served by the manager to read the instances
+		err := backoff.Retry(func() error {
+			return migrateStorageVersion(mgr)
+		}, kfutils.NewDefaultBackoff())
+		if err != nil {
+			log.Errorf("Failed to migrate the KfDef instances to the storage version. Error: %v.", err)
+		}
+		// The manager stops as soon as one of its runnables returns
+		<-stop
+		return nil
+	}))
+}
+
+// migrateStorageVersion rewrites every KfDef instance so that it is stored with the storage version of the CRD,
+// then drops the former versions from the stored versions of the CRD for them to be removed in a later release.
+func migrateStorageVersion(mgr manager.Manager) error {
+	crdClient, err := crdclientset.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	crd, err := crdClient.CustomResourceDefinitions().Get(kfdefCRD, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	storageVersion := ""
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			storageVersion = version.Name
+		}
+	}
+	if storageVersion == "" || len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion {
+		return nil
+	}
+	log.Infof("Migrating the KfDef instances stored as %v to %v.", crd.Status.StoredVersions, storageVersion)
+	operatorUpgradeable.begin("MigrationInProgress", "KfDef instances are being migrated to "+storageVersion)
+	defer operatorUpgradeable.end()
+
+	// read the instances from the API server rather than from the cache, which is not started yet
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	instances := &kfdefv1.KfDefList{}
+	if err := c.List(context.TODO(), instances); err != nil {
+		return err
+	}
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		// an update without changes is enough for the API server to store the instance with the storage version
+		if err := c.Update(context.TODO(), instance); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		log.Infof("KfDef %v/%v migrated to %v.", instance.Namespace, instance.Name, storageVersion)
+	}
+
+	crd.Status.StoredVersions = []string{storageVersion}
+	if _, err := crdClient.CustomResourceDefinitions().UpdateStatus(crd); err != nil {
+		return err
+	}
+	log.Infof("KfDef instances migrated to %v.", storageVersion)
+	return nil
+}
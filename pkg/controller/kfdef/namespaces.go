@@ -0,0 +1,88 @@
+package kfdef
+
+import (
+	"context"
+	"reflect"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// NamespaceSelector restricts the KfDef instances reconciled by the operator to the namespaces whose labels
+// it matches. The namespaces are selected as their labels change. All namespaces are selected when it is nil.
+var NamespaceSelector labels.Selector
+
+// isNamespaceSelected returns true if the KfDef instances of the namespace are reconciled by the operator
+func isNamespaceSelected(c client.Client, namespace string) (bool, error) {
+	if NamespaceSelector == nil {
+		return true, nil
+	}
+	ns := &v1.Namespace{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: namespace}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return NamespaceSelector.Matches(labels.Set(ns.GetLabels())), nil
+}
+
+// watchSelectedNamespaces reconciles the KfDef instances of a namespace when it becomes selected by NamespaceSelector
+func watchSelectedNamespaces(c controller.Controller, r client.Client) error {
+	if NamespaceSelector == nil {
+		return nil
+	}
+	log.Infof("Reconciling the KfDef instances of the namespaces selected by %v.", NamespaceSelector)
+	return c.Watch(&source.Kind{Type: &v1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: namespaceInstances(r),
+	}, namespacePredicates)
+}
+
+// namespaceInstances maps a namespace to the reconcile requests of its KfDef instances
+func namespaceInstances(r client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		instances := &kfdefv1.KfDefList{}
+		if err := r.List(context.TODO(), instances, client.InNamespace(a.Meta.GetName())); err != nil {
+			log.Errorf("Failed to list the KfDef instances of namespace %v. Error: %v.", a.Meta.GetName(), err)
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, instance := range instances.Items {
+			log.Infof("Namespace %v selected, reconciling KfDef %v.", a.Meta.GetName(), instance.Name)
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace},
+			})
+		}
+		return requests
+	}
+}
+
+var namespacePredicates = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return NamespaceSelector.Matches(labels.Set(e.Meta.GetLabels()))
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	DeleteFunc: func(_ event.DeleteEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) {
+			return false
+		}
+		return !NamespaceSelector.Matches(labels.Set(e.MetaOld.GetLabels())) &&
+			NamespaceSelector.Matches(labels.Set(e.MetaNew.GetLabels()))
+	},
+}
@@ -0,0 +1,150 @@
+package kfdef
+
+import (
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// watchRecorder is a controller recording the sources it watches
+type watchRecorder struct {
+	sources []source.Source
+}
+
+func (c *watchRecorder) Reconcile(reconcile.Request) (reconcile.Result, error) {
+	return reconcile.Result{}, nil
+}
+
+func (c *watchRecorder) Watch(src source.Source, _ handler.EventHandler, _ ...predicate.Predicate) error {
+	c.sources = append(c.sources, src)
+	return nil
+}
+
+func (c *watchRecorder) Start(<-chan struct{}) error {
+	return nil
+}
+
+// setNamespaceSelector sets NamespaceSelector to selector, or to nil when empty, and returns a function restoring it
+func setNamespaceSelector(t *testing.T, selector string) func() {
+	previous := NamespaceSelector
+	restore := func() { NamespaceSelector = previous }
+	NamespaceSelector = nil
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		NamespaceSelector = s
+	}
+	return restore
+}
+
+func TestIsNamespaceSelected(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme,
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "watched", Labels: map[string]string{"opendatahub.io/watched": "true"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+	type testCase struct {
+		Name      string
+		Selector  string
+		Namespace string
+		Selected  bool
+	}
+	testCases := []testCase{
+		{Name: "no selector", Namespace: "other", Selected: true},
+		{Name: "no selector missing namespace", Namespace: "missing", Selected: true},
+		{Name: "matching labels", Selector: "opendatahub.io/watched=true", Namespace: "watched", Selected: true},
+		{Name: "other labels", Selector: "opendatahub.io/watched=true", Namespace: "other"},
+		{Name: "missing namespace", Selector: "opendatahub.io/watched=true", Namespace: "missing"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer setNamespaceSelector(t, tc.Selector)()
+			selected, err := isNamespaceSelected(c, tc.Namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if selected != tc.Selected {
+				t.Errorf("Expected namespace %v selected to be %v, got %v", tc.Namespace, tc.Selected, selected)
+			}
+		})
+	}
+}
+
+func TestWatchSelectedNamespaces(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme)
+
+	defer setNamespaceSelector(t, "")()
+	recorder := &watchRecorder{}
+	if err := watchSelectedNamespaces(recorder, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.sources) != 0 {
+		t.Errorf("Expected no watch without a selector, got %v", recorder.sources)
+	}
+
+	NamespaceSelector = labels.SelectorFromSet(labels.Set{"opendatahub.io/watched": "true"})
+	if err := watchSelectedNamespaces(recorder, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.sources) != 1 {
+		t.Fatalf("Expected the namespaces to be watched, got %v", recorder.sources)
+	}
+	if kind, ok := recorder.sources[0].(*source.Kind); !ok {
+		t.Errorf("Expected a watch of the namespaces, got %v", recorder.sources[0])
+	} else if _, ok := kind.Type.(*v1.Namespace); !ok {
+		t.Errorf("Expected a watch of the namespaces, got %T", kind.Type)
+	}
+}
+
+func TestNamespaceInstances(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := kfdefv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme,
+		&kfdefv1.KfDef{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "watched"}},
+		&kfdefv1.KfDef{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "other"}},
+	)
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "watched"}}
+	requests := namespaceInstances(c)(handler.MapObject{Meta: ns, Object: ns})
+	if len(requests) != 1 || requests[0].Namespace != "watched" || requests[0].Name != "opendatahub" {
+		t.Errorf("Expected the KfDef watched/opendatahub to be reconciled, got %v", requests)
+	}
+}
+
+func TestNamespacePredicates(t *testing.T) {
+	defer setNamespaceSelector(t, "opendatahub.io/watched=true")()
+	watched := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "odh", Labels: map[string]string{"opendatahub.io/watched": "true"}}}
+	other := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "odh"}}
+
+	if !namespacePredicates.Create(event.CreateEvent{Meta: watched, Object: watched}) {
+		t.Errorf("Expected the creation of a selected namespace to be reconciled")
+	}
+	if namespacePredicates.Create(event.CreateEvent{Meta: other, Object: other}) {
+		t.Errorf("Expected the creation of another namespace to be ignored")
+	}
+	if !namespacePredicates.Update(event.UpdateEvent{MetaOld: other, ObjectOld: other, MetaNew: watched, ObjectNew: watched}) {
+		t.Errorf("Expected a namespace becoming selected to be reconciled")
+	}
+	if namespacePredicates.Update(event.UpdateEvent{MetaOld: watched, ObjectOld: watched, MetaNew: other, ObjectNew: other}) {
+		t.Errorf("Expected a namespace no longer selected to be ignored")
+	}
+	if namespacePredicates.Delete(event.DeleteEvent{Meta: watched, Object: watched}) {
+		t.Errorf("Expected the deletion of a namespace to be ignored")
+	}
+}
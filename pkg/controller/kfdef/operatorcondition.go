@@ -0,0 +1,200 @@
+package kfdef
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// operatorConditionNameEnvVar is set by OLM to the name of the OperatorCondition of the operator
+	operatorConditionNameEnvVar = "OPERATOR_CONDITION_NAME"
+	// operatorUpgradeableCondition is the condition type OLM reads to tell whether the operator can be upgraded
+	operatorUpgradeableCondition = "Upgradeable"
+)
+
+// OperatorConditionGVK is the kind of the OLM OperatorCondition through which the operator tells OLM whether it can be upgraded
+var OperatorConditionGVK = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v2", Kind: "OperatorCondition"}
+
+// operatorConditionRetryPeriod is how often the OperatorCondition is updated again after a failed update
+const operatorConditionRetryPeriod = 10 * time.Second
+
+// operatorUpgradeable reports the operator as not upgradeable while KfDef instances are applied or migrated
+var operatorUpgradeable = &upgradeableCondition{}
+
+// upgradeableState is the status, reason and message of the Upgradeable condition
+type upgradeableState struct {
+	status  string
+	reason  string
+	message string
+}
+
+// upgradeable is the Upgradeable condition reported while no operation is in flight
+var upgradeable = upgradeableState{status: "True", reason: "NoOperationInProgress", message: "The operator can be upgraded"}
+
+// upgradeableCondition sets the Upgradeable condition of the OperatorCondition of the operator to False
+// while operations are in flight, and back to True once they are all done. The operations only record the
+// condition to report; a single writer updates the OperatorCondition with the latest one, outside of the lock, and
+// retries until it succeeds.
+type upgradeableCondition struct {
+	sync.Mutex
+	// client is nil when the operator is not deployed by OLM
+	client   client.Client
+	key      client.ObjectKey
+	inFlight int
+	// desired is the condition to report, written the one last written to the OperatorCondition
+	desired upgradeableState
+	written upgradeableState
+	// changed wakes the writer up when desired changes
+	changed chan struct{}
+}
+
+// init looks up the OperatorCondition of the operator, if it is deployed by OLM, and starts reporting the
+// Upgradeable condition
+func (c *upgradeableCondition) init(cfg *rest.Config) error {
+	name := os.Getenv(operatorConditionNameEnvVar)
+	if name == "" {
+		log.Infof("%v is not set, not reporting the operator conditions to OLM.", operatorConditionNameEnvVar)
+		return nil
+	}
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return err
+	}
+	kubeclient, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.client = kubeclient
+	c.key = client.ObjectKey{Namespace: namespace, Name: name}
+	if c.inFlight == 0 {
+		c.desired = upgradeable
+	}
+	c.changed = make(chan struct{}, 1)
+	c.Unlock()
+	go c.run()
+	c.notify()
+	return nil
+}
+
+// begin reports the operator as not upgradeable until the matching call to end
+func (c *upgradeableCondition) begin(reason string, message string) {
+	c.Lock()
+	c.inFlight++
+	if c.inFlight == 1 {
+		c.desired = upgradeableState{status: "False", reason: reason, message: message}
+	}
+	c.Unlock()
+	c.notify()
+}
+
+// end reports the operator as upgradeable again once no operation is in flight
+func (c *upgradeableCondition) end() {
+	c.Lock()
+	c.inFlight--
+	if c.inFlight == 0 {
+		c.desired = upgradeable
+	}
+	c.Unlock()
+	c.notify()
+}
+
+// during reports the operator as not upgradeable while operation runs, and returns its error
+func (c *upgradeableCondition) during(reason string, message string, operation func() error) error {
+	c.begin(reason, message)
+	defer c.end()
+	return operation()
+}
+
+// notify wakes the writer up, if it is started and not already woken up
+func (c *upgradeableCondition) notify() {
+	c.Lock()
+	changed := c.changed
+	c.Unlock()
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+// run writes the desired condition every time it changes, and again after operatorConditionRetryPeriod while
+// it fails to
+func (c *upgradeableCondition) run() {
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-c.changed:
+		case <-retry:
+		}
+		retry = nil
+		if err := c.sync(); err != nil {
+			log.Warnf("Failed to update OperatorCondition %v, retrying in %v. Error: %v.", c.key,
+				operatorConditionRetryPeriod, err)
+			retry = time.After(operatorConditionRetryPeriod)
+		}
+	}
+}
+
+// sync writes the desired condition to the OperatorCondition unless it was the last one written. The conditions
+// reported while it writes are written on the next call.
+func (c *upgradeableCondition) sync() error {
+	c.Lock()
+	kubeclient, key, desired, written := c.client, c.key, c.desired, c.written
+	c.Unlock()
+	if kubeclient == nil || desired == written {
+		return nil
+	}
+	if err := setUpgradeable(kubeclient, key, desired); err != nil {
+		return err
+	}
+	c.Lock()
+	c.written = desired
+	c.Unlock()
+	log.Infof("Operator reported to OLM as %v=%v: %v.", operatorUpgradeableCondition, desired.status, desired.message)
+	return nil
+}
+
+// setUpgradeable updates the Upgradeable condition in the spec of the OperatorCondition key
+func setUpgradeable(kubeclient client.Client, key client.ObjectKey, state upgradeableState) error {
+	operatorCondition := &unstructured.Unstructured{}
+	operatorCondition.SetGroupVersionKind(OperatorConditionGVK)
+	if err := kubeclient.Get(context.TODO(), key, operatorCondition); err != nil {
+		return err
+	}
+	conditions, _, err := unstructured.NestedSlice(operatorCondition.Object, "spec", "conditions")
+	if err != nil {
+		return err
+	}
+	condition := map[string]interface{}{
+		"type":               operatorUpgradeableCondition,
+		"status":             state.status,
+		"reason":             state.reason,
+		"message":            state.message,
+		"lastTransitionTime": time.Now().UTC().Format(time.RFC3339),
+	}
+	updated := []interface{}{}
+	for _, cond := range conditions {
+		if m, ok := cond.(map[string]interface{}); ok && m["type"] == operatorUpgradeableCondition {
+			if m["status"] == state.status {
+				condition["lastTransitionTime"] = m["lastTransitionTime"]
+			}
+			continue
+		}
+		updated = append(updated, cond)
+	}
+	updated = append(updated, condition)
+	if err := unstructured.SetNestedSlice(operatorCondition.Object, updated, "spec", "conditions"); err != nil {
+		return err
+	}
+	return kubeclient.Update(context.TODO(), operatorCondition)
+}
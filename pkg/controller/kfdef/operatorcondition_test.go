@@ -0,0 +1,92 @@
+package kfdef
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// failingUpdates is a client whose updates fail while fail is set
+type failingUpdates struct {
+	client.Client
+	fail bool
+}
+
+func (c *failingUpdates) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if c.fail {
+		return errors.New("update failed")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestUpgradeableCondition(t *testing.T) {
+	operatorCondition := &unstructured.Unstructured{}
+	operatorCondition.SetGroupVersionKind(OperatorConditionGVK)
+	operatorCondition.SetNamespace("odh-operator")
+	operatorCondition.SetName("opendatahub-operator.v1.2.0")
+	kubeclient := &failingUpdates{Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme)}
+	if err := kubeclient.Create(context.TODO(), operatorCondition); err != nil {
+		t.Fatal(err)
+	}
+	key := client.ObjectKey{Namespace: "odh-operator", Name: "opendatahub-operator.v1.2.0"}
+	c := &upgradeableCondition{client: kubeclient, key: key, desired: upgradeable}
+
+	expectCondition := func(status string, reason string) {
+		t.Helper()
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(OperatorConditionGVK)
+		if err := kubeclient.Get(context.TODO(), key, u); err != nil {
+			t.Fatal(err)
+		}
+		conditions, _, _ := unstructured.NestedSlice(u.Object, "spec", "conditions")
+		if len(conditions) != 1 {
+			t.Fatalf("Expected a single condition, got %v", conditions)
+		}
+		cond := conditions[0].(map[string]interface{})
+		if cond["type"] != operatorUpgradeableCondition || cond["status"] != status || cond["reason"] != reason {
+			t.Errorf("Expected the Upgradeable condition to be %v with reason %v, got %v", status, reason, cond)
+		}
+	}
+
+	if err := c.sync(); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition("True", "NoOperationInProgress")
+
+	// the updates are retried with the latest condition once they succeed again
+	kubeclient.fail = true
+	c.begin("ReconcileInProgress", "KfDef odh/opendatahub is being applied")
+	c.begin("MigrationInProgress", "KfDef instances are being migrated to v1")
+	if err := c.sync(); err == nil {
+		t.Fatalf("Expected the update of the OperatorCondition to fail")
+	}
+	expectCondition("True", "NoOperationInProgress")
+	kubeclient.fail = false
+	if err := c.sync(); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition("False", "ReconcileInProgress")
+
+	// the operator is upgradeable once every operation ended, even the failed ones
+	c.end()
+	if err := c.sync(); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition("False", "ReconcileInProgress")
+	if err := c.during("ReconcileInProgress", "KfDef odh/opendatahub is being deleted", func() error {
+		return errors.New("delete failed")
+	}); err == nil {
+		t.Errorf("Expected the error of the operation to be returned")
+	}
+	c.end()
+	if err := c.sync(); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition("True", "NoOperationInProgress")
+}
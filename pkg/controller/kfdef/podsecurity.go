@@ -0,0 +1,62 @@
+package kfdef
+
+import (
+	"context"
+	"fmt"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// namespacePodSecurity returns the pod security level the namespace of the KfDef is labelled with, the least
+// restrictive level of its applications. It is empty when neither the spec nor the applications set one.
+func namespacePodSecurity(instance *kfdefv1.KfDef) kfconfig.PodSecurityLevel {
+	levels := []kfconfig.PodSecurityLevel{}
+	for _, app := range instance.Spec.Applications {
+		level := app.PodSecurity
+		if level == "" {
+			level = instance.Spec.PodSecurity
+		}
+		levels = append(levels, kfconfig.PodSecurityLevel(level))
+	}
+	return kustomize.LeastRestrictivePodSecurity(levels...)
+}
+
+// reconcilePodSecurity labels the namespace of the KfDef with the pod security level of its applications, before
+// their pods are admitted. The applications namespace is left alone, it is labelled with the level of the
+// OperatorConfig.
+func (r *ReconcileKfDef) reconcilePodSecurity(instance *kfdefv1.KfDef) error {
+	level := namespacePodSecurity(instance)
+	if level == "" || instance.Namespace == operatorconfig.Current().ApplicationsNamespace {
+		return nil
+	}
+	ns := &v1.Namespace{}
+	if err := r.client.Get(context.TODO(), client.ObjectKey{Name: instance.Namespace}, ns); err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get namespace %v: %v", instance.Namespace, err),
+		}
+	}
+	if ns.Labels[kustomize.PodSecurityEnforceLabel] == string(level) && ns.Labels[kustomize.PodSecurityLabelSyncLabel] == "false" {
+		return nil
+	}
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	ns.Labels[kustomize.PodSecurityEnforceLabel] = string(level)
+	ns.Labels[kustomize.PodSecurityLabelSyncLabel] = "false"
+	if err := r.client.Update(context.TODO(), ns); err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to label namespace %v with the %v pod security level: %v", instance.Namespace, level, err),
+		}
+	}
+	log.Infof("Labelled namespace %v with the %v pod security level.", instance.Namespace, level)
+	return nil
+}
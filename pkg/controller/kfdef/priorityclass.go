@@ -0,0 +1,38 @@
+package kfdef
+
+import (
+	"strings"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// watchControlPlanePriorityClass reconciles every KfDef instance when the operator settings assign another
+// PriorityClass to the control plane, so that the pods of the applications are rolled out with it
+func watchControlPlanePriorityClass(c controller.Controller) error {
+	events := operatorconfig.Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.PriorityClasses.ControlPlane.Name != next.PriorityClasses.ControlPlane.Name
+	})
+	return c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			requests := []reconcile.Request{}
+			for _, k := range kfdefInstances.list() {
+				kfdefCr := strings.Split(k, ".")
+				log.Infof("PriorityClass of the control plane of OperatorConfig %v changed, reconciling KfDef %v.",
+					a.Meta.GetName(), k)
+				kustomize.InvalidateRenderCache(kfdefCr[1], kfdefCr[0])
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: kfdefCr[0], Namespace: kfdefCr[1]},
+				})
+			}
+			return requests
+		}),
+	})
+}
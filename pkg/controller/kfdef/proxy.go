@@ -0,0 +1,59 @@
+package kfdef
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// watchClusterProxy reconciles every KfDef instance when the OpenShift cluster-wide Proxy changes, so that
+// the proxy environment variables of the Deployments are updated. It is a no-op on clusters without the Proxy kind.
+func watchClusterProxy(c controller.Controller) error {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(kustomize.ClusterProxyGVK)
+	err := c.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			requests := []reconcile.Request{}
+			for _, k := range kfdefInstances.list() {
+				kfdefCr := strings.Split(k, ".")
+				log.Infof("Cluster-wide Proxy changed, reconciling KfDef %v.", k)
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: kfdefCr[0], Namespace: kfdefCr[1]},
+				})
+			}
+			return requests
+		}),
+	}, proxyPredicates)
+	if err != nil {
+		log.Infof("Not watching the cluster-wide Proxy: %v.", err)
+	}
+	return nil
+}
+
+var proxyPredicates = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		// the Proxy is read when the KfDef instances are reconciled
+		return false
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return true
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldStatus, _, _ := unstructured.NestedMap(e.ObjectOld.(*unstructured.Unstructured).Object, "status")
+		newStatus, _, _ := unstructured.NestedMap(e.ObjectNew.(*unstructured.Unstructured).Object, "status")
+		return !reflect.DeepEqual(oldStatus, newStatus)
+	},
+}
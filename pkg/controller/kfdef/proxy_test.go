@@ -0,0 +1,52 @@
+package kfdef
+
+import (
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestProxyPredicates(t *testing.T) {
+	proxy := func(status map[string]interface{}, resourceVersion string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(kustomize.ClusterProxyGVK)
+		u.SetName("cluster")
+		u.SetResourceVersion(resourceVersion)
+		if status != nil {
+			u.Object["status"] = status
+		}
+		return u
+	}
+	current := proxy(map[string]interface{}{"httpProxy": "http://proxy:3128", "noProxy": ".svc"}, "1")
+
+	type testCase struct {
+		Name     string
+		Old      *unstructured.Unstructured
+		New      *unstructured.Unstructured
+		Expected bool
+	}
+	testCases := []testCase{
+		{Name: "status unchanged", Old: current, New: proxy(map[string]interface{}{"httpProxy": "http://proxy:3128", "noProxy": ".svc"}, "2")},
+		{Name: "NO_PROXY changed", Old: current, Expected: true,
+			New: proxy(map[string]interface{}{"httpProxy": "http://proxy:3128", "noProxy": ".svc,.cluster.local"}, "2")},
+		{Name: "proxy removed", Old: current, New: proxy(nil, "2"), Expected: true},
+		{Name: "proxy set", Old: proxy(nil, "1"), New: current, Expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			e := event.UpdateEvent{MetaOld: tc.Old, ObjectOld: tc.Old, MetaNew: tc.New, ObjectNew: tc.New}
+			if reconciled := proxyPredicates.Update(e); reconciled != tc.Expected {
+				t.Errorf("Expected the KfDef instances to be reconciled (%v), got %v", tc.Expected, reconciled)
+			}
+		})
+	}
+
+	if proxyPredicates.Create(event.CreateEvent{Meta: current, Object: current}) {
+		t.Errorf("Expected the creation of the Proxy to be ignored, it is read when the KfDef instances are reconciled")
+	}
+	if !proxyPredicates.Delete(event.DeleteEvent{Meta: current, Object: current}) {
+		t.Errorf("Expected the deletion of the Proxy to reconcile the KfDef instances")
+	}
+}
@@ -0,0 +1,214 @@
+package kfdef
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// serviceMeshMemberRollName is the name the Service Mesh operator requires for the ServiceMeshMemberRoll
+const serviceMeshMemberRollName = "default"
+
+var (
+	// ServiceMeshControlPlane is the <namespace>/<name> of the ServiceMeshControlPlane of the KfDef instances
+	// whose serviceMesh is Managed. It is created when it does not exist.
+	ServiceMeshControlPlane = "istio-system/data-science-smcp"
+
+	// ServiceMeshControlPlaneGVK is the kind of the control planes of the OpenShift Service Mesh
+	ServiceMeshControlPlaneGVK = schema.GroupVersionKind{Group: "maistra.io", Version: "v2", Kind: "ServiceMeshControlPlane"}
+	// ServiceMeshMemberRollGVK is the kind listing the namespaces enrolled into the OpenShift Service Mesh
+	ServiceMeshMemberRollGVK = schema.GroupVersionKind{Group: "maistra.io", Version: "v1", Kind: "ServiceMeshMemberRoll"}
+)
+
+// reconcileServiceMesh makes sure the ServiceMeshControlPlane exists and is ready, and enrolls the namespace of the
+// KfDef into the mesh, before the sidecars are injected into its applications. It is a no-op unless the serviceMesh
+// of the KfDef is Managed.
+func (r *ReconcileKfDef) reconcileServiceMesh(instance *kfdefv1.KfDef) error {
+	if instance.Spec.ServiceMesh != kfdefv1.Managed {
+		return nil
+	}
+	namespace, name, err := serviceMeshControlPlaneKey()
+	if err != nil {
+		return err
+	}
+
+	smcp := &unstructured.Unstructured{}
+	smcp.SetGroupVersionKind(ServiceMeshControlPlaneGVK)
+	err = r.client.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, smcp)
+	switch {
+	case meta.IsNoMatchError(err):
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: "serviceMesh is Managed but the OpenShift Service Mesh operator is not installed",
+		}
+	case errors.IsNotFound(err):
+		if err := r.createServiceMeshControlPlane(namespace, name); err != nil {
+			return err
+		}
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("ServiceMeshControlPlane %v created, waiting for it to be ready", ServiceMeshControlPlane),
+		}
+	case err != nil:
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get ServiceMeshControlPlane %v: %v", ServiceMeshControlPlane, err),
+		}
+	}
+	if !isServiceMeshReady(smcp) {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("ServiceMeshControlPlane %v is not ready", ServiceMeshControlPlane),
+		}
+	}
+	return enrollNamespace(r.client, namespace, instance.Namespace)
+}
+
+// serviceMeshControlPlaneKey returns the namespace and the name of ServiceMeshControlPlane
+func serviceMeshControlPlaneKey() (string, string, error) {
+	parts := strings.SplitN(ServiceMeshControlPlane, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("ServiceMeshControlPlane %v must be of the form <namespace>/<name>", ServiceMeshControlPlane),
+		}
+	}
+	return parts[0], parts[1], nil
+}
+
+// createServiceMeshControlPlane creates a minimal ServiceMeshControlPlane, along with its namespace
+func (r *ReconcileKfDef) createServiceMeshControlPlane(namespace string, name string) error {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: kfutils.WithManagedLabel(nil)}}
+	if err := r.client.Create(context.TODO(), ns); err != nil && !errors.IsAlreadyExists(err) {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to create namespace %v of the ServiceMeshControlPlane: %v", namespace, err),
+		}
+	}
+	smcp := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"tracing":  map[string]interface{}{"type": "None"},
+			"security": map[string]interface{}{"dataPlane": map[string]interface{}{"mtls": true}},
+		},
+	}}
+	smcp.SetGroupVersionKind(ServiceMeshControlPlaneGVK)
+	smcp.SetNamespace(namespace)
+	smcp.SetName(name)
+	smcp.SetLabels(kfutils.WithManagedLabel(nil))
+	if err := r.client.Create(context.TODO(), smcp); err != nil && !errors.IsAlreadyExists(err) {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to create ServiceMeshControlPlane %v/%v: %v", namespace, name, err),
+		}
+	}
+	log.Infof("Created ServiceMeshControlPlane %v/%v.", namespace, name)
+	return nil
+}
+
+// isServiceMeshReady returns true if the Ready condition of the ServiceMeshControlPlane is True
+func isServiceMeshReady(smcp *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(smcp.Object, "status", "conditions")
+	for _, cond := range conditions {
+		if m, ok := cond.(map[string]interface{}); ok && m["type"] == "Ready" {
+			return m["status"] == "True"
+		}
+	}
+	return false
+}
+
+// EnrollNamespace adds member to the members of the ServiceMeshMemberRoll of the namespace of
+// ServiceMeshControlPlane, creating it if needed
+func EnrollNamespace(c client.Client, member string) error {
+	namespace, _, err := serviceMeshControlPlaneKey()
+	if err != nil {
+		return err
+	}
+	return enrollNamespace(c, namespace, member)
+}
+
+// UnenrollNamespace removes member from the members of the ServiceMeshMemberRoll of the namespace of
+// ServiceMeshControlPlane. It is a no-op if the Service Mesh operator is not installed or the namespace is not
+// enrolled.
+func UnenrollNamespace(c client.Client, member string) error {
+	namespace, _, err := serviceMeshControlPlaneKey()
+	if err != nil {
+		return err
+	}
+	smmr := &unstructured.Unstructured{}
+	smmr.SetGroupVersionKind(ServiceMeshMemberRollGVK)
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: serviceMeshMemberRollName}, smmr)
+	if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+		return nil
+	}
+	if err == nil {
+		members, _, _ := unstructured.NestedStringSlice(smmr.Object, "spec", "members")
+		kept := []string{}
+		for _, m := range members {
+			if m != member {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == len(members) {
+			return nil
+		}
+		if err := unstructured.SetNestedStringSlice(smmr.Object, kept, "spec", "members"); err != nil {
+			return err
+		}
+		err = c.Update(context.TODO(), smmr)
+	}
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to remove namespace %v from the ServiceMeshMemberRoll of %v: %v", member, namespace, err),
+		}
+	}
+	log.Infof("Removed namespace %v from the service mesh of %v.", member, namespace)
+	return nil
+}
+
+// enrollNamespace adds member to the members of the ServiceMeshMemberRoll of the control plane namespace,
+// creating it if needed
+func enrollNamespace(c client.Client, namespace string, member string) error {
+	smmr := &unstructured.Unstructured{}
+	smmr.SetGroupVersionKind(ServiceMeshMemberRollGVK)
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: serviceMeshMemberRollName}, smmr)
+	if errors.IsNotFound(err) {
+		smmr.SetNamespace(namespace)
+		smmr.SetName(serviceMeshMemberRollName)
+		if err := unstructured.SetNestedStringSlice(smmr.Object, []string{member}, "spec", "members"); err != nil {
+			return err
+		}
+		err = c.Create(context.TODO(), smmr)
+	} else if err == nil {
+		members, _, _ := unstructured.NestedStringSlice(smmr.Object, "spec", "members")
+		for _, m := range members {
+			if m == member {
+				return nil
+			}
+		}
+		if err := unstructured.SetNestedStringSlice(smmr.Object, append(members, member), "spec", "members"); err != nil {
+			return err
+		}
+		err = c.Update(context.TODO(), smmr)
+	}
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to enroll namespace %v into the ServiceMeshMemberRoll of %v: %v", member, namespace, err),
+		}
+	}
+	log.Infof("Enrolled namespace %v into the service mesh of %v.", member, namespace)
+	return nil
+}
@@ -3,19 +3,52 @@ package kfdef
 import (
 	"context"
 	"reflect"
+	"sort"
 
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
 	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/reconcilecontext"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 const DeploymentCompleted string = "Kubeflow Deployment completed"
 
+// ResourceConflict is the reason of the Conflict condition set when resources are managed by another KfDef or field manager
+const ResourceConflict string = "ResourceConflict"
+
+// SignatureVerificationFailed is the reason of the SignatureUnverified condition set when the signature of
+// the manifests of a repo couldn't be verified
+const SignatureVerificationFailed string = "SignatureVerificationFailed"
+
+// DestructiveCRDChange is the reason of the CRDUpgradeRefused condition set when the manifests upgrade CRDs in a
+// way that would lose the data of their custom resources
+const DestructiveCRDChange string = "DestructiveCRDChange"
+
+// DryRunCompleted is the reason of the DryRun condition set when the manifests were validated without being applied
+const DryRunCompleted string = "DryRunCompleted"
+
+// ReconcilePaused is the reason of the Paused condition set when the KfDef is annotated to stop its reconciliation
+const ReconcilePaused string = "ReconcilePaused"
+
+// FIPSModeDetected is the reason of the FIPSMode condition when the FIPS mode follows the install config of the
+// cluster, FIPSModeConfigured when it is set by the --fips-mode flag of the operator
+const (
+	FIPSModeDetected   string = "FIPSModeDetected"
+	FIPSModeConfigured string = "FIPSModeConfigured"
+)
+
 // The setKfDefStatus method accepts a custom resource of type KfDef type
 // It retrieves the current stored version of the resource and compares the
-// status subresource. If different, the status is updated
-func (r *ReconcileKfDef) setKfDefStatus(cr *kfdefv1.KfDef) error {
-	ctx := context.Background()
+// status subresource. If different, the status is updated. The status is written once the reconciliation of ctx
+// timed out too, for its failure to be recorded.
+func (r *ReconcileKfDef) setKfDefStatus(ctx context.Context, cr *kfdefv1.KfDef) error {
+	ctx, cancel := reconcilecontext.ForStatus(ctx)
+	defer cancel()
 	objKey := types.NamespacedName{
 		Namespace: cr.Namespace,
 		Name:      cr.Name,
@@ -33,8 +66,8 @@ func (r *ReconcileKfDef) setKfDefStatus(cr *kfdefv1.KfDef) error {
 	return nil
 }
 
-func (r *ReconcileKfDef) reconcileStatus(cr *kfdefv1.KfDef) error {
-	return r.setKfDefStatus(cr)
+func (r *ReconcileKfDef) reconcileStatus(ctx context.Context, cr *kfdefv1.KfDef) error {
+	return r.setKfDefStatus(ctx, cr)
 }
 
 func getReconcileStatus(cr *kfdefv1.KfDef, err error) error {
@@ -49,14 +82,208 @@ func getReconcileStatus(cr *kfdefv1.KfDef, err error) error {
 		})
 	}
 
+	if kfapis.IsConflict(err) {
+		conditions = append(conditions, kfdefv1.KfDefCondition{
+			LastUpdateTime: metav1.Now(),
+			Status:         corev1.ConditionTrue,
+			Reason:         ResourceConflict,
+			Message:        err.(*kfapis.KfError).Message,
+			Type:           kfdefv1.KfConflict,
+		})
+	}
+
+	if kfapis.IsUnverified(err) {
+		conditions = append(conditions, kfdefv1.KfDefCondition{
+			LastUpdateTime: metav1.Now(),
+			Status:         corev1.ConditionTrue,
+			Reason:         SignatureVerificationFailed,
+			Message:        err.(*kfapis.KfError).Message,
+			Type:           kfdefv1.KfSignatureUnverified,
+		})
+	}
+
+	if kfapis.IsUnsafeUpgrade(err) {
+		conditions = append(conditions, kfdefv1.KfDefCondition{
+			LastUpdateTime: metav1.Now(),
+			Status:         corev1.ConditionTrue,
+			Reason:         DestructiveCRDChange,
+			Message:        err.(*kfapis.KfError).Message,
+			Type:           kfdefv1.KfCRDUpgradeRefused,
+		})
+	}
+
 	conditions = append(conditions, kfdefv1.KfDefCondition{
 		LastUpdateTime: cr.CreationTimestamp,
 		Status:         corev1.ConditionTrue,
 		Reason:         DeploymentCompleted,
 		Type:           kfdefv1.KfAvailable,
-	})
+	}, fipsModeCondition(cr))
+
+	cr.Status.Conditions = conditions
+
+	return err
+}
+
+// getDryRunStatus sets the conditions of a KfDef whose manifests were validated instead of applied
+func getDryRunStatus(cr *kfdefv1.KfDef, err error) error {
+	conditions := []kfdefv1.KfDefCondition{}
+
+	if err != nil {
+		conditions = append(conditions, kfdefv1.KfDefCondition{
+			LastUpdateTime: cr.CreationTimestamp,
+			Status:         corev1.ConditionTrue,
+			Reason:         err.Error(),
+			Type:           kfdefv1.KfDegraded,
+		})
+	} else {
+		conditions = append(conditions, kfdefv1.KfDefCondition{
+			LastUpdateTime: cr.CreationTimestamp,
+			Status:         corev1.ConditionTrue,
+			Reason:         DryRunCompleted,
+			Message:        "the changes are in ConfigMap " + kustomize.DryRunConfigMapName(cr.Name),
+			Type:           kfdefv1.KfDryRun,
+		})
+	}
+	conditions = append(conditions, fipsModeCondition(cr))
 
 	cr.Status.Conditions = conditions
 
 	return err
 }
+
+// fipsModeCondition returns the FIPSMode condition of a KfDef, True when its applications are deployed in FIPS
+// mode. The time it last transitioned is kept from the conditions of cr.
+func fipsModeCondition(cr *kfdefv1.KfDef) kfdefv1.KfDefCondition {
+	cond := kfdefv1.KfDefCondition{
+		LastUpdateTime:     metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+		Status:             corev1.ConditionFalse,
+		Reason:             FIPSModeDetected,
+		Message:            "the applications are deployed without their FIPS-compliant variants",
+		Type:               kfdefv1.KfFIPSMode,
+	}
+	if kustomize.FIPSMode != kustomize.FIPSAuto {
+		cond.Reason = FIPSModeConfigured
+	}
+	if kustomize.IsFIPSEnabled() {
+		cond.Status = corev1.ConditionTrue
+		cond.Message = "the applications are deployed with their FIPS-compliant overlays and images, the ones " +
+			"incompatible with FIPS are refused"
+	}
+	for _, c := range cr.Status.Conditions {
+		if c.Type == kfdefv1.KfFIPSMode && c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+		}
+	}
+	return cond
+}
+
+// setPausedStatus adds the Paused condition to the conditions of a KfDef whose reconciliation is paused, keeping
+// the conditions of its last reconcile. It returns false if the condition was already set.
+func setPausedStatus(cr *kfdefv1.KfDef) bool {
+	for _, c := range cr.Status.Conditions {
+		if c.Type == kfdefv1.KfPaused {
+			return false
+		}
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, kfdefv1.KfDefCondition{
+		LastUpdateTime:     metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+		Status:             corev1.ConditionTrue,
+		Reason:             ReconcilePaused,
+		Message:            "the applications are neither applied nor restored until the annotation is removed",
+		Type:               kfdefv1.KfPaused,
+	})
+	return true
+}
+
+// setApplicationConditions records one condition per application of the KfDef. Conditions reported by
+// the kustomize plugin are copied as is; applications without a reported condition are Degraded when
+// err is set and Progressing otherwise.
+func setApplicationConditions(cr *kfdefv1.KfDef, conds []kfconfig.ApplicationCondition, err error) {
+	reported := map[string]kfconfig.ApplicationCondition{}
+	for _, cond := range conds {
+		reported[cond.Application] = cond
+	}
+
+	now := metav1.Now()
+	appConditions := []kfdefv1.ApplicationCondition{}
+	for _, app := range cr.Spec.Applications {
+		cond := kfdefv1.ApplicationCondition{
+			Application:        app.Name,
+			Type:               kfdefv1.KfProgressing,
+			Status:             corev1.ConditionTrue,
+			LastUpdateTime:     now,
+			LastTransitionTime: now,
+		}
+		if r, ok := reported[app.Name]; ok {
+			cond.Type = kfdefv1.KfDefConditionType(r.Type)
+			cond.Status = r.Status
+			cond.Reason = r.Reason
+			cond.Message = r.Message
+			cond.ManifestsDigest = r.ManifestsDigest
+		} else if err != nil {
+			cond.Type = kfdefv1.KfDegraded
+			cond.Reason = "LoadFailed"
+			if kfapis.IsUnverified(err) {
+				cond.Reason = SignatureVerificationFailed
+			}
+			cond.Message = err.Error()
+		}
+		for _, old := range cr.Status.ApplicationConditions {
+			if old.Application == cond.Application && old.Type == cond.Type {
+				cond.LastTransitionTime = old.LastTransitionTime
+			}
+			if old.Application == cond.Application && cond.ManifestsDigest == "" {
+				cond.ManifestsDigest = old.ManifestsDigest
+			}
+		}
+		appConditions = append(appConditions, cond)
+	}
+	cr.Status.ApplicationConditions = appConditions
+}
+
+// setHookFailures records the hook Jobs of the applications that failed all their attempts in the last apply
+func setHookFailures(cr *kfdefv1.KfDef, failures []kfconfig.HookFailure) {
+	cr.Status.HookFailures = nil
+	for _, failure := range failures {
+		cr.Status.HookFailures = append(cr.Status.HookFailures, kfdefv1.HookFailure{
+			Application:     failure.Application,
+			Job:             failure.Job,
+			Hook:            failure.Hook,
+			Attempts:        failure.Attempts,
+			LastFailureTime: failure.LastFailureTime,
+			Message:         failure.Message,
+			Logs:            failure.Logs,
+		})
+	}
+}
+
+// setSecurityContextConstraints records the SecurityContextConstraints granted to the ServiceAccounts of the
+// applications applied, sorted so that the status does not change with the order the applications were applied in
+func setSecurityContextConstraints(cr *kfdefv1.KfDef, grants []kfconfig.SCCGrant) {
+	cr.Status.SecurityContextConstraints = nil
+	for _, grant := range grants {
+		cr.Status.SecurityContextConstraints = append(cr.Status.SecurityContextConstraints, kfdefv1.SCCGrant{
+			Application:    grant.Application,
+			ServiceAccount: grant.ServiceAccount,
+			Profile:        grant.Profile,
+			Name:           grant.Name,
+		})
+	}
+	sort.Slice(cr.Status.SecurityContextConstraints, func(i, j int) bool {
+		a, b := cr.Status.SecurityContextConstraints[i], cr.Status.SecurityContextConstraints[j]
+		if a.Application != b.Application {
+			return a.Application < b.Application
+		}
+		return a.ServiceAccount < b.ServiceAccount
+	})
+}
+
+// reportComponentReadiness exports the readiness of every application of the KfDef
+func reportComponentReadiness(cr *kfdefv1.KfDef) {
+	for _, cond := range cr.Status.ApplicationConditions {
+		ready := cond.Type == kfdefv1.KfAvailable && cond.Status == corev1.ConditionTrue
+		metrics.SetComponentReady(cr.Namespace, cr.Name, cond.Application, ready)
+	}
+}
@@ -0,0 +1,204 @@
+package kfdef
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PreflightChecksPassed is the reason of the Upgradeable condition set when the applications can be upgraded
+	PreflightChecksPassed string = "PreflightChecksPassed"
+	// PreflightChecksFailed is the reason of the Upgradeable condition set when an upgrade pre-flight check failed
+	PreflightChecksFailed string = "PreflightChecksFailed"
+
+	// preflightRetryPeriod is how often the pre-flight checks of a blocked upgrade are run again
+	preflightRetryPeriod = 5 * time.Minute
+)
+
+// OperatorVersion is the version of the operator, recorded in the status of the KfDef instances once applied.
+// The upgrade pre-flight checks are run when it differs from the recorded one.
+var OperatorVersion = ""
+
+// BlockUpgrades makes the operator leave the applications of a KfDef as they are after an operator upgrade
+// until the pre-flight checks pass.
+var BlockUpgrades = false
+
+// DeprecatedComponents maps the applications deprecated in this version of the operator to the
+// reason they are deprecated. KfDef instances still deploying them fail the upgrade pre-flight checks.
+// They are loaded from the file of the upgrade checks, see LoadUpgradeChecks.
+var DeprecatedComponents = map[string]string{}
+
+// RequiredCRDs maps applications to the CRDs that have to be installed on the cluster, e.g. by another
+// operator, before this version of the operator upgrades them. They are loaded with DeprecatedComponents.
+var RequiredCRDs = map[string][]string{}
+
+// upgradeChecks is the content of the file of the upgrade pre-flight checks
+type upgradeChecks struct {
+	DeprecatedComponents map[string]string   `json:"deprecatedComponents,omitempty"`
+	RequiredCRDs         map[string][]string `json:"requiredCRDs,omitempty"`
+}
+
+// LoadUpgradeChecks adds the deprecated applications and the required CRDs of the YAML data to
+// DeprecatedComponents and RequiredCRDs, replacing the CRDs required by the applications it lists.
+func LoadUpgradeChecks(data []byte) error {
+	checks := &upgradeChecks{}
+	if err := yaml.UnmarshalStrict(data, checks, yaml.DisallowUnknownFields); err != nil {
+		return err
+	}
+	for app, reason := range checks.DeprecatedComponents {
+		if reason == "" {
+			return fmt.Errorf("the reason application %v is deprecated is empty", app)
+		}
+	}
+	for app, reason := range checks.DeprecatedComponents {
+		DeprecatedComponents[app] = reason
+	}
+	for app, crds := range checks.RequiredCRDs {
+		RequiredCRDs[app] = crds
+	}
+	return nil
+}
+
+// isUpgrading returns true if the KfDef was last applied by another version of the operator
+func isUpgrading(instance *kfdefv1.KfDef) bool {
+	return OperatorVersion != "" && instance.Status.OperatorVersion != "" &&
+		instance.Status.OperatorVersion != OperatorVersion
+}
+
+// checkUpgrade runs the pre-flight checks of an operator upgrade when the KfDef was last applied by another
+// version of the operator, and returns an error describing every check that failed.
+func (r *ReconcileKfDef) checkUpgrade(instance *kfdefv1.KfDef) error {
+	if !isUpgrading(instance) {
+		return nil
+	}
+	log.Infof("Running the pre-flight checks of the upgrade of KfDef %v from %v to %v.",
+		instance.Name, instance.Status.OperatorVersion, OperatorVersion)
+
+	crdClient, err := crdclientset.NewForConfig(r.restConfig)
+	if err != nil {
+		return err
+	}
+	return preflightChecks(crdClient.CustomResourceDefinitions(), instance)
+}
+
+// preflightChecks checks the KfDef CRD, the deprecated applications and the CRDs required by the applications of
+// the KfDef, and returns an error describing every check that failed.
+func preflightChecks(crds crdclientset.CustomResourceDefinitionInterface, instance *kfdefv1.KfDef) error {
+	failures := []string{}
+	crd, err := crds.Get(kfdefCRD, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get CRD %v: %v", kfdefCRD, err)
+	}
+	if err := checkCRDCompatibility(crd, kfdefv1.SchemeGroupVersion.Version); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if deprecated := deprecatedComponents(instance); len(deprecated) > 0 {
+		failures = append(failures, "deprecated applications in use: "+strings.Join(deprecated, ", "))
+	}
+	missing := []string{}
+	for _, name := range requiredCRDs(instance) {
+		if _, err := crds.Get(name, metav1.GetOptions{}); err != nil {
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to get CRD %v: %v", name, err)
+			}
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		failures = append(failures, "required CRDs not installed: "+strings.Join(missing, ", "))
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("upgrade from %v to %v: %v", instance.Status.OperatorVersion, OperatorVersion,
+			strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkCRDCompatibility checks that the KfDef CRD serves and stores the given version, and still serves the
+// versions instances are stored with.
+func checkCRDCompatibility(crd *apiextensionsv1beta1.CustomResourceDefinition, version string) error {
+	served := map[string]bool{}
+	storage := ""
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			served[v.Name] = true
+		}
+		if v.Storage {
+			storage = v.Name
+		}
+	}
+	if len(crd.Spec.Versions) == 0 {
+		served[crd.Spec.Version] = true
+		storage = crd.Spec.Version
+	}
+	if !served[version] || storage != version {
+		return fmt.Errorf("CRD %v does not serve and store %v", crd.Name, version)
+	}
+	for _, stored := range crd.Status.StoredVersions {
+		if !served[stored] {
+			return fmt.Errorf("CRD %v does not serve %v stored instances", crd.Name, stored)
+		}
+	}
+	return nil
+}
+
+// deprecatedComponents returns the deprecated applications of the KfDef
+func deprecatedComponents(instance *kfdefv1.KfDef) []string {
+	deprecated := []string{}
+	for _, app := range instance.Spec.Applications {
+		if reason, ok := DeprecatedComponents[app.Name]; ok {
+			deprecated = append(deprecated, fmt.Sprintf("%v (%v)", app.Name, reason))
+		}
+	}
+	return deprecated
+}
+
+// requiredCRDs returns the CRDs required by the applications of the KfDef
+func requiredCRDs(instance *kfdefv1.KfDef) []string {
+	crds := map[string]bool{}
+	for _, app := range instance.Spec.Applications {
+		for _, crd := range RequiredCRDs[app.Name] {
+			crds[crd] = true
+		}
+	}
+	names := []string{}
+	for crd := range crds {
+		names = append(names, crd)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setUpgradeableCondition records the result of the upgrade pre-flight checks in the Upgradeable condition of the KfDef
+func setUpgradeableCondition(cr *kfdefv1.KfDef, err error) {
+	cond := kfdefv1.KfDefCondition{
+		LastUpdateTime: cr.CreationTimestamp,
+		Status:         corev1.ConditionTrue,
+		Reason:         PreflightChecksPassed,
+		Type:           kfdefv1.KfUpgradeable,
+	}
+	if err != nil {
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = PreflightChecksFailed
+		cond.Message = err.Error()
+	}
+	conditions := []kfdefv1.KfDefCondition{}
+	for _, c := range cr.Status.Conditions {
+		if c.Type != kfdefv1.KfUpgradeable {
+			conditions = append(conditions, c)
+		}
+	}
+	cr.Status.Conditions = append(conditions, cond)
+}
@@ -0,0 +1,184 @@
+package kfdef
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	crdfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// setUpgradeChecks replaces the upgrade checks and the operator version, and returns a function restoring them
+func setUpgradeChecks(version string, deprecated map[string]string, required map[string][]string) func() {
+	previousVersion, previousDeprecated, previousRequired := OperatorVersion, DeprecatedComponents, RequiredCRDs
+	OperatorVersion, DeprecatedComponents, RequiredCRDs = version, deprecated, required
+	return func() {
+		OperatorVersion, DeprecatedComponents, RequiredCRDs = previousVersion, previousDeprecated, previousRequired
+	}
+}
+
+func kfdefCRDWith(versions []string, storage string, stored []string) *apiextensionsv1beta1.CustomResourceDefinition {
+	crd := &apiextensionsv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: kfdefCRD}}
+	for _, v := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1beta1.CustomResourceDefinitionVersion{
+			Name: v, Served: true, Storage: v == storage,
+		})
+	}
+	crd.Status.StoredVersions = stored
+	return crd
+}
+
+func TestCheckCRDCompatibility(t *testing.T) {
+	type testCase struct {
+		Name  string
+		CRD   *apiextensionsv1beta1.CustomResourceDefinition
+		Error string
+	}
+	legacy := &apiextensionsv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: kfdefCRD}}
+	legacy.Spec.Version = "v1"
+	testCases := []testCase{
+		{Name: "v1 stored", CRD: kfdefCRDWith([]string{"v1", "v1beta1"}, "v1", []string{"v1beta1", "v1"})},
+		{Name: "single version", CRD: legacy},
+		{Name: "v1beta1 stored", CRD: kfdefCRDWith([]string{"v1", "v1beta1"}, "v1beta1", nil), Error: "does not serve and store v1"},
+		{Name: "v1 not served", CRD: kfdefCRDWith([]string{"v1beta1"}, "v1beta1", nil), Error: "does not serve and store v1"},
+		{Name: "stored version not served", CRD: kfdefCRDWith([]string{"v1"}, "v1", []string{"v1beta1"}), Error: "does not serve v1beta1 stored instances"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := checkCRDCompatibility(tc.CRD, "v1")
+			if tc.Error == "" && err != nil {
+				t.Errorf("Expected the CRD to be compatible, got %v", err)
+			}
+			if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+				t.Errorf("Expected error %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
+
+func TestPreflightChecks(t *testing.T) {
+	defer setUpgradeChecks("v1.2.0",
+		map[string]string{"odh-argo": "replaced by data-science-pipelines-operator"},
+		map[string][]string{
+			"kserve":     {"knativeservings.operator.knative.dev", "servicemeshcontrolplanes.maistra.io"},
+			"model-mesh": {"servicemeshcontrolplanes.maistra.io"},
+		})()
+	crd := kfdefCRDWith([]string{"v1", "v1beta1"}, "v1", []string{"v1"})
+	knative := &apiextensionsv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "knativeservings.operator.knative.dev"}}
+
+	type testCase struct {
+		Name         string
+		Applications []string
+		CRDs         []runtime.Object
+		Errors       []string
+	}
+	testCases := []testCase{
+		{Name: "passed", Applications: []string{"odh-dashboard", "kserve"}, CRDs: []runtime.Object{crd, knative,
+			&apiextensionsv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "servicemeshcontrolplanes.maistra.io"}}}},
+		{Name: "deprecated application", Applications: []string{"odh-dashboard", "odh-argo"}, CRDs: []runtime.Object{crd},
+			Errors: []string{"deprecated applications in use: odh-argo (replaced by data-science-pipelines-operator)"}},
+		{Name: "missing CRDs", Applications: []string{"kserve", "model-mesh"}, CRDs: []runtime.Object{crd, knative},
+			Errors: []string{"required CRDs not installed: servicemeshcontrolplanes.maistra.io"}},
+		{Name: "every failure", Applications: []string{"odh-argo", "kserve"}, CRDs: []runtime.Object{
+			kfdefCRDWith([]string{"v1", "v1beta1"}, "v1beta1", nil)},
+			Errors: []string{"does not serve and store v1", "deprecated applications in use: odh-argo",
+				"required CRDs not installed: knativeservings.operator.knative.dev, servicemeshcontrolplanes.maistra.io"}},
+		{Name: "missing KfDef CRD", Applications: []string{"odh-dashboard"}, Errors: []string{"failed to get CRD " + kfdefCRD}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			instance := &kfdefv1.KfDef{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh"}}
+			instance.Status.OperatorVersion = "v1.1.0"
+			for _, app := range tc.Applications {
+				instance.Spec.Applications = append(instance.Spec.Applications, kfdefv1.Application{Name: app})
+			}
+			crdClient := crdfake.NewSimpleClientset(tc.CRDs...)
+			err := preflightChecks(crdClient.ApiextensionsV1beta1().CustomResourceDefinitions(), instance)
+			if len(tc.Errors) == 0 {
+				if err != nil {
+					t.Errorf("Expected the pre-flight checks to pass, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected the pre-flight checks to fail with %v", tc.Errors)
+			}
+			for _, e := range tc.Errors {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("Expected error %q, got %v", e, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIsUpgrading(t *testing.T) {
+	defer setUpgradeChecks("v1.2.0", map[string]string{}, map[string][]string{})()
+	for version, upgrading := range map[string]bool{"": false, "v1.2.0": false, "v1.1.0": true} {
+		instance := &kfdefv1.KfDef{}
+		instance.Status.OperatorVersion = version
+		if isUpgrading(instance) != upgrading {
+			t.Errorf("Expected a KfDef applied by version %q to be upgrading %v", version, upgrading)
+		}
+	}
+}
+
+func TestLoadUpgradeChecks(t *testing.T) {
+	defer setUpgradeChecks("v1.2.0", map[string]string{"odh-seldon": "removed"},
+		map[string][]string{"kserve": {"knativeservings.operator.knative.dev"}})()
+	data := `deprecatedComponents:
+  odh-argo: replaced by data-science-pipelines-operator
+requiredCRDs:
+  kserve:
+  - knativeservings.operator.knative.dev
+  - servicemeshcontrolplanes.maistra.io
+`
+	if err := LoadUpgradeChecks([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	expectedDeprecated := map[string]string{"odh-seldon": "removed", "odh-argo": "replaced by data-science-pipelines-operator"}
+	if !reflect.DeepEqual(DeprecatedComponents, expectedDeprecated) {
+		t.Errorf("Expected the deprecated applications %v, got %v", expectedDeprecated, DeprecatedComponents)
+	}
+	expectedRequired := map[string][]string{"kserve": {"knativeservings.operator.knative.dev", "servicemeshcontrolplanes.maistra.io"}}
+	if !reflect.DeepEqual(RequiredCRDs, expectedRequired) {
+		t.Errorf("Expected the required CRDs %v, got %v", expectedRequired, RequiredCRDs)
+	}
+
+	for _, invalid := range []string{"deprecated: [odh-argo]", "deprecatedComponents:\n  odh-argo: \"\""} {
+		if err := LoadUpgradeChecks([]byte(invalid)); err == nil {
+			t.Errorf("Expected %q to be refused", invalid)
+		}
+	}
+}
+
+func TestSetUpgradeableCondition(t *testing.T) {
+	instance := &kfdefv1.KfDef{}
+	instance.Status.Conditions = []kfdefv1.KfDefCondition{{Type: kfdefv1.KfAvailable, Status: corev1.ConditionTrue}}
+
+	setUpgradeableCondition(instance, errors.New("required CRDs not installed"))
+	setUpgradeableCondition(instance, errors.New("deprecated applications in use"))
+	if len(instance.Status.Conditions) != 2 {
+		t.Fatalf("Expected a single Upgradeable condition besides the others, got %v", instance.Status.Conditions)
+	}
+	cond := instance.Status.Conditions[1]
+	if cond.Type != kfdefv1.KfUpgradeable || cond.Status != corev1.ConditionFalse || cond.Reason != PreflightChecksFailed ||
+		cond.Message != "deprecated applications in use" {
+		t.Errorf("Expected the failed pre-flight checks to be recorded, got %v", cond)
+	}
+
+	setUpgradeableCondition(instance, nil)
+	cond = instance.Status.Conditions[1]
+	if cond.Status != corev1.ConditionTrue || cond.Reason != PreflightChecksPassed || cond.Message != "" {
+		t.Errorf("Expected the passed pre-flight checks to be recorded, got %v", cond)
+	}
+	if instance.Status.Conditions[0].Type != kfdefv1.KfAvailable {
+		t.Errorf("Expected the other conditions to be kept, got %v", instance.Status.Conditions)
+	}
+}
@@ -0,0 +1,405 @@
+package modelregistry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mrv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/modelregistry/v1alpha1"
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/database"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/externalsecret"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// externalSecretPollInterval is the interval the connection Secret of the external secret store is checked at
+	// until it is created
+	externalSecretPollInterval = 10 * time.Second
+	// databasePollInterval is the interval the existing database is checked at until it is reachable
+	databasePollInterval = 30 * time.Second
+)
+
+// AddToManager adds the ModelRegistry controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("modelregistry-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileModelRegistry{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("modelregistry-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for modelregistry.")
+	c, err := controller.New("modelregistry-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource ModelRegistry
+	err = c.Watch(&source.Kind{Type: &mrv1alpha1.ModelRegistry{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the provisioned resources and requeue the owner ModelRegistry, so that the resources
+	// edited or deleted by hand are restored and the phase follows the migration Jobs and the Deployments
+	for _, t := range []runtime.Object{&appsv1.Deployment{}, &corev1.Service{}, &corev1.Secret{},
+		&corev1.PersistentVolumeClaim{}, &batchv1.Job{}} {
+		err := c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &mrv1alpha1.ModelRegistry{},
+		}, kfutils.ManagedPredicate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to the PriorityClass of the control plane and requeue all the ModelRegistries
+	kubeclient := mgr.GetClient()
+	events := operatorconfig.Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.PriorityClasses.ControlPlane.Name != next.PriorityClasses.ControlPlane.Name
+	})
+	err = c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			registries := &mrv1alpha1.ModelRegistryList{}
+			if err := kubeclient.List(context.TODO(), registries); err != nil {
+				log.Warnf("Failed to list the ModelRegistries for OperatorConfig %v: %v", a.Meta.GetName(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, registry := range registries.Items {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: registry.Name, Namespace: registry.Namespace},
+				})
+			}
+			return requests
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the connection Secrets of the existing databases, which are not owned by the registries
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			registries := &mrv1alpha1.ModelRegistryList{}
+			if err := kubeclient.List(context.TODO(), registries, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+				log.Warnf("Failed to list the ModelRegistries of namespace %v: %v", a.Meta.GetNamespace(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, registry := range registries.Items {
+				if registry.Spec.Database.ConnectionSecret == a.Meta.GetName() {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: registry.Name, Namespace: registry.Namespace},
+					})
+				}
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileModelRegistry implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileModelRegistry{}
+
+// ReconcileModelRegistry reconciles a ModelRegistry object by provisioning the registry of its namespace
+type ReconcileModelRegistry struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile provisions the database of a ModelRegistry, or checks the connection Secret of its existing database,
+// created first when it is read from an external secret store, and that the database is reachable,
+// migrates the schema of the database with a Job whenever the image of the registry changes, and deploys the
+// registry server once the schema is migrated. The resources are owned by the ModelRegistry and garbage collected
+// with it.
+func (r *ReconcileModelRegistry) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling ModelRegistry. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &mrv1alpha1.ModelRegistry{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// Invalid specs and connection Secrets are not retried, they are reconciled again once they are fixed
+	if err := validate(&instance.Spec); err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "InvalidSpec", "%v", err)
+		return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryFailed, err.Error())
+	}
+	if deploysDatabase(instance) {
+		err = database.Provision(r.client, r.scheme, instance, managedDatabase(instance))
+	} else {
+		err = database.Remove(r.client, managedDatabase(instance))
+	}
+	waiting, message := false, ""
+	if err == nil {
+		waiting, message, err = r.provisionExternalSecret(instance)
+	}
+	if err != nil {
+		log.Errorf("Failed to provision the database of ModelRegistry %v: %v", request.NamespacedName, err)
+		if statusErr := r.updateStatus(instance, mrv1alpha1.ModelRegistryFailed, err.Error()); statusErr != nil {
+			log.Warnf("Failed to update the status of ModelRegistry %v: %v", request.NamespacedName, statusErr)
+		}
+		return reconcile.Result{}, err
+	}
+
+	if deploysDatabase(instance) {
+		available, err := database.Available(r.client, managedDatabase(instance))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !available {
+			return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing,
+				fmt.Sprintf("waiting for the database %v to be available", databaseName(instance)))
+		}
+	} else if waiting {
+		if message != "" {
+			message = ": " + message
+		}
+		message = fmt.Sprintf("waiting for the connection Secret %v of the external secret store%v",
+			instance.Spec.Database.ConnectionSecret, message)
+		return reconcile.Result{RequeueAfter: externalSecretPollInterval},
+			r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing, message)
+	} else {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: instance.Namespace, Name: instance.Spec.Database.ConnectionSecret}
+		if err := r.client.Get(context.TODO(), key, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				return reconcile.Result{}, err
+			}
+			message := fmt.Sprintf("connection Secret %v not found", key.Name)
+			return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryFailed, message)
+		}
+		if err := validateConnectionSecret(secret); err != nil {
+			return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryFailed, err.Error())
+		}
+		// The existing database is checked until it is reachable, its failures are not errors of the ModelRegistry
+		if err := database.CheckConnection(connectionAddress(instance, secret)); err != nil {
+			return reconcile.Result{RequeueAfter: databasePollInterval},
+				r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing, err.Error())
+		}
+	}
+
+	migrated, err := r.migrateSchema(instance)
+	if err != nil || !migrated {
+		return reconcile.Result{}, err
+	}
+
+	if err := r.createOrUpdateDeployment(instance, registryDeployment(instance)); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.createOrUpdateService(instance, registryService(instance)); err != nil {
+		return reconcile.Result{}, err
+	}
+	available, err := r.deploymentAvailable(instance, registryName(instance))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !available {
+		return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing,
+			fmt.Sprintf("waiting for the registry server %v to be available", registryName(instance)))
+	}
+	return reconcile.Result{}, r.updateStatus(instance, mrv1alpha1.ModelRegistryReady, "the registry server is available")
+}
+
+// provisionExternalSecret creates or updates the ExternalSecret of the connection Secret of instance read from an
+// external secret store, or deletes it once it is no longer set. It returns true, along with the message of the
+// ExternalSecret, while the Secret does not exist yet.
+func (r *ReconcileModelRegistry) provisionExternalSecret(instance *mrv1alpha1.ModelRegistry) (bool, string, error) {
+	keep := map[string]bool{}
+	waiting, message := false, ""
+	if source, ok := externalSecretSource(&instance.Spec); ok {
+		name := instance.Spec.Database.ConnectionSecret
+		keep[name] = true
+		desired := externalsecret.ExternalSecret(instance.Namespace, name, labels(instance, "external-secret"), source)
+		if err := externalsecret.Apply(r.client, r.scheme, instance, desired); err != nil {
+			return false, "", err
+		}
+		materialized, msg, err := externalsecret.Materialized(r.client, instance.Namespace, name)
+		if err != nil {
+			return false, "", err
+		}
+		waiting, message = !materialized, msg
+	}
+	return waiting, message, externalsecret.Prune(r.client, instance.Namespace, labels(instance, "external-secret"), keep)
+}
+
+// migrateSchema runs the Job migrating the schema of the database for the image of the registry, and returns true
+// once it completed. The Jobs of the previous migrations are deleted then.
+func (r *ReconcileModelRegistry) migrateSchema(instance *mrv1alpha1.ModelRegistry) (bool, error) {
+	job := &batchv1.Job{}
+	name := migrationJobName(instance)
+	err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: instance.Namespace, Name: name}, job)
+	if errors.IsNotFound(err) {
+		job = migrationJob(instance)
+		if err := controllerutil.SetControllerReference(instance, job, r.scheme); err != nil {
+			return false, err
+		}
+		log.Infof("Migrating the schema of the database of ModelRegistry %v/%v with Job %v.", instance.Namespace,
+			instance.Name, name)
+		if err := r.client.Create(context.TODO(), job); err != nil && !errors.IsAlreadyExists(err) {
+			return false, fmt.Errorf("failed to create the migration Job %v: %v", name, err)
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "MigrationStarted", "Migrating the schema with Job %v", name)
+		return false, r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing,
+			fmt.Sprintf("migrating the schema of the database with Job %v", name))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	finished, failed, message := kfutils.JobFinished(job)
+	switch {
+	case failed:
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "MigrationFailed", "Job %v failed: %v", name, message)
+		return false, r.updateStatus(instance, mrv1alpha1.ModelRegistryFailed,
+			fmt.Sprintf("migration Job %v failed: %v, delete it to retry", name, message))
+	case !finished:
+		return false, r.updateStatus(instance, mrv1alpha1.ModelRegistryProgressing,
+			fmt.Sprintf("migrating the schema of the database with Job %v", name))
+	}
+
+	// The Job of the current migration is kept, so that it does not run again
+	jobs := &batchv1.JobList{}
+	err = r.client.List(context.TODO(), jobs, client.InNamespace(instance.Namespace),
+		client.MatchingLabels(labels(instance, "migration")))
+	if err != nil {
+		return false, err
+	}
+	propagation := metav1.DeletePropagationBackground
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == name {
+			continue
+		}
+		err := r.client.Delete(context.TODO(), &jobs.Items[i], client.PropagationPolicy(propagation))
+		if err != nil && !errors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete the migration Job %v: %v", jobs.Items[i].Name, err)
+		}
+	}
+	if instance.Status.MigratedImage != registryImage(instance) {
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "MigrationSucceeded", "Migrated the schema for %v",
+			registryImage(instance))
+		instance.Status.MigratedImage = registryImage(instance)
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// createOrUpdateDeployment creates the desired Deployment, or updates the pod template of the live one
+func (r *ReconcileModelRegistry) createOrUpdateDeployment(instance *mrv1alpha1.ModelRegistry, desired *appsv1.Deployment) error {
+	deployment := &appsv1.Deployment{ObjectMeta: metaKey(desired.ObjectMeta)}
+	return r.createOrUpdate(instance, deployment, func() error {
+		deployment.Labels = desired.Labels
+		// The selector of a Deployment is immutable
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = desired.Spec.Selector
+		}
+		deployment.Spec.Replicas = desired.Spec.Replicas
+		deployment.Spec.Strategy = desired.Spec.Strategy
+		deployment.Spec.Template = desired.Spec.Template
+		return nil
+	})
+}
+
+// createOrUpdateService creates the desired Service, or updates the selector and the ports of the live one
+func (r *ReconcileModelRegistry) createOrUpdateService(instance *mrv1alpha1.ModelRegistry, desired *corev1.Service) error {
+	service := &corev1.Service{ObjectMeta: metaKey(desired.ObjectMeta)}
+	return r.createOrUpdate(instance, service, func() error {
+		service.Labels = desired.Labels
+		service.Spec.Selector = desired.Spec.Selector
+		service.Spec.Ports = desired.Spec.Ports
+		return nil
+	})
+}
+
+// createOrUpdate creates obj, or updates the live object, after mutating it with mutate and making instance its
+// controller
+func (r *ReconcileModelRegistry) createOrUpdate(instance *mrv1alpha1.ModelRegistry, obj runtime.Object, mutate func() error) error {
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, obj, func() error {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(instance, obj.(metav1.Object), r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision %T %v: %v", obj, obj.(metav1.Object).GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("%T %v/%v of ModelRegistry %v %v.", obj, instance.Namespace, obj.(metav1.Object).GetName(),
+			instance.Name, result)
+	}
+	return nil
+}
+
+// deploymentAvailable returns true if the Deployment name of instance has an available replica
+func (r *ReconcileModelRegistry) deploymentAvailable(instance *mrv1alpha1.ModelRegistry, name string) (bool, error) {
+	deployment := &appsv1.Deployment{}
+	err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: instance.Namespace, Name: name}, deployment)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return deployment.Status.AvailableReplicas > 0, nil
+}
+
+// updateStatus updates the status of instance if it changed
+func (r *ReconcileModelRegistry) updateStatus(instance *mrv1alpha1.ModelRegistry, phase mrv1alpha1.ModelRegistryPhase, message string) error {
+	status := mrv1alpha1.ModelRegistryStatus{
+		ObservedGeneration: instance.Generation,
+		Phase:              phase,
+		Message:            message,
+		MigratedImage:      instance.Status.MigratedImage,
+	}
+	if phase == mrv1alpha1.ModelRegistryReady {
+		status.URL = registryURL(instance)
+	}
+	if instance.Status == status {
+		return nil
+	}
+	instance.Status = status
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// metaKey returns the name and the namespace of meta
+func metaKey(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace}
+}
@@ -0,0 +1,275 @@
+package modelregistry
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	mrv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/modelregistry/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/database"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/externalsecret"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	registryHTTPPort = 8080
+	registryGRPCPort = 9090
+
+	defaultDBUser      = "modelregistry"
+	defaultDBName      = "modelregistry"
+	defaultStorageSize = "10Gi"
+	migrationBackoff   = 3
+
+	modelRegistryNameLabel = "modelregistry.opendatahub.io/modelregistry"
+	componentLabel         = "component"
+)
+
+var (
+	// RegistryImage is the image of the registry servers that do not set theirs
+	RegistryImage = "quay.io/opendatahub/model-registry:v0.1.0"
+	// MySQLImage is the image of the MySQL databases that do not set theirs
+	MySQLImage = "registry.redhat.io/rhel8/mysql-80:1"
+	// PostgreSQLImage is the image of the PostgreSQL databases that do not set theirs
+	PostgreSQLImage = "registry.redhat.io/rhel9/postgresql-15:1"
+
+	// requiredConnectionKeys are the keys a connection Secret must hold, the port is optional
+	requiredConnectionKeys = []string{database.HostKey, database.UsernameKey, database.PasswordKey, database.DatabaseKey}
+)
+
+// validate returns an error describing the first invalid field of the spec
+func validate(spec *mrv1alpha1.ModelRegistrySpec) error {
+	switch spec.Database.Type {
+	case "", mrv1alpha1.MySQL, mrv1alpha1.PostgreSQL:
+	default:
+		return fmt.Errorf("database.type must be %v or %v, got %q", mrv1alpha1.MySQL, mrv1alpha1.PostgreSQL, spec.Database.Type)
+	}
+	if spec.Database.ConnectionSecret != "" && (spec.Database.Image != "" || spec.Database.StorageSize != nil) {
+		return fmt.Errorf("database.image and database.storageSize cannot be set with database.connectionSecret")
+	}
+	if source, ok := externalSecretSource(spec); ok {
+		if spec.Database.ConnectionSecret == "" {
+			return fmt.Errorf("database.externalSecret requires database.connectionSecret")
+		}
+		return externalsecret.Validate("database.externalSecret", source)
+	}
+	return nil
+}
+
+// externalSecretSource returns the external secret the connection Secret is created from, if any
+func externalSecretSource(spec *mrv1alpha1.ModelRegistrySpec) (externalsecret.Source, bool) {
+	ext := spec.Database.ExternalSecret
+	if ext == nil {
+		return externalsecret.Source{}, false
+	}
+	return externalsecret.Source{
+		StoreName:       ext.StoreName,
+		StoreKind:       ext.StoreKind,
+		Key:             ext.Key,
+		RefreshInterval: ext.RefreshInterval,
+	}, true
+}
+
+// validateConnectionSecret returns an error if the connection Secret of an existing database misses a key
+func validateConnectionSecret(secret *corev1.Secret) error {
+	for _, key := range requiredConnectionKeys {
+		if len(secret.Data[key]) == 0 {
+			return fmt.Errorf("connection Secret %v has no %v", secret.Name, key)
+		}
+	}
+	return nil
+}
+
+// connectionAddress returns the host and the port of the existing database of instance read from its connection
+// Secret, the port defaulting to the one of the type of the database
+func connectionAddress(instance *mrv1alpha1.ModelRegistry, secret *corev1.Secret) (string, string) {
+	port := string(secret.Data[database.PortKey])
+	if port == "" {
+		port = fmt.Sprint(managedDatabase(instance).Port())
+	}
+	return string(secret.Data[database.HostKey]), port
+}
+
+// registryName and databaseName are the names of the resources provisioned for a ModelRegistry
+func registryName(instance *mrv1alpha1.ModelRegistry) string {
+	return "model-registry-" + instance.Name
+}
+
+func databaseName(instance *mrv1alpha1.ModelRegistry) string {
+	return "model-registry-db-" + instance.Name
+}
+
+// connectionSecretName returns the name of the Secret the registry reads the connection of its database from
+func connectionSecretName(instance *mrv1alpha1.ModelRegistry) string {
+	if instance.Spec.Database.ConnectionSecret != "" {
+		return instance.Spec.Database.ConnectionSecret
+	}
+	return databaseName(instance)
+}
+
+// registryURL returns the URL of the REST API of the registry inside the cluster
+func registryURL(instance *mrv1alpha1.ModelRegistry) string {
+	return fmt.Sprintf("http://%v.%v.svc:%v", registryName(instance), instance.Namespace, registryHTTPPort)
+}
+
+// databaseType returns the type of the database of instance
+func databaseType(instance *mrv1alpha1.ModelRegistry) mrv1alpha1.DatabaseType {
+	if instance.Spec.Database.Type == "" {
+		return mrv1alpha1.MySQL
+	}
+	return instance.Spec.Database.Type
+}
+
+// registryImage returns the image of the registry server of instance
+func registryImage(instance *mrv1alpha1.ModelRegistry) string {
+	if instance.Spec.Image != "" {
+		return instance.Spec.Image
+	}
+	return RegistryImage
+}
+
+// labels returns the labels of the resources of component
+func labels(instance *mrv1alpha1.ModelRegistry, component string) map[string]string {
+	return map[string]string{modelRegistryNameLabel: instance.Name, componentLabel: component}
+}
+
+// objectMeta returns the metadata of a resource of component named name
+func objectMeta(instance *mrv1alpha1.ModelRegistry, name string, component string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: instance.Namespace, Labels: kfutils.WithManagedLabel(labels(instance, component))}
+}
+
+// connectionEnv returns the environment variables the registry server and the migrations connect to the database
+// with
+func connectionEnv(instance *mrv1alpha1.ModelRegistry) []corev1.EnvVar {
+	secret := connectionSecretName(instance)
+	dbType := "mysql"
+	if databaseType(instance) == mrv1alpha1.PostgreSQL {
+		dbType = "postgres"
+	}
+	optional := true
+	port := kfutils.SecretEnv("DB_PORT", secret, database.PortKey)
+	port.ValueFrom.SecretKeyRef.Optional = &optional
+	return []corev1.EnvVar{
+		{Name: "DB_TYPE", Value: dbType},
+		kfutils.SecretEnv("DB_HOST", secret, database.HostKey),
+		port,
+		kfutils.SecretEnv("DB_USER", secret, database.UsernameKey),
+		kfutils.SecretEnv("DB_PASSWORD", secret, database.PasswordKey),
+		kfutils.SecretEnv("DB_NAME", secret, database.DatabaseKey),
+	}
+}
+
+// managedDatabase returns the MySQL or PostgreSQL database deployed with the registry
+func managedDatabase(instance *mrv1alpha1.ModelRegistry) *database.Database {
+	engine, image := database.MySQL, MySQLImage
+	if databaseType(instance) == mrv1alpha1.PostgreSQL {
+		engine, image = database.PostgreSQL, PostgreSQLImage
+	}
+	if instance.Spec.Database.Image != "" {
+		image = instance.Spec.Database.Image
+	}
+	size := resource.MustParse(defaultStorageSize)
+	if instance.Spec.Database.StorageSize != nil {
+		size = *instance.Spec.Database.StorageSize
+	}
+	return &database.Database{
+		Name:        databaseName(instance),
+		SecretName:  databaseName(instance),
+		Namespace:   instance.Namespace,
+		Labels:      labels(instance, "database"),
+		Engine:      engine,
+		Image:       image,
+		StorageSize: size,
+		User:        defaultDBUser,
+		DBName:      defaultDBName,
+	}
+}
+
+// migrationJobName returns the name of the Job migrating the schema of the database of instance. It changes with
+// the image of the registry and the database, so that the schema is migrated again when either changes.
+func migrationJobName(instance *mrv1alpha1.ModelRegistry) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v/%v/%v", registryImage(instance), databaseType(instance),
+		connectionSecretName(instance))))
+	return fmt.Sprintf("%v-migrate-%x", registryName(instance), hash[:5])
+}
+
+// migrationJob returns the Job migrating the schema of the database to the version of the image of the registry
+func migrationJob(instance *mrv1alpha1.ModelRegistry) *batchv1.Job {
+	backoff := int32(migrationBackoff)
+	return &batchv1.Job{
+		ObjectMeta: objectMeta(instance, migrationJobName(instance), "migration"),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels(instance, "migration")},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:  "migrate",
+						Image: registryImage(instance),
+						Args:  []string{"migrate"},
+						Env:   connectionEnv(instance),
+					}},
+				},
+			},
+		},
+	}
+}
+
+// registryDeployment returns the Deployment of the registry server
+func registryDeployment(instance *mrv1alpha1.ModelRegistry) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: objectMeta(instance, registryName(instance), "registry"),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels(instance, "registry")},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels(instance, "registry")},
+				Spec: corev1.PodSpec{
+					PriorityClassName: operatorconfig.Current().PriorityClasses.ControlPlane.Name,
+					Containers: []corev1.Container{{
+						Name:  "model-registry",
+						Image: registryImage(instance),
+						Args:  []string{"serve", fmt.Sprintf("--port=%v", registryHTTPPort), fmt.Sprintf("--grpc-port=%v", registryGRPCPort)},
+						Ports: []corev1.ContainerPort{
+							{Name: "http", ContainerPort: registryHTTPPort, Protocol: corev1.ProtocolTCP},
+							{Name: "grpc", ContainerPort: registryGRPCPort, Protocol: corev1.ProtocolTCP},
+						},
+						Env:       connectionEnv(instance),
+						Resources: instance.Spec.Resources,
+						ReadinessProbe: &corev1.Probe{
+							Handler:             corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(registryHTTPPort)}},
+							InitialDelaySeconds: 3,
+							PeriodSeconds:       5,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// registryService returns the Service of the REST and gRPC ports of the registry server
+func registryService(instance *mrv1alpha1.ModelRegistry) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: objectMeta(instance, registryName(instance), "registry"),
+		Spec: corev1.ServiceSpec{
+			Selector: labels(instance, "registry"),
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: registryHTTPPort, TargetPort: intstr.FromInt(registryHTTPPort), Protocol: corev1.ProtocolTCP},
+				{Name: "grpc", Port: registryGRPCPort, TargetPort: intstr.FromInt(registryGRPCPort), Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+}
+
+// deploysDatabase returns true if the database is deployed with the registry
+func deploysDatabase(instance *mrv1alpha1.ModelRegistry) bool {
+	return instance.Spec.Database.ConnectionSecret == ""
+}
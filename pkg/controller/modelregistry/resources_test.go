@@ -0,0 +1,161 @@
+package modelregistry
+
+import (
+	"testing"
+
+	mrv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/modelregistry/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/database"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestValidate(t *testing.T) {
+	size := resource.MustParse("20Gi")
+	type testCase struct {
+		Name  string
+		Spec  mrv1alpha1.ModelRegistrySpec
+		Valid bool
+	}
+	testCases := []testCase{
+		{
+			Name:  "defaults",
+			Spec:  mrv1alpha1.ModelRegistrySpec{},
+			Valid: true,
+		},
+		{
+			Name: "postgresql",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				Type:        mrv1alpha1.PostgreSQL,
+				StorageSize: &size,
+			}},
+			Valid: true,
+		},
+		{
+			Name: "existing database",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				Type:             mrv1alpha1.PostgreSQL,
+				ConnectionSecret: "registry-db",
+			}},
+			Valid: true,
+		},
+		{
+			Name: "invalid type",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{Type: "SQLite"}},
+		},
+		{
+			Name: "storage size of an existing database",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				ConnectionSecret: "registry-db",
+				StorageSize:      &size,
+			}},
+		},
+		{
+			Name: "external secret",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				ConnectionSecret: "registry-db",
+				ExternalSecret:   &mrv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/registry/db"},
+			}},
+			Valid: true,
+		},
+		{
+			Name: "external secret without connection secret",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				ExternalSecret: &mrv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/registry/db"},
+			}},
+		},
+		{
+			Name: "external secret without key",
+			Spec: mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{
+				ConnectionSecret: "registry-db",
+				ExternalSecret:   &mrv1alpha1.ExternalSecret{StoreName: "vault"},
+			}},
+		},
+	}
+
+	for _, c := range testCases {
+		err := validate(&c.Spec)
+		if c.Valid && err != nil {
+			t.Errorf("Case %v: expected the spec to be valid, got %v", c.Name, err)
+		}
+		if !c.Valid && err == nil {
+			t.Errorf("Case %v: expected the spec to be invalid", c.Name)
+		}
+	}
+}
+
+func TestValidateConnectionSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-db"},
+		Data: map[string][]byte{
+			"host":     []byte("postgres.example.com"),
+			"username": []byte("registry"),
+			"password": []byte("secret"),
+			"database": []byte("registry"),
+		},
+	}
+	if err := validateConnectionSecret(secret); err != nil {
+		t.Errorf("Expected a Secret without port to be valid, got %v", err)
+	}
+	delete(secret.Data, "password")
+	if err := validateConnectionSecret(secret); err == nil {
+		t.Errorf("Expected a Secret without password to be invalid")
+	}
+}
+
+func TestMigrationJobName(t *testing.T) {
+	instance := &mrv1alpha1.ModelRegistry{ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "project"}}
+	name := migrationJobName(instance)
+	if name != migrationJobName(instance.DeepCopy()) {
+		t.Errorf("Expected the name of the migration Job to be stable")
+	}
+
+	upgraded := instance.DeepCopy()
+	upgraded.Spec.Image = "quay.io/opendatahub/model-registry:v0.2.0"
+	if migrationJobName(upgraded) == name {
+		t.Errorf("Expected a new migration Job for a new image")
+	}
+	external := instance.DeepCopy()
+	external.Spec.Database.ConnectionSecret = "registry-db"
+	if migrationJobName(external) == name {
+		t.Errorf("Expected a new migration Job for a new database")
+	}
+}
+
+func TestManagedDatabase(t *testing.T) {
+	instance := &mrv1alpha1.ModelRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "project"},
+		Spec:       mrv1alpha1.ModelRegistrySpec{Database: mrv1alpha1.Database{Type: mrv1alpha1.PostgreSQL}},
+	}
+	db := managedDatabase(instance)
+	if db.Engine != database.PostgreSQL || db.Image != PostgreSQLImage {
+		t.Errorf("Expected a PostgreSQL database, got %v with %v", db.Engine, db.Image)
+	}
+	if data := db.ConnectionData(); data["host"] != "model-registry-db-sample.project.svc" || data["port"] != "5432" {
+		t.Errorf("Expected the connection of the PostgreSQL Service, got %v", data)
+	}
+	secret := &corev1.Secret{Data: map[string][]byte{"host": []byte("db.example.com")}}
+	if host, port := connectionAddress(instance, secret); host != "db.example.com" || port != "5432" {
+		t.Errorf("Expected the default port of PostgreSQL, got %v:%v", host, port)
+	}
+	for _, e := range connectionEnv(instance) {
+		if e.Name == "DB_TYPE" && e.Value != "postgres" {
+			t.Errorf("Expected DB_TYPE postgres, got %v", e.Value)
+		}
+		if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef.Name != "model-registry-db-sample" {
+			t.Errorf("Expected %v to be read from the Secret of the database, got %v", e.Name, e.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+}
+
+func TestResourcesPassPredicate(t *testing.T) {
+	instance := &mrv1alpha1.ModelRegistry{ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "project"}}
+	objects := []metav1.Object{migrationJob(instance), registryDeployment(instance), registryService(instance)}
+	for _, obj := range objects {
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: obj}) {
+			t.Errorf("Expected the events of %v to pass the predicate of the watches, got labels %v", obj.GetName(), obj.GetLabels())
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package notebook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	opmetrics "github.com/kubeflow/kfctl/v3/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// StopAnnotation stops a notebook: the notebook controller scales its StatefulSet down while it is set
+	StopAnnotation = "kubeflow-resource-stopped"
+	// LastActivityAnnotation is the last time the kernels of a notebook were active
+	LastActivityAnnotation = "notebooks.kubeflow.org/last-activity"
+	// CullIdleTimeAnnotation overrides CullIdleTime for the notebooks of a namespace, 0 never stops them
+	CullIdleTimeAnnotation = "notebooks.opendatahub.io/cull-idle-time"
+)
+
+// NotebookGVK is the kind of the notebooks created by the notebook controller
+var NotebookGVK = schema.GroupVersionKind{Group: "kubeflow.org", Version: "v1", Kind: "Notebook"}
+
+var (
+	// CullIdleTime is how long the kernels of a notebook are idle before it is stopped
+	CullIdleTime = 24 * time.Hour
+	// IdlenessCheckPeriod is how often the activity of the running notebooks is probed
+	IdlenessCheckPeriod = time.Minute
+	// ClusterDomain is the domain of the Services of the notebooks
+	ClusterDomain = "cluster.local"
+)
+
+// kernel is a Jupyter kernel, as listed by the Jupyter API
+type kernel struct {
+	ExecutionState string    `json:"execution_state"`
+	LastActivity   time.Time `json:"last_activity"`
+}
+
+// AddToManager adds the notebook culler to the Manager. The Notebook CRD must be installed.
+func AddToManager(m manager.Manager, options controller.Options) error {
+	if _, err := m.GetRESTMapper().RESTMapping(NotebookGVK.GroupKind(), NotebookGVK.Version); err != nil {
+		return fmt.Errorf("the notebook culler requires the %v CRD: %v", NotebookGVK, err)
+	}
+	options.Reconciler = ratelimiter.Wrap("notebook-culler", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileNotebook{
+		client:       mgr.GetClient(),
+		recorder:     mgr.GetEventRecorderFor("notebook-culler"),
+		probeKernels: probeKernels,
+	}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for notebook culling.")
+	c, err := controller.New("notebook-culler", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for the notebooks being created, started, stopped and deleted
+	notebook := &unstructured.Unstructured{}
+	notebook.SetGroupVersionKind(NotebookGVK)
+	return c.Watch(&source.Kind{Type: notebook}, &handler.EnqueueRequestForObject{}, notebookPredicates)
+}
+
+var notebookPredicates = predicate.Funcs{
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		_, wasStopped := e.MetaOld.GetAnnotations()[StopAnnotation]
+		_, stopped := e.MetaNew.GetAnnotations()[StopAnnotation]
+		return wasStopped != stopped
+	},
+}
+
+// blank assignment to verify that ReconcileNotebook implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileNotebook{}
+
+// ReconcileNotebook stops the notebooks whose kernels are idle
+type ReconcileNotebook struct {
+	client client.Client
+	// recorder to generate events
+	recorder record.EventRecorder
+	// probeKernels lists the kernels of a notebook with the Jupyter API
+	probeKernels func(namespace, name string) ([]kernel, error)
+}
+
+// Reconcile probes the kernels of a running notebook every IdlenessCheckPeriod and records their last activity
+// in the LastActivityAnnotation. The notebook is stopped with the StopAnnotation once they are idle for longer than
+// the cull idle time of its namespace. The notebooks that do not answer are not stopped.
+func (r *ReconcileNotebook) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	notebook := &unstructured.Unstructured{}
+	notebook.SetGroupVersionKind(NotebookGVK)
+	if err := r.client.Get(context.TODO(), request.NamespacedName, notebook); err != nil {
+		if errors.IsNotFound(err) {
+			opmetrics.DeleteNotebook(request.Namespace, request.Name)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if notebook.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+	annotations := notebook.GetAnnotations()
+	if _, stopped := annotations[StopAnnotation]; stopped {
+		opmetrics.DeleteNotebook(request.Namespace, request.Name)
+		return reconcile.Result{}, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: request.Namespace}, ns); err != nil {
+		return reconcile.Result{}, err
+	}
+	idleTime, err := cullIdleTime(ns)
+	if err != nil {
+		log.Warnf("Invalid %v of namespace %v, using %v. Error: %v.", CullIdleTimeAnnotation, ns.Name, CullIdleTime, err)
+		idleTime = CullIdleTime
+	}
+	if idleTime == 0 {
+		return reconcile.Result{}, nil
+	}
+
+	now := time.Now()
+	lastActivity, err := time.Parse(time.RFC3339, annotations[LastActivityAnnotation])
+	if err != nil {
+		lastActivity = now
+	}
+	kernels, err := r.probeKernels(request.Namespace, request.Name)
+	if err != nil {
+		log.Debugf("Failed to probe the kernels of notebook %v. Error: %v.", request.NamespacedName, err)
+		return reconcile.Result{RequeueAfter: IdlenessCheckPeriod}, nil
+	}
+	lastActivity = latestActivity(kernels, lastActivity, now)
+	opmetrics.SetNotebookLastActivity(request.Namespace, request.Name, lastActivity)
+
+	idleSince := lastActivity.Format(time.RFC3339)
+	if now.Sub(lastActivity) < idleTime {
+		if annotations[LastActivityAnnotation] != idleSince {
+			notebook.SetAnnotations(withAnnotation(annotations, LastActivityAnnotation, idleSince))
+			if err := r.client.Update(context.TODO(), notebook); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{RequeueAfter: IdlenessCheckPeriod}, nil
+	}
+
+	// The last activity is removed so that the notebook is not stopped again as soon as it is restarted
+	delete(annotations, LastActivityAnnotation)
+	notebook.SetAnnotations(withAnnotation(annotations, StopAnnotation, now.Format(time.RFC3339)))
+	if err := r.client.Update(context.TODO(), notebook); err != nil {
+		return reconcile.Result{}, err
+	}
+	log.Infof("Stopped notebook %v, idle since %v.", request.NamespacedName, idleSince)
+	r.recorder.Eventf(notebook, corev1.EventTypeNormal, "NotebookCulled", "Notebook stopped after being idle since %v", idleSince)
+	opmetrics.NotebookCulled(request.Namespace, request.Name)
+	return reconcile.Result{}, nil
+}
+
+// withAnnotation sets the annotation in annotations, which may be nil, and returns them
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
+
+// cullIdleTime returns the cull idle time of the notebooks of the namespace
+func cullIdleTime(ns *corev1.Namespace) (time.Duration, error) {
+	value, ok := ns.GetAnnotations()[CullIdleTimeAnnotation]
+	if !ok {
+		return CullIdleTime, nil
+	}
+	idleTime, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if idleTime < 0 {
+		return 0, fmt.Errorf("negative duration %v", value)
+	}
+	return idleTime, nil
+}
+
+// latestActivity returns the last activity of the kernels, now if one of them is busy. The previous activity is
+// returned when it is later, e.g. once the kernels are shut down.
+func latestActivity(kernels []kernel, previous time.Time, now time.Time) time.Time {
+	latest := previous
+	for _, k := range kernels {
+		if k.ExecutionState == "busy" {
+			return now
+		}
+		if k.LastActivity.After(latest) {
+			latest = k.LastActivity
+		}
+	}
+	if latest.After(now) {
+		return now
+	}
+	return latest
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// probeKernels lists the kernels of a notebook with the Jupyter API served by the Service of the notebook
+func probeKernels(namespace, name string) ([]kernel, error) {
+	url := fmt.Sprintf("http://%v.%v.svc.%v/notebook/%v/%v/api/kernels", name, namespace, ClusterDomain, namespace, name)
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %v returned %v", url, resp.Status)
+	}
+	kernels := []kernel{}
+	if err := json.NewDecoder(resp.Body).Decode(&kernels); err != nil {
+		return nil, err
+	}
+	return kernels, nil
+}
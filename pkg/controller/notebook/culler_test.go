@@ -0,0 +1,66 @@
+package notebook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLatestActivity(t *testing.T) {
+	kernels := []kernel{}
+	if err := json.Unmarshal([]byte(`[
+  {"id": "7f3c", "name": "python3", "last_activity": "2021-03-01T10:15:30.123456Z", "execution_state": "idle", "connections": 1},
+  {"id": "9a1e", "name": "python3", "last_activity": "2021-03-01T09:00:00.000000Z", "execution_state": "idle", "connections": 0}
+]`), &kernels); err != nil {
+		t.Fatalf("Failed to parse the kernels: %v", err)
+	}
+
+	previous := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2021, 3, 1, 10, 15, 30, 123456000, time.UTC)
+	if latest := latestActivity(kernels, previous, now); !latest.Equal(expected) {
+		t.Errorf("Expected the last activity of the kernels %v, got %v", expected, latest)
+	}
+	if latest := latestActivity(nil, previous, now); !latest.Equal(previous) {
+		t.Errorf("Expected the previous activity without kernels, got %v", latest)
+	}
+	if latest := latestActivity(kernels, now, now); !latest.Equal(now) {
+		t.Errorf("Expected the previous activity when it is later than the kernels, got %v", latest)
+	}
+
+	kernels = append(kernels, kernel{ExecutionState: "busy", LastActivity: previous})
+	if latest := latestActivity(kernels, previous, now); !latest.Equal(now) {
+		t.Errorf("Expected a busy kernel to be active now, got %v", latest)
+	}
+}
+
+func TestCullIdleTime(t *testing.T) {
+	type testCase struct {
+		Annotations map[string]string
+		Expected    time.Duration
+		Err         bool
+	}
+	testCases := []testCase{
+		{Annotations: nil, Expected: CullIdleTime},
+		{Annotations: map[string]string{CullIdleTimeAnnotation: "2h"}, Expected: 2 * time.Hour},
+		{Annotations: map[string]string{CullIdleTimeAnnotation: "0"}, Expected: 0},
+		{Annotations: map[string]string{CullIdleTimeAnnotation: "-1h"}, Err: true},
+		{Annotations: map[string]string{CullIdleTimeAnnotation: "a day"}, Err: true},
+	}
+	for _, c := range testCases {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "data-science", Annotations: c.Annotations}}
+		idleTime, err := cullIdleTime(ns)
+		if c.Err {
+			if err == nil {
+				t.Errorf("Expected %v to be rejected", c.Annotations)
+			}
+			continue
+		}
+		if err != nil || idleTime != c.Expected {
+			t.Errorf("Expected the cull idle time of %v to be %v, got %v, %v", c.Annotations, c.Expected, idleTime, err)
+		}
+	}
+}
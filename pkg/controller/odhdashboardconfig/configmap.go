@@ -0,0 +1,49 @@
+package odhdashboardconfig
+
+import (
+	"encoding/json"
+
+	dashboardv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/odhdashboardconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ConfigKey is the key of the ConfigMap holding the settings read by the dashboard
+const ConfigKey = "config.json"
+
+// DefaultNotebookSizes are the sizes offered to the users when the OdhDashboardConfig sets none
+var DefaultNotebookSizes = []dashboardv1alpha1.NotebookSize{
+	notebookSize("Small", "1", "8Gi", "2", "8Gi"),
+	notebookSize("Medium", "3", "24Gi", "6", "24Gi"),
+	notebookSize("Large", "7", "56Gi", "14", "56Gi"),
+}
+
+// notebookSize returns a notebook size with the cpu and memory requests and limits
+func notebookSize(name, cpuRequest, memoryRequest, cpuLimit, memoryLimit string) dashboardv1alpha1.NotebookSize {
+	return dashboardv1alpha1.NotebookSize{
+		Name: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpuRequest),
+				corev1.ResourceMemory: resource.MustParse(memoryRequest),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpuLimit),
+				corev1.ResourceMemory: resource.MustParse(memoryLimit),
+			},
+		},
+	}
+}
+
+// configData returns the data of the ConfigMap of the settings: the spec, with the default notebook sizes when
+// it sets none, as JSON under ConfigKey
+func configData(spec dashboardv1alpha1.OdhDashboardConfigSpec) (map[string]string, error) {
+	if len(spec.NotebookSizes) == 0 {
+		spec.NotebookSizes = DefaultNotebookSizes
+	}
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{ConfigKey: string(data)}, nil
+}
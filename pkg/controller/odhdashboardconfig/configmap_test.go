@@ -0,0 +1,59 @@
+package odhdashboardconfig
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	dashboardv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/odhdashboardconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigData(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Spec     dashboardv1alpha1.OdhDashboardConfigSpec
+		Expected dashboardv1alpha1.OdhDashboardConfigSpec
+	}
+	xlarge := notebookSize("X Large", "15", "120Gi", "30", "120Gi")
+	testCases := []testCase{
+		{
+			Name:     "defaults",
+			Spec:     dashboardv1alpha1.OdhDashboardConfigSpec{},
+			Expected: dashboardv1alpha1.OdhDashboardConfigSpec{NotebookSizes: DefaultNotebookSizes},
+		},
+		{
+			Name: "settings",
+			Spec: dashboardv1alpha1.OdhDashboardConfigSpec{
+				EnabledTiles:        []string{"jupyterhub", "odh-dashboard"},
+				NotebookSizes:       []dashboardv1alpha1.NotebookSize{xlarge},
+				NotebookIdleTimeout: &metav1.Duration{Duration: 2 * time.Hour},
+			},
+			Expected: dashboardv1alpha1.OdhDashboardConfigSpec{
+				EnabledTiles:        []string{"jupyterhub", "odh-dashboard"},
+				NotebookSizes:       []dashboardv1alpha1.NotebookSize{xlarge},
+				NotebookIdleTimeout: &metav1.Duration{Duration: 2 * time.Hour},
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		data, err := configData(c.Spec)
+		if err != nil {
+			t.Fatalf("%v: failed to render the settings: %v", c.Name, err)
+		}
+		if len(data) != 1 {
+			t.Errorf("%v: expected the settings to be rendered under %v only, got %v", c.Name, ConfigKey, data)
+		}
+		rendered := dashboardv1alpha1.OdhDashboardConfigSpec{}
+		if err := json.Unmarshal([]byte(data[ConfigKey]), &rendered); err != nil {
+			t.Fatalf("%v: failed to parse the rendered settings: %v", c.Name, err)
+		}
+		// The quantities are compared once rendered, their cached string forms differ once parsed again
+		expected, _ := json.Marshal(c.Expected)
+		actual, _ := json.Marshal(rendered)
+		if string(expected) != string(actual) {
+			t.Errorf("%v: expected the settings %s, got %s", c.Name, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package odhdashboardconfig
+
+import (
+	"context"
+	"reflect"
+
+	dashboardv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/odhdashboardconfig/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddToManager adds the OdhDashboardConfig controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("odhdashboardconfig-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileOdhDashboardConfig{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("odhdashboardconfig-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for odhdashboardconfig.")
+	c, err := controller.New("odhdashboardconfig-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource OdhDashboardConfig
+	err = c.Watch(&source.Kind{Type: &dashboardv1alpha1.OdhDashboardConfig{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the ConfigMaps the settings are rendered in and requeue the owner OdhDashboardConfig,
+	// so that the ConfigMaps edited by hand are reverted
+	return c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &dashboardv1alpha1.OdhDashboardConfig{},
+	}, kfutils.ManagedPredicate)
+}
+
+// blank assignment to verify that ReconcileOdhDashboardConfig implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileOdhDashboardConfig{}
+
+// ReconcileOdhDashboardConfig reconciles an OdhDashboardConfig object by rendering its settings in a ConfigMap
+type ReconcileOdhDashboardConfig struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile renders the settings of an OdhDashboardConfig in the ConfigMap of the same name and namespace read by
+// the dashboard. A ConfigMap that already exists is taken over, and its data is replaced whenever it differs from
+// the settings.
+func (r *ReconcileOdhDashboardConfig) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling OdhDashboardConfig. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &dashboardv1alpha1.OdhDashboardConfig{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The ConfigMap is owned by the OdhDashboardConfig and garbage collected with it.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	data, err := configData(instance.Spec)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	cm := &corev1.ConfigMap{}
+	err = r.client.Get(context.TODO(), request.NamespacedName, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: instance.Name, Namespace: instance.Namespace, Labels: kfutils.WithManagedLabel(nil)},
+			Data:       data,
+		}
+		if err := controllerutil.SetControllerReference(instance, cm, r.scheme); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Infof("Creating ConfigMap %v of the dashboard settings.", request.NamespacedName)
+		if err := r.client.Create(context.TODO(), cm); err != nil {
+			return reconcile.Result{}, err
+		}
+	} else if !metav1.IsControlledBy(cm, instance) || !kfutils.IsManaged(cm) || !reflect.DeepEqual(cm.Data, data) {
+		if !metav1.IsControlledBy(cm, instance) {
+			if err := controllerutil.SetControllerReference(instance, cm, r.scheme); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.recorder.Eventf(instance, corev1.EventTypeNormal, "ConfigMapAdopted",
+				"ConfigMap %s is now managed by the OdhDashboardConfig", cm.Name)
+		}
+		log.Infof("Updating ConfigMap %v of the dashboard settings.", request.NamespacedName)
+		cm.Labels = kfutils.WithManagedLabel(cm.Labels)
+		cm.Data = data
+		if err := r.client.Update(context.TODO(), cm); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	status := dashboardv1alpha1.OdhDashboardConfigStatus{
+		ObservedGeneration: instance.Generation,
+		ConfigMap:          cm.Name,
+	}
+	if reflect.DeepEqual(instance.Status, status) {
+		return reconcile.Result{}, nil
+	}
+	instance.Status = status
+	return reconcile.Result{}, r.client.Status().Update(context.TODO(), instance)
+}
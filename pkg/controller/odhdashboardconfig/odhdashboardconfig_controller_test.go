@@ -0,0 +1,48 @@
+package odhdashboardconfig
+
+import (
+	"context"
+	"testing"
+
+	dashboardv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/odhdashboardconfig/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestConfigMapPassesPredicate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := dashboardv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	instance := &dashboardv1alpha1.OdhDashboardConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "odh-dashboard-config", Namespace: "opendatahub", UID: "uid"},
+	}
+	r := &ReconcileOdhDashboardConfig{
+		client:   fake.NewFakeClientWithScheme(scheme, instance),
+		scheme:   scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile(%v) failed: %v", key, err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), key, cm); err != nil {
+		t.Fatalf("Expected ConfigMap %v to be created: %v", key, err)
+	}
+	if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: cm, Object: cm}) {
+		t.Errorf("Expected the events of the ConfigMap to pass the predicate of the watches, got labels %v", cm.Labels)
+	}
+}
@@ -0,0 +1,248 @@
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	kfdefcontroller "github.com/kubeflow/kfctl/v3/pkg/controller/kfdef"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// generatedNamespaceLabel marks the namespaces created by the operator, deleted when it is uninstalled
+	generatedNamespaceLabel = "opendatahub.io/generated-namespace"
+	// clusterMonitoringLabel makes the OpenShift monitoring stack scrape the ServiceMonitors of a namespace
+	clusterMonitoringLabel = "openshift.io/cluster-monitoring"
+	// podSecurityEnforceLabel is the Pod Security Standard the pods of a namespace are admitted with
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	// podSecurityLabelSyncLabel set to false keeps OpenShift from overwriting the Pod Security labels
+	podSecurityLabelSyncLabel = "security.openshift.io/scc.podSecurityLabelSync"
+	// meshInjectionLabel makes Istio inject its sidecar in the pods of a namespace
+	meshInjectionLabel = "istio-injection"
+)
+
+// AddToManager adds the OperatorConfig controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("operatorconfig-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileOperatorConfig{
+		client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor("operatorconfig-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for operatorconfig.")
+	c, err := controller.New("operatorconfig-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource OperatorConfig
+	err = c.Watch(&source.Kind{Type: &ocv1alpha1.OperatorConfig{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the applications namespace and requeue the OperatorConfig, so that the namespace
+	// deleted or relabelled by hand is restored. The namespace is not owned by the OperatorConfig, it is kept
+	// when the OperatorConfig is deleted.
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			if a.Meta.GetName() != operatorconfig.Current().ApplicationsNamespace {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: ocv1alpha1.SingletonName}}}
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the PriorityClasses the operator manages and requeue the OperatorConfig, so that the
+	// PriorityClasses deleted by hand are restored
+	return c.Watch(&source.Kind{Type: &schedulingv1.PriorityClass{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			for _, class := range managedPriorityClasses(operatorconfig.Current()) {
+				if a.Meta.GetName() == class.Name {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: ocv1alpha1.SingletonName}}}
+				}
+			}
+			return nil
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileOperatorConfig implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileOperatorConfig{}
+
+// ReconcileOperatorConfig reconciles the OperatorConfig singleton by reconfiguring the operator with its settings
+type ReconcileOperatorConfig struct {
+	client client.Client
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile makes the operator run with the settings of the OperatorConfig singleton, or with its flags once the
+// OperatorConfig is deleted, and creates the applications namespace and the PriorityClasses it manages. The controllers depending on the settings
+// that changed are notified to reconcile their resources again. Invalid settings are not applied, the operator
+// keeps running with the previous ones until they are fixed.
+func (r *ReconcileOperatorConfig) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling OperatorConfig. Request.Name: %v.", request.Name)
+
+	instance := &ocv1alpha1.OperatorConfig{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if request.Name != ocv1alpha1.SingletonName {
+		if err != nil {
+			return reconcile.Result{}, nil
+		}
+		message := fmt.Sprintf("only the OperatorConfig named %v configures the operator", ocv1alpha1.SingletonName)
+		return reconcile.Result{}, r.updateStatus(instance, ocv1alpha1.OperatorConfigIgnored, message, "")
+	}
+	if err != nil || instance.GetDeletionTimestamp() != nil {
+		if operatorconfig.Set(nil, nil) {
+			log.Infof("OperatorConfig %v deleted, reconfiguring the operator with its flags.", request.Name)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if err := operatorconfig.Validate(instance.Spec); err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "InvalidSpec", "%v", err)
+		return reconcile.Result{}, r.updateStatus(instance, ocv1alpha1.OperatorConfigFailed, err.Error(), "")
+	}
+	settings := operatorconfig.Effective(instance.Spec)
+	if err := r.reconcileApplicationsNamespace(settings); err != nil {
+		log.Errorf("Failed to reconcile the applications namespace %v: %v", settings.ApplicationsNamespace, err)
+		if statusErr := r.updateStatus(instance, ocv1alpha1.OperatorConfigFailed, err.Error(), ""); statusErr != nil {
+			log.Warnf("Failed to update the status of OperatorConfig %v: %v", request.Name, statusErr)
+		}
+		return reconcile.Result{}, err
+	}
+	if err := r.reconcilePriorityClasses(settings); err != nil {
+		log.Errorf("Failed to reconcile the PriorityClasses: %v", err)
+		if statusErr := r.updateStatus(instance, ocv1alpha1.OperatorConfigFailed, err.Error(), ""); statusErr != nil {
+			log.Warnf("Failed to update the status of OperatorConfig %v: %v", request.Name, statusErr)
+		}
+		return reconcile.Result{}, err
+	}
+	if operatorconfig.Set(&instance.Spec, instance) {
+		log.Infof("Reconfiguring the operator with OperatorConfig %v: %+v.", request.Name, settings)
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "Reconfigured",
+			"The operator runs with the settings of the OperatorConfig")
+	}
+	return reconcile.Result{}, r.updateStatus(instance, ocv1alpha1.OperatorConfigReady,
+		"the operator runs with the settings", settings.ApplicationsNamespace)
+}
+
+// reconcileApplicationsNamespace creates the applications namespace of settings with its labels, or restores the
+// labels of the existing one, and enrolls it into the service mesh or removes it from the mesh
+func (r *ReconcileOperatorConfig) reconcileApplicationsNamespace(settings ocv1alpha1.OperatorConfigSpec) error {
+	ns := &corev1.Namespace{}
+	err := r.client.Get(context.TODO(), client.ObjectKey{Name: settings.ApplicationsNamespace}, ns)
+	if errors.IsNotFound(err) {
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   settings.ApplicationsNamespace,
+			Labels: map[string]string{generatedNamespaceLabel: "true"},
+		}}
+		setNamespaceLabels(ns, settings)
+		log.Infof("Creating the applications namespace %v.", ns.Name)
+		err = r.client.Create(context.TODO(), ns)
+	} else if err == nil && setNamespaceLabels(ns, settings) {
+		log.Infof("Restoring the labels of the applications namespace %v.", ns.Name)
+		err = r.client.Update(context.TODO(), ns)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch settings.ServiceMesh {
+	case ocv1alpha1.Managed:
+		return kfdefcontroller.EnrollNamespace(r.client, settings.ApplicationsNamespace)
+	case ocv1alpha1.Removed:
+		return kfdefcontroller.UnenrollNamespace(r.client, settings.ApplicationsNamespace)
+	}
+	return nil
+}
+
+// namespaceLabels returns the labels of the applications namespace required by settings, and the labels it must
+// not have. The labels of the settings that are Unmanaged are in neither.
+func namespaceLabels(settings ocv1alpha1.OperatorConfigSpec) (map[string]string, []string) {
+	required := map[string]string{
+		podSecurityEnforceLabel:   string(settings.PodSecurity),
+		podSecurityLabelSyncLabel: "false",
+	}
+	removed := []string{}
+	if settings.Monitoring.ManagementState == ocv1alpha1.Removed {
+		removed = append(removed, clusterMonitoringLabel)
+	} else {
+		required[clusterMonitoringLabel] = "true"
+	}
+	switch settings.ServiceMesh {
+	case ocv1alpha1.Managed:
+		required[meshInjectionLabel] = "enabled"
+	case ocv1alpha1.Removed:
+		removed = append(removed, meshInjectionLabel)
+	}
+	return required, removed
+}
+
+// setNamespaceLabels sets the labels of the applications namespace required by settings and removes the ones it
+// must not have, restoring the labels stripped by hand. It returns true if the labels changed.
+func setNamespaceLabels(ns *corev1.Namespace, settings ocv1alpha1.OperatorConfigSpec) bool {
+	required, removed := namespaceLabels(settings)
+	changed := false
+	for _, key := range removed {
+		if _, ok := ns.Labels[key]; ok {
+			delete(ns.Labels, key)
+			changed = true
+		}
+	}
+	for key, value := range required {
+		if current, ok := ns.Labels[key]; ok && current == value {
+			continue
+		}
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		ns.Labels[key] = value
+		changed = true
+	}
+	return changed
+}
+
+// updateStatus updates the status of instance if it changed
+func (r *ReconcileOperatorConfig) updateStatus(instance *ocv1alpha1.OperatorConfig, phase ocv1alpha1.OperatorConfigPhase, message string, applicationsNamespace string) error {
+	status := ocv1alpha1.OperatorConfigStatus{
+		ObservedGeneration:    instance.Generation,
+		Phase:                 phase,
+		Message:               message,
+		ApplicationsNamespace: applicationsNamespace,
+	}
+	if instance.Status == status {
+		return nil
+	}
+	instance.Status = status
+	return r.client.Status().Update(context.TODO(), instance)
+}
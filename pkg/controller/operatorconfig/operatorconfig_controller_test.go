@@ -0,0 +1,281 @@
+package operatorconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	defer operatorconfig.Set(nil, nil)
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := ocv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	instance := &ocv1alpha1.OperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: ocv1alpha1.SingletonName},
+		Spec: ocv1alpha1.OperatorConfigSpec{
+			ApplicationsNamespace: "odh-applications",
+			NetworkPolicy:         ocv1alpha1.NetworkPolicyUnmanaged,
+		},
+	}
+	other := &ocv1alpha1.OperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	r := &ReconcileOperatorConfig{
+		client:   fake.NewFakeClientWithScheme(scheme, instance, other),
+		recorder: record.NewFakeRecorder(10),
+	}
+	reconcileConfig := func(name string) *ocv1alpha1.OperatorConfig {
+		if _, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatalf("Reconcile(%v) failed: %v", name, err)
+		}
+		current := &ocv1alpha1.OperatorConfig{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, current); err != nil {
+			t.Fatal(err)
+		}
+		return current
+	}
+
+	current := reconcileConfig(ocv1alpha1.SingletonName)
+	if current.Status.Phase != ocv1alpha1.OperatorConfigReady || current.Status.ApplicationsNamespace != "odh-applications" {
+		t.Errorf("Expected the OperatorConfig to be ready, got %+v", current.Status)
+	}
+	if settings := operatorconfig.Current(); settings.NetworkPolicy != ocv1alpha1.NetworkPolicyUnmanaged {
+		t.Errorf("Expected the operator to run with the settings of the OperatorConfig, got %+v", settings)
+	}
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "odh-applications"}, ns); err != nil {
+		t.Fatalf("Expected the applications namespace to be created: %v", err)
+	}
+	if ns.Labels[generatedNamespaceLabel] != "true" || ns.Labels[clusterMonitoringLabel] != "true" ||
+		ns.Labels[podSecurityEnforceLabel] != "baseline" {
+		t.Errorf("Expected the applications namespace to be labelled, got %v", ns.Labels)
+	}
+
+	// the labels stripped by hand are restored
+	ns.Labels = map[string]string{generatedNamespaceLabel: "true"}
+	if err := r.client.Update(context.TODO(), ns); err != nil {
+		t.Fatal(err)
+	}
+	reconcileConfig(ocv1alpha1.SingletonName)
+	ns = &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "odh-applications"}, ns); err != nil {
+		t.Fatal(err)
+	}
+	if ns.Labels[clusterMonitoringLabel] != "true" || ns.Labels[podSecurityEnforceLabel] != "baseline" ||
+		ns.Labels[podSecurityLabelSyncLabel] != "false" {
+		t.Errorf("Expected the labels of the applications namespace to be restored, got %v", ns.Labels)
+	}
+
+	// invalid settings are reported and not applied
+	current.Spec.NetworkPolicy = "Removed"
+	current.Spec.Monitoring.ManagementState = ocv1alpha1.Removed
+	if err := r.client.Update(context.TODO(), current); err != nil {
+		t.Fatal(err)
+	}
+	if current = reconcileConfig(ocv1alpha1.SingletonName); current.Status.Phase != ocv1alpha1.OperatorConfigFailed {
+		t.Errorf("Expected the invalid OperatorConfig to fail, got %+v", current.Status)
+	}
+	if settings := operatorconfig.Current(); settings.NetworkPolicy != ocv1alpha1.NetworkPolicyUnmanaged {
+		t.Errorf("Expected the operator to keep its settings, got %+v", settings)
+	}
+
+	// the monitoring label is removed along with the monitoring
+	current.Spec.NetworkPolicy = ocv1alpha1.NetworkPolicyManaged
+	if err := r.client.Update(context.TODO(), current); err != nil {
+		t.Fatal(err)
+	}
+	if current = reconcileConfig(ocv1alpha1.SingletonName); current.Status.Phase != ocv1alpha1.OperatorConfigReady {
+		t.Errorf("Expected the fixed OperatorConfig to be ready, got %+v", current.Status)
+	}
+	ns = &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "odh-applications"}, ns); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ns.Labels[clusterMonitoringLabel]; ok {
+		t.Errorf("Expected the monitoring label to be removed, got %v", ns.Labels)
+	}
+
+	if current = reconcileConfig("other"); current.Status.Phase != ocv1alpha1.OperatorConfigIgnored {
+		t.Errorf("Expected the OperatorConfig other than %v to be ignored, got %+v", ocv1alpha1.SingletonName, current.Status)
+	}
+
+	// the flags are restored once the OperatorConfig is deleted
+	if err := r.client.Delete(context.TODO(), current); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.client.Delete(context.TODO(), &ocv1alpha1.OperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: ocv1alpha1.SingletonName}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"other", ocv1alpha1.SingletonName} {
+		if _, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatalf("Reconcile(%v) failed: %v", name, err)
+		}
+	}
+	if settings := operatorconfig.Current(); settings != operatorconfig.Defaults {
+		t.Errorf("Expected the operator to run with its flags, got %+v", settings)
+	}
+}
+
+func TestSetNamespaceLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		settings ocv1alpha1.OperatorConfigSpec
+		expected map[string]string
+		changed  bool
+	}{
+		{
+			name:     "defaults",
+			settings: operatorconfig.Defaults,
+			expected: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+			},
+			changed: true,
+		},
+		{
+			name: "unchanged",
+			labels: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+				"team":                    "data-science",
+			},
+			settings: operatorconfig.Defaults,
+			expected: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+				"team":                    "data-science",
+			},
+		},
+		{
+			name: "restricted with the mesh",
+			labels: map[string]string{
+				podSecurityEnforceLabel: "privileged",
+			},
+			settings: operatorconfig.Effective(ocv1alpha1.OperatorConfigSpec{
+				PodSecurity: ocv1alpha1.PodSecurityRestricted,
+				ServiceMesh: ocv1alpha1.Managed,
+			}),
+			expected: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "restricted",
+				podSecurityLabelSyncLabel: "false",
+				meshInjectionLabel:        "enabled",
+			},
+			changed: true,
+		},
+		{
+			name: "monitoring and mesh removed",
+			labels: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+				meshInjectionLabel:        "enabled",
+			},
+			settings: operatorconfig.Effective(ocv1alpha1.OperatorConfigSpec{
+				Monitoring:  ocv1alpha1.Monitoring{ManagementState: ocv1alpha1.Removed},
+				ServiceMesh: ocv1alpha1.Removed,
+			}),
+			expected: map[string]string{
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+			},
+			changed: true,
+		},
+		{
+			name: "unmanaged mesh left alone",
+			labels: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+				meshInjectionLabel:        "disabled",
+			},
+			settings: operatorconfig.Defaults,
+			expected: map[string]string{
+				clusterMonitoringLabel:    "true",
+				podSecurityEnforceLabel:   "baseline",
+				podSecurityLabelSyncLabel: "false",
+				meshInjectionLabel:        "disabled",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "odh-applications", Labels: test.labels}}
+			if changed := setNamespaceLabels(ns, test.settings); changed != test.changed {
+				t.Errorf("Expected setNamespaceLabels() to return %v, got %v", test.changed, changed)
+			}
+			if !reflect.DeepEqual(ns.Labels, test.expected) {
+				t.Errorf("Expected the labels %v, got %v", test.expected, ns.Labels)
+			}
+		})
+	}
+}
+
+func TestReconcilePriorityClasses(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	existing := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high-priority"}, Value: 5000}
+	r := &ReconcileOperatorConfig{
+		client:   fake.NewFakeClientWithScheme(scheme, existing),
+		recorder: record.NewFakeRecorder(10),
+	}
+	settings := operatorconfig.Effective(ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+		ControlPlane: ocv1alpha1.PriorityClass{Name: "odh-control-plane", ManagementState: ocv1alpha1.Managed, Value: 1000000},
+		Workloads:    ocv1alpha1.PriorityClass{Name: "high-priority"},
+	}})
+	if err := r.reconcilePriorityClasses(settings); err != nil {
+		t.Fatalf("reconcilePriorityClasses() failed: %v", err)
+	}
+	class := &schedulingv1.PriorityClass{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "odh-control-plane"}, class); err != nil {
+		t.Fatalf("Expected the PriorityClass of the control plane to be created: %v", err)
+	}
+	if class.Value != 1000000 || class.Labels[generatedPriorityClassLabel] != "true" || !kfutils.IsManaged(class) {
+		t.Errorf("Expected the PriorityClass to be created with its value, got %v %v", class.Value, class.Labels)
+	}
+
+	// the value of a generated PriorityClass is changed by recreating it
+	settings.PriorityClasses.ControlPlane.Value = 2000000
+	if err := r.reconcilePriorityClasses(settings); err != nil {
+		t.Fatalf("reconcilePriorityClasses() failed: %v", err)
+	}
+	class = &schedulingv1.PriorityClass{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "odh-control-plane"}, class); err != nil {
+		t.Fatal(err)
+	}
+	if class.Value != 2000000 {
+		t.Errorf("Expected the PriorityClass to be recreated with value 2000000, got %v", class.Value)
+	}
+
+	// the PriorityClasses created by the administrators are left alone
+	settings.PriorityClasses.Workloads = ocv1alpha1.PriorityClass{Name: "high-priority", ManagementState: ocv1alpha1.Managed}
+	if err := r.reconcilePriorityClasses(settings); err == nil {
+		t.Errorf("Expected an error for the PriorityClass not created by the operator")
+	}
+	class = &schedulingv1.PriorityClass{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "high-priority"}, class); err != nil || class.Value != 5000 {
+		t.Errorf("Expected PriorityClass high-priority to be kept, got %v %v", class.Value, err)
+	}
+}
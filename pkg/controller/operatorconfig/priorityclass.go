@@ -0,0 +1,71 @@
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// generatedPriorityClassLabel marks the PriorityClasses created by the operator, the only ones it recreates when
+// their value changes
+const generatedPriorityClassLabel = "opendatahub.io/generated-priority-class"
+
+// managedPriorityClasses returns the PriorityClasses of settings the operator creates, by the description of the
+// pods they are assigned to
+func managedPriorityClasses(settings ocv1alpha1.OperatorConfigSpec) map[string]ocv1alpha1.PriorityClass {
+	classes := map[string]ocv1alpha1.PriorityClass{}
+	if settings.PriorityClasses.ControlPlane.ManagementState == ocv1alpha1.Managed {
+		classes["the control plane"] = settings.PriorityClasses.ControlPlane
+	}
+	if settings.PriorityClasses.Workloads.ManagementState == ocv1alpha1.Managed {
+		classes["the user workloads"] = settings.PriorityClasses.Workloads
+	}
+	return classes
+}
+
+// reconcilePriorityClasses creates the PriorityClasses of settings the operator manages, and recreates the ones
+// whose value changed. The PriorityClasses are kept when the settings no longer name them, for the pods naming
+// them to still be admitted.
+func (r *ReconcileOperatorConfig) reconcilePriorityClasses(settings ocv1alpha1.OperatorConfigSpec) error {
+	for pods, class := range managedPriorityClasses(settings) {
+		live := &schedulingv1.PriorityClass{}
+		err := r.client.Get(context.TODO(), client.ObjectKey{Name: class.Name}, live)
+		switch {
+		case err == nil && live.Value == class.Value:
+			continue
+		case err == nil:
+			if live.Labels[generatedPriorityClassLabel] != "true" {
+				return fmt.Errorf("PriorityClass %v exists with value %v and was not created by the operator", class.Name,
+					live.Value)
+			}
+			// The value of a PriorityClass is immutable, the pods created before keep the previous one
+			log.Infof("Recreating PriorityClass %v with value %v.", class.Name, class.Value)
+			if err := r.client.Delete(context.TODO(), live); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		case errors.IsNotFound(err):
+			log.Infof("Creating PriorityClass %v with value %v.", class.Name, class.Value)
+		default:
+			return err
+		}
+		err = r.client.Create(context.TODO(), &schedulingv1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   class.Name,
+				Labels: kfutils.WithManagedLabel(map[string]string{generatedPriorityClassLabel: "true"}),
+			},
+			Value:       class.Value,
+			Description: "Assigned by the Open Data Hub operator to the pods of " + pods,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
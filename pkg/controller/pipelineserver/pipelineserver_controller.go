@@ -0,0 +1,338 @@
+package pipelineserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	psv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/pipelineserver/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/database"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/externalsecret"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// externalSecretPollInterval is the interval the Secrets of the external secret store are checked at until
+	// they are created
+	externalSecretPollInterval = 10 * time.Second
+	// databasePollInterval is the interval the external database is checked at until it is reachable
+	databasePollInterval = 30 * time.Second
+)
+
+// AddToManager adds the PipelineServer controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("pipelineserver-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcilePipelineServer{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("pipelineserver-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for pipelineserver.")
+	c, err := controller.New("pipelineserver-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource PipelineServer
+	err = c.Watch(&source.Kind{Type: &psv1alpha1.PipelineServer{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the provisioned resources and requeue the owner PipelineServer, so that the resources
+	// edited or deleted by hand are restored and the phase follows the availability of the Deployments
+	for _, t := range []runtime.Object{&appsv1.Deployment{}, &corev1.Service{}, &corev1.Secret{},
+		&corev1.PersistentVolumeClaim{}, &corev1.ServiceAccount{}, &rbacv1.Role{}, &rbacv1.RoleBinding{}} {
+		err := c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &psv1alpha1.PipelineServer{},
+		}, kfutils.ManagedPredicate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to the PriorityClass of the control plane and requeue all the PipelineServers
+	kubeclient := mgr.GetClient()
+	events := operatorconfig.Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.PriorityClasses.ControlPlane.Name != next.PriorityClasses.ControlPlane.Name
+	})
+	return c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			servers := &psv1alpha1.PipelineServerList{}
+			if err := kubeclient.List(context.TODO(), servers); err != nil {
+				log.Warnf("Failed to list the PipelineServers for OperatorConfig %v: %v", a.Meta.GetName(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, server := range servers.Items {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: server.Name, Namespace: server.Namespace},
+				})
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcilePipelineServer implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcilePipelineServer{}
+
+// ReconcilePipelineServer reconciles a PipelineServer object by provisioning the pipelines stack of its namespace
+type ReconcilePipelineServer struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile provisions the API server of a PipelineServer, connected to its object storage and to the MariaDB
+// database deployed with it or to its external database, once it is reachable. The Secrets read from an external secret store are
+// created first, and the stack is deployed once they exist. The resources are owned by the PipelineServer and
+// garbage collected with it.
+func (r *ReconcilePipelineServer) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling PipelineServer. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &psv1alpha1.PipelineServer{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// Invalid specs are not retried, they are reconciled again once they are fixed
+	if err := validate(&instance.Spec); err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "InvalidSpec", "%v", err)
+		return reconcile.Result{}, r.updateStatus(instance, psv1alpha1.PipelineServerFailed, err.Error())
+	}
+	waiting, err := r.provisionExternalSecrets(instance)
+	if err == nil && len(waiting) == 0 {
+		err = r.provision(instance)
+	}
+	if err != nil {
+		log.Errorf("Failed to provision PipelineServer %v: %v", request.NamespacedName, err)
+		if statusErr := r.updateStatus(instance, psv1alpha1.PipelineServerFailed, err.Error()); statusErr != nil {
+			log.Warnf("Failed to update the status of PipelineServer %v: %v", request.NamespacedName, statusErr)
+		}
+		return reconcile.Result{}, err
+	}
+	if len(waiting) > 0 {
+		message := fmt.Sprintf("waiting for the Secrets of the external secret store: %v", strings.Join(waiting, ", "))
+		return reconcile.Result{RequeueAfter: externalSecretPollInterval},
+			r.updateStatus(instance, psv1alpha1.PipelineServerProgressing, message)
+	}
+	// The external database is checked until it is reachable, its failures are not errors of the PipelineServer
+	if !deploysMariaDB(instance) {
+		host, port, _, _, _ := dbConnection(instance)
+		if err := database.CheckConnection(host, port); err != nil {
+			return reconcile.Result{RequeueAfter: databasePollInterval},
+				r.updateStatus(instance, psv1alpha1.PipelineServerProgressing, err.Error())
+		}
+	}
+
+	phase, message := psv1alpha1.PipelineServerReady, "the API server and the database are available"
+	unavailable, err := r.unavailableDeployments(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(unavailable) > 0 {
+		phase, message = psv1alpha1.PipelineServerProgressing, fmt.Sprintf("waiting for Deployments %v to be available", unavailable)
+	}
+	return reconcile.Result{}, r.updateStatus(instance, phase, message)
+}
+
+// provisionExternalSecrets creates or updates the ExternalSecrets of the Secrets of instance read from an external
+// secret store, deletes the ones no longer set, and returns the Secrets that do not exist yet
+func (r *ReconcilePipelineServer) provisionExternalSecrets(instance *psv1alpha1.PipelineServer) ([]string, error) {
+	keep := map[string]bool{}
+	waiting := []string{}
+	for _, ref := range externalSecretRefs(&instance.Spec) {
+		if keep[ref.secret] {
+			continue
+		}
+		keep[ref.secret] = true
+		desired := externalsecret.ExternalSecret(instance.Namespace, ref.secret, labels(instance, "external-secret"), ref.source)
+		if err := externalsecret.Apply(r.client, r.scheme, instance, desired); err != nil {
+			return nil, err
+		}
+		materialized, message, err := externalsecret.Materialized(r.client, instance.Namespace, ref.secret)
+		if err != nil {
+			return nil, err
+		}
+		if !materialized {
+			if message != "" {
+				ref.secret += " (" + message + ")"
+			}
+			waiting = append(waiting, ref.secret)
+		}
+	}
+	return waiting, externalsecret.Prune(r.client, instance.Namespace, labels(instance, "external-secret"), keep)
+}
+
+// provision creates or updates the resources of the pipelines stack of instance
+func (r *ReconcilePipelineServer) provision(instance *psv1alpha1.PipelineServer) error {
+	if deploysMariaDB(instance) {
+		if err := database.Provision(r.client, r.scheme, instance, mariaDB(instance)); err != nil {
+			return err
+		}
+	} else if err := database.Remove(r.client, mariaDB(instance)); err != nil {
+		return err
+	}
+
+	desiredAccount := apiServerServiceAccount(instance)
+	account := &corev1.ServiceAccount{ObjectMeta: metaKey(desiredAccount.ObjectMeta)}
+	err := r.createOrUpdate(instance, account, func() error {
+		account.Labels = desiredAccount.Labels
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	desiredRole := apiServerRole(instance)
+	role := &rbacv1.Role{ObjectMeta: metaKey(desiredRole.ObjectMeta)}
+	err = r.createOrUpdate(instance, role, func() error {
+		role.Labels, role.Rules = desiredRole.Labels, desiredRole.Rules
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	desiredBinding := apiServerRoleBinding(instance)
+	binding := &rbacv1.RoleBinding{ObjectMeta: metaKey(desiredBinding.ObjectMeta)}
+	err = r.createOrUpdate(instance, binding, func() error {
+		binding.Labels, binding.Subjects = desiredBinding.Labels, desiredBinding.Subjects
+		// The role of a binding is immutable
+		if binding.CreationTimestamp.IsZero() {
+			binding.RoleRef = desiredBinding.RoleRef
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.createOrUpdateDeployment(instance, apiServerDeployment(instance)); err != nil {
+		return err
+	}
+	return r.createOrUpdateService(instance, apiServerService(instance))
+}
+
+// createOrUpdateDeployment creates the desired Deployment, or updates the pod template of the live one
+func (r *ReconcilePipelineServer) createOrUpdateDeployment(instance *psv1alpha1.PipelineServer, desired *appsv1.Deployment) error {
+	deployment := &appsv1.Deployment{ObjectMeta: metaKey(desired.ObjectMeta)}
+	return r.createOrUpdate(instance, deployment, func() error {
+		deployment.Labels = desired.Labels
+		// The selector of a Deployment is immutable
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = desired.Spec.Selector
+		}
+		deployment.Spec.Replicas = desired.Spec.Replicas
+		deployment.Spec.Strategy = desired.Spec.Strategy
+		deployment.Spec.Template = desired.Spec.Template
+		return nil
+	})
+}
+
+// createOrUpdateService creates the desired Service, or updates the selector and the ports of the live one
+func (r *ReconcilePipelineServer) createOrUpdateService(instance *psv1alpha1.PipelineServer, desired *corev1.Service) error {
+	service := &corev1.Service{ObjectMeta: metaKey(desired.ObjectMeta)}
+	return r.createOrUpdate(instance, service, func() error {
+		service.Labels = desired.Labels
+		service.Spec.Selector = desired.Spec.Selector
+		service.Spec.Ports = desired.Spec.Ports
+		return nil
+	})
+}
+
+// createOrUpdate creates obj, or updates the live object, after mutating it with mutate and making instance its
+// controller
+func (r *ReconcilePipelineServer) createOrUpdate(instance *psv1alpha1.PipelineServer, obj runtime.Object, mutate func() error) error {
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, obj, func() error {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(instance, obj.(metav1.Object), r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision %T %v: %v", obj, obj.(metav1.Object).GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Infof("%T %v/%v of PipelineServer %v %v.", obj, instance.Namespace, obj.(metav1.Object).GetName(),
+			instance.Name, result)
+	}
+	return nil
+}
+
+// unavailableDeployments returns the names of the Deployments of instance that have no available replica
+func (r *ReconcilePipelineServer) unavailableDeployments(instance *psv1alpha1.PipelineServer) ([]string, error) {
+	names := []string{apiServerName(instance)}
+	if deploysMariaDB(instance) {
+		names = append([]string{mariaDBName(instance)}, names...)
+	}
+	unavailable := []string{}
+	for _, name := range names {
+		deployment := &appsv1.Deployment{}
+		err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: instance.Namespace, Name: name}, deployment)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if err != nil || deployment.Status.AvailableReplicas == 0 {
+			unavailable = append(unavailable, name)
+		}
+	}
+	return unavailable, nil
+}
+
+// updateStatus updates the status of instance if it changed
+func (r *ReconcilePipelineServer) updateStatus(instance *psv1alpha1.PipelineServer, phase psv1alpha1.PipelineServerPhase, message string) error {
+	status := psv1alpha1.PipelineServerStatus{
+		ObservedGeneration: instance.Generation,
+		Phase:              phase,
+		Message:            message,
+	}
+	if phase != psv1alpha1.PipelineServerFailed {
+		status.APIServerURL = apiServerURL(instance)
+	}
+	if instance.Status == status {
+		return nil
+	}
+	instance.Status = status
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// metaKey returns the name and the namespace of meta
+func metaKey(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace}
+}
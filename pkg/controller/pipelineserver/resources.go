@@ -0,0 +1,308 @@
+package pipelineserver
+
+import (
+	"fmt"
+	"net"
+
+	psv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/pipelineserver/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/database"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/externalsecret"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	apiServerHTTPPort = 8888
+	apiServerGRPCPort = 8887
+
+	defaultDBUser           = "mlpipeline"
+	defaultDBName           = "mlpipeline"
+	defaultStorageSize      = "10Gi"
+	defaultAccessKeyKey     = "AWS_ACCESS_KEY_ID"
+	defaultSecretKeyKey     = "AWS_SECRET_ACCESS_KEY"
+	pipelineServerNameLabel = "pipelines.opendatahub.io/pipelineserver"
+	componentLabel          = "component"
+)
+
+var (
+	// APIServerImage is the image of the API servers that do not set theirs
+	APIServerImage = "quay.io/opendatahub/ds-pipelines-api-server:v1.0.0"
+	// MariaDBImage is the image of the MariaDB databases that do not set theirs
+	MariaDBImage = "registry.redhat.io/rhel8/mariadb-103:1"
+)
+
+// validate returns an error describing the first invalid field of the spec
+func validate(spec *psv1alpha1.PipelineServerSpec) error {
+	storage := spec.ObjectStorage
+	switch {
+	case storage.Host == "":
+		return fmt.Errorf("objectStorage.host is required")
+	case storage.Bucket == "":
+		return fmt.Errorf("objectStorage.bucket is required")
+	case storage.CredentialsSecret.SecretName == "":
+		return fmt.Errorf("objectStorage.credentialsSecret.secretName is required")
+	case storage.Scheme != "" && storage.Scheme != "http" && storage.Scheme != "https":
+		return fmt.Errorf("objectStorage.scheme must be http or https, got %q", storage.Scheme)
+	}
+	if db := spec.Database.ExternalDB; db != nil {
+		switch {
+		case db.Host == "":
+			return fmt.Errorf("database.externalDB.host is required")
+		case db.PasswordSecret.Name == "" || db.PasswordSecret.Key == "":
+			return fmt.Errorf("database.externalDB.passwordSecret requires a name and a key")
+		}
+	}
+
+	sources := map[string]externalsecret.Source{}
+	for _, ref := range externalSecretRefs(spec) {
+		if err := externalsecret.Validate(ref.field, ref.source); err != nil {
+			return err
+		}
+		if source, ok := sources[ref.secret]; ok && source != ref.source {
+			return fmt.Errorf("%v creates Secret %v from another external secret", ref.field, ref.secret)
+		}
+		sources[ref.secret] = ref.source
+	}
+	return nil
+}
+
+// externalSecretRef is a Secret of the spec created from an external secret store
+type externalSecretRef struct {
+	field  string
+	secret string
+	source externalsecret.Source
+}
+
+// externalSecretRefs returns the Secrets of the spec created from an external secret store
+func externalSecretRefs(spec *psv1alpha1.PipelineServerSpec) []externalSecretRef {
+	refs := []externalSecretRef{}
+	add := func(field string, secret string, ext *psv1alpha1.ExternalSecret) {
+		if ext == nil {
+			return
+		}
+		refs = append(refs, externalSecretRef{field: field + ".externalSecret", secret: secret, source: externalsecret.Source{
+			StoreName:       ext.StoreName,
+			StoreKind:       ext.StoreKind,
+			Key:             ext.Key,
+			RefreshInterval: ext.RefreshInterval,
+		}})
+	}
+	credentials := spec.ObjectStorage.CredentialsSecret
+	add("objectStorage.credentialsSecret", credentials.SecretName, credentials.ExternalSecret)
+	if db := spec.Database.ExternalDB; db != nil {
+		add("database.externalDB.passwordSecret", db.PasswordSecret.Name, db.PasswordSecret.ExternalSecret)
+	}
+	return refs
+}
+
+// apiServerName, mariaDBName and dbSecretName are the names of the resources provisioned for a PipelineServer
+func apiServerName(instance *psv1alpha1.PipelineServer) string {
+	return "ds-pipeline-" + instance.Name
+}
+
+func mariaDBName(instance *psv1alpha1.PipelineServer) string {
+	return "mariadb-" + instance.Name
+}
+
+func dbSecretName(instance *psv1alpha1.PipelineServer) string {
+	return "ds-pipeline-db-" + instance.Name
+}
+
+// apiServerURL returns the URL of the API server inside the cluster
+func apiServerURL(instance *psv1alpha1.PipelineServer) string {
+	return fmt.Sprintf("http://%v.%v.svc:%v", apiServerName(instance), instance.Namespace, apiServerHTTPPort)
+}
+
+// labels returns the labels of the resources of component
+func labels(instance *psv1alpha1.PipelineServer, component string) map[string]string {
+	return map[string]string{pipelineServerNameLabel: instance.Name, componentLabel: component}
+}
+
+// objectMeta returns the metadata of a resource of component named name
+func objectMeta(instance *psv1alpha1.PipelineServer, name string, component string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: instance.Namespace, Labels: kfutils.WithManagedLabel(labels(instance, component))}
+}
+
+// dbConnection returns the host, port, user, database name and password Secret of the database of the API server
+func dbConnection(instance *psv1alpha1.PipelineServer) (string, string, string, string, psv1alpha1.SecretKey) {
+	if db := instance.Spec.Database.ExternalDB; db != nil {
+		port, user, name := db.Port, db.Username, db.DBName
+		if port == "" {
+			port = fmt.Sprint(database.EnginePort(database.MariaDB))
+		}
+		if user == "" {
+			user = defaultDBUser
+		}
+		if name == "" {
+			name = defaultDBName
+		}
+		return db.Host, port, user, name, db.PasswordSecret
+	}
+	db := mariaDB(instance)
+	return db.Host(), fmt.Sprint(db.Port()), db.User, db.DBName,
+		psv1alpha1.SecretKey{Name: db.SecretName, Key: database.PasswordKey}
+}
+
+// mariaDB returns the MariaDB database deployed with the API server
+func mariaDB(instance *psv1alpha1.PipelineServer) *database.Database {
+	image := instance.Spec.Database.MariaDB.Image
+	if image == "" {
+		image = MariaDBImage
+	}
+	size := resource.MustParse(defaultStorageSize)
+	if instance.Spec.Database.MariaDB.StorageSize != nil {
+		size = *instance.Spec.Database.MariaDB.StorageSize
+	}
+	return &database.Database{
+		Name:        mariaDBName(instance),
+		SecretName:  dbSecretName(instance),
+		Namespace:   instance.Namespace,
+		Labels:      labels(instance, "mariadb"),
+		Engine:      database.MariaDB,
+		Image:       image,
+		StorageSize: size,
+		User:        defaultDBUser,
+		DBName:      defaultDBName,
+	}
+}
+
+// apiServerServiceAccount returns the ServiceAccount the API server runs the pipelines with
+func apiServerServiceAccount(instance *psv1alpha1.PipelineServer) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{ObjectMeta: objectMeta(instance, apiServerName(instance), "api-server")}
+}
+
+// apiServerRole returns the Role of the API server, allowing it to run the pipelines with Tekton in its namespace
+func apiServerRole(instance *psv1alpha1.PipelineServer) *rbacv1.Role {
+	return &rbacv1.Role{
+		ObjectMeta: objectMeta(instance, apiServerName(instance), "api-server"),
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"tekton.dev"},
+				Resources: []string{"pipelineruns", "taskruns", "conditions", "runs", "tasks"},
+				Verbs:     []string{"create", "get", "list", "watch", "update", "patch", "delete"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/log"},
+				Verbs:     []string{"get", "list", "watch", "delete"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"persistentvolumeclaims"},
+				Verbs:     []string{"create", "get", "list", "delete"},
+			},
+		},
+	}
+}
+
+// apiServerRoleBinding returns the RoleBinding of the Role of the API server to its ServiceAccount
+func apiServerRoleBinding(instance *psv1alpha1.PipelineServer) *rbacv1.RoleBinding {
+	name := apiServerName(instance)
+	return &rbacv1.RoleBinding{
+		ObjectMeta: objectMeta(instance, name, "api-server"),
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: instance.Namespace}},
+	}
+}
+
+// apiServerDeployment returns the Deployment of the API server, connected to the database and the object storage
+func apiServerDeployment(instance *psv1alpha1.PipelineServer) *appsv1.Deployment {
+	image := instance.Spec.APIServer.Image
+	if image == "" {
+		image = APIServerImage
+	}
+	dbHost, dbPort, dbUser, dbName, dbPassword := dbConnection(instance)
+	storage := instance.Spec.ObjectStorage
+	scheme := storage.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	host, port := storage.Host, ""
+	if h, p, err := net.SplitHostPort(storage.Host); err == nil {
+		host, port = h, p
+	}
+	accessKey, secretKey := storage.CredentialsSecret.AccessKey, storage.CredentialsSecret.SecretKey
+	if accessKey == "" {
+		accessKey = defaultAccessKeyKey
+	}
+	if secretKey == "" {
+		secretKey = defaultSecretKeyKey
+	}
+
+	replicas := int32(1)
+	name := apiServerName(instance)
+	return &appsv1.Deployment{
+		ObjectMeta: objectMeta(instance, name, "api-server"),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels(instance, "api-server")},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels(instance, "api-server")},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: name,
+					PriorityClassName:  operatorconfig.Current().PriorityClasses.ControlPlane.Name,
+					Containers: []corev1.Container{{
+						Name:  "ds-pipeline-api-server",
+						Image: image,
+						Ports: []corev1.ContainerPort{
+							{Name: "http", ContainerPort: apiServerHTTPPort, Protocol: corev1.ProtocolTCP},
+							{Name: "grpc", ContainerPort: apiServerGRPCPort, Protocol: corev1.ProtocolTCP},
+						},
+						Env: []corev1.EnvVar{
+							{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+							}},
+							{Name: "DBCONFIG_HOSTNAME", Value: dbHost},
+							{Name: "DBCONFIG_PORT", Value: dbPort},
+							{Name: "DBCONFIG_USER", Value: dbUser},
+							{Name: "DBCONFIG_DBNAME", Value: dbName},
+							kfutils.SecretEnv("DBCONFIG_PASSWORD", dbPassword.Name, dbPassword.Key),
+							{Name: "OBJECTSTORECONFIG_HOST", Value: host},
+							{Name: "OBJECTSTORECONFIG_PORT", Value: port},
+							{Name: "OBJECTSTORECONFIG_SECURE", Value: fmt.Sprint(scheme == "https")},
+							{Name: "OBJECTSTORECONFIG_BUCKETNAME", Value: storage.Bucket},
+							kfutils.SecretEnv("OBJECTSTORECONFIG_ACCESSKEY", storage.CredentialsSecret.SecretName, accessKey),
+							kfutils.SecretEnv("OBJECTSTORECONFIG_SECRETACCESSKEY", storage.CredentialsSecret.SecretName, secretKey),
+							{Name: "ARTIFACT_BUCKET", Value: storage.Bucket},
+							{Name: "ARTIFACT_ENDPOINT", Value: scheme + "://" + storage.Host},
+							{Name: "ARTIFACT_ENDPOINT_SCHEME", Value: scheme + "://"},
+						},
+						Resources: instance.Spec.APIServer.Resources,
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{
+								Path: "/apis/v1beta1/healthz", Port: intstr.FromInt(apiServerHTTPPort),
+							}},
+							InitialDelaySeconds: 3,
+							PeriodSeconds:       5,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// apiServerService returns the Service of the HTTP and gRPC ports of the API server
+func apiServerService(instance *psv1alpha1.PipelineServer) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: objectMeta(instance, apiServerName(instance), "api-server"),
+		Spec: corev1.ServiceSpec{
+			Selector: labels(instance, "api-server"),
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: apiServerHTTPPort, TargetPort: intstr.FromInt(apiServerHTTPPort), Protocol: corev1.ProtocolTCP},
+				{Name: "grpc", Port: apiServerGRPCPort, TargetPort: intstr.FromInt(apiServerGRPCPort), Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+}
+
+// deploysMariaDB returns true if the MariaDB database is deployed with the API server
+func deploysMariaDB(instance *psv1alpha1.PipelineServer) bool {
+	return instance.Spec.Database.ExternalDB == nil
+}
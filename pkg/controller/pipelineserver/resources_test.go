@@ -0,0 +1,257 @@
+package pipelineserver
+
+import (
+	"testing"
+
+	psv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/pipelineserver/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestValidate(t *testing.T) {
+	storage := psv1alpha1.ObjectStorage{
+		Host:              "minio.example.com:9000",
+		Bucket:            "pipelines",
+		CredentialsSecret: psv1alpha1.S3CredentialsSecret{SecretName: "minio"},
+	}
+	type testCase struct {
+		Name  string
+		Spec  psv1alpha1.PipelineServerSpec
+		Valid bool
+	}
+	testCases := []testCase{
+		{
+			Name:  "mariadb",
+			Spec:  psv1alpha1.PipelineServerSpec{ObjectStorage: storage},
+			Valid: true,
+		},
+		{
+			Name: "external database",
+			Spec: psv1alpha1.PipelineServerSpec{
+				ObjectStorage: storage,
+				Database: psv1alpha1.Database{ExternalDB: &psv1alpha1.ExternalDB{
+					Host:           "mysql.example.com",
+					PasswordSecret: psv1alpha1.SecretKey{Name: "mysql", Key: "password"},
+				}},
+			},
+			Valid: true,
+		},
+		{
+			Name: "no bucket",
+			Spec: psv1alpha1.PipelineServerSpec{ObjectStorage: psv1alpha1.ObjectStorage{
+				Host:              storage.Host,
+				CredentialsSecret: storage.CredentialsSecret,
+			}},
+		},
+		{
+			Name: "invalid scheme",
+			Spec: psv1alpha1.PipelineServerSpec{ObjectStorage: psv1alpha1.ObjectStorage{
+				Host:              storage.Host,
+				Scheme:            "ftp",
+				Bucket:            storage.Bucket,
+				CredentialsSecret: storage.CredentialsSecret,
+			}},
+		},
+		{
+			Name: "external database without password",
+			Spec: psv1alpha1.PipelineServerSpec{
+				ObjectStorage: storage,
+				Database:      psv1alpha1.Database{ExternalDB: &psv1alpha1.ExternalDB{Host: "mysql.example.com"}},
+			},
+		},
+		{
+			Name: "external secrets",
+			Spec: psv1alpha1.PipelineServerSpec{
+				ObjectStorage: psv1alpha1.ObjectStorage{
+					Host:   storage.Host,
+					Bucket: storage.Bucket,
+					CredentialsSecret: psv1alpha1.S3CredentialsSecret{
+						SecretName:     "pipelines",
+						ExternalSecret: &psv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/pipelines"},
+					},
+				},
+				Database: psv1alpha1.Database{ExternalDB: &psv1alpha1.ExternalDB{
+					Host: "mysql.example.com",
+					PasswordSecret: psv1alpha1.SecretKey{
+						Name:           "pipelines",
+						Key:            "password",
+						ExternalSecret: &psv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/pipelines"},
+					},
+				}},
+			},
+			Valid: true,
+		},
+		{
+			Name: "conflicting external secrets",
+			Spec: psv1alpha1.PipelineServerSpec{
+				ObjectStorage: psv1alpha1.ObjectStorage{
+					Host:   storage.Host,
+					Bucket: storage.Bucket,
+					CredentialsSecret: psv1alpha1.S3CredentialsSecret{
+						SecretName:     "pipelines",
+						ExternalSecret: &psv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/s3"},
+					},
+				},
+				Database: psv1alpha1.Database{ExternalDB: &psv1alpha1.ExternalDB{
+					Host: "mysql.example.com",
+					PasswordSecret: psv1alpha1.SecretKey{
+						Name:           "pipelines",
+						Key:            "password",
+						ExternalSecret: &psv1alpha1.ExternalSecret{StoreName: "vault", Key: "secret/data/mysql"},
+					},
+				}},
+			},
+		},
+		{
+			Name: "external secret of an invalid store kind",
+			Spec: psv1alpha1.PipelineServerSpec{ObjectStorage: psv1alpha1.ObjectStorage{
+				Host:   storage.Host,
+				Bucket: storage.Bucket,
+				CredentialsSecret: psv1alpha1.S3CredentialsSecret{
+					SecretName:     "pipelines",
+					ExternalSecret: &psv1alpha1.ExternalSecret{StoreName: "vault", StoreKind: "Vault", Key: "secret/data/s3"},
+				},
+			}},
+		},
+	}
+
+	for _, c := range testCases {
+		err := validate(&c.Spec)
+		if c.Valid && err != nil {
+			t.Errorf("Case %v: expected the spec to be valid, got %v", c.Name, err)
+		}
+		if !c.Valid && err == nil {
+			t.Errorf("Case %v: expected the spec to be invalid", c.Name)
+		}
+	}
+}
+
+func TestAPIServerEnv(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Spec     psv1alpha1.PipelineServerSpec
+		Expected map[string]string
+		Secrets  map[string]corev1.SecretKeySelector
+	}
+	storage := psv1alpha1.ObjectStorage{
+		Host:              "minio.example.com:9000",
+		Scheme:            "http",
+		Bucket:            "pipelines",
+		CredentialsSecret: psv1alpha1.S3CredentialsSecret{SecretName: "minio"},
+	}
+	testCases := []testCase{
+		{
+			Name: "mariadb",
+			Spec: psv1alpha1.PipelineServerSpec{ObjectStorage: storage},
+			Expected: map[string]string{
+				"DBCONFIG_HOSTNAME":            "mariadb-sample.project.svc",
+				"DBCONFIG_PORT":                "3306",
+				"DBCONFIG_USER":                "mlpipeline",
+				"DBCONFIG_DBNAME":              "mlpipeline",
+				"OBJECTSTORECONFIG_HOST":       "minio.example.com",
+				"OBJECTSTORECONFIG_PORT":       "9000",
+				"OBJECTSTORECONFIG_SECURE":     "false",
+				"ARTIFACT_ENDPOINT":            "http://minio.example.com:9000",
+				"ARTIFACT_ENDPOINT_SCHEME":     "http://",
+				"ARTIFACT_BUCKET":              "pipelines",
+				"OBJECTSTORECONFIG_BUCKETNAME": "pipelines",
+			},
+			Secrets: map[string]corev1.SecretKeySelector{
+				"DBCONFIG_PASSWORD":                 secretKey("ds-pipeline-db-sample", "password"),
+				"OBJECTSTORECONFIG_ACCESSKEY":       secretKey("minio", "AWS_ACCESS_KEY_ID"),
+				"OBJECTSTORECONFIG_SECRETACCESSKEY": secretKey("minio", "AWS_SECRET_ACCESS_KEY"),
+			},
+		},
+		{
+			Name: "external database",
+			Spec: psv1alpha1.PipelineServerSpec{
+				ObjectStorage: psv1alpha1.ObjectStorage{
+					Host:   "s3.amazonaws.com",
+					Bucket: "pipelines",
+					CredentialsSecret: psv1alpha1.S3CredentialsSecret{
+						SecretName: "aws",
+						AccessKey:  "accesskey",
+						SecretKey:  "secretkey",
+					},
+				},
+				Database: psv1alpha1.Database{ExternalDB: &psv1alpha1.ExternalDB{
+					Host:           "mysql.example.com",
+					Port:           "3307",
+					Username:       "pipelines",
+					DBName:         "pipelines",
+					PasswordSecret: psv1alpha1.SecretKey{Name: "mysql", Key: "mysql-password"},
+				}},
+			},
+			Expected: map[string]string{
+				"DBCONFIG_HOSTNAME":        "mysql.example.com",
+				"DBCONFIG_PORT":            "3307",
+				"DBCONFIG_USER":            "pipelines",
+				"DBCONFIG_DBNAME":          "pipelines",
+				"OBJECTSTORECONFIG_HOST":   "s3.amazonaws.com",
+				"OBJECTSTORECONFIG_PORT":   "",
+				"OBJECTSTORECONFIG_SECURE": "true",
+				"ARTIFACT_ENDPOINT":        "https://s3.amazonaws.com",
+				"ARTIFACT_ENDPOINT_SCHEME": "https://",
+			},
+			Secrets: map[string]corev1.SecretKeySelector{
+				"DBCONFIG_PASSWORD":                 secretKey("mysql", "mysql-password"),
+				"OBJECTSTORECONFIG_ACCESSKEY":       secretKey("aws", "accesskey"),
+				"OBJECTSTORECONFIG_SECRETACCESSKEY": secretKey("aws", "secretkey"),
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		instance := &psv1alpha1.PipelineServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "project"},
+			Spec:       c.Spec,
+		}
+		if deploysMariaDB(instance) != (c.Spec.Database.ExternalDB == nil) {
+			t.Errorf("Case %v: expected MariaDB to be deployed only without an external database", c.Name)
+		}
+		env := map[string]corev1.EnvVar{}
+		for _, e := range apiServerDeployment(instance).Spec.Template.Spec.Containers[0].Env {
+			env[e.Name] = e
+		}
+		for name, value := range c.Expected {
+			if e, ok := env[name]; !ok || e.Value != value {
+				t.Errorf("Case %v: expected %v to be %q, got %q", c.Name, name, value, e.Value)
+			}
+		}
+		for name, selector := range c.Secrets {
+			e := env[name]
+			if e.ValueFrom == nil || e.ValueFrom.SecretKeyRef == nil || *e.ValueFrom.SecretKeyRef != selector {
+				t.Errorf("Case %v: expected %v to be set from %v/%v, got %v", c.Name, name, selector.Name, selector.Key, e.ValueFrom)
+			}
+		}
+	}
+}
+
+func secretKey(name string, key string) corev1.SecretKeySelector {
+	return corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func TestAPIServerDeploymentLabels(t *testing.T) {
+	instance := &psv1alpha1.PipelineServer{ObjectMeta: metav1.ObjectMeta{Name: "pipelines", Namespace: "fraud-detection"}}
+	deployment := apiServerDeployment(instance)
+	if !kfutils.IsManaged(deployment) {
+		t.Errorf("Expected the Deployment to be labelled %v, got %v", kfutils.ManagedLabel, deployment.Labels)
+	}
+	// The selector of an existing Deployment cannot be changed
+	if _, found := deployment.Spec.Selector.MatchLabels[kfutils.ManagedLabel]; found {
+		t.Errorf("Expected the selector without %v, got %v", kfutils.ManagedLabel, deployment.Spec.Selector.MatchLabels)
+	}
+}
+
+func TestResourcesPassPredicate(t *testing.T) {
+	instance := &psv1alpha1.PipelineServer{ObjectMeta: metav1.ObjectMeta{Name: "pipelines", Namespace: "fraud-detection"}}
+	objects := []metav1.Object{apiServerServiceAccount(instance), apiServerRole(instance), apiServerRoleBinding(instance),
+		apiServerDeployment(instance), apiServerService(instance)}
+	for _, obj := range objects {
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: obj}) {
+			t.Errorf("Expected the events of %v to pass the predicate of the watches, got labels %v", obj.GetName(), obj.GetLabels())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package ratelimiter delays the retries of failed reconciliations. The controllers of the version of
+// controller-runtime used here always retry with the default rate limiter of client-go, whose per request delay
+// starts at 5ms, and do not let the controllers configure another one: persistent errors are retried in quick
+// succession. The failed requests are requeued after a delay instead, and counted in ReconcileErrors since
+// controller-runtime never sees their errors.
+package ratelimiter
+
+import (
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	// BaseDelay is the delay before retrying a request that failed once
+	BaseDelay = 5 * time.Second
+	// MaxDelay is the maximum delay before retrying a request that keeps failing
+	MaxDelay = 10 * time.Minute
+)
+
+// Wrap returns a reconcile.Reconciler retrying the requests failed by r after an exponential delay,
+// from BaseDelay up to MaxDelay, that is reset when the request succeeds. The errors are counted for the
+// controller name.
+func Wrap(name string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &rateLimitedReconciler{
+		name:       name,
+		reconciler: r,
+		limiter:    workqueue.NewItemExponentialFailureRateLimiter(BaseDelay, MaxDelay),
+	}
+}
+
+// blank assignment to verify that rateLimitedReconciler implements reconcile.Reconciler
+var _ reconcile.Reconciler = &rateLimitedReconciler{}
+
+type rateLimitedReconciler struct {
+	name       string
+	reconciler reconcile.Reconciler
+	limiter    workqueue.RateLimiter
+}
+
+// Reconcile calls the wrapped reconciler and turns its errors into a delayed requeue, after logging and
+// counting them
+func (r *rateLimitedReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	result, err := r.reconciler.Reconcile(request)
+	if err != nil {
+		delay := r.limiter.When(request)
+		metrics.ReconcileErrors.WithLabelValues(r.name).Inc()
+		log.Errorf("%v failed to reconcile %v, retrying in %v. Error: %v.", r.name, request, delay, err)
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	r.limiter.Forget(request)
+	return result, nil
+}
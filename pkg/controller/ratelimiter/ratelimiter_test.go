@@ -0,0 +1,43 @@
+package ratelimiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeReconciler struct {
+	errs []error
+}
+
+func (f *fakeReconciler) Reconcile(_ reconcile.Request) (reconcile.Result, error) {
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return reconcile.Result{}, err
+}
+
+func TestWrap(t *testing.T) {
+	BaseDelay = time.Second
+	MaxDelay = 3 * time.Second
+	failed := errors.New("failed")
+	r := Wrap("test-controller", &fakeReconciler{errs: []error{failed, failed, failed, nil, failed}})
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "odh", Namespace: "opendatahub"}}
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 0, time.Second} {
+		result, err := r.Reconcile(request)
+		if err != nil {
+			t.Errorf("Expected the error to be turned into a requeue, got %v", err)
+		}
+		if result.RequeueAfter != expected {
+			t.Errorf("Expected a requeue after %v, got %v", expected, result.RequeueAfter)
+		}
+	}
+	if errs := testutil.ToFloat64(metrics.ReconcileErrors.WithLabelValues("test-controller")); errs != 4 {
+		t.Errorf("Expected 4 errors to be counted, got %v", errs)
+	}
+}
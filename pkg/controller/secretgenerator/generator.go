@@ -0,0 +1,193 @@
+package secretgenerator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// NameAnnotation makes a Secret of the manifests the template of a generated Secret, and names the key of the
+	// generated value
+	NameAnnotation = "secret-generator.opendatahub.io/name"
+	// TypeAnnotation is the type of the generated value, random, oauth or htpasswd. Defaults to random.
+	TypeAnnotation = "secret-generator.opendatahub.io/type"
+	// ComplexityAnnotation is the length of the generated password. Defaults to 16.
+	ComplexityAnnotation = "secret-generator.opendatahub.io/complexity"
+	// UserAnnotation is the user of the generated htpasswd entry. Defaults to admin.
+	UserAnnotation = "secret-generator.opendatahub.io/user"
+	// RotationIntervalAnnotation is the duration after which the value is generated again, e.g. 720h. The value
+	// is never rotated when it is not set.
+	RotationIntervalAnnotation = "secret-generator.opendatahub.io/rotation-interval"
+	// RotateAnnotation rotates the value once whenever it changes, e.g. set to the current date
+	RotateAnnotation = "secret-generator.opendatahub.io/rotate"
+
+	// generatedAtAnnotation is the time the value of a generated Secret was generated at
+	generatedAtAnnotation = "secret-generator.opendatahub.io/generated-at"
+	// rotatedForAnnotation is the RotateAnnotation of the template the value of a generated Secret was generated for
+	rotatedForAnnotation = "secret-generator.opendatahub.io/rotated-for"
+	// generatedSuffix is the suffix of the name of a generated Secret
+	generatedSuffix = "-generated"
+
+	defaultComplexity = 16
+	defaultUser       = "admin"
+	passwordKeySuffix = "-password"
+	passwordChars     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// generatorType is the type of a generated value
+type generatorType string
+
+const (
+	// randomType generates an alphanumeric password
+	randomType generatorType = "random"
+	// oauthType generates a cookie secret of the OAuth proxy, 32 random bytes encoded in base64
+	oauthType generatorType = "oauth"
+	// htpasswdType generates a password and its bcrypt htpasswd entry
+	htpasswdType generatorType = "htpasswd"
+)
+
+// generator holds the settings parsed from the annotations of a template
+type generator struct {
+	name             string
+	kind             generatorType
+	complexity       int
+	user             string
+	rotationInterval time.Duration
+	rotate           string
+}
+
+// isTemplate returns true if the Secret is the template of a generated Secret
+func isTemplate(secret *corev1.Secret) bool {
+	_, found := secret.Annotations[NameAnnotation]
+	return found
+}
+
+// generatedName returns the name of the Secret generated from a template
+func generatedName(template *corev1.Secret) string {
+	return template.Name + generatedSuffix
+}
+
+// parseGenerator returns the generator of the annotations of a template, or an error describing the first invalid
+// annotation
+func parseGenerator(annotations map[string]string) (*generator, error) {
+	g := &generator{
+		name:       annotations[NameAnnotation],
+		kind:       generatorType(annotations[TypeAnnotation]),
+		complexity: defaultComplexity,
+		user:       annotations[UserAnnotation],
+		rotate:     annotations[RotateAnnotation],
+	}
+	if g.name == "" {
+		return nil, fmt.Errorf("%v is required", NameAnnotation)
+	}
+	switch g.kind {
+	case "":
+		g.kind = randomType
+	case randomType, oauthType, htpasswdType:
+	default:
+		return nil, fmt.Errorf("%v must be random, oauth or htpasswd, got %q", TypeAnnotation, g.kind)
+	}
+	if value, found := annotations[ComplexityAnnotation]; found {
+		complexity, err := strconv.Atoi(value)
+		if err != nil || complexity < 8 || complexity > 128 {
+			return nil, fmt.Errorf("%v must be a number between 8 and 128, got %q", ComplexityAnnotation, value)
+		}
+		g.complexity = complexity
+	}
+	if g.user == "" {
+		g.user = defaultUser
+	}
+	if value, found := annotations[RotationIntervalAnnotation]; found {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < time.Hour {
+			return nil, fmt.Errorf("%v must be a duration of an hour or more, got %q", RotationIntervalAnnotation, value)
+		}
+		g.rotationInterval = interval
+	}
+	return g, nil
+}
+
+// generate returns the generated keys and values
+func (g *generator) generate() (map[string][]byte, error) {
+	switch g.kind {
+	case oauthType:
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		return map[string][]byte{g.name: []byte(base64.StdEncoding.EncodeToString(b))}, nil
+	case htpasswdType:
+		password, err := randomPassword(g.complexity)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{
+			g.name:                     []byte(g.user + ":" + string(hash)),
+			g.name + passwordKeySuffix: []byte(password),
+		}, nil
+	default:
+		password, err := randomPassword(g.complexity)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{g.name: []byte(password)}, nil
+	}
+}
+
+// generatedKeys returns the keys of the values generated by g
+func (g *generator) generatedKeys() []string {
+	if g.kind == htpasswdType {
+		return []string{g.name, g.name + passwordKeySuffix}
+	}
+	return []string{g.name}
+}
+
+// rotationDue returns true if the value of the generated Secret must be generated again, and otherwise the time
+// until its rotation, 0 if it is never rotated
+func (g *generator) rotationDue(generated *corev1.Secret, now time.Time) (bool, time.Duration) {
+	for _, key := range g.generatedKeys() {
+		if len(generated.Data[key]) == 0 {
+			return true, 0
+		}
+	}
+	if g.rotate != generated.Annotations[rotatedForAnnotation] {
+		return true, 0
+	}
+	if g.rotationInterval == 0 {
+		return false, 0
+	}
+	generatedAt, err := time.Parse(time.RFC3339, generated.Annotations[generatedAtAnnotation])
+	if err != nil {
+		return true, 0
+	}
+	remaining := generatedAt.Add(g.rotationInterval).Sub(now)
+	if remaining <= 0 {
+		return true, 0
+	}
+	return false, remaining
+}
+
+// randomPassword returns an alphanumeric password of the length
+func randomPassword(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(passwordChars)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+	return string(b), nil
+}
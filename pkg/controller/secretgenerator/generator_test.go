@@ -0,0 +1,129 @@
+package secretgenerator
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseGenerator(t *testing.T) {
+	type testCase struct {
+		Name        string
+		Annotations map[string]string
+		Valid       bool
+	}
+	testCases := []testCase{
+		{
+			Name:        "defaults",
+			Annotations: map[string]string{NameAnnotation: "password"},
+			Valid:       true,
+		},
+		{
+			Name: "htpasswd",
+			Annotations: map[string]string{
+				NameAnnotation:             "htpasswd",
+				TypeAnnotation:             "htpasswd",
+				ComplexityAnnotation:       "24",
+				UserAnnotation:             "internal",
+				RotationIntervalAnnotation: "720h",
+			},
+			Valid: true,
+		},
+		{
+			Name:        "no name",
+			Annotations: map[string]string{NameAnnotation: ""},
+		},
+		{
+			Name:        "invalid type",
+			Annotations: map[string]string{NameAnnotation: "password", TypeAnnotation: "uuid"},
+		},
+		{
+			Name:        "short password",
+			Annotations: map[string]string{NameAnnotation: "password", ComplexityAnnotation: "4"},
+		},
+		{
+			Name:        "frequent rotation",
+			Annotations: map[string]string{NameAnnotation: "password", RotationIntervalAnnotation: "5m"},
+		},
+	}
+
+	for _, c := range testCases {
+		_, err := parseGenerator(c.Annotations)
+		if c.Valid && err != nil {
+			t.Errorf("Case %v: expected the annotations to be valid, got %v", c.Name, err)
+		}
+		if !c.Valid && err == nil {
+			t.Errorf("Case %v: expected the annotations to be invalid", c.Name)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	random := &generator{name: "password", kind: randomType, complexity: 20}
+	values, err := random.generate()
+	if err != nil {
+		t.Fatalf("Failed to generate a password: %v", err)
+	}
+	if password := string(values["password"]); len(password) != 20 || strings.Trim(password, passwordChars) != "" {
+		t.Errorf("Expected an alphanumeric password of 20 characters, got %q", password)
+	}
+
+	oauth := &generator{name: "cookie_secret", kind: oauthType}
+	values, err = oauth.generate()
+	if err != nil {
+		t.Fatalf("Failed to generate a cookie secret: %v", err)
+	}
+	if secret, err := base64.StdEncoding.DecodeString(string(values["cookie_secret"])); err != nil || len(secret) != 32 {
+		t.Errorf("Expected 32 bytes encoded in base64, got %q", values["cookie_secret"])
+	}
+
+	htpasswd := &generator{name: "htpasswd", kind: htpasswdType, complexity: 16, user: "admin"}
+	values, err = htpasswd.generate()
+	if err != nil {
+		t.Fatalf("Failed to generate an htpasswd entry: %v", err)
+	}
+	parts := strings.SplitN(string(values["htpasswd"]), ":", 2)
+	if len(parts) != 2 || parts[0] != "admin" {
+		t.Fatalf("Expected an htpasswd entry of admin, got %q", values["htpasswd"])
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(parts[1]), values["htpasswd-password"]); err != nil {
+		t.Errorf("Expected the entry to match the password: %v", err)
+	}
+}
+
+func TestRotationDue(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := &generator{name: "password", kind: randomType, rotationInterval: 24 * time.Hour}
+	generated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			generatedAtAnnotation: now.Add(-time.Hour).Format(time.RFC3339),
+		}},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	if due, remaining := g.rotationDue(generated, now); due || remaining != 23*time.Hour {
+		t.Errorf("Expected a rotation in 23h, got %v %v", due, remaining)
+	}
+	if due, _ := g.rotationDue(generated, now.Add(24*time.Hour)); !due {
+		t.Errorf("Expected the rotation to be due once the interval elapsed")
+	}
+
+	g.rotate = "2023-06-01"
+	if due, _ := g.rotationDue(generated, now); !due {
+		t.Errorf("Expected a rotation once the rotate annotation changes")
+	}
+	generated.Annotations[rotatedForAnnotation] = "2023-06-01"
+	g.rotationInterval = 0
+	if due, remaining := g.rotationDue(generated, now.Add(1000*time.Hour)); due || remaining != 0 {
+		t.Errorf("Expected no rotation without interval, got %v %v", due, remaining)
+	}
+
+	delete(generated.Data, "password")
+	if due, _ := g.rotationDue(generated, now); !due {
+		t.Errorf("Expected a missing value to be generated")
+	}
+}
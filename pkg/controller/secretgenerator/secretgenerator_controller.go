@@ -0,0 +1,185 @@
+package secretgenerator
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddToManager adds the secret generator controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("secretgenerator-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileSecretGenerator{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("secretgenerator-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for secretgenerator.")
+	c, err := controller.New("secretgenerator-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the templates of the generated Secrets
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{}, templatePredicates)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the generated Secrets and requeue their template, so that the Secrets edited or
+	// deleted by hand are restored
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &corev1.Secret{},
+	}, kfutils.ManagedPredicate)
+}
+
+var templatePredicates = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return isTemplateObject(e.Object)
+	},
+	DeleteFunc: func(_ event.DeleteEvent) bool {
+		return false
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return isTemplateObject(e.ObjectNew)
+	},
+}
+
+// isTemplateObject returns true if obj is the template of a generated Secret
+func isTemplateObject(obj runtime.Object) bool {
+	secret, ok := obj.(*corev1.Secret)
+	return ok && isTemplate(secret)
+}
+
+// blank assignment to verify that ReconcileSecretGenerator implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileSecretGenerator{}
+
+// ReconcileSecretGenerator reconciles the Secret templates of the manifests by generating their Secrets
+type ReconcileSecretGenerator struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile creates the <template>-generated Secret of a Secret template of the manifests, holding the data of the
+// template along with the value generated as set by its annotations. The value is kept until it is rotated. The
+// generated Secret is owned by the template, and deleted along with the application of the template.
+func (r *ReconcileSecretGenerator) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	template := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, template)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !isTemplate(template) || template.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+	log.Infof("Reconciling Secret template. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	// Invalid templates are not retried, they are reconciled again once they are fixed
+	g, err := parseGenerator(template.Annotations)
+	if err != nil {
+		r.recorder.Eventf(template, corev1.EventTypeWarning, "InvalidTemplate", "%v", err)
+		return reconcile.Result{}, nil
+	}
+
+	generated := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: template.Namespace, Name: generatedName(template)}
+	err = r.client.Get(context.TODO(), key, generated)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if errors.IsNotFound(err) {
+		generated = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: kfutils.WithManagedLabel(nil)},
+			Type:       template.Type,
+		}
+	}
+
+	now := time.Now()
+	due, remaining := g.rotationDue(generated, now)
+	data := map[string][]byte{}
+	for k, v := range template.Data {
+		data[k] = v
+	}
+	if due {
+		values, err := g.generate()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		for k, v := range values {
+			data[k] = v
+		}
+		if g.rotationInterval > 0 {
+			remaining = g.rotationInterval
+		}
+	} else {
+		for _, k := range g.generatedKeys() {
+			data[k] = generated.Data[k]
+		}
+	}
+	if !due && reflect.DeepEqual(data, generated.Data) && metav1.IsControlledBy(generated, template) &&
+		kfutils.IsManaged(generated) {
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
+	generated.Data = data
+	generated.Labels = kfutils.WithManagedLabel(generated.Labels)
+	if due {
+		if generated.Annotations == nil {
+			generated.Annotations = map[string]string{}
+		}
+		generated.Annotations[generatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+		generated.Annotations[rotatedForAnnotation] = g.rotate
+	}
+	if err := controllerutil.SetControllerReference(template, generated, r.scheme); err != nil {
+		return reconcile.Result{}, err
+	}
+	if generated.CreationTimestamp.IsZero() {
+		log.Infof("Generating Secret %v.", key)
+		err = r.client.Create(context.TODO(), generated)
+	} else {
+		if due {
+			log.Infof("Rotating Secret %v.", key)
+			r.recorder.Eventf(template, corev1.EventTypeNormal, "SecretRotated", "Secret %v generated again", key.Name)
+		}
+		err = r.client.Update(context.TODO(), generated)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: remaining}, nil
+}
@@ -0,0 +1,43 @@
+package secretgenerator
+
+import (
+	"context"
+	"testing"
+
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestGeneratedSecretPassesPredicate(t *testing.T) {
+	template := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "oauth-config",
+		Namespace:   "opendatahub",
+		UID:         "uid",
+		Annotations: map[string]string{NameAnnotation: "secret"},
+	}}
+	r := &ReconcileSecretGenerator{
+		client:   fake.NewFakeClientWithScheme(scheme.Scheme, template),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	key := types.NamespacedName{Name: template.Name, Namespace: template.Namespace}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile(%v) failed: %v", key, err)
+	}
+
+	generated := &corev1.Secret{}
+	key.Name = generatedName(template)
+	if err := r.client.Get(context.TODO(), key, generated); err != nil {
+		t.Fatalf("Expected Secret %v to be generated: %v", key, err)
+	}
+	if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: generated, Object: generated}) {
+		t.Errorf("Expected the events of the generated Secret to pass the predicate of the watches, got labels %v", generated.Labels)
+	}
+}
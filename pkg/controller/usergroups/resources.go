@@ -0,0 +1,156 @@
+package usergroups
+
+import (
+	"sort"
+	"strings"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ownerLabel is the label of the RBAC resources synchronized for a DataScienceCluster, <namespace>.<name>.
+	// The cluster-scoped resources cannot be owned by the namespaced DataScienceCluster.
+	ownerLabel = "opendatahub.io/group-sync"
+	// dataScienceProjectLabel is the label of the namespaces of the data science projects
+	dataScienceProjectLabel = "opendatahub.io/dashboard"
+	// aggregateToAdminLabel adds the rules of a ClusterRole to the admin ClusterRole
+	aggregateToAdminLabel = "rbac.authorization.k8s.io/aggregate-to-admin"
+
+	adminsName       = "odh-admins"
+	usersName        = "odh-users"
+	adminRole        = "odh-admin"
+	userRole         = "odh-user"
+	projectAdminRole = "odh-project-admin"
+	namespaceAdmin   = "admin"
+)
+
+var (
+	// DefaultAdminGroups administer the Open Data Hub when the DataScienceCluster sets no admin groups
+	DefaultAdminGroups = []string{"odh-admins"}
+	// DefaultUserGroups use the Open Data Hub when the DataScienceCluster sets no user groups
+	DefaultUserGroups = []string{"system:authenticated"}
+
+	readVerbs = []string{"get", "list", "watch"}
+	allVerbs  = []string{"*"}
+)
+
+// owner returns the value of the owner label of the resources of a DataScienceCluster
+func owner(instance *dscv1alpha1.DataScienceCluster) string {
+	return instance.Namespace + "." + instance.Name
+}
+
+// ownerKey returns the DataScienceCluster of the value of an owner label. The name of a namespace has no dot.
+func ownerKey(value string) (types.NamespacedName, bool) {
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+// adminGroups and userGroups return the sorted groups of the DataScienceCluster, or the default ones
+func adminGroups(groups *dscv1alpha1.Groups) []string {
+	return sortedGroups(groups.AdminGroups, DefaultAdminGroups)
+}
+
+func userGroups(groups *dscv1alpha1.Groups) []string {
+	return sortedGroups(groups.UserGroups, DefaultUserGroups)
+}
+
+// sortedGroups returns the sorted groups without duplicates nor empty names, or the defaults if there is none
+func sortedGroups(groups []string, defaults []string) []string {
+	set := map[string]bool{}
+	for _, g := range groups {
+		if g != "" {
+			set[g] = true
+		}
+	}
+	if len(set) == 0 {
+		for _, g := range defaults {
+			set[g] = true
+		}
+	}
+	sorted := []string{}
+	for g := range set {
+		sorted = append(sorted, g)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// subjects returns the subjects of the groups
+func subjects(groups []string) []rbacv1.Subject {
+	result := []rbacv1.Subject{}
+	for _, g := range groups {
+		result = append(result, rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: g})
+	}
+	return result
+}
+
+// objectMeta returns the metadata of a resource of a DataScienceCluster
+func objectMeta(instance *dscv1alpha1.DataScienceCluster, namespace, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: kfutils.WithManagedLabel(map[string]string{ownerLabel: owner(instance)})}
+}
+
+// clusterRoles returns the ClusterRoles of the admins and the users of the dashboard, and the rules on the
+// notebooks and the pipelines aggregated to the admin ClusterRole bound in the managed namespaces
+func clusterRoles(instance *dscv1alpha1.DataScienceCluster) []*rbacv1.ClusterRole {
+	projectAdmin := &rbacv1.ClusterRole{
+		ObjectMeta: objectMeta(instance, "", projectAdminRole),
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{"kubeflow.org"}, Resources: []string{"notebooks"}, Verbs: allVerbs},
+			{APIGroups: []string{"pipelines.opendatahub.io"}, Resources: []string{"pipelineservers"}, Verbs: allVerbs},
+			{APIGroups: []string{"datasciencepipelinesapplications.opendatahub.io"},
+				Resources: []string{"datasciencepipelinesapplications"}, Verbs: allVerbs},
+		},
+	}
+	projectAdmin.Labels[aggregateToAdminLabel] = "true"
+	return []*rbacv1.ClusterRole{
+		{
+			ObjectMeta: objectMeta(instance, "", adminRole),
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"dashboard.opendatahub.io"}, Resources: []string{"odhdashboardconfigs"}, Verbs: allVerbs},
+				{APIGroups: []string{"accelerator.opendatahub.io"}, Resources: []string{"acceleratorprofiles"}, Verbs: allVerbs},
+				{APIGroups: []string{"datascienceproject.opendatahub.io"}, Resources: []string{"datascienceprojects"}, Verbs: allVerbs},
+				{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: readVerbs},
+			},
+		},
+		{
+			ObjectMeta: objectMeta(instance, "", userRole),
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"dashboard.opendatahub.io"}, Resources: []string{"odhdashboardconfigs"}, Verbs: readVerbs},
+				{APIGroups: []string{"accelerator.opendatahub.io"}, Resources: []string{"acceleratorprofiles"}, Verbs: readVerbs},
+			},
+		},
+		projectAdmin,
+	}
+}
+
+// clusterRoleBindings returns the ClusterRoleBindings of the admin and the user groups of a DataScienceCluster
+func clusterRoleBindings(instance *dscv1alpha1.DataScienceCluster) []*rbacv1.ClusterRoleBinding {
+	return []*rbacv1.ClusterRoleBinding{
+		{
+			ObjectMeta: objectMeta(instance, "", adminsName),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: adminRole},
+			Subjects:   subjects(adminGroups(&instance.Spec.Groups)),
+		},
+		{
+			ObjectMeta: objectMeta(instance, "", usersName),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: userRole},
+			Subjects:   subjects(userGroups(&instance.Spec.Groups)),
+		},
+	}
+}
+
+// roleBinding returns the RoleBinding of the admin groups of a DataScienceCluster in a managed namespace
+func roleBinding(instance *dscv1alpha1.DataScienceCluster, namespace string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: objectMeta(instance, namespace, adminsName),
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: namespaceAdmin},
+		Subjects:   subjects(adminGroups(&instance.Spec.Groups)),
+	}
+}
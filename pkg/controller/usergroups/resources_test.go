@@ -0,0 +1,110 @@
+package usergroups
+
+import (
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestGroups(t *testing.T) {
+	type testCase struct {
+		Name   string
+		Groups dscv1alpha1.Groups
+		Admins []string
+		Users  []string
+	}
+	testCases := []testCase{
+		{
+			Name:   "defaults",
+			Admins: DefaultAdminGroups,
+			Users:  DefaultUserGroups,
+		},
+		{
+			Name: "configured",
+			Groups: dscv1alpha1.Groups{
+				AdminGroups: []string{"platform-team", "odh-admins", "platform-team"},
+				UserGroups:  []string{"data-scientists", ""},
+			},
+			Admins: []string{"odh-admins", "platform-team"},
+			Users:  []string{"data-scientists"},
+		},
+		{
+			Name:   "empty names",
+			Groups: dscv1alpha1.Groups{AdminGroups: []string{""}},
+			Admins: DefaultAdminGroups,
+			Users:  DefaultUserGroups,
+		},
+	}
+
+	for _, c := range testCases {
+		if admins := adminGroups(&c.Groups); !reflect.DeepEqual(admins, c.Admins) {
+			t.Errorf("Case %v: expected admin groups %v, got %v", c.Name, c.Admins, admins)
+		}
+		if users := userGroups(&c.Groups); !reflect.DeepEqual(users, c.Users) {
+			t.Errorf("Case %v: expected user groups %v, got %v", c.Name, c.Users, users)
+		}
+	}
+}
+
+func TestOwnerKey(t *testing.T) {
+	instance := &dscv1alpha1.DataScienceCluster{ObjectMeta: metav1.ObjectMeta{Name: "default.dsc", Namespace: "opendatahub"}}
+	key, ok := ownerKey(owner(instance))
+	if !ok || key != (types.NamespacedName{Namespace: "opendatahub", Name: "default.dsc"}) {
+		t.Errorf("Expected the DataScienceCluster of the owner label, got %v", key)
+	}
+	if _, ok := ownerKey("opendatahub"); ok {
+		t.Errorf("Expected an invalid owner label to be ignored")
+	}
+}
+
+func TestBindings(t *testing.T) {
+	instance := &dscv1alpha1.DataScienceCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "opendatahub"},
+		Spec:       dscv1alpha1.DataScienceClusterSpec{Groups: dscv1alpha1.Groups{AdminGroups: []string{"platform-team"}}},
+	}
+	admins := []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "platform-team"}}
+
+	binding := roleBinding(instance, "fraud-detection")
+	if binding.Namespace != "fraud-detection" || binding.RoleRef.Name != "admin" || !reflect.DeepEqual(binding.Subjects, admins) {
+		t.Errorf("Expected the admin groups to administer the namespace, got %v", binding)
+	}
+	if binding.Labels[ownerLabel] != "opendatahub.default" {
+		t.Errorf("Expected the binding to be labeled with its DataScienceCluster, got %v", binding.Labels)
+	}
+	for _, b := range clusterRoleBindings(instance) {
+		if b.Name == adminsName && !reflect.DeepEqual(b.Subjects, admins) {
+			t.Errorf("Expected the admin groups to be bound to %v, got %v", adminRole, b.Subjects)
+		}
+		if b.Name == usersName && b.Subjects[0].Name != "system:authenticated" {
+			t.Errorf("Expected all the users to be bound to %v, got %v", userRole, b.Subjects)
+		}
+	}
+	for _, role := range clusterRoles(instance) {
+		aggregated := role.Labels[aggregateToAdminLabel] == "true"
+		if aggregated != (role.Name == projectAdminRole) {
+			t.Errorf("Expected only %v to be aggregated to admin, got %v", projectAdminRole, role.Name)
+		}
+	}
+}
+
+func TestResourcesPassPredicate(t *testing.T) {
+	instance := &dscv1alpha1.DataScienceCluster{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "opendatahub"}}
+	objects := []metav1.Object{roleBinding(instance, "fraud-detection")}
+	for _, role := range clusterRoles(instance) {
+		objects = append(objects, role)
+	}
+	for _, binding := range clusterRoleBindings(instance) {
+		objects = append(objects, binding)
+	}
+	for _, obj := range objects {
+		if !kfutils.ManagedPredicate.Create(event.CreateEvent{Meta: obj}) {
+			t.Errorf("Expected the events of %v to pass the predicate of the watches, got labels %v", obj.GetName(), obj.GetLabels())
+		}
+	}
+}
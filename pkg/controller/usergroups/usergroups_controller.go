@@ -0,0 +1,269 @@
+package usergroups
+
+import (
+	"context"
+	"fmt"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/ratelimiter"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddToManager adds the user groups controller to the Manager
+func AddToManager(m manager.Manager, options controller.Options) error {
+	options.Reconciler = ratelimiter.Wrap("usergroups-controller", newReconciler(m))
+	return add(m, options)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileUserGroups{
+		client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor("usergroups-controller")}
+}
+
+// add adds a new Controller to mgr with options.Reconciler as the reconcile.Reconciler
+func add(mgr manager.Manager, options controller.Options) error {
+	log.Infof("Adding controller for usergroups.")
+	c, err := controller.New("usergroups-controller", mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the groups of the DataScienceClusters
+	err = c.Watch(&source.Kind{Type: &dscv1alpha1.DataScienceCluster{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the synchronized resources and requeue the DataScienceCluster of their owner label, so
+	// that the resources edited or deleted by hand are restored
+	ownerRequests := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			key, ok := ownerKey(a.Meta.GetLabels()[ownerLabel])
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: key}}
+		}),
+	}
+	for _, t := range []runtime.Object{&rbacv1.ClusterRole{}, &rbacv1.ClusterRoleBinding{}, &rbacv1.RoleBinding{}} {
+		if err := c.Watch(&source.Kind{Type: t}, ownerRequests, kfutils.ManagedPredicate); err != nil {
+			return err
+		}
+	}
+
+	// Watch for the data science projects and requeue all the DataScienceClusters, binding their admin groups
+	kubeclient := mgr.GetClient()
+	return c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			if a.Meta.GetLabels()[dataScienceProjectLabel] != "true" {
+				return nil
+			}
+			instances := &dscv1alpha1.DataScienceClusterList{}
+			if err := kubeclient.List(context.TODO(), instances); err != nil {
+				log.Warnf("Failed to list the DataScienceClusters for namespace %v: %v", a.Meta.GetName(), err)
+				return nil
+			}
+			requests := []reconcile.Request{}
+			for _, instance := range instances.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: instance.Namespace,
+					Name:      instance.Name,
+				}})
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileUserGroups implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileUserGroups{}
+
+// ReconcileUserGroups reconciles the groups of a DataScienceCluster by synchronizing them into RBAC resources
+type ReconcileUserGroups struct {
+	client client.Client
+	// recorder to generate events
+	recorder record.EventRecorder
+}
+
+// Reconcile binds the admin and the user groups of a DataScienceCluster to the ClusterRoles of the dashboard, and
+// the admin groups to the admin ClusterRole in the namespace of the DataScienceCluster and in every data science
+// project. The resources are labeled with the DataScienceCluster and deleted along with it.
+func (r *ReconcileUserGroups) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Reconciling user groups. Request.Namespace: %v, Request.Name: %v.", request.Namespace, request.Name)
+
+	instance := &dscv1alpha1.DataScienceCluster{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if errors.IsNotFound(err) || instance.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, r.removeAll(request.Namespace + "." + request.Name)
+	}
+
+	if err := r.sync(instance); err != nil {
+		log.Errorf("Failed to synchronize the groups of DataScienceCluster %v: %v", request.NamespacedName, err)
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "GroupSyncFailed", "%v", err)
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// sync creates or updates the RBAC resources of the groups of instance, and deletes the RoleBindings of the
+// namespaces that are no longer managed
+func (r *ReconcileUserGroups) sync(instance *dscv1alpha1.DataScienceCluster) error {
+	for _, desired := range clusterRoles(instance) {
+		role := &rbacv1.ClusterRole{}
+		role.Name = desired.Name
+		err := r.createOrUpdate(role, func() error {
+			role.Labels, role.Rules = desired.Labels, desired.Rules
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, desired := range clusterRoleBindings(instance) {
+		if err := r.applyClusterRoleBinding(desired); err != nil {
+			return err
+		}
+	}
+
+	namespaces := map[string]bool{instance.Namespace: true}
+	projects := &corev1.NamespaceList{}
+	if err := r.client.List(context.TODO(), projects, client.MatchingLabels{dataScienceProjectLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the data science projects: %v", err)
+	}
+	for _, ns := range projects.Items {
+		if ns.GetDeletionTimestamp() == nil {
+			namespaces[ns.Name] = true
+		}
+	}
+	for ns := range namespaces {
+		desired := roleBinding(instance, ns)
+		if err := r.applyRoleBinding(desired); err != nil {
+			return err
+		}
+	}
+
+	bindings := &rbacv1.RoleBindingList{}
+	if err := r.client.List(context.TODO(), bindings, client.MatchingLabels{ownerLabel: owner(instance)}); err != nil {
+		return fmt.Errorf("failed to list the RoleBindings of the groups: %v", err)
+	}
+	for i := range bindings.Items {
+		if !namespaces[bindings.Items[i].Namespace] {
+			if err := r.delete(&bindings.Items[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyClusterRoleBinding creates or updates the desired ClusterRoleBinding. A binding to another role is
+// recreated since the role of a binding is immutable.
+func (r *ReconcileUserGroups) applyClusterRoleBinding(desired *rbacv1.ClusterRoleBinding) error {
+	binding := &rbacv1.ClusterRoleBinding{}
+	err := r.client.Get(context.TODO(), client.ObjectKey{Name: desired.Name}, binding)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get ClusterRoleBinding %v: %v", desired.Name, err)
+	}
+	if err == nil && binding.RoleRef != desired.RoleRef {
+		if err := r.delete(binding); err != nil {
+			return err
+		}
+	}
+	binding = &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: desired.Name}}
+	return r.createOrUpdate(binding, func() error {
+		binding.Labels, binding.Subjects = desired.Labels, desired.Subjects
+		if binding.CreationTimestamp.IsZero() {
+			binding.RoleRef = desired.RoleRef
+		}
+		return nil
+	})
+}
+
+// applyRoleBinding creates or updates the desired RoleBinding. A binding to another role is recreated since the
+// role of a binding is immutable.
+func (r *ReconcileUserGroups) applyRoleBinding(desired *rbacv1.RoleBinding) error {
+	key := client.ObjectKey{Namespace: desired.Namespace, Name: desired.Name}
+	binding := &rbacv1.RoleBinding{}
+	err := r.client.Get(context.TODO(), key, binding)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get RoleBinding %v: %v", key, err)
+	}
+	if err == nil && binding.RoleRef != desired.RoleRef {
+		if err := r.delete(binding); err != nil {
+			return err
+		}
+	}
+	binding = &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	return r.createOrUpdate(binding, func() error {
+		binding.Labels, binding.Subjects = desired.Labels, desired.Subjects
+		if binding.CreationTimestamp.IsZero() {
+			binding.RoleRef = desired.RoleRef
+		}
+		return nil
+	})
+}
+
+// removeAll deletes the RBAC resources synchronized for the DataScienceCluster of the owner label value
+func (r *ReconcileUserGroups) removeAll(owner string) error {
+	selector := client.MatchingLabels{ownerLabel: owner}
+	lists := []runtime.Object{&rbacv1.RoleBindingList{}, &rbacv1.ClusterRoleBindingList{}, &rbacv1.ClusterRoleList{}}
+	for _, list := range lists {
+		if err := r.client.List(context.TODO(), list, selector); err != nil {
+			return fmt.Errorf("failed to list the %T of the groups of DataScienceCluster %v: %v", list, owner, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := r.delete(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createOrUpdate creates obj, or updates the live object, after mutating it with mutate
+func (r *ReconcileUserGroups) createOrUpdate(obj runtime.Object, mutate func() error) error {
+	result, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, obj, mutate)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize %T %v: %v", obj, obj.(metav1.Object).GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		o := obj.(metav1.Object)
+		log.Infof("%T %v of the user groups %v.", obj, types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}, result)
+	}
+	return nil
+}
+
+// delete deletes obj if it exists
+func (r *ReconcileUserGroups) delete(obj runtime.Object) error {
+	o := obj.(metav1.Object)
+	if err := r.client.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %T %v: %v", obj, o.GetName(), err)
+	}
+	log.Infof("Deleted %T %v.", obj, types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()})
+	return nil
+}
@@ -0,0 +1,350 @@
+// Package cosign verifies the cosign signatures of manifest tarballs and OCI artifacts
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureAnnotation holds the base64 signature of the layers of the signature artifacts
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// CertificateAnnotation holds the PEM certificate of the keyless signatures of the signature artifacts
+	CertificateAnnotation = "dev.sigstore.cosign/certificate"
+	// ChainAnnotation holds the PEM intermediate certificates of the keyless signatures of the signature artifacts
+	ChainAnnotation = "dev.sigstore.cosign/chain"
+	// BundleAnnotation holds the Rekor bundle of the keyless signatures of the signature artifacts
+	BundleAnnotation = "dev.sigstore.cosign/bundle"
+	// SimpleSigningMediaType is the media type of the signed payloads of the signature artifacts
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+var (
+	// oidcIssuerV1 is the extension of the Fulcio certificates holding the OIDC issuer as a raw string
+	oidcIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidcIssuerV2 is the extension of the Fulcio certificates holding the OIDC issuer as a DER UTF8String
+	oidcIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Verifier checks signatures made with a key pair, or keyless signatures made with a certificate issued by
+// Fulcio to an identity and recorded in the Rekor transparency log.
+type Verifier struct {
+	publicKey crypto.PublicKey
+	identity  string
+	issuer    string
+	roots     *x509.CertPool
+	rekorKey  crypto.PublicKey
+}
+
+// rekorBundle is the proof that a signature was recorded in the Rekor transparency log: the entry of the log
+// with the time it was integrated, and the signed entry timestamp of Rekor over them
+type rekorBundle struct {
+	SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+	Payload              struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogIndex       int64  `json:"logIndex"`
+		LogID          string `json:"logID"`
+	} `json:"Payload"`
+}
+
+// hashedRekord is the body of the entries of the Rekor transparency log recording a signature
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// parsePublicKey parses a PEM public key
+func parsePublicKey(publicKey []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// NewKeyVerifier returns a Verifier of the signatures made with the private key of the PEM publicKey,
+// as generated by cosign generate-key-pair
+func NewKeyVerifier(publicKey []byte) (*Verifier, error) {
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	return &Verifier{publicKey: key}, nil
+}
+
+// NewKeylessVerifier returns a Verifier of the keyless signatures made with a certificate issued to identity,
+// an email or URI, by the OIDC issuer. The certificates must chain up to the PEM roots, e.g. the Fulcio root CA,
+// and the signatures must have been recorded in the transparency log signing its entries with the PEM rekorKey.
+func NewKeylessVerifier(identity string, issuer string, roots []byte, rekorKey []byte) (*Verifier, error) {
+	if identity == "" || issuer == "" {
+		return nil, fmt.Errorf("both the identity and the issuer of keyless signatures must be set")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(roots) {
+		return nil, fmt.Errorf("no root certificate found to verify the keyless signatures")
+	}
+	if len(rekorKey) == 0 {
+		return nil, fmt.Errorf("no transparency log public key found to verify the keyless signatures")
+	}
+	key, err := parsePublicKey(rekorKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transparency log public key: %v", err)
+	}
+	return &Verifier{identity: identity, issuer: issuer, roots: pool, rekorKey: key}, nil
+}
+
+// VerifyBlob checks the base64 signature of data, as made by cosign sign-blob. The PEM certificate, the
+// PEM intermediate certificates of chain and the Rekor bundle are required by keyless signatures.
+func (v *Verifier) VerifyBlob(data []byte, signature []byte, certificate []byte, chain []byte, bundle []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	key := v.publicKey
+	if key == nil {
+		cert, err := v.verifyKeyless(data, sig, certificate, chain, bundle)
+		if err != nil {
+			return err
+		}
+		key = cert.PublicKey
+	}
+	return verifySignature(key, data, sig)
+}
+
+// VerifyArtifact checks the signature of a simple signing payload of a signature artifact, as pushed by cosign sign,
+// and that the payload signs the manifest digest of the artifact.
+func (v *Verifier) VerifyArtifact(payload []byte, signature []byte, certificate []byte, chain []byte, bundle []byte,
+	digest string) error {
+	if err := v.VerifyBlob(payload, signature, certificate, chain, bundle); err != nil {
+		return err
+	}
+	simpleSigning := struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}{}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return fmt.Errorf("invalid signed payload: %v", err)
+	}
+	if simpleSigning.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("the signature is for %v, not %v", simpleSigning.Critical.Image.DockerManifestDigest, digest)
+	}
+	return nil
+}
+
+// verifyKeyless checks that the certificate of the keyless signature sig of data chains up to the roots and was
+// issued to the identity by the issuer, and returns it. The certificates of Fulcio are only valid for a few minutes:
+// the bundle must prove that the signature was recorded in the transparency log while the certificate was valid,
+// and the certificate is checked as of that time.
+func (v *Verifier) verifyKeyless(data []byte, sig []byte, certificate []byte, chain []byte, bundle []byte) (*x509.Certificate, error) {
+	if len(certificate) == 0 {
+		return nil, fmt.Errorf("keyless signatures require a certificate")
+	}
+	certs, err := parseCertificates(certificate)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("invalid certificate: %v", err)
+	}
+	cert := certs[0]
+	intermediates := x509.NewCertPool()
+	chainCerts, err := parseCertificates(chain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate chain: %v", err)
+	}
+	for _, c := range append(certs[1:], chainCerts...) {
+		intermediates.AddCert(c)
+	}
+	signedAt, err := v.verifyBundle(bundle, data, sig, cert)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("untrusted certificate: %v", err)
+	}
+
+	identities := append([]string{}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	found := false
+	for _, identity := range identities {
+		if identity == v.identity {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("the certificate was issued to %v, not %v", strings.Join(identities, ", "), v.identity)
+	}
+	if issuer := certificateIssuer(cert); issuer != v.issuer {
+		return nil, fmt.Errorf("the certificate was issued by %q, not %v", issuer, v.issuer)
+	}
+	return cert, nil
+}
+
+// verifyBundle checks that the Rekor bundle proves that the signature sig of data, made with the certificate cert,
+// was recorded in the transparency log while cert was valid, and returns the time it was recorded
+func (v *Verifier) verifyBundle(data []byte, signed []byte, sig []byte, cert *x509.Certificate) (time.Time, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return time.Time{}, fmt.Errorf("keyless signatures require a transparency log bundle")
+	}
+	// The bundles written by cosign sign-blob --bundle hold the Rekor bundle along with the signature
+	file := struct {
+		RekorBundle *rekorBundle `json:"rekorBundle"`
+	}{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log bundle: %v", err)
+	}
+	bundle := file.RekorBundle
+	if bundle == nil {
+		bundle = &rekorBundle{}
+		if err := json.Unmarshal(data, bundle); err != nil {
+			return time.Time{}, fmt.Errorf("invalid transparency log bundle: %v", err)
+		}
+	}
+
+	// The signed entry timestamp signs the canonical JSON of the payload, whose keys are sorted by json.Marshal
+	payload, err := json.Marshal(map[string]interface{}{
+		"body":           bundle.Payload.Body,
+		"integratedTime": bundle.Payload.IntegratedTime,
+		"logID":          bundle.Payload.LogID,
+		"logIndex":       bundle.Payload.LogIndex,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifySignature(v.rekorKey, payload, bundle.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp of the transparency log bundle: %v", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log entry: %v", err)
+	}
+	entry := &hashedRekord{}
+	if err := json.Unmarshal(body, entry); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log entry: %v", err)
+	}
+	digest := sha256.Sum256(signed)
+	if entry.Kind != "hashedrekord" || entry.Spec.Data.Hash.Algorithm != "sha256" ||
+		entry.Spec.Data.Hash.Value != hex.EncodeToString(digest[:]) {
+		return time.Time{}, fmt.Errorf("the transparency log entry does not record the signed data")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, sig) {
+		return time.Time{}, fmt.Errorf("the transparency log entry does not record the signature")
+	}
+	entryCerts, err := parseCertificates(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || len(entryCerts) == 0 || !entryCerts[0].Equal(cert) {
+		return time.Time{}, fmt.Errorf("the transparency log entry does not record the certificate of the signature")
+	}
+
+	signedAt := time.Unix(bundle.Payload.IntegratedTime, 0)
+	if signedAt.Before(cert.NotBefore) || signedAt.After(cert.NotAfter) {
+		return time.Time{}, fmt.Errorf("the signature was recorded on %v, outside of the validity of its certificate, "+
+			"from %v to %v", signedAt.UTC(), cert.NotBefore.UTC(), cert.NotAfter.UTC())
+	}
+	return signedAt, nil
+}
+
+// certificateIssuer returns the OIDC issuer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidcIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidcIssuerV1) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// parseCertificates parses PEM certificates, which may be base64 encoded as written by cosign sign-blob
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) > 0 && !strings.HasPrefix(string(data), "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// verifySignature checks the signature of data with the ECDSA, RSA or ed25519 key
+func verifySignature(key crypto.PublicKey, data []byte, sig []byte) error {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("invalid signature: %v", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+// SignatureTag returns the tag of the signature artifact of the artifact with manifest digest, e.g.
+// sha256-<hex>.sig, as pushed by cosign sign
+func SignatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
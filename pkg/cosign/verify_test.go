@@ -0,0 +1,214 @@
+package cosign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+// bundle returns the Rekor bundle, signed with rekorKey, of the entry recording at integratedTime the base64
+// signature of data made with the PEM certificate
+func bundle(t *testing.T, rekorKey *ecdsa.PrivateKey, data []byte, signature []byte, certificate []byte,
+	integratedTime time.Time) []byte {
+	digest := sha256.Sum256(data)
+	entry := map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data":      map[string]interface{}{"hash": map[string]interface{}{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])}},
+			"signature": map[string]interface{}{"content": string(signature), "publicKey": map[string]interface{}{"content": string(certificate)}},
+		},
+	}
+	body, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := map[string]interface{}{
+		"body":           base64.StdEncoding.EncodeToString(body),
+		"integratedTime": integratedTime.Unix(),
+		"logID":          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		"logIndex":       42,
+	}
+	canonical, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := base64.StdEncoding.DecodeString(string(sign(t, rekorKey, canonical)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]interface{}{"SignedEntryTimestamp": set, "Payload": payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestKeyVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewKeyVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tarball := []byte("manifests")
+	if err := v.VerifyBlob(tarball, sign(t, key, tarball), nil, nil, nil); err != nil {
+		t.Errorf("Expected the signature to be valid: %v", err)
+	}
+	if err := v.VerifyBlob([]byte("tampered"), sign(t, key, tarball), nil, nil, nil); err == nil {
+		t.Errorf("Expected the signature of tampered data to be invalid")
+	}
+
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"quay.io/opendatahub/odh-manifests"},` +
+		`"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"},"optional":null}`)
+	if err := v.VerifyArtifact(payload, sign(t, key, payload), nil, nil, nil, digest); err != nil {
+		t.Errorf("Expected the artifact signature to be valid: %v", err)
+	}
+	if err := v.VerifyArtifact(payload, sign(t, key, payload), nil, nil, nil, "sha256:fedcba"); err == nil {
+		t.Errorf("Expected the signature of another artifact to be invalid")
+	}
+
+	if _, err := NewKeyVerifier([]byte("not a key")); err == nil {
+		t.Errorf("Expected an invalid public key to be rejected")
+	}
+}
+
+func TestKeylessVerifier(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+	roots := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+
+	// The signing certificates are only valid for a few minutes, they have expired by the time they are verified
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, _ := asn1.MarshalWithParams("https://github.com/login/oauth", "utf8")
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-30 * time.Minute),
+		NotAfter:        time.Now().Add(-20 * time.Minute),
+		EmailAddresses:  []string{"release@opendatahub.io"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidcIssuerV2, Value: issuer}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := []byte(base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})))
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorDER, err := x509.MarshalPKIXPublicKey(&rekorKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPublicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorDER})
+
+	tarball := []byte("manifests")
+	signature := sign(t, key, tarball)
+	signedAt := leaf.NotBefore.Add(time.Minute)
+	type testCase struct {
+		Name     string
+		Identity string
+		Issuer   string
+		// Signature is the signature verified, Bundle its Rekor bundle
+		Signature []byte
+		Bundle    []byte
+		Valid     bool
+	}
+	testCases := []testCase{
+		{Name: "valid", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth", Signature: signature,
+			Bundle: bundle(t, rekorKey, tarball, signature, certificate, signedAt), Valid: true},
+		{Name: "sign-blob bundle", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth",
+			Signature: signature, Bundle: []byte(`{"base64Signature":"` + string(signature) + `","rekorBundle":` +
+				string(bundle(t, rekorKey, tarball, signature, certificate, signedAt)) + "}"), Valid: true},
+		{Name: "other identity", Identity: "someone@example.com", Issuer: "https://github.com/login/oauth",
+			Signature: signature, Bundle: bundle(t, rekorKey, tarball, signature, certificate, signedAt)},
+		{Name: "other issuer", Identity: "release@opendatahub.io", Issuer: "https://accounts.google.com",
+			Signature: signature, Bundle: bundle(t, rekorKey, tarball, signature, certificate, signedAt)},
+		{Name: "no bundle", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth", Signature: signature},
+		{Name: "bundle of another log", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth",
+			Signature: signature, Bundle: bundle(t, key, tarball, signature, certificate, signedAt)},
+		// A signature made with the key of the certificate once it expired, e.g. leaked
+		{Name: "recorded after the expiry", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth",
+			Signature: signature, Bundle: bundle(t, rekorKey, tarball, signature, certificate, leaf.NotAfter.Add(time.Minute))},
+		{Name: "bundle of another signature", Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth",
+			Signature: signature, Bundle: bundle(t, rekorKey, []byte("other"), sign(t, key, []byte("other")), certificate, signedAt)},
+	}
+	for _, c := range testCases {
+		v, err := NewKeylessVerifier(c.Identity, c.Issuer, roots, rekorPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = v.VerifyBlob(tarball, c.Signature, certificate, nil, c.Bundle)
+		if c.Valid && err != nil {
+			t.Errorf("%v: expected the signature to be valid: %v", c.Name, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("%v: expected the signature to be invalid", c.Name)
+		}
+	}
+
+	v, _ := NewKeylessVerifier("release@opendatahub.io", "https://github.com/login/oauth", roots, rekorPublicKey)
+	validBundle := bundle(t, rekorKey, tarball, signature, certificate, signedAt)
+	if err := v.VerifyBlob(tarball, signature, nil, nil, validBundle); err == nil {
+		t.Errorf("Expected a keyless signature without certificate to be invalid")
+	}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherSignature := sign(t, other, tarball)
+	if err := v.VerifyBlob(tarball, otherSignature, certificate, nil,
+		bundle(t, rekorKey, tarball, otherSignature, certificate, signedAt)); err == nil {
+		t.Errorf("Expected a signature made with another key to be invalid")
+	}
+	if _, err := NewKeylessVerifier("release@opendatahub.io", "https://github.com/login/oauth", roots, nil); err == nil {
+		t.Errorf("Expected keyless signatures to require the public key of the transparency log")
+	}
+}
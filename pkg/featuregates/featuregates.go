@@ -0,0 +1,158 @@
+// Package featuregates toggles the experimental components and behaviors of the operator, so that they ship
+// disabled until they are ready and can be enabled per cluster, with the --feature-gates flag of the operator, or
+// per DataScienceCluster and KfDef instance, with their featureGates field.
+package featuregates
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature is the name of a feature gate
+type Feature string
+
+// Stage is the maturity of a feature
+type Stage string
+
+const (
+	// Alpha features are disabled by default, they may change or be removed in a later release
+	Alpha Stage = "Alpha"
+	// Beta features are enabled by default, they can still be disabled in case of trouble
+	Beta Stage = "Beta"
+)
+
+// FeatureSpec describes a feature gate
+type FeatureSpec struct {
+	Default     bool
+	Stage       Stage
+	Description string
+}
+
+const (
+	// KServeRawDeployment allows the rawDeployment mode of the KServe serving platform of the modelserving
+	// component, serving the models with Deployments instead of Knative services
+	KServeRawDeployment Feature = "KServeRawDeployment"
+	// AuthorinoAuth allows the Authorino auth provider of the KfDef instances
+	AuthorinoAuth Feature = "AuthorinoAuth"
+)
+
+// Features are the known feature gates
+var Features = map[Feature]FeatureSpec{
+	KServeRawDeployment: {
+		Default:     true,
+		Stage:       Beta,
+		Description: "serve the KServe models with Deployments when the modelserving component sets rawDeployment",
+	},
+	AuthorinoAuth: {
+		Default:     true,
+		Stage:       Beta,
+		Description: "protect the Routes of the KfDef instances whose auth is Authorino with Authorino AuthConfigs",
+	},
+}
+
+// FeatureGates holds the features enabled or disabled explicitly, the others are set to their default
+type FeatureGates struct {
+	mu      sync.RWMutex
+	enabled map[Feature]bool
+}
+
+// Gates are the feature gates of the operator, set with the --feature-gates flag
+var Gates = New()
+
+// New returns feature gates setting every feature to its default
+func New() *FeatureGates {
+	return &FeatureGates{enabled: map[Feature]bool{}}
+}
+
+// Enabled returns true if the feature is enabled
+func (g *FeatureGates) Enabled(f Feature) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if enabled, ok := g.enabled[f]; ok {
+		return enabled
+	}
+	return Features[f].Default
+}
+
+// With returns a copy of the feature gates overridden by the featureGates field of a custom resource, along with
+// the unknown features it sets, which are ignored so that the gates of removed features remain harmless.
+func (g *FeatureGates) With(overrides map[string]bool) (*FeatureGates, []string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	gates := New()
+	for f, enabled := range g.enabled {
+		gates.enabled[f] = enabled
+	}
+	unknown := []string{}
+	for name, enabled := range overrides {
+		if _, ok := Features[Feature(name)]; !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		gates.enabled[Feature(name)] = enabled
+	}
+	sort.Strings(unknown)
+	return gates, unknown
+}
+
+// Set parses a comma-separated list of <feature>=<true|false> pairs, implementing pflag.Value. Unknown features
+// are refused.
+func (g *FeatureGates) Set(value string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("missing the value of feature gate %v, must be <feature>=<true|false>", pair)
+		}
+		name := Feature(strings.TrimSpace(kv[0]))
+		if _, ok := Features[name]; !ok {
+			return fmt.Errorf("unknown feature gate %v", name)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("invalid value %v of feature gate %v, must be true or false", kv[1], name)
+		}
+		g.enabled[name] = enabled
+	}
+	return nil
+}
+
+// String returns the features enabled or disabled explicitly, implementing pflag.Value
+func (g *FeatureGates) String() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pairs := []string{}
+	for f, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", f, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Type implements pflag.Value
+func (g *FeatureGates) Type() string {
+	return "mapStringBool"
+}
+
+// Describe lists the known features, their stage and their default, one per line
+func Describe() string {
+	names := []string{}
+	for f := range Features {
+		names = append(names, string(f))
+	}
+	sort.Strings(names)
+	lines := []string{}
+	for _, name := range names {
+		spec := Features[Feature(name)]
+		lines = append(lines, fmt.Sprintf("%v=true|false (%v - default=%v): %v", name, spec.Stage, spec.Default, spec.Description))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,74 @@
+package featuregates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFeatureGates(t *testing.T) {
+	Features["TestAlpha"] = FeatureSpec{Default: false, Stage: Alpha}
+	defer delete(Features, "TestAlpha")
+
+	tests := []struct {
+		name      string
+		flag      string
+		overrides map[string]bool
+		wantErr   bool
+		want      map[Feature]bool
+		unknown   []string
+	}{
+		{
+			name: "defaults",
+			want: map[Feature]bool{KServeRawDeployment: true, AuthorinoAuth: true, "TestAlpha": false},
+		},
+		{
+			name: "flag",
+			flag: "TestAlpha=true, AuthorinoAuth=false",
+			want: map[Feature]bool{KServeRawDeployment: true, AuthorinoAuth: false, "TestAlpha": true},
+		},
+		{
+			name:      "resource overrides the flag",
+			flag:      "TestAlpha=true",
+			overrides: map[string]bool{"TestAlpha": false, "KServeRawDeployment": false, "Removed": true},
+			want:      map[Feature]bool{KServeRawDeployment: false, AuthorinoAuth: true, "TestAlpha": false},
+			unknown:   []string{"Removed"},
+		},
+		{
+			name:    "unknown flag",
+			flag:    "Removed=true",
+			wantErr: true,
+		},
+		{
+			name:    "invalid flag",
+			flag:    "TestAlpha",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			flag:    "TestAlpha=maybe",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates := New()
+			if err := gates.Set(tt.flag); (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			overridden, unknown := gates.With(tt.overrides)
+			if len(unknown) > 0 || len(tt.unknown) > 0 {
+				if !reflect.DeepEqual(unknown, tt.unknown) {
+					t.Errorf("With() unknown = %v, want %v", unknown, tt.unknown)
+				}
+			}
+			for f, want := range tt.want {
+				if got := overridden.Enabled(f); got != want {
+					t.Errorf("Enabled(%v) = %v, want %v", f, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package healthz serves the liveness and readiness endpoints of the operator. It follows the
+// healthz package of newer controller-runtime releases, which the manager of the version used here lacks.
+package healthz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Checker reports an error if the check fails
+type Checker func(req *http.Request) error
+
+// Ping is a Checker that always succeeds, reporting that the process is able to serve requests
+var Ping Checker = func(_ *http.Request) error { return nil }
+
+// Handler is an http.Handler running a set of named checks. It replies 200 if all checks pass and
+// 500 listing the failed checks otherwise. The result of every check is listed with ?verbose.
+type Handler struct {
+	lock   sync.RWMutex
+	checks map[string]Checker
+}
+
+// NewHandler returns a Handler without checks
+func NewHandler() *Handler {
+	return &Handler{checks: map[string]Checker{}}
+}
+
+// AddCheck adds a named check to the handler
+func (h *Handler) AddCheck(name string, check Checker) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.checks[name] = check
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.lock.RLock()
+	names := []string{}
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := false
+	results := []string{}
+	for _, name := range names {
+		if err := h.checks[name](req); err != nil {
+			failed = true
+			results = append(results, fmt.Sprintf("[-]%v failed: %v", name, err))
+		} else {
+			results = append(results, fmt.Sprintf("[+]%v ok", name))
+		}
+	}
+	h.lock.RUnlock()
+
+	if failed {
+		log.Debugf("Check %v failed: %v", req.URL.Path, strings.Join(results, ", "))
+		http.Error(w, strings.Join(results, "\n")+"\ncheck failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, verbose := req.URL.Query()["verbose"]; verbose {
+		fmt.Fprintln(w, strings.Join(results, "\n"))
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// Flag is a Checker failing until Set is called, used to report that a startup step completed
+type Flag struct {
+	lock    sync.RWMutex
+	set     bool
+	pending string
+}
+
+// NewFlag returns an unset Flag. The check fails with the pending message until the flag is set.
+func NewFlag(pending string) *Flag {
+	return &Flag{pending: pending}
+}
+
+// Set marks the startup step as completed
+func (f *Flag) Set() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.set = true
+}
+
+// Check implements Checker
+func (f *Flag) Check(_ *http.Request) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if !f.set {
+		return errors.New(f.pending)
+	}
+	return nil
+}
+
+// SetOnStart returns a runnable of the manager setting the flag once started. The manager starts the runnables not
+// needing the leader election on every replica, once the informers of the kinds watched by its controllers are
+// synced.
+func (f *Flag) SetOnStart() *FlagSetter {
+	return &FlagSetter{flag: f}
+}
+
+// FlagSetter is a manager.Runnable setting a Flag, see SetOnStart
+type FlagSetter struct {
+	flag *Flag
+}
+
+// Start sets the flag, then waits for the manager to stop
+func (s *FlagSetter) Start(stop <-chan struct{}) error {
+	s.flag.Set()
+	<-stop
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica sets the flag
+func (s *FlagSetter) NeedLeaderElection() bool {
+	return false
+}
+
+// Serve starts serving the liveness checks on /healthz, the readiness checks on /readyz and the readiness checks of
+// the leader on /readyz/leader at addr. It returns immediately; the server runs until the process exits.
+func Serve(addr string, healthz *Handler, readyz *Handler, leader *Handler) {
+	mux := newMux(healthz, readyz, leader)
+	go func() {
+		log.Infof("Serving health probes on %v.", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Errorf("Health probes server exited. Error: %v.", err)
+		}
+	}()
+}
+
+// newMux returns the mux of the probes, see Serve
+func newMux(healthz *Handler, readyz *Handler, leader *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthz)
+	mux.Handle("/readyz", readyz)
+	mux.Handle("/readyz/leader", leader)
+	return mux
+}
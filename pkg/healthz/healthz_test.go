@@ -0,0 +1,114 @@
+package healthz
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	type testCase struct {
+		Name           string
+		Checks         map[string]Checker
+		ExpectedStatus int
+	}
+
+	cacheSynced := NewFlag("waiting for the caches to sync")
+	testCases := []testCase{
+		{
+			Name:           "no-checks",
+			Checks:         map[string]Checker{},
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:           "ping",
+			Checks:         map[string]Checker{"ping": Ping},
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name: "failed-check",
+			Checks: map[string]Checker{
+				"ping":   Ping,
+				"leader": func(_ *http.Request) error { return fmt.Errorf("not the leader") },
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:           "unset-flag",
+			Checks:         map[string]Checker{"cache": cacheSynced.Check},
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range testCases {
+		h := NewHandler()
+		for name, check := range c.Checks {
+			h.AddCheck(name, check)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+		if rec.Code != c.ExpectedStatus {
+			t.Errorf("Case %v: expected status %v; got %v: %v", c.Name, c.ExpectedStatus, rec.Code, rec.Body.String())
+		}
+	}
+
+	cacheSynced.Set()
+	h := NewHandler()
+	h.AddCheck("cache", cacheSynced.Check)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz?verbose", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "[+]cache ok") {
+		t.Errorf("Case set-flag: expected status 200 with verbose output; got %v: %v", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSetOnStart(t *testing.T) {
+	cacheSynced := NewFlag("waiting for the caches to sync")
+	setter := cacheSynced.SetOnStart()
+	if setter.NeedLeaderElection() {
+		t.Errorf("Expected the flag to be set on every replica")
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- setter.Start(stop) }()
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected the runnable to stop along with the manager, got %v", err)
+	}
+	if err := cacheSynced.Check(nil); err != nil {
+		t.Errorf("Expected the flag to be set once started, got %v", err)
+	}
+}
+
+func TestLeaderReadiness(t *testing.T) {
+	leaderElected := NewFlag("waiting to become the leader")
+	readyz, leader := NewHandler(), NewHandler()
+	readyz.AddCheck("ping", Ping)
+	leader.AddCheck("ping", Ping)
+	leader.AddCheck("leader", leaderElected.Check)
+	mux := newMux(NewHandler(), readyz, leader)
+
+	for _, c := range []struct {
+		Path           string
+		ExpectedStatus int
+	}{
+		{"/readyz", http.StatusOK},
+		{"/readyz/leader", http.StatusInternalServerError},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", c.Path, nil))
+		if rec.Code != c.ExpectedStatus {
+			t.Errorf("Expected %v to reply %v on a replica that is not the leader, got %v: %v", c.Path, c.ExpectedStatus,
+				rec.Code, rec.Body.String())
+		}
+	}
+
+	leaderElected.Set()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz/leader", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the leader to be ready, got %v: %v", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,166 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Metadata is the content of the Chart.yaml of a chart used by the templates
+type Metadata struct {
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Version     string `json:"version,omitempty"`
+	AppVersion  string `json:"appVersion,omitempty"`
+	KubeVersion string `json:"kubeVersion,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// Chart is a Helm chart loaded from a directory or an archive
+type Chart struct {
+	Metadata Metadata
+	// Values are the default values of values.yaml
+	Values map[string]interface{}
+	// Templates are the files of the templates directory, by path relative to the chart
+	Templates map[string][]byte
+	// Files are the other files of the chart, by path relative to the chart
+	Files map[string][]byte
+	// Dependencies are the subcharts of the charts directory
+	Dependencies []*Chart
+}
+
+// LoadDir loads the chart of dir
+func LoadDir(dir string) (*Chart, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load(files)
+}
+
+// LoadArchive loads the chart of a gzipped tarball, holding the chart in its top-level directory
+func LoadArchive(data []byte) (*Chart, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+	files := map[string][]byte{}
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		parts := strings.SplitN(path.Clean(header.Name), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[parts[1]] = content
+	}
+	return load(files)
+}
+
+// load builds a chart from its files, by path relative to the chart
+func load(files map[string][]byte) (*Chart, error) {
+	chart := &Chart{Values: map[string]interface{}{}, Templates: map[string][]byte{}, Files: map[string][]byte{}}
+	metadata, ok := files["Chart.yaml"]
+	if !ok {
+		return nil, fmt.Errorf("Chart.yaml not found")
+	}
+	if err := yaml.Unmarshal(metadata, &chart.Metadata); err != nil {
+		return nil, fmt.Errorf("invalid Chart.yaml: %v", err)
+	}
+	if chart.Metadata.Name == "" {
+		return nil, fmt.Errorf("Chart.yaml has no name")
+	}
+	if values, ok := files["values.yaml"]; ok {
+		if err := yaml.Unmarshal(values, &chart.Values); err != nil {
+			return nil, fmt.Errorf("invalid values.yaml of chart %v: %v", chart.Metadata.Name, err)
+		}
+		if chart.Values == nil {
+			chart.Values = map[string]interface{}{}
+		}
+	}
+
+	subcharts := map[string]map[string][]byte{}
+	archives := []string{}
+	for name, data := range files {
+		switch {
+		case strings.HasPrefix(name, "templates/"):
+			chart.Templates[name] = data
+		case strings.HasPrefix(name, "charts/"):
+			parts := strings.SplitN(strings.TrimPrefix(name, "charts/"), "/", 2)
+			if len(parts) == 1 {
+				if strings.HasSuffix(parts[0], ".tgz") {
+					archives = append(archives, name)
+				}
+				continue
+			}
+			if subcharts[parts[0]] == nil {
+				subcharts[parts[0]] = map[string][]byte{}
+			}
+			subcharts[parts[0]][parts[1]] = data
+		default:
+			chart.Files[name] = data
+		}
+	}
+
+	names := []string{}
+	for name := range subcharts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dep, err := load(subcharts[name])
+		if err != nil {
+			return nil, fmt.Errorf("subchart %v of chart %v: %v", name, chart.Metadata.Name, err)
+		}
+		chart.Dependencies = append(chart.Dependencies, dep)
+	}
+	sort.Strings(archives)
+	for _, name := range archives {
+		dep, err := LoadArchive(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("subchart %v of chart %v: %v", name, chart.Metadata.Name, err)
+		}
+		chart.Dependencies = append(chart.Dependencies, dep)
+	}
+	return chart, nil
+}
@@ -0,0 +1,287 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"github.com/ghodss/yaml"
+)
+
+// Release identifies the release the chart is rendered for
+type Release struct {
+	Name      string
+	Namespace string
+	Service   string
+	IsInstall bool
+	IsUpgrade bool
+	Revision  int
+}
+
+// KubeVersion is the version of the cluster, as reported by .Capabilities.KubeVersion
+type KubeVersion struct {
+	Version    string
+	Major      string
+	Minor      string
+	GitVersion string
+}
+
+// String returns the version, e.g. v1.18.3
+func (v KubeVersion) String() string {
+	return v.Version
+}
+
+// VersionSet is the set of the API versions served by the cluster, e.g. apps/v1 or apps/v1/Deployment
+type VersionSet []string
+
+// Has returns true if the cluster serves apiVersion
+func (s VersionSet) Has(apiVersion string) bool {
+	for _, v := range s {
+		if v == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities describes the cluster the chart is rendered for
+type Capabilities struct {
+	KubeVersion KubeVersion
+	APIVersions VersionSet
+}
+
+// DefaultCapabilities are used when the capabilities of the cluster are unknown
+var DefaultCapabilities = &Capabilities{
+	KubeVersion: KubeVersion{Version: "v1.18.0", Major: "1", Minor: "18", GitVersion: "v1.18.0"},
+	APIVersions: VersionSet{"v1"},
+}
+
+// Files gives the templates access to the files of the chart that are neither templates nor values
+type Files map[string][]byte
+
+// Get returns the content of the file name, or an empty string
+func (f Files) Get(name string) string {
+	return string(f[name])
+}
+
+// GetBytes returns the content of the file name, or nil
+func (f Files) GetBytes(name string) []byte {
+	return f[name]
+}
+
+// Lines returns the lines of the file name
+func (f Files) Lines(name string) []string {
+	if len(f[name]) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimRight(string(f[name]), "\n"), "\n")
+}
+
+// Glob returns the files matching pattern
+func (f Files) Glob(pattern string) Files {
+	matches := Files{}
+	for name, data := range f {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches[name] = data
+		}
+	}
+	return matches
+}
+
+// renderable is a template of a chart along with the values it is rendered with
+type renderable struct {
+	chart    *Chart
+	basePath string
+	values   map[string]interface{}
+}
+
+// Render renders the templates of the chart and of its subcharts for release, with values overriding the
+// default values of the chart. It returns the manifests of the templates that are not partials, by template name.
+func Render(chart *Chart, release Release, values map[string]interface{}, caps *Capabilities) (map[string]string, error) {
+	if caps == nil {
+		caps = DefaultCapabilities
+	}
+	if release.Service == "" {
+		release.Service = "Helm"
+	}
+
+	templates := map[string]renderable{}
+	collect(chart, chart.Metadata.Name, CoalesceValues(chart.Values, values), templates)
+
+	names := []string{}
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := template.New("gotpl").Option("missingkey=zero")
+	funcs := funcMap(t)
+	t.Funcs(funcs)
+	for _, name := range names {
+		r := templates[name]
+		if _, err := t.New(name).Parse(string(r.chart.Templates[strings.TrimPrefix(name, r.basePath+"/")])); err != nil {
+			return nil, fmt.Errorf("parse error in %v: %v", name, err)
+		}
+	}
+
+	rendered := map[string]string{}
+	for _, name := range names {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || strings.EqualFold(base, "NOTES.txt") {
+			continue
+		}
+		r := templates[name]
+		data := map[string]interface{}{
+			"Values":       r.values,
+			"Release":      release,
+			"Chart":        r.chart.Metadata,
+			"Capabilities": caps,
+			"Files":        Files(r.chart.Files),
+			"Template":     map[string]interface{}{"Name": name, "BasePath": r.basePath + "/templates"},
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, fmt.Errorf("render error in %v: %v", name, err)
+		}
+		rendered[name] = strings.Replace(buf.String(), "<no value>", "", -1)
+	}
+	return rendered, nil
+}
+
+// collect adds the templates of chart and of its subcharts to templates. The subcharts are rendered with the
+// values of their key in values and the global values.
+func collect(chart *Chart, basePath string, values map[string]interface{}, templates map[string]renderable) {
+	for name := range chart.Templates {
+		templates[path.Join(basePath, name)] = renderable{chart: chart, basePath: basePath, values: values}
+	}
+	for _, dep := range chart.Dependencies {
+		depValues := map[string]interface{}{}
+		if v, ok := values[dep.Metadata.Name].(map[string]interface{}); ok {
+			depValues = v
+		}
+		if global, ok := values["global"].(map[string]interface{}); ok {
+			depValues = CoalesceValues(depValues, map[string]interface{}{"global": global})
+		}
+		collect(dep, path.Join(basePath, "charts", dep.Metadata.Name), CoalesceValues(dep.Values, depValues), templates)
+	}
+}
+
+// CoalesceValues returns the defaults overridden by values. Maps are merged recursively and null values
+// remove the key of the defaults.
+func CoalesceValues(defaults map[string]interface{}, values map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range defaults {
+		result[k] = v
+	}
+	for k, v := range values {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		src, srcOK := v.(map[string]interface{})
+		dst, dstOK := result[k].(map[string]interface{})
+		if srcOK && dstOK {
+			result[k] = CoalesceValues(dst, src)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// funcMap returns the sprig functions, without the ones reading the environment, along with the Helm ones
+func funcMap(t *template.Template) template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "env")
+	delete(funcs, "expandenv")
+
+	includedNames := map[string]int{}
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		if includedNames[name] > 1000 {
+			return "", fmt.Errorf("rendering template has a nested reference name: %s", name)
+		}
+		includedNames[name]++
+		defer func() { includedNames[name]-- }()
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	funcs["tpl"] = func(text string, data interface{}) (string, error) {
+		clone, err := t.Clone()
+		if err != nil {
+			return "", err
+		}
+		tpl, err := clone.New("tpl").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return strings.Replace(buf.String(), "<no value>", "", -1), nil
+	}
+	funcs["required"] = func(message string, value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, fmt.Errorf(message)
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return nil, fmt.Errorf(message)
+		}
+		return value, nil
+	}
+	// the resources of the cluster are not looked up, as with helm template
+	funcs["lookup"] = func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		return map[string]interface{}{}, nil
+	}
+	funcs["toYaml"] = func(v interface{}) string {
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(string(data), "\n")
+	}
+	funcs["fromYaml"] = func(s string) map[string]interface{} {
+		m := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(s), &m); err != nil {
+			m["Error"] = err.Error()
+		}
+		return m
+	}
+	funcs["fromYamlArray"] = func(s string) []interface{} {
+		a := []interface{}{}
+		if err := yaml.Unmarshal([]byte(s), &a); err != nil {
+			a = []interface{}{err.Error()}
+		}
+		return a
+	}
+	funcs["toJson"] = func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	funcs["fromJson"] = func(s string) map[string]interface{} {
+		m := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			m["Error"] = err.Error()
+		}
+		return m
+	}
+	funcs["fromJsonArray"] = func(s string) []interface{} {
+		a := []interface{}{}
+		if err := json.Unmarshal([]byte(s), &a); err != nil {
+			a = []interface{}{err.Error()}
+		}
+		return a
+	}
+	return funcs
+}
@@ -0,0 +1,203 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var chartFiles = map[string]string{
+	"Chart.yaml":  "apiVersion: v2\nname: dashboard\nversion: 1.0.0\n",
+	"values.yaml": "replicas: 1\nimage:\n  name: quay.io/opendatahub/odh-dashboard\n  tag: v1.0.0\nroute:\n  enabled: true\n",
+	"templates/_helpers.tpl": `{{- define "dashboard.labels" -}}
+app: {{ .Release.Name }}
+chart: {{ .Chart.Name }}-{{ .Chart.Version }}
+{{- end -}}`,
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+  labels:
+{{ include "dashboard.labels" . | indent 4 }}
+spec:
+  replicas: {{ .Values.replicas }}
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: {{ printf "%s:%s" .Values.image.name .Values.image.tag | quote }}
+`,
+	"templates/route.yaml": `{{- if .Values.route.enabled }}
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: {{ .Release.Name }}
+{{- end }}
+`,
+	"templates/NOTES.txt":                "{{ .Release.Name }} installed",
+	"charts/database/Chart.yaml":         "apiVersion: v2\nname: database\nversion: 0.1.0\n",
+	"charts/database/values.yaml":        "storage: 1Gi\n",
+	"charts/database/templates/pvc.yaml": "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: {{ .Release.Name }}-db\n  namespace: {{ .Values.global.namespace }}\nspec:\n  resources:\n    requests:\n      storage: {{ .Values.storage }}\n",
+}
+
+func writeChart(t *testing.T, dir string) {
+	for name, content := range chartFiles {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func chartArchive(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range chartFiles {
+		if err := tw.WriteHeader(&tar.Header{Name: "dashboard/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+func TestRender(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helm-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	writeChart(t, tmp)
+
+	chart, err := LoadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{
+		"replicas": 2,
+		"image":    map[string]interface{}{"tag": "v1.1.0"},
+		"route":    map[string]interface{}{"enabled": false},
+		"database": map[string]interface{}{"storage": "10Gi"},
+		"global":   map[string]interface{}{"namespace": "opendatahub"},
+	}
+	rendered, err := Render(chart, Release{Name: "odh-dashboard", Namespace: "opendatahub"}, values, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"dashboard/templates/deployment.yaml": {
+			"name: odh-dashboard",
+			"    app: odh-dashboard\n    chart: dashboard-1.0.0",
+			"replicas: 2",
+			`image: "quay.io/opendatahub/odh-dashboard:v1.1.0"`,
+		},
+		"dashboard/charts/database/templates/pvc.yaml": {
+			"name: odh-dashboard-db",
+			"namespace: opendatahub",
+			"storage: 10Gi",
+		},
+	}
+	for name, fragments := range expected {
+		for _, fragment := range fragments {
+			if !strings.Contains(rendered[name], fragment) {
+				t.Errorf("%v: expected %q in:\n%v", name, fragment, rendered[name])
+			}
+		}
+	}
+	if strings.TrimSpace(rendered["dashboard/templates/route.yaml"]) != "" {
+		t.Errorf("Expected the disabled Route not to be rendered, got:\n%v", rendered["dashboard/templates/route.yaml"])
+	}
+	for _, name := range []string{"dashboard/templates/_helpers.tpl", "dashboard/templates/NOTES.txt"} {
+		if _, ok := rendered[name]; ok {
+			t.Errorf("Expected %v not to be rendered", name)
+		}
+	}
+}
+
+func TestCoalesceValues(t *testing.T) {
+	defaults := map[string]interface{}{
+		"image":    map[string]interface{}{"name": "odh-dashboard", "tag": "v1.0.0"},
+		"replicas": 1,
+		"debug":    true,
+	}
+	values := map[string]interface{}{
+		"image": map[string]interface{}{"tag": "v1.1.0"},
+		"debug": nil,
+	}
+	result := CoalesceValues(defaults, values)
+	image := result["image"].(map[string]interface{})
+	if image["name"] != "odh-dashboard" || image["tag"] != "v1.1.0" || result["replicas"] != 1 {
+		t.Errorf("Unexpected values: %v", result)
+	}
+	if _, ok := result["debug"]; ok {
+		t.Errorf("Expected debug to be removed: %v", result)
+	}
+	if defaults["image"].(map[string]interface{})["tag"] != "v1.0.0" {
+		t.Errorf("Expected the defaults to be left unchanged: %v", defaults)
+	}
+}
+
+func TestFetchHTTP(t *testing.T) {
+	archive := chartArchive(t)
+	digest := fmt.Sprintf("%x", sha256.Sum256(archive))
+	index := fmt.Sprintf(`apiVersion: v1
+entries:
+  dashboard:
+  - name: dashboard
+    version: 1.1.0-rc.1
+    urls: [charts/dashboard-1.1.0-rc.1.tgz]
+  - name: dashboard
+    version: 1.0.0
+    digest: %v
+    urls: [charts/dashboard-1.0.0.tgz]
+  - name: dashboard
+    version: 0.9.0
+    urls: [charts/dashboard-0.9.0.tgz]
+`, digest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			w.Write([]byte(index))
+		case "/charts/dashboard-1.0.0.tgz":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, version := range []string{"", "1.0.0", ">= 1.0, < 1.1"} {
+		data, err := Fetch(server.URL, "dashboard", version)
+		if err != nil {
+			t.Fatalf("version %q: %v", version, err)
+		}
+		chart, err := LoadArchive(data)
+		if err != nil {
+			t.Fatalf("version %q: %v", version, err)
+		}
+		if chart.Metadata.Name != "dashboard" || len(chart.Templates) != 4 || len(chart.Dependencies) != 1 {
+			t.Errorf("version %q: unexpected chart %+v", version, chart)
+		}
+	}
+	if _, err := Fetch(server.URL, "dashboard", "2.0.0"); err == nil {
+		t.Errorf("Expected fetching a missing version to fail")
+	}
+}
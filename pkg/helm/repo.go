@@ -0,0 +1,176 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-version"
+	"github.com/kubeflow/kfctl/v3/pkg/oci"
+)
+
+// ChartLayerMediaType is the media type of the layer holding the chart archive of the charts pushed to OCI registries
+const ChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+var (
+	// archives caches the archives of the charts fetched at a given version, which do not change
+	archives      = map[string][]byte{}
+	archivesMutex sync.Mutex
+)
+
+// IndexFile is the index.yaml of a chart repository
+type IndexFile struct {
+	Entries map[string][]ChartVersion `json:"entries"`
+}
+
+// ChartVersion is a version of a chart in the index of a chart repository
+type ChartVersion struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	URLs    []string `json:"urls"`
+	Digest  string   `json:"digest,omitempty"`
+}
+
+// Fetch returns the archive of the version of chart in repo, which is either the URL of a chart repository or an
+// oci:// registry. The version is a version or a constraint such as ">= 1.0, < 2.0". The latest version is fetched
+// when it is empty.
+func Fetch(repo string, chart string, chartVersion string) ([]byte, error) {
+	if strings.HasPrefix(repo, oci.Scheme) {
+		return fetchOCI(repo, chart, chartVersion)
+	}
+	return fetchHTTP(repo, chart, chartVersion)
+}
+
+// fetchOCI pulls the chart from an OCI registry, where the version of the chart is the tag of its artifact
+func fetchOCI(repo string, chart string, chartVersion string) ([]byte, error) {
+	ref, err := oci.ParseReference(strings.TrimSuffix(repo, "/") + "/" + chart)
+	if err != nil {
+		return nil, err
+	}
+	if chartVersion != "" {
+		// OCI tags can't contain +, helm push replaces it with _
+		ref.Tag = strings.Replace(chartVersion, "+", "_", -1)
+	}
+	key := ref.String()
+	if data := cachedArchive(key); data != nil && ref.Tag != "latest" {
+		return data, nil
+	}
+	client := &oci.Client{}
+	data, err := client.PullLayer(ref, ChartLayerMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't pull chart %v: %v", ref, err)
+	}
+	cacheArchive(key, data)
+	return data, nil
+}
+
+// fetchHTTP downloads the chart from a chart repository, looking up its version in the index of the repository
+func fetchHTTP(repo string, chart string, chartVersion string) ([]byte, error) {
+	repoURL, err := url.Parse(strings.TrimSuffix(repo, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart repository %v: %v", repo, err)
+	}
+	data, err := download(repoURL.String() + "index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	index := &IndexFile{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid index of chart repository %v: %v", repo, err)
+	}
+	cv, err := index.Get(chart, chartVersion)
+	if err != nil {
+		return nil, fmt.Errorf("chart repository %v: %v", repo, err)
+	}
+	if len(cv.URLs) == 0 {
+		return nil, fmt.Errorf("chart repository %v has no URL for chart %v %v", repo, chart, cv.Version)
+	}
+	chartURL, err := repoURL.Parse(cv.URLs[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL of chart %v %v: %v", chart, cv.Version, err)
+	}
+
+	key := chartURL.String()
+	if data := cachedArchive(key); data != nil {
+		return data, nil
+	}
+	if data, err = download(key); err != nil {
+		return nil, err
+	}
+	if cv.Digest != "" && fmt.Sprintf("%x", sha256.Sum256(data)) != strings.TrimPrefix(cv.Digest, "sha256:") {
+		return nil, fmt.Errorf("digest of chart %v %v does not match the index of %v", chart, cv.Version, repo)
+	}
+	cacheArchive(key, data)
+	return data, nil
+}
+
+// Get returns the version of chart matching chartVersion, which is a version or a constraint.
+// The latest version, excluding the prereleases, is returned when chartVersion is empty.
+func (i *IndexFile) Get(chart string, chartVersion string) (*ChartVersion, error) {
+	versions, ok := i.Entries[chart]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("chart %v not found", chart)
+	}
+	for _, cv := range versions {
+		if cv.Version == chartVersion {
+			return &cv, nil
+		}
+	}
+
+	var constraints version.Constraints
+	if chartVersion != "" {
+		var err error
+		if constraints, err = version.NewConstraint(chartVersion); err != nil {
+			return nil, fmt.Errorf("version %v of chart %v not found", chartVersion, chart)
+		}
+	}
+	matches := []*version.Version{}
+	byVersion := map[*version.Version]ChartVersion{}
+	for _, cv := range versions {
+		v, err := version.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		if constraints == nil && v.Prerelease() != "" || constraints != nil && !constraints.Check(v) {
+			continue
+		}
+		matches = append(matches, v)
+		byVersion[v] = cv
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no version of chart %v matches %q", chart, chartVersion)
+	}
+	sort.Sort(version.Collection(matches))
+	cv := byVersion[matches[len(matches)-1]]
+	return &cv, nil
+}
+
+func download(target string) ([]byte, error) {
+	resp, err := http.Get(target)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't download %v: %v", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("couldn't download %v: %v", target, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func cachedArchive(key string) []byte {
+	archivesMutex.Lock()
+	defer archivesMutex.Unlock()
+	return archives[key]
+}
+
+func cacheArchive(key string, data []byte) {
+	archivesMutex.Lock()
+	defer archivesMutex.Unlock()
+	archives[key] = data
+}
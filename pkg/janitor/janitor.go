@@ -0,0 +1,359 @@
+// Package janitor periodically garbage collects the resources left behind in the data science projects and in the
+// applications namespace: the pods of the completed pipeline runs, the ReplicaSets of the previous revisions of the
+// Deployments of the operator and the PersistentVolumeClaims of the deleted notebooks. Every kind of resource is reclaimed once
+// its own TTL elapsed, the kinds whose TTL is 0 are kept.
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/controller/notebook"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// dashboardLabel marks the namespaces of the data science projects, and the claims created by the dashboard
+	// for the notebooks
+	dashboardLabel = "opendatahub.io/dashboard"
+	// pipelineRunLabel is the label of the pods of the Tekton pipeline runs
+	pipelineRunLabel = "tekton.dev/pipelineRun"
+	// revisionAnnotation is the revision of the Deployment a ReplicaSet holds the pod template of
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// OrphanedSinceAnnotation is the time a claim of the dashboard was first found mounted by no notebook nor pod
+	OrphanedSinceAnnotation = "opendatahub.io/orphaned-since"
+)
+
+var (
+	// Interval is the time between two garbage collections
+	Interval = time.Hour
+	// PipelineRunPodTTL is how long the pods of the completed pipeline runs are kept once completed
+	PipelineRunPodTTL = 24 * time.Hour
+	// ReplicaSetTTL is how long the ReplicaSets of the previous revisions of the Deployments of the operator are
+	// kept once superseded. They are left to the revisionHistoryLimit of their Deployment by default.
+	ReplicaSetTTL = time.Duration(0)
+	// OrphanedClaimTTL is how long the claims of the deleted notebooks are kept once orphaned
+	OrphanedClaimTTL = time.Duration(0)
+)
+
+// AddToManager adds the janitor to the manager. It garbage collects the resources once started, then every
+// Interval.
+func AddToManager(mgr manager.Manager) error {
+	j := &janitor{reader: mgr.GetAPIReader(), client: mgr.GetClient(), now: time.Now}
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+		for {
+			if err := j.sweep(); err != nil {
+				log.Warnf("Failed to garbage collect the resources. Error: %v.", err)
+			}
+			select {
+			case <-stop:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}))
+}
+
+// janitor garbage collects the resources read from the API server, rather than from the cache of the manager, so
+// that the pods and the ReplicaSets of the cluster are not cached by the operator
+type janitor struct {
+	reader client.Reader
+	client client.Client
+	now    func() time.Time
+}
+
+// sweep garbage collects the resources of the data science projects and of the applications namespace. A
+// namespace failing to be swept does not keep the others from being swept.
+func (j *janitor) sweep() error {
+	namespaces := &corev1.NamespaceList{}
+	if err := j.reader.List(context.TODO(), namespaces, client.MatchingLabels{dashboardLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the data science projects: %v", err)
+	}
+	projects := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		projects[ns.Name] = true
+	}
+	names := []string{operatorconfig.Current().ApplicationsNamespace}
+	for name := range projects {
+		if name != names[0] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	for _, ns := range names {
+		if PipelineRunPodTTL > 0 && projects[ns] {
+			if err := j.reapPipelineRunPods(ns); err != nil {
+				log.Warnf("Failed to garbage collect the pipeline run pods of namespace %v. Error: %v.", ns, err)
+			}
+		}
+		if ReplicaSetTTL > 0 {
+			if err := j.reapReplicaSets(ns); err != nil {
+				log.Warnf("Failed to garbage collect the ReplicaSets of namespace %v. Error: %v.", ns, err)
+			}
+		}
+		if OrphanedClaimTTL > 0 && projects[ns] {
+			if err := j.reapOrphanedClaims(ns); err != nil {
+				log.Warnf("Failed to garbage collect the orphaned claims of namespace %v. Error: %v.", ns, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reapPipelineRunPods deletes the pods of the pipeline runs of namespace completed for longer than
+// PipelineRunPodTTL
+func (j *janitor) reapPipelineRunPods(namespace string) error {
+	pods := &corev1.PodList{}
+	if err := j.reader.List(context.TODO(), pods, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels[pipelineRunLabel] == "" {
+			continue
+		}
+		completed, ok := completedAt(pod)
+		if !ok || j.now().Sub(completed) < PipelineRunPodTTL {
+			continue
+		}
+		if err := j.delete(pod, "Pod"); err != nil {
+			return err
+		}
+		log.Infof("Deleted pod %v/%v of pipeline run %v, completed at %v.", pod.Namespace, pod.Name,
+			pod.Labels[pipelineRunLabel], completed.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// completedAt returns the time the last container of a succeeded or failed pod terminated, or the time the pod
+// was created if none of its containers reports it. It returns false for the pods that did not complete.
+func completedAt(pod *corev1.Pod) (time.Time, bool) {
+	if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+		return time.Time{}, false
+	}
+	completed := pod.CreationTimestamp.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.FinishedAt.After(completed) {
+			completed = terminated.FinishedAt.Time
+		}
+	}
+	return completed, true
+}
+
+// reapReplicaSets deletes the ReplicaSets of the Deployments of the operator in namespace superseded by a later
+// revision of their Deployment for longer than ReplicaSetTTL. The ReplicaSets of the other Deployments, e.g. the
+// ones of the users in the data science projects, are kept for them to be rolled back.
+func (j *janitor) reapReplicaSets(namespace string) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := j.reader.List(context.TODO(), deployments, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	managed := map[types.UID]bool{}
+	for i := range deployments.Items {
+		if utils.IsManaged(&deployments.Items[i]) {
+			managed[deployments.Items[i].UID] = true
+		}
+	}
+	if len(managed) == 0 {
+		return nil
+	}
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := j.reader.List(context.TODO(), replicaSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for _, rs := range staleReplicaSets(replicaSets.Items, managed, j.now(), ReplicaSetTTL) {
+		if err := j.delete(rs, "ReplicaSet"); err != nil {
+			return err
+		}
+		log.Infof("Deleted ReplicaSet %v/%v of revision %v.", rs.Namespace, rs.Name, rs.Annotations[revisionAnnotation])
+	}
+	return nil
+}
+
+// staleReplicaSets returns the ReplicaSets scaled down to no pod whose Deployment, one of the managed ones, created a
+// later revision more than ttl before now. The ReplicaSets of the latest revision of every Deployment, kept for it
+// to be scaled, and the ones not owned by a managed Deployment are never stale.
+func staleReplicaSets(replicaSets []appsv1.ReplicaSet, managed map[types.UID]bool, now time.Time, ttl time.Duration) []*appsv1.ReplicaSet {
+	type revision struct {
+		number int64
+		rs     *appsv1.ReplicaSet
+	}
+	revisions := map[types.UID][]revision{}
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		owner := metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != "Deployment" || !managed[owner.UID] {
+			continue
+		}
+		number, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions[owner.UID] = append(revisions[owner.UID], revision{number: number, rs: rs})
+	}
+
+	stale := []*appsv1.ReplicaSet{}
+	for _, deployment := range revisions {
+		sort.Slice(deployment, func(i, k int) bool { return deployment[i].number < deployment[k].number })
+		for i, r := range deployment[:len(deployment)-1] {
+			if r.rs.Status.Replicas > 0 || (r.rs.Spec.Replicas != nil && *r.rs.Spec.Replicas > 0) {
+				continue
+			}
+			// A ReplicaSet is superseded once the ReplicaSet of the next revision is created
+			superseded := deployment[i+1].rs.CreationTimestamp.Time
+			if now.Sub(superseded) >= ttl {
+				stale = append(stale, r.rs)
+			}
+		}
+	}
+	sort.Slice(stale, func(i, k int) bool { return stale[i].Name < stale[k].Name })
+	return stale
+}
+
+// reapOrphanedClaims annotates the claims of the dashboard of namespace mounted by no notebook nor pod with the
+// time they were found orphaned, and deletes the ones orphaned for longer than OrphanedClaimTTL. The claims
+// mounted again are no longer orphaned. The claims owned by another resource are left to the garbage collector.
+func (j *janitor) reapOrphanedClaims(namespace string) error {
+	claims := &corev1.PersistentVolumeClaimList{}
+	err := j.reader.List(context.TODO(), claims, client.InNamespace(namespace), client.MatchingLabels{dashboardLabel: "true"})
+	if err != nil {
+		return err
+	}
+	if len(claims.Items) == 0 {
+		return nil
+	}
+	mounted, err := j.mountedClaims(namespace)
+	if err != nil {
+		return err
+	}
+	now := j.now()
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if len(claim.OwnerReferences) > 0 || claim.DeletionTimestamp != nil {
+			continue
+		}
+		orphanedSince, orphaned := claim.Annotations[OrphanedSinceAnnotation]
+		switch {
+		case mounted[claim.Name] && orphaned:
+			delete(claim.Annotations, OrphanedSinceAnnotation)
+			if err := j.client.Update(context.TODO(), claim); err != nil {
+				return err
+			}
+		case mounted[claim.Name]:
+		case !orphaned:
+			if claim.Annotations == nil {
+				claim.Annotations = map[string]string{}
+			}
+			claim.Annotations[OrphanedSinceAnnotation] = now.Format(time.RFC3339)
+			if err := j.client.Update(context.TODO(), claim); err != nil {
+				return err
+			}
+			log.Infof("PersistentVolumeClaim %v/%v is mounted by no notebook, deleting it in %v.", claim.Namespace,
+				claim.Name, OrphanedClaimTTL)
+		default:
+			since, err := time.Parse(time.RFC3339, orphanedSince)
+			if err != nil {
+				log.Warnf("Invalid %v of PersistentVolumeClaim %v/%v: %v.", OrphanedSinceAnnotation, claim.Namespace,
+					claim.Name, err)
+				continue
+			}
+			if now.Sub(since) < OrphanedClaimTTL {
+				continue
+			}
+			if err := j.delete(claim, "PersistentVolumeClaim"); err != nil {
+				return err
+			}
+			log.Infof("Deleted PersistentVolumeClaim %v/%v, mounted by no notebook since %v.", claim.Namespace,
+				claim.Name, orphanedSince)
+		}
+	}
+	return nil
+}
+
+// mountedClaims returns the names of the claims of namespace mounted by a notebook, running or stopped, or by a
+// pod
+func (j *janitor) mountedClaims(namespace string) (map[string]bool, error) {
+	mounted := map[string]bool{}
+	pods := &corev1.PodList{}
+	if err := j.reader.List(context.TODO(), pods, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		addClaims(mounted, pod.Spec.Volumes)
+	}
+
+	notebooks := &unstructured.UnstructuredList{}
+	notebooks.SetGroupVersionKind(notebook.NotebookGVK.GroupVersion().WithKind(notebook.NotebookGVK.Kind + "List"))
+	err := j.reader.List(context.TODO(), notebooks, client.InNamespace(namespace))
+	if meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) {
+		// Without the Notebook CRD the claims are only mounted by pods
+		return mounted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, nb := range notebooks.Items {
+		template, found, err := unstructured.NestedMap(nb.Object, "spec", "template", "spec")
+		if err != nil || !found {
+			continue
+		}
+		podSpec := &corev1.PodSpec{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, podSpec); err != nil {
+			// The claims of a notebook that cannot be read are kept
+			return nil, fmt.Errorf("invalid pod template of notebook %v: %v", nb.GetName(), err)
+		}
+		addClaims(mounted, podSpec.Volumes)
+	}
+	return mounted, nil
+}
+
+// addClaims adds the claims mounted by volumes to mounted
+func addClaims(mounted map[string]bool, volumes []corev1.Volume) {
+	for _, volume := range volumes {
+		if volume.PersistentVolumeClaim != nil {
+			mounted[volume.PersistentVolumeClaim.ClaimName] = true
+		}
+	}
+}
+
+// delete deletes obj of kind, counting it as reclaimed along with the storage it requests
+func (j *janitor) delete(obj runtime.Object, kind string) error {
+	err := j.client.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	storage := int64(0)
+	if claim, ok := obj.(*corev1.PersistentVolumeClaim); ok {
+		request := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		storage = request.Value()
+	}
+	metrics.Reclaimed(accessor.GetNamespace(), kind, storage)
+	return nil
+}
@@ -0,0 +1,282 @@
+package janitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var now = time.Date(2023, 11, 14, 12, 0, 0, 0, time.UTC)
+
+func TestCompletedAt(t *testing.T) {
+	created := metav1.NewTime(now.Add(-2 * time.Hour))
+	terminated := func(finished time.Time) corev1.ContainerStatus {
+		return corev1.ContainerStatus{State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(finished)},
+		}}
+	}
+	tests := []struct {
+		name      string
+		phase     corev1.PodPhase
+		statuses  []corev1.ContainerStatus
+		completed time.Time
+		ok        bool
+	}{
+		{name: "running", phase: corev1.PodRunning},
+		{name: "pending", phase: corev1.PodPending},
+		{name: "succeeded without statuses", phase: corev1.PodSucceeded, completed: created.Time, ok: true},
+		{
+			name:      "succeeded",
+			phase:     corev1.PodSucceeded,
+			statuses:  []corev1.ContainerStatus{terminated(now.Add(-time.Hour)), terminated(now.Add(-90 * time.Minute))},
+			completed: now.Add(-time.Hour),
+			ok:        true,
+		},
+		{
+			name:      "failed",
+			phase:     corev1.PodFailed,
+			statuses:  []corev1.ContainerStatus{{}, terminated(now.Add(-30 * time.Minute))},
+			completed: now.Add(-30 * time.Minute),
+			ok:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Status:     corev1.PodStatus{Phase: tt.phase, ContainerStatuses: tt.statuses},
+			}
+			completed, ok := completedAt(pod)
+			if ok != tt.ok || !completed.Equal(tt.completed) {
+				t.Errorf("Expected the pod to be completed (%v) at %v, got (%v) at %v", tt.ok, tt.completed, ok, completed)
+			}
+		})
+	}
+}
+
+// replicaSet returns a ReplicaSet of revision of the Deployment of uid, created age before now
+func replicaSet(name, uid, revision string, age time.Duration, replicas int32) appsv1.ReplicaSet {
+	controller := true
+	rs := appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "opendatahub",
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			Annotations:       map[string]string{revisionAnnotation: revision},
+		},
+		Spec: appsv1.ReplicaSetSpec{Replicas: &replicas},
+	}
+	if uid != "" {
+		rs.OwnerReferences = []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment", UID: types.UID(uid), Controller: &controller},
+		}
+	}
+	return rs
+}
+
+func TestStaleReplicaSets(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicaSets []appsv1.ReplicaSet
+		expected    []string
+	}{
+		{
+			name: "superseded for longer than the TTL",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("dashboard-3", "dashboard", "3", time.Hour, 1),
+				replicaSet("dashboard-1", "dashboard", "1", 20*24*time.Hour, 0),
+				replicaSet("dashboard-2", "dashboard", "2", 10*24*time.Hour, 0),
+			},
+			expected: []string{"dashboard-1"},
+		},
+		{
+			name: "latest revision",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("dashboard-1", "dashboard", "1", 20*24*time.Hour, 0),
+			},
+			expected: []string{},
+		},
+		{
+			name: "not scaled down",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("dashboard-1", "dashboard", "1", 20*24*time.Hour, 1),
+				replicaSet("dashboard-2", "dashboard", "2", 10*24*time.Hour, 0),
+			},
+			expected: []string{},
+		},
+		{
+			name: "revisions compared as numbers",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("dashboard-10", "dashboard", "10", 10*24*time.Hour, 0),
+				replicaSet("dashboard-9", "dashboard", "9", 20*24*time.Hour, 0),
+				replicaSet("dashboard-11", "dashboard", "11", time.Hour, 1),
+			},
+			expected: []string{"dashboard-9"},
+		},
+		{
+			name: "grouped by Deployment",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("dashboard-1", "dashboard", "1", 20*24*time.Hour, 0),
+				replicaSet("dashboard-2", "dashboard", "2", 10*24*time.Hour, 0),
+				replicaSet("registry-1", "registry", "1", time.Hour, 0),
+			},
+			expected: []string{"dashboard-1"},
+		},
+		{
+			name: "not owned by a Deployment",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("standalone-1", "", "1", 20*24*time.Hour, 0),
+				replicaSet("standalone-2", "", "2", 10*24*time.Hour, 0),
+			},
+			expected: []string{},
+		},
+		{
+			name: "Deployment not managed by the operator",
+			replicaSets: []appsv1.ReplicaSet{
+				replicaSet("model-1", "model", "1", 20*24*time.Hour, 0),
+				replicaSet("model-2", "model", "2", 10*24*time.Hour, 0),
+			},
+			expected: []string{},
+		},
+	}
+	managed := map[types.UID]bool{"dashboard": true, "registry": true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			for _, rs := range staleReplicaSets(tt.replicaSets, managed, now, 7*24*time.Hour) {
+				names = append(names, rs.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected the stale ReplicaSets %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	defer func(pods, replicaSets, claims time.Duration) {
+		PipelineRunPodTTL, ReplicaSetTTL, OrphanedClaimTTL = pods, replicaSets, claims
+	}(PipelineRunPodTTL, ReplicaSetTTL, OrphanedClaimTTL)
+	PipelineRunPodTTL, ReplicaSetTTL, OrphanedClaimTTL = 24*time.Hour, 7*24*time.Hour, 48*time.Hour
+
+	project := map[string]string{dashboardLabel: "true"}
+	pod := func(name string, labels map[string]string, phase corev1.PodPhase, age time.Duration, claim string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "project",
+				Labels:            labels,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if claim != "" {
+			p.Spec.Volumes = []corev1.Volume{{
+				Name:         claim,
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim}},
+			}}
+		}
+		return p
+	}
+	claim := func(name, orphanedSince string) *corev1.PersistentVolumeClaim {
+		c := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "project", Labels: project},
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+			}},
+		}
+		if orphanedSince != "" {
+			c.Annotations = map[string]string{OrphanedSinceAnnotation: orphanedSince}
+		}
+		return c
+	}
+	mounted := claim("mounted", now.Add(-72*time.Hour).Format(time.RFC3339))
+	expired := claim("expired", now.Add(-72*time.Hour).Format(time.RFC3339))
+	recent := claim("recent", now.Add(-time.Hour).Format(time.RFC3339))
+	owned := claim("owned", "")
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "owner"}}
+	old, latest := replicaSet("dashboard-1", "dashboard", "1", 20*24*time.Hour, 0), replicaSet("dashboard-2", "dashboard", "2", 10*24*time.Hour, 1)
+	userOld, userLatest := replicaSet("model-1", "model", "1", 20*24*time.Hour, 0), replicaSet("model-2", "model", "2", 10*24*time.Hour, 1)
+
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "project", Labels: project}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub"}},
+		pod("completed-run", map[string]string{pipelineRunLabel: "run"}, corev1.PodSucceeded, 48*time.Hour, ""),
+		pod("recent-run", map[string]string{pipelineRunLabel: "run"}, corev1.PodFailed, time.Hour, ""),
+		pod("running-run", map[string]string{pipelineRunLabel: "run"}, corev1.PodRunning, 48*time.Hour, ""),
+		pod("completed-job", nil, corev1.PodSucceeded, 48*time.Hour, ""),
+		pod("notebook-0", nil, corev1.PodRunning, 48*time.Hour, "mounted"),
+		claim("unmounted", ""), mounted, expired, recent, owned, &old, &latest, &userOld, &userLatest,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "opendatahub", UID: "dashboard",
+			Labels: utils.WithManagedLabel(nil)}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "model", Namespace: "opendatahub", UID: "model"}},
+	}
+	c := fake.NewFakeClient(objects...)
+	j := &janitor{reader: c, client: c, now: func() time.Time { return now }}
+	metrics.JanitorReclaimed.Reset()
+	metrics.JanitorReclaimedStorage.Reset()
+	if err := j.sweep(); err != nil {
+		t.Fatalf("Failed to garbage collect the resources: %v", err)
+	}
+
+	exists := func(obj runtime.Object, namespace, name string) bool {
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, obj)
+		if err != nil && !errors.IsNotFound(err) {
+			t.Fatalf("Failed to get %v/%v: %v", namespace, name, err)
+		}
+		return err == nil
+	}
+	for name, kept := range map[string]bool{"completed-run": false, "recent-run": true, "running-run": true, "completed-job": true, "notebook-0": true} {
+		if exists(&corev1.Pod{}, "project", name) != kept {
+			t.Errorf("Expected pod %v to be kept (%v)", name, kept)
+		}
+	}
+	for name, kept := range map[string]bool{"dashboard-1": false, "dashboard-2": true, "model-1": true, "model-2": true} {
+		if exists(&appsv1.ReplicaSet{}, "opendatahub", name) != kept {
+			t.Errorf("Expected ReplicaSet %v to be kept (%v)", name, kept)
+		}
+	}
+	for name, annotation := range map[string]string{
+		"unmounted": now.Format(time.RFC3339),
+		"mounted":   "",
+		"recent":    now.Add(-time.Hour).Format(time.RFC3339),
+		"owned":     "",
+	} {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if !exists(pvc, "project", name) {
+			t.Errorf("Expected PersistentVolumeClaim %v to be kept", name)
+			continue
+		}
+		if since := pvc.Annotations[OrphanedSinceAnnotation]; since != annotation {
+			t.Errorf("Expected PersistentVolumeClaim %v to be orphaned since %q, got %q", name, annotation, since)
+		}
+	}
+	if exists(&corev1.PersistentVolumeClaim{}, "project", "expired") {
+		t.Errorf("Expected PersistentVolumeClaim expired to be deleted")
+	}
+
+	for kind, expected := range map[string]float64{"Pod": 1, "PersistentVolumeClaim": 1} {
+		if reclaimed := testutil.ToFloat64(metrics.JanitorReclaimed.WithLabelValues("project", kind)); reclaimed != expected {
+			t.Errorf("Expected %v %v to be reclaimed, got %v", expected, kind, reclaimed)
+		}
+	}
+	if reclaimed := testutil.ToFloat64(metrics.JanitorReclaimed.WithLabelValues("opendatahub", "ReplicaSet")); reclaimed != 1 {
+		t.Errorf("Expected 1 ReplicaSet to be reclaimed, got %v", reclaimed)
+	}
+	if storage := testutil.ToFloat64(metrics.JanitorReclaimedStorage.WithLabelValues("project")); storage != 20*1024*1024*1024 {
+		t.Errorf("Expected 20Gi of storage to be reclaimed, got %v", storage)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package coordinator
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -34,6 +35,7 @@ import (
 	"github.com/kubeflow/kfctl/v3/pkg/kfapp/minikube"
 	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
 	kfconfigloaders "github.com/kubeflow/kfctl/v3/pkg/kfconfig/loaders"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
 	"github.com/kubeflow/kfctl/v3/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
@@ -314,6 +316,11 @@ type KfDefGetterV1beta1 interface {
 	GetKfDefV1Beta1() *kfdefsv1beta1.KfDef
 }
 
+// Get reference to the KfConfig the application is built from.
+type KfConfigGetter interface {
+	GetKfConfig() *kfconfig.KfConfig
+}
+
 // Get reference to the plugin .
 type PluginGetter interface {
 	GetPlugin(name string) (kftypesv3.KfApp, bool)
@@ -324,6 +331,11 @@ func (kfapp *coordinator) GetKfDef() *kfdefsv1beta1.KfDef {
 	return nil
 }
 
+// GetKfConfig returns the KfConfig used by this application, including the status recorded by the plugins.
+func (kfapp *coordinator) GetKfConfig() *kfconfig.KfConfig {
+	return kfapp.KfDef
+}
+
 // GetKfDefV1Beta1 returns a copy of KfDef V1Beta1 used by this application.
 func (kfapp *coordinator) GetKfDefV1Beta1() *kfdefsv1beta1.KfDef {
 	kfdefIns := &kfdefsv1beta1.KfDef{}
@@ -365,6 +377,12 @@ func (kfapp *coordinator) Dump(resources kftypesv3.ResourceEnum) error {
 }
 
 func (kfapp *coordinator) Apply(resources kftypesv3.ResourceEnum) error {
+	return kfapp.ApplyContext(context.Background(), resources)
+}
+
+// ApplyContext applies the KfApp with ctx, the package managers implementing kftypesv3.KfAppContext are applied
+// with it too
+func (kfapp *coordinator) ApplyContext(ctx context.Context, resources kftypesv3.ResourceEnum) error {
 	platform := func() error {
 		if kfapp.KfDef.Spec.Platform != "" {
 			platform := kfapp.Platforms[kfapp.KfDef.Spec.Platform]
@@ -390,10 +408,23 @@ func (kfapp *coordinator) Apply(resources kftypesv3.ResourceEnum) error {
 
 	k8s := func() error {
 		for packageManagerName, packageManager := range kfapp.PackageManagers {
-			packageManagerErr := packageManager.Apply(kftypesv3.K8S)
+			var packageManagerErr error
+			if withContext, ok := packageManager.(kftypesv3.KfAppContext); ok {
+				packageManagerErr = withContext.ApplyContext(ctx, kftypesv3.K8S)
+			} else {
+				packageManagerErr = packageManager.Apply(kftypesv3.K8S)
+			}
 			if packageManagerErr != nil {
+				code := int(kfapis.INTERNAL_ERROR)
+				// Keep conflicts and refused upgrades distinguishable so the operator can report them on the KfDef.
+				if kfapis.IsConflict(packageManagerErr) {
+					code = int(kfapis.CONFLICT)
+				}
+				if kfapis.IsUnsafeUpgrade(packageManagerErr) {
+					code = int(kfapis.UNSAFE_UPGRADE)
+				}
 				return &kfapis.KfError{
-					Code: int(kfapis.INTERNAL_ERROR),
+					Code: code,
 					Message: fmt.Sprintf("kfApp Apply failed for %v: %v",
 						packageManagerName, packageManagerErr),
 				}
@@ -430,7 +461,14 @@ func (kfapp *coordinator) Apply(resources kftypesv3.ResourceEnum) error {
 		}
 	}
 
-	if err := kfapp.KfDef.SyncCache(); err != nil {
+	span := tracing.Start(tracing.Active(tracing.Key(kfapp.KfDef.Namespace, kfapp.KfDef.Name)), "Fetch")
+	err := kfapp.KfDef.SyncCacheContext(ctx)
+	span.End(err)
+	if err != nil {
+		// Manifests whose signature couldn't be verified are reported as such
+		if kfapis.IsUnverified(err) {
+			return err
+		}
 		return &kfapis.KfError{
 			Code:    int(kfapis.INTERNAL_ERROR),
 			Message: fmt.Sprintf("could not sync cache. Error: %v", err),
@@ -460,6 +498,12 @@ func (kfapp *coordinator) Apply(resources kftypesv3.ResourceEnum) error {
 }
 
 func (kfapp *coordinator) Delete(resources kftypesv3.ResourceEnum) error {
+	return kfapp.DeleteContext(context.Background(), resources)
+}
+
+// DeleteContext deletes the KfApp with ctx, the package managers implementing kftypesv3.KfAppContext are deleted
+// with it too
+func (kfapp *coordinator) DeleteContext(ctx context.Context, resources kftypesv3.ResourceEnum) error {
 	platform := func() error {
 		if kfapp.KfDef.Spec.Platform != "" {
 			platform := kfapp.Platforms[kfapp.KfDef.Spec.Platform]
@@ -485,7 +529,12 @@ func (kfapp *coordinator) Delete(resources kftypesv3.ResourceEnum) error {
 
 	k8s := func() error {
 		for packageManagerName, packageManager := range kfapp.PackageManagers {
-			packageManagerErr := packageManager.Delete(kftypesv3.K8S)
+			var packageManagerErr error
+			if withContext, ok := packageManager.(kftypesv3.KfAppContext); ok {
+				packageManagerErr = withContext.DeleteContext(ctx, kftypesv3.K8S)
+			} else {
+				packageManagerErr = packageManager.Delete(kftypesv3.K8S)
+			}
 			if packageManagerErr != nil {
 				return &kfapis.KfError{
 					Code: int(kfapis.INTERNAL_ERROR),
@@ -497,7 +546,11 @@ func (kfapp *coordinator) Delete(resources kftypesv3.ResourceEnum) error {
 		return nil
 	}
 
-	if err := kfapp.KfDef.SyncCache(); err != nil {
+	if err := kfapp.KfDef.SyncCacheContext(ctx); err != nil {
+		// Manifests whose signature couldn't be verified are reported as such
+		if kfapis.IsUnverified(err) {
+			return err
+		}
 		return &kfapis.KfError{
 			Code:    int(kfapis.INTERNAL_ERROR),
 			Message: fmt.Sprintf("could not sync cache. Error: %v", err),
@@ -579,6 +632,10 @@ func (kfapp *coordinator) Generate(resources kftypesv3.ResourceEnum) error {
 	usageReportWarn(kfapp.KfDef.Spec.Applications)
 
 	if err := kfapp.KfDef.SyncCache(); err != nil {
+		// Manifests whose signature couldn't be verified are reported as such
+		if kfapis.IsUnverified(err) {
+			return err
+		}
 		return &kfapis.KfError{
 			Code:    int(kfapis.INTERNAL_ERROR),
 			Message: fmt.Sprintf("could not sync cache. Error: %v", err),
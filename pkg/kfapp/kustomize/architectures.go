@@ -0,0 +1,189 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// ArchitectureLabel is the label of the nodes naming their CPU architecture
+	ArchitectureLabel = "kubernetes.io/arch"
+	// NoCompatibleNodesReason is the reason of the condition of an application whose workloads support none of the
+	// architectures of the nodes
+	NoCompatibleNodesReason = "NoCompatibleNodes"
+)
+
+// supportedArchitectures are the architectures the workloads of the manifests may be annotated with
+var supportedArchitectures = map[string]bool{
+	"amd64":   true,
+	"arm64":   true,
+	"ppc64le": true,
+	"s390x":   true,
+}
+
+// loadNodeArchitectures returns the architectures of the nodes of the cluster
+func (kustomize *kustomize) loadNodeArchitectures() (map[string]bool, error) {
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing k8s client: %v", err),
+		}
+	}
+	return nodeArchitectures(kubeclient)
+}
+
+// nodeArchitectures returns the architectures the nodes listed by reader are labelled with
+func nodeArchitectures(reader client.Reader) (map[string]bool, error) {
+	nodes := &v1.NodeList{}
+	if err := reader.List(context.TODO(), nodes); err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to list the nodes: %v", err),
+		}
+	}
+	architectures := map[string]bool{}
+	for _, node := range nodes.Items {
+		if arch := node.Labels[ArchitectureLabel]; arch != "" {
+			architectures[arch] = true
+		}
+	}
+	return architectures, nil
+}
+
+// sortedArchitectures returns the architectures of a set, sorted
+func sortedArchitectures(architectures map[string]bool) []string {
+	sorted := []string{}
+	for arch := range architectures {
+		sorted = append(sorted, arch)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// parseArchitectures returns the architectures listed, separated by commas, by the kfctl.kubeflow.io/architectures
+// annotation of a workload
+func parseArchitectures(value string) ([]string, error) {
+	architectures := []string{}
+	for _, arch := range strings.Split(value, ",") {
+		arch = strings.TrimSpace(arch)
+		if arch == "" {
+			continue
+		}
+		if !supportedArchitectures[arch] {
+			return nil, fmt.Errorf("unknown architecture %v, must be one of %v", arch,
+				strings.Join(sortedArchitectures(supportedArchitectures), ", "))
+		}
+		architectures = append(architectures, arch)
+	}
+	if len(architectures) == 0 {
+		return nil, fmt.Errorf("no architecture listed")
+	}
+	return architectures, nil
+}
+
+// injectArchitectures requires the workloads of resMap annotated with kfctl.kubeflow.io/architectures to be
+// scheduled onto nodes of the architectures they list, adding them to every node selector term of their node
+// affinity. It returns the workloads none of nodeArchitectures is listed for, when they are known.
+func injectArchitectures(resMap resmap.ResMap, nodeArchitectures map[string]bool) ([]string, error) {
+	unschedulable := []string{}
+	for _, res := range resMap.Resources() {
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		value, ok := res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.Architectures}, "/")]
+		if !ok {
+			continue
+		}
+		architectures, err := parseArchitectures(value)
+		if err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("invalid architectures of %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		field, found, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		podSpec, ok := field.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		requireArchitectures(podSpec, architectures)
+		res.SetMap(obj.Object)
+
+		if nodeArchitectures == nil {
+			continue
+		}
+		compatible := false
+		for _, arch := range architectures {
+			compatible = compatible || nodeArchitectures[arch]
+		}
+		if !compatible {
+			unschedulable = append(unschedulable, fmt.Sprintf("%v %v (%v)", res.GetKind(), res.GetName(),
+				strings.Join(architectures, ", ")))
+		}
+	}
+	return unschedulable, nil
+}
+
+// requireArchitectures adds the requirement of the architectures to the node selector terms of the required node
+// affinity of podSpec, creating a term when it has none
+func requireArchitectures(podSpec map[string]interface{}, architectures []string) {
+	values := []interface{}{}
+	for _, arch := range architectures {
+		values = append(values, arch)
+	}
+	requirement := map[string]interface{}{"key": ArchitectureLabel, "operator": "In", "values": values}
+
+	affinity, _ := podSpec["affinity"].(map[string]interface{})
+	if affinity == nil {
+		affinity = map[string]interface{}{}
+	}
+	nodeAffinity, _ := affinity["nodeAffinity"].(map[string]interface{})
+	if nodeAffinity == nil {
+		nodeAffinity = map[string]interface{}{}
+	}
+	required, _ := nodeAffinity["requiredDuringSchedulingIgnoredDuringExecution"].(map[string]interface{})
+	if required == nil {
+		required = map[string]interface{}{}
+	}
+	terms, _ := required["nodeSelectorTerms"].([]interface{})
+	if len(terms) == 0 {
+		terms = []interface{}{map[string]interface{}{}}
+	}
+	for i, t := range terms {
+		term, _ := t.(map[string]interface{})
+		if term == nil {
+			term = map[string]interface{}{}
+		}
+		expressions, _ := term["matchExpressions"].([]interface{})
+		kept := []interface{}{}
+		// The requirement replaces the one of the manifests, so that rendering again does not add it twice
+		for _, e := range expressions {
+			if expression, ok := e.(map[string]interface{}); ok && expression["key"] == ArchitectureLabel {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		term["matchExpressions"] = append(kept, requirement)
+		terms[i] = term
+	}
+	required["nodeSelectorTerms"] = terms
+	nodeAffinity["requiredDuringSchedulingIgnoredDuringExecution"] = required
+	affinity["nodeAffinity"] = nodeAffinity
+	podSpec["affinity"] = affinity
+}
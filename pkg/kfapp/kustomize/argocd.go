@@ -0,0 +1,127 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// ArgoCDIgnore leaves the resources tracked by Argo CD to it: they are neither applied, restored nor pruned
+	ArgoCDIgnore = "ignore"
+	// ArgoCDAdopt applies the resources tracked by Argo CD, taking over the fields it applied, and keeps Argo CD from
+	// pruning or deleting them once they are removed from its applications
+	ArgoCDAdopt = "adopt"
+
+	// ArgoCDTrackingAnnotation is the annotation of the resources tracked by Argo CD with the annotation tracking method
+	ArgoCDTrackingAnnotation = "argocd.argoproj.io/tracking-id"
+	// argoCDAnnotationPrefix is the prefix of the annotations Argo CD reads and writes
+	argoCDAnnotationPrefix = "argocd.argoproj.io/"
+	// argoCDSyncOptionsAnnotation keeps the adopted resources when Argo CD prunes or deletes its applications
+	argoCDSyncOptionsAnnotation = "argocd.argoproj.io/sync-options"
+	argoCDAdoptedSyncOptions    = "Prune=false,Delete=false"
+)
+
+var (
+	// ArgoCDMode tells what the operator does with the resources tracked by Argo CD, ArgoCDIgnore or ArgoCDAdopt.
+	// They are applied as any other resource when it is empty.
+	ArgoCDMode = ""
+	// ArgoCDTrackingLabel is the label of the resources tracked by Argo CD with the label tracking method, e.g.
+	// app.kubernetes.io/instance. Only the tracking annotation is looked up when it is empty.
+	ArgoCDTrackingLabel = ""
+	// ArgoCDFieldManagers are the field managers Argo CD applies the resources with
+	ArgoCDFieldManagers = []string{"argocd-controller", "argocd-application-controller"}
+)
+
+// ValidateArgoCDMode returns an error if mode is not a valid ArgoCDMode
+func ValidateArgoCDMode(mode string) error {
+	switch mode {
+	case "", ArgoCDIgnore, ArgoCDAdopt:
+		return nil
+	}
+	return fmt.Errorf("invalid Argo CD mode %q, must be %v or %v", mode, ArgoCDIgnore, ArgoCDAdopt)
+}
+
+// IsArgoCDTracked returns true if the resource is tracked by an application of Argo CD
+func IsArgoCDTracked(obj metav1.Object) bool {
+	if _, found := obj.GetAnnotations()[ArgoCDTrackingAnnotation]; found {
+		return true
+	}
+	if ArgoCDTrackingLabel == "" {
+		return false
+	}
+	_, found := obj.GetLabels()[ArgoCDTrackingLabel]
+	return found
+}
+
+// IgnoresArgoCDResource returns true if the resource is left to Argo CD
+func IgnoresArgoCDResource(obj metav1.Object) bool {
+	return ArgoCDMode == ArgoCDIgnore && IsArgoCDTracked(obj)
+}
+
+// WithoutArgoCDMetadata returns a copy of the labels or annotations m without the ones Argo CD writes, so that the
+// changes Argo CD makes to them are not taken as drift. m is returned as it is when ArgoCDMode is empty.
+func WithoutArgoCDMetadata(m map[string]string) map[string]string {
+	if ArgoCDMode == "" || m == nil {
+		return m
+	}
+	filtered := map[string]string{}
+	for k, v := range m {
+		if strings.HasPrefix(k, argoCDAnnotationPrefix) || ArgoCDTrackingLabel != "" && k == ArgoCDTrackingLabel {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
+// injectArgoCDCompatibility prepares the resources of resMap for the ArgoCDMode. The tracking annotation and label
+// are removed from the manifests so that the operator and Argo CD never set them to different values. In ignore
+// mode, the resources whose live copy is tracked by Argo CD are removed from resMap. In adopt mode, the resources are
+// annotated for Argo CD to keep them when they are removed from its applications.
+func injectArgoCDCompatibility(resMap resmap.ResMap, namespace string, kubeclient client.Client) error {
+	if ArgoCDMode == "" {
+		return nil
+	}
+	for _, res := range resMap.Resources() {
+		annotations := res.GetAnnotations()
+		if _, found := annotations[ArgoCDTrackingAnnotation]; found || ArgoCDMode == ArgoCDAdopt {
+			delete(annotations, ArgoCDTrackingAnnotation)
+			if ArgoCDMode == ArgoCDAdopt {
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				annotations[argoCDSyncOptionsAnnotation] = argoCDAdoptedSyncOptions
+			}
+			res.SetAnnotations(annotations)
+		}
+		if labels := res.GetLabels(); ArgoCDTrackingLabel != "" && labels[ArgoCDTrackingLabel] != "" {
+			delete(labels, ArgoCDTrackingLabel)
+			res.SetLabels(labels)
+		}
+
+		if ArgoCDMode != ArgoCDIgnore {
+			continue
+		}
+		current, err := getLiveResource(kubeclient, res, namespace)
+		if err != nil {
+			return err
+		}
+		if current == nil || !IsArgoCDTracked(current) {
+			continue
+		}
+		log.Infof("Skipping %v %v/%v: it is tracked by Argo CD.", current.GetKind(), current.GetNamespace(), current.GetName())
+		if err := resMap.Remove(res.CurId()); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to skip %v %v: %v", current.GetKind(), current.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,495 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+const (
+	// OAuthProxyContainer is the name of the oauth-proxy sidecar. The workloads already running one are left alone.
+	OAuthProxyContainer = "oauth-proxy"
+	// oauthProxyPort is the port the oauth-proxy sidecar serves HTTPS on
+	oauthProxyPort = 8443
+	// cookieSecretKey is the key of the session cookie secret in the oauth config Secret
+	cookieSecretKey = "cookie_secret"
+)
+
+var (
+	// OAuthProxyImage is the image of the oauth-proxy sidecars
+	OAuthProxyImage = "registry.redhat.io/openshift4/ose-oauth-proxy:latest"
+	// AuthorinoProvider is the name of the extension provider of the Service Mesh authorizing the requests with Authorino
+	AuthorinoProvider = "authorino"
+)
+
+// authTarget is a Route of the manifests along with the Service and the workload it exposes
+type authTarget struct {
+	route     *resource.Resource
+	service   *resource.Resource
+	workload  *resource.Resource
+	namespace string
+	// upstream is the port of the pods the Route sends the requests to
+	upstream int64
+}
+
+// injectAuth puts the authentication layer of provider in front of the Routes of resMap, except the ones annotated
+// with kfctl.kubeflow.io/auth=false. Only the users allowed to get the Service of a Route can reach it.
+// Resources without a namespace are in the given namespace.
+func injectAuth(resMap resmap.ResMap, namespace string, provider kfconfig.AuthProvider, serviceMesh kfconfig.ManagementState) error {
+	if provider == "" {
+		return nil
+	}
+	if provider != kfconfig.OAuthProxy && provider != kfconfig.Authorino {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("unknown auth provider %v, must be %v or %v", provider, kfconfig.OAuthProxy, kfconfig.Authorino),
+		}
+	}
+	if provider == kfconfig.Authorino && serviceMesh != kfconfig.Managed {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("the %v auth provider requires a Managed serviceMesh", kfconfig.Authorino),
+		}
+	}
+
+	generated := []map[string]interface{}{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Route" {
+			continue
+		}
+		if auth, err := strconv.ParseBool(res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.Auth}, "/")]); err == nil && !auth {
+			continue
+		}
+		target, err := findAuthTarget(resMap, res, namespace)
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("failed to protect Route %v: %v", res.GetName(), err),
+			}
+		}
+		if target == nil {
+			continue
+		}
+		var objs []map[string]interface{}
+		if provider == kfconfig.OAuthProxy {
+			objs, err = injectOAuthProxy(resMap, target)
+		} else {
+			objs, err = authorizeWithAuthorino(target)
+		}
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to protect Route %v with %v: %v", res.GetName(), provider, err),
+			}
+		}
+		generated = append(generated, objs...)
+	}
+
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, obj := range generated {
+		res := factory.FromMap(obj)
+		if _, err := resMap.GetById(res.OrgId()); err == nil {
+			continue
+		}
+		if err := resMap.Append(res); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
+
+// findAuthTarget returns the Service and the workload of resMap the route exposes, or nil if they are not in resMap
+// or if the workload already runs an oauth-proxy sidecar
+func findAuthTarget(resMap resmap.ResMap, route *resource.Resource, namespace string) (*authTarget, error) {
+	serviceName, _, _ := unstructured.NestedString(route.Map(), "spec", "to", "name")
+	var service *resource.Resource
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "Service" && res.GetName() == serviceName {
+			service = res
+		}
+	}
+	if service == nil {
+		log.Warnf("Not protecting Route %v, its Service %v is not in the manifests", route.GetName(), serviceName)
+		return nil, nil
+	}
+	selector, _, _ := unstructured.NestedStringMap(service.Map(), "spec", "selector")
+	var workload *resource.Resource
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Deployment" || len(selector) == 0 {
+			continue
+		}
+		labels, _, _ := unstructured.NestedStringMap(res.Map(), "spec", "template", "metadata", "labels")
+		matches := true
+		for k, v := range selector {
+			matches = matches && labels[k] == v
+		}
+		if matches {
+			workload = res
+		}
+	}
+	if workload == nil {
+		log.Warnf("Not protecting Route %v, the Deployment of Service %v is not in the manifests", route.GetName(), serviceName)
+		return nil, nil
+	}
+	containers, _, _ := unstructured.NestedSlice(workload.Map(), "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		if c, ok := c.(map[string]interface{}); ok && c["name"] == OAuthProxyContainer {
+			return nil, nil
+		}
+	}
+
+	targetPort, err := routeTargetPort(route, service)
+	if err != nil {
+		return nil, err
+	}
+	upstream := int64(targetPort.IntValue())
+	if targetPort.Type == intstr.String {
+		upstream = containerPort(containers, targetPort.StrVal)
+		if upstream == 0 {
+			return nil, fmt.Errorf("no container of Deployment %v has a port named %v", workload.GetName(), targetPort.StrVal)
+		}
+	}
+	ns := service.GetNamespace()
+	if ns == "" {
+		ns = namespace
+	}
+	return &authTarget{route: route, service: service, workload: workload, namespace: ns, upstream: upstream}, nil
+}
+
+// injectOAuthProxy adds an oauth-proxy sidecar to the workload of the target and sends the requests of the Route
+// to it. It returns the Secret of its session cookies and the ServiceAccount and the ClusterRoleBinding it needs.
+func injectOAuthProxy(resMap resmap.ResMap, target *authTarget) ([]map[string]interface{}, error) {
+	route := &unstructured.Unstructured{Object: target.route.Map()}
+	service := &unstructured.Unstructured{Object: target.service.Map()}
+	serviceName := service.GetName()
+	generated := []map[string]interface{}{}
+
+	// the serving certificate of the sidecar is the one of the Service, requested if it has none
+	tlsSecret := ServingCertificateSecret(service)
+	if tlsSecret == "" {
+		tlsSecret = serviceName + "-oauth-proxy-tls"
+		anns := service.GetAnnotations()
+		if anns == nil {
+			anns = map[string]string{}
+		}
+		anns[strings.Join([]string{utils.KfDefAnnotation, utils.ServingCertSecret}, "/")] = tlsSecret
+		service.SetAnnotations(anns)
+	}
+	ports, _, _ := unstructured.NestedSlice(service.Object, "spec", "ports")
+	ports = append(ports, map[string]interface{}{
+		"name":       OAuthProxyContainer,
+		"port":       int64(oauthProxyPort),
+		"targetPort": int64(oauthProxyPort),
+	})
+	if err := unstructured.SetNestedSlice(service.Object, ports, "spec", "ports"); err != nil {
+		return nil, err
+	}
+	target.service.SetMap(service.Object)
+
+	workload := &unstructured.Unstructured{Object: target.workload.Map()}
+	serviceAccount, _, _ := unstructured.NestedString(workload.Object, "spec", "template", "spec", "serviceAccountName")
+	if serviceAccount == "" {
+		serviceAccount = serviceName + "-oauth-proxy"
+		if err := unstructured.SetNestedField(workload.Object, serviceAccount, "spec", "template", "spec", "serviceAccountName"); err != nil {
+			return nil, err
+		}
+	}
+	redirect, err := json.Marshal(map[string]interface{}{
+		"kind":       "OAuthRedirectReference",
+		"apiVersion": "v1",
+		"reference":  map[string]interface{}{"kind": "Route", "name": route.GetName()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	redirectAnnotation := "serviceaccounts.openshift.io/oauth-redirectreference." + route.GetName()
+	found := false
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "ServiceAccount" || res.GetName() != serviceAccount {
+			continue
+		}
+		found = true
+		sa := &unstructured.Unstructured{Object: res.Map()}
+		anns := sa.GetAnnotations()
+		if anns == nil {
+			anns = map[string]string{}
+		}
+		anns[redirectAnnotation] = string(redirect)
+		sa.SetAnnotations(anns)
+		res.SetMap(sa.Object)
+	}
+	if !found {
+		generated = append(generated, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata": map[string]interface{}{
+				"name":        serviceAccount,
+				"namespace":   target.namespace,
+				"annotations": map[string]interface{}{redirectAnnotation: string(redirect)},
+			},
+		})
+	}
+
+	sar, err := json.Marshal(map[string]interface{}{
+		"namespace":    target.namespace,
+		"resource":     "services",
+		"resourceName": serviceName,
+		"verb":         "get",
+	})
+	if err != nil {
+		return nil, err
+	}
+	cookieSecret := serviceName + "-oauth-config"
+	sidecar := map[string]interface{}{
+		"name":  OAuthProxyContainer,
+		"image": OAuthProxyImage,
+		"args": []interface{}{
+			"--provider=openshift",
+			fmt.Sprintf("--https-address=:%d", oauthProxyPort),
+			"--http-address=",
+			"--openshift-service-account=" + serviceAccount,
+			fmt.Sprintf("--upstream=http://localhost:%d", target.upstream),
+			"--tls-cert=/etc/tls/private/tls.crt",
+			"--tls-key=/etc/tls/private/tls.key",
+			"--cookie-secret-file=/etc/oauth/config/" + cookieSecretKey,
+			"--openshift-sar=" + string(sar),
+		},
+		"ports": []interface{}{
+			map[string]interface{}{"name": OAuthProxyContainer, "containerPort": int64(oauthProxyPort)},
+		},
+		"volumeMounts": []interface{}{
+			map[string]interface{}{"name": "oauth-proxy-tls", "mountPath": "/etc/tls/private"},
+			map[string]interface{}{"name": "oauth-proxy-config", "mountPath": "/etc/oauth/config"},
+		},
+	}
+	containers, _, _ := unstructured.NestedSlice(workload.Object, "spec", "template", "spec", "containers")
+	if err := unstructured.SetNestedSlice(workload.Object, append(containers, sidecar), "spec", "template", "spec", "containers"); err != nil {
+		return nil, err
+	}
+	volumes, _, _ := unstructured.NestedSlice(workload.Object, "spec", "template", "spec", "volumes")
+	volumes = append(volumes,
+		map[string]interface{}{"name": "oauth-proxy-tls", "secret": map[string]interface{}{"secretName": tlsSecret}},
+		map[string]interface{}{"name": "oauth-proxy-config", "secret": map[string]interface{}{"secretName": cookieSecret}},
+	)
+	if err := unstructured.SetNestedSlice(workload.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+		return nil, err
+	}
+	target.workload.SetMap(workload.Object)
+
+	if err := unstructured.SetNestedField(route.Object, OAuthProxyContainer, "spec", "port", "targetPort"); err != nil {
+		return nil, err
+	}
+	tls := map[string]interface{}{"termination": "reencrypt", "insecureEdgeTerminationPolicy": "Redirect"}
+	if err := unstructured.SetNestedMap(route.Object, tls, "spec", "tls"); err != nil {
+		return nil, err
+	}
+	target.route.SetMap(route.Object)
+
+	cookie, err := newCookieSecret()
+	if err != nil {
+		return nil, err
+	}
+	generated = append(generated,
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": cookieSecret, "namespace": target.namespace},
+			"type":       "Opaque",
+			"data":       map[string]interface{}{cookieSecretKey: base64.StdEncoding.EncodeToString([]byte(cookie))},
+		},
+		map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "ClusterRoleBinding",
+			"metadata":   map[string]interface{}{"name": target.namespace + "-" + serviceAccount + "-auth-delegator"},
+			"roleRef": map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     "ClusterRole",
+				"name":     "system:auth-delegator",
+			},
+			"subjects": []interface{}{
+				map[string]interface{}{"kind": "ServiceAccount", "name": serviceAccount, "namespace": target.namespace},
+			},
+		},
+	)
+	return generated, nil
+}
+
+// authorizeWithAuthorino returns the AuthConfig allowing the users who can get the Service of the target, and the
+// AuthorizationPolicy of the Service Mesh sending the requests to its pods to Authorino
+func authorizeWithAuthorino(target *authTarget) ([]map[string]interface{}, error) {
+	serviceName := target.service.GetName()
+	route := &unstructured.Unstructured{Object: target.route.Map()}
+	hosts := []interface{}{}
+	if host, _, _ := unstructured.NestedString(route.Object, "spec", "host"); host != "" {
+		hosts = append(hosts, host)
+	}
+	hosts = append(hosts, serviceName+"."+target.namespace+".svc", serviceName+"."+target.namespace+".svc.cluster.local")
+	selector, _, _ := unstructured.NestedMap(target.service.Map(), "spec", "selector")
+
+	return []map[string]interface{}{
+		{
+			"apiVersion": "authorino.kuadrant.io/v1beta1",
+			"kind":       "AuthConfig",
+			"metadata":   map[string]interface{}{"name": serviceName + "-auth", "namespace": target.namespace},
+			"spec": map[string]interface{}{
+				"hosts": hosts,
+				"identity": []interface{}{
+					map[string]interface{}{
+						"name":       "kubernetes-users",
+						"kubernetes": map[string]interface{}{"audiences": []interface{}{"https://kubernetes.default.svc"}},
+					},
+				},
+				"authorization": []interface{}{
+					map[string]interface{}{
+						"name": "kubernetes-rbac",
+						"kubernetes": map[string]interface{}{
+							"user": map[string]interface{}{
+								"valueFrom": map[string]interface{}{"authJSON": "auth.identity.user.username"},
+							},
+							"resourceAttributes": map[string]interface{}{
+								"namespace": map[string]interface{}{"value": target.namespace},
+								"resource":  map[string]interface{}{"value": "services"},
+								"name":      map[string]interface{}{"value": serviceName},
+								"verb":      map[string]interface{}{"value": "get"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"apiVersion": "security.istio.io/v1beta1",
+			"kind":       "AuthorizationPolicy",
+			"metadata":   map[string]interface{}{"name": serviceName + "-auth", "namespace": target.namespace},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"matchLabels": selector},
+				"action":   "CUSTOM",
+				"provider": map[string]interface{}{"name": AuthorinoProvider},
+				"rules":    []interface{}{map[string]interface{}{}},
+			},
+		},
+	}, nil
+}
+
+// keepCookieSecrets replaces the session cookie secrets generated for the oauth-proxy sidecars with the ones
+// of the Secrets already deployed, so that the sessions of the users survive the applies
+func keepCookieSecrets(resMap resmap.ResMap, namespace string, kubeclient client.Client) error {
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Secret" || !strings.HasSuffix(res.GetName(), "-oauth-config") {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		current := &v1.Secret{}
+		if err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: ns, Name: obj.GetName()}, current); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to get Secret %v/%v: %v", ns, obj.GetName(), err),
+			}
+		}
+		cookie, ok := current.Data[cookieSecretKey]
+		if !ok {
+			continue
+		}
+		if err := unstructured.SetNestedField(obj.Object, base64.StdEncoding.EncodeToString(cookie), "data", cookieSecretKey); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to keep the cookie secret of Secret %v/%v: %v", ns, obj.GetName(), err),
+			}
+		}
+		res.SetMap(obj.Object)
+	}
+	return nil
+}
+
+// newCookieSecret returns a random secret of 32 characters for the session cookies of oauth-proxy
+func newCookieSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// routeTargetPort returns the port of the pods the route sends the requests to: its target port, or the target port
+// of the port of the Service it names, or of the first port of the Service
+func routeTargetPort(route *resource.Resource, service *resource.Resource) (intstr.IntOrString, error) {
+	routePort, found, _ := unstructured.NestedFieldNoCopy(route.Map(), "spec", "port", "targetPort")
+	switch port := routePort.(type) {
+	case int64:
+		return intstr.FromInt(int(port)), nil
+	case float64:
+		return intstr.FromInt(int(port)), nil
+	case string:
+		if p, err := strconv.Atoi(port); err == nil {
+			return intstr.FromInt(p), nil
+		}
+	}
+	ports, _, _ := unstructured.NestedSlice(service.Map(), "spec", "ports")
+	for _, p := range ports {
+		p, ok := p.(map[string]interface{})
+		if !ok || (found && p["name"] != routePort) {
+			continue
+		}
+		target, ok := p["targetPort"]
+		if !ok {
+			target = p["port"]
+		}
+		switch target := target.(type) {
+		case int64:
+			return intstr.FromInt(int(target)), nil
+		case float64:
+			return intstr.FromInt(int(target)), nil
+		case string:
+			return intstr.Parse(target), nil
+		}
+	}
+	if found {
+		return intstr.IntOrString{}, fmt.Errorf("Service %v has no port named %v", service.GetName(), routePort)
+	}
+	return intstr.IntOrString{}, fmt.Errorf("Service %v has no port", service.GetName())
+}
+
+// containerPort returns the port named name of the containers, or 0
+func containerPort(containers []interface{}, name string) int64 {
+	for _, c := range containers {
+		c, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ports, _, _ := unstructured.NestedSlice(c, "ports")
+		for _, p := range ports {
+			if p, ok := p.(map[string]interface{}); ok && p["name"] == name {
+				port, _, _ := unstructured.NestedInt64(p, "containerPort")
+				return port
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+// defaultTargetCPUUtilization is the CPU utilization the Deployments are scaled on when the autoscaling sets no target
+const defaultTargetCPUUtilization = 80
+
+// injectAutoscaling adds a HorizontalPodAutoscaler, named after the Deployment, for the Deployments of resMap the
+// autoscaling scales, and removes their replicas for the operator not to reset the ones set by the
+// HorizontalPodAutoscaler. The Deployments scaled by a HorizontalPodAutoscaler of the manifests are left alone.
+func injectAutoscaling(resMap resmap.ResMap, autoscaling *kfconfig.Autoscaling) error {
+	if autoscaling == nil {
+		return nil
+	}
+	scaled := map[string]bool{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "HorizontalPodAutoscaler" {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(res.Map(), "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(res.Map(), "spec", "scaleTargetRef", "name")
+		if kind == "Deployment" {
+			scaled[name] = true
+		}
+	}
+	wanted := map[string]bool{}
+	for _, name := range autoscaling.Deployments {
+		wanted[name] = true
+	}
+
+	autoscalers := []*resource.Resource{}
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Deployment" || (len(wanted) > 0 && !wanted[res.GetName()]) {
+			continue
+		}
+		delete(wanted, res.GetName())
+		if scaled[res.GetName()] {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		unstructured.RemoveNestedField(obj.Object, "spec", "replicas")
+		res.SetMap(obj.Object)
+
+		metadata := map[string]interface{}{"name": res.GetName()}
+		if res.GetNamespace() != "" {
+			metadata["namespace"] = res.GetNamespace()
+		}
+		minReplicas := int64(1)
+		if autoscaling.MinReplicas != nil {
+			minReplicas = int64(*autoscaling.MinReplicas)
+		}
+		autoscalers = append(autoscalers, factory.FromMap(map[string]interface{}{
+			"apiVersion": "autoscaling/v2",
+			"kind":       "HorizontalPodAutoscaler",
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"scaleTargetRef": map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       res.GetName(),
+				},
+				"minReplicas": minReplicas,
+				"maxReplicas": int64(autoscaling.MaxReplicas),
+				"metrics":     autoscalingMetrics(autoscaling),
+			},
+		}))
+	}
+	if len(wanted) > 0 {
+		missing := []string{}
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("the manifests have no Deployment %v to autoscale", strings.Join(missing, ", ")),
+		}
+	}
+	for _, autoscaler := range autoscalers {
+		if err := resMap.Append(autoscaler); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add HorizontalPodAutoscaler %v: %v", autoscaler.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
+
+// autoscalingMetrics returns the metrics of the HorizontalPodAutoscalers of autoscaling: the utilizations of the CPU
+// and the memory, then the custom metrics of the pods
+func autoscalingMetrics(autoscaling *kfconfig.Autoscaling) []interface{} {
+	utilization := func(name string, target int32) interface{} {
+		return map[string]interface{}{
+			"type": "Resource",
+			"resource": map[string]interface{}{
+				"name":   name,
+				"target": map[string]interface{}{"type": "Utilization", "averageUtilization": int64(target)},
+			},
+		}
+	}
+	metrics := []interface{}{}
+	if autoscaling.TargetCPUUtilization != nil {
+		metrics = append(metrics, utilization("cpu", *autoscaling.TargetCPUUtilization))
+	}
+	if autoscaling.TargetMemoryUtilization != nil {
+		metrics = append(metrics, utilization("memory", *autoscaling.TargetMemoryUtilization))
+	}
+	for _, m := range autoscaling.Metrics {
+		metrics = append(metrics, map[string]interface{}{
+			"type": "Pods",
+			"pods": map[string]interface{}{
+				"metric": map[string]interface{}{"name": m.Name},
+				"target": map[string]interface{}{"type": "AverageValue", "averageValue": m.TargetAverageValue},
+			},
+		})
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, utilization("cpu", defaultTargetCPUUtilization))
+	}
+	return metrics
+}
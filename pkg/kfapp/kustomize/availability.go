@@ -0,0 +1,135 @@
+package kustomize
+
+import (
+	"fmt"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+// defaultTopologyKey spreads the pods of a workload across the nodes
+const defaultTopologyKey = "kubernetes.io/hostname"
+
+// mergeAvailability returns the availability of an application: its own, or the one of the spec
+func mergeAvailability(spec *kfconfig.Availability, app *kfconfig.Availability) *kfconfig.Availability {
+	if app != nil {
+		return app
+	}
+	return spec
+}
+
+// injectAvailability adds a PodDisruptionBudget and topology spread constraints to the Deployments and the
+// StatefulSets of resMap running several replicas. The workloads whose pods are selected by a PodDisruptionBudget
+// of the manifests get none, and the pod templates setting topology spread constraints keep theirs.
+func injectAvailability(resMap resmap.ResMap, availability *kfconfig.Availability) error {
+	if availability == nil {
+		return nil
+	}
+	maxUnavailable := intstr.FromInt(1)
+	if availability.MaxUnavailable != nil {
+		maxUnavailable = *availability.MaxUnavailable
+	}
+	topologyKey := availability.TopologyKey
+	if topologyKey == "" {
+		topologyKey = defaultTopologyKey
+	}
+
+	budgets := []*resource.Resource{}
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Deployment" && res.GetKind() != "StatefulSet" {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found || replicas < 2 {
+			continue
+		}
+		selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+		if len(selector) == 0 {
+			log.Warnf("Not protecting %v %v from disruptions, it has no matchLabels selector", res.GetKind(), res.GetName())
+			continue
+		}
+		matchLabels := stringMap(selector)
+
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "template", "spec", "topologySpreadConstraints"); !found {
+			constraints := []interface{}{
+				map[string]interface{}{
+					"maxSkew":           int64(1),
+					"topologyKey":       topologyKey,
+					"whenUnsatisfiable": "ScheduleAnyway",
+					"labelSelector":     map[string]interface{}{"matchLabels": matchLabels},
+				},
+			}
+			if err := unstructured.SetNestedSlice(obj.Object, constraints, "spec", "template", "spec", "topologySpreadConstraints"); err != nil {
+				return &kfapisv3.KfError{
+					Code:    int(kfapisv3.INTERNAL_ERROR),
+					Message: fmt.Sprintf("failed to spread the pods of %v %v: %v", res.GetKind(), res.GetName(), err),
+				}
+			}
+			res.SetMap(obj.Object)
+		}
+
+		labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		if hasDisruptionBudget(resMap, labels) {
+			continue
+		}
+		metadata := map[string]interface{}{"name": res.GetName()}
+		if obj.GetNamespace() != "" {
+			metadata["namespace"] = obj.GetNamespace()
+		}
+		var max interface{} = maxUnavailable.StrVal
+		if maxUnavailable.Type == intstr.Int {
+			max = int64(maxUnavailable.IntVal)
+		}
+		budget := factory.FromMap(map[string]interface{}{
+			"apiVersion": "policy/v1",
+			"kind":       "PodDisruptionBudget",
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"maxUnavailable": max,
+				"selector":       map[string]interface{}{"matchLabels": matchLabels},
+			},
+		})
+		if _, err := resMap.GetById(budget.OrgId()); err == nil {
+			continue
+		}
+		budgets = append(budgets, budget)
+	}
+	for _, budget := range budgets {
+		if err := resMap.Append(budget); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add PodDisruptionBudget %v: %v", budget.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
+
+// hasDisruptionBudget returns true if a PodDisruptionBudget of resMap selects the pods with the labels
+func hasDisruptionBudget(resMap resmap.ResMap, labels map[string]string) bool {
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "PodDisruptionBudget" {
+			continue
+		}
+		selector, _, _ := unstructured.NestedStringMap(res.Map(), "spec", "selector", "matchLabels")
+		if len(selector) == 0 {
+			continue
+		}
+		matches := true
+		for k, v := range selector {
+			matches = matches && labels[k] == v
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
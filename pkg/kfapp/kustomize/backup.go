@@ -0,0 +1,175 @@
+package kustomize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// RestoreOrderLabel is the label of the resources of every application giving the order they are restored in.
+	// The applications are restored after the ones they depend on, which have a lower order.
+	RestoreOrderLabel = utils.KfDefAnnotation + "/restore-order"
+	// backupDumpFile is the file of the data volume of a database the pre-backup hook dumps the database to
+	backupDumpFile = "velero-backup.sql"
+	// backupHookTimeout is the time the database has to be dumped before the backup fails
+	backupHookTimeout = "10m"
+)
+
+// backupDumpCommands are the shell commands dumping the databases of the engines supported by the
+// kfctl.kubeflow.io/backup annotation. They read the credentials from the environment variables of the images of
+// the sclorg databases and are formatted with the path of the dump.
+var backupDumpCommands = map[string]string{
+	"mysql":      `mysqldump --single-transaction --routines -u"$MYSQL_USER" -p"$MYSQL_PASSWORD" "$MYSQL_DATABASE" > %v`,
+	"mariadb":    `mysqldump --single-transaction --routines -u"$MYSQL_USER" -p"$MYSQL_PASSWORD" "$MYSQL_DATABASE" > %v`,
+	"postgresql": `PGPASSWORD="$POSTGRESQL_PASSWORD" pg_dump -U "$POSTGRESQL_USER" "$POSTGRESQL_DATABASE" > %v`,
+}
+
+// restoreOrder returns the restore order of the application: 0 if it depends on no application, and one more than
+// the highest order of the applications it depends on otherwise. The dependencies are checked by applicationOrder.
+func restoreOrder(applications []kfconfig.Application, name string) int {
+	dependencies := map[string][]string{}
+	for _, app := range applications {
+		if _, ok := dependencies[app.Name]; !ok {
+			dependencies[app.Name] = app.DependsOn
+		}
+	}
+	orders := map[string]int{}
+	var order func(name string, depth int) int
+	order = func(name string, depth int) int {
+		if o, ok := orders[name]; ok {
+			return o
+		}
+		o := 0
+		// Cycles are reported by applicationOrder, the depth only prevents an infinite recursion
+		if depth <= len(dependencies) {
+			for _, dependency := range dependencies[name] {
+				if d := order(dependency, depth+1) + 1; d > o {
+					o = d
+				}
+			}
+		}
+		orders[name] = o
+		return o
+	}
+	return order(name, 0)
+}
+
+// injectBackupHooks labels every resource of resMap with its restore order, and adds the Velero backup hooks to the
+// workloads annotated with kfctl.kubeflow.io/backup, naming the engine of the database they run. Before the volumes
+// are backed up, the database is dumped to the first volume backed by a PersistentVolumeClaim mounted by the first
+// container, and these volumes are listed for the file system backup of Velero.
+func injectBackupHooks(resMap resmap.ResMap, order int) error {
+	for _, res := range resMap.Resources() {
+		labels := res.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[RestoreOrderLabel] = strconv.Itoa(order)
+		res.SetLabels(labels)
+
+		engine := res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.Backup}, "/")]
+		if engine == "" {
+			continue
+		}
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		obj := res.Map()
+		annotations, err := backupHooks(obj, path, engine)
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("failed to add the backup hooks to %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		annotationsPath := append(append([]string{}, path[:len(path)-1]...), "metadata", "annotations")
+		podAnnotations, _, _ := unstructured.NestedStringMap(obj, annotationsPath...)
+		if podAnnotations == nil {
+			podAnnotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			podAnnotations[k] = v
+		}
+		if err := unstructured.SetNestedStringMap(obj, podAnnotations, annotationsPath...); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add the backup hooks to %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		res.SetMap(obj)
+	}
+	return nil
+}
+
+// BackupHooks returns the Velero annotations of the pod template of a workload running a database of the engine,
+// mysql, mariadb or postgresql, given the spec of its pods. They are the annotations kfctl.kubeflow.io/backup adds
+// to the workloads of the manifests.
+func BackupHooks(podSpec map[string]interface{}, engine string) (map[string]string, error) {
+	return backupHooks(podSpec, nil, engine)
+}
+
+// backupHooks returns the Velero annotations of the pod template at path of the workload running a database of
+// the engine
+func backupHooks(obj map[string]interface{}, path []string, engine string) (map[string]string, error) {
+	dump, ok := backupDumpCommands[engine]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database engine %v", engine)
+	}
+	claims := map[string]bool{}
+	volumes, _, _ := unstructured.NestedSlice(obj, append(append([]string{}, path...), "volumes")...)
+	for _, v := range volumes {
+		v, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, found := v["persistentVolumeClaim"]; found {
+			claims[fmt.Sprint(v["name"])] = true
+		}
+	}
+	containers, _, _ := unstructured.NestedSlice(obj, append(append([]string{}, path...), "containers")...)
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("it has no container")
+	}
+	container, _ := containers[0].(map[string]interface{})
+	mounts, _, _ := unstructured.NestedSlice(container, "volumeMounts")
+	backedUp := []string{}
+	dir := ""
+	for _, m := range mounts {
+		m, ok := m.(map[string]interface{})
+		if !ok || !claims[fmt.Sprint(m["name"])] {
+			continue
+		}
+		backedUp = append(backedUp, fmt.Sprint(m["name"]))
+		if dir == "" {
+			dir = fmt.Sprint(m["mountPath"])
+		}
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("container %v mounts no PersistentVolumeClaim to dump the database to", container["name"])
+	}
+
+	// The redirection of the dump is kept readable in the annotation
+	var command bytes.Buffer
+	encoder := json.NewEncoder(&command)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode([]string{"/bin/bash", "-c", fmt.Sprintf(dump, strings.TrimSuffix(dir, "/")+"/"+backupDumpFile)}); err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"pre.hook.backup.velero.io/container": fmt.Sprint(container["name"]),
+		"pre.hook.backup.velero.io/command":   strings.TrimSpace(command.String()),
+		"pre.hook.backup.velero.io/on-error":  "Fail",
+		"pre.hook.backup.velero.io/timeout":   backupHookTimeout,
+		"backup.velero.io/backup-volumes":     strings.Join(backedUp, ","),
+	}, nil
+}
@@ -0,0 +1,253 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// TrustedCABundleConfigMap is the ConfigMap holding the trusted CA bundle in the namespaces of the Deployments
+	TrustedCABundleConfigMap = "odh-trusted-ca-bundle"
+	// TrustedCABundleKey is the key of the trusted CA bundle in TrustedCABundleConfigMap
+	TrustedCABundleKey = "ca-bundle.crt"
+	// TrustedCABundleMountPath is the directory of the trusted CA bundle mounted in the containers of the Deployments
+	TrustedCABundleMountPath = "/etc/pki/ca-trust/extracted/pem"
+	// InjectTrustedCABundleLabel makes OpenShift fill a ConfigMap with the cluster-wide trusted CA bundle
+	InjectTrustedCABundleLabel = "config.openshift.io/inject-trusted-cabundle"
+
+	trustedCABundleVolume = "trusted-ca-bundle"
+	trustedCABundleFile   = "tls-ca-bundle.pem"
+	// trustedCABundleMountFile is the bundle of the images the trusted CA bundle is mounted in place of
+	trustedCABundleMountFile = TrustedCABundleMountPath + "/" + trustedCABundleFile
+	// trustedCABundleHash is the pod template annotation rolling the Deployments out when the bundle changes
+	trustedCABundleHash = "trusted-ca-bundle-hash"
+)
+
+// SystemCABundleFile is the bundle of the public CAs of the operator image, the same as the one of the images of
+// the components. The bundles of the trusted CA bundle ConfigMaps are appended to it, as the mounted bundle replaces
+// the one of the images.
+var SystemCABundleFile = trustedCABundleMountFile
+
+// injectTrustedCABundle mounts the trusted CA bundle in the containers of the Deployments of resMap.
+// The bundle is kept in TrustedCABundleConfigMap in the namespace of every Deployment, filled by OpenShift with the
+// cluster-wide trusted CA bundle, or copied from the ConfigMap named by the kfctl.kubeflow.io/trusted-ca-bundle annotation.
+// Deployments are left alone while the bundle of their namespace is empty. The ConfigMaps are only read when sync is false.
+func (kustomize *kustomize) injectTrustedCABundle(resMap resmap.ResMap, kubeclient client.Client, sync bool) error {
+	source, err := kustomize.trustedCABundleSource(kubeclient)
+	if err != nil {
+		return err
+	}
+	bundles := map[string]string{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Deployment" {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: res.Map()}
+		namespace := u.GetNamespace()
+		if namespace == "" {
+			namespace = kustomize.kfDef.Namespace
+		}
+		bundle, ok := bundles[namespace]
+		if !ok {
+			if bundle, err = kustomize.syncTrustedCABundle(kubeclient, namespace, source, sync); err != nil {
+				return err
+			}
+			bundles[namespace] = bundle
+		}
+		if bundle == "" {
+			continue
+		}
+		if err := mountTrustedCABundle(u, bundle); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to mount the trusted CA bundle in Deployment %v: %v", u.GetName(), err),
+			}
+		}
+		res.SetMap(u.Object)
+	}
+	return nil
+}
+
+// trustedCABundleSource returns the bundle of the ConfigMap named by the kfctl.kubeflow.io/trusted-ca-bundle annotation,
+// or nil when the bundle is injected by OpenShift. The bundle is the TrustedCABundleKey of the ConfigMap, or all its keys,
+// appended to the system CA bundle.
+func (kustomize *kustomize) trustedCABundleSource(kubeclient client.Client) (*string, error) {
+	ref := kustomize.kfDef.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.TrustedCABundle}, "/")]
+	if ref == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("trusted CA bundle ConfigMap %v must be of the form <namespace>/<name>", ref),
+		}
+	}
+	cm := &v1.ConfigMap{}
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: parts[0], Name: parts[1]}, cm); err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("failed to get the trusted CA bundle ConfigMap %v: %v", ref, err),
+		}
+	}
+	keys := []string{TrustedCABundleKey}
+	if _, ok := cm.Data[TrustedCABundleKey]; !ok {
+		keys = []string{}
+		for key := range cm.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+	var bundle strings.Builder
+	system, err := ioutil.ReadFile(SystemCABundleFile)
+	if err != nil {
+		log.Warnf("Failed to read the system CA bundle %v, the trusted CA bundle %v must hold the public CAs: %v",
+			SystemCABundleFile, ref, err)
+	} else {
+		bundle.WriteString(strings.TrimSpace(string(system)) + "\n")
+	}
+	for _, key := range keys {
+		bundle.WriteString(strings.TrimSpace(cm.Data[key]) + "\n")
+	}
+	b := bundle.String()
+	return &b, nil
+}
+
+// syncTrustedCABundle creates or updates TrustedCABundleConfigMap in namespace and returns its bundle.
+// Without source, the ConfigMap is labelled for OpenShift to fill it. The bundle is empty until the namespace exists.
+func (kustomize *kustomize) syncTrustedCABundle(kubeclient client.Client, namespace string, source *string, sync bool) (string, error) {
+	cm := &v1.ConfigMap{}
+	err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: TrustedCABundleConfigMap}, cm)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return "", &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get ConfigMap %v/%v: %v", namespace, TrustedCABundleConfigMap, err),
+		}
+	}
+	if !sync {
+		return cm.Data[TrustedCABundleKey], nil
+	}
+	if k8serrors.IsNotFound(err) {
+		kfdefAnn := strings.Join([]string{utils.KfDefAnnotation, utils.KfDefInstance}, "/")
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        TrustedCABundleConfigMap,
+				Namespace:   namespace,
+				Labels:      utils.WithManagedLabel(nil),
+				Annotations: map[string]string{kfdefAnn: kustomize.kfDef.Name + "." + kustomize.kfDef.Namespace},
+			},
+		}
+		if source == nil {
+			cm.Labels[InjectTrustedCABundleLabel] = "true"
+		} else {
+			cm.Data = map[string]string{TrustedCABundleKey: *source}
+		}
+		if err := kubeclient.Create(context.TODO(), cm); err != nil {
+			if k8serrors.IsNotFound(err) {
+				log.Infof("Namespace %v does not exist yet, not mounting the trusted CA bundle.", namespace)
+				return "", nil
+			}
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to create ConfigMap %v/%v: %v", namespace, TrustedCABundleConfigMap, err),
+			}
+		}
+		return cm.Data[TrustedCABundleKey], nil
+	}
+	if (source != nil && cm.Data[TrustedCABundleKey] != *source) || !utils.IsManaged(cm) {
+		cm.Labels = utils.WithManagedLabel(cm.Labels)
+		if source != nil {
+			cm.Data = map[string]string{TrustedCABundleKey: *source}
+		}
+		if err := kubeclient.Update(context.TODO(), cm); err != nil {
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to update ConfigMap %v/%v: %v", namespace, TrustedCABundleConfigMap, err),
+			}
+		}
+	}
+	return cm.Data[TrustedCABundleKey], nil
+}
+
+// mountTrustedCABundle mounts the bundle of TrustedCABundleConfigMap in the containers of the Deployment u in place of
+// the bundle of their image, and annotates its pod template with the hash of bundle so that the Deployment is rolled
+// out when the bundle changes. Only the bundle file is mounted, the other files of TrustedCABundleMountPath are kept.
+func mountTrustedCABundle(u *unstructured.Unstructured, bundle string) error {
+	hashAnn := strings.Join([]string{utils.KfDefAnnotation, trustedCABundleHash}, "/")
+	err := unstructured.SetNestedField(u.Object, fmt.Sprintf("%x", sha256.Sum256([]byte(bundle))),
+		"spec", "template", "metadata", "annotations", hashAnn)
+	if err != nil {
+		return err
+	}
+
+	volumes, _, err := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "volumes")
+	if err != nil {
+		return err
+	}
+	if !hasNamedItem(volumes, "name", trustedCABundleVolume) {
+		volumes = append(volumes, map[string]interface{}{
+			"name": trustedCABundleVolume,
+			"configMap": map[string]interface{}{
+				"name":  TrustedCABundleConfigMap,
+				"items": []interface{}{map[string]interface{}{"key": TrustedCABundleKey, "path": trustedCABundleFile}},
+			},
+		})
+		if err := unstructured.SetNestedSlice(u.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, err := unstructured.NestedSlice(u.Object, "spec", "template", "spec", field)
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mounts, _ := container["volumeMounts"].([]interface{})
+			if hasNamedItem(mounts, "name", trustedCABundleVolume) || hasNamedItem(mounts, "mountPath", TrustedCABundleMountPath) ||
+				hasNamedItem(mounts, "mountPath", trustedCABundleMountFile) {
+				continue
+			}
+			container["volumeMounts"] = append(mounts, map[string]interface{}{
+				"name":      trustedCABundleVolume,
+				"mountPath": trustedCABundleMountFile,
+				"subPath":   trustedCABundleFile,
+				"readOnly":  true,
+			})
+		}
+		if len(containers) > 0 {
+			if err := unstructured.SetNestedSlice(u.Object, containers, "spec", "template", "spec", field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasNamedItem returns true if one of items has field set to value
+func hasNamedItem(items []interface{}, field string, value string) bool {
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok && m[field] == value {
+			return true
+		}
+	}
+	return false
+}
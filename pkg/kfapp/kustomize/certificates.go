@@ -0,0 +1,96 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+// ServiceCAAnnotation makes the OpenShift service CA operator issue and rotate a serving certificate for a Service
+const ServiceCAAnnotation = "service.beta.openshift.io/serving-cert-secret-name"
+
+var (
+	// CertManagerIssuer is the cert-manager ClusterIssuer of the serving certificates.
+	// They are issued by the OpenShift service CA when it is empty.
+	CertManagerIssuer = ""
+	// CertificateRenewBefore is how long before their expiry the serving certificates are issued again
+	CertificateRenewBefore = 30 * 24 * time.Hour
+)
+
+// ServingCertificateSecret returns the name of the secret of the serving certificate requested by a Service of the
+// manifests with the kfctl.kubeflow.io/serving-cert-secret annotation, or an empty string.
+func ServingCertificateSecret(service *unstructured.Unstructured) string {
+	if service.GetKind() != "Service" {
+		return ""
+	}
+	return service.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.ServingCertSecret}, "/")]
+}
+
+// issueServingCertificates requests a serving certificate for every Service of resMap annotated with
+// kfctl.kubeflow.io/serving-cert-secret, stored in the secret named by the annotation. The certificates
+// are issued by cert-manager with a Certificate added to resMap when CertManagerIssuer is set, and by
+// the OpenShift service CA otherwise. Services without a namespace are in the given namespace.
+func issueServingCertificates(resMap resmap.ResMap, namespace string) error {
+	certificates := []*resource.Resource{}
+	for _, res := range resMap.Resources() {
+		service := &unstructured.Unstructured{Object: res.Map()}
+		secret := ServingCertificateSecret(service)
+		if secret == "" {
+			continue
+		}
+		if CertManagerIssuer == "" {
+			anns := service.GetAnnotations()
+			anns[ServiceCAAnnotation] = secret
+			service.SetAnnotations(anns)
+			res.SetMap(service.Object)
+			continue
+		}
+
+		ns := service.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		name := service.GetName()
+		metadata := map[string]interface{}{"name": name + "-serving-cert"}
+		if service.GetNamespace() != "" {
+			metadata["namespace"] = service.GetNamespace()
+		}
+		certificates = append(certificates, resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl()).FromMap(
+			map[string]interface{}{
+				"apiVersion": "cert-manager.io/v1",
+				"kind":       "Certificate",
+				"metadata":   metadata,
+				"spec": map[string]interface{}{
+					"secretName": secret,
+					"dnsNames": []interface{}{
+						name,
+						name + "." + ns,
+						name + "." + ns + ".svc",
+						name + "." + ns + ".svc.cluster.local",
+					},
+					"issuerRef": map[string]interface{}{
+						"group": "cert-manager.io",
+						"kind":  "ClusterIssuer",
+						"name":  CertManagerIssuer,
+					},
+					"renewBefore": CertificateRenewBefore.String(),
+				},
+			}))
+	}
+	for _, certificate := range certificates {
+		if err := resMap.Append(certificate); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add Certificate %v: %v", certificate.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ChangedAtKey is the key of the changes ConfigMap holding the time the manifests last changed
+const ChangedAtKey = "changedAt"
+
+// ChangesConfigMapName returns the name of the ConfigMap recording the resources added, changed and removed by the
+// last change of the manifests of the KfDef, keyed by application name
+func ChangesConfigMapName(kfdef string) string {
+	return kfdef + "-changes"
+}
+
+// manifestChanges returns, for every application whose resources changed from the previous inventory to the current
+// one, the resources it added, changed and removed, one per line. Only the kinds and the names of the resources are
+// listed, never their content. The resources of the previous inventory recorded without digests are not reported as
+// changed.
+func manifestChanges(previous map[string][]inventoryItem, current map[string][]inventoryItem) map[string]string {
+	type change struct {
+		key  string
+		line string
+	}
+	apps := map[string]bool{}
+	for app := range previous {
+		apps[app] = true
+	}
+	for app := range current {
+		apps[app] = true
+	}
+	changes := map[string]string{}
+	for app := range apps {
+		before := map[string]inventoryItem{}
+		for _, item := range previous[app] {
+			before[item.key()] = item
+		}
+		after := map[string]bool{}
+		changed := []change{}
+		for _, item := range current[app] {
+			after[item.key()] = true
+			old, ok := before[item.key()]
+			switch {
+			case !ok:
+				changed = append(changed, change{item.key(), fmt.Sprintf("+ %v %v: added", item.Kind, item.name())})
+			case old.Digest != "" && item.Digest != "" && old.Digest != item.Digest:
+				changed = append(changed, change{item.key(), fmt.Sprintf("~ %v %v: changed", item.Kind, item.name())})
+			}
+		}
+		for _, item := range previous[app] {
+			if !after[item.key()] {
+				changed = append(changed, change{item.key(), fmt.Sprintf("- %v %v: removed", item.Kind, item.name())})
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		sort.Slice(changed, func(i, j int) bool { return changed[i].key < changed[j].key })
+		var lines strings.Builder
+		for _, c := range changed {
+			lines.WriteString(c.line + "\n")
+		}
+		changes[app] = lines.String()
+	}
+	return changes
+}
+
+// name returns the namespace and the name of the resource
+func (i inventoryItem) name() string {
+	if i.Namespace == "" {
+		return i.Name
+	}
+	return i.Namespace + "/" + i.Name
+}
+
+// writeManifestChanges replaces the changes ConfigMap of the KfDef with the changes of its applications, changed at
+// now. The ConfigMap is left as it is when nothing changed, so that it keeps the last change.
+func writeManifestChanges(kubeclient client.Client, kfdef string, namespace string, changes map[string]string, now time.Time) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	data := map[string]string{ChangedAtKey: now.UTC().Format(time.RFC3339)}
+	apps := []string{}
+	for app, lines := range changes {
+		data[app] = lines
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	if err := writeKfDefConfigMap(kubeclient, kfdef, namespace, ChangesConfigMapName(kfdef), data); err != nil {
+		return err
+	}
+	log.Infof("Changes of the manifests of %v written to ConfigMap %v/%v", strings.Join(apps, ", "), namespace,
+		ChangesConfigMapName(kfdef))
+	return nil
+}
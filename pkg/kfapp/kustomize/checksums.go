@@ -0,0 +1,157 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// ConfigChecksumAnnotation is the annotation of the pod templates holding the checksum of the ConfigMaps and the
+// Secrets of the manifests their pods reference, so that the pods are rolled out again once these change
+const ConfigChecksumAnnotation = "opendatahub.io/config-checksum"
+
+// checksumPodTemplatePaths are the paths of the pod templates of the kinds of resources the checksums are injected
+// into. The pod template of a Job is immutable, and the pods of the other kinds are not rolled out again.
+var checksumPodTemplatePaths = map[string][]string{
+	"Deployment":       {"spec", "template"},
+	"StatefulSet":      {"spec", "template"},
+	"DaemonSet":        {"spec", "template"},
+	"DeploymentConfig": {"spec", "template"},
+}
+
+// injectConfigChecksums sets the checksum annotation of the pod templates of the workloads of resMap referencing
+// ConfigMaps or Secrets of resMap, through their volumes or the environment of their containers. The checksum
+// covers the data of the referenced resources, so that changing them rolls out the workloads.
+func injectConfigChecksums(resMap resmap.ResMap) error {
+	hashes := map[string]string{}
+	for _, res := range resMap.Resources() {
+		kind := res.GetKind()
+		if kind != "ConfigMap" && kind != "Secret" {
+			continue
+		}
+		hash, err := configHash(res.Map())
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to compute the checksum of %v %v: %v", kind, res.GetName(), err),
+			}
+		}
+		hashes[configKey(kind, res.GetNamespace(), res.GetName())] = hash
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	for _, res := range resMap.Resources() {
+		path, ok := checksumPodTemplatePaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		field, found, err := unstructured.NestedFieldNoCopy(obj.Object, append(path, "spec")...)
+		spec, ok := field.(map[string]interface{})
+		if err != nil || !found || !ok {
+			continue
+		}
+		refs := []string{}
+		for _, ref := range configReferences(spec) {
+			key := configKey(ref[0], res.GetNamespace(), ref[1])
+			if hash, ok := hashes[key]; ok {
+				refs = append(refs, key+"="+hash)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		sort.Strings(refs)
+		sum := sha256.Sum256([]byte(strings.Join(refs, "\n")))
+
+		annotations, _, _ := unstructured.NestedStringMap(obj.Object, append(path, "metadata", "annotations")...)
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[ConfigChecksumAnnotation] = hex.EncodeToString(sum[:])
+		if err := unstructured.SetNestedStringMap(obj.Object, annotations, append(path, "metadata", "annotations")...); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to inject the config checksum into %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		res.SetMap(obj.Object)
+	}
+	return nil
+}
+
+// configKey returns the key of a ConfigMap or a Secret in the checksums
+func configKey(kind string, namespace string, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// configHash returns the hex encoded sha256 of the data, the string data and the binary data of a ConfigMap or a
+// Secret. The keys are encoded in order, so that the hash is stable.
+func configHash(obj map[string]interface{}) (string, error) {
+	content := map[string]interface{}{}
+	for _, field := range []string{"data", "stringData", "binaryData"} {
+		if v, ok := obj[field]; ok {
+			content[field] = v
+		}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// configReferences returns the kind and the name of the ConfigMaps and the Secrets referenced by the volumes of a
+// pod spec and by the environment of its containers
+func configReferences(spec map[string]interface{}) [][2]string {
+	refs := [][2]string{}
+	add := func(kind string, obj interface{}, fields ...string) {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if name, found, _ := unstructured.NestedString(m, fields...); found && name != "" {
+			refs = append(refs, [2]string{kind, name})
+		}
+	}
+
+	volumes, _ := spec["volumes"].([]interface{})
+	for _, v := range volumes {
+		add("ConfigMap", v, "configMap", "name")
+		add("Secret", v, "secret", "secretName")
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		projected, _ := volume["projected"].(map[string]interface{})
+		sources, _ := projected["sources"].([]interface{})
+		for _, s := range sources {
+			add("ConfigMap", s, "configMap", "name")
+			add("Secret", s, "secret", "name")
+		}
+	}
+
+	walkContainers(spec, func(container map[string]interface{}) {
+		envFrom, _ := container["envFrom"].([]interface{})
+		for _, e := range envFrom {
+			add("ConfigMap", e, "configMapRef", "name")
+			add("Secret", e, "secretRef", "name")
+		}
+		env, _ := container["env"].([]interface{})
+		for _, e := range env {
+			add("ConfigMap", e, "valueFrom", "configMapKeyRef", "name")
+			add("Secret", e, "valueFrom", "secretKeyRef", "name")
+		}
+	})
+	return refs
+}
@@ -0,0 +1,198 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// crdVersion is a version of a CustomResourceDefinition
+type crdVersion struct {
+	served  bool
+	storage bool
+	schema  map[string]interface{}
+}
+
+// checkCRDUpgrades compares the CustomResourceDefinitions of resMap to the installed ones, and returns the changes
+// they make to them. The CRDs must keep serving the versions their custom resources are stored in, and must not
+// remove or retype the fields of their storage version, which would be pruned from the stored resources. Such
+// changes are refused with an UNSAFE_UPGRADE error, unless the CRD is listed in the
+// kfctl.kubeflow.io/allow-destructive-crd-upgrades annotation of the KfDef.
+func checkCRDUpgrades(resMap resmap.ResMap, instance metav1.Object, kubeclient client.Client) ([]string, error) {
+	allowed := map[string]bool{}
+	annotation := instance.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.AllowDestructiveCRDUpgrades}, "/")]
+	for _, name := range strings.Split(annotation, ",") {
+		allowed[strings.TrimSpace(name)] = true
+	}
+
+	upgrades := []string{}
+	refused := []string{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		current, err := getLiveResource(kubeclient, res, "")
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			continue
+		}
+		changes, destructive := diffCRD(current.Object, res.Map())
+		if len(destructive) > 0 && !allowed[res.GetName()] {
+			refused = append(refused, fmt.Sprintf("%v %v", res.GetName(), strings.Join(destructive, ", ")))
+			continue
+		}
+		if changes = append(destructive, changes...); len(changes) > 0 {
+			upgrades = append(upgrades, fmt.Sprintf("%v (%v)", res.GetName(), strings.Join(changes, ", ")))
+		}
+	}
+	if len(refused) > 0 {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.UNSAFE_UPGRADE),
+			Message: fmt.Sprintf("refusing destructive CRD upgrades: %v", strings.Join(refused, "; ")),
+		}
+	}
+	return upgrades, nil
+}
+
+// diffCRD returns the changes the desired CRD makes to the current one, and separately the ones that would make
+// stored custom resources unreadable or lose their data
+func diffCRD(current map[string]interface{}, desired map[string]interface{}) ([]string, []string) {
+	currentVersions, currentStorage := crdVersions(current)
+	desiredVersions, desiredStorage := crdVersions(desired)
+	changes := []string{}
+	destructive := []string{}
+
+	stored, _, _ := unstructured.NestedStringSlice(current, "status", "storedVersions")
+	for _, version := range stored {
+		if v, ok := desiredVersions[version]; !ok || !v.served {
+			destructive = append(destructive, fmt.Sprintf("no longer serves stored version %v", version))
+		}
+	}
+	names := []string{}
+	for name := range desiredVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v, ok := currentVersions[name]; !ok || (!v.served && desiredVersions[name].served) {
+			changes = append(changes, fmt.Sprintf("serves version %v", name))
+		}
+	}
+	if desiredStorage != currentStorage && desiredStorage != "" {
+		changes = append(changes, fmt.Sprintf("stores version %v instead of %v", desiredStorage, currentStorage))
+	}
+
+	if v, ok := desiredVersions[currentStorage]; ok {
+		removed, retyped, added := diffSchema("", currentVersions[currentStorage].schema, v.schema)
+		for _, field := range removed {
+			destructive = append(destructive, fmt.Sprintf("removes field %v of version %v", field, currentStorage))
+		}
+		for _, field := range retyped {
+			destructive = append(destructive, fmt.Sprintf("changes the type of field %v of version %v", field, currentStorage))
+		}
+		for _, field := range added {
+			changes = append(changes, fmt.Sprintf("adds field %v to version %v", field, currentStorage))
+		}
+	}
+	return changes, destructive
+}
+
+// crdVersions returns the versions of a CRD of the apiextensions.k8s.io v1 or v1beta1 API, and its storage version
+func crdVersions(crd map[string]interface{}) (map[string]crdVersion, string) {
+	// The schema of a v1beta1 CRD may be set once for all its versions
+	common, _, _ := unstructured.NestedFieldNoCopy(crd, "spec", "validation", "openAPIV3Schema")
+	commonSchema, _ := common.(map[string]interface{})
+
+	versions := map[string]crdVersion{}
+	storage := ""
+	list, _, _ := unstructured.NestedFieldNoCopy(crd, "spec", "versions")
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		v, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := v["name"].(string)
+		version := crdVersion{schema: commonSchema}
+		version.served, _ = v["served"].(bool)
+		version.storage, _ = v["storage"].(bool)
+		if s, _, _ := unstructured.NestedFieldNoCopy(v, "schema", "openAPIV3Schema"); s != nil {
+			version.schema, _ = s.(map[string]interface{})
+		}
+		versions[name] = version
+		if version.storage {
+			storage = name
+		}
+	}
+	if len(versions) == 0 {
+		if name, _, _ := unstructured.NestedString(crd, "spec", "version"); name != "" {
+			versions[name] = crdVersion{served: true, storage: true, schema: commonSchema}
+			storage = name
+		}
+	}
+	return versions, storage
+}
+
+// diffSchema returns the fields of the current OpenAPI schema removed or retyped by the desired one, and the
+// fields it adds. Nothing is removed from the fields preserving unknown fields, or from schemas that are not set.
+func diffSchema(path string, current map[string]interface{}, desired map[string]interface{}) ([]string, []string, []string) {
+	removed, retyped, added := []string{}, []string{}, []string{}
+	if current == nil || desired == nil {
+		return removed, retyped, added
+	}
+	currentType, _ := current["type"].(string)
+	desiredType, _ := desired["type"].(string)
+	if currentType != "" && desiredType != "" && currentType != desiredType {
+		return removed, append(retyped, fieldPath(path)), added
+	}
+	preserves, _ := desired["x-kubernetes-preserve-unknown-fields"].(bool)
+
+	currentProperties, _ := current["properties"].(map[string]interface{})
+	desiredProperties, _ := desired["properties"].(map[string]interface{})
+	names := []string{}
+	for name := range currentProperties {
+		names = append(names, name)
+	}
+	for name := range desiredProperties {
+		if _, ok := currentProperties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, _ := currentProperties[name].(map[string]interface{})
+		d, ok := desiredProperties[name].(map[string]interface{})
+		switch {
+		case !ok && !preserves:
+			removed = append(removed, fieldPath(path+"."+name))
+		case !ok:
+		case c == nil:
+			added = append(added, fieldPath(path+"."+name))
+		default:
+			r, t, a := diffSchema(path+"."+name, c, d)
+			removed, retyped, added = append(removed, r...), append(retyped, t...), append(added, a...)
+		}
+	}
+
+	currentItems, _ := current["items"].(map[string]interface{})
+	desiredItems, _ := desired["items"].(map[string]interface{})
+	r, t, a := diffSchema(path+"[]", currentItems, desiredItems)
+	return append(removed, r...), append(retyped, t...), append(added, a...)
+}
+
+// fieldPath returns the path of a field of a schema, the root being the custom resource
+func fieldPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return strings.TrimPrefix(path, ".")
+}
@@ -0,0 +1,190 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DryRunDiffSuffix is appended to the application names to build the keys of the dry run ConfigMap
+const DryRunDiffSuffix = ".diff"
+
+// DryRunConfigMapName returns the name of the ConfigMap holding the changes a dry run of the KfDef would make
+func DryRunConfigMapName(kfdef string) string {
+	return kfdef + "-dry-run"
+}
+
+// dryRunApply validates every resource of the manifests with a server-side dry run apply and returns
+// the changes they would make to the cluster, along with the fields managed by other field managers that
+// would not be applied. Resources without a namespace are applied to the given namespace.
+func dryRunApply(ctx context.Context, kubeclient client.Client, mapper meta.RESTMapper, data []byte, namespace string) (string, error) {
+	resources, err := utils.SplitYAML(data)
+	if err != nil {
+		return "", &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error splitting yaml: %v", err),
+		}
+	}
+	var diff strings.Builder
+	for _, res := range resources {
+		desired := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(res, &desired.Object); err != nil {
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("error parsing yaml: %v", err),
+			}
+		}
+		gvk := desired.GroupVersionKind()
+		if gvk.Kind == "" {
+			continue
+		}
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				// The kind is defined by a CRD of the manifests that is not installed yet
+				diff.WriteString(fmt.Sprintf("+ %v %v: created, kind not installed yet\n", gvk.Kind, desired.GetName()))
+				continue
+			}
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("error mapping %v: %v", gvk, err),
+			}
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && desired.GetNamespace() == "" {
+			desired.SetNamespace(namespace)
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(gvk)
+		err = kubeclient.Get(ctx, client.ObjectKey{Name: desired.GetName(), Namespace: desired.GetNamespace()}, live)
+		if k8serrors.IsNotFound(err) {
+			live = nil
+		} else if err != nil {
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to get %v %v: %v", gvk.Kind, resourceName(desired), err),
+			}
+		}
+
+		result := desired.DeepCopy()
+		err = utils.ServerSideApply(ctx, kubeclient, result, client.DryRunAll)
+		if kfapisv3.IsConflict(err) {
+			diff.WriteString(fmt.Sprintf("! %v\n", err.(*kfapisv3.KfError).Message))
+			continue
+		}
+		if err != nil {
+			return "", &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("dry run of %v %v rejected: %v", gvk.Kind, resourceName(desired), err),
+			}
+		}
+		diff.WriteString(resourceDiff(live, result))
+	}
+	return diff.String(), nil
+}
+
+// DiffManifests returns the changes applying the manifests would make to the cluster, validated with a server-side
+// dry run, and the fields managed by other field managers that would not be applied
+func DiffManifests(kubeclient client.Client, mapper meta.RESTMapper, data []byte, namespace string) (string, error) {
+	return dryRunApply(context.Background(), kubeclient, mapper, data, namespace)
+}
+
+// resourceDiff describes the change from live to result, where a nil live resource is created.
+// It returns an empty string when the resource is unchanged.
+func resourceDiff(live *unstructured.Unstructured, result *unstructured.Unstructured) string {
+	if live == nil {
+		return fmt.Sprintf("+ %v %v: created\n", result.GetKind(), resourceName(result))
+	}
+	d := cmp.Diff(diffFields(live), diffFields(result))
+	if d == "" {
+		return ""
+	}
+	return fmt.Sprintf("~ %v %v: changed\n%v\n", result.GetKind(), resourceName(result), d)
+}
+
+// diffFields returns the fields of obj that are set by the manifests, leaving out the status and the
+// metadata maintained by the API server.
+func diffFields(obj *unstructured.Unstructured) map[string]interface{} {
+	u := obj.DeepCopy()
+	unstructured.RemoveNestedField(u.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(u.Object, "metadata", field)
+	}
+	return u.Object
+}
+
+func resourceName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// writeDryRunDiffs saves the changes of every application in the dry run ConfigMap of the KfDef,
+// owned by the KfDef so that it is deleted along with it.
+func writeDryRunDiffs(kubeclient client.Client, kfdef string, namespace string, diffs map[string]string) error {
+	apps := []string{}
+	for key, diff := range diffs {
+		if diff == "" {
+			diffs[key] = "no changes\n"
+		}
+		apps = append(apps, strings.TrimSuffix(key, DryRunDiffSuffix))
+	}
+	sort.Strings(apps)
+
+	if err := writeKfDefConfigMap(kubeclient, kfdef, namespace, DryRunConfigMapName(kfdef), diffs); err != nil {
+		return err
+	}
+	log.Infof("Dry run of %v written to ConfigMap %v/%v", strings.Join(apps, ", "), namespace, DryRunConfigMapName(kfdef))
+	return nil
+}
+
+// writeKfDefConfigMap creates or updates the ConfigMap name with data, owned by the KfDef
+// so that it is garbage collected along with it.
+func writeKfDefConfigMap(kubeclient client.Client, kfdef string, namespace string, name string, data map[string]string) error {
+	cm := &v1.ConfigMap{}
+	err := kubeclient.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: namespace}, cm)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get ConfigMap %v: %v", name, err),
+		}
+	}
+	exists := err == nil
+	cm.Name = name
+	cm.Namespace = namespace
+	cm.Data = data
+
+	owner := &unstructured.Unstructured{}
+	owner.SetGroupVersionKind(schema.GroupVersionKind{Group: "kfdef.apps.kubeflow.org", Version: "v1", Kind: "KfDef"})
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Name: kfdef, Namespace: namespace}, owner); err == nil {
+		cm.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, owner.GroupVersionKind())}
+	}
+
+	if exists {
+		err = kubeclient.Update(context.TODO(), cm)
+	} else {
+		err = kubeclient.Create(context.TODO(), cm)
+	}
+	if err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to write ConfigMap %v: %v", cm.Name, err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// FIPSAuto enables the FIPS mode when the cluster was installed with FIPS enabled
+	FIPSAuto = "auto"
+	// FIPSEnabled enables the FIPS mode whatever the cluster
+	FIPSEnabled = "enabled"
+	// FIPSDisabled disables the FIPS mode whatever the cluster
+	FIPSDisabled = "disabled"
+
+	// FIPSOverlay is the overlay of the components deploying their FIPS-compliant variant
+	FIPSOverlay = "fips"
+	// FIPSImagesKey is the key of the related images ConfigMap holding the FIPS-compliant variants of the images,
+	// using the schema of its images key. They take precedence over the images key in FIPS mode.
+	FIPSImagesKey = "fipsImages"
+	// FIPSIncompatibleReason is the reason of the condition of an application refused in FIPS mode
+	FIPSIncompatibleReason = "FIPSIncompatible"
+	// fipsIncompatible is the value of the kfctl.kubeflow.io/fips annotation of the resources that cannot run on a
+	// FIPS-enabled cluster
+	fipsIncompatible = "incompatible"
+
+	// clusterConfigNamespace and clusterConfigName are the ConfigMap holding the install config of an OpenShift
+	// cluster, under clusterConfigKey
+	clusterConfigNamespace = "kube-system"
+	clusterConfigName      = "cluster-config-v1"
+	clusterConfigKey       = "install-config"
+)
+
+var (
+	// FIPSMode tells whether the components are deployed in FIPS mode, FIPSAuto, FIPSEnabled or FIPSDisabled
+	FIPSMode = FIPSAuto
+	// fipsEnabled is set by ConfigureFIPS once the mode is resolved
+	fipsEnabled = false
+)
+
+// ValidateFIPSMode returns an error if mode is not a valid FIPSMode
+func ValidateFIPSMode(mode string) error {
+	switch mode {
+	case FIPSAuto, FIPSEnabled, FIPSDisabled:
+		return nil
+	}
+	return fmt.Errorf("invalid FIPS mode %q, must be %v, %v or %v", mode, FIPSAuto, FIPSEnabled, FIPSDisabled)
+}
+
+// ConfigureFIPS resolves FIPSMode, reading the install config of the cluster with reader when it is FIPSAuto.
+// The FIPS mode is disabled when the install config cannot be read.
+func ConfigureFIPS(reader client.Reader) {
+	switch FIPSMode {
+	case FIPSEnabled:
+		fipsEnabled = true
+	case FIPSDisabled:
+		fipsEnabled = false
+	default:
+		enabled, err := DetectFIPS(reader)
+		if err != nil {
+			log.Warnf("Failed to detect whether the cluster is FIPS-enabled, disabling the FIPS mode: %v.", err)
+		}
+		fipsEnabled = enabled
+	}
+	if fipsEnabled {
+		log.Infof("FIPS mode enabled, deploying the FIPS-compliant variants of the components.")
+	}
+}
+
+// IsFIPSEnabled returns true if the components are deployed in FIPS mode
+func IsFIPSEnabled() bool {
+	return fipsEnabled
+}
+
+// DetectFIPS returns true if the cluster was installed with FIPS enabled, as recorded by the install config of
+// OpenShift. It returns false on the clusters without install config.
+func DetectFIPS(reader client.Reader) (bool, error) {
+	cm := &v1.ConfigMap{}
+	err := reader.Get(context.TODO(), client.ObjectKey{Namespace: clusterConfigNamespace, Name: clusterConfigName}, cm)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get ConfigMap %v/%v: %v", clusterConfigNamespace, clusterConfigName, err)
+	}
+	installConfig := struct {
+		FIPS bool `json:"fips"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cm.Data[clusterConfigKey]), &installConfig); err != nil {
+		return false, fmt.Errorf("failed to parse key %v of ConfigMap %v/%v: %v", clusterConfigKey, clusterConfigNamespace,
+			clusterConfigName, err)
+	}
+	return installConfig.FIPS, nil
+}
+
+// fipsOverlays returns overlays with the FIPS overlay of the component at compDir added when enabled and the
+// component has one
+func fipsOverlays(enabled bool, compDir string, overlays []string) []string {
+	if !enabled {
+		return overlays
+	}
+	for _, overlay := range overlays {
+		if overlay == FIPSOverlay {
+			return overlays
+		}
+	}
+	if _, err := os.Stat(path.Join(compDir, "overlays", FIPSOverlay)); err != nil {
+		return overlays
+	}
+	return append(append([]string{}, overlays...), FIPSOverlay)
+}
+
+// fipsIncompatibleError is returned for the applications refused in FIPS mode
+type fipsIncompatibleError struct {
+	app       string
+	resources []string
+}
+
+func (e *fipsIncompatibleError) Error() string {
+	return fmt.Sprintf("application %v cannot be enabled on a FIPS-enabled cluster, %v annotated with %v/%v: %v",
+		e.app, strings.Join(e.resources, ", "), utils.KfDefAnnotation, utils.FIPS, fipsIncompatible)
+}
+
+// checkFIPSCompatible returns an error listing the resources of resMap annotated as FIPS-incompatible
+func checkFIPSCompatible(resMap resmap.ResMap, app string) error {
+	resources := []string{}
+	for _, res := range resMap.Resources() {
+		if res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.FIPS}, "/")] == fipsIncompatible {
+			resources = append(resources, res.GetKind()+" "+res.GetName())
+		}
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	sort.Strings(resources)
+	return &fipsIncompatibleError{app: app, resources: resources}
+}
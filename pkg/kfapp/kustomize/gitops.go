@@ -0,0 +1,41 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// pushManifests pushes the rendered manifests of the applications, by name, to the GitOps repository of the KfDef.
+// The manifests of the applications mapped to nil are left as they are in the repository.
+func (kustomize *kustomize) pushManifests(parent *tracing.Span, manifests map[string][]byte) error {
+	gitOps := kustomize.kfDef.Spec.GitOps
+	span := tracing.Start(parent, "Push", "gitops.uri", gitOps.URI)
+	message := fmt.Sprintf("Render KfDef %v/%v\n\nGeneration: %v\n", kustomize.kfDef.Namespace, kustomize.kfDef.Name,
+		kustomize.kfDef.Generation)
+	commit, err := kfconfig.PushManifests(gitOps, kustomize.kfDef.Namespace, kustomize.kfDef.Name, manifests, message)
+	span.End(err)
+	branch := gitOps.Branch
+	if branch == "" {
+		branch = kfconfig.DefaultGitOpsBranch
+	}
+	for app, data := range manifests {
+		if data == nil {
+			continue
+		}
+		if err != nil {
+			kustomize.kfDef.SetApplicationCondition(app, kfconfig.Degraded, "PushFailed", err.Error())
+			continue
+		}
+		kustomize.kfDef.SetApplicationCondition(app, kfconfig.Available, "PushSucceeded",
+			fmt.Sprintf("application pushed to branch %v of %v in commit %v", branch, gitOps.URI, commit))
+	}
+	if err != nil {
+		log.Errorf("Failed to push the manifests of %v to %v: %v", kustomize.kfDef.Name, gitOps.URI, err)
+		return err
+	}
+	log.Infof("Manifests of %v are in commit %v of branch %v of %v", kustomize.kfDef.Name, commit, branch, gitOps.URI)
+	return nil
+}
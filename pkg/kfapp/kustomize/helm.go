@@ -0,0 +1,206 @@
+package kustomize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kftypesv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	"github.com/kubeflow/kfctl/v3/pkg/helm"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/kustomize/v3/pkg/types"
+)
+
+// generateHelmApp renders the chart of app into a kustomize package of kustomizeDir holding the rendered
+// manifests, so that they go through the same pipeline as the manifests of the kustomize applications.
+func (kustomize *kustomize) generateHelmApp(app kfconfig.Application, kustomizeDir string) error {
+	config := app.HelmConfig
+	chart, err := kustomize.loadChart(app)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	if config.Values != nil && len(config.Values.Raw) > 0 {
+		if err := yaml.Unmarshal(config.Values.Raw, &values); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("invalid helm values of application %v: %v", app.Name, err),
+			}
+		}
+	}
+	release := helm.Release{
+		Name:      config.ReleaseName,
+		Namespace: kustomize.kfDef.Namespace,
+		IsInstall: true,
+		Revision:  1,
+	}
+	if release.Name == "" {
+		release.Name = app.Name
+	}
+
+	log.Infof("Rendering chart %v %v of application %v", chart.Metadata.Name, chart.Metadata.Version, app.Name)
+	rendered, err := helm.Render(chart, release, values, kustomize.helmCapabilities())
+	if err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't render chart of application %v: %v", app.Name, err),
+		}
+	}
+	manifests := helmManifests(rendered)
+
+	appDir := path.Join(kustomizeDir, app.Name)
+	if err := os.MkdirAll(appDir, os.ModePerm); err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't create directory %v: %v", appDir, err),
+		}
+	}
+	manifestsFile := app.Name + ".yaml"
+	if err := ioutil.WriteFile(path.Join(appDir, manifestsFile), []byte(manifests), 0644); err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't write the manifests of application %v: %v", app.Name, err),
+		}
+	}
+	kustomization := &types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+		Resources: []string{manifestsFile},
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't marshal the kustomization of application %v: %v", app.Name, err),
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(appDir, kftypesv3.KustomizationFile), data, 0644); err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't write the kustomization of application %v: %v", app.Name, err),
+		}
+	}
+	return nil
+}
+
+// loadChart loads the chart of app from its directory in a repo of the spec, or fetches it from its chart repository
+func (kustomize *kustomize) loadChart(app kfconfig.Application) (*helm.Chart, error) {
+	config := app.HelmConfig
+	if config.RepoRef != nil {
+		repoCache, ok := kustomize.kfDef.GetRepoCache(config.RepoRef.Name)
+		if !ok {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("application %v refers to repo %v which wasn't found in KfDef.Status.ReposCache", app.Name, config.RepoRef.Name),
+			}
+		}
+		chart, err := helm.LoadDir(path.Join(repoCache.LocalPath, config.RepoRef.Path))
+		if err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("couldn't load chart of application %v: %v", app.Name, err),
+			}
+		}
+		return chart, nil
+	}
+
+	if config.Repo == "" || config.Chart == "" {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("application %v has neither helmConfig.repoRef nor helmConfig.repo and helmConfig.chart", app.Name),
+		}
+	}
+	data, err := helm.Fetch(config.Repo, config.Chart, config.Version)
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't fetch chart of application %v: %v", app.Name, err),
+		}
+	}
+	chart, err := helm.LoadArchive(data)
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't load chart of application %v: %v", app.Name, err),
+		}
+	}
+	return chart, nil
+}
+
+// helmCapabilities returns the version and the API versions of the cluster, or the defaults of helm
+// when the manifests are generated without a cluster.
+func (kustomize *kustomize) helmCapabilities() *helm.Capabilities {
+	if kustomize.restConfig == nil {
+		return helm.DefaultCapabilities
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(kustomize.restConfig)
+	if err != nil {
+		log.Warnf("Couldn't create a discovery client, rendering the charts with the default capabilities: %v", err)
+		return helm.DefaultCapabilities
+	}
+	version, err := client.ServerVersion()
+	if err != nil {
+		log.Warnf("Couldn't get the server version, rendering the charts with the default capabilities: %v", err)
+		return helm.DefaultCapabilities
+	}
+	caps := &helm.Capabilities{
+		KubeVersion: helm.KubeVersion{
+			Version:    version.GitVersion,
+			Major:      version.Major,
+			Minor:      version.Minor,
+			GitVersion: version.GitVersion,
+		},
+	}
+	_, resourceLists, err := client.ServerGroupsAndResources()
+	if err != nil {
+		log.Warnf("Couldn't discover all the API versions of the server: %v", err)
+	}
+	for _, resourceList := range resourceLists {
+		caps.APIVersions = append(caps.APIVersions, resourceList.GroupVersion)
+		for _, resource := range resourceList.APIResources {
+			caps.APIVersions = append(caps.APIVersions, resourceList.GroupVersion+"/"+resource.Kind)
+		}
+	}
+	return caps
+}
+
+// helmManifests joins the rendered templates in a multi-document yaml, dropping the empty documents
+func helmManifests(rendered map[string]string) string {
+	names := []string{}
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests strings.Builder
+	for _, name := range names {
+		for _, doc := range strings.Split("\n"+rendered[name], "\n---") {
+			if isEmptyDocument(doc) {
+				continue
+			}
+			fmt.Fprintf(&manifests, "---\n# Source: %v\n%v\n", name, strings.Trim(doc, "\n"))
+		}
+	}
+	return manifests.String()
+}
+
+// isEmptyDocument returns true when doc only holds blank lines and comments
+func isEmptyDocument(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PreApplyHook is the kfctl.kubeflow.io/hook of the Jobs run before the other resources of their application
+	// are applied
+	PreApplyHook = "pre-apply"
+	// PostApplyHook is the kfctl.kubeflow.io/hook of the Jobs run once the other resources of their application
+	// are applied
+	PostApplyHook = "post-apply"
+	// HookFailedReason is the reason of an application whose hook Job failed all its attempts
+	HookFailedReason = "HookFailed"
+	// HookChecksumAnnotation is the annotation of the hook Jobs holding the checksum of their manifest, so that a
+	// completed Job is only run again once its manifest changes
+	HookChecksumAnnotation = utils.KfDefAnnotation + "/hook-checksum"
+)
+
+var (
+	// HookJobTimeout is how long a hook Job may run, unless it is annotated with kfctl.kubeflow.io/hook-timeout
+	HookJobTimeout = 10 * time.Minute
+	// HookJobRetries is how many times a failed hook Job is run again, unless it is annotated with
+	// kfctl.kubeflow.io/hook-retries
+	HookJobRetries = 2
+	// hookLogLines and hookLogBytes bound the logs of a failed hook Job recorded in the status of the KfDef
+	hookLogLines int64 = 20
+	hookLogBytes int64 = 4096
+	// hookPollInterval is the time between two checks of a running hook Job
+	hookPollInterval = 5 * time.Second
+)
+
+// hookJob is a Job of the manifests run before or after the other resources of its application are applied
+type hookJob struct {
+	hook     string
+	job      *unstructured.Unstructured
+	timeout  time.Duration
+	retries  int
+	checksum string
+}
+
+// hookError is returned when a hook Job failed all its attempts
+type hookError struct {
+	failure kfconfig.HookFailure
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("%v hook Job %v failed after %v attempts: %v", e.failure.Hook, e.failure.Job,
+		e.failure.Attempts, e.failure.Message)
+}
+
+// parseHook returns the hook Job of a resource annotated with kfctl.kubeflow.io/hook, or nil if the resource is
+// not a hook. The namespaced hooks without a namespace are run in namespace.
+func parseHook(obj *unstructured.Unstructured, namespace string) (*hookJob, error) {
+	annotations := obj.GetAnnotations()
+	hook, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.Hook}, "/")]
+	if !ok {
+		return nil, nil
+	}
+	if hook != PreApplyHook && hook != PostApplyHook {
+		return nil, fmt.Errorf("invalid hook %q of %v %v, must be %v or %v", hook, obj.GetKind(), obj.GetName(),
+			PreApplyHook, PostApplyHook)
+	}
+	if obj.GetKind() != "Job" || obj.GroupVersionKind().Group != "batch" {
+		return nil, fmt.Errorf("%v %v can not be a hook, only Jobs can", obj.GetKind(), obj.GetName())
+	}
+	h := &hookJob{hook: hook, job: obj, timeout: HookJobTimeout, retries: HookJobRetries}
+	if value, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.HookTimeout}, "/")]; ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid hook timeout %q of Job %v, must be a positive duration", value, obj.GetName())
+		}
+		h.timeout = timeout
+	}
+	if value, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.HookRetries}, "/")]; ok {
+		retries, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || retries < 0 {
+			return nil, fmt.Errorf("invalid hook retries %q of Job %v, must be a positive number", value, obj.GetName())
+		}
+		h.retries = retries
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	h.checksum = hex.EncodeToString(sum[:])
+	annotations[HookChecksumAnnotation] = h.checksum
+	obj.SetAnnotations(annotations)
+	return h, nil
+}
+
+// splitHooks splits the manifests into the pre-apply hook Jobs, the post-apply hook Jobs and the other resources,
+// keeping the order of the manifests
+func splitHooks(data []byte, namespace string) ([]*hookJob, []*hookJob, []byte, error) {
+	resources, err := utils.SplitYAML(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pre, post := []*hookJob{}, []*hookJob{}
+	rest := [][]byte{}
+	for _, res := range resources {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(res, &obj.Object); err != nil {
+			return nil, nil, nil, err
+		}
+		hook, err := parseHook(obj, namespace)
+		if err != nil {
+			return nil, nil, nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: err.Error(),
+			}
+		}
+		switch {
+		case hook == nil:
+			rest = append(rest, res)
+		case hook.hook == PreApplyHook:
+			pre = append(pre, hook)
+		default:
+			post = append(post, hook)
+		}
+	}
+	if len(pre) == 0 && len(post) == 0 {
+		return pre, post, data, nil
+	}
+	return pre, post, []byte(strings.Join(byteStrings(rest), "---\n")), nil
+}
+
+// byteStrings converts the documents of the manifests to strings
+func byteStrings(documents [][]byte) []string {
+	strs := []string{}
+	for _, doc := range documents {
+		strs = append(strs, string(doc))
+	}
+	return strs
+}
+
+// runHooks runs the hook Jobs of the application one after the other, and stops at the first one failing all its
+// attempts or once ctx is done
+func (kustomize *kustomize) runHooks(ctx context.Context, app kfconfig.Application, hooks []*hookJob, parent *tracing.Span) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(kustomize.restConfig)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		span := tracing.Start(parent, "Hook "+hook.job.GetName(), "hook", hook.hook)
+		err := runHook(ctx, kubeclient, clientset, app.Name, hook)
+		span.End(err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHook runs the hook Job until it completes, creating it again when it fails up to its retries. A Job already
+// completed from the same manifest is not run again, and a Job still running from the same manifest is waited for.
+// The Job is left running once ctx is done, it is waited for by the next reconcile.
+func runHook(ctx context.Context, kubeclient client.Client, clientset kubernetes.Interface, app string, hook *hookJob) error {
+	key := client.ObjectKey{Namespace: hook.job.GetNamespace(), Name: hook.job.GetName()}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(hook.job.GroupVersionKind())
+	err := kubeclient.Get(ctx, key, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	var running *unstructured.Unstructured
+	if err == nil {
+		complete, failed := workloadReady(existing)
+		if existing.GetAnnotations()[HookChecksumAnnotation] == hook.checksum && failed == nil {
+			if complete {
+				log.Infof("Hook Job %v of application %v already completed, skipping it", key, app)
+				return nil
+			}
+			running = existing
+		} else if err := deleteHookJob(ctx, kubeclient, existing); err != nil {
+			return err
+		}
+	}
+
+	failure := kfconfig.HookFailure{Application: app, Job: key.String(), Hook: hook.hook}
+	for attempt := 1; attempt <= hook.retries+1; attempt++ {
+		job := running
+		running = nil
+		if job == nil {
+			log.Infof("Running %v hook Job %v of application %v, attempt %v", hook.hook, key, app, attempt)
+			job = hook.job.DeepCopy()
+			if err := kubeclient.Create(ctx, job); err != nil {
+				return err
+			}
+		} else {
+			log.Infof("Waiting for the running %v hook Job %v of application %v", hook.hook, key, app)
+		}
+		err := waitForHookJob(ctx, kubeclient, job, hook.timeout)
+		if err == nil {
+			log.Infof("Hook Job %v of application %v completed", key, app)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		log.Warnf("Hook Job %v of application %v failed: %v", key, app, err)
+		failure.Attempts = attempt
+		failure.LastFailureTime = metav1.Now()
+		failure.Message = err.Error()
+		failure.Logs = hookJobLogs(clientset, job)
+		if err := deleteHookJob(ctx, kubeclient, job); err != nil {
+			return err
+		}
+	}
+	return &hookError{failure: failure}
+}
+
+// waitForHookJob waits for the Job to complete within timeout, and returns an error if it failed, did not
+// complete in time or ctx is done first
+func waitForHookJob(ctx context.Context, kubeclient client.Client, job *unstructured.Unstructured, timeout time.Duration) error {
+	var failed error
+	err := poll(ctx, hookPollInterval, timeout, func() (bool, error) {
+		if err := kubeclient.Get(ctx, client.ObjectKey{Namespace: job.GetNamespace(), Name: job.GetName()}, job); err != nil {
+			return false, err
+		}
+		complete, err := workloadReady(job)
+		failed = err
+		return complete || failed != nil, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("job did not complete within %v", timeout)
+	}
+	if err != nil {
+		return err
+	}
+	return failed
+}
+
+// deleteHookJob deletes the Job and its pods, and waits for it to be gone so that it can be created again
+func deleteHookJob(ctx context.Context, kubeclient client.Client, job *unstructured.Unstructured) error {
+	err := kubeclient.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return poll(ctx, time.Second, time.Minute, func() (bool, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(job.GroupVersionKind())
+		err := kubeclient.Get(ctx, client.ObjectKey{Namespace: job.GetNamespace(), Name: job.GetName()}, obj)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// hookJobLogs returns the last lines of the logs of the last pod of the Job, or why they could not be read
+func hookJobLogs(clientset kubernetes.Interface, job *unstructured.Unstructured) string {
+	pods, err := clientset.CoreV1().Pods(job.GetNamespace()).List(metav1.ListOptions{
+		LabelSelector: "job-name=" + job.GetName(),
+	})
+	if err != nil {
+		return fmt.Sprintf("failed to list the pods of the Job: %v", err)
+	}
+	var last *corev1.Pod
+	for i, pod := range pods.Items {
+		if last == nil || last.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			last = &pods.Items[i]
+		}
+	}
+	if last == nil || len(last.Spec.Containers) == 0 {
+		return "the Job has no pod"
+	}
+	logs, err := clientset.CoreV1().Pods(last.Namespace).GetLogs(last.Name, &corev1.PodLogOptions{
+		Container:  last.Spec.Containers[0].Name,
+		TailLines:  &hookLogLines,
+		LimitBytes: &hookLogBytes,
+	}).DoRaw()
+	if err != nil {
+		return fmt.Sprintf("failed to get the logs of pod %v: %v", last.Name, err)
+	}
+	return string(logs)
+}
@@ -0,0 +1,252 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// RelatedImagesKey is the key of the related images ConfigMap holding the image overrides,
+	// a list using the schema of the images of the KfDef spec
+	RelatedImagesKey = "images"
+	// ImagesReportKey is the key of the images ConfigMap listing the images deployed by the KfDef
+	ImagesReportKey = "relatedImages.yaml"
+	// ImageContentSourcePolicyKey is the key of the images ConfigMap holding an ImageContentSourcePolicy
+	// mirroring the repositories of the overridden images
+	ImageContentSourcePolicyKey = "imageContentSourcePolicy.yaml"
+)
+
+// ImagesConfigMapName returns the name of the ConfigMap reporting the images deployed by the KfDef
+func ImagesConfigMapName(kfdef string) string {
+	return kfdef + "-images"
+}
+
+// relatedImage is an image referenced by the manifests along with the image it is deployed as
+type relatedImage struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// loadImageOverrides returns the image overrides of the KfDef merged into the ones of the related images
+// ConfigMap named by the kfctl.kubeflow.io/related-images annotation, along with its FIPS-compliant images in FIPS
+// mode. The overrides of the KfDef take precedence.
+func (kustomize *kustomize) loadImageOverrides() ([]kfconfig.Image, error) {
+	ref := kustomize.kfDef.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.RelatedImages}, "/")]
+	if ref == "" {
+		return kustomize.kfDef.Spec.Images, nil
+	}
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("related images ConfigMap %v must be of the form <namespace>/<name>", ref),
+		}
+	}
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing k8s client: %v", err),
+		}
+	}
+	cm := &v1.ConfigMap{}
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: parts[0], Name: parts[1]}, cm); err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("failed to get the related images ConfigMap %v: %v", ref, err),
+		}
+	}
+	related := []kfconfig.Image{}
+	if err := yaml.Unmarshal([]byte(cm.Data[RelatedImagesKey]), &related); err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("failed to parse key %v of the related images ConfigMap %v: %v", RelatedImagesKey, ref, err),
+		}
+	}
+	if IsFIPSEnabled() {
+		fips := []kfconfig.Image{}
+		if err := yaml.Unmarshal([]byte(cm.Data[FIPSImagesKey]), &fips); err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("failed to parse key %v of the related images ConfigMap %v: %v", FIPSImagesKey, ref, err),
+			}
+		}
+		related = mergeImages(related, fips)
+	}
+	return mergeImages(related, kustomize.kfDef.Spec.Images), nil
+}
+
+// mergeImages returns the images of base with the ones of the same name replaced by overrides,
+// followed by the other overrides
+func mergeImages(base []kfconfig.Image, overrides []kfconfig.Image) []kfconfig.Image {
+	byName := map[string]kfconfig.Image{}
+	for _, image := range overrides {
+		byName[image.Name] = image
+	}
+	merged := []kfconfig.Image{}
+	for _, image := range base {
+		if override, ok := byName[image.Name]; ok {
+			image = override
+			delete(byName, image.Name)
+		}
+		merged = append(merged, image)
+	}
+	for _, image := range overrides {
+		if _, ok := byName[image.Name]; ok {
+			merged = append(merged, image)
+		}
+	}
+	return merged
+}
+
+// overrideImages replaces the container images of the resources of resMap matching images and returns
+// the images the resources reference, keyed by the image they were rendered with.
+func overrideImages(resMap resmap.ResMap, images []kfconfig.Image) map[string]string {
+	referenced := map[string]string{}
+	for _, res := range resMap.Resources() {
+		m := res.Map()
+		walkContainers(m, func(container map[string]interface{}) {
+			image, ok := container["image"].(string)
+			if !ok || image == "" {
+				return
+			}
+			newImage := overrideImage(image, images)
+			container["image"] = newImage
+			referenced[image] = newImage
+		})
+		res.SetMap(m)
+	}
+	return referenced
+}
+
+// walkContainers calls fn for every container of the pod templates found in obj
+func walkContainers(obj interface{}, fn func(container map[string]interface{})) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if list, ok := v.([]interface{}); ok && (k == "containers" || k == "initContainers" || k == "ephemeralContainers") {
+				for _, c := range list {
+					if container, ok := c.(map[string]interface{}); ok {
+						fn(container)
+					}
+				}
+				continue
+			}
+			walkContainers(v, fn)
+		}
+	case []interface{}:
+		for _, v := range o {
+			walkContainers(v, fn)
+		}
+	}
+}
+
+// splitImage splits an image reference into its name and its tag and digest, e.g. ":v1" or "@sha256:<hex>"
+func splitImage(image string) (string, string) {
+	name, suffix := image, ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, suffix = name[:i], name[i:]+suffix
+	}
+	return name, suffix
+}
+
+// overrideImage returns image with the name, tag or digest set by the first of images matching its name
+func overrideImage(image string, images []kfconfig.Image) string {
+	name, suffix := splitImage(image)
+	for _, override := range images {
+		if override.Name != name {
+			continue
+		}
+		if override.NewName != "" {
+			name = override.NewName
+		}
+		if override.Digest != "" {
+			suffix = "@" + override.Digest
+		} else if override.NewTag != "" {
+			suffix = ":" + override.NewTag
+		}
+		return name + suffix
+	}
+	return image
+}
+
+// imagesReport returns the data of the images ConfigMap of the KfDef: the images referenced by the manifests
+// along with the image they are deployed as, and an ImageContentSourcePolicy mirroring the repositories
+// of the images deployed from another registry.
+func imagesReport(kfdef string, referenced map[string]string) (map[string]string, error) {
+	related := []relatedImage{}
+	mirrors := map[string]string{}
+	for name, image := range referenced {
+		related = append(related, relatedImage{Name: name, Image: image})
+		source, _ := splitImage(name)
+		mirror, _ := splitImage(image)
+		if source != mirror {
+			mirrors[source] = mirror
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		return related[i].Name < related[j].Name
+	})
+	data := map[string]string{}
+	out, err := yaml.Marshal(related)
+	if err != nil {
+		return nil, err
+	}
+	data[ImagesReportKey] = string(out)
+
+	if len(mirrors) == 0 {
+		return data, nil
+	}
+	sources := []string{}
+	for source := range mirrors {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	digestMirrors := []interface{}{}
+	for _, source := range sources {
+		digestMirrors = append(digestMirrors, map[string]interface{}{
+			"source":  source,
+			"mirrors": []interface{}{mirrors[source]},
+		})
+	}
+	icsp := map[string]interface{}{
+		"apiVersion": "operator.openshift.io/v1alpha1",
+		"kind":       "ImageContentSourcePolicy",
+		"metadata":   map[string]interface{}{"name": kfdef},
+		"spec":       map[string]interface{}{"repositoryDigestMirrors": digestMirrors},
+	}
+	if out, err = yaml.Marshal(icsp); err != nil {
+		return nil, err
+	}
+	data[ImageContentSourcePolicyKey] = string(out)
+	return data, nil
+}
+
+// writeImagesReport writes the images deployed by the KfDef to its images ConfigMap
+func (kustomize *kustomize) writeImagesReport() error {
+	data, err := imagesReport(kustomize.kfDef.Name, kustomize.relatedImages)
+	if err != nil {
+		return err
+	}
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return err
+	}
+	return writeKfDefConfigMap(kubeclient, kustomize.kfDef.Name, kustomize.kfDef.Namespace,
+		ImagesConfigMapName(kustomize.kfDef.Name), data)
+}
@@ -0,0 +1,306 @@
+package kustomize
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+// RouteGVK is the kind of the OpenShift Routes. The clusters not serving it are vanilla Kubernetes clusters.
+var RouteGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+var (
+	// IngressClass is the ingress class of the Ingresses generated on Kubernetes, or the default class when empty
+	IngressClass = ""
+	// IngressTLSSecret is the secret holding the certificate of the Ingresses generated on Kubernetes for TLS Routes.
+	// The ingress controller serves its default certificate when it is empty.
+	IngressTLSSecret = ""
+	// IngressDomain is the domain of the hosts of the Ingresses generated on Kubernetes for Routes without a host,
+	// named <route>-<namespace>.<domain> like on OpenShift. They match any host when it is empty.
+	IngressDomain = ""
+)
+
+// servesRoutes returns true if the cluster serves the OpenShift Routes
+func (kustomize *kustomize) servesRoutes() (bool, error) {
+	kustomize.initK8sClients()
+	dc, err := discovery.NewDiscoveryClientForConfig(kustomize.restConfig)
+	if err != nil {
+		return false, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing discovery client: %v", err),
+		}
+	}
+	if _, err := dc.ServerResourcesForGroupVersion(RouteGVK.GroupVersion().String()); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to discover the OpenShift Routes: %v", err),
+		}
+	}
+	return true, nil
+}
+
+// exposeServices adds a Route, named after the Service, for every Service of resMap annotated with
+// kfctl.kubeflow.io/expose, unless resMap already has it. The annotation names the port of the Service exposed,
+// or is true to expose its first port.
+func exposeServices(resMap resmap.ResMap) error {
+	routes := []*resource.Resource{}
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Service" {
+			continue
+		}
+		expose := res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.Expose}, "/")]
+		if expose == "" || expose == "false" {
+			continue
+		}
+		service := &unstructured.Unstructured{Object: res.Map()}
+		port, err := exposedPort(service, expose)
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("failed to expose Service %v: %v", service.GetName(), err),
+			}
+		}
+		metadata := map[string]interface{}{"name": service.GetName()}
+		if service.GetNamespace() != "" {
+			metadata["namespace"] = service.GetNamespace()
+		}
+		if labels := service.GetLabels(); len(labels) > 0 {
+			metadata["labels"] = stringMap(labels)
+		}
+		route := factory.FromMap(map[string]interface{}{
+			"apiVersion": RouteGVK.GroupVersion().String(),
+			"kind":       RouteGVK.Kind,
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"to":   map[string]interface{}{"kind": "Service", "name": service.GetName()},
+				"port": map[string]interface{}{"targetPort": port},
+				"tls":  map[string]interface{}{"termination": "edge", "insecureEdgeTerminationPolicy": "Redirect"},
+			},
+		})
+		if _, err := resMap.GetById(route.OrgId()); err == nil {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	for _, route := range routes {
+		if err := resMap.Append(route); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add Route %v: %v", route.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
+
+// exposedPort returns the target port of the Route exposing the port of the service named by expose, the name of
+// the port of the Service if it has one and its target port otherwise
+func exposedPort(service *unstructured.Unstructured, expose string) (interface{}, error) {
+	ports, _, _ := unstructured.NestedSlice(service.Object, "spec", "ports")
+	for _, p := range ports {
+		p, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if expose != "true" && p["name"] != expose && fmt.Sprint(p["port"]) != expose {
+			continue
+		}
+		if name, ok := p["name"].(string); ok && name != "" {
+			return name, nil
+		}
+		if target, ok := p["targetPort"]; ok {
+			return target, nil
+		}
+		return p["port"], nil
+	}
+	return nil, fmt.Errorf("it has no port %v", expose)
+}
+
+// routesToIngresses replaces the Routes of resMap with Ingresses of the same names, for the clusters not serving
+// the OpenShift Routes. Resources without a namespace are in the given namespace.
+func routesToIngresses(resMap resmap.ResMap, namespace string) error {
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != RouteGVK.Kind {
+			continue
+		}
+		route := &unstructured.Unstructured{Object: res.Map()}
+		serviceName, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+		var service *unstructured.Unstructured
+		for _, s := range resMap.Resources() {
+			if s.GetKind() == "Service" && s.GetName() == serviceName {
+				service = &unstructured.Unstructured{Object: s.Map()}
+			}
+		}
+		ingress, err := routeToIngress(route, service, namespace)
+		if err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("failed to convert Route %v to an Ingress: %v", route.GetName(), err),
+			}
+		}
+		if err := resMap.Remove(res.CurId()); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to remove Route %v: %v", route.GetName(), err),
+			}
+		}
+		if err := resMap.Append(factory.FromMap(ingress)); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add Ingress %v: %v", route.GetName(), err),
+			}
+		}
+		log.Infof("Route %v converted to an Ingress", route.GetName())
+	}
+	return nil
+}
+
+// routeToIngress returns the Ingress sending the requests of the host and the path of the route to the port of
+// its Service. service is nil when it is not in the manifests.
+func routeToIngress(route *unstructured.Unstructured, service *unstructured.Unstructured, namespace string) (map[string]interface{}, error) {
+	serviceName, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+	port, err := ingressBackendPort(route, service)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := route.GetNamespace()
+	if ns == "" {
+		ns = namespace
+	}
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	if host == "" && IngressDomain != "" {
+		host = route.GetName() + "-" + ns + "." + IngressDomain
+	}
+	path, _, _ := unstructured.NestedString(route.Object, "spec", "path")
+	if path == "" {
+		path = "/"
+	}
+	rule := map[string]interface{}{
+		"http": map[string]interface{}{
+			"paths": []interface{}{
+				map[string]interface{}{
+					"path":     path,
+					"pathType": "Prefix",
+					"backend": map[string]interface{}{
+						"service": map[string]interface{}{"name": serviceName, "port": port},
+					},
+				},
+			},
+		},
+	}
+	if host != "" {
+		rule["host"] = host
+	}
+	spec := map[string]interface{}{"rules": []interface{}{rule}}
+	if IngressClass != "" {
+		spec["ingressClassName"] = IngressClass
+	}
+
+	annotations := route.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	termination, _, _ := unstructured.NestedString(route.Object, "spec", "tls", "termination")
+	if termination != "" {
+		tls := map[string]interface{}{}
+		if host != "" {
+			tls["hosts"] = []interface{}{host}
+		}
+		if IngressTLSSecret != "" {
+			tls["secretName"] = IngressTLSSecret
+		}
+		spec["tls"] = []interface{}{tls}
+	}
+	// the TLS termination of the Routes behind the router are translated to the annotations of ingress-nginx
+	switch termination {
+	case "reencrypt":
+		annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+	case "passthrough":
+		annotations["nginx.ingress.kubernetes.io/ssl-passthrough"] = "true"
+	}
+
+	metadata := map[string]interface{}{"name": route.GetName()}
+	if route.GetNamespace() != "" {
+		metadata["namespace"] = route.GetNamespace()
+	}
+	if labels := route.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = stringMap(labels)
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = stringMap(annotations)
+	}
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   metadata,
+		"spec":       spec,
+	}, nil
+}
+
+// ingressBackendPort returns the port of the Service the route sends its requests to: the port of the Service whose
+// name or target port is the target port of the route, or the first port of the Service if the route has none
+func ingressBackendPort(route *unstructured.Unstructured, service *unstructured.Unstructured) (map[string]interface{}, error) {
+	target, found, _ := unstructured.NestedFieldNoCopy(route.Object, "spec", "port", "targetPort")
+	if s, ok := target.(string); ok {
+		if n, err := strconv.Atoi(s); err == nil {
+			target = int64(n)
+		}
+	}
+	var ports []interface{}
+	if service != nil {
+		ports, _, _ = unstructured.NestedSlice(service.Object, "spec", "ports")
+	}
+	for _, p := range ports {
+		p, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if found {
+			targetPort, ok := p["targetPort"]
+			if !ok {
+				targetPort = p["port"]
+			}
+			if p["name"] != target && fmt.Sprint(targetPort) != fmt.Sprint(target) {
+				continue
+			}
+		}
+		if name, ok := p["name"].(string); ok && name != "" {
+			return map[string]interface{}{"name": name}, nil
+		}
+		return map[string]interface{}{"number": p["port"]}, nil
+	}
+	switch target := target.(type) {
+	case string:
+		return map[string]interface{}{"name": target}, nil
+	case int64, float64:
+		return map[string]interface{}{"number": target}, nil
+	}
+	serviceName, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+	return nil, fmt.Errorf("it has no target port and its Service %v is not in the manifests", serviceName)
+}
+
+// stringMap returns m as a map of the unstructured objects
+func stringMap(m map[string]string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
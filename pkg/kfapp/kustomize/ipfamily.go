@@ -0,0 +1,35 @@
+package kustomize
+
+import (
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// injectIPFamilyPolicy sets policy on the Services of resMap that do not set an IP family policy, and removes the IP
+// families they list so that the cluster assigns the ones of policy, e.g. an IPv6 address to the Services of manifests
+// listing IPv4 only on single-stack IPv6 clusters. The ExternalName Services, which have no address, are left alone.
+func injectIPFamilyPolicy(resMap resmap.ResMap, policy kfconfig.IPFamilyPolicy) {
+	if policy == "" {
+		return
+	}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Service" {
+			continue
+		}
+		m := res.Map()
+		spec, _ := m["spec"].(map[string]interface{})
+		if spec == nil {
+			spec = map[string]interface{}{}
+		}
+		if spec["type"] == "ExternalName" {
+			continue
+		}
+		if _, ok := spec["ipFamilyPolicy"]; ok {
+			continue
+		}
+		spec["ipFamilyPolicy"] = string(policy)
+		delete(spec, "ipFamilies")
+		m["spec"] = spec
+		res.SetMap(m)
+	}
+}
@@ -19,6 +19,7 @@ package kustomize
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
@@ -28,10 +29,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	errutil "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/cenkalti/backoff"
 	"github.com/ghodss/yaml"
@@ -39,12 +42,19 @@ import (
 	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
 	kftypesv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
 	kfdefsv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/audit"
+	"github.com/kubeflow/kfctl/v3/pkg/featuregates"
 	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
 	"github.com/kubeflow/kfctl/v3/pkg/utils"
 	"github.com/otiai10/copy"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -105,6 +115,27 @@ type kustomize struct {
 	componentMap     map[string]bool
 	packageMap       map[string]*[]string
 	restConfig       *rest.Config
+	// images overrides the container images of the rendered manifests
+	images []kfconfig.Image
+	// proxyEnv holds the proxy environment variables set on the Deployments of the manifests
+	proxyEnv map[string]string
+	// kubernetes is true when the cluster does not serve the OpenShift Routes, the Routes of the manifests are
+	// converted to Ingresses
+	kubernetes bool
+	// relatedImages maps the images referenced by the rendered manifests to the images they are deployed as
+	relatedImages map[string]string
+	// sccGrants are the SecurityContextConstraints granted to the ServiceAccounts of the manifests of every application
+	sccGrants map[string][]kfconfig.SCCGrant
+	// appImages maps the images referenced by the manifests of each application to the images they are deployed as
+	appImages map[string]map[string]string
+	// repoDigests holds the digests of the repo caches keying the render cache
+	repoDigests map[string]string
+	// crdUpgrades maps the applications to the changes their manifests make to the installed CRDs
+	crdUpgrades map[string][]string
+	// nodeArchitectures are the architectures of the nodes of the cluster, nil when they are unknown
+	nodeArchitectures map[string]bool
+	// unschedulable maps the applications to their workloads supporting none of the nodeArchitectures
+	unschedulable map[string][]string
 	// when set to true, apply() will skip local kube config, directly build config from restConfig
 	configOverwrite bool
 }
@@ -112,8 +143,14 @@ type kustomize struct {
 const (
 	defaultUserId = "anonymous"
 	outputDir     = "kustomize"
+	// RenderedDir is the directory of the app dir holding the manifests applied for each application
+	RenderedDir = "rendered"
 )
 
+// ApplyRetries is how many times the resources of an application that failed to apply are applied again, within
+// 10 minutes. Only the resources that were not applied are, see utils.ApplyBatch.
+var ApplyRetries = 25
+
 // Setter defines an interface for modifying the plugin.
 type Setter interface {
 	SetK8sRestConfig(r *rest.Config)
@@ -137,18 +174,102 @@ func GetKfApp(kfdef *kfconfig.KfConfig) kftypesv3.KfApp {
 	return _kustomize
 }
 
+// operatorConfig returns the config of the cluster the operator runs in, or of the kubeconfig when the operator
+// runs outside of the cluster, e.g. with --run-local, rate limited along with the apply clients
+func operatorConfig() *rest.Config {
+	if config, err := rest.InClusterConfig(); err == nil {
+		return utils.RateLimited(config)
+	}
+	return utils.RateLimited(kftypesv3.GetConfig())
+}
+
 // initK8sClients initializes the K8s clients if they haven't already been initialized.
 // it is a null op otherwise.
 func (kustomize *kustomize) initK8sClients() error {
 	if kustomize.restConfig == nil {
 		log.Infof("Initializing a default restConfig for Kubernetes")
-		kustomize.restConfig = kftypesv3.GetConfig()
+		kustomize.restConfig = utils.RateLimited(kftypesv3.GetConfig())
 	}
 
 	return nil
 }
 
-func (kustomize *kustomize) render(app kfconfig.Application) ([]byte, error) {
+// setOperatorAnnotation returns true when the resources are deployed by the Kubeflow operator and must be
+// annotated with the KfDef instance managing them.
+func (kustomize *kustomize) setOperatorAnnotation() bool {
+	annotations := kustomize.kfDef.GetAnnotations()
+	if setOperator, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.SetAnnotation}, "/")]; ok {
+		if setOperatorBool, err := strconv.ParseBool(setOperator); err == nil {
+			return setOperatorBool
+		}
+	}
+	return false
+}
+
+// featureGates returns the feature gates of the operator overridden by the ones of the KfDef
+func (kustomize *kustomize) featureGates() *featuregates.FeatureGates {
+	gates, _ := featuregates.Gates.With(kustomize.kfDef.Spec.FeatureGates)
+	return gates
+}
+
+// RenderedManifestsPath returns the file the manifests applied for app are kept in, under the app dir.
+func RenderedManifestsPath(appDir string, app string) string {
+	return path.Join(appDir, RenderedDir, app+".yaml")
+}
+
+// writeRenderedManifests keeps the manifests applied for app so that the operator can restore
+// resources that drift from them without rendering the application again.
+func (kustomize *kustomize) writeRenderedManifests(app string, data []byte) error {
+	kustomize.indexRenderedManifests(app, data)
+	renderedPath := RenderedManifestsPath(kustomize.kfDef.Spec.AppDir, app)
+	if err := os.MkdirAll(path.Dir(renderedPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(renderedPath, data, 0644)
+}
+
+// renderClients are the clients the manifests of the applications are rendered with when installed through the
+// operator. They are built once per Apply, not for every application.
+type renderClients struct {
+	// instance is the KfDef the resources are owned by
+	instance   *unstructured.Unstructured
+	kubeclient client.Client
+}
+
+// newRenderClients builds the clients of render, it returns nil unless installed through the operator
+func (kustomize *kustomize) newRenderClients() (*renderClients, error) {
+	if !kustomize.setOperatorAnnotation() {
+		return nil, nil
+	}
+	// retrieve the UID of the KfDef resource using dynamic client
+	config := operatorConfig()
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to create dynamic client: %v", err),
+		}
+	}
+	kfDefRes := schema.GroupVersionResource{Group: "kfdef.apps.kubeflow.org", Version: "v1", Resource: "kfdefs"}
+	instance, err := dyn.Resource(kfDefRes).Namespace(kustomize.kfDef.GetNamespace()).Get(kustomize.kfDef.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get the KfDef object: %v", err),
+		}
+	}
+	kubeclient, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to create kubernetes client: %v", err),
+		}
+	}
+	return &renderClients{instance: instance, kubeclient: kubeclient}, nil
+}
+
+// render returns the manifests of app, clients are the ones returned by newRenderClients
+func (kustomize *kustomize) render(app kfconfig.Application, clients *renderClients) ([]byte, error) {
 	kustomizeDir := path.Join(kustomize.kfDef.Spec.AppDir, outputDir)
 	resMap, err := EvaluateKustomizeManifest(path.Join(kustomizeDir, app.Name))
 	if err != nil {
@@ -159,36 +280,134 @@ func (kustomize *kustomize) render(app kfconfig.Application) ([]byte, error) {
 		}
 	}
 
+	if IsFIPSEnabled() {
+		if err := checkFIPSCompatible(resMap, app.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if kustomize.sccGrants == nil {
+		kustomize.sccGrants = map[string][]kfconfig.SCCGrant{}
+	}
+	// The SecurityContextConstraints are granted on OpenShift only, they are applied along with the ServiceAccounts
+	if !kustomize.kubernetes {
+		if kustomize.sccGrants[app.Name], err = grantSecurityContextConstraints(resMap, app.Name, kustomize.kfDef.Namespace); err != nil {
+			return nil, err
+		}
+	}
 	sortResourceByKind(resMap, utils.InstallOrder)
+	for _, res := range resMap.Resources() {
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		if _, err := utils.ResourceWave(obj); err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: err.Error(),
+			}
+		}
+		if _, err := parseHook(obj.DeepCopy(), kustomize.kfDef.Namespace); err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INVALID_ARGUMENT),
+				Message: err.Error(),
+			}
+		}
+	}
 
-	// check to set owner references for resources if installed through kubeflow operator
-	annotations := kustomize.kfDef.GetAnnotations()
-	setOperatorAnnotation := false
-	if setOperator, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.SetAnnotation}, "/")]; ok {
-		if setOperatorBool, err := strconv.ParseBool(setOperator); err == nil {
-			setOperatorAnnotation = setOperatorBool
+	if kustomize.relatedImages == nil {
+		kustomize.relatedImages = map[string]string{}
+	}
+	if kustomize.appImages == nil {
+		kustomize.appImages = map[string]map[string]string{}
+	}
+	kustomize.appImages[app.Name] = overrideImages(resMap, kustomize.images)
+	for name, image := range kustomize.appImages[app.Name] {
+		kustomize.relatedImages[name] = image
+	}
+	injectProxyEnv(resMap, kustomize.proxyEnv)
+	if err := overrideWorkloads(resMap, app.Name, app.Overrides); err != nil {
+		return nil, err
+	}
+	if err := injectScheduling(resMap, mergeScheduling(kustomize.kfDef.Spec.Scheduling, app.Scheduling)); err != nil {
+		return nil, err
+	}
+	if err := injectAvailability(resMap, mergeAvailability(kustomize.kfDef.Spec.Availability, app.Availability)); err != nil {
+		return nil, err
+	}
+	if err := injectAutoscaling(resMap, app.Autoscaling); err != nil {
+		return nil, err
+	}
+	if kustomize.unschedulable == nil {
+		kustomize.unschedulable = map[string][]string{}
+	}
+	if kustomize.unschedulable[app.Name], err = injectArchitectures(resMap, kustomize.nodeArchitectures); err != nil {
+		return nil, err
+	}
+	injectIPFamilyPolicy(resMap, kustomize.kfDef.Spec.IPFamilyPolicy)
+	injectPriorityClass(resMap, operatorconfig.Current().PriorityClasses.ControlPlane.Name)
+	if kustomize.kfDef.Spec.ServiceMesh == kfconfig.Managed {
+		if err := injectSidecars(resMap); err != nil {
+			return nil, err
+		}
+	}
+	if err := exposeServices(resMap); err != nil {
+		return nil, err
+	}
+	if kustomize.kfDef.Spec.Auth == kfconfig.Authorino && !kustomize.featureGates().Enabled(featuregates.AuthorinoAuth) {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("the %v auth provider is disabled by the %v feature gate", kfconfig.Authorino, featuregates.AuthorinoAuth),
 		}
 	}
+	if err := injectAuth(resMap, kustomize.kfDef.Namespace, kustomize.kfDef.Spec.Auth, kustomize.kfDef.Spec.ServiceMesh); err != nil {
+		return nil, err
+	}
+	if kustomize.kubernetes {
+		if err := routesToIngresses(resMap, kustomize.kfDef.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	if err := issueServingCertificates(resMap, kustomize.kfDef.Namespace); err != nil {
+		return nil, err
+	}
+	if err := injectBackupHooks(resMap, restoreOrder(kustomize.kfDef.Spec.Applications, app.Name)); err != nil {
+		return nil, err
+	}
+	// The workloads are hardened once every container, sidecars included, has been added
+	if err := injectPodSecurity(resMap, appPodSecurity(&kustomize.kfDef.Spec, app), namespacePodSecurity(&kustomize.kfDef.Spec),
+		operatorconfig.Current().ApplicationsNamespace); err != nil {
+		return nil, err
+	}
 
 	//TODO this should be streamed
 	var data []byte
-	if setOperatorAnnotation {
-		// retrieve the UID of the KfDef resource using dynamic client
-		config, _ := rest.InClusterConfig()
-		dyn, err := dynamic.NewForConfig(config)
-		if err != nil {
-			return nil, &kfapisv3.KfError{
-				Code:    int(kfapisv3.INTERNAL_ERROR),
-				Message: fmt.Sprintf("failed to create dynamic client: %v", err),
-			}
+	// check to set owner references for resources if installed through kubeflow operator
+	if clients != nil {
+		instance, kubeclient := clients.instance, clients.kubeclient
+		if err = checkOwnershipConflicts(resMap, instance, kubeclient); err != nil {
+			return nil, err
 		}
-		kfDefRes := schema.GroupVersionResource{Group: "kfdef.apps.kubeflow.org", Version: "v1", Resource: "kfdefs"}
-		instance, err := dyn.Resource(kfDefRes).Namespace(kustomize.kfDef.GetNamespace()).Get(kustomize.kfDef.GetName(), metav1.GetOptions{})
+		upgrades, err := checkCRDUpgrades(resMap, instance, kubeclient)
 		if err != nil {
-			return nil, &kfapisv3.KfError{
-				Code:    int(kfapisv3.INTERNAL_ERROR),
-				Message: fmt.Sprintf("failed to get the KfDef object: %v", err),
-			}
+			return nil, err
+		}
+		if kustomize.crdUpgrades == nil {
+			kustomize.crdUpgrades = map[string][]string{}
+		}
+		kustomize.crdUpgrades[app.Name] = upgrades
+		if err = removeIgnoredResources(resMap, instance.GetNamespace(), kubeclient); err != nil {
+			return nil, err
+		}
+		if err = injectArgoCDCompatibility(resMap, instance.GetNamespace(), kubeclient); err != nil {
+			return nil, err
+		}
+		if err = kustomize.injectTrustedCABundle(resMap, kubeclient, !utils.IsDryRun(kustomize.kfDef)); err != nil {
+			return nil, err
+		}
+		if err = keepCookieSecrets(resMap, instance.GetNamespace(), kubeclient); err != nil {
+			return nil, err
+		}
+		// The checksums are computed once the cookie secrets are kept, which would roll out the workloads otherwise
+		if err = injectConfigChecksums(resMap); err != nil {
+			return nil, err
 		}
 		data, err = GenerateYamlWithOperatorAnnotation(resMap, instance)
 		if err != nil {
@@ -198,6 +417,9 @@ func (kustomize *kustomize) render(app kfconfig.Application) ([]byte, error) {
 			}
 		}
 	} else {
+		if err := injectConfigChecksums(resMap); err != nil {
+			return nil, err
+		}
 		data, err = resMap.AsYaml()
 		if err != nil {
 			return nil, &kfapisv3.KfError{
@@ -211,6 +433,15 @@ func (kustomize *kustomize) render(app kfconfig.Application) ([]byte, error) {
 
 // Dump prints the kustomize generated resources to stdout
 func (kustomize *kustomize) Dump(resources kftypesv3.ResourceEnum) error {
+	images, err := kustomize.loadImageOverrides()
+	if err != nil {
+		return err
+	}
+	kustomize.images = images
+	clients, err := kustomize.newRenderClients()
+	if err != nil {
+		return err
+	}
 
 	applications := make(map[string]bool)
 	for _, app := range kustomize.kfDef.Spec.Applications {
@@ -220,7 +451,7 @@ func (kustomize *kustomize) Dump(resources kftypesv3.ResourceEnum) error {
 		}
 		applications[app.Name] = true
 
-		data, err := kustomize.render(app)
+		data, err := kustomize.render(app, clients)
 		if err != nil {
 			return err
 		}
@@ -232,6 +463,12 @@ func (kustomize *kustomize) Dump(resources kftypesv3.ResourceEnum) error {
 
 // Apply deploys kustomize generated resources to the kubenetes api server
 func (kustomize *kustomize) Apply(resources kftypesv3.ResourceEnum) error {
+	return kustomize.ApplyContext(context.Background(), resources)
+}
+
+// ApplyContext is Apply with the applies, the hooks, the readiness waits and the prune cancelled once applyCtx is
+// done, e.g. when the reconciliation of the operator times out or the operator shuts down
+func (kustomize *kustomize) ApplyContext(applyCtx context.Context, resources kftypesv3.ResourceEnum) error {
 	var restConfig *rest.Config = nil
 	if kustomize.configOverwrite && kustomize.restConfig != nil {
 		restConfig = kustomize.restConfig
@@ -240,6 +477,8 @@ func (kustomize *kustomize) Apply(resources kftypesv3.ResourceEnum) error {
 	if err != nil {
 		return err
 	}
+	// The applications applied are indexed again, along with their rendered manifests
+	ForgetRenderedManifests(kustomize.kfDef.Namespace, kustomize.kfDef.Name)
 
 	// Read clusterName and write to KfDef.
 	kubeconfig := kftypesv3.GetKubeConfig()
@@ -255,19 +494,175 @@ func (kustomize *kustomize) Apply(resources kftypesv3.ResourceEnum) error {
 		}
 	}
 
-	applications := make(map[string]bool)
+	// Every application is pending until it has been applied
 	for _, app := range kustomize.kfDef.Spec.Applications {
-		if applications[app.Name] == true {
-			// if the application name already
-			continue
+		kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Progressing, "ApplyPending",
+			"waiting for the application to be applied")
+	}
+
+	if kustomize.images, err = kustomize.loadImageOverrides(); err != nil {
+		return err
+	}
+	kustomize.relatedImages = map[string]string{}
+	if kustomize.setOperatorAnnotation() {
+		// Changes of the proxy configuration roll the Deployments out again as their pod templates change
+		if kustomize.proxyEnv, err = kustomize.loadProxyEnv(); err != nil {
+			return err
+		}
+	}
+
+	servesRoutes, err := kustomize.servesRoutes()
+	if err != nil {
+		return err
+	}
+	kustomize.kubernetes = !servesRoutes
+	kustomize.nodeArchitectures = nil
+	if kustomize.setOperatorAnnotation() {
+		// The workloads are still scheduled by architecture when the nodes cannot be listed, they are only not reported
+		if kustomize.nodeArchitectures, err = kustomize.loadNodeArchitectures(); err != nil {
+			log.Warnf("Failed to detect the architectures of the nodes: %v", err)
+		}
+	}
+
+	// In dry run mode, the changes the manifests would make are recorded instead of applied
+	dryRun := utils.IsDryRun(kustomize.kfDef)
+	diffs := map[string]string{}
+	// In GitOps mode, the manifests are pushed to the git repository of the spec instead of applied
+	gitOps := kustomize.kfDef.Spec.GitOps != nil && !dryRun
+	exported := map[string][]byte{}
+	// The resources applied for every application are recorded to prune the ones removed from the manifests, and to
+	// record the changes of the manifests
+	recording := kustomize.setOperatorAnnotation() && !dryRun && !gitOps
+	var inventory map[string][]inventoryItem
+	applied := map[string][]byte{}
+	var kubeclient client.Client
+	var mapper meta.RESTMapper
+	if dryRun || recording {
+		kustomize.initK8sClients()
+		if kubeclient, err = client.New(kustomize.restConfig, client.Options{}); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("error initializing k8s client: %v", err),
+			}
+		}
+		kubeclient = utils.WithFaults(audit.WithAudit(kubeclient))
+	}
+	if dryRun {
+		if mapper, err = apiutil.NewDiscoveryRESTMapper(kustomize.restConfig); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("error initializing k8s rest mapper: %v", err),
+			}
+		}
+	}
+	clients, err := kustomize.newRenderClients()
+	if err != nil {
+		return err
+	}
+	if recording {
+		if inventory, err = readInventory(applyCtx, kubeclient, kustomize.kfDef.Name, kustomize.kfDef.Namespace); err != nil {
+			return err
+		}
+	}
+
+	// The applications are applied by MaxConcurrentApplies workers. Rendering them and recording their status
+	// is serialized by mu, only their resources are applied at the same time.
+	var mu sync.Mutex
+	parent := tracing.Active(tracing.Key(kustomize.kfDef.Namespace, kustomize.kfDef.Name))
+	applyApplication := func(app kfconfig.Application) error {
+		mu.Lock()
+		// Paused applications are left as they are, their resources remain in the inventory
+		if utils.IsApplicationPaused(kustomize.kfDef, app.Name) {
+			log.Infof("Reconciliation of application %v is paused, skipping it", app.Name)
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Progressing, "ApplicationPaused",
+				"reconciliation paused by the "+utils.KfDefAnnotation+"/"+utils.PausedApplications+" annotation")
+			if gitOps {
+				// Their manifests are left as they are in the repository
+				exported[app.Name] = nil
+			}
+			mu.Unlock()
+			return nil
+		}
+		// Applications applied from the same manifests are left as they are
+		cacheKey := ""
+		if kustomize.renderCacheEnabled() && !gitOps {
+			var err error
+			if cacheKey, err = kustomize.renderCacheKey(app); err != nil {
+				log.Warnf("Failed to compute the render cache key of application %v: %v", app.Name, err)
+			} else if entry, ok := kustomize.cachedRender(app.Name, cacheKey); ok && (!recording || inventory[app.Name] != nil) {
+				log.Infof("Application %v is unchanged since it was applied at %v, skipping it", app.Name, entry.AppliedAt)
+				// The app dir is recreated on every reconcile, the manifests applied are read from it e.g. to restore
+				// the resources drifting from them
+				if kustomize.setOperatorAnnotation() {
+					if err := kustomize.writeRenderedManifests(app.Name, entry.Rendered); err != nil {
+						log.Warnf("Failed to keep the manifests applied for %v: %v", app.Name, err)
+					}
+				}
+				for name, image := range entry.RelatedImages {
+					kustomize.relatedImages[name] = image
+				}
+				kustomize.kfDef.Status.SecurityContextConstraints = append(kustomize.kfDef.Status.SecurityContextConstraints,
+					entry.SecurityContextConstraints...)
+				kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Available, "ApplySucceeded",
+					"application unchanged since it was applied successfully")
+				kustomize.kfDef.SetApplicationDigest(app.Name, cacheKey)
+				mu.Unlock()
+				return nil
+			}
 		}
-		applications[app.Name] = true
 
 		log.Infof("Deploying application %v", app.Name)
-		data, err := kustomize.render(app)
+		start := time.Now()
+		span := tracing.Start(parent, "Application", "application", app.Name)
+		renderSpan := tracing.Start(span, "Render")
+		data, err := kustomize.render(app, clients)
+		renderSpan.End(err)
 		if err != nil {
+			span.End(err)
+			reason := "RenderFailed"
+			if kfapisv3.IsConflict(err) {
+				reason = "ResourceConflict"
+			}
+			if kfapisv3.IsUnsafeUpgrade(err) {
+				reason = "CRDUpgradeRefused"
+			}
+			if _, ok := err.(*fipsIncompatibleError); ok {
+				reason = FIPSIncompatibleReason
+			}
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Degraded, reason, err.Error())
+			mu.Unlock()
+			if !dryRun {
+				metrics.ObserveComponentReconcile(kustomize.kfDef.Namespace, kustomize.kfDef.Name, app.Name, time.Since(start), err)
+			}
 			return err
 		}
+		mu.Unlock()
+
+		if gitOps {
+			span.End(nil)
+			mu.Lock()
+			defer mu.Unlock()
+			exported[app.Name] = data
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Progressing, "ManifestsRendered",
+				"application rendered, its manifests are pushed to the GitOps repository")
+			return nil
+		}
+
+		if dryRun {
+			diff, err := dryRunApply(applyCtx, kubeclient, mapper, data, kustomize.kfDef.Namespace)
+			span.End(err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Errorf("Dry run failed for application %v: %v", app.Name, err)
+				kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Degraded, "DryRunFailed", err.Error())
+				return err
+			}
+			diffs[app.Name+DryRunDiffSuffix] = diff
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Progressing, "DryRunSucceeded",
+				"application validated by the API server, see the changes in ConfigMap "+DryRunConfigMapName(kustomize.kfDef.Name))
+			return nil
+		}
 
 		// TODO(https://github.com/kubeflow/manifests/issues/806): Bump the timeout because cert-manager takes
 		// a long time to start. Any application that needs to create a certificate will fail because it won't
@@ -275,20 +670,140 @@ func (kustomize *kustomize) Apply(resources kftypesv3.ResourceEnum) error {
 		// and return a PermanentError to avoid retrying and taking 10 minutes to fail.
 		b := utils.NewDefaultBackoff()
 		b.MaxElapsedTime = 10 * time.Minute
-		err = backoff.RetryNotify(
-			func() error {
-				return apply.Apply(data)
-			},
-			b,
-			func(e error, duration time.Duration) {
-				log.Warnf("Encountered error applying application %v: %v", app.Name, e)
-				log.Warnf("Will retry in %.0f seconds.", duration.Seconds())
-			})
+		applySpan := tracing.Start(span, "Apply")
+		// The hook Jobs run before and after the other resources are applied
+		preHooks, postHooks, resources, err := splitHooks(data, kustomize.kfDef.Namespace)
+		if err == nil {
+			err = kustomize.runHooks(applyCtx, app, preHooks, applySpan)
+		}
+		var batch *utils.ApplyBatch
+		if err == nil {
+			batch, err = utils.NewApplyBatch(resources)
+		}
+		if err == nil {
+			err = backoff.RetryNotify(
+				func() error {
+					err := apply.ApplyBatch(applyCtx, batch, applySpan)
+					// Conflicts are not resolved by retrying, the other field managers keep their fields
+					if kfapisv3.IsConflict(err) {
+						return backoff.Permanent(err)
+					}
+					return err
+				},
+				backoff.WithContext(backoff.WithMaxRetries(b, uint64(ApplyRetries)), applyCtx),
+				func(e error, duration time.Duration) {
+					log.Warnf("Encountered error applying application %v: %v", app.Name, e)
+					log.Warnf("Will retry the %d resources not applied in %.0f seconds.", batch.Pending(), duration.Seconds())
+				})
+		}
+		if err == nil {
+			err = kustomize.runHooks(applyCtx, app, postHooks, applySpan)
+		}
+		applySpan.End(err)
+		span.End(err)
+		metrics.ObserveComponentReconcile(kustomize.kfDef.Namespace, kustomize.kfDef.Name, app.Name, time.Since(start), err)
+		mu.Lock()
+		defer mu.Unlock()
 		if err != nil {
 			log.Errorf("Permanently failed applying application %v: %v", app.Name, err)
+			reason := "ApplyFailed"
+			if kfapisv3.IsConflict(err) {
+				reason = "ResourceConflict"
+			}
+			if hookErr, ok := err.(*hookError); ok {
+				reason = HookFailedReason
+				kustomize.kfDef.Status.HookFailures = append(kustomize.kfDef.Status.HookFailures, hookErr.failure)
+			}
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Degraded, reason, err.Error())
 			return err
 		}
 		log.Infof("Successfully applied application %v", app.Name)
+		if kustomize.setOperatorAnnotation() {
+			if err := kustomize.writeRenderedManifests(app.Name, data); err != nil {
+				log.Warnf("Failed to keep the manifests applied for %v: %v", app.Name, err)
+			}
+		}
+		// The applications without compatible nodes are not cached, for their condition to clear once nodes join
+		if cacheKey != "" && len(kustomize.unschedulable[app.Name]) == 0 {
+			kustomize.storeRender(app.Name, cacheKey, data, kustomize.appImages[app.Name], kustomize.sccGrants[app.Name], start)
+		}
+		applied[app.Name] = data
+		kustomize.kfDef.Status.SecurityContextConstraints = append(kustomize.kfDef.Status.SecurityContextConstraints,
+			kustomize.sccGrants[app.Name]...)
+		message := "application applied successfully"
+		if upgrades := kustomize.crdUpgrades[app.Name]; len(upgrades) > 0 {
+			log.Infof("Upgraded the CRDs of application %v: %v", app.Name, strings.Join(upgrades, "; "))
+			message += ", upgraded CRDs " + strings.Join(upgrades, "; ")
+		}
+		if unschedulable := kustomize.unschedulable[app.Name]; len(unschedulable) > 0 {
+			message = fmt.Sprintf("no node of the architectures of %v, the nodes are %v", strings.Join(unschedulable, "; "),
+				strings.Join(sortedArchitectures(kustomize.nodeArchitectures), ", "))
+			log.Warnf("Application %v has no compatible nodes: %v", app.Name, message)
+			kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Degraded, NoCompatibleNodesReason, message)
+			return nil
+		}
+		kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Available, "ApplySucceeded", message)
+		if cacheKey != "" {
+			kustomize.kfDef.SetApplicationDigest(app.Name, cacheKey)
+		}
+		return nil
+	}
+	// The applications depending on an application are applied once its Deployments are available and its Jobs
+	// completed
+	gated := gatedApplications(kustomize.kfDef.Spec.Applications)
+	err = applyInOrder(kustomize.kfDef.Spec.Applications, MaxConcurrentApplies, func(app kfconfig.Application) error {
+		if err := applyApplication(app); err != nil || !gated[app.Name] || dryRun || gitOps {
+			return err
+		}
+		mu.Lock()
+		paused := utils.IsApplicationPaused(kustomize.kfDef, app.Name)
+		data := applied[app.Name]
+		mu.Unlock()
+		if paused {
+			return nil
+		}
+		err := kustomize.waitForReadiness(applyCtx, app, data)
+		if err == nil {
+			return nil
+		}
+		log.Errorf("Application %v is not ready: %v", app.Name, err)
+		reason := ReadinessFailedReason
+		if readinessErr, ok := err.(*readinessError); ok {
+			reason = readinessErr.reason
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(kustomize.unschedulable[app.Name]) > 0 {
+			reason = NoCompatibleNodesReason
+		}
+		kustomize.kfDef.SetApplicationCondition(app.Name, kfconfig.Degraded, reason, err.Error())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if recording {
+		span := tracing.Start(parent, "Prune")
+		err := kustomize.pruneApplications(applyCtx, kubeclient, inventory, applied)
+		span.End(err)
+		if err != nil {
+			return err
+		}
+	}
+
+	if gitOps {
+		return kustomize.pushManifests(parent, exported)
+	}
+
+	if dryRun {
+		return writeDryRunDiffs(kubeclient, kustomize.kfDef.Name, kustomize.kfDef.Namespace, diffs)
+	}
+
+	if kustomize.setOperatorAnnotation() {
+		if err := kustomize.writeImagesReport(); err != nil {
+			log.Warnf("Failed to report the images of %v: %v", kustomize.kfDef.Name, err)
+		}
 	}
 
 	// Default user namespace when multi-tenancy enabled
@@ -365,6 +880,16 @@ func (kustomize *kustomize) deleteGlobalResources() error {
 
 // Delete is called from 'kfctl delete ...'. Will delete all resources deployed from the Apply method
 func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
+	return kustomize.DeleteContext(context.Background(), resources)
+}
+
+// DeleteContext is Delete with the deletion of the applications stopped once ctx is done
+func (kustomize *kustomize) DeleteContext(ctx context.Context, resources kftypesv3.ResourceEnum) error {
+	// The applications are applied again if they are deployed again
+	for _, app := range kustomize.kfDef.Spec.Applications {
+		kustomize.forgetRender(app.Name)
+	}
+
 	annotations := kustomize.kfDef.GetAnnotations()
 	forceDelete := false
 	if forceDel, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.ForceDelete}, "/")]; ok {
@@ -383,6 +908,14 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 			byOperator = byOperatorAnnBol
 		}
 	}
+	// The operator only deletes cluster-scoped resources other than namespaces when allowed to
+	deleteClusterScoped := !byOperator
+	if deleteAnn, ok := annotations[strings.Join([]string{utils.KfDefAnnotation, utils.DeleteClusterScoped}, "/")]; ok {
+		if deleteAnnBool, err := strconv.ParseBool(deleteAnn); err == nil {
+			deleteClusterScoped = deleteClusterScoped || deleteAnnBool
+		}
+	}
+	kfdefCr := strings.Join([]string{kustomize.kfDef.Name, kustomize.kfDef.Namespace}, ".")
 
 	// Get kubeconfig for cluster and initialize clients
 	msg := ""
@@ -418,12 +951,31 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 			Message: fmt.Sprintf("error initializing k8s client: %v", err),
 		}
 	}
+	mapper, err := apiutil.NewDiscoveryRESTMapper(kustomize.restConfig)
+	if err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing k8s rest mapper: %v", err),
+		}
+	}
 
 	// Delete in reverse application order
 	kustomizeDir := path.Join(kustomize.kfDef.Spec.AppDir, outputDir)
 	errList := []error{}
+	retained := false
 	for idx := range kustomize.kfDef.Spec.Applications {
 		app := &kustomize.kfDef.Spec.Applications[len(kustomize.kfDef.Spec.Applications)-1-idx]
+		if app.DeletionPolicy == kfconfig.DeletionPolicyRetain {
+			log.Infof("Retaining application %v", app.Name)
+			retained = true
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("deletion of application %v stopped: %v", app.Name, err),
+			}
+		}
 		log.Infof("Deleting application %v", app.Name)
 		resMap, err := EvaluateKustomizeManifest(path.Join(kustomizeDir, app.Name))
 		if err != nil {
@@ -452,7 +1004,10 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 			}
 		}
 		for _, r := range resources {
-			err := utils.DeleteResource(r, kubeclient, 5*time.Minute, byOperator)
+			if !deleteClusterScoped && isClusterScoped(mapper, r) {
+				continue
+			}
+			err := utils.DeleteResource(r, kubeclient, 5*time.Minute, byOperator, kfdefCr)
 			if err != nil {
 				msg := fmt.Sprintf("error evaluating kustomization manifest for %v: %v", app.Name, err)
 				errList = append(errList, errors.New(msg))
@@ -472,6 +1027,11 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 	// Finally, delete the kubeflow namespace
 	// TODO(yanniszark): Remove this once the Kubeflow namespace is created by kustomize manifests
 
+	// The retained applications may have resources in the namespace
+	if retained {
+		log.Infof("Keeping namespace %v of the retained applications", kustomize.kfDef.Namespace)
+		return nil
+	}
 	corev1client, err := corev1.NewForConfig(kustomize.restConfig)
 	if err != nil {
 		return &kfapisv3.KfError{
@@ -486,8 +1046,7 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 		if byOperator {
 			anns := ns.GetAnnotations()
 			kfdefAnn := strings.Join([]string{utils.KfDefAnnotation, utils.KfDefInstance}, "/")
-			_, found := anns[kfdefAnn]
-			if !found {
+			if owner, found := anns[kfdefAnn]; !found || owner != kfdefCr {
 				return nil
 			}
 		}
@@ -505,6 +1064,24 @@ func (kustomize *kustomize) Delete(resources kftypesv3.ResourceEnum) error {
 	return nil
 }
 
+// isClusterScoped returns true if the resource is cluster-scoped, namespaces excepted.
+// Resources of unknown kinds are not cluster-scoped.
+func isClusterScoped(mapper meta.RESTMapper, resourceBytes []byte) bool {
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(resourceBytes, &u.Object); err != nil {
+		return false
+	}
+	gvk := u.GroupVersionKind()
+	if gvk.Kind == "Namespace" {
+		return false
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameRoot
+}
+
 // sortResourceByKind does in-place sort of resources by Kind.
 func sortResourceByKind(resMap resmap.ResMap, order utils.SortOrder) {
 	resourcesInUninstallOrder := utils.SortByKind(resMap.Resources(), order)
@@ -548,19 +1125,28 @@ func (kustomize *kustomize) Generate(resources kftypesv3.ResourceEnum) error {
 			}
 		}
 
-		_, ok := kustomize.kfDef.GetRepoCache(kftypesv3.ManifestsRepoName)
-		if !ok {
-			log.Infof("Repo %v not listed in KfDef.Status; Resync'ing cache", kftypesv3.ManifestsRepoName)
-			if err := kustomize.kfDef.SyncCache(); err != nil {
-				log.Errorf("Syncing the cached failed: %v", err)
-				return errors.WithStack(err)
+		// The repos are only needed when some application is not deployed from a chart repository
+		usesRepos := false
+		for _, app := range kustomize.kfDef.Spec.Applications {
+			if app.HelmConfig == nil || app.HelmConfig.RepoRef != nil {
+				usesRepos = true
 			}
 		}
+		if usesRepos {
+			_, ok := kustomize.kfDef.GetRepoCache(kftypesv3.ManifestsRepoName)
+			if !ok {
+				log.Infof("Repo %v not listed in KfDef.Status; Resync'ing cache", kftypesv3.ManifestsRepoName)
+				if err := kustomize.kfDef.SyncCache(); err != nil {
+					log.Errorf("Syncing the cached failed: %v", err)
+					return errors.WithStack(err)
+				}
+			}
 
-		// Check again after sync
-		_, ok = kustomize.kfDef.GetRepoCache(kftypesv3.ManifestsRepoName)
-		if !ok {
-			return errors.WithStack(fmt.Errorf("Repo %v not listed in KfDef.Status; ", kftypesv3.ManifestsRepoName))
+			// Check again after sync
+			_, ok = kustomize.kfDef.GetRepoCache(kftypesv3.ManifestsRepoName)
+			if !ok {
+				return errors.WithStack(fmt.Errorf("Repo %v not listed in KfDef.Status; ", kftypesv3.ManifestsRepoName))
+			}
 		}
 
 		// determine whether we are using the new pattern of using kustomize to build stacks.
@@ -568,6 +1154,14 @@ func (kustomize *kustomize) Generate(resources kftypesv3.ResourceEnum) error {
 		for _, app := range kustomize.kfDef.Spec.Applications {
 			log.Infof("Processing application: %v", app.Name)
 
+			if app.HelmConfig != nil {
+				if err := kustomize.generateHelmApp(app, kustomizeDir); err != nil {
+					log.Errorf("%v", err)
+					return err
+				}
+				continue
+			}
+
 			if app.KustomizeConfig == nil {
 				err := fmt.Errorf("application %v is missing KustomizeConfig", app.Name)
 				log.Errorf("%v", err)
@@ -625,8 +1219,10 @@ func (kustomize *kustomize) Generate(resources kftypesv3.ResourceEnum) error {
 						Message: fmt.Sprintf("couldn't copy application %s: %v", app.Name, err),
 					}
 				}
+				// In FIPS mode, the components deploy their FIPS-compliant variant when they have one
+				overlays := fipsOverlays(IsFIPSEnabled(), path.Join(kustomizeDir, app.Name), app.KustomizeConfig.Overlays)
 				if err := GenerateKustomizationFile(kustomize.kfDef, kustomizeDir, app.Name,
-					app.KustomizeConfig.Overlays, app.KustomizeConfig.Parameters); err != nil {
+					overlays, app.KustomizeConfig.Parameters); err != nil {
 					return &kfapisv3.KfError{
 						Code:    int(kfapisv3.INTERNAL_ERROR),
 						Message: fmt.Sprintf("couldn't generate kustomization file for component %s: %v", app.Name, err),
@@ -776,7 +1372,7 @@ func (kustomize *kustomize) mapDirs(dirPath string, root bool, depth int, leafMa
 }
 
 func (kustomize *kustomize) SetK8sRestConfig(r *rest.Config) {
-	kustomize.restConfig = r
+	kustomize.restConfig = utils.RateLimited(r)
 	kustomize.configOverwrite = true
 }
 
@@ -1389,7 +1985,100 @@ func CreateKustomizationMaps() map[MapType]map[string]bool {
 	}
 }
 
-// GenerateYamlWithOperatorAnnotation adds operator info to the annotation to every resource
+// checkOwnershipConflicts returns a KfError with code CONFLICT listing the resources of resMap
+// that already exist and are annotated as managed by another KfDef instance which still exists.
+// Namespaces and CRDs are shared between deployments and never conflict.
+func checkOwnershipConflicts(resMap resmap.ResMap, instance *unstructured.Unstructured, kubeclient client.Client) error {
+	kfdefAnn := strings.Join([]string{utils.KfDefAnnotation, utils.KfDefInstance}, "/")
+	kfdefCr := strings.Join([]string{instance.GetName(), instance.GetNamespace()}, ".")
+
+	conflicts := []string{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "Namespace" || res.GetKind() == "CustomResourceDefinition" {
+			continue
+		}
+		current, err := getLiveResource(kubeclient, res, instance.GetNamespace())
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			continue
+		}
+		owner, found := current.GetAnnotations()[kfdefAnn]
+		if !found || owner == kfdefCr || !kfDefExists(kubeclient, owner) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%v %v/%v is managed by KfDef %v",
+			current.GetKind(), current.GetNamespace(), current.GetName(), owner))
+	}
+	if len(conflicts) > 0 {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.CONFLICT),
+			Message: fmt.Sprintf("resources conflict with another KfDef: %v", strings.Join(conflicts, "; ")),
+		}
+	}
+	return nil
+}
+
+// removeIgnoredResources removes from resMap the resources whose live copy is annotated with
+// kfctl.kubeflow.io/ignore-drift=true, so that changes made to them on the cluster are kept.
+func removeIgnoredResources(resMap resmap.ResMap, namespace string, kubeclient client.Client) error {
+	for _, res := range resMap.Resources() {
+		current, err := getLiveResource(kubeclient, res, namespace)
+		if err != nil {
+			return err
+		}
+		if current == nil || !utils.IgnoresDrift(current) {
+			continue
+		}
+		log.Infof("Skipping %v %v/%v: drift is ignored.", current.GetKind(), current.GetNamespace(), current.GetName())
+		if err := resMap.Remove(res.CurId()); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to skip %v %v: %v", current.GetKind(), current.GetName(), err),
+			}
+		}
+	}
+	return nil
+}
+
+// getLiveResource returns the cluster copy of res, or nil if it does not exist yet.
+// Resources without a namespace are looked up in the given namespace.
+func getLiveResource(kubeclient client.Client, res *resource.Resource, namespace string) (*unstructured.Unstructured, error) {
+	desired := &unstructured.Unstructured{Object: res.Map()}
+	if desired.GetNamespace() != "" {
+		namespace = desired.GetNamespace()
+	}
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(desired.GroupVersionKind())
+	err := kubeclient.Get(context.TODO(), client.ObjectKey{Name: desired.GetName(), Namespace: namespace}, current)
+	if err != nil {
+		if _, ok := err.(*meta.NoKindMatchError); ok || k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get %v %v: %v", desired.GetKind(), desired.GetName(), err),
+		}
+	}
+	return current, nil
+}
+
+// kfDefExists returns true unless the KfDef named by a kfdef-instance annotation value (<name>.<namespace>)
+// is known to be gone, so that resources left behind by a deleted KfDef can be adopted.
+func kfDefExists(kubeclient client.Client, kfdefCr string) bool {
+	idx := strings.LastIndex(kfdefCr, ".")
+	if idx < 0 {
+		return true
+	}
+	kfdef := &unstructured.Unstructured{}
+	kfdef.SetGroupVersionKind(schema.GroupVersionKind{Group: "kfdef.apps.kubeflow.org", Version: "v1", Kind: "KfDef"})
+	err := kubeclient.Get(context.TODO(), client.ObjectKey{Name: kfdefCr[:idx], Namespace: kfdefCr[idx+1:]}, kfdef)
+	return !k8serrors.IsNotFound(err)
+}
+
+// GenerateYamlWithOperatorAnnotation adds operator info to the annotation to every resource, along with the
+// opendatahub.io/managed-by label selecting them in the caches and the watches of the operator
 // some code copied from ResMap.AsYaml() func
 func GenerateYamlWithOperatorAnnotation(resMap resmap.ResMap, instance *unstructured.Unstructured) ([]byte, error) {
 	addAnnotation := true
@@ -1414,7 +2103,7 @@ func GenerateYamlWithOperatorAnnotation(resMap resmap.ResMap, instance *unstruct
 		kfdefCr := strings.Join([]string{instance.GetName(), instance.GetNamespace()}, ".")
 
 		if m.GetKind() == "Namespace" {
-			config, _ := rest.InClusterConfig()
+			config := operatorConfig()
 			corev1client, err := corev1.NewForConfig(config)
 			if err != nil {
 				return nil, &kfapisv3.KfError{
@@ -1440,6 +2129,12 @@ func GenerateYamlWithOperatorAnnotation(resMap resmap.ResMap, instance *unstruct
 		if addAnnotation {
 			anns[kfdefAnn] = kfdefCr
 			m.SetAnnotations(anns)
+			labels := m.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[utils.ManagedLabel] = utils.ManagedValue
+			m.SetLabels(labels)
 		}
 		out, err := yaml.Marshal(m)
 		if err != nil {
@@ -1,20 +1,42 @@
 package kustomize
 
 import (
+	"context"
+	"fmt"
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"sigs.k8s.io/kustomize/v3/pkg/types"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
 	"github.com/otiai10/copy"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/transformer"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	kresource "sigs.k8s.io/kustomize/v3/pkg/resource"
 )
 
 // This test tests that GenerateKustomizationFile will produce correct kustomization.yaml
@@ -213,3 +235,2483 @@ func TestCreateStackAppKustomization(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceDiff(t *testing.T) {
+	configMap := func(value string, resourceVersion string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":            "odh-config",
+				"namespace":       "opendatahub",
+				"resourceVersion": resourceVersion,
+			},
+			"data": map[string]interface{}{"key": value},
+		}}
+	}
+
+	if diff := resourceDiff(nil, configMap("a", "1")); diff != "+ ConfigMap opendatahub/odh-config: created\n" {
+		t.Errorf("Unexpected diff for a created resource: %q", diff)
+	}
+	if diff := resourceDiff(configMap("a", "1"), configMap("a", "2")); diff != "" {
+		t.Errorf("Unexpected diff for an unchanged resource: %q", diff)
+	}
+	diff := resourceDiff(configMap("a", "1"), configMap("b", "2"))
+	if !strings.HasPrefix(diff, "~ ConfigMap opendatahub/odh-config: changed\n") || !strings.Contains(diff, `"b"`) {
+		t.Errorf("Unexpected diff for a changed resource: %q", diff)
+	}
+}
+
+func TestOverrideImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	images := mergeImages([]kfconfig.Image{
+		{Name: "quay.io/opendatahub/odh-dashboard", NewName: "registry.example.com/odh-dashboard"},
+		{Name: "quay.io/opendatahub/notebooks", NewTag: "v1"},
+	}, []kfconfig.Image{
+		{Name: "quay.io/opendatahub/notebooks", NewName: "registry.example.com/notebooks", Digest: digest},
+		{Name: "localhost:5000/odh", NewTag: "v2"},
+	})
+
+	testCases := map[string]string{
+		"quay.io/opendatahub/odh-dashboard:v1.0":           "registry.example.com/odh-dashboard:v1.0",
+		"quay.io/opendatahub/odh-dashboard":                "registry.example.com/odh-dashboard",
+		"quay.io/opendatahub/notebooks:v1":                 "registry.example.com/notebooks@" + digest,
+		"quay.io/opendatahub/notebooks:v1@sha256:b":        "registry.example.com/notebooks@" + digest,
+		"localhost:5000/odh:v1":                            "localhost:5000/odh:v2",
+		"quay.io/opendatahub/odh-dashboard-extension:v1.0": "quay.io/opendatahub/odh-dashboard-extension:v1.0",
+	}
+	for image, expected := range testCases {
+		if actual := overrideImage(image, images); actual != expected {
+			t.Errorf("Override of %v: got %v; want %v", image, actual, expected)
+		}
+	}
+
+	data, err := imagesReport("opendatahub", map[string]string{
+		"quay.io/opendatahub/odh-dashboard:v1.0": "registry.example.com/odh-dashboard:v1.0",
+		"quay.io/opendatahub/odh-manifests:v1.0": "quay.io/opendatahub/odh-manifests:v1.0",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	icsp := data[ImageContentSourcePolicyKey]
+	if !strings.Contains(icsp, "source: quay.io/opendatahub/odh-dashboard") || strings.Contains(icsp, "odh-manifests") {
+		t.Errorf("Unexpected ImageContentSourcePolicy: %v", icsp)
+	}
+}
+
+func TestSyncTrustedCABundleLabels(t *testing.T) {
+	k := &kustomize{kfDef: &kfconfig.KfConfig{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "opendatahub"}}}
+	unlabelled := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: TrustedCABundleConfigMap, Namespace: "existing"}}
+	c := fake.NewFakeClientWithScheme(kubescheme.Scheme, unlabelled)
+	source := "bundle"
+	for _, namespace := range []string{"opendatahub", "existing"} {
+		if _, err := k.syncTrustedCABundle(c, namespace, &source, true); err != nil {
+			t.Fatalf("Failed to sync the trusted CA bundle of %v: %v", namespace, err)
+		}
+		cm := &v1.ConfigMap{}
+		if err := c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: TrustedCABundleConfigMap}, cm); err != nil {
+			t.Fatalf("Expected the trusted CA bundle of %v: %v", namespace, err)
+		}
+		if !utils.IsManaged(cm) {
+			t.Errorf("Expected the trusted CA bundle of %v to be labelled %v, got %v", namespace, utils.ManagedLabel, cm.Labels)
+		}
+	}
+}
+
+func TestTrustedCABundleSource(t *testing.T) {
+	system, err := ioutil.TempFile("", "tls-ca-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(system.Name())
+	if _, err := system.WriteString("public CAs\n"); err != nil {
+		t.Fatal(err)
+	}
+	system.Close()
+	defer func(file string) { SystemCABundleFile = file }(SystemCABundleFile)
+
+	ann := utils.KfDefAnnotation + "/" + utils.TrustedCABundle
+	c := fake.NewFakeClientWithScheme(kubescheme.Scheme,
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "odh"},
+			Data: map[string]string{TrustedCABundleKey: "proxy CA", "other.crt": "other CA"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "odh"},
+			Data: map[string]string{"b.crt": "second CA\n", "a.crt": "first CA"}},
+	)
+	type testCase struct {
+		Name       string
+		ConfigMap  string
+		SystemFile string
+		// Bundle is <nil> when the bundle is injected by OpenShift
+		Bundle string
+	}
+	testCases := []testCase{
+		{Name: "injected by OpenShift", SystemFile: system.Name(), Bundle: "<nil>"},
+		{Name: "bundle key", ConfigMap: "odh/bundle", SystemFile: system.Name(), Bundle: "public CAs\nproxy CA\n"},
+		{Name: "all keys", ConfigMap: "odh/keys", SystemFile: system.Name(), Bundle: "public CAs\nfirst CA\nsecond CA\n"},
+		{Name: "no system bundle", ConfigMap: "odh/bundle", SystemFile: system.Name() + "-missing", Bundle: "proxy CA\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			SystemCABundleFile = tc.SystemFile
+			k := &kustomize{kfDef: &kfconfig.KfConfig{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "odh"}}}
+			if tc.ConfigMap != "" {
+				k.kfDef.SetAnnotations(map[string]string{ann: tc.ConfigMap})
+			}
+			bundle, err := k.trustedCABundleSource(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := "<nil>"
+			if bundle != nil {
+				got = *bundle
+			}
+			if got != tc.Bundle {
+				t.Errorf("Expected bundle %q, got %q", tc.Bundle, got)
+			}
+		})
+	}
+}
+
+func TestMountTrustedCABundle(t *testing.T) {
+	deployment := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+      - name: oauth-proxy
+        image: quay.io/openshift/oauth-proxy
+        volumeMounts:
+        - name: ca
+          mountPath: /etc/pki/ca-trust/extracted/pem
+`), &deployment.Object); err != nil {
+		t.Fatalf("Failed to parse the Deployment: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := mountTrustedCABundle(deployment, "bundle"); err != nil {
+			t.Fatalf("Failed to mount the trusted CA bundle: %v", err)
+		}
+	}
+	volumes, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "volumes")
+	if len(volumes) != 1 {
+		t.Errorf("Expected a single volume; got %v", volumes)
+	}
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	for i, c := range containers {
+		// the container mounting its own bundle keeps it
+		mounts, _, _ := unstructured.NestedSlice(c.(map[string]interface{}), "volumeMounts")
+		if len(mounts) != 1 {
+			t.Errorf("Expected a single volume mount in container %v; got %v", i, mounts)
+		}
+	}
+	// only the bundle file is mounted, the other files of the directory are kept
+	mount := containers[0].(map[string]interface{})["volumeMounts"].([]interface{})[0].(map[string]interface{})
+	if mount["mountPath"] != "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem" || mount["subPath"] != "tls-ca-bundle.pem" {
+		t.Errorf("Expected the bundle to be mounted as a single file; got %v", mount)
+	}
+	hash, _, _ := unstructured.NestedString(deployment.Object, "spec", "template", "metadata", "annotations",
+		"kfctl.kubeflow.io/trusted-ca-bundle-hash")
+	if hash == "" {
+		t.Errorf("Expected the pod template to be annotated with the hash of the bundle")
+	}
+}
+
+func TestOverrideWorkload(t *testing.T) {
+	deployment := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  replicas: 2
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: quay.io/opendatahub/odh-dashboard
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+        resources:
+          requests:
+            cpu: 500m
+            memory: 1Gi
+          limits:
+            cpu: "1"
+            memory: 2Gi
+      - name: oauth-proxy
+        image: quay.io/openshift/oauth-proxy
+`), &deployment.Object); err != nil {
+		t.Fatalf("Failed to parse the Deployment: %v", err)
+	}
+
+	replicas := int32(1)
+	override := kfconfig.WorkloadOverride{
+		Name:     "odh-dashboard",
+		Replicas: &replicas,
+		Containers: []kfconfig.ContainerOverride{
+			{
+				Name: "dashboard",
+				Resources: v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+				},
+			},
+			{
+				Name: "init",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+				},
+			},
+			{
+				Name: "unknown",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+				},
+			},
+		},
+	}
+	if err := overrideWorkload(deployment, override); err != nil {
+		t.Fatalf("Failed to override the Deployment: %v", err)
+	}
+
+	type field struct {
+		path     []string
+		expected string
+	}
+	fields := []field{
+		{[]string{"spec", "template", "spec", "containers", "dashboard", "resources", "limits", "memory"}, "4Gi"},
+		{[]string{"spec", "template", "spec", "containers", "dashboard", "resources", "limits", "cpu"}, "1"},
+		{[]string{"spec", "template", "spec", "containers", "dashboard", "resources", "requests", "memory"}, "1Gi"},
+		{[]string{"spec", "template", "spec", "initContainers", "init", "resources", "requests", "cpu"}, "100m"},
+		{[]string{"spec", "template", "spec", "containers", "oauth-proxy", "image"}, "quay.io/openshift/oauth-proxy"},
+	}
+	for _, f := range fields {
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, f.path[:4]...)
+		value := ""
+		for _, c := range containers {
+			if c := c.(map[string]interface{}); c["name"] == f.path[4] {
+				value = fmt.Sprint(getNested(c, f.path[5:]...))
+			}
+		}
+		if value != f.expected {
+			t.Errorf("Expected %v to be %v, got %v", strings.Join(f.path, "."), f.expected, value)
+		}
+	}
+	for _, list := range []string{"containers", "initContainers"} {
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", list)
+		if len(containers) != map[string]int{"containers": 2, "initContainers": 1}[list] {
+			t.Errorf("Expected the %v to be kept, got %v", list, containers)
+		}
+	}
+	if replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); replicas != 1 {
+		t.Errorf("Expected 1 replica, got %v", replicas)
+	}
+
+	service := &unstructured.Unstructured{}
+	service.SetKind("Service")
+	if err := overrideWorkload(service, override); err == nil {
+		t.Errorf("Expected the override of a Service to be rejected")
+	}
+}
+
+// getNested returns the value of the field of obj at fields
+func getNested(obj map[string]interface{}, fields ...string) interface{} {
+	value, _, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	return value
+}
+
+func TestSchedulePodSpec(t *testing.T) {
+	cronJob := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: odh-cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          nodeSelector:
+            kubernetes.io/os: linux
+            node-role.kubernetes.io/infra: ""
+          tolerations:
+          - key: node-role.kubernetes.io/infra
+            operator: Exists
+            effect: NoSchedule
+          affinity:
+            podAntiAffinity:
+              preferredDuringSchedulingIgnoredDuringExecution:
+              - weight: 100
+                podAffinityTerm:
+                  topologyKey: kubernetes.io/hostname
+          containers:
+          - name: cleanup
+            image: quay.io/opendatahub/odh-cleanup
+`), &cronJob.Object); err != nil {
+		t.Fatalf("Failed to parse the CronJob: %v", err)
+	}
+
+	spec := &kfconfig.Scheduling{
+		NodeSelector: map[string]string{"node-role.kubernetes.io/infra": "true"},
+		Tolerations: []v1.Toleration{
+			{Key: "node-role.kubernetes.io/infra", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+			{Key: "nvidia.com/gpu", Operator: v1.TolerationOpExists},
+		},
+	}
+	app := &kfconfig.Scheduling{
+		Affinity: &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{Key: "nvidia.com/gpu.present", Operator: v1.NodeSelectorOpIn, Values: []string{"true"}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if err := schedulePodSpec(cronJob, podSpecPaths["CronJob"], mergeScheduling(spec, app)); err != nil {
+		t.Fatalf("Failed to inject the scheduling into the CronJob: %v", err)
+	}
+
+	podSpec := getNested(cronJob.Object, podSpecPaths["CronJob"]...).(map[string]interface{})
+	nodeSelector := map[string]interface{}{"kubernetes.io/os": "linux", "node-role.kubernetes.io/infra": "true"}
+	if !cmp.Equal(podSpec["nodeSelector"], nodeSelector) {
+		t.Errorf("Expected the node selector to be %v, got %v", nodeSelector, podSpec["nodeSelector"])
+	}
+	if tolerations := podSpec["tolerations"].([]interface{}); len(tolerations) != 2 {
+		t.Errorf("Expected the toleration of the gpu nodes to be added once, got %v", tolerations)
+	}
+	if getNested(podSpec, "affinity", "podAntiAffinity") == nil {
+		t.Errorf("Expected the pod anti-affinity to be kept, got %v", podSpec["affinity"])
+	}
+	terms, _, _ := unstructured.NestedSlice(podSpec, "affinity", "nodeAffinity",
+		"requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+	if len(terms) != 1 {
+		t.Errorf("Expected the node affinity to be set, got %v", podSpec["affinity"])
+	}
+}
+
+func TestInjectProxyEnv(t *testing.T) {
+	env := map[string]string{"HTTP_PROXY": "http://proxy:3128", "HTTPS_PROXY": "http://proxy:3128", "NO_PROXY": ".svc,.cluster.local"}
+	type testCase struct {
+		Name string
+		Env  map[string]string
+		// Container is the env of the container, Expected the env once injected
+		Container string
+		Expected  []interface{}
+	}
+	proxyEnv := func(noProxy string) []interface{} {
+		return []interface{}{
+			map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+			map[string]interface{}{"name": "HTTPS_PROXY", "value": "http://proxy:3128"},
+			map[string]interface{}{"name": "NO_PROXY", "value": noProxy},
+		}
+	}
+	testCases := []testCase{
+		{Name: "no env", Env: env, Expected: proxyEnv(".svc,.cluster.local")},
+		{Name: "no proxy", Env: map[string]string{}, Container: `
+        env:
+        - name: PORT
+          value: "8080"`, Expected: []interface{}{map[string]interface{}{"name": "PORT", "value": "8080"}}},
+		{Name: "other env", Env: env, Container: `
+        env:
+        - name: PORT
+          value: "8080"`, Expected: append([]interface{}{map[string]interface{}{"name": "PORT", "value": "8080"}},
+			proxyEnv(".svc,.cluster.local")...)},
+		{Name: "proxy of the container", Env: env, Container: `
+        env:
+        - name: HTTPS_PROXY
+          value: http://other-proxy:3128`, Expected: []interface{}{
+			map[string]interface{}{"name": "HTTPS_PROXY", "value": "http://other-proxy:3128"},
+			map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+			map[string]interface{}{"name": "NO_PROXY", "value": ".svc,.cluster.local"},
+		}},
+		{Name: "NO_PROXY merged", Env: env, Container: `
+        env:
+        - name: NO_PROXY
+          value: "minio, .svc"`, Expected: []interface{}{
+			map[string]interface{}{"name": "NO_PROXY", "value": "minio,.svc,.cluster.local"},
+			map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+			map[string]interface{}{"name": "HTTPS_PROXY", "value": "http://proxy:3128"},
+		}},
+		{Name: "NO_PROXY from a ConfigMap", Env: env, Container: `
+        env:
+        - name: NO_PROXY
+          valueFrom:
+            configMapKeyRef:
+              name: proxy
+              key: noProxy`, Expected: []interface{}{
+			map[string]interface{}{"name": "NO_PROXY", "valueFrom": map[string]interface{}{
+				"configMapKeyRef": map[string]interface{}{"name": "proxy", "key": "noProxy"}}},
+			map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+			map[string]interface{}{"name": "HTTPS_PROXY", "value": "http://proxy:3128"},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard`+tc.Container+`
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: odh-migration
+spec:
+  template:
+    spec:
+      containers:
+      - name: migration
+        image: quay.io/opendatahub/odh-migration
+`)
+			injectProxyEnv(resMap, tc.Env)
+			for _, res := range resMap.Resources() {
+				containers, _, _ := unstructured.NestedSlice(res.Map(), "spec", "template", "spec", "containers")
+				containerEnv, _ := containers[0].(map[string]interface{})["env"].([]interface{})
+				if res.GetKind() == "Job" {
+					if len(containerEnv) != 0 {
+						t.Errorf("Expected the Job to be left as it is, got %v", containerEnv)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(containerEnv, tc.Expected) {
+					t.Errorf("Expected env %v, got %v", tc.Expected, containerEnv)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadProxyEnv(t *testing.T) {
+	for _, v := range ProxyEnvVars {
+		defer os.Setenv(v.Name, os.Getenv(v.Name))
+		os.Unsetenv(v.Name)
+	}
+	os.Setenv("HTTPS_PROXY", "http://proxy:3128")
+	os.Setenv("NO_PROXY", ".svc")
+	// the environment of the operator takes precedence, the cluster is not read
+	k := &kustomize{}
+	env, err := k.loadProxyEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"HTTPS_PROXY": "http://proxy:3128", "NO_PROXY": ".svc"}; !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected the proxy environment variables %v, got %v", expected, env)
+	}
+}
+
+func TestClusterProxyEnv(t *testing.T) {
+	env, err := clusterProxyEnv(fake.NewFakeClientWithScheme(kubescheme.Scheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 0 {
+		t.Errorf("Expected no proxy without the cluster-wide Proxy, got %v", env)
+	}
+
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(ClusterProxyGVK)
+	proxy.SetName("cluster")
+	if err := unstructured.SetNestedMap(proxy.Object, map[string]interface{}{
+		"httpProxy": "http://proxy:3128",
+		"noProxy":   ".cluster.local,.svc,10.0.0.0/16",
+	}, "status"); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(kubescheme.Scheme)
+	if err := c.Create(context.TODO(), proxy); err != nil {
+		t.Fatal(err)
+	}
+	env, err = clusterProxyEnv(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"HTTP_PROXY": "http://proxy:3128", "NO_PROXY": ".cluster.local,.svc,10.0.0.0/16"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected the proxy environment variables %v, got %v", expected, env)
+	}
+}
+
+func TestInjectSidecars(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: odh-migration
+spec:
+  template:
+    metadata:
+      annotations:
+        sidecar.istio.io/inject: "false"
+    spec:
+      containers:
+      - name: migration
+        image: quay.io/opendatahub/odh-migration
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+spec:
+  ports:
+  - port: 8443
+`)
+	if err := injectSidecars(resMap); err != nil {
+		t.Fatalf("Failed to inject the sidecars: %v", err)
+	}
+
+	expected := map[string]interface{}{"Deployment": "true", "Job": "false", "Service": nil}
+	for _, res := range resMap.Resources() {
+		value := getNested(res.Map(), "spec", "template", "metadata", "annotations", SidecarInjectAnnotation)
+		if value != expected[res.GetKind()] {
+			t.Errorf("Expected the sidecar annotation of the %v to be %v, got %v", res.GetKind(), expected[res.GetKind()], value)
+		}
+	}
+}
+
+func TestInjectAuth(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    metadata:
+      labels:
+        app: odh-dashboard
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+        ports:
+        - name: http
+          containerPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+spec:
+  selector:
+    app: odh-dashboard
+  ports:
+  - name: http
+    port: 80
+    targetPort: http
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: odh-dashboard
+spec:
+  to:
+    kind: Service
+    name: odh-dashboard
+  port:
+    targetPort: http
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: odh-dashboard-public
+  annotations:
+    kfctl.kubeflow.io/auth: "false"
+spec:
+  to:
+    kind: Service
+    name: odh-dashboard
+`
+	resMap := mustResMap(t, manifests)
+	if err := injectAuth(resMap, "opendatahub", kfconfig.OAuthProxy, ""); err != nil {
+		t.Fatalf("Failed to inject the oauth-proxy: %v", err)
+	}
+	kinds := map[string]map[string]interface{}{}
+	for _, res := range resMap.Resources() {
+		kinds[res.GetKind()+"/"+res.GetName()] = res.Map()
+	}
+	containers := getNested(kinds["Deployment/odh-dashboard"], "spec", "template", "spec", "containers").([]interface{})
+	if len(containers) != 2 || containers[1].(map[string]interface{})["name"] != OAuthProxyContainer {
+		t.Fatalf("Expected the oauth-proxy sidecar to be added, got %v", containers)
+	}
+	args := containers[1].(map[string]interface{})["args"].([]interface{})
+	if args[4] != "--upstream=http://localhost:8080" {
+		t.Errorf("Expected the oauth-proxy to send the requests to the dashboard port, got %v", args[4])
+	}
+	if sa := getNested(kinds["Deployment/odh-dashboard"], "spec", "template", "spec", "serviceAccountName"); sa != "odh-dashboard-oauth-proxy" {
+		t.Errorf("Expected the Deployment to run as the generated ServiceAccount, got %v", sa)
+	}
+	if port := getNested(kinds["Route/odh-dashboard"], "spec", "port", "targetPort"); port != OAuthProxyContainer {
+		t.Errorf("Expected the Route to send the requests to the oauth-proxy, got %v", port)
+	}
+	if port := getNested(kinds["Route/odh-dashboard-public"], "spec", "port"); port != nil {
+		t.Errorf("Expected the Route opting out to be left as it is, got port %v", port)
+	}
+	if secret := getNested(kinds["Service/odh-dashboard"], "metadata", "annotations", "kfctl.kubeflow.io/serving-cert-secret"); secret != "odh-dashboard-oauth-proxy-tls" {
+		t.Errorf("Expected the Service to request the serving certificate of the oauth-proxy, got %v", secret)
+	}
+	for _, id := range []string{"Secret/odh-dashboard-oauth-config", "ServiceAccount/odh-dashboard-oauth-proxy",
+		"ClusterRoleBinding/opendatahub-odh-dashboard-oauth-proxy-auth-delegator"} {
+		if _, ok := kinds[id]; !ok {
+			t.Errorf("Expected %v to be generated", id)
+		}
+	}
+	if err := injectAuth(resMap, "opendatahub", kfconfig.OAuthProxy, ""); err != nil {
+		t.Fatalf("Failed to inject the oauth-proxy again: %v", err)
+	}
+	containers = getNested(resMap.Resources()[0].Map(), "spec", "template", "spec", "containers").([]interface{})
+	if len(containers) != 2 {
+		t.Errorf("Expected the Deployment running an oauth-proxy to be left as it is, got %v", containers)
+	}
+
+	resMap = mustResMap(t, manifests)
+	if err := injectAuth(resMap, "opendatahub", kfconfig.Authorino, ""); err == nil {
+		t.Errorf("Expected Authorino to require the Service Mesh")
+	}
+	if err := injectAuth(resMap, "opendatahub", kfconfig.Authorino, kfconfig.Managed); err != nil {
+		t.Fatalf("Failed to authorize with Authorino: %v", err)
+	}
+	generated := []string{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "AuthConfig" || res.GetKind() == "AuthorizationPolicy" {
+			generated = append(generated, res.GetKind()+"/"+res.GetName())
+		}
+	}
+	if !reflect.DeepEqual(generated, []string{"AuthConfig/odh-dashboard-auth", "AuthorizationPolicy/odh-dashboard-auth"}) {
+		t.Errorf("Expected an AuthConfig and an AuthorizationPolicy to be generated, got %v", generated)
+	}
+}
+
+func TestRoutesToIngresses(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+  annotations:
+    kfctl.kubeflow.io/expose: https
+spec:
+  ports:
+  - name: https
+    port: 443
+    targetPort: 8443
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: jupyterhub
+spec:
+  ports:
+  - port: 8080
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: jupyterhub
+spec:
+  host: jupyterhub.example.com
+  to:
+    kind: Service
+    name: jupyterhub
+  port:
+    targetPort: 8080
+`)
+	IngressClass = "nginx"
+	IngressDomain = "apps.example.com"
+	defer func() {
+		IngressClass = ""
+		IngressDomain = ""
+	}()
+	if err := exposeServices(resMap); err != nil {
+		t.Fatalf("Failed to expose the Services: %v", err)
+	}
+	if err := routesToIngresses(resMap, "opendatahub"); err != nil {
+		t.Fatalf("Failed to convert the Routes: %v", err)
+	}
+
+	ingresses := map[string]map[string]interface{}{}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "Route" {
+			t.Errorf("Expected Route %v to be converted", res.GetName())
+		}
+		if res.GetKind() == "Ingress" {
+			ingresses[res.GetName()] = res.Map()
+		}
+	}
+	expected := map[string]struct {
+		host string
+		port map[string]interface{}
+		tls  bool
+	}{
+		"odh-dashboard": {"odh-dashboard-opendatahub.apps.example.com", map[string]interface{}{"name": "https"}, true},
+		"jupyterhub":    {"jupyterhub.example.com", map[string]interface{}{"number": int64(8080)}, false},
+	}
+	for name, e := range expected {
+		ingress, ok := ingresses[name]
+		if !ok {
+			t.Errorf("Expected an Ingress %v", name)
+			continue
+		}
+		rule := getNested(ingress, "spec", "rules").([]interface{})[0].(map[string]interface{})
+		if rule["host"] != e.host {
+			t.Errorf("Expected the host of Ingress %v to be %v, got %v", name, e.host, rule["host"])
+		}
+		path := getNested(rule, "http", "paths").([]interface{})[0].(map[string]interface{})
+		if port := getNested(path, "backend", "service", "port"); !reflect.DeepEqual(port, e.port) {
+			t.Errorf("Expected the backend port of Ingress %v to be %v, got %v", name, e.port, port)
+		}
+		if class := getNested(ingress, "spec", "ingressClassName"); class != "nginx" {
+			t.Errorf("Expected the class of Ingress %v to be nginx, got %v", name, class)
+		}
+		if _, tls := ingress["spec"].(map[string]interface{})["tls"]; tls != e.tls {
+			t.Errorf("Expected the TLS of Ingress %v to be %v", name, e.tls)
+		}
+	}
+}
+
+func TestInjectAvailability(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: odh-dashboard
+  template:
+    metadata:
+      labels:
+        app: odh-dashboard
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: modelmesh-controller
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      control-plane: modelmesh-controller
+  template:
+    metadata:
+      labels:
+        control-plane: modelmesh-controller
+    spec:
+      topologySpreadConstraints:
+      - maxSkew: 1
+        topologyKey: topology.kubernetes.io/zone
+        whenUnsatisfiable: DoNotSchedule
+        labelSelector:
+          matchLabels:
+            control-plane: modelmesh-controller
+      containers:
+      - name: manager
+        image: quay.io/opendatahub/modelmesh-controller
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: modelmesh-controller-pdb
+spec:
+  minAvailable: 2
+  selector:
+    matchLabels:
+      control-plane: modelmesh-controller
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-migration
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: odh-migration
+  template:
+    metadata:
+      labels:
+        app: odh-migration
+    spec:
+      containers:
+      - name: migration
+        image: quay.io/opendatahub/odh-migration
+`)
+	maxUnavailable := intstr.FromString("50%")
+	availability := &kfconfig.Availability{MaxUnavailable: &maxUnavailable}
+	if err := injectAvailability(resMap, availability); err != nil {
+		t.Fatalf("Failed to inject the availability: %v", err)
+	}
+
+	budgets := map[string]interface{}{}
+	spread := map[string]interface{}{}
+	for _, res := range resMap.Resources() {
+		switch res.GetKind() {
+		case "PodDisruptionBudget":
+			budgets[res.GetName()] = getNested(res.Map(), "spec", "maxUnavailable")
+		case "Deployment":
+			constraints, _, _ := unstructured.NestedSlice(res.Map(), "spec", "template", "spec", "topologySpreadConstraints")
+			if len(constraints) > 0 {
+				spread[res.GetName()] = constraints[0].(map[string]interface{})["topologyKey"]
+			}
+		}
+	}
+	expectedBudgets := map[string]interface{}{"odh-dashboard": "50%", "modelmesh-controller-pdb": nil}
+	if !reflect.DeepEqual(budgets, expectedBudgets) {
+		t.Errorf("Expected the PodDisruptionBudgets %v, got %v", expectedBudgets, budgets)
+	}
+	expectedSpread := map[string]interface{}{"odh-dashboard": defaultTopologyKey, "modelmesh-controller": "topology.kubernetes.io/zone"}
+	if !reflect.DeepEqual(spread, expectedSpread) {
+		t.Errorf("Expected the topology keys %v, got %v", expectedSpread, spread)
+	}
+
+	app := &kfconfig.Availability{TopologyKey: "topology.kubernetes.io/zone"}
+	if merged := mergeAvailability(availability, app); merged != app {
+		t.Errorf("Expected the availability of the application to replace the one of the spec, got %+v", merged)
+	}
+	if merged := mergeAvailability(availability, nil); merged != availability {
+		t.Errorf("Expected the availability of the spec, got %+v", merged)
+	}
+}
+
+func TestInjectPodSecurity(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: odh-notebooks
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: opendatahub
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        image: quay.io/opendatahub/odh-dashboard
+        securityContext:
+          capabilities:
+            add:
+            - NET_BIND_SERVICE
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: node-agent
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 0
+      containers:
+      - name: agent
+        image: quay.io/opendatahub/node-agent
+        securityContext:
+          privileged: true
+`)
+	if err := injectPodSecurity(resMap, kfconfig.PodSecurityRestricted, kfconfig.PodSecurityBaseline, "opendatahub"); err != nil {
+		t.Fatalf("Failed to inject the pod security: %v", err)
+	}
+
+	for _, res := range resMap.Resources() {
+		switch res.GetName() {
+		case "odh-notebooks":
+			expected := map[string]string{PodSecurityEnforceLabel: "baseline", PodSecurityLabelSyncLabel: "false"}
+			if !reflect.DeepEqual(res.GetLabels(), expected) {
+				t.Errorf("Expected the namespace to be labelled with %v, got %v", expected, res.GetLabels())
+			}
+		case "opendatahub":
+			if len(res.GetLabels()) > 0 {
+				t.Errorf("Expected the applications namespace to be left alone, got %v", res.GetLabels())
+			}
+		case "odh-dashboard":
+			podContext := getNested(res.Map(), "spec", "template", "spec", "securityContext")
+			expected := map[string]interface{}{"runAsNonRoot": true, "seccompProfile": map[string]interface{}{"type": "RuntimeDefault"}}
+			if !reflect.DeepEqual(podContext, expected) {
+				t.Errorf("Expected the pod security context %v, got %v", expected, podContext)
+			}
+			containers, _, _ := unstructured.NestedSlice(res.Map(), "spec", "template", "spec", "containers")
+			context := containers[0].(map[string]interface{})["securityContext"]
+			expected = map[string]interface{}{
+				"allowPrivilegeEscalation": false,
+				"capabilities":             map[string]interface{}{"add": []interface{}{"NET_BIND_SERVICE"}, "drop": []interface{}{"ALL"}},
+			}
+			if !reflect.DeepEqual(context, expected) {
+				t.Errorf("Expected the container security context %v, got %v", expected, context)
+			}
+		case "node-agent":
+			// the privileged workloads cannot satisfy the restricted level, they are not made to run as non-root
+			if runAsNonRoot := getNested(res.Map(), "spec", "template", "spec", "securityContext", "runAsNonRoot"); runAsNonRoot != nil {
+				t.Errorf("Expected the workload running as root to be left running as root, got runAsNonRoot %v", runAsNonRoot)
+			}
+		}
+	}
+
+	if level := LeastRestrictivePodSecurity("", kfconfig.PodSecurityRestricted, kfconfig.PodSecurityBaseline); level != kfconfig.PodSecurityBaseline {
+		t.Errorf("Expected the least restrictive level to be baseline, got %v", level)
+	}
+	if level := LeastRestrictivePodSecurity("", ""); level != "" {
+		t.Errorf("Expected no level when the applications have none, got %v", level)
+	}
+	if violations := hardenPodSpec(map[string]interface{}{"containers": []interface{}{
+		map[string]interface{}{"name": "agent", "securityContext": map[string]interface{}{"privileged": true, "runAsUser": int64(0)}},
+	}}); len(violations) != 2 {
+		t.Errorf("Expected the privileged container running as root to be reported twice, got %v", violations)
+	}
+}
+
+func TestGrantSecurityContextConstraints(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: odh-dashboard
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: mlflow
+  namespace: odh-mlflow
+  annotations:
+    kfctl.kubeflow.io/scc: anyuid
+`)
+	grants, err := grantSecurityContextConstraints(resMap, "mlflow", "opendatahub")
+	if err != nil {
+		t.Fatalf("Failed to grant the SecurityContextConstraints: %v", err)
+	}
+	expected := []kfconfig.SCCGrant{{Application: "mlflow", ServiceAccount: "odh-mlflow/mlflow", Profile: "anyuid", Name: "odh-odh-mlflow-mlflow"}}
+	if !reflect.DeepEqual(grants, expected) {
+		t.Errorf("Expected the grants %+v, got %+v", expected, grants)
+	}
+	sccs := 0
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != SecurityContextConstraintsGVK.Kind {
+			continue
+		}
+		sccs++
+		if users := getNested(res.Map(), "users"); !reflect.DeepEqual(users, []interface{}{"system:serviceaccount:odh-mlflow:mlflow"}) {
+			t.Errorf("Expected the SecurityContextConstraints to be granted to the ServiceAccount only, got %v", users)
+		}
+		if runAsUser := getNested(res.Map(), "runAsUser", "type"); runAsUser != "RunAsAny" {
+			t.Errorf("Expected the anyuid SecurityContextConstraints to run as any user, got %v", runAsUser)
+		}
+		if privileged := getNested(res.Map(), "allowPrivilegedContainer"); privileged != false {
+			t.Errorf("Expected the SecurityContextConstraints not to allow privileged containers, got %v", privileged)
+		}
+	}
+	if sccs != 1 {
+		t.Errorf("Expected one SecurityContextConstraints, got %v", sccs)
+	}
+
+	resMap = mustResMap(t, `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: node-agent
+  annotations:
+    kfctl.kubeflow.io/scc: privileged
+`)
+	if _, err := grantSecurityContextConstraints(resMap, "node-agent", "opendatahub"); err == nil {
+		t.Errorf("Expected the unknown privileged profile to be refused")
+	}
+}
+
+func TestInjectBackupHooks(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mariadb
+  annotations:
+    kfctl.kubeflow.io/backup: mariadb
+spec:
+  template:
+    spec:
+      containers:
+      - name: mariadb
+        image: registry.redhat.io/rhel8/mariadb-103
+        volumeMounts:
+        - name: config
+          mountPath: /etc/my.cnf.d
+        - name: data
+          mountPath: /var/lib/mysql/
+      volumes:
+      - name: config
+        configMap:
+          name: mariadb-config
+      - name: data
+        persistentVolumeClaim:
+          claimName: mariadb-pvc
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: mariadb-pvc
+spec:
+  accessModes: [ReadWriteOnce]
+  resources:
+    requests:
+      storage: 10Gi
+`)
+	if err := injectBackupHooks(resMap, 1); err != nil {
+		t.Fatalf("Failed to inject the backup hooks: %v", err)
+	}
+	for _, res := range resMap.Resources() {
+		if order := res.GetLabels()[RestoreOrderLabel]; order != "1" {
+			t.Errorf("Expected %v %v to be restored in order 1, got %v", res.GetKind(), res.GetName(), order)
+		}
+		if res.GetKind() != "Deployment" {
+			continue
+		}
+		annotations, _, _ := unstructured.NestedStringMap(res.Map(), "spec", "template", "metadata", "annotations")
+		expected := map[string]string{
+			"pre.hook.backup.velero.io/container": "mariadb",
+			"pre.hook.backup.velero.io/command": `["/bin/bash","-c","mysqldump --single-transaction --routines ` +
+				`-u\"$MYSQL_USER\" -p\"$MYSQL_PASSWORD\" \"$MYSQL_DATABASE\" > /var/lib/mysql/velero-backup.sql"]`,
+			"pre.hook.backup.velero.io/on-error": "Fail",
+			"pre.hook.backup.velero.io/timeout":  "10m",
+			"backup.velero.io/backup-volumes":    "data",
+		}
+		if !reflect.DeepEqual(annotations, expected) {
+			t.Errorf("Expected the annotations %v, got %v", expected, annotations)
+		}
+	}
+
+	unsupported := mustResMap(t, `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: mongodb
+  annotations:
+    kfctl.kubeflow.io/backup: mongodb
+spec:
+  template:
+    spec:
+      containers:
+      - name: mongodb
+`)
+	if err := injectBackupHooks(unsupported, 0); err == nil {
+		t.Errorf("Expected an error for an unsupported database engine")
+	}
+
+	apps := []kfconfig.Application{
+		{Name: "odh-common"},
+		{Name: "data-science-pipelines-operator", DependsOn: []string{"odh-common"}},
+		{Name: "odh-dashboard", DependsOn: []string{"odh-common", "data-science-pipelines-operator"}},
+	}
+	for name, expected := range map[string]int{"odh-common": 0, "data-science-pipelines-operator": 1, "odh-dashboard": 2} {
+		if order := restoreOrder(apps, name); order != expected {
+			t.Errorf("Expected %v to be restored in order %v, got %v", name, expected, order)
+		}
+	}
+}
+
+func TestInjectConfigChecksums(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+spec:
+  template:
+    spec:
+      containers:
+      - name: dashboard
+        envFrom:
+        - configMapRef:
+            name: dashboard-config
+      - name: oauth-proxy
+        env:
+        - name: COOKIE_SECRET
+          valueFrom:
+            secretKeyRef:
+              name: dashboard-oauth-config
+              key: cookie_secret
+      volumes:
+      - name: ca
+        configMap:
+          name: odh-trusted-ca-bundle
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unrelated
+spec:
+  template:
+    spec:
+      containers:
+      - name: unrelated
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dashboard-config
+data:
+  LOG_LEVEL: %v
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: dashboard-oauth-config
+stringData:
+  cookie_secret: secret
+`
+	checksum := func(logLevel string) string {
+		resMap := mustResMap(t, fmt.Sprintf(manifests, logLevel))
+		if err := injectConfigChecksums(resMap); err != nil {
+			t.Fatalf("Failed to inject the checksums: %v", err)
+		}
+		checksums := map[string]string{}
+		for _, res := range resMap.Resources() {
+			annotations, _, _ := unstructured.NestedStringMap(res.Map(), "spec", "template", "metadata", "annotations")
+			checksums[res.GetName()] = annotations[ConfigChecksumAnnotation]
+		}
+		if checksums["unrelated"] != "" {
+			t.Errorf("Expected no checksum for a workload without config, got %v", checksums["unrelated"])
+		}
+		if checksums["odh-dashboard"] == "" {
+			t.Fatalf("Expected the checksum of the config of the dashboard")
+		}
+		return checksums["odh-dashboard"]
+	}
+	info := checksum("info")
+	if again := checksum("info"); again != info {
+		t.Errorf("Expected a stable checksum, got %v and %v", info, again)
+	}
+	if debug := checksum("debug"); debug == info {
+		t.Errorf("Expected the checksum to change along with the ConfigMap")
+	}
+}
+
+func TestInjectArgoCDCompatibility(t *testing.T) {
+	defer func() { ArgoCDMode, ArgoCDTrackingLabel = "", "" }()
+	ArgoCDTrackingLabel = "app.kubernetes.io/instance"
+	manifests := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: tracked
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: untracked
+  labels:
+    app: odh-dashboard
+    app.kubernetes.io/instance: odh-dashboard
+  annotations:
+    argocd.argoproj.io/tracking-id: odh:/ConfigMap:opendatahub/untracked
+`
+	tracked := &unstructured.Unstructured{}
+	tracked.SetAPIVersion("v1")
+	tracked.SetKind("ConfigMap")
+	tracked.SetNamespace("opendatahub")
+	tracked.SetName("tracked")
+	tracked.SetAnnotations(map[string]string{ArgoCDTrackingAnnotation: "odh:/ConfigMap:opendatahub/tracked"})
+	kubeclient := fake.NewFakeClient(tracked)
+
+	for _, mode := range []string{ArgoCDIgnore, ArgoCDAdopt} {
+		ArgoCDMode = mode
+		resMap := mustResMap(t, manifests)
+		if err := injectArgoCDCompatibility(resMap, "opendatahub", kubeclient); err != nil {
+			t.Fatalf("%v: failed to inject the Argo CD compatibility: %v", mode, err)
+		}
+		names := []string{}
+		for _, res := range resMap.Resources() {
+			names = append(names, res.GetName())
+			if _, found := res.GetAnnotations()[ArgoCDTrackingAnnotation]; found {
+				t.Errorf("%v: expected the tracking annotation of %v to be removed", mode, res.GetName())
+			}
+			if _, found := res.GetLabels()[ArgoCDTrackingLabel]; found {
+				t.Errorf("%v: expected the tracking label of %v to be removed", mode, res.GetName())
+			}
+			syncOptions, found := res.GetAnnotations()["argocd.argoproj.io/sync-options"]
+			if mode == ArgoCDAdopt && syncOptions != "Prune=false,Delete=false" || mode == ArgoCDIgnore && found {
+				t.Errorf("%v: unexpected sync options of %v: %q", mode, res.GetName(), syncOptions)
+			}
+		}
+		expected := []string{"tracked", "untracked"}
+		if mode == ArgoCDIgnore {
+			expected = []string{"untracked"}
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%v: expected the resources %v, got %v", mode, expected, names)
+		}
+	}
+
+	annotations := WithoutArgoCDMetadata(map[string]string{
+		ArgoCDTrackingAnnotation:           "odh:/ConfigMap:opendatahub/tracked",
+		"kfctl.kubeflow.io/kfdef-instance": "opendatahub.opendatahub",
+	})
+	if !reflect.DeepEqual(annotations, map[string]string{"kfctl.kubeflow.io/kfdef-instance": "opendatahub.opendatahub"}) {
+		t.Errorf("Expected the annotations of Argo CD to be filtered out, got %v", annotations)
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	appDir, err := ioutil.TempDir("", "render-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(appDir)
+	defer func(dir string) { RenderCacheDir = dir }(RenderCacheDir)
+	RenderCacheDir = path.Join(appDir, "render-cache")
+	repoDir := path.Join(appDir, ".cache", "manifests")
+	appKustomizeDir := path.Join(appDir, outputDir, "odh-dashboard")
+	for _, dir := range []string{path.Join(repoDir, "odh-dashboard"), appKustomizeDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := path.Join(repoDir, "odh-dashboard", "deployment.yaml")
+	if err := ioutil.WriteFile(manifest, []byte("kind: Deployment\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := kfconfig.Application{
+		Name: "odh-dashboard",
+		KustomizeConfig: &kfconfig.KustomizeConfig{
+			RepoRef:  &kfconfig.RepoRef{Name: "manifests", Path: "odh-dashboard"},
+			Overlays: []string{"authentication"},
+		},
+	}
+	newKustomize := func() *kustomize {
+		return &kustomize{kfDef: &kfconfig.KfConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "opendatahub"},
+			Spec:       kfconfig.KfConfigSpec{AppDir: appDir},
+			Status:     kfconfig.Status{Caches: []kfconfig.Cache{{Name: "manifests", LocalPath: repoDir}}},
+		}}
+	}
+
+	key, err := newKustomize().renderCacheKey(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := []byte("kind: Deployment\n")
+	newKustomize().storeRender(app.Name, key, rendered, map[string]string{"quay.io/opendatahub/odh-dashboard": "registry.example.com/odh-dashboard"}, nil, time.Now())
+
+	// the entries are read from the app dir when they are not in memory
+	renderCache.Lock()
+	renderCache.entries = map[string]renderCacheEntry{}
+	renderCache.Unlock()
+	entry, ok := newKustomize().cachedRender(app.Name, key)
+	if !ok {
+		t.Fatalf("Expected the unchanged application to be cached")
+	}
+	if entry.RelatedImages["quay.io/opendatahub/odh-dashboard"] != "registry.example.com/odh-dashboard" {
+		t.Errorf("Expected the related images to be cached; got %v", entry.RelatedImages)
+	}
+	if string(entry.Rendered) != string(rendered) {
+		t.Errorf("Expected the rendered manifests to be cached; got %q", entry.Rendered)
+	}
+
+	overlays := app
+	overlays.KustomizeConfig = &kfconfig.KustomizeConfig{RepoRef: app.KustomizeConfig.RepoRef}
+	if k, _ := newKustomize().renderCacheKey(overlays); k == key {
+		t.Errorf("Expected the key to change with the overlays")
+	}
+	if err := ioutil.WriteFile(manifest, []byte("kind: StatefulSet\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if k, _ := newKustomize().renderCacheKey(app); k == key {
+		t.Errorf("Expected the key to change with the manifests of the repo")
+	}
+
+	InvalidateRenderCache("opendatahub", "opendatahub")
+	if _, ok := newKustomize().cachedRender(app.Name, key); ok {
+		t.Errorf("Expected the invalidated application not to be cached")
+	}
+
+	ForgetRenders("opendatahub", "opendatahub")
+	if _, err := os.Stat(newKustomize().renderCachePath(app.Name)); !os.IsNotExist(err) {
+		t.Errorf("Expected the entries of the deleted KfDef to be removed, got %v", err)
+	}
+}
+
+func TestFindRenderedResource(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-dashboard-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  namespace: odh-apps
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: odh-dashboard
+`
+	if err := IndexRenderedManifests("opendatahub", "odh", "odh-dashboard", []byte(manifests)); err != nil {
+		t.Fatal(err)
+	}
+	defer ForgetRenderedManifests("opendatahub", "odh")
+	kfdef := k8stypes.NamespacedName{Namespace: "opendatahub", Name: "odh"}
+
+	type testCase struct {
+		Name  string
+		GVK   schema.GroupVersionKind
+		Key   k8stypes.NamespacedName
+		Found bool
+	}
+	testCases := []testCase{
+		{
+			Name:  "namespace of the KfDef",
+			GVK:   schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Key:   k8stypes.NamespacedName{Namespace: "opendatahub", Name: "odh-dashboard-config"},
+			Found: true,
+		},
+		{
+			Name: "other namespace",
+			GVK:  schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Key:  k8stypes.NamespacedName{Namespace: "odh-apps", Name: "odh-dashboard-config"},
+		},
+		{
+			Name:  "explicit namespace",
+			GVK:   schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Key:   k8stypes.NamespacedName{Namespace: "odh-apps", Name: "odh-dashboard"},
+			Found: true,
+		},
+		{
+			Name:  "cluster-scoped",
+			GVK:   schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+			Key:   k8stypes.NamespacedName{Name: "odh-dashboard"},
+			Found: true,
+		},
+		{
+			Name: "other kind",
+			GVK:  schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+			Key:  k8stypes.NamespacedName{Namespace: "opendatahub", Name: "odh-dashboard-config"},
+		},
+	}
+	for _, c := range testCases {
+		u, key, app := FindRenderedResource(c.GVK, c.Key)
+		if (u != nil) != c.Found {
+			t.Errorf("%v: FindRenderedResource = %v, expected found = %v", c.Name, u, c.Found)
+			continue
+		}
+		if u == nil {
+			continue
+		}
+		if key != kfdef || app != "odh-dashboard" || u.GetNamespace() != c.Key.Namespace {
+			t.Errorf("%v: FindRenderedResource = %v/%v in %v of %v, expected namespace %v in odh-dashboard of %v",
+				c.Name, u.GetNamespace(), u.GetName(), app, key, c.Key.Namespace, kfdef)
+		}
+	}
+
+	ForgetRenderedManifests("opendatahub", "odh")
+	if u, _, _ := FindRenderedResource(testCases[0].GVK, testCases[0].Key); u != nil {
+		t.Errorf("Expected the resources of the forgotten KfDef not to be found")
+	}
+}
+
+func TestApplyInOrder(t *testing.T) {
+	apps := []kfconfig.Application{
+		{Name: "kserve", DependsOn: []string{"istio", "knative"}},
+		{Name: "istio"},
+		{Name: "knative", DependsOn: []string{"istio"}},
+		{Name: "odh-dashboard"},
+		{Name: "odh-notebooks"},
+	}
+
+	for _, workers := range []int{1, 3} {
+		var mu sync.Mutex
+		applied := []string{}
+		err := applyInOrder(apps, workers, func(app kfconfig.Application) error {
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, dependency := range app.DependsOn {
+				found := false
+				for _, name := range applied {
+					found = found || name == dependency
+				}
+				if !found {
+					t.Errorf("workers %v: %v applied before %v", workers, app.Name, dependency)
+				}
+			}
+			applied = append(applied, app.Name)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("workers %v: %v", workers, err)
+		}
+		if len(applied) != len(apps) {
+			t.Errorf("workers %v: expected every application to be applied; got %v", workers, applied)
+		}
+		if workers == 1 && strings.Join(applied, ",") != "istio,knative,kserve,odh-dashboard,odh-notebooks" {
+			t.Errorf("Expected the applications to be applied in the order of the spec; got %v", applied)
+		}
+	}
+
+	// the applications depending on an application that failed are not applied
+	var mu sync.Mutex
+	applied := map[string]bool{}
+	err := applyInOrder(apps, 1, func(app kfconfig.Application) error {
+		mu.Lock()
+		defer mu.Unlock()
+		applied[app.Name] = true
+		if app.Name == "istio" {
+			return fmt.Errorf("istio failed")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "istio failed" {
+		t.Errorf("Expected the error of istio; got %v", err)
+	}
+	if applied["knative"] || applied["kserve"] {
+		t.Errorf("Expected the applications depending on istio not to be applied; got %v", applied)
+	}
+
+	cycle := []kfconfig.Application{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}
+	if err := applyInOrder(cycle, 1, func(kfconfig.Application) error { return nil }); err == nil {
+		t.Errorf("Expected applications depending on each other to be rejected")
+	}
+	unknown := []kfconfig.Application{{Name: "a", DependsOn: []string{"c"}}}
+	if err := applyInOrder(unknown, 1, func(kfconfig.Application) error { return nil }); err == nil {
+		t.Errorf("Expected applications depending on unknown applications to be rejected")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+
+	items, err := inventoryItems(mapper, []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: odh-dashboard
+`), "opendatahub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []inventoryItem{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "opendatahub", Name: "odh-dashboard"},
+		{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "odh-dashboard"},
+	}
+	for i := range items {
+		if len(items[i].Digest) != 64 {
+			t.Errorf("Expected a sha256 digest of %v %v, got %q", items[i].Kind, items[i].Name, items[i].Digest)
+		}
+		items[i].Digest = ""
+	}
+	if !cmp.Equal(items, expected) {
+		t.Errorf("Unexpected inventory: %v", cmp.Diff(expected, items))
+	}
+
+	configMap := func(name string, kfdef string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("opendatahub")
+		u.SetName(name)
+		if kfdef != "" {
+			u.SetAnnotations(map[string]string{"kfctl.kubeflow.io/kfdef-instance": kfdef})
+		}
+		return u
+	}
+	item := func(name string) inventoryItem {
+		return inventoryItem{APIVersion: "v1", Kind: "ConfigMap", Namespace: "opendatahub", Name: name}
+	}
+	kubeclient := fake.NewFakeClient(configMap("kept", "opendatahub.opendatahub"), configMap("moved", "opendatahub.opendatahub"),
+		configMap("removed", "opendatahub.opendatahub"), configMap("removed-app", "opendatahub.opendatahub"),
+		configMap("taken-over", "other.opendatahub"))
+	k := &kustomize{kfDef: &kfconfig.KfConfig{ObjectMeta: metav1.ObjectMeta{Name: "opendatahub", Namespace: "opendatahub"}}}
+	previous := map[string][]inventoryItem{
+		"odh-dashboard": {item("kept"), item("moved"), item("removed"), item("taken-over")},
+		"odh-notebooks": {item("removed-app")},
+	}
+	current := map[string][]inventoryItem{
+		"odh-dashboard": {item("kept")},
+		"odh-common":    {item("moved")},
+	}
+	if err := k.prune(context.TODO(), kubeclient, previous, current); err != nil {
+		t.Fatal(err)
+	}
+	for name, exists := range map[string]bool{"kept": true, "moved": true, "removed": false, "removed-app": false, "taken-over": true} {
+		err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: "opendatahub", Name: name}, configMap(name, ""))
+		if exists && err != nil {
+			t.Errorf("Expected ConfigMap %v to be kept: %v", name, err)
+		} else if !exists && !k8serrors.IsNotFound(err) {
+			t.Errorf("Expected ConfigMap %v to be pruned; got %v", name, err)
+		}
+	}
+}
+
+func TestManifestChanges(t *testing.T) {
+	item := func(name string, digest string) inventoryItem {
+		return inventoryItem{APIVersion: "v1", Kind: "ConfigMap", Namespace: "opendatahub", Name: name, Digest: digest}
+	}
+	previous := map[string][]inventoryItem{
+		"odh-dashboard": {item("changed", "a"), item("kept", "b"), item("removed", "c"), item("recorded-without-digest", "")},
+		"odh-notebooks": {item("removed-app", "d")},
+		"odh-common":    {item("common", "e")},
+	}
+	current := map[string][]inventoryItem{
+		"odh-dashboard": {item("added", "f"), item("changed", "g"), item("kept", "b"), item("recorded-without-digest", "h")},
+		"odh-common":    {item("common", "e")},
+		"odh-pipelines": {{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "pipelines", Digest: "i"}},
+	}
+	expected := map[string]string{
+		"odh-dashboard": "+ ConfigMap opendatahub/added: added\n~ ConfigMap opendatahub/changed: changed\n- ConfigMap opendatahub/removed: removed\n",
+		"odh-notebooks": "- ConfigMap opendatahub/removed-app: removed\n",
+		"odh-pipelines": "+ ClusterRole pipelines: added\n",
+	}
+	changes := manifestChanges(previous, current)
+	if !cmp.Equal(changes, expected) {
+		t.Errorf("Unexpected changes: %v", cmp.Diff(expected, changes))
+	}
+
+	kubeclient := fake.NewFakeClient()
+	now := time.Date(2023, 11, 14, 12, 0, 0, 0, time.UTC)
+	if err := writeManifestChanges(kubeclient, "opendatahub", "opendatahub", changes, now); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing changing keeps the last change
+	if err := writeManifestChanges(kubeclient, "opendatahub", "opendatahub", manifestChanges(current, current), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	cm := &v1.ConfigMap{}
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: "opendatahub", Name: "opendatahub-changes"}, cm); err != nil {
+		t.Fatal(err)
+	}
+	expected[ChangedAtKey] = "2023-11-14T12:00:00Z"
+	if !cmp.Equal(cm.Data, expected) {
+		t.Errorf("Unexpected changes ConfigMap: %v", cmp.Diff(expected, cm.Data))
+	}
+}
+
+func TestDiffCRD(t *testing.T) {
+	current := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: notebooks.kubeflow.org
+spec:
+  versions:
+  - name: v1beta1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              image:
+                type: string
+              replicas:
+                type: integer
+status:
+  storedVersions:
+  - v1beta1
+`
+	type testCase struct {
+		Name        string
+		Desired     string
+		Changes     []string
+		Destructive []string
+	}
+	testCases := []testCase{
+		{
+			Name: "new version and field",
+			Desired: `
+spec:
+  versions:
+  - name: v1beta1
+    served: true
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              image:
+                type: string
+              replicas:
+                type: integer
+              resources:
+                type: object
+  - name: v1
+    served: true
+    storage: true
+`,
+			Changes:     []string{"serves version v1", "stores version v1 instead of v1beta1", "adds field spec.resources to version v1beta1"},
+			Destructive: []string{},
+		},
+		{
+			Name: "stored version dropped",
+			Desired: `
+spec:
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`,
+			Changes:     []string{"serves version v1", "stores version v1 instead of v1beta1"},
+			Destructive: []string{"no longer serves stored version v1beta1"},
+		},
+		{
+			Name: "fields removed and retyped",
+			Desired: `
+spec:
+  versions:
+  - name: v1beta1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              replicas:
+                type: string
+`,
+			Changes:     []string{},
+			Destructive: []string{"removes field spec.image of version v1beta1", "changes the type of field spec.replicas of version v1beta1"},
+		},
+		{
+			Name: "unknown fields preserved",
+			Desired: `
+spec:
+  versions:
+  - name: v1beta1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+`,
+			Changes:     []string{},
+			Destructive: []string{},
+		},
+	}
+	currentObj := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(current), &currentObj); err != nil {
+		t.Fatalf("Failed to parse the current CRD: %v", err)
+	}
+	for _, c := range testCases {
+		desired := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(c.Desired), &desired); err != nil {
+			t.Fatalf("%v: failed to parse the desired CRD: %v", c.Name, err)
+		}
+		changes, destructive := diffCRD(currentObj, desired)
+		if !reflect.DeepEqual(changes, c.Changes) {
+			t.Errorf("%v: expected changes %v, got %v", c.Name, c.Changes, changes)
+		}
+		if !reflect.DeepEqual(destructive, c.Destructive) {
+			t.Errorf("%v: expected destructive changes %v, got %v", c.Name, c.Destructive, destructive)
+		}
+	}
+}
+
+func TestWorkloadReadiness(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+  namespace: db
+---
+apiVersion: example.com/v1
+kind: Deployment
+metadata:
+  name: not-apps
+`
+	workloads, err := gatedWorkloads([]byte(manifests), "odh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, obj := range workloads {
+		names = append(names, obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+	}
+	if expected := []string{"Deployment odh/controller", "Job db/migration"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the workloads %v, got %v", expected, names)
+	}
+
+	gated := gatedApplications([]kfconfig.Application{
+		{Name: "istio"},
+		{Name: "kserve", DependsOn: []string{"istio"}},
+		{Name: "dashboard", DependsOn: []string{"istio", "kserve"}},
+	})
+	if expected := map[string]bool{"istio": true, "kserve": true}; !reflect.DeepEqual(gated, expected) {
+		t.Errorf("Expected the gated applications %v, got %v", expected, gated)
+	}
+
+	workload := func(kind string, generation int64, spec string, status string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		data := fmt.Sprintf("kind: %v\nmetadata:\n  name: w\n  generation: %v\nspec:\n%vstatus:\n%v", kind, generation, spec, status)
+		// Decoded as by the API client, with integer numbers
+		json, err := yaml.YAMLToJSON([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := obj.UnmarshalJSON(json); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	available := "  conditions:\n  - type: Available\n    status: \"True\"\n"
+	testCases := []struct {
+		name     string
+		workload *unstructured.Unstructured
+		ready    bool
+		failed   bool
+	}{
+		{
+			name:     "available deployment",
+			workload: workload("Deployment", 2, "  replicas: 2\n", "  observedGeneration: 2\n  updatedReplicas: 2\n"+available),
+			ready:    true,
+		},
+		{
+			name:     "default replicas",
+			workload: workload("Deployment", 1, "  paused: false\n", "  observedGeneration: 1\n  updatedReplicas: 1\n"+available),
+			ready:    true,
+		},
+		{
+			name:     "rollout not observed",
+			workload: workload("Deployment", 3, "  replicas: 2\n", "  observedGeneration: 2\n  updatedReplicas: 2\n"+available),
+		},
+		{
+			name:     "rollout in progress",
+			workload: workload("Deployment", 2, "  replicas: 2\n", "  observedGeneration: 2\n  updatedReplicas: 1\n"+available),
+		},
+		{
+			name:     "unavailable deployment",
+			workload: workload("Deployment", 1, "  replicas: 1\n", "  observedGeneration: 1\n  updatedReplicas: 1\n  conditions:\n  - type: Available\n    status: \"False\"\n"),
+		},
+		{
+			name:     "complete job",
+			workload: workload("Job", 1, "  parallelism: 1\n", "  conditions:\n  - type: Complete\n    status: \"True\"\n"),
+			ready:    true,
+		},
+		{
+			name:     "running job",
+			workload: workload("Job", 1, "  parallelism: 1\n", "  active: 1\n"),
+		},
+		{
+			name:     "failed job",
+			workload: workload("Job", 1, "  parallelism: 1\n", "  conditions:\n  - type: Failed\n    status: \"True\"\n"),
+			failed:   true,
+		},
+	}
+	for _, test := range testCases {
+		ready, err := workloadReady(test.workload)
+		if (err != nil) != test.failed {
+			t.Errorf("%v: expected failed %v, got %v", test.name, test.failed, err)
+		}
+		if ready != test.ready {
+			t.Errorf("%v: expected ready %v, got %v", test.name, test.ready, ready)
+		}
+	}
+}
+
+func TestWaitForWorkloadsCancelled(t *testing.T) {
+	defer func(interval time.Duration) { readinessPollInterval = interval }(readinessPollInterval)
+	readinessPollInterval = time.Millisecond
+	workloads, err := gatedWorkloads([]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: controller\n"), "odh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The Deployment is never ready
+	kubeclient := fake.NewFakeClient(workloads[0].DeepCopy())
+
+	err = waitForWorkloads(context.TODO(), kubeclient, "kserve", workloads, 10*time.Millisecond)
+	if readinessErr, ok := err.(*readinessError); !ok || readinessErr.reason != ReadinessTimeoutReason {
+		t.Errorf("Expected a readiness timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err = waitForWorkloads(ctx, kubeclient, "kserve", workloads, time.Hour)
+	if err != context.Canceled {
+		t.Errorf("Expected the wait to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the wait to stop once the context is cancelled, took %v", elapsed)
+	}
+}
+
+func TestHookJobs(t *testing.T) {
+	manifests := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+  annotations:
+    kfctl.kubeflow.io/hook: pre-apply
+    kfctl.kubeflow.io/hook-timeout: 5m
+    kfctl.kubeflow.io/hook-retries: "0"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: smoke-test
+  namespace: tests
+  annotations:
+    kfctl.kubeflow.io/hook: post-apply
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: not-a-hook
+`
+	pre, post, rest, err := splitHooks([]byte(manifests), "odh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pre) != 1 || pre[0].job.GetName() != "migration" || pre[0].job.GetNamespace() != "odh" ||
+		pre[0].timeout != 5*time.Minute || pre[0].retries != 0 {
+		t.Errorf("Expected the pre-apply hook odh/migration with a 5m timeout and no retry, got %+v", pre)
+	}
+	if len(post) != 1 || post[0].job.GetName() != "smoke-test" || post[0].job.GetNamespace() != "tests" ||
+		post[0].timeout != HookJobTimeout || post[0].retries != HookJobRetries {
+		t.Errorf("Expected the post-apply hook tests/smoke-test with the default timeout and retries, got %+v", post)
+	}
+	if pre[0].checksum == "" || pre[0].job.GetAnnotations()[HookChecksumAnnotation] != pre[0].checksum {
+		t.Errorf("Expected the checksum annotation of the hook, got %v", pre[0].job.GetAnnotations())
+	}
+	workloads, err := gatedWorkloads(rest, "odh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(workloads) != 1 || workloads[0].GetName() != "not-a-hook" || !strings.Contains(string(rest), "name: config") {
+		t.Errorf("Expected the resources without the hooks, got %s", rest)
+	}
+
+	for _, invalid := range []string{
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: d\n  annotations:\n    kfctl.kubeflow.io/hook: pre-apply\n",
+		"apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: j\n  annotations:\n    kfctl.kubeflow.io/hook: pre-install\n",
+		"apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: j\n  annotations:\n    kfctl.kubeflow.io/hook: pre-apply\n    kfctl.kubeflow.io/hook-timeout: soon\n",
+		"apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: j\n  annotations:\n    kfctl.kubeflow.io/hook: pre-apply\n    kfctl.kubeflow.io/hook-retries: \"-1\"\n",
+	} {
+		if _, _, _, err := splitHooks([]byte(invalid), "odh"); err == nil {
+			t.Errorf("Expected an error for the hook %s", invalid)
+		}
+	}
+
+	defer func(interval time.Duration) { hookPollInterval = interval }(hookPollInterval)
+	hookPollInterval = time.Millisecond
+	// A Job completed from the same manifest is not run again
+	completed := pre[0].job.DeepCopy()
+	if err := unstructured.SetNestedSlice(completed.Object, []interface{}{
+		map[string]interface{}{"type": "Complete", "status": "True"},
+	}, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	kubeclient := fake.NewFakeClient(completed)
+	if err := runHook(context.TODO(), kubeclient, kubefake.NewSimpleClientset(), "model-registry", pre[0]); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// A Job that does not complete is run again up to its retries
+	hook := post[0]
+	hook.timeout = 10 * time.Millisecond
+	hook.retries = 1
+	kubeclient = fake.NewFakeClient()
+	err = runHook(context.TODO(), kubeclient, kubefake.NewSimpleClientset(), "model-registry", hook)
+	hookErr, ok := err.(*hookError)
+	if !ok {
+		t.Fatalf("Expected a hook error, got %v", err)
+	}
+	expected := kfconfig.HookFailure{
+		Application:     "model-registry",
+		Job:             "tests/smoke-test",
+		Hook:            PostApplyHook,
+		Attempts:        2,
+		LastFailureTime: hookErr.failure.LastFailureTime,
+		Message:         "job did not complete within 10ms",
+		Logs:            "the Job has no pod",
+	}
+	if !reflect.DeepEqual(hookErr.failure, expected) {
+		t.Errorf("Expected the failure %+v, got %+v", expected, hookErr.failure)
+	}
+	job := &unstructured.Unstructured{}
+	job.SetGroupVersionKind(hook.job.GroupVersionKind())
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Namespace: "tests", Name: "smoke-test"}, job); !k8serrors.IsNotFound(err) {
+		t.Errorf("Expected the failed Job to be deleted, got %v", err)
+	}
+}
+
+func TestDetectFIPS(t *testing.T) {
+	clusterConfig := func(installConfig string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-config-v1"},
+			Data:       map[string]string{"install-config": installConfig},
+		}
+	}
+	testCases := []struct {
+		name      string
+		objects   []runtime.Object
+		expected  bool
+		expectErr bool
+	}{
+		{name: "no install config", expected: false},
+		{name: "fips enabled", objects: []runtime.Object{clusterConfig("apiVersion: v1\nfips: true\n")}, expected: true},
+		{name: "fips disabled", objects: []runtime.Object{clusterConfig("apiVersion: v1\nfips: false\n")}, expected: false},
+		{name: "fips unset", objects: []runtime.Object{clusterConfig("apiVersion: v1\n")}, expected: false},
+		{name: "invalid install config", objects: []runtime.Object{clusterConfig("fips: [")}, expectErr: true},
+	}
+	for _, c := range testCases {
+		enabled, err := DetectFIPS(fake.NewFakeClient(c.objects...))
+		if (err != nil) != c.expectErr {
+			t.Errorf("%v: expected error %v, got %v", c.name, c.expectErr, err)
+			continue
+		}
+		if enabled != c.expected {
+			t.Errorf("%v: expected FIPS enabled %v, got %v", c.name, c.expected, enabled)
+		}
+	}
+}
+
+func TestFIPSOverlays(t *testing.T) {
+	compDir, err := ioutil.TempDir("", "fips-overlays")
+	if err != nil {
+		t.Fatalf("Failed to create the component dir: %v", err)
+	}
+	defer os.RemoveAll(compDir)
+	if err := os.MkdirAll(path.Join(compDir, "overlays", FIPSOverlay), 0755); err != nil {
+		t.Fatalf("Failed to create the fips overlay: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		enabled  bool
+		compDir  string
+		overlays []string
+		expected []string
+	}{
+		{name: "disabled", enabled: false, compDir: compDir, overlays: []string{"istio"}, expected: []string{"istio"}},
+		{name: "enabled", enabled: true, compDir: compDir, overlays: []string{"istio"}, expected: []string{"istio", "fips"}},
+		{name: "already set", enabled: true, compDir: compDir, overlays: []string{"fips", "istio"}, expected: []string{"fips", "istio"}},
+		{name: "no fips overlay", enabled: true, compDir: path.Join(compDir, "overlays"), overlays: nil, expected: nil},
+	}
+	for _, c := range testCases {
+		overlays := fipsOverlays(c.enabled, c.compDir, c.overlays)
+		if !reflect.DeepEqual(overlays, c.expected) {
+			t.Errorf("%v: expected the overlays %v, got %v", c.name, c.expected, overlays)
+		}
+	}
+}
+
+func TestCheckFIPSCompatible(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+`)
+	if err := checkFIPSCompatible(resMap, "odh-dashboard"); err != nil {
+		t.Errorf("Expected the application to be FIPS compatible, got %v", err)
+	}
+
+	resMap = mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: legacy-crypto
+  annotations:
+    kfctl.kubeflow.io/fips: incompatible
+`)
+	err := checkFIPSCompatible(resMap, "legacy")
+	if _, ok := err.(*fipsIncompatibleError); !ok {
+		t.Fatalf("Expected the application to be refused, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Deployment legacy-crypto") {
+		t.Errorf("Expected the error to name the incompatible Deployment, got %v", err)
+	}
+}
+
+func TestInjectArchitectures(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  annotations:
+    kfctl.kubeflow.io/architectures: amd64, arm64
+spec:
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: node-role.kubernetes.io/worker
+                operator: Exists
+            - matchExpressions:
+              - key: node-role.kubernetes.io/infra
+                operator: Exists
+      containers:
+      - name: dashboard
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: model-mesh
+  annotations:
+    kfctl.kubeflow.io/architectures: s390x
+spec:
+  template:
+    spec:
+      containers:
+      - name: model-mesh
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: notebook-controller
+spec:
+  template:
+    spec:
+      containers:
+      - name: manager
+`
+	resMap := mustResMap(t, manifests)
+	unschedulable, err := injectArchitectures(resMap, map[string]bool{"amd64": true, "ppc64le": true})
+	if err != nil {
+		t.Fatalf("Failed to inject the architectures: %v", err)
+	}
+	if expected := []string{"StatefulSet model-mesh (s390x)"}; !reflect.DeepEqual(unschedulable, expected) {
+		t.Errorf("Expected the workloads without compatible nodes %v, got %v", expected, unschedulable)
+	}
+	requirement := map[string]interface{}{"key": ArchitectureLabel, "operator": "In", "values": []interface{}{"amd64", "arm64"}}
+	for _, res := range resMap.Resources() {
+		terms, _ := getNested(res.Map(), "spec", "template", "spec", "affinity", "nodeAffinity",
+			"requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms").([]interface{})
+		switch res.GetName() {
+		case "odh-dashboard":
+			if len(terms) != 2 {
+				t.Fatalf("Expected the 2 node selector terms of the Deployment to be kept, got %v", terms)
+			}
+			for _, term := range terms {
+				expressions := term.(map[string]interface{})["matchExpressions"].([]interface{})
+				if len(expressions) != 2 || !reflect.DeepEqual(expressions[1], requirement) {
+					t.Errorf("Expected the architectures to be required by every node selector term, got %v", expressions)
+				}
+			}
+		case "model-mesh":
+			if len(terms) != 1 {
+				t.Errorf("Expected a node selector term to be created, got %v", terms)
+			}
+		case "notebook-controller":
+			if terms != nil {
+				t.Errorf("Expected the Deployment without architectures to be left alone, got %v", terms)
+			}
+		}
+	}
+
+	resMap = mustResMap(t, manifests)
+	if unschedulable, err := injectArchitectures(resMap, nil); err != nil || len(unschedulable) != 0 {
+		t.Errorf("Expected no workload to be reported when the architectures of the nodes are unknown, got %v, %v",
+			unschedulable, err)
+	}
+
+	resMap = mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  annotations:
+    kfctl.kubeflow.io/architectures: x86_64
+`)
+	if _, err := injectArchitectures(resMap, nil); err == nil {
+		t.Errorf("Expected the unknown x86_64 architecture to be refused")
+	}
+}
+
+func TestNodeArchitectures(t *testing.T) {
+	node := func(name string, arch string) *v1.Node {
+		n := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if arch != "" {
+			n.Labels[ArchitectureLabel] = arch
+		}
+		return n
+	}
+	architectures, err := nodeArchitectures(fake.NewFakeClient(node("worker-0", "amd64"), node("worker-1", "arm64"),
+		node("worker-2", "amd64"), node("unlabelled", "")))
+	if err != nil {
+		t.Fatalf("Failed to detect the architectures of the nodes: %v", err)
+	}
+	if expected := map[string]bool{"amd64": true, "arm64": true}; !reflect.DeepEqual(architectures, expected) {
+		t.Errorf("Expected the architectures %v, got %v", expected, architectures)
+	}
+}
+
+func TestInjectIPFamilyPolicy(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+spec:
+  ipFamilies:
+  - IPv4
+  ports:
+  - port: 8443
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: model-mesh
+spec:
+  ipFamilyPolicy: SingleStack
+  ipFamilies:
+  - IPv6
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: external-db
+spec:
+  type: ExternalName
+  externalName: db.example.com
+`)
+	injectIPFamilyPolicy(resMap, kfconfig.IPFamilyPolicyPreferDualStack)
+	expected := map[string]interface{}{
+		"odh-dashboard": "PreferDualStack",
+		"model-mesh":    "SingleStack",
+		"external-db":   nil,
+	}
+	for _, res := range resMap.Resources() {
+		if policy := getNested(res.Map(), "spec", "ipFamilyPolicy"); policy != expected[res.GetName()] {
+			t.Errorf("Expected Service %v to have the IP family policy %v, got %v", res.GetName(), expected[res.GetName()], policy)
+		}
+		families := getNested(res.Map(), "spec", "ipFamilies")
+		switch res.GetName() {
+		case "odh-dashboard":
+			if families != nil {
+				t.Errorf("Expected the IP families of Service %v to be removed, got %v", res.GetName(), families)
+			}
+		case "model-mesh":
+			if !reflect.DeepEqual(families, []interface{}{"IPv6"}) {
+				t.Errorf("Expected the IP families of Service %v to be kept, got %v", res.GetName(), families)
+			}
+		}
+	}
+}
+
+func TestInjectAutoscaling(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+  namespace: opendatahub
+spec:
+  replicas: 2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: model-mesh
+spec:
+  replicas: 1
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: model-mesh
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: model-mesh
+  maxReplicas: 5
+`
+	resMap := mustResMap(t, manifests)
+	minReplicas, memory := int32(2), int32(70)
+	err := injectAutoscaling(resMap, &kfconfig.Autoscaling{
+		MinReplicas:             &minReplicas,
+		MaxReplicas:             4,
+		TargetMemoryUtilization: &memory,
+		Metrics:                 []kfconfig.AutoscalingMetric{{Name: "requests_per_second", TargetAverageValue: "100"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to inject the autoscaling: %v", err)
+	}
+	autoscalers := 0
+	for _, res := range resMap.Resources() {
+		switch {
+		case res.GetKind() == "Deployment" && res.GetName() == "odh-dashboard":
+			if replicas := getNested(res.Map(), "spec", "replicas"); replicas != nil {
+				t.Errorf("Expected the replicas of the autoscaled Deployment to be removed, got %v", replicas)
+			}
+		case res.GetKind() == "Deployment" && res.GetName() == "model-mesh":
+			if replicas := getNested(res.Map(), "spec", "replicas"); replicas == nil {
+				t.Errorf("Expected the Deployment autoscaled by the manifests to be left alone")
+			}
+		case res.GetKind() == "HorizontalPodAutoscaler":
+			autoscalers++
+			if res.GetName() != "odh-dashboard" {
+				continue
+			}
+			if res.GetNamespace() != "opendatahub" {
+				t.Errorf("Expected the HorizontalPodAutoscaler in the namespace of the Deployment, got %v", res.GetNamespace())
+			}
+			if min, max := getNested(res.Map(), "spec", "minReplicas"), getNested(res.Map(), "spec", "maxReplicas"); min != int64(2) || max != int64(4) {
+				t.Errorf("Expected the HorizontalPodAutoscaler to scale between 2 and 4 replicas, got %v and %v", min, max)
+			}
+			metrics, _ := getNested(res.Map(), "spec", "metrics").([]interface{})
+			if len(metrics) != 2 {
+				t.Fatalf("Expected the memory and the custom metrics, got %v", metrics)
+			}
+			if name := getNested(metrics[0].(map[string]interface{}), "resource", "name"); name != "memory" {
+				t.Errorf("Expected the memory utilization first, got %v", name)
+			}
+			if value := getNested(metrics[1].(map[string]interface{}), "pods", "target", "averageValue"); value != "100" {
+				t.Errorf("Expected the custom metric to target an average of 100, got %v", value)
+			}
+		}
+	}
+	if autoscalers != 2 {
+		t.Errorf("Expected 2 HorizontalPodAutoscalers, got %v", autoscalers)
+	}
+
+	resMap = mustResMap(t, manifests)
+	if err := injectAutoscaling(resMap, &kfconfig.Autoscaling{Deployments: []string{"notebook-controller"}, MaxReplicas: 2}); err == nil {
+		t.Errorf("Expected the autoscaling of a missing Deployment to fail")
+	}
+	if metrics := autoscalingMetrics(&kfconfig.Autoscaling{MaxReplicas: 2}); len(metrics) != 1 ||
+		getNested(metrics[0].(map[string]interface{}), "resource", "target", "averageUtilization") != int64(defaultTargetCPUUtilization) {
+		t.Errorf("Expected the CPU utilization to be targeted by default, got %v", metrics)
+	}
+}
+
+func TestInjectPriorityClass(t *testing.T) {
+	resMap := mustResMap(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ds-pipeline-api-server
+spec:
+  template:
+    spec:
+      priority: 0
+      containers:
+      - name: api-server
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: node-agent
+spec:
+  template:
+    spec:
+      priorityClassName: system-node-critical
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: cleanup
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-config
+`)
+	injectPriorityClass(resMap, "odh-control-plane")
+	expected := map[string]struct {
+		class string
+		path  []string
+	}{
+		"ds-pipeline-api-server": {"odh-control-plane", []string{"spec", "template", "spec"}},
+		"node-agent":             {"system-node-critical", []string{"spec", "template", "spec"}},
+		"cleanup":                {"odh-control-plane", []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+	}
+	for _, res := range resMap.Resources() {
+		e, ok := expected[res.GetName()]
+		if !ok {
+			if _, found := res.Map()["spec"]; found {
+				t.Errorf("Expected %v %v to be left alone, got %v", res.GetKind(), res.GetName(), res.Map())
+			}
+			continue
+		}
+		podSpec := getNested(res.Map(), e.path...).(map[string]interface{})
+		if podSpec["priorityClassName"] != e.class {
+			t.Errorf("Expected %v %v to have the PriorityClass %v, got %v", res.GetKind(), res.GetName(), e.class,
+				podSpec["priorityClassName"])
+		}
+		if _, ok := podSpec["priority"]; ok {
+			t.Errorf("Expected the priority of %v %v to be removed", res.GetKind(), res.GetName())
+		}
+	}
+}
+
+func TestIssueServingCertificates(t *testing.T) {
+	manifests := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard
+  annotations:
+    kfctl.kubeflow.io/serving-cert-secret: dashboard-proxy-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard-metrics
+  namespace: monitoring
+  annotations:
+    kfctl.kubeflow.io/serving-cert-secret: dashboard-metrics-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: odh-dashboard-internal
+`
+	defer func(issuer string) { CertManagerIssuer = issuer }(CertManagerIssuer)
+
+	CertManagerIssuer = ""
+	resMap := mustResMap(t, manifests)
+	if err := issueServingCertificates(resMap, "odh"); err != nil {
+		t.Fatal(err)
+	}
+	expectedSecrets := map[string]string{"odh-dashboard": "dashboard-proxy-tls", "odh-dashboard-metrics": "dashboard-metrics-tls"}
+	if resMap.Size() != 3 {
+		t.Errorf("Expected no Certificate without a cert-manager issuer, got %v resources", resMap.Size())
+	}
+	for _, res := range resMap.Resources() {
+		if secret := res.GetAnnotations()[ServiceCAAnnotation]; secret != expectedSecrets[res.GetName()] {
+			t.Errorf("Expected the service CA to issue %q for Service %v, got %q", expectedSecrets[res.GetName()],
+				res.GetName(), secret)
+		}
+	}
+
+	CertManagerIssuer = "odh-ca"
+	resMap = mustResMap(t, manifests)
+	if err := issueServingCertificates(resMap, "odh"); err != nil {
+		t.Fatal(err)
+	}
+	expectedCertificates := map[string]map[string]interface{}{
+		"odh-dashboard-serving-cert": {
+			"secretName": "dashboard-proxy-tls",
+			"dnsNames": []interface{}{"odh-dashboard", "odh-dashboard.odh", "odh-dashboard.odh.svc",
+				"odh-dashboard.odh.svc.cluster.local"},
+			"issuerRef":   map[string]interface{}{"group": "cert-manager.io", "kind": "ClusterIssuer", "name": "odh-ca"},
+			"renewBefore": "720h0m0s",
+		},
+		"odh-dashboard-metrics-serving-cert": {
+			"secretName": "dashboard-metrics-tls",
+			"dnsNames": []interface{}{"odh-dashboard-metrics", "odh-dashboard-metrics.monitoring",
+				"odh-dashboard-metrics.monitoring.svc", "odh-dashboard-metrics.monitoring.svc.cluster.local"},
+			"issuerRef":   map[string]interface{}{"group": "cert-manager.io", "kind": "ClusterIssuer", "name": "odh-ca"},
+			"renewBefore": "720h0m0s",
+		},
+	}
+	certificates := 0
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "Service" {
+			if secret, ok := res.GetAnnotations()[ServiceCAAnnotation]; ok {
+				t.Errorf("Expected the service CA not to issue %q with a cert-manager issuer", secret)
+			}
+			continue
+		}
+		certificates++
+		expected, ok := expectedCertificates[res.GetName()]
+		if res.GetKind() != "Certificate" || !ok {
+			t.Errorf("Unexpected %v %v", res.GetKind(), res.GetName())
+			continue
+		}
+		if !reflect.DeepEqual(res.Map()["spec"], expected) {
+			t.Errorf("Expected the Certificate %v to be %v, got %v", res.GetName(), expected, res.Map()["spec"])
+		}
+	}
+	if certificates != len(expectedCertificates) {
+		t.Errorf("Expected %v Certificates, got %v", len(expectedCertificates), certificates)
+	}
+}
+
+// mustResMap returns the resources of the manifests, failing the test if they can't be parsed
+func mustResMap(t *testing.T, manifests string) resmap.ResMap {
+	t.Helper()
+	rf := resmap.NewFactory(kresource.NewFactory(kunstruct.NewKunstructuredFactoryImpl()), transformer.NewFactoryImpl())
+	resMap, err := rf.NewResMapFromBytes([]byte(manifests))
+	if err != nil {
+		t.Fatalf("Failed to parse the resources: %v", err)
+	}
+	return resMap
+}
@@ -0,0 +1,112 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// workloadKinds are the kinds of the workloads that can be overridden, along with the struct their strategic
+// merge patches are computed with. Overrides without a kind apply to a Deployment.
+var workloadKinds = map[string]interface{}{
+	"Deployment":  appsv1.Deployment{},
+	"StatefulSet": appsv1.StatefulSet{},
+}
+
+// overrideWorkloads patches the workloads of resMap with the overrides of the application. The overrides of the
+// workloads and of the containers that are not in the manifests are skipped.
+func overrideWorkloads(resMap resmap.ResMap, app string, overrides []kfconfig.WorkloadOverride) error {
+	for _, override := range overrides {
+		kind := override.Kind
+		if kind == "" {
+			kind = "Deployment"
+		}
+		found := false
+		for _, res := range resMap.Resources() {
+			if res.GetKind() != kind || res.GetName() != override.Name {
+				continue
+			}
+			found = true
+			obj := &unstructured.Unstructured{Object: res.Map()}
+			if err := overrideWorkload(obj, override); err != nil {
+				return &kfapisv3.KfError{
+					Code:    int(kfapisv3.INVALID_ARGUMENT),
+					Message: fmt.Sprintf("failed to override %v %v of application %v: %v", kind, override.Name, app, err),
+				}
+			}
+			res.SetMap(obj.Object)
+		}
+		if !found {
+			log.Warnf("Skipping the overrides of %v %v, it is not in the manifests of application %v", kind, override.Name, app)
+		}
+	}
+	return nil
+}
+
+// overrideWorkload applies a strategic merge patch setting the replicas and the resources of the containers of
+// override to the workload obj. The requests and limits the override does not set are kept.
+func overrideWorkload(obj *unstructured.Unstructured, override kfconfig.WorkloadOverride) error {
+	dataStruct, ok := workloadKinds[obj.GetKind()]
+	if !ok {
+		return fmt.Errorf("only the replicas and the resources of Deployments and StatefulSets can be overridden")
+	}
+
+	spec := map[string]interface{}{}
+	if override.Replicas != nil {
+		spec["replicas"] = *override.Replicas
+	}
+	patches := map[string][]interface{}{}
+	for _, c := range override.Containers {
+		list := containerList(obj, c.Name)
+		if list == "" {
+			log.Warnf("Skipping the overrides of container %v, it is not in %v %v", c.Name, obj.GetKind(), obj.GetName())
+			continue
+		}
+		patches[list] = append(patches[list], map[string]interface{}{"name": c.Name, "resources": c.Resources})
+	}
+	if len(patches) > 0 {
+		podSpec := map[string]interface{}{}
+		for list, containers := range patches {
+			podSpec[list] = containers
+		}
+		spec["template"] = map[string]interface{}{"spec": podSpec}
+	}
+	if len(spec) == 0 {
+		return nil
+	}
+
+	original, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, dataStruct)
+	if err != nil {
+		return err
+	}
+	return obj.UnmarshalJSON(patched)
+}
+
+// containerList returns the list of the pod template of the workload holding the container name, containers or
+// initContainers, or an empty string if there is no such container
+func containerList(obj *unstructured.Unstructured, name string) string {
+	for _, list := range []string{"containers", "initContainers"} {
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", list)
+		for _, c := range containers {
+			if c, ok := c.(map[string]interface{}); ok && c["name"] == name {
+				return list
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+)
+
+// MaxConcurrentApplies is the number of applications of a KfDef that are applied at the same time. The applications
+// are applied one at a time in the order of the spec when it is 1, or after the applications they depend on.
+var MaxConcurrentApplies = 1
+
+// applicationOrder returns the applications of the spec, without the duplicate names, along with the applications
+// each of them depends on. It fails if an application depends on an unknown application or on itself, directly or not.
+func applicationOrder(applications []kfconfig.Application) ([]kfconfig.Application, map[string][]string, error) {
+	apps := []kfconfig.Application{}
+	names := []string{}
+	dependencies := map[string][]string{}
+	for _, app := range applications {
+		if _, ok := dependencies[app.Name]; ok {
+			continue
+		}
+		apps = append(apps, app)
+		names = append(names, app.Name)
+		dependencies[app.Name] = app.DependsOn
+	}
+	for _, app := range apps {
+		for _, dependency := range app.DependsOn {
+			if _, ok := dependencies[dependency]; !ok {
+				return nil, nil, &kfapisv3.KfError{
+					Code:    int(kfapisv3.INVALID_ARGUMENT),
+					Message: fmt.Sprintf("application %v depends on unknown application %v", app.Name, dependency),
+				}
+			}
+		}
+	}
+
+	if cycle := utils.DependencyCycle(names, dependencies); cycle != nil {
+		return nil, nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("applications depend on each other: %v", strings.Join(cycle, " -> ")),
+		}
+	}
+	return apps, dependencies, nil
+}
+
+// applyInOrder calls apply for every application once the applications it depends on were applied, with at most
+// workers applications at the same time. The applications ready to be applied are started in the order of the spec.
+// No application is started once one failed; the error of the first one that failed is returned once the others
+// being applied are done.
+func applyInOrder(applications []kfconfig.Application, workers int, apply func(kfconfig.Application) error) error {
+	apps, dependencies, err := applicationOrder(applications)
+	if err != nil {
+		return err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+	started := map[string]bool{}
+	done := map[string]bool{}
+	running := 0
+	var firstErr error
+	for {
+		for _, app := range apps {
+			if firstErr != nil || running >= workers {
+				break
+			}
+			if started[app.Name] || !allDone(dependencies[app.Name], done) {
+				continue
+			}
+			started[app.Name] = true
+			running++
+			go func(app kfconfig.Application) {
+				results <- result{name: app.Name, err: apply(app)}
+			}(app)
+		}
+		if running == 0 {
+			return firstErr
+		}
+		r := <-results
+		running--
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		done[r.name] = true
+	}
+}
+
+// allDone returns true if every one of names is done
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+const (
+	// PodSecurityEnforceLabel is the Pod Security Standard the pods of a namespace are admitted with
+	PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	// PodSecurityLabelSyncLabel set to false keeps OpenShift from overwriting the Pod Security labels of a namespace
+	PodSecurityLabelSyncLabel = "security.openshift.io/scc.podSecurityLabelSync"
+)
+
+// podSecurityOrder ranks the pod security levels from the least to the most restrictive
+var podSecurityOrder = map[kfconfig.PodSecurityLevel]int{
+	kfconfig.PodSecurityPrivileged: 0,
+	kfconfig.PodSecurityBaseline:   1,
+	kfconfig.PodSecurityRestricted: 2,
+}
+
+// LeastRestrictivePodSecurity returns the least restrictive of levels, the level a namespace shared by applications
+// requiring them is labelled with. The empty levels are ignored, it is empty when all of them are.
+func LeastRestrictivePodSecurity(levels ...kfconfig.PodSecurityLevel) kfconfig.PodSecurityLevel {
+	var least kfconfig.PodSecurityLevel
+	for _, level := range levels {
+		if _, ok := podSecurityOrder[level]; !ok {
+			continue
+		}
+		if least == "" || podSecurityOrder[level] < podSecurityOrder[least] {
+			least = level
+		}
+	}
+	return least
+}
+
+// appPodSecurity returns the pod security level of app, the one of spec unless the application sets its own
+func appPodSecurity(spec *kfconfig.KfConfigSpec, app kfconfig.Application) kfconfig.PodSecurityLevel {
+	if app.PodSecurity != "" {
+		return app.PodSecurity
+	}
+	return spec.PodSecurity
+}
+
+// namespacePodSecurity returns the least restrictive pod security level of the applications of spec
+func namespacePodSecurity(spec *kfconfig.KfConfigSpec) kfconfig.PodSecurityLevel {
+	levels := []kfconfig.PodSecurityLevel{}
+	for _, app := range spec.Applications {
+		levels = append(levels, appPodSecurity(spec, app))
+	}
+	return LeastRestrictivePodSecurity(levels...)
+}
+
+// injectPodSecurity labels the Namespaces of resMap, but the applications namespace, with namespaceLevel, and
+// hardens the workloads of resMap when level, the level of their application, is restricted. The workloads that
+// cannot be hardened, e.g. privileged ones, are left as they are.
+func injectPodSecurity(resMap resmap.ResMap, level kfconfig.PodSecurityLevel, namespaceLevel kfconfig.PodSecurityLevel,
+	applicationsNamespace string) error {
+	for _, res := range resMap.Resources() {
+		if res.GetKind() == "Namespace" && namespaceLevel != "" && res.GetName() != applicationsNamespace {
+			labels := res.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[PodSecurityEnforceLabel] = string(namespaceLevel)
+			labels[PodSecurityLabelSyncLabel] = "false"
+			res.SetLabels(labels)
+			continue
+		}
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok || level != kfconfig.PodSecurityRestricted {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		field, found, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		podSpec, ok := field.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if violations := hardenPodSpec(podSpec); len(violations) > 0 {
+			log.Warnf("%v %v does not satisfy the %v pod security level: %v.", res.GetKind(), res.GetName(),
+				level, strings.Join(violations, ", "))
+		}
+		res.SetMap(obj.Object)
+	}
+	return nil
+}
+
+// hardenPodSpec sets the fields of the security contexts of podSpec and of its containers that the restricted pod
+// security level requires and that they leave unset: runAsNonRoot, the RuntimeDefault seccomp profile, no privilege
+// escalation and no capabilities. The fields they set are kept. It returns the reasons podSpec does not satisfy the
+// level.
+func hardenPodSpec(podSpec map[string]interface{}) []string {
+	violations := []string{}
+	podContext, _ := podSpec["securityContext"].(map[string]interface{})
+	if podContext == nil {
+		podContext = map[string]interface{}{}
+	}
+	runsAsRoot := isRootUser(podContext["runAsUser"])
+	walkContainers(podSpec, func(container map[string]interface{}) {
+		if context, ok := container["securityContext"].(map[string]interface{}); ok && isRootUser(context["runAsUser"]) {
+			runsAsRoot = true
+		}
+	})
+
+	switch podContext["runAsNonRoot"] {
+	case nil:
+		if runsAsRoot {
+			violations = append(violations, "runs as root")
+		} else {
+			podContext["runAsNonRoot"] = true
+		}
+	case false:
+		violations = append(violations, "runAsNonRoot is false")
+	}
+	if profile, ok := podContext["seccompProfile"].(map[string]interface{}); !ok {
+		podContext["seccompProfile"] = map[string]interface{}{"type": "RuntimeDefault"}
+	} else if profile["type"] == "Unconfined" {
+		violations = append(violations, "the seccomp profile is Unconfined")
+	}
+	podSpec["securityContext"] = podContext
+
+	walkContainers(podSpec, func(container map[string]interface{}) {
+		name := container["name"]
+		context, _ := container["securityContext"].(map[string]interface{})
+		if context == nil {
+			context = map[string]interface{}{}
+		}
+		if context["privileged"] == true {
+			violations = append(violations, fmt.Sprintf("container %v is privileged", name))
+		}
+		if context["runAsNonRoot"] == false {
+			violations = append(violations, fmt.Sprintf("container %v sets runAsNonRoot to false", name))
+		}
+		if profile, ok := context["seccompProfile"].(map[string]interface{}); ok && profile["type"] == "Unconfined" {
+			violations = append(violations, fmt.Sprintf("the seccomp profile of container %v is Unconfined", name))
+		}
+		switch context["allowPrivilegeEscalation"] {
+		case nil:
+			context["allowPrivilegeEscalation"] = false
+		case true:
+			violations = append(violations, fmt.Sprintf("container %v allows privilege escalation", name))
+		}
+		capabilities, _ := context["capabilities"].(map[string]interface{})
+		if capabilities == nil {
+			capabilities = map[string]interface{}{}
+		}
+		if drop, ok := capabilities["drop"].([]interface{}); !ok {
+			capabilities["drop"] = []interface{}{"ALL"}
+		} else if !containsString(drop, "ALL") {
+			violations = append(violations, fmt.Sprintf("container %v does not drop all capabilities", name))
+		}
+		if add, ok := capabilities["add"].([]interface{}); ok {
+			for _, c := range add {
+				if c != "NET_BIND_SERVICE" {
+					violations = append(violations, fmt.Sprintf("container %v adds capability %v", name, c))
+				}
+			}
+		}
+		context["capabilities"] = capabilities
+		container["securityContext"] = context
+	})
+	return violations
+}
+
+// isRootUser returns true if the runAsUser of a security context is the root user
+func isRootUser(runAsUser interface{}) bool {
+	switch uid := runAsUser.(type) {
+	case int64:
+		return uid == 0
+	case int:
+		return uid == 0
+	case float64:
+		return uid == 0
+	}
+	return false
+}
+
+// containsString returns true if list holds the string s
+func containsString(list []interface{}, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
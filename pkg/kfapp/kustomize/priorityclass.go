@@ -0,0 +1,37 @@
+package kustomize
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// injectPriorityClass assigns the PriorityClass name to the pods of the workloads of resMap that do not name one,
+// so that the pods of the applications are evicted after the user workloads under cluster pressure. The pods of
+// the manifests naming a PriorityClass, e.g. system-cluster-critical, keep it.
+func injectPriorityClass(resMap resmap.ResMap, name string) {
+	if name == "" {
+		return
+	}
+	for _, res := range resMap.Resources() {
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		field, found, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		podSpec, ok := field.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if className, _ := podSpec["priorityClassName"].(string); className != "" {
+			continue
+		}
+		// The priority of the pods is resolved from the PriorityClass when they are admitted
+		delete(podSpec, "priority")
+		podSpec["priorityClassName"] = name
+		res.SetMap(obj.Object)
+	}
+}
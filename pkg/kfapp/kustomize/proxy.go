@@ -0,0 +1,128 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// ProxyEnvVars are the proxy environment variables propagated to the Deployments of the manifests,
+// along with the field of the status of the OpenShift cluster-wide Proxy they are read from.
+var ProxyEnvVars = []struct {
+	Name  string
+	Field string
+}{
+	{Name: "HTTP_PROXY", Field: "httpProxy"},
+	{Name: "HTTPS_PROXY", Field: "httpsProxy"},
+	{Name: "NO_PROXY", Field: "noProxy"},
+}
+
+// ClusterProxyGVK is the kind of the OpenShift cluster-wide Proxy configuration, named cluster
+var ClusterProxyGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Proxy"}
+
+// loadProxyEnv returns the proxy environment variables of the operator. When none is set, they are read from the
+// status of the OpenShift cluster-wide Proxy, if any.
+func (kustomize *kustomize) loadProxyEnv() (map[string]string, error) {
+	env := map[string]string{}
+	for _, v := range ProxyEnvVars {
+		if value := os.Getenv(v.Name); value != "" {
+			env[v.Name] = value
+		}
+	}
+	if len(env) > 0 {
+		return env, nil
+	}
+
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing k8s client: %v", err),
+		}
+	}
+	return clusterProxyEnv(kubeclient)
+}
+
+// clusterProxyEnv returns the proxy environment variables set in the status of the OpenShift cluster-wide Proxy,
+// none on clusters without it
+func clusterProxyEnv(kubeclient client.Client) (map[string]string, error) {
+	env := map[string]string{}
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(ClusterProxyGVK)
+	if err := kubeclient.Get(context.TODO(), client.ObjectKey{Name: "cluster"}, proxy); err != nil {
+		if meta.IsNoMatchError(err) || k8serrors.IsNotFound(err) {
+			return env, nil
+		}
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get the cluster-wide Proxy: %v", err),
+		}
+	}
+	for _, v := range ProxyEnvVars {
+		if value, _, _ := unstructured.NestedString(proxy.Object, "status", v.Field); value != "" {
+			env[v.Name] = value
+		}
+	}
+	return env, nil
+}
+
+// injectProxyEnv sets env on the containers of the Deployments of resMap that do not set the variables themselves.
+// The hosts of the NO_PROXY of env are added to the NO_PROXY value of the containers that set it, so that they
+// still reach the cluster without the proxy.
+func injectProxyEnv(resMap resmap.ResMap, env map[string]string) {
+	if len(env) == 0 {
+		return
+	}
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "Deployment" {
+			continue
+		}
+		m := res.Map()
+		walkContainers(m, func(container map[string]interface{}) {
+			containerEnv, _ := container["env"].([]interface{})
+			defined := map[string]bool{}
+			for _, e := range containerEnv {
+				if e, ok := e.(map[string]interface{}); ok {
+					if name, ok := e["name"].(string); ok {
+						defined[name] = true
+					}
+					if value, ok := e["value"].(string); ok && e["name"] == "NO_PROXY" && env["NO_PROXY"] != "" {
+						e["value"] = mergeNoProxy(value, env["NO_PROXY"])
+					}
+				}
+			}
+			for _, v := range ProxyEnvVars {
+				if value, ok := env[v.Name]; ok && !defined[v.Name] {
+					containerEnv = append(containerEnv, map[string]interface{}{"name": v.Name, "value": value})
+					container["env"] = containerEnv
+				}
+			}
+		})
+		res.SetMap(m)
+	}
+}
+
+// mergeNoProxy appends the hosts of the comma-separated list noProxy missing from the list value
+func mergeNoProxy(value string, noProxy string) string {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, list := range []string{value, noProxy} {
+		for _, host := range strings.Split(list, ",") {
+			if host = strings.TrimSpace(host); host != "" && !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return strings.Join(hosts, ",")
+}
@@ -0,0 +1,255 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	errutil "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// PruneResources makes the operator delete the resources it applied that are no longer in the manifests of the
+// applications of a KfDef, e.g. after an upgrade of the manifests or once an application is removed from the KfDef.
+var PruneResources = true
+
+// InventoryConfigMapName returns the name of the ConfigMap listing the resources applied for each application of
+// the KfDef, keyed by application name
+func InventoryConfigMapName(kfdef string) string {
+	return kfdef + "-inventory"
+}
+
+// inventoryItem identifies a resource applied for an application
+type inventoryItem struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Digest is the digest of the resource rendered from the manifests, empty in the inventories recorded before
+	// the digests were
+	Digest string `json:"digest,omitempty"`
+}
+
+// key identifies the resource whatever the version of its kind
+func (i inventoryItem) key() string {
+	return strings.Join([]string{schema.FromAPIVersionAndKind(i.APIVersion, i.Kind).Group, i.Kind, i.Namespace, i.Name}, "/")
+}
+
+// inventoryItems returns the resources of the manifests. The namespaced resources without a namespace are applied
+// to namespace.
+func inventoryItems(mapper meta.RESTMapper, data []byte, namespace string) ([]inventoryItem, error) {
+	resources, err := utils.SplitYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	items := []inventoryItem{}
+	for _, res := range resources {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(res, &u.Object); err != nil {
+			return nil, err
+		}
+		if u.GetKind() == "" {
+			continue
+		}
+		// The fields of the resource are marshalled sorted, for its digest to only change along with them
+		content, err := json.Marshal(u.Object)
+		if err != nil {
+			return nil, err
+		}
+		item := inventoryItem{APIVersion: u.GetAPIVersion(), Kind: u.GetKind(), Namespace: u.GetNamespace(), Name: u.GetName(),
+			Digest: fmt.Sprintf("%x", sha256.Sum256(content))}
+		if item.Namespace == "" && isNamespaced(mapper, u.GroupVersionKind()) {
+			item.Namespace = namespace
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// isNamespaced returns true if the resources of kind gvk are namespaced, or if their scope is unknown, e.g. the kind
+// of a CRD that is not installed yet
+func isNamespaced(mapper meta.RESTMapper, gvk schema.GroupVersionKind) bool {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	return err != nil || mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
+
+// readInventory returns the resources applied for each application of the KfDef, or nil if they were never recorded
+func readInventory(ctx context.Context, kubeclient client.Client, kfdef string, namespace string) (map[string][]inventoryItem, error) {
+	cm := &v1.ConfigMap{}
+	err := kubeclient.Get(ctx, client.ObjectKey{Name: InventoryConfigMapName(kfdef), Namespace: namespace}, cm)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to get the inventory of %v: %v", kfdef, err),
+		}
+	}
+	inventory := map[string][]inventoryItem{}
+	for app, data := range cm.Data {
+		items := []inventoryItem{}
+		if err := yaml.Unmarshal([]byte(data), &items); err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("invalid inventory of application %v of %v: %v", app, kfdef, err),
+			}
+		}
+		inventory[app] = items
+	}
+	return inventory, nil
+}
+
+// writeInventory records the resources applied for each application of the KfDef
+func writeInventory(kubeclient client.Client, kfdef string, namespace string, inventory map[string][]inventoryItem) error {
+	data := map[string]string{}
+	for app, items := range inventory {
+		sort.Slice(items, func(i, j int) bool { return items[i].key() < items[j].key() })
+		out, err := yaml.Marshal(items)
+		if err != nil {
+			return err
+		}
+		data[app] = string(out)
+	}
+	return writeKfDefConfigMap(kubeclient, kfdef, namespace, InventoryConfigMapName(kfdef), data)
+}
+
+// staleItems returns the resources of the previous inventory that are in none of the applications of the current one
+func staleItems(previous map[string][]inventoryItem, current map[string][]inventoryItem) []inventoryItem {
+	keep := map[string]bool{}
+	for _, items := range current {
+		for _, item := range items {
+			keep[item.key()] = true
+		}
+	}
+	stale := []inventoryItem{}
+	seen := map[string]bool{}
+	apps := []string{}
+	for app := range previous {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		for _, item := range previous[app] {
+			if keep[item.key()] || seen[item.key()] {
+				continue
+			}
+			seen[item.key()] = true
+			stale = append(stale, item)
+		}
+	}
+	return stale
+}
+
+// prune deletes the resources of the previous inventory that are no longer in the manifests of the current one.
+// Only the resources still annotated as managed by the KfDef are deleted, and namespaces, CRDs and the resources
+// annotated with kfctl.kubeflow.io/ignore-drift=true or left to Argo CD are kept.
+func (kustomize *kustomize) prune(ctx context.Context, kubeclient client.Client, previous map[string][]inventoryItem, current map[string][]inventoryItem) error {
+	kfdefAnn := strings.Join([]string{utils.KfDefAnnotation, utils.KfDefInstance}, "/")
+	kfdefCr := strings.Join([]string{kustomize.kfDef.Name, kustomize.kfDef.Namespace}, ".")
+	errs := []error{}
+	for _, item := range staleItems(previous, current) {
+		if item.Kind == "Namespace" || item.Kind == "CustomResourceDefinition" {
+			log.Infof("Keeping %v %v removed from the manifests of %v.", item.Kind, item.Name, kfdefCr)
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(item.APIVersion)
+		obj.SetKind(item.Kind)
+		err := kubeclient.Get(ctx, client.ObjectKey{Namespace: item.Namespace, Name: item.Name}, obj)
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get %v %v/%v: %v", item.Kind, item.Namespace, item.Name, err))
+			continue
+		}
+		if obj.GetAnnotations()[kfdefAnn] != kfdefCr || utils.IgnoresDrift(obj) || IgnoresArgoCDResource(obj) ||
+			obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		log.Infof("Pruning %v %v/%v removed from the manifests of %v.", item.Kind, item.Namespace, item.Name, kfdefCr)
+		err = kubeclient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %v %v/%v: %v", item.Kind, item.Namespace, item.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("failed to prune the resources removed from the manifests: %v", errutil.NewAggregate(errs)),
+		}
+	}
+	return nil
+}
+
+// pruneApplications records the resources of the applications applied from their manifests, or of the previous
+// inventory for the applications that were skipped as unchanged, prunes the resources of the previous inventory
+// that are no longer in any application unless PruneResources is disabled, and records the changes from the previous
+// inventory. The inventory is left as it is when some resources couldn't be pruned, so that they are pruned on the
+// next reconcile.
+func (kustomize *kustomize) pruneApplications(ctx context.Context, kubeclient client.Client, previous map[string][]inventoryItem, applied map[string][]byte) error {
+	// The kinds of the CRDs that were just applied are discovered again
+	mapper, err := apiutil.NewDiscoveryRESTMapper(kustomize.restConfig)
+	if err != nil {
+		return &kfapisv3.KfError{
+			Code:    int(kfapisv3.INTERNAL_ERROR),
+			Message: fmt.Sprintf("error initializing k8s rest mapper: %v", err),
+		}
+	}
+	current := map[string][]inventoryItem{}
+	for _, app := range kustomize.kfDef.Spec.Applications {
+		data, ok := applied[app.Name]
+		if !ok {
+			if items, ok := previous[app.Name]; ok {
+				current[app.Name] = items
+			}
+			continue
+		}
+		if current[app.Name], err = inventoryItems(mapper, data, kustomize.kfDef.Namespace); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to list the resources of application %v: %v", app.Name, err),
+			}
+		}
+	}
+	// The drift controllers must not restore the resources of the applications removed from the KfDef
+	for app := range previous {
+		if _, ok := current[app]; ok {
+			continue
+		}
+		if err := os.Remove(RenderedManifestsPath(kustomize.kfDef.Spec.AppDir, app)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove the manifests applied for %v: %v", app, err)
+		}
+		kustomize.forgetRender(app)
+	}
+	if previous != nil && PruneResources {
+		if err := kustomize.prune(ctx, kubeclient, previous, current); err != nil {
+			return err
+		}
+	}
+	if err := writeInventory(kubeclient, kustomize.kfDef.Name, kustomize.kfDef.Namespace, current); err != nil {
+		return err
+	}
+	// The first inventory is not a change, the resources it lists may have been applied by a previous version
+	if previous == nil {
+		return nil
+	}
+	return writeManifestChanges(kubeclient, kustomize.kfDef.Name, kustomize.kfDef.Namespace, manifestChanges(previous, current), time.Now())
+}
@@ -0,0 +1,189 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReadinessTimeoutReason is the reason of an application whose workloads were not ready within its timeout
+	ReadinessTimeoutReason = "ReadinessTimeout"
+	// ReadinessFailedReason is the reason of an application whose Jobs failed
+	ReadinessFailedReason = "ReadinessFailed"
+)
+
+var (
+	// ReadinessTimeout is how long the applications depending on an application wait for it to be ready, unless
+	// it sets its own readinessTimeout
+	ReadinessTimeout = 10 * time.Minute
+	// readinessPollInterval is the time between two checks of the workloads of an application
+	readinessPollInterval = 5 * time.Second
+)
+
+// readinessError is returned when an application other applications depend on did not get ready
+type readinessError struct {
+	reason  string
+	message string
+}
+
+func (e *readinessError) Error() string {
+	return e.message
+}
+
+// gatedApplications returns the applications other applications depend on
+func gatedApplications(applications []kfconfig.Application) map[string]bool {
+	gated := map[string]bool{}
+	for _, app := range applications {
+		for _, dependency := range app.DependsOn {
+			gated[dependency] = true
+		}
+	}
+	return gated
+}
+
+// gatedWorkloads returns the Deployments and the Jobs of the manifests, the namespaced ones without a namespace
+// being in namespace
+func gatedWorkloads(data []byte, namespace string) ([]*unstructured.Unstructured, error) {
+	resources, err := utils.SplitYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*unstructured.Unstructured{}
+	for _, res := range resources {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(res, &obj.Object); err != nil {
+			return nil, err
+		}
+		group := obj.GroupVersionKind().Group
+		if !(obj.GetKind() == "Deployment" && group == "apps") && !(obj.GetKind() == "Job" && group == "batch") {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		workloads = append(workloads, obj)
+	}
+	return workloads, nil
+}
+
+// workloadReady returns true if the Deployment is available with its latest pod template, or if the Job completed.
+// An error is returned if the Job failed, it will not get ready.
+func workloadReady(obj *unstructured.Unstructured) (bool, error) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	hasCondition := func(conditionType string) bool {
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == conditionType && condition["status"] == "True" {
+				return true
+			}
+		}
+		return false
+	}
+	if obj.GetKind() == "Job" {
+		if hasCondition("Failed") {
+			return false, fmt.Errorf("job %v/%v failed", obj.GetNamespace(), obj.GetName())
+		}
+		return hasCondition("Complete"), nil
+	}
+
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	return observed >= obj.GetGeneration() && updated >= replicas && hasCondition("Available"), nil
+}
+
+// poll calls condition every interval until it returns true or an error, timeout expires or ctx is done. It returns
+// wait.ErrWaitTimeout once timeout expires, and the error of ctx once it is done.
+func poll(ctx context.Context, interval time.Duration, timeout time.Duration, condition wait.ConditionFunc) error {
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := wait.PollImmediateUntil(interval, condition, pollCtx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// waitForReadiness waits for the Deployments of the manifests of the application to be available and its Jobs to
+// complete, within the readiness timeout of the application, or until ctx is done. The manifests the application
+// was last applied from are read when data is nil.
+func (kustomize *kustomize) waitForReadiness(ctx context.Context, app kfconfig.Application, data []byte) error {
+	if data == nil {
+		var err error
+		if data, err = ioutil.ReadFile(RenderedManifestsPath(kustomize.kfDef.Spec.AppDir, app.Name)); err != nil {
+			log.Warnf("Not waiting for application %v to be ready, its manifests are unknown: %v", app.Name, err)
+			return nil
+		}
+	}
+	workloads, err := gatedWorkloads(data, kustomize.kfDef.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+	timeout := ReadinessTimeout
+	if app.ReadinessTimeout != nil {
+		timeout = app.ReadinessTimeout.Duration
+	}
+	kustomize.initK8sClients()
+	kubeclient, err := client.New(kustomize.restConfig, client.Options{})
+	if err != nil {
+		return err
+	}
+	return waitForWorkloads(ctx, kubeclient, app.Name, workloads, timeout)
+}
+
+// waitForWorkloads waits for the workloads of the application to be ready within timeout, or until ctx is done
+func waitForWorkloads(ctx context.Context, kubeclient client.Client, app string, workloads []*unstructured.Unstructured, timeout time.Duration) error {
+	log.Infof("Waiting up to %v for application %v to be ready", timeout, app)
+	pending := []string{}
+	err := poll(ctx, readinessPollInterval, timeout, func() (bool, error) {
+		pending = []string{}
+		for _, workload := range workloads {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(workload.GroupVersionKind())
+			err := kubeclient.Get(ctx, client.ObjectKey{Namespace: workload.GetNamespace(), Name: workload.GetName()}, obj)
+			if err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+			ready := false
+			if err == nil {
+				if ready, err = workloadReady(obj); err != nil {
+					return false, &readinessError{reason: ReadinessFailedReason, message: err.Error()}
+				}
+			}
+			if !ready {
+				pending = append(pending, fmt.Sprintf("%v %v/%v", strings.ToLower(workload.GetKind()),
+					workload.GetNamespace(), workload.GetName()))
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return &readinessError{
+			reason:  ReadinessTimeoutReason,
+			message: fmt.Sprintf("application not ready after %v: %v", timeout, strings.Join(pending, ", ")),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Application %v is ready", app)
+	return nil
+}
@@ -0,0 +1,256 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// RenderCacheDir is the directory holding the render cache entries of the applications, by namespace and KfDef. It is
+// kept out of the app dirs, which are deleted on every reconcile.
+var RenderCacheDir = path.Join(os.TempDir(), "kfdef-render-cache")
+
+// RenderCacheTTL is how long an application that was applied is not rendered nor applied again as long as its
+// manifests, overlays and parameters don't change. The render cache is disabled when it is 0.
+var RenderCacheTTL = time.Hour
+
+// renderCacheEntry records the key of the manifests an application was last applied from
+type renderCacheEntry struct {
+	Key string `json:"key"`
+	// Rendered are the manifests applied, written back to the app dir when the application is skipped
+	Rendered []byte `json:"rendered,omitempty"`
+	// RelatedImages are the images of the manifests along with the images they were deployed as
+	RelatedImages map[string]string `json:"relatedImages,omitempty"`
+	// SecurityContextConstraints are the SecurityContextConstraints granted to the ServiceAccounts of the manifests
+	SecurityContextConstraints []kfconfig.SCCGrant `json:"securityContextConstraints,omitempty"`
+	AppliedAt                  time.Time           `json:"appliedAt"`
+}
+
+// renderCache keeps the entries of the applications in memory, by <namespace>/<kfdef>/<application>.
+// invalidated holds when the entries of each <namespace>/<kfdef> were last invalidated.
+var renderCache = struct {
+	sync.Mutex
+	entries     map[string]renderCacheEntry
+	invalidated map[string]time.Time
+}{
+	entries:     map[string]renderCacheEntry{},
+	invalidated: map[string]time.Time{},
+}
+
+// InvalidateRenderCache makes every application of the KfDef namespace/name render and apply again on the next
+// reconcile, e.g. once the resources they deployed were changed or the trusted CA bundle was updated.
+func InvalidateRenderCache(namespace string, name string) {
+	renderCache.Lock()
+	defer renderCache.Unlock()
+	renderCache.invalidated[namespace+"/"+name] = time.Now()
+}
+
+// renderCacheEnabled returns true if the applications can be skipped when they are unchanged. Only the applications
+// deployed by the operator are, the resources they deployed are watched to invalidate the cache.
+func (kustomize *kustomize) renderCacheEnabled() bool {
+	return RenderCacheTTL > 0 && kustomize.setOperatorAnnotation() && !utils.IsDryRun(kustomize.kfDef)
+}
+
+// renderCacheKey returns the key of the manifests of app: the digest of its repo, its overlays and parameters,
+// the kustomization generated for it, the overrides, scheduling, pod security, FIPS mode and IP family policy applied
+// to every application, and the architectures of the nodes.
+func (kustomize *kustomize) renderCacheKey(app kfconfig.Application) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "kfdef %v %v %v\n", kustomize.kfDef.Namespace, kustomize.kfDef.Name, kustomize.kfDef.UID)
+	fmt.Fprintf(h, "application %v\n", app.Name)
+	spec, err := json.Marshal(app)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "spec %s\n", spec)
+
+	var repoRef *kfconfig.RepoRef
+	if app.KustomizeConfig != nil {
+		repoRef = app.KustomizeConfig.RepoRef
+	} else if app.HelmConfig != nil {
+		repoRef = app.HelmConfig.RepoRef
+	}
+	if repoRef != nil {
+		digest, err := kustomize.repoDigest(repoRef.Name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "repo %v %v\n", repoRef.Name, digest)
+	}
+	if err := hashDir(h, path.Join(kustomize.kfDef.Spec.AppDir, outputDir, app.Name)); err != nil {
+		return "", err
+	}
+
+	overrides, err := json.Marshal(struct {
+		Images                []kfconfig.Image          `json:"images"`
+		ProxyEnv              map[string]string         `json:"proxyEnv"`
+		Annotations           map[string]string         `json:"annotations"`
+		Scheduling            *kfconfig.Scheduling      `json:"scheduling"`
+		Availability          *kfconfig.Availability    `json:"availability"`
+		ServiceMesh           kfconfig.ManagementState  `json:"serviceMesh"`
+		Auth                  kfconfig.AuthProvider     `json:"auth"`
+		Kubernetes            bool                      `json:"kubernetes"`
+		FeatureGates          string                    `json:"featureGates"`
+		PodSecurity           kfconfig.PodSecurityLevel `json:"podSecurity"`
+		NamespacePodSecurity  kfconfig.PodSecurityLevel `json:"namespacePodSecurity"`
+		ApplicationsNamespace string                    `json:"applicationsNamespace"`
+		FIPS                  bool                      `json:"fips"`
+		NodeArchitectures     []string                  `json:"nodeArchitectures"`
+		IPFamilyPolicy        kfconfig.IPFamilyPolicy   `json:"ipFamilyPolicy"`
+		PriorityClass         string                    `json:"priorityClass"`
+	}{kustomize.images, kustomize.proxyEnv, kustomize.kfDef.GetAnnotations(), kustomize.kfDef.Spec.Scheduling,
+		kustomize.kfDef.Spec.Availability, kustomize.kfDef.Spec.ServiceMesh, kustomize.kfDef.Spec.Auth, kustomize.kubernetes,
+		kustomize.featureGates().String(), appPodSecurity(&kustomize.kfDef.Spec, app), namespacePodSecurity(&kustomize.kfDef.Spec),
+		operatorconfig.Current().ApplicationsNamespace, IsFIPSEnabled(), sortedArchitectures(kustomize.nodeArchitectures),
+		kustomize.kfDef.Spec.IPFamilyPolicy, operatorconfig.Current().PriorityClasses.ControlPlane.Name})
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "overrides %s\n", overrides)
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// repoDigest returns the digest of the files of the cache of repo name
+func (kustomize *kustomize) repoDigest(name string) (string, error) {
+	if digest, ok := kustomize.repoDigests[name]; ok {
+		return digest, nil
+	}
+	cache, ok := kustomize.kfDef.GetRepoCache(name)
+	if !ok {
+		return "", fmt.Errorf("repo %v wasn't found in KfDef.Status.ReposCache", name)
+	}
+	h := sha256.New()
+	if err := hashDir(h, cache.LocalPath); err != nil {
+		return "", err
+	}
+	if kustomize.repoDigests == nil {
+		kustomize.repoDigests = map[string]string{}
+	}
+	kustomize.repoDigests[name] = fmt.Sprintf("sha256:%x", h.Sum(nil))
+	return kustomize.repoDigests[name], nil
+}
+
+// hashDir writes the path and the content of the regular files of dir to h, in lexical order
+func hashDir(h hash.Hash, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "file %v %d\n", filepath.ToSlash(rel), info.Size())
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// renderCacheID returns the id of the render cache entry of app
+func (kustomize *kustomize) renderCacheID(app string) string {
+	return strings.Join([]string{kustomize.kfDef.Namespace, kustomize.kfDef.Name, app}, "/")
+}
+
+// renderCachePath returns the file the render cache entry of app is kept in
+func (kustomize *kustomize) renderCachePath(app string) string {
+	return path.Join(RenderCacheDir, kustomize.kfDef.Namespace, kustomize.kfDef.Name, app+".json")
+}
+
+// cachedRender returns the entry of app if it was applied from the manifests of key, within RenderCacheTTL and since
+// the cache was last invalidated. The entry is read from the app dir when it is not in memory.
+func (kustomize *kustomize) cachedRender(app string, key string) (*renderCacheEntry, bool) {
+	renderCache.Lock()
+	defer renderCache.Unlock()
+	id := kustomize.renderCacheID(app)
+	entry, ok := renderCache.entries[id]
+	if !ok {
+		data, err := ioutil.ReadFile(kustomize.renderCachePath(app))
+		if err != nil {
+			return nil, false
+		}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Warnf("Ignoring the invalid render cache entry of %v: %v", app, err)
+			return nil, false
+		}
+		renderCache.entries[id] = entry
+	}
+	invalidated := renderCache.invalidated[kustomize.kfDef.Namespace+"/"+kustomize.kfDef.Name]
+	// The entries kept before the rendered manifests were cannot restore them
+	if entry.Key != key || entry.Rendered == nil || !entry.AppliedAt.After(invalidated) || time.Since(entry.AppliedAt) > RenderCacheTTL {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// storeRender records that app was applied from the manifests of key, rendered as data. appliedAt is when the apply
+// started, so that the changes made to the resources while they were applied invalidate the entry.
+func (kustomize *kustomize) storeRender(app string, key string, data []byte, relatedImages map[string]string,
+	grants []kfconfig.SCCGrant, appliedAt time.Time) {
+	entry := renderCacheEntry{Key: key, Rendered: data, RelatedImages: relatedImages, SecurityContextConstraints: grants,
+		AppliedAt: appliedAt}
+	renderCache.Lock()
+	renderCache.entries[kustomize.renderCacheID(app)] = entry
+	renderCache.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		cachePath := kustomize.renderCachePath(app)
+		if err = os.MkdirAll(path.Dir(cachePath), 0755); err == nil {
+			err = ioutil.WriteFile(cachePath, data, 0644)
+		}
+	}
+	if err != nil {
+		log.Warnf("Failed to keep the render cache entry of %v: %v", app, err)
+	}
+}
+
+// forgetRender removes the entry of app, so that it is rendered and applied again
+func (kustomize *kustomize) forgetRender(app string) {
+	renderCache.Lock()
+	delete(renderCache.entries, kustomize.renderCacheID(app))
+	renderCache.Unlock()
+	if err := os.Remove(kustomize.renderCachePath(app)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove the render cache entry of %v: %v", app, err)
+	}
+}
+
+// ForgetRenders removes the entries of every application of the KfDef namespace/name, once it is deleted
+func ForgetRenders(namespace string, name string) {
+	prefix := namespace + "/" + name + "/"
+	renderCache.Lock()
+	for id := range renderCache.entries {
+		if strings.HasPrefix(id, prefix) {
+			delete(renderCache.entries, id)
+		}
+	}
+	delete(renderCache.invalidated, namespace+"/"+name)
+	renderCache.Unlock()
+	if err := os.RemoveAll(path.Join(RenderCacheDir, namespace, name)); err != nil {
+		log.Warnf("Failed to remove the render cache entries of KfDef %v/%v: %v", namespace, name, err)
+	}
+}
@@ -0,0 +1,98 @@
+package kustomize
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// renderedIndex holds the resources of the manifests applied for every application, by <namespace>/<kfdef>, then
+// by application and resource key. It is filled once per apply, so that the drift controllers find the manifest of
+// a resource without reading the manifests of every KfDef.
+var renderedIndex = struct {
+	sync.RWMutex
+	kfdefs map[string]map[string]map[string]*unstructured.Unstructured
+}{
+	kfdefs: map[string]map[string]map[string]*unstructured.Unstructured{},
+}
+
+// renderedKey identifies a resource of the manifests by group, kind, namespace and name
+func renderedKey(group, kind, namespace, name string) string {
+	return strings.Join([]string{group, kind, namespace, name}, "/")
+}
+
+// IndexRenderedManifests replaces the resources indexed for app of the KfDef namespace/name with the ones of data
+func IndexRenderedManifests(namespace string, name string, app string, data []byte) error {
+	manifests, err := utils.SplitYAML(data)
+	if err != nil {
+		return err
+	}
+	resources := map[string]*unstructured.Unstructured{}
+	for _, manifest := range manifests {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(manifest, &u.Object); err != nil {
+			return err
+		}
+		gvk := u.GroupVersionKind()
+		if gvk.Kind == "" {
+			continue
+		}
+		resources[renderedKey(gvk.Group, gvk.Kind, u.GetNamespace(), u.GetName())] = u
+	}
+
+	renderedIndex.Lock()
+	defer renderedIndex.Unlock()
+	kfdef := namespace + "/" + name
+	if renderedIndex.kfdefs[kfdef] == nil {
+		renderedIndex.kfdefs[kfdef] = map[string]map[string]*unstructured.Unstructured{}
+	}
+	renderedIndex.kfdefs[kfdef][app] = resources
+	return nil
+}
+
+// ForgetRenderedManifests removes the resources indexed for the KfDef namespace/name, before it is applied again or
+// once it is deleted
+func ForgetRenderedManifests(namespace string, name string) {
+	renderedIndex.Lock()
+	defer renderedIndex.Unlock()
+	delete(renderedIndex.kfdefs, namespace+"/"+name)
+}
+
+// FindRenderedResource looks up the resource of kind gvk and key in the manifests applied by every KfDef of the
+// operator and returns a copy of it, along with the KfDef and the application it belongs to. Resources without a
+// namespace belong to the namespace of the KfDef. It returns nil if the resource was not applied by a KfDef.
+func FindRenderedResource(gvk schema.GroupVersionKind, key k8stypes.NamespacedName) (*unstructured.Unstructured, k8stypes.NamespacedName, string) {
+	renderedIndex.RLock()
+	defer renderedIndex.RUnlock()
+	for kfdef, apps := range renderedIndex.kfdefs {
+		parts := strings.SplitN(kfdef, "/", 2)
+		kfdefKey := k8stypes.NamespacedName{Namespace: parts[0], Name: parts[1]}
+		for app, resources := range apps {
+			if u, ok := resources[renderedKey(gvk.Group, gvk.Kind, key.Namespace, key.Name)]; ok {
+				return u.DeepCopy(), kfdefKey, app
+			}
+			if key.Namespace == "" || key.Namespace != kfdefKey.Namespace {
+				continue
+			}
+			if u, ok := resources[renderedKey(gvk.Group, gvk.Kind, "", key.Name)]; ok {
+				u = u.DeepCopy()
+				u.SetNamespace(kfdefKey.Namespace)
+				return u, kfdefKey, app
+			}
+		}
+	}
+	return nil, k8stypes.NamespacedName{}, ""
+}
+
+// indexRenderedManifests indexes the manifests applied for app, logging the manifests that cannot be parsed
+func (kustomize *kustomize) indexRenderedManifests(app string, data []byte) {
+	if err := IndexRenderedManifests(kustomize.kfDef.Namespace, kustomize.kfDef.Name, app, data); err != nil {
+		log.Warnf("Failed to index the manifests applied for %v, its resources are not restored: %v", app, err)
+	}
+}
@@ -0,0 +1,110 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+)
+
+// SecurityContextConstraintsGVK is the kind of the OpenShift SecurityContextConstraints
+var SecurityContextConstraintsGVK = schema.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+
+// sccProfiles are the strategies of the users and the groups of the SecurityContextConstraints profiles the
+// ServiceAccounts of the manifests can be granted. The profiles only relax the users the pods run as, the other
+// fields are the ones of the restricted-v2 SecurityContextConstraints of OpenShift.
+var sccProfiles = map[string]map[string]string{
+	// anyuid lets the pods run as any user, root included, e.g. for images requiring a fixed user
+	"anyuid": {"runAsUser": "RunAsAny", "fsGroup": "RunAsAny"},
+	// nonroot lets the pods run as any user but root, instead of a user of the range of their namespace
+	"nonroot": {"runAsUser": "MustRunAsNonRoot", "fsGroup": "RunAsAny"},
+}
+
+// sccName returns the name of the SecurityContextConstraints granted to a ServiceAccount
+func sccName(namespace string, serviceAccount string) string {
+	return strings.Join([]string{"odh", namespace, serviceAccount}, "-")
+}
+
+// grantSecurityContextConstraints adds to resMap a SecurityContextConstraints for every ServiceAccount of resMap
+// annotated with kfctl.kubeflow.io/scc, with the profile the annotation names and only granted to the ServiceAccount.
+// The ServiceAccounts without a namespace are in namespace. It returns the SecurityContextConstraints granted.
+func grantSecurityContextConstraints(resMap resmap.ResMap, app string, namespace string) ([]kfconfig.SCCGrant, error) {
+	grants := []kfconfig.SCCGrant{}
+	sccs := []*resource.Resource{}
+	factory := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != "ServiceAccount" {
+			continue
+		}
+		profile := res.GetAnnotations()[strings.Join([]string{utils.KfDefAnnotation, utils.SCC}, "/")]
+		if profile == "" {
+			continue
+		}
+		strategies, ok := sccProfiles[profile]
+		if !ok {
+			profiles := []string{}
+			for p := range sccProfiles {
+				profiles = append(profiles, p)
+			}
+			sort.Strings(profiles)
+			return nil, &kfapisv3.KfError{
+				Code: int(kfapisv3.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("ServiceAccount %v requests the unknown SecurityContextConstraints profile %v, must be one of %v",
+					res.GetName(), profile, strings.Join(profiles, ", ")),
+			}
+		}
+		saNamespace := res.GetNamespace()
+		if saNamespace == "" {
+			saNamespace = namespace
+		}
+		name := sccName(saNamespace, res.GetName())
+		sccs = append(sccs, factory.FromMap(map[string]interface{}{
+			"apiVersion":               SecurityContextConstraintsGVK.GroupVersion().String(),
+			"kind":                     SecurityContextConstraintsGVK.Kind,
+			"metadata":                 map[string]interface{}{"name": name},
+			"allowHostDirVolumePlugin": false,
+			"allowHostIPC":             false,
+			"allowHostNetwork":         false,
+			"allowHostPID":             false,
+			"allowHostPorts":           false,
+			"allowPrivilegeEscalation": false,
+			"allowPrivilegedContainer": false,
+			"allowedCapabilities":      []interface{}{"NET_BIND_SERVICE"},
+			"requiredDropCapabilities": []interface{}{"ALL"},
+			"readOnlyRootFilesystem":   false,
+			"runAsUser":                map[string]interface{}{"type": strategies["runAsUser"]},
+			"fsGroup":                  map[string]interface{}{"type": strategies["fsGroup"]},
+			"seLinuxContext":           map[string]interface{}{"type": "MustRunAs"},
+			"supplementalGroups":       map[string]interface{}{"type": "RunAsAny"},
+			"seccompProfiles":          []interface{}{"runtime/default"},
+			"volumes": []interface{}{"configMap", "csi", "downwardAPI", "emptyDir", "ephemeral",
+				"persistentVolumeClaim", "projected", "secret"},
+			"users":  []interface{}{fmt.Sprintf("system:serviceaccount:%v:%v", saNamespace, res.GetName())},
+			"groups": []interface{}{},
+		}))
+		grants = append(grants, kfconfig.SCCGrant{
+			Application:    app,
+			ServiceAccount: saNamespace + "/" + res.GetName(),
+			Profile:        profile,
+			Name:           name,
+		})
+		log.Infof("Granting the %v SecurityContextConstraints %v to ServiceAccount %v/%v.", profile, name, saNamespace, res.GetName())
+	}
+	for _, scc := range sccs {
+		if err := resMap.Append(scc); err != nil {
+			return nil, &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to add SecurityContextConstraints %v: %v", scc.GetName(), err),
+			}
+		}
+	}
+	return grants, nil
+}
@@ -0,0 +1,138 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// podSpecPaths are the paths of the pod specs of the kinds of resources the scheduling is injected into
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"DeploymentConfig":      {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// mergeScheduling returns the scheduling of an application: the fields set by the scheduling of the application
+// replace the ones of the scheduling of the spec.
+func mergeScheduling(spec *kfconfig.Scheduling, app *kfconfig.Scheduling) *kfconfig.Scheduling {
+	if spec == nil || app == nil {
+		if app != nil {
+			return app
+		}
+		return spec
+	}
+	merged := *spec
+	if app.NodeSelector != nil {
+		merged.NodeSelector = app.NodeSelector
+	}
+	if app.Tolerations != nil {
+		merged.Tolerations = app.Tolerations
+	}
+	if app.Affinity != nil {
+		merged.Affinity = app.Affinity
+	}
+	return &merged
+}
+
+// injectScheduling injects the scheduling into the pod templates of the resources of resMap
+func injectScheduling(resMap resmap.ResMap, scheduling *kfconfig.Scheduling) error {
+	if scheduling == nil {
+		return nil
+	}
+	for _, res := range resMap.Resources() {
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: res.Map()}
+		if err := schedulePodSpec(obj, path, scheduling); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to inject the scheduling into %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		res.SetMap(obj.Object)
+	}
+	return nil
+}
+
+// schedulePodSpec adds the node selector and the tolerations of scheduling to the pod spec of obj at path, and
+// replaces its node affinity, pod affinity and pod anti-affinity with the ones scheduling sets
+func schedulePodSpec(obj *unstructured.Unstructured, path []string, scheduling *kfconfig.Scheduling) error {
+	field, found, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+	if err != nil || !found {
+		return err
+	}
+	podSpec, ok := field.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%v is not an object", path)
+	}
+
+	if len(scheduling.NodeSelector) > 0 {
+		nodeSelector, _ := podSpec["nodeSelector"].(map[string]interface{})
+		if nodeSelector == nil {
+			nodeSelector = map[string]interface{}{}
+		}
+		for k, v := range scheduling.NodeSelector {
+			nodeSelector[k] = v
+		}
+		podSpec["nodeSelector"] = nodeSelector
+	}
+
+	if len(scheduling.Tolerations) > 0 {
+		tolerations, _ := podSpec["tolerations"].([]interface{})
+		present := map[string]bool{}
+		for _, t := range tolerations {
+			key, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			present[string(key)] = true
+		}
+		for i := range scheduling.Tolerations {
+			t, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&scheduling.Tolerations[i])
+			if err != nil {
+				return err
+			}
+			key, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			if !present[string(key)] {
+				present[string(key)] = true
+				tolerations = append(tolerations, t)
+			}
+		}
+		podSpec["tolerations"] = tolerations
+	}
+
+	if scheduling.Affinity != nil {
+		configured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(scheduling.Affinity)
+		if err != nil {
+			return err
+		}
+		affinity, _ := podSpec["affinity"].(map[string]interface{})
+		if affinity == nil {
+			affinity = map[string]interface{}{}
+		}
+		for k, v := range configured {
+			affinity[k] = v
+		}
+		if len(affinity) > 0 {
+			podSpec["affinity"] = affinity
+		}
+	}
+	return nil
+}
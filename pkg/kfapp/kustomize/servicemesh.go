@@ -0,0 +1,39 @@
+package kustomize
+
+import (
+	"fmt"
+
+	kfapisv3 "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+)
+
+// SidecarInjectAnnotation makes the Service Mesh inject its sidecar in the pods of the namespaces of the mesh
+const SidecarInjectAnnotation = "sidecar.istio.io/inject"
+
+// injectSidecars annotates the pod templates of the resources of resMap for the Service Mesh to inject its sidecar
+// in their pods. The pod templates already setting the annotation, e.g. to opt out of the mesh, keep it.
+func injectSidecars(resMap resmap.ResMap) error {
+	for _, res := range resMap.Resources() {
+		path, ok := podSpecPaths[res.GetKind()]
+		if !ok {
+			continue
+		}
+		obj := res.Map()
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj, path...); !found {
+			continue
+		}
+		annotation := append(append([]string{}, path[:len(path)-1]...), "metadata", "annotations", SidecarInjectAnnotation)
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj, annotation...); found {
+			continue
+		}
+		if err := unstructured.SetNestedField(obj, "true", annotation...); err != nil {
+			return &kfapisv3.KfError{
+				Code:    int(kfapisv3.INTERNAL_ERROR),
+				Message: fmt.Sprintf("failed to inject the sidecar into %v %v: %v", res.GetKind(), res.GetName(), err),
+			}
+		}
+		res.SetMap(obj)
+	}
+	return nil
+}
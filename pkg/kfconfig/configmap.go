@@ -0,0 +1,115 @@
+package kfconfig
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kftypesv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	"github.com/kubeflow/kfctl/v3/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapScheme is the scheme of the repo URIs pointing to a ConfigMap holding a gzipped tarball of the manifests,
+// e.g. configmap://opendatahub/odh-manifests?key=manifests.tar.gz. It lets disconnected clusters install
+// without fetching the manifests from the network.
+const ConfigMapScheme = "configmap"
+
+// ParseConfigMapURI returns the namespace, the name and the binaryData key of the ConfigMap uri points to.
+// The key is empty when the ConfigMap is expected to hold a single binaryData key.
+func ParseConfigMapURI(uri string) (string, string, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != ConfigMapScheme {
+		return "", "", "", fmt.Errorf("%v is not a %v:// uri", uri, ConfigMapScheme)
+	}
+	name := strings.Trim(u.Path, "/")
+	if u.Host == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", "", fmt.Errorf("%v must be of the form %v://<namespace>/<name>[?key=<key>]", uri, ConfigMapScheme)
+	}
+	return u.Host, name, u.Query().Get("key"), nil
+}
+
+// configMapTarball returns the tarball stored under key in the binaryData of cm,
+// or its only binaryData entry if key is empty
+func configMapTarball(cm *v1.ConfigMap, key string) ([]byte, error) {
+	if key == "" {
+		if len(cm.BinaryData) != 1 {
+			return nil, fmt.Errorf("ConfigMap %v/%v has %v binaryData keys, set the key with ?key=<key>",
+				cm.Namespace, cm.Name, len(cm.BinaryData))
+		}
+		for _, data := range cm.BinaryData {
+			return data, nil
+		}
+	}
+	data, ok := cm.BinaryData[key]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %v/%v has no binaryData key %v", cm.Namespace, cm.Name, key)
+	}
+	return data, nil
+}
+
+// fetchConfigMap extracts the manifests tarball of the ConfigMap the URI of r points to into cacheDir.
+// The signature of the tarball is verified with v, if set, against the signature stored under the key of
+// the tarball suffixed with SignatureSuffix in the data of the ConfigMap.
+func fetchConfigMap(r Repo, cacheDir string, v *cosign.Verifier) error {
+	uri := r.URI
+	namespace, name, key, err := ParseConfigMapURI(uri)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: err.Error(),
+		}
+	}
+	config := kftypesv3.GetConfig()
+	if config == nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't load the cluster config to fetch %v", uri),
+		}
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't create a client to fetch %v: %v", uri, err),
+		}
+	}
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't get the ConfigMap %v/%v: %v", namespace, name, err),
+		}
+	}
+	data, err := configMapTarball(cm, key)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: err.Error(),
+		}
+	}
+	if v != nil {
+		if key == "" {
+			for k := range cm.BinaryData {
+				key = k
+			}
+		}
+		signature, ok := cm.Data[key+SignatureSuffix]
+		if !ok {
+			return unverified(r, fmt.Errorf("ConfigMap %v/%v has no %v key", namespace, name, key+SignatureSuffix))
+		}
+		if err := v.VerifyBlob(data, []byte(signature), []byte(cm.Data[key+CertificateSuffix]), nil,
+			[]byte(cm.Data[key+BundleSuffix])); err != nil {
+			return unverified(r, err)
+		}
+		log.Infof("Signature of repo %v verified", r.Name)
+	}
+	return untar(data, cacheDir)
+}
@@ -0,0 +1,64 @@
+package kfconfig
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigMapTarball(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Data     map[string][]byte
+		Key      string
+		Expected string
+		Err      bool
+	}
+
+	testCases := []testCase{
+		{
+			Name:     "single-key",
+			Data:     map[string][]byte{"manifests.tar.gz": []byte("manifests")},
+			Expected: "manifests",
+		},
+		{
+			Name:     "explicit-key",
+			Data:     map[string][]byte{"a.tar.gz": []byte("a"), "b.tar.gz": []byte("b")},
+			Key:      "b.tar.gz",
+			Expected: "b",
+		},
+		{
+			Name: "ambiguous-key",
+			Data: map[string][]byte{"a.tar.gz": []byte("a"), "b.tar.gz": []byte("b")},
+			Err:  true,
+		},
+		{
+			Name: "missing-key",
+			Data: map[string][]byte{"a.tar.gz": []byte("a")},
+			Key:  "b.tar.gz",
+			Err:  true,
+		},
+	}
+
+	for _, c := range testCases {
+		cm := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "odh-manifests", Namespace: "opendatahub"},
+			BinaryData: c.Data,
+		}
+		data, err := configMapTarball(cm, c.Key)
+		if c.Err {
+			if err == nil {
+				t.Errorf("Case %v: expected an error", c.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Case %v: unexpected error: %v", c.Name, err)
+			continue
+		}
+		if string(data) != c.Expected {
+			t.Errorf("Case %v: got %v; want %v", c.Name, string(data), c.Expected)
+		}
+	}
+}
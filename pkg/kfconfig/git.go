@@ -0,0 +1,284 @@
+package kfconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	kftypesv3 "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	log "github.com/sirupsen/logrus"
+	git "gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// GitPrefix forces a repo URI to be fetched with git, as with go-getter
+	GitPrefix = "git::"
+
+	// lastFetchFile is touched in the clones every time they are fetched
+	lastFetchFile = "kfctl-last-fetch"
+)
+
+var (
+	// GitCacheDir is the directory the git repos are cloned in. The clones are shared by the KfDefs
+	// and kept across reconciliations.
+	GitCacheDir = "/tmp/.git-cache"
+	// GitFetchInterval is how long the clone of a git repo is used before being fetched again
+	GitFetchInterval = 5 * time.Minute
+
+	// gitMutex serializes the operations on the clones
+	gitMutex sync.Mutex
+)
+
+// ParseGitURI returns the URL to clone and the ref of a repo URI pointing to a git repo, and false if it does not.
+// Git repo URIs are prefixed with git::, e.g. git::https://github.com/opendatahub-io/odh-manifests?ref=v1.1.0,
+// use the ssh scheme or the scp-like syntax of ssh, e.g. git@github.com:opendatahub-io/odh-manifests.git,
+// or end with .git. The ref is the branch, tag or commit of the ref query parameter, if any.
+func ParseGitURI(uri string) (string, string, bool) {
+	forced := strings.HasPrefix(uri, GitPrefix)
+	uri = strings.TrimPrefix(uri, GitPrefix)
+
+	// scp-like syntax, user@host:path
+	if !strings.Contains(uri, "://") && strings.Contains(uri, "@") && strings.Contains(uri, ":") {
+		ref := ""
+		if idx := strings.Index(uri, "?ref="); idx > 0 {
+			uri, ref = uri[:idx], uri[idx+len("?ref="):]
+		}
+		return uri, ref, true
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", false
+	}
+	if !forced && u.Scheme != "ssh" && u.Scheme != "git" && !strings.HasSuffix(u.Path, ".git") {
+		return "", "", false
+	}
+	if u.Scheme == "" || u.Scheme == "file" && !forced {
+		return "", "", false
+	}
+	query := u.Query()
+	ref := query.Get("ref")
+	query.Del("ref")
+	u.RawQuery = query.Encode()
+	return u.String(), ref, true
+}
+
+// fetchGit extracts the ref of the git repo at gitURL into cacheDir. The repo is cloned in GitCacheDir and
+// fetched again when its clone is older than GitFetchInterval. The credentials are read from the Secret
+// secretName of namespace, if set. The clone and the fetch are cancelled once ctx is done.
+func fetchGit(ctx context.Context, gitURL string, ref string, namespace string, secretName string, cacheDir string) error {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	cloneDir := filepath.Join(GitCacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(gitURL)))[:16])
+	auth, err := gitAuth(gitURL, namespace, secretName, cloneDir)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.PlainOpen(cloneDir)
+	if err == git.ErrRepositoryNotExists {
+		log.Infof("Cloning %v to %v", gitURL, cloneDir)
+		repo, err = git.PlainCloneContext(ctx, cloneDir, true, &git.CloneOptions{URL: gitURL, Auth: auth, Tags: git.AllTags})
+		if err != nil {
+			os.RemoveAll(cloneDir)
+			return &kfapis.KfError{
+				Code:    int(kfapis.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("couldn't clone %v: %v", gitURL, err),
+			}
+		}
+		touch(filepath.Join(cloneDir, lastFetchFile))
+	} else if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't open the clone of %v: %v", gitURL, err),
+		}
+	} else if fi, err := os.Stat(filepath.Join(cloneDir, lastFetchFile)); err != nil || time.Since(fi.ModTime()) > GitFetchInterval {
+		log.Infof("Fetching %v in %v", gitURL, cloneDir)
+		err := repo.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"},
+			Auth:     auth,
+			Tags:     git.AllTags,
+			Force:    true,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return &kfapis.KfError{
+				Code:    int(kfapis.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("couldn't fetch %v: %v", gitURL, err),
+			}
+		}
+		touch(filepath.Join(cloneDir, lastFetchFile))
+	}
+
+	commit, err := resolveRef(repo, ref)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't resolve ref %q of %v: %v", ref, gitURL, err),
+		}
+	}
+	log.Infof("Extracting commit %v of %v to %v", commit.Hash, gitURL, cacheDir)
+	if err := extractCommit(commit, cacheDir); err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't extract commit %v of %v: %v", commit.Hash, gitURL, err),
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the commit of a remote branch, a tag or a commit SHA of repo. The default branch is used
+// when ref is empty.
+func resolveRef(repo *git.Repository, ref string) (*object.Commit, error) {
+	if ref == "" {
+		head, err := repo.Reference(plumbing.HEAD, false)
+		if err != nil {
+			return nil, err
+		}
+		ref = head.Target().Short()
+	}
+	for _, rev := range []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref} {
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err == nil {
+			return repo.CommitObject(*hash)
+		}
+	}
+	return nil, plumbing.ErrReferenceNotFound
+}
+
+// extractCommit writes the files of commit to dir
+func extractCommit(commit *object.Commit, dir string) error {
+	files, err := commit.Files()
+	if err != nil {
+		return err
+	}
+	return files.ForEach(func(f *object.File) error {
+		target := filepath.Join(dir, f.Name)
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		reader, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		if f.Mode == filemode.Symlink {
+			link, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(string(link), target)
+		}
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, reader)
+		return err
+	})
+}
+
+// gitAuth returns the credentials of the Secret secretName of namespace for gitURL, or nil if secretName is empty.
+// The Secret holds username and password for https URLs, or ssh-privatekey and optionally known_hosts for ssh URLs,
+// as the kubernetes.io/basic-auth and kubernetes.io/ssh-auth Secrets do.
+func gitAuth(gitURL string, namespace string, secretName string, cloneDir string) (transport.AuthMethod, error) {
+	if secretName == "" {
+		return nil, nil
+	}
+	secret, err := getSecret(namespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := secret.Data["ssh-privatekey"]; ok {
+		auth, err := gitssh.NewPublicKeys("git", key, "")
+		if err != nil {
+			return nil, &kfapis.KfError{
+				Code:    int(kfapis.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("invalid ssh-privatekey in Secret %v/%v: %v", namespace, secretName, err),
+			}
+		}
+		if u, err := url.Parse(gitURL); err == nil && u.User != nil {
+			auth.User = u.User.Username()
+		} else if idx := strings.Index(gitURL, "@"); idx > 0 && !strings.Contains(gitURL, "://") {
+			auth.User = gitURL[:idx]
+		}
+		if knownHosts, ok := secret.Data["known_hosts"]; ok {
+			file := cloneDir + ".known_hosts"
+			if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+				return nil, err
+			}
+			if err := ioutil.WriteFile(file, knownHosts, 0600); err != nil {
+				return nil, err
+			}
+			if auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(file); err != nil {
+				return nil, &kfapis.KfError{
+					Code:    int(kfapis.INVALID_ARGUMENT),
+					Message: fmt.Sprintf("invalid known_hosts in Secret %v/%v: %v", namespace, secretName, err),
+				}
+			}
+		}
+		return auth, nil
+	}
+	return &githttp.BasicAuth{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
+
+// getSecret returns the Secret name of namespace holding the credentials of a repo
+func getSecret(namespace string, name string) (*v1.Secret, error) {
+	config := kftypesv3.GetConfig()
+	if config == nil {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't load the cluster config to read Secret %v/%v", namespace, name),
+		}
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't create a client to read Secret %v/%v: %v", namespace, name, err),
+		}
+	}
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't get the credentials of Secret %v/%v: %v", namespace, name, err),
+		}
+	}
+	return secret, nil
+}
+
+// touch sets the modification time of file to now, creating it if needed
+func touch(file string) {
+	if err := ioutil.WriteFile(file, []byte(time.Now().UTC().Format(time.RFC3339)), 0644); err != nil {
+		log.Warnf("Couldn't write %v: %v", file, err)
+	}
+}
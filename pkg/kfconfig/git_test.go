@@ -0,0 +1,127 @@
+package kfconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestParseGitURI(t *testing.T) {
+	type testCase struct {
+		URI   string
+		URL   string
+		Ref   string
+		IsGit bool
+	}
+
+	testCases := []testCase{
+		{
+			URI:   "git::https://github.com/opendatahub-io/odh-manifests?ref=v1.1.0",
+			URL:   "https://github.com/opendatahub-io/odh-manifests",
+			Ref:   "v1.1.0",
+			IsGit: true,
+		},
+		{
+			URI:   "https://github.com/opendatahub-io/odh-manifests.git",
+			URL:   "https://github.com/opendatahub-io/odh-manifests.git",
+			IsGit: true,
+		},
+		{
+			URI:   "ssh://git@github.com/opendatahub-io/odh-manifests.git?ref=master",
+			URL:   "ssh://git@github.com/opendatahub-io/odh-manifests.git",
+			Ref:   "master",
+			IsGit: true,
+		},
+		{
+			URI:   "git@github.com:opendatahub-io/odh-manifests.git?ref=0123abc",
+			URL:   "git@github.com:opendatahub-io/odh-manifests.git",
+			Ref:   "0123abc",
+			IsGit: true,
+		},
+		{
+			URI:   "git::file:///tmp/manifests",
+			URL:   "file:///tmp/manifests",
+			IsGit: true,
+		},
+		{
+			URI: "https://github.com/opendatahub-io/odh-manifests/tarball/v1.1.0",
+		},
+		{
+			URI: "file:///tmp/manifests.git",
+		},
+		{
+			URI: "/tmp/manifests",
+		},
+	}
+
+	for _, c := range testCases {
+		url, ref, isGit := ParseGitURI(c.URI)
+		if isGit != c.IsGit || url != c.URL || ref != c.Ref {
+			t.Errorf("%v: got (%q, %q, %v), want (%q, %q, %v)", c.URI, url, ref, isGit, c.URL, c.Ref, c.IsGit)
+		}
+	}
+}
+
+func TestFetchGit(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "fetch-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	GitCacheDir = filepath.Join(tmp, "clones")
+
+	// a repo with a commit tagged v1 and a later commit on master
+	origin := filepath.Join(tmp, "origin")
+	repo, err := git.PlainInit(origin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(content string) {
+		if err := os.MkdirAll(filepath.Join(origin, "app", "base"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(origin, "app", "base", "kustomization.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("app"); err != nil {
+			t.Fatal(err)
+		}
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		if _, err := worktree.Commit(content, &git.CommitOptions{Author: signature}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("v1")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	commit("v2")
+
+	for ref, expected := range map[string]string{"": "v2", "master": "v2", "v1": "v1", head.Hash().String(): "v1"} {
+		cacheDir := filepath.Join(tmp, "cache", ref)
+		if err := fetchGit(context.Background(), "file://"+origin, ref, "", "", cacheDir); err != nil {
+			t.Fatalf("ref %q: %v", ref, err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(cacheDir, "app", "base", "kustomization.yaml"))
+		if err != nil {
+			t.Fatalf("ref %q: %v", ref, err)
+		}
+		if string(content) != expected {
+			t.Errorf("ref %q: got %q, want %q", ref, content, expected)
+		}
+	}
+}
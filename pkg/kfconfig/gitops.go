@@ -0,0 +1,299 @@
+package kfconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/openpgp"
+	git "gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const (
+	// DefaultGitOpsBranch is the branch the manifests are pushed to when the GitOps spec does not set one
+	DefaultGitOpsBranch = "main"
+
+	// signingKeyKey and signingKeyPassphraseKey are the entries of the Secret of the GitOps repository holding the
+	// OpenPGP key signing the commits
+	signingKeyKey           = "signing-key"
+	signingKeyPassphraseKey = "signing-key-passphrase"
+)
+
+var (
+	// GitOpsAuthorName and GitOpsAuthorEmail are the author of the commits of the manifests, unless they are signed,
+	// in which case the identity of the signing key is used
+	GitOpsAuthorName  = "Open Data Hub Operator"
+	GitOpsAuthorEmail = "opendatahub-operator@opendatahub.io"
+)
+
+// PushManifests writes the manifests of every application of files, by name, to <path>/<application>.yaml in the
+// branch of the GitOps repository, and pushes them in a commit with message. The directory is owned by the KfDef
+// name of namespace: the files of the applications it no longer has are removed, those of the applications whose
+// manifests are nil are left as they are. It returns the hash of the commit of the branch holding the manifests,
+// which is not created when they did not change.
+func PushManifests(gitOps *GitOps, namespace string, name string, files map[string][]byte, message string) (string, error) {
+	gitURL := strings.TrimPrefix(gitOps.URI, GitPrefix)
+	if u, _, ok := ParseGitURI(gitOps.URI); ok {
+		gitURL = u
+	}
+	branch := gitOps.Branch
+	if branch == "" {
+		branch = DefaultGitOpsBranch
+	}
+	dir := gitOps.Path
+	if dir == "" {
+		dir = path.Join(namespace, name)
+	}
+	dir = path.Clean(strings.Trim(dir, "/"))
+	if dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("invalid path %q of the GitOps repository %v", gitOps.Path, gitURL),
+		}
+	}
+
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	cloneDir := filepath.Join(GitCacheDir, "gitops", fmt.Sprintf("%x", sha256.Sum256([]byte(gitURL+"#"+branch)))[:16])
+	auth, err := gitAuth(gitURL, namespace, gitOps.SecretName, cloneDir)
+	if err != nil {
+		return "", err
+	}
+	var signKey *openpgp.Entity
+	if gitOps.SecretName != "" {
+		secret, err := getSecret(namespace, gitOps.SecretName)
+		if err != nil {
+			return "", err
+		}
+		if key, ok := secret.Data[signingKeyKey]; ok {
+			if signKey, err = readSigningKey(key, secret.Data[signingKeyPassphraseKey]); err != nil {
+				return "", &kfapis.KfError{
+					Code:    int(kfapis.INVALID_ARGUMENT),
+					Message: fmt.Sprintf("invalid %v in Secret %v/%v: %v", signingKeyKey, namespace, gitOps.SecretName, err),
+				}
+			}
+		}
+	}
+	return pushManifests(gitURL, branch, dir, cloneDir, auth, signKey, files, message)
+}
+
+// pushManifests commits the files of the applications to dir in the branch of the clone of gitURL in cloneDir, and
+// pushes the branch
+func pushManifests(gitURL string, branch string, dir string, cloneDir string, auth transport.AuthMethod,
+	signKey *openpgp.Entity, files map[string][]byte, message string) (string, error) {
+	repo, err := openGitOpsClone(gitURL, cloneDir, auth)
+	if err != nil {
+		return "", err
+	}
+	if err := checkoutGitOpsBranch(repo, branch, auth); err != nil {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't check out branch %v of %v: %v", branch, gitURL, err),
+		}
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't open the worktree of the clone of %v: %v", gitURL, err),
+		}
+	}
+	if err := writeManifests(wt, dir, files); err != nil {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't write the manifests to %v of %v: %v", dir, gitURL, err),
+		}
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't get the status of the clone of %v: %v", gitURL, err),
+		}
+	}
+	if status.IsClean() {
+		log.Infof("Manifests of %v are unchanged in branch %v of %v", dir, branch, gitURL)
+		head, err := repo.Head()
+		if err != nil {
+			return "", nil
+		}
+		return head.Hash().String(), nil
+	}
+
+	author := &object.Signature{Name: GitOpsAuthorName, Email: GitOpsAuthorEmail, When: time.Now()}
+	if signKey != nil {
+		for _, identity := range signKey.Identities {
+			author.Name, author.Email = identity.UserId.Name, identity.UserId.Email
+			break
+		}
+	}
+	hash, err := wt.Commit(message, &git.CommitOptions{All: true, Author: author, SignKey: signKey})
+	if err != nil {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't commit the manifests of %v to %v: %v", dir, gitURL, err),
+		}
+	}
+	refName := plumbing.NewBranchReferenceName(branch)
+	log.Infof("Pushing commit %v of %v to branch %v of %v", hash, dir, branch, gitURL)
+	err = repo.Push(&git.PushOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(refName + ":" + refName)},
+		Auth:       auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't push branch %v to %v: %v", branch, gitURL, err),
+		}
+	}
+	return hash.String(), nil
+}
+
+// openGitOpsClone returns the clone of gitURL in cloneDir, cloning it if needed. The clone of an empty repository
+// is initialized with gitURL as its origin.
+func openGitOpsClone(gitURL string, cloneDir string, auth transport.AuthMethod) (*git.Repository, error) {
+	repo, err := git.PlainOpen(cloneDir)
+	if err == nil {
+		return repo, nil
+	}
+	if err != git.ErrRepositoryNotExists {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("couldn't open the clone of %v: %v", gitURL, err),
+		}
+	}
+	log.Infof("Cloning %v to %v", gitURL, cloneDir)
+	repo, err = git.PlainClone(cloneDir, false, &git.CloneOptions{URL: gitURL, Auth: auth})
+	if err == transport.ErrEmptyRemoteRepository {
+		os.RemoveAll(cloneDir)
+		if repo, err = git.PlainInit(cloneDir, false); err == nil {
+			_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{gitURL}})
+		}
+	}
+	if err != nil {
+		os.RemoveAll(cloneDir)
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't clone %v: %v", gitURL, err),
+		}
+	}
+	return repo, nil
+}
+
+// checkoutGitOpsBranch fetches the remote and checks out branch at its remote commit, discarding the changes of the
+// worktree. A branch missing from the remote is created from the commit checked out, if any.
+func checkoutGitOpsBranch(repo *git.Repository, branch string, auth transport.AuthMethod) error {
+	err := repo.Fetch(&git.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Auth:     auth,
+		Force:    true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate && err != transport.ErrEmptyRemoteRepository {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	refName := plumbing.NewBranchReferenceName(branch)
+	_, err = repo.Reference(refName, false)
+	exists := err == nil
+
+	if remote, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch), true); err == nil {
+		if exists {
+			if err := wt.Checkout(&git.CheckoutOptions{Branch: refName, Force: true}); err != nil {
+				return err
+			}
+		} else if err := wt.Checkout(&git.CheckoutOptions{Branch: refName, Hash: remote.Hash(), Create: true, Force: true}); err != nil {
+			return err
+		}
+		return wt.Reset(&git.ResetOptions{Commit: remote.Hash(), Mode: git.HardReset})
+	}
+	if exists {
+		return wt.Checkout(&git.CheckoutOptions{Branch: refName, Force: true})
+	}
+	if head, err := repo.Head(); err == nil {
+		return wt.Checkout(&git.CheckoutOptions{Branch: refName, Hash: head.Hash(), Create: true, Force: true})
+	}
+	// The first commit of an empty repository creates the branch
+	return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName))
+}
+
+// writeManifests replaces the files of dir of the worktree with the manifests of the applications, keeping the
+// files of the applications whose manifests are nil, and stages them
+func writeManifests(wt *git.Worktree, dir string, files map[string][]byte) error {
+	root := filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(dir))
+	entries, err := ioutil.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		app := strings.TrimSuffix(entry.Name(), ".yaml")
+		if data, ok := files[app]; ok && data == nil && app != entry.Name() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return err
+	}
+	apps := []string{}
+	for app, data := range files {
+		if data != nil {
+			apps = append(apps, app)
+		}
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		if err := ioutil.WriteFile(filepath.Join(root, app+".yaml"), files[app], 0644); err != nil {
+			return err
+		}
+		if _, err := wt.Add(path.Join(dir, app+".yaml")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSigningKey returns the first entity of the ASCII-armored OpenPGP key ring, decrypted with passphrase if needed
+func readSigningKey(armored []byte, passphrase []byte) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armored))
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("no private key")
+	}
+	entity := entities[0]
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entity, nil
+}
@@ -0,0 +1,167 @@
+package kfconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestPushManifests(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "push-manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	GitCacheDir = filepath.Join(tmp, "clones")
+
+	// an empty repo, the branch is created by the first push
+	origin := filepath.Join(tmp, "origin")
+	remote, err := git.PlainInit(origin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitOps := &GitOps{URI: "git::file://" + origin, Branch: "odh"}
+	files := func(hash string) map[string]string {
+		ref, err := remote.Reference(plumbing.NewBranchReferenceName("odh"), true)
+		if err != nil {
+			t.Fatalf("Expected branch odh to be pushed: %v", err)
+		}
+		if ref.Hash().String() != hash {
+			t.Errorf("Expected branch odh at %v, got %v", hash, ref.Hash())
+		}
+		commit, err := remote.CommitObject(ref.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents := map[string]string{}
+		iter, err := commit.Files()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = iter.ForEach(func(f *object.File) error {
+			content, err := f.Contents()
+			contents[f.Name] = content
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contents
+	}
+
+	first, err := PushManifests(gitOps, "opendatahub", "odh", map[string][]byte{
+		"odh-dashboard":  []byte("kind: Deployment\n"),
+		"odh-notebooks":  []byte("kind: StatefulSet\n"),
+		"odh-monitoring": []byte("kind: ServiceMonitor\n"),
+	}, "Render KfDef opendatahub/odh")
+	if err != nil {
+		t.Fatalf("Failed to push the manifests: %v", err)
+	}
+	expected := map[string]string{
+		"opendatahub/odh/odh-dashboard.yaml":  "kind: Deployment\n",
+		"opendatahub/odh/odh-notebooks.yaml":  "kind: StatefulSet\n",
+		"opendatahub/odh/odh-monitoring.yaml": "kind: ServiceMonitor\n",
+	}
+	if contents := files(first); !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected the files %v, got %v", expected, contents)
+	}
+
+	// The manifests of odh-notebooks are kept, the ones of odh-monitoring are removed
+	second, err := PushManifests(gitOps, "opendatahub", "odh", map[string][]byte{
+		"odh-dashboard": []byte("kind: Deployment\nreplicas: 2\n"),
+		"odh-notebooks": nil,
+	}, "Render KfDef opendatahub/odh")
+	if err != nil {
+		t.Fatalf("Failed to push the manifests: %v", err)
+	}
+	if second == first {
+		t.Errorf("Expected a new commit")
+	}
+	expected = map[string]string{
+		"opendatahub/odh/odh-dashboard.yaml": "kind: Deployment\nreplicas: 2\n",
+		"opendatahub/odh/odh-notebooks.yaml": "kind: StatefulSet\n",
+	}
+	if contents := files(second); !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected the files %v, got %v", expected, contents)
+	}
+
+	// Unchanged manifests are not committed again
+	third, err := PushManifests(gitOps, "opendatahub", "odh", map[string][]byte{
+		"odh-dashboard": []byte("kind: Deployment\nreplicas: 2\n"),
+		"odh-notebooks": nil,
+	}, "Render KfDef opendatahub/odh")
+	if err != nil {
+		t.Fatalf("Failed to push the manifests: %v", err)
+	}
+	if third != second {
+		t.Errorf("Expected no new commit, got %v", third)
+	}
+
+	if _, err := PushManifests(&GitOps{URI: gitOps.URI, Path: "../odh"}, "opendatahub", "odh", nil, ""); err == nil {
+		t.Errorf("Expected a path outside of the repository to be rejected")
+	}
+}
+
+func TestPushSignedManifests(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "push-signed-manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	origin := filepath.Join(tmp, "origin")
+	remote, err := git.PlainInit(origin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := openpgp.NewEntity("ODH Deployer", "", "deployer@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	signKey, err := readSigningKey(armored.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("Failed to read the signing key: %v", err)
+	}
+
+	hash, err := pushManifests("file://"+origin, "main", "odh", filepath.Join(tmp, "clone"), nil, signKey,
+		map[string][]byte{"odh-dashboard": []byte("kind: Deployment\n")}, "Render KfDef opendatahub/odh")
+	if err != nil {
+		t.Fatalf("Failed to push the manifests: %v", err)
+	}
+	commit, err := remote.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		t.Fatalf("Expected commit %v to be pushed: %v", hash, err)
+	}
+	if commit.Author.Email != "deployer@example.com" {
+		t.Errorf("Expected the commit to be authored by the identity of the key, got %v", commit.Author)
+	}
+	var public bytes.Buffer
+	if w, err = armor.Encode(&public, openpgp.PublicKeyType, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := commit.Verify(public.String()); err != nil {
+		t.Errorf("Expected the commit to be signed: %v", err)
+	}
+}
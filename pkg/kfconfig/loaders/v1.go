@@ -47,7 +47,12 @@ func (v V1) LoadKfConfig(def interface{}) (*kfconfig.KfConfig, error) {
 	config.Spec.Version = kfdef.Spec.Version
 	for _, app := range kfdef.Spec.Applications {
 		application := kfconfig.Application{
-			Name: app.Name,
+			Name:           app.Name,
+			DeletionPolicy: kfconfig.DeletionPolicy(app.DeletionPolicy),
+			DependsOn:      app.DependsOn,
+		}
+		if app.ReadinessTimeout != nil {
+			application.ReadinessTimeout = app.ReadinessTimeout.DeepCopy()
 		}
 		if app.KustomizeConfig != nil {
 			kconfig := &kfconfig.KustomizeConfig{
@@ -74,6 +79,61 @@ func (v V1) LoadKfConfig(def interface{}) (*kfconfig.KfConfig, error) {
 			}
 			application.KustomizeConfig = kconfig
 		}
+		if app.HelmConfig != nil {
+			hconfig := &kfconfig.HelmConfig{
+				Repo:        app.HelmConfig.Repo,
+				Chart:       app.HelmConfig.Chart,
+				Version:     app.HelmConfig.Version,
+				ReleaseName: app.HelmConfig.ReleaseName,
+				Values:      app.HelmConfig.Values.DeepCopy(),
+			}
+			if app.HelmConfig.RepoRef != nil {
+				hconfig.RepoRef = &kfconfig.RepoRef{
+					Name: app.HelmConfig.RepoRef.Name,
+					Path: app.HelmConfig.RepoRef.Path,
+				}
+			}
+			application.HelmConfig = hconfig
+		}
+		for _, o := range app.Overrides {
+			override := kfconfig.WorkloadOverride{
+				Kind: o.Kind,
+				Name: o.Name,
+			}
+			if o.Replicas != nil {
+				replicas := *o.Replicas
+				override.Replicas = &replicas
+			}
+			for _, c := range o.Containers {
+				override.Containers = append(override.Containers, kfconfig.ContainerOverride{
+					Name:      c.Name,
+					Resources: *c.Resources.DeepCopy(),
+				})
+			}
+			application.Overrides = append(application.Overrides, override)
+		}
+		if app.Scheduling != nil {
+			scheduling := kfconfig.Scheduling(*app.Scheduling.DeepCopy())
+			application.Scheduling = &scheduling
+		}
+		if app.Availability != nil {
+			availability := kfconfig.Availability(*app.Availability.DeepCopy())
+			application.Availability = &availability
+		}
+		application.PodSecurity = kfconfig.PodSecurityLevel(app.PodSecurity)
+		if app.Autoscaling != nil {
+			autoscaling := &kfconfig.Autoscaling{
+				Deployments:             app.Autoscaling.Deployments,
+				MinReplicas:             app.Autoscaling.MinReplicas,
+				MaxReplicas:             app.Autoscaling.MaxReplicas,
+				TargetCPUUtilization:    app.Autoscaling.TargetCPUUtilization,
+				TargetMemoryUtilization: app.Autoscaling.TargetMemoryUtilization,
+			}
+			for _, m := range app.Autoscaling.Metrics {
+				autoscaling.Metrics = append(autoscaling.Metrics, kfconfig.AutoscalingMetric(m))
+			}
+			application.Autoscaling = autoscaling.DeepCopy()
+		}
 		config.Spec.Applications = append(config.Spec.Applications, application)
 	}
 
@@ -131,12 +191,47 @@ func (v V1) LoadKfConfig(def interface{}) (*kfconfig.KfConfig, error) {
 
 	for _, repo := range kfdef.Spec.Repos {
 		r := kfconfig.Repo{
-			Name: repo.Name,
-			URI:  repo.URI,
+			Name:       repo.Name,
+			URI:        repo.URI,
+			Ref:        repo.Ref,
+			SecretName: repo.SecretName,
+		}
+		if repo.Verify != nil {
+			r.Verify = &kfconfig.Verification{
+				PublicKey: repo.Verify.PublicKey,
+				Identity:  repo.Verify.Identity,
+				Issuer:    repo.Verify.Issuer,
+			}
 		}
 		config.Spec.Repos = append(config.Spec.Repos, r)
 	}
 
+	for _, image := range kfdef.Spec.Images {
+		config.Spec.Images = append(config.Spec.Images, kfconfig.Image{
+			Name:    image.Name,
+			NewName: image.NewName,
+			NewTag:  image.NewTag,
+			Digest:  image.Digest,
+		})
+	}
+	if kfdef.Spec.Scheduling != nil {
+		scheduling := kfconfig.Scheduling(*kfdef.Spec.Scheduling.DeepCopy())
+		config.Spec.Scheduling = &scheduling
+	}
+	if kfdef.Spec.Availability != nil {
+		availability := kfconfig.Availability(*kfdef.Spec.Availability.DeepCopy())
+		config.Spec.Availability = &availability
+	}
+	if kfdef.Spec.GitOps != nil {
+		gitOps := kfconfig.GitOps(*kfdef.Spec.GitOps)
+		config.Spec.GitOps = &gitOps
+	}
+	config.Spec.ServiceMesh = kfconfig.ManagementState(kfdef.Spec.ServiceMesh)
+	config.Spec.Auth = kfconfig.AuthProvider(kfdef.Spec.Auth)
+	config.Spec.PodSecurity = kfconfig.PodSecurityLevel(kfdef.Spec.PodSecurity)
+	config.Spec.IPFamilyPolicy = kfconfig.IPFamilyPolicy(kfdef.Spec.IPFamilyPolicy)
+	config.Spec.FeatureGates = kfdef.Spec.FeatureGates
+
 	for _, cond := range kfdef.Status.Conditions {
 		c := kfconfig.Condition{
 			Type:               kfconfig.ConditionType(cond.Type),
@@ -148,6 +243,19 @@ func (v V1) LoadKfConfig(def interface{}) (*kfconfig.KfConfig, error) {
 		}
 		config.Status.Conditions = append(config.Status.Conditions, c)
 	}
+	for _, cond := range kfdef.Status.ApplicationConditions {
+		c := kfconfig.ApplicationCondition{
+			Application:        cond.Application,
+			Type:               kfconfig.ConditionType(cond.Type),
+			Status:             cond.Status,
+			LastUpdateTime:     cond.LastUpdateTime,
+			LastTransitionTime: cond.LastTransitionTime,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+			ManifestsDigest:    cond.ManifestsDigest,
+		}
+		config.Status.ApplicationConditions = append(config.Status.ApplicationConditions, c)
+	}
 	for _, cache := range kfdef.Status.ReposCache {
 		c := kfconfig.Cache{
 			Name:      cache.Name,
@@ -172,7 +280,12 @@ func (v V1) LoadKfDef(config kfconfig.KfConfig, out interface{}) error {
 
 	for _, app := range config.Spec.Applications {
 		application := kfdeftypes.Application{
-			Name: app.Name,
+			Name:           app.Name,
+			DeletionPolicy: kfdeftypes.DeletionPolicy(app.DeletionPolicy),
+			DependsOn:      app.DependsOn,
+		}
+		if app.ReadinessTimeout != nil {
+			application.ReadinessTimeout = app.ReadinessTimeout.DeepCopy()
 		}
 		if app.KustomizeConfig != nil {
 			kconfig := &kfdeftypes.KustomizeConfig{
@@ -194,6 +307,61 @@ func (v V1) LoadKfDef(config kfconfig.KfConfig, out interface{}) error {
 			}
 			application.KustomizeConfig = kconfig
 		}
+		if app.HelmConfig != nil {
+			hconfig := &kfdeftypes.HelmConfig{
+				Repo:        app.HelmConfig.Repo,
+				Chart:       app.HelmConfig.Chart,
+				Version:     app.HelmConfig.Version,
+				ReleaseName: app.HelmConfig.ReleaseName,
+				Values:      app.HelmConfig.Values.DeepCopy(),
+			}
+			if app.HelmConfig.RepoRef != nil {
+				hconfig.RepoRef = &kfdeftypes.RepoRef{
+					Name: app.HelmConfig.RepoRef.Name,
+					Path: app.HelmConfig.RepoRef.Path,
+				}
+			}
+			application.HelmConfig = hconfig
+		}
+		for _, o := range app.Overrides {
+			override := kfdeftypes.WorkloadOverride{
+				Kind: o.Kind,
+				Name: o.Name,
+			}
+			if o.Replicas != nil {
+				replicas := *o.Replicas
+				override.Replicas = &replicas
+			}
+			for _, c := range o.Containers {
+				override.Containers = append(override.Containers, kfdeftypes.ContainerOverride{
+					Name:      c.Name,
+					Resources: *c.Resources.DeepCopy(),
+				})
+			}
+			application.Overrides = append(application.Overrides, override)
+		}
+		if app.Scheduling != nil {
+			scheduling := kfdeftypes.Scheduling(*app.Scheduling.DeepCopy())
+			application.Scheduling = &scheduling
+		}
+		if app.Availability != nil {
+			availability := kfdeftypes.Availability(*app.Availability.DeepCopy())
+			application.Availability = &availability
+		}
+		application.PodSecurity = kfdeftypes.PodSecurityLevel(app.PodSecurity)
+		if app.Autoscaling != nil {
+			autoscaling := &kfdeftypes.Autoscaling{
+				Deployments:             app.Autoscaling.Deployments,
+				MinReplicas:             app.Autoscaling.MinReplicas,
+				MaxReplicas:             app.Autoscaling.MaxReplicas,
+				TargetCPUUtilization:    app.Autoscaling.TargetCPUUtilization,
+				TargetMemoryUtilization: app.Autoscaling.TargetMemoryUtilization,
+			}
+			for _, m := range app.Autoscaling.Metrics {
+				autoscaling.Metrics = append(autoscaling.Metrics, kfdeftypes.AutoscalingMetric(m))
+			}
+			application.Autoscaling = autoscaling.DeepCopy()
+		}
 		kfdef.Spec.Applications = append(kfdef.Spec.Applications, application)
 	}
 
@@ -224,12 +392,47 @@ func (v V1) LoadKfDef(config kfconfig.KfConfig, out interface{}) error {
 
 	for _, repo := range config.Spec.Repos {
 		r := kfdeftypes.Repo{
-			Name: repo.Name,
-			URI:  repo.URI,
+			Name:       repo.Name,
+			URI:        repo.URI,
+			Ref:        repo.Ref,
+			SecretName: repo.SecretName,
+		}
+		if repo.Verify != nil {
+			r.Verify = &kfdeftypes.Verification{
+				PublicKey: repo.Verify.PublicKey,
+				Identity:  repo.Verify.Identity,
+				Issuer:    repo.Verify.Issuer,
+			}
 		}
 		kfdef.Spec.Repos = append(kfdef.Spec.Repos, r)
 	}
 
+	for _, image := range config.Spec.Images {
+		kfdef.Spec.Images = append(kfdef.Spec.Images, kfdeftypes.Image{
+			Name:    image.Name,
+			NewName: image.NewName,
+			NewTag:  image.NewTag,
+			Digest:  image.Digest,
+		})
+	}
+	if config.Spec.Scheduling != nil {
+		scheduling := kfdeftypes.Scheduling(*config.Spec.Scheduling.DeepCopy())
+		kfdef.Spec.Scheduling = &scheduling
+	}
+	if config.Spec.Availability != nil {
+		availability := kfdeftypes.Availability(*config.Spec.Availability.DeepCopy())
+		kfdef.Spec.Availability = &availability
+	}
+	if config.Spec.GitOps != nil {
+		gitOps := kfdeftypes.GitOps(*config.Spec.GitOps)
+		kfdef.Spec.GitOps = &gitOps
+	}
+	kfdef.Spec.ServiceMesh = kfdeftypes.ManagementState(config.Spec.ServiceMesh)
+	kfdef.Spec.Auth = kfdeftypes.AuthProvider(config.Spec.Auth)
+	kfdef.Spec.PodSecurity = kfdeftypes.PodSecurityLevel(config.Spec.PodSecurity)
+	kfdef.Spec.IPFamilyPolicy = kfdeftypes.IPFamilyPolicy(config.Spec.IPFamilyPolicy)
+	kfdef.Spec.FeatureGates = config.Spec.FeatureGates
+
 	for _, cond := range config.Status.Conditions {
 		c := kfdeftypes.KfDefCondition{
 			Type:               kfdeftypes.KfDefConditionType(cond.Type),
@@ -242,6 +445,20 @@ func (v V1) LoadKfDef(config kfconfig.KfConfig, out interface{}) error {
 		kfdef.Status.Conditions = append(kfdef.Status.Conditions, c)
 	}
 
+	for _, cond := range config.Status.ApplicationConditions {
+		c := kfdeftypes.ApplicationCondition{
+			Application:        cond.Application,
+			Type:               kfdeftypes.KfDefConditionType(cond.Type),
+			Status:             cond.Status,
+			LastUpdateTime:     cond.LastUpdateTime,
+			LastTransitionTime: cond.LastTransitionTime,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+			ManifestsDigest:    cond.ManifestsDigest,
+		}
+		kfdef.Status.ApplicationConditions = append(kfdef.Status.ApplicationConditions, c)
+	}
+
 	for _, cache := range config.Status.Caches {
 		c := kfdeftypes.RepoCache{
 			Name:      cache.Name,
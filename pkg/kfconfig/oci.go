@@ -0,0 +1,109 @@
+package kfconfig
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/cosign"
+	"github.com/kubeflow/kfctl/v3/pkg/oci"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// IsOCIURI returns true if the repo URI points to an OCI artifact, e.g. oci://quay.io/opendatahub/odh-manifests:v1.1.0
+func IsOCIURI(uri string) bool {
+	return strings.HasPrefix(uri, oci.Scheme)
+}
+
+// fetchOCI extracts the layers of the OCI artifact the URI of r points to into cacheDir. The ref of r overrides the
+// tag of the URI, or pins the artifact when it is a digest. The manifest and the layers are verified against their
+// digests, and the signature of the artifact with v if set, before being extracted. The credentials are read from
+// the pull Secret of r in namespace, if set. The pulls are cancelled once ctx is done.
+func fetchOCI(ctx context.Context, r Repo, namespace string, cacheDir string, v *cosign.Verifier) error {
+	uri, ref, secretName := r.URI, r.Ref, r.SecretName
+	reference, err := oci.ParseReference(uri)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: err.Error(),
+		}
+	}
+	if strings.Contains(ref, ":") {
+		reference.Digest = ref
+	} else if ref != "" {
+		reference.Tag = ref
+	}
+
+	client := &oci.Client{Context: ctx}
+	if secretName != "" {
+		secret, err := getSecret(namespace, secretName)
+		if err != nil {
+			return err
+		}
+		data, ok := secret.Data[v1.DockerConfigJsonKey]
+		if !ok {
+			data = secret.Data[v1.DockerConfigKey]
+		}
+		username, password, ok := oci.CredentialsFromDockerConfig(data, reference.Registry)
+		if !ok {
+			return &kfapis.KfError{
+				Code:    int(kfapis.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("pull Secret %v/%v has no credentials for %v", namespace, secretName, reference.Registry),
+			}
+		}
+		client.Username, client.Password = username, password
+	}
+
+	manifest, digest, err := client.Manifest(reference)
+	if err != nil {
+		return &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: fmt.Sprintf("couldn't pull %v: %v", reference, err),
+		}
+	}
+	if v != nil {
+		if err := verifyArtifact(r, v, client, reference, digest); err != nil {
+			return err
+		}
+	}
+	for _, layer := range manifest.Layers {
+		data, err := client.Blob(reference, layer.Digest)
+		if err != nil {
+			return &kfapis.KfError{
+				Code:    int(kfapis.INVALID_ARGUMENT),
+				Message: fmt.Sprintf("couldn't pull layer %v of %v: %v", layer.Digest, reference, err),
+			}
+		}
+		log.Infof("Extracting layer %v of %v to %v", layer.Digest, reference, cacheDir)
+		if err := extractLayer(layer, data, cacheDir); err != nil {
+			return &kfapis.KfError{
+				Code:    int(kfapis.INTERNAL_ERROR),
+				Message: fmt.Sprintf("couldn't extract layer %v of %v: %v", layer.Digest, reference, err),
+			}
+		}
+	}
+	return nil
+}
+
+// extractLayer writes a layer to dir. Gzipped tarballs are extracted, the other layers are written to the file
+// named by their title annotation, as pushed by oras.
+func extractLayer(layer oci.Descriptor, data []byte, dir string) error {
+	if strings.HasSuffix(layer.MediaType, "tar+gzip") || strings.HasSuffix(layer.MediaType, "tar.gzip") {
+		return untar(data, dir)
+	}
+	title := layer.Annotations[oci.TitleAnnotation]
+	if title == "" {
+		return fmt.Errorf("layer of media type %v is neither a gzipped tarball nor has a %v annotation",
+			layer.MediaType, oci.TitleAnnotation)
+	}
+	target := filepath.Join(dir, filepath.Clean("/"+title))
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target, data, 0644)
+}
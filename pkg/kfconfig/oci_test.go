@@ -0,0 +1,94 @@
+package kfconfig
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/oci"
+)
+
+func TestFetchOCI(t *testing.T) {
+	var layer bytes.Buffer
+	gzw := gzip.NewWriter(&layer)
+	tw := tar.NewWriter(gzw)
+	content := []byte("resources: []\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "odh-common/base/kustomization.yaml", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	gzw.Close()
+	readme := []byte("manifests")
+
+	digest := func(data []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+	blobs := map[string][]byte{digest(layer.Bytes()): layer.Bytes(), digest(readme): readme}
+	manifest, _ := json.Marshal(oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.ManifestMediaType,
+		Layers: []oci.Descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digest(layer.Bytes())},
+			{MediaType: "text/markdown", Digest: digest(readme), Annotations: map[string]string{oci.TitleAnnotation: "README.md"}},
+		},
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/opendatahub/odh-manifests/manifests/"):
+			// the same manifest is served for any tag or digest
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/opendatahub/odh-manifests/blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/opendatahub/odh-manifests/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defaultTransport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = server.Client().Transport
+	defer func() { http.DefaultClient.Transport = defaultTransport }()
+
+	tmp, err := ioutil.TempDir("", "fetch-oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	uri := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/opendatahub/odh-manifests"
+
+	for _, ref := range []string{"v1.1.0", digest(manifest)} {
+		cacheDir := filepath.Join(tmp, strings.Replace(ref, ":", "-", -1))
+		if err := fetchOCI(context.Background(), Repo{Name: "manifests", URI: uri, Ref: ref}, "", cacheDir, nil); err != nil {
+			t.Fatalf("ref %v: %v", ref, err)
+		}
+		for file, expected := range map[string][]byte{"odh-common/base/kustomization.yaml": content, "README.md": readme} {
+			data, err := ioutil.ReadFile(filepath.Join(cacheDir, file))
+			if err != nil {
+				t.Errorf("ref %v: %v", ref, err)
+			} else if !bytes.Equal(data, expected) {
+				t.Errorf("ref %v: %v is %q, want %q", ref, file, data, expected)
+			}
+		}
+	}
+
+	if err := fetchOCI(context.Background(), Repo{Name: "manifests", URI: uri, Ref: digest([]byte("another manifest"))}, "", filepath.Join(tmp, "tampered"), nil); err == nil {
+		t.Errorf("Expected fetching an artifact not matching its digest to fail")
+	}
+}
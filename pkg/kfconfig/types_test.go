@@ -30,6 +30,7 @@ import (
 	"reflect"
 	"sigs.k8s.io/kustomize/v3/pkg/types"
 	"testing"
+	"time"
 )
 
 func TestSyncCache(t *testing.T) {
@@ -203,6 +204,39 @@ func TestSyncCache(t *testing.T) {
 
 }
 
+func TestSyncCacheManifestsDir(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("Failed to create directory; %v", err)
+	}
+	defer os.RemoveAll(testDir)
+	manifestsDir := path.Join(testDir, "odh-manifests")
+	if err := os.Mkdir(manifestsDir, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory; %v", err)
+	}
+	ManifestsDir = manifestsDir
+	defer func() { ManifestsDir = "" }()
+
+	c := &KfConfig{
+		Spec: KfConfigSpec{
+			AppDir: path.Join(testDir, "app"),
+			Repos:  []Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}},
+		},
+		Status: Status{
+			Caches: []Cache{{Name: "manifests", LocalPath: path.Join(testDir, "app", ".cache", "manifests")}},
+		},
+	}
+	if err := c.SyncCache(); err != nil {
+		t.Fatalf("Could not sync cache; %v", err)
+	}
+	if len(c.Status.Caches) != 1 || c.Status.Caches[0].LocalPath != manifestsDir {
+		t.Fatalf("Caches; got %v; want the manifests read from %v", c.Status.Caches, manifestsDir)
+	}
+	if _, err := os.Stat(path.Join(testDir, "app", ".cache", "manifests")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the manifests not to be copied to the cache; %v", err)
+	}
+}
+
 type FakePluginSpec struct {
 	Param     string `json:"param,omitempty"`
 	BoolParam bool   `json:"boolParam,omitempty"`
@@ -1230,3 +1264,50 @@ func Pformat(value interface{}) (string, error) {
 	}
 	return string(valueJson), nil
 }
+
+func TestKfConfig_SetApplicationCondition(t *testing.T) {
+	transitionTime := metav1.NewTime(metav1.Now().Add(-time.Hour))
+	config := &KfConfig{
+		Status: Status{
+			ApplicationConditions: []ApplicationCondition{
+				{
+					Application:        "app1",
+					Type:               Available,
+					Status:             v1.ConditionTrue,
+					LastTransitionTime: transitionTime,
+				},
+			},
+		},
+	}
+
+	// Same type keeps the transition time, a new application is appended.
+	config.SetApplicationCondition("app1", Available, "ApplySucceeded", "")
+	config.SetApplicationCondition("app2", Progressing, "ApplyPending", "")
+	if len(config.Status.ApplicationConditions) != 2 {
+		t.Fatalf("Expected 2 application conditions; got %v", len(config.Status.ApplicationConditions))
+	}
+	if cond := config.Status.ApplicationConditions[0]; !cond.LastTransitionTime.Equal(&transitionTime) || cond.Reason != "ApplySucceeded" {
+		t.Errorf("Unexpected condition for app1: %+v", cond)
+	}
+
+	// A different type replaces the condition and resets the transition time.
+	config.SetApplicationCondition("app1", Degraded, "ApplyFailed", "boom")
+	cond := config.Status.ApplicationConditions[0]
+	if cond.Type != Degraded || cond.Message != "boom" || cond.LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("Unexpected condition for app1: %+v", cond)
+	}
+}
+
+func TestKfConfig_SetApplicationDigest(t *testing.T) {
+	c := &KfConfig{}
+	c.SetApplicationCondition("dashboard", Available, "ApplySucceeded", "application applied successfully")
+	c.SetApplicationDigest("dashboard", "sha256:1234")
+	c.SetApplicationCondition("dashboard", Degraded, "ApplyFailed", "failed")
+	cond := c.Status.ApplicationConditions[0]
+	if len(c.Status.ApplicationConditions) != 1 || cond.Type != Degraded {
+		t.Fatalf("Expected a single Degraded condition, got %v", c.Status.ApplicationConditions)
+	}
+	if cond.ManifestsDigest != "sha256:1234" {
+		t.Errorf("Expected the digest of the manifests last applied to be kept, got %v", cond.ManifestsDigest)
+	}
+}
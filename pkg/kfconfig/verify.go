@@ -0,0 +1,142 @@
+package kfconfig
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
+	"github.com/kubeflow/kfctl/v3/pkg/cosign"
+	"github.com/kubeflow/kfctl/v3/pkg/oci"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SignatureSuffix is appended to the URI of a manifests tarball, or to its ConfigMap key, to get its cosign signature
+	SignatureSuffix = ".sig"
+	// CertificateSuffix is appended to the URI of a manifests tarball, or to its ConfigMap key, to get the certificate
+	// of its keyless cosign signature
+	CertificateSuffix = ".pem"
+	// BundleSuffix is appended to the URI of a manifests tarball, or to its ConfigMap key, to get the Rekor bundle
+	// of its keyless cosign signature
+	BundleSuffix = ".bundle"
+)
+
+var (
+	// CosignPublicKey is the PEM public key the manifests of the repos that don't set their own verification
+	// must be signed with. The signatures of these repos are not verified when it is empty.
+	CosignPublicKey []byte
+	// FulcioRoots are the PEM certificates the certificates of the keyless signatures must chain up to
+	FulcioRoots []byte
+	// RekorPublicKey is the PEM public key of the transparency log the keyless signatures must be recorded in
+	RekorPublicKey []byte
+)
+
+// verifier returns the Verifier of the signatures of the manifests of r, or nil if they don't need to be verified
+func verifier(r Repo) (*cosign.Verifier, error) {
+	var v *cosign.Verifier
+	var err error
+	switch {
+	case r.Verify != nil && r.Verify.PublicKey != "":
+		v, err = cosign.NewKeyVerifier([]byte(r.Verify.PublicKey))
+	case r.Verify != nil:
+		v, err = cosign.NewKeylessVerifier(r.Verify.Identity, r.Verify.Issuer, FulcioRoots, RekorPublicKey)
+	case len(CosignPublicKey) > 0:
+		v, err = cosign.NewKeyVerifier(CosignPublicKey)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.UNVERIFIED),
+			Message: fmt.Sprintf("couldn't verify the signature of repo %v: %v", r.Name, err),
+		}
+	}
+	return v, nil
+}
+
+// unverified returns the KfError of the manifests of repo r whose signature couldn't be verified
+func unverified(r Repo, err error) error {
+	log.Errorf("Signature verification of repo %v failed: %v", r.Name, err)
+	return &kfapis.KfError{
+		Code:    int(kfapis.UNVERIFIED),
+		Message: fmt.Sprintf("signature verification of repo %v failed: %v", r.Name, err),
+	}
+}
+
+// verifyTarball checks the cosign signature of body, the tarball of repo r. The signature is downloaded with hclient
+// from the URI of r suffixed with SignatureSuffix, along with the certificate and the bundle of keyless signatures.
+func verifyTarball(ctx context.Context, r Repo, v *cosign.Verifier, hclient *http.Client, body []byte) error {
+	signature, err := downloadSuffixed(ctx, hclient, r.URI, SignatureSuffix)
+	if err != nil {
+		return unverified(r, err)
+	}
+	// The certificate and the bundle are only needed by keyless signatures
+	certificate, _ := downloadSuffixed(ctx, hclient, r.URI, CertificateSuffix)
+	bundle, _ := downloadSuffixed(ctx, hclient, r.URI, BundleSuffix)
+	if err := v.VerifyBlob(body, signature, certificate, nil, bundle); err != nil {
+		return unverified(r, err)
+	}
+	log.Infof("Signature of repo %v verified", r.Name)
+	return nil
+}
+
+// verifyArtifact checks the cosign signatures of the OCI artifact of reference with manifest digest, stored in
+// the signature artifact of the same repository as pushed by cosign sign. One valid signature is enough.
+func verifyArtifact(r Repo, v *cosign.Verifier, client *oci.Client, reference oci.Reference, digest string) error {
+	sigRef := reference
+	sigRef.Tag, sigRef.Digest = cosign.SignatureTag(digest), ""
+	manifest, _, err := client.Manifest(sigRef)
+	if err != nil {
+		return unverified(r, fmt.Errorf("couldn't get the signatures of %v: %v", reference, err))
+	}
+	errs := []error{}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosign.SimpleSigningMediaType {
+			continue
+		}
+		payload, err := client.Blob(sigRef, layer.Digest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = v.VerifyArtifact(payload, []byte(layer.Annotations[cosign.SignatureAnnotation]),
+			[]byte(layer.Annotations[cosign.CertificateAnnotation]), []byte(layer.Annotations[cosign.ChainAnnotation]),
+			[]byte(layer.Annotations[cosign.BundleAnnotation]), digest)
+		if err == nil {
+			log.Infof("Signature of repo %v verified", r.Name)
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return unverified(r, fmt.Errorf("%v has no signature", reference))
+	}
+	return unverified(r, fmt.Errorf("no valid signature of %v: %v", reference, errs))
+}
+
+// downloadSuffixed downloads the file at the path of uri suffixed with suffix
+func downloadSuffixed(ctx context.Context, hclient *http.Client, uri string, suffix string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += suffix
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", "kfctl")
+	resp, err := hclient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't download %v: %v", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("couldn't download %v: %v", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,177 @@
+// Package metrics defines the Prometheus metrics reported for the components of every KfDef. They are
+// registered with the controller-runtime registry and served by the manager along with the controller metrics.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var labels = []string{"namespace", "kfdef", "component"}
+
+var (
+	// ComponentReconcileDuration is the time taken to render and apply a component of a KfDef
+	ComponentReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kfdef_component_reconcile_duration_seconds",
+		Help:    "Time taken to render and apply a component of a KfDef, in seconds.",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, labels)
+
+	// ComponentReconcileErrors is the number of times a component of a KfDef failed to be rendered or applied
+	ComponentReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kfdef_component_reconcile_errors_total",
+		Help: "Number of times a component of a KfDef failed to be rendered or applied.",
+	}, labels)
+
+	// ComponentReady is 1 when a component of a KfDef is Available and 0 otherwise
+	ComponentReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kfdef_component_ready",
+		Help: "Whether a component of a KfDef is available (1) or not (0).",
+	}, labels)
+
+	// CertificateExpiry is the expiry time of a serving certificate issued for a Service of a KfDef
+	CertificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kfdef_certificate_expiry_timestamp_seconds",
+		Help: "Expiry time of a serving certificate issued for a Service of a KfDef, in seconds since the epoch.",
+	}, []string{"namespace", "kfdef", "secret"})
+
+	// OperatorLeader is 1 on the replica of the operator that is the leader, and 0 on the other replicas.
+	// The component metrics are only reported by the leader.
+	OperatorLeader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kfdef_operator_leader",
+		Help: "Whether this replica of the operator is the leader reconciling the KfDefs (1) or not (0).",
+	})
+
+	// NotebooksCulled is the number of notebooks stopped by the notebook culler after being idle
+	NotebooksCulled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notebook_culled_total",
+		Help: "Number of notebooks stopped after being idle for longer than the cull idle time.",
+	}, []string{"namespace"})
+
+	// NotebookLastActivity is the last time the kernels of a running notebook were active
+	NotebookLastActivity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notebook_last_activity_timestamp_seconds",
+		Help: "Last time the kernels of a running notebook were active, in seconds since the epoch.",
+	}, []string{"namespace", "notebook"})
+
+	// UsageInfo is 1 for every DataScienceCluster opting in to the usage telemetry, labeled with the usage data
+	UsageInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "odh_usage_info",
+		Help: "Usage data of a DataScienceCluster opting in to the telemetry, always 1.",
+	}, []string{"namespace", "datasciencecluster", "cluster_id", "version", "cluster_size"})
+
+	// UsageComponentEnabled is 1 when a component of a DataScienceCluster opting in to the usage telemetry is
+	// enabled and 0 otherwise
+	UsageComponentEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "odh_usage_component_enabled",
+		Help: "Whether a component of a DataScienceCluster opting in to the telemetry is enabled (1) or not (0).",
+	}, []string{"namespace", "datasciencecluster", "component"})
+
+	// JanitorReclaimed is the number of resources garbage collected by the janitor
+	JanitorReclaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janitor_reclaimed_total",
+		Help: "Number of resources garbage collected by the janitor, by kind.",
+	}, []string{"namespace", "kind"})
+
+	// JanitorReclaimedStorage is the storage requested by the PersistentVolumeClaims garbage collected by the janitor
+	JanitorReclaimedStorage = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janitor_reclaimed_storage_bytes_total",
+		Help: "Storage requested by the PersistentVolumeClaims garbage collected by the janitor, in bytes.",
+	}, []string{"namespace"})
+
+	// ReconcileErrors is the number of reconciliations failed by a controller. The failed reconciliations are
+	// requeued after a delay instead of being returned to controller-runtime, which does not count them.
+	ReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "odh_operator_reconcile_errors_total",
+		Help: "Number of reconciliations failed by a controller of the operator, by controller.",
+	}, []string{"controller"})
+
+	// AuditRecordsDropped is the number of audit records that could not be delivered to a sink
+	AuditRecordsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_records_dropped_total",
+		Help: "Number of audit records that could not be delivered to a sink, by sink.",
+	}, []string{"sink"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(ComponentReconcileDuration, ComponentReconcileErrors, ComponentReady, CertificateExpiry, OperatorLeader,
+		NotebooksCulled, NotebookLastActivity, UsageInfo, UsageComponentEnabled, JanitorReclaimed, JanitorReclaimedStorage,
+		ReconcileErrors, AuditRecordsDropped)
+}
+
+// ObserveComponentReconcile records the duration and the outcome of the reconciliation of a component
+func ObserveComponentReconcile(namespace, kfdef, component string, duration time.Duration, err error) {
+	ComponentReconcileDuration.WithLabelValues(namespace, kfdef, component).Observe(duration.Seconds())
+	if err != nil {
+		ComponentReconcileErrors.WithLabelValues(namespace, kfdef, component).Inc()
+	}
+}
+
+// SetComponentReady records whether a component is available
+func SetComponentReady(namespace, kfdef, component string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	ComponentReady.WithLabelValues(namespace, kfdef, component).Set(value)
+}
+
+// DeleteComponent stops reporting the metrics of a component, e.g. once its KfDef is deleted
+func DeleteComponent(namespace, kfdef, component string) {
+	ComponentReconcileDuration.DeleteLabelValues(namespace, kfdef, component)
+	ComponentReconcileErrors.DeleteLabelValues(namespace, kfdef, component)
+	ComponentReady.DeleteLabelValues(namespace, kfdef, component)
+}
+
+// SetCertificateExpiry records the expiry time of the serving certificate stored in a secret
+func SetCertificateExpiry(namespace, kfdef, secret string, expiry time.Time) {
+	CertificateExpiry.WithLabelValues(namespace, kfdef, secret).Set(float64(expiry.Unix()))
+}
+
+// DeleteCertificate stops reporting the expiry time of the serving certificate stored in a secret
+func DeleteCertificate(namespace, kfdef, secret string) {
+	CertificateExpiry.DeleteLabelValues(namespace, kfdef, secret)
+}
+
+// SetLeader records whether this replica of the operator is the leader
+func SetLeader(leader bool) {
+	value := 0.0
+	if leader {
+		value = 1
+	}
+	OperatorLeader.Set(value)
+}
+
+// SetNotebookLastActivity records the last time the kernels of a notebook were active
+func SetNotebookLastActivity(namespace, notebook string, lastActivity time.Time) {
+	NotebookLastActivity.WithLabelValues(namespace, notebook).Set(float64(lastActivity.Unix()))
+}
+
+// NotebookCulled counts a notebook stopped after being idle and stops reporting its activity
+func NotebookCulled(namespace, notebook string) {
+	NotebooksCulled.WithLabelValues(namespace).Inc()
+	DeleteNotebook(namespace, notebook)
+}
+
+// DeleteNotebook stops reporting the activity of a notebook, e.g. once it is stopped or deleted
+func DeleteNotebook(namespace, notebook string) {
+	NotebookLastActivity.DeleteLabelValues(namespace, notebook)
+}
+
+// ResetUsage stops reporting the usage data of every DataScienceCluster, before the ones still opting in to the
+// telemetry are reported again
+func ResetUsage() {
+	UsageInfo.Reset()
+	UsageComponentEnabled.Reset()
+}
+
+// Reclaimed counts a resource of kind garbage collected by the janitor in namespace, along with the storage it
+// requested
+func Reclaimed(namespace, kind string, storageBytes int64) {
+	JanitorReclaimed.WithLabelValues(namespace, kind).Inc()
+	if storageBytes > 0 {
+		JanitorReclaimedStorage.WithLabelValues(namespace).Add(float64(storageBytes))
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestComponentMetrics(t *testing.T) {
+	ObserveComponentReconcile("opendatahub", "odh", "odh-dashboard", time.Second, nil)
+	ObserveComponentReconcile("opendatahub", "odh", "odh-dashboard", time.Second, errors.New("apply failed"))
+	if errs := testutil.ToFloat64(ComponentReconcileErrors.WithLabelValues("opendatahub", "odh", "odh-dashboard")); errs != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+
+	SetComponentReady("opendatahub", "odh", "odh-dashboard", true)
+	if ready := testutil.ToFloat64(ComponentReady.WithLabelValues("opendatahub", "odh", "odh-dashboard")); ready != 1 {
+		t.Errorf("Expected the component to be ready, got %v", ready)
+	}
+
+	DeleteComponent("opendatahub", "odh", "odh-dashboard")
+	if n := testutil.CollectAndCount(ComponentReady); n != 0 {
+		t.Errorf("Expected no readiness after deletion, got %v series", n)
+	}
+}
+
+func TestCertificateMetrics(t *testing.T) {
+	expiry := time.Unix(1700000000, 0)
+	SetCertificateExpiry("opendatahub", "odh", "odh-dashboard-tls", expiry)
+	if value := testutil.ToFloat64(CertificateExpiry.WithLabelValues("opendatahub", "odh", "odh-dashboard-tls")); value != 1700000000 {
+		t.Errorf("Expected the expiry time of the certificate, got %v", value)
+	}
+
+	DeleteCertificate("opendatahub", "odh", "odh-dashboard-tls")
+	if n := testutil.CollectAndCount(CertificateExpiry); n != 0 {
+		t.Errorf("Expected no expiry time after deletion, got %v series", n)
+	}
+}
+
+func TestLeaderMetrics(t *testing.T) {
+	SetLeader(false)
+	if leader := testutil.ToFloat64(OperatorLeader); leader != 0 {
+		t.Errorf("Expected the replica not to be the leader, got %v", leader)
+	}
+	SetLeader(true)
+	if leader := testutil.ToFloat64(OperatorLeader); leader != 1 {
+		t.Errorf("Expected the replica to be the leader, got %v", leader)
+	}
+}
+
+func TestNotebookMetrics(t *testing.T) {
+	SetNotebookLastActivity("data-science", "notebook", time.Unix(1700000000, 0))
+	if value := testutil.ToFloat64(NotebookLastActivity.WithLabelValues("data-science", "notebook")); value != 1700000000 {
+		t.Errorf("Expected the last activity of the notebook, got %v", value)
+	}
+
+	NotebookCulled("data-science", "notebook")
+	if culled := testutil.ToFloat64(NotebooksCulled.WithLabelValues("data-science")); culled != 1 {
+		t.Errorf("Expected 1 culled notebook, got %v", culled)
+	}
+	if n := testutil.CollectAndCount(NotebookLastActivity); n != 0 {
+		t.Errorf("Expected no last activity once the notebook is culled, got %v series", n)
+	}
+}
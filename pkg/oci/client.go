@@ -0,0 +1,363 @@
+// Package oci pulls artifacts, e.g. Helm charts, from OCI registries with the registry v2 API
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Scheme prefixes the references of OCI artifacts, e.g. oci://quay.io/opendatahub/charts/odh-dashboard:1.0.0
+	Scheme = "oci://"
+
+	// ManifestMediaType is the media type of the OCI image manifests
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// DockerManifestMediaType is the media type of the Docker image manifests
+	DockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// TitleAnnotation names the file a layer holds, as set by oras push
+	TitleAnnotation = "org.opencontainers.image.title"
+
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Reference identifies an artifact of a registry by tag or digest
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses references such as oci://quay.io/opendatahub/charts/odh-dashboard:1.0.0 or
+// quay.io/opendatahub/charts/odh-dashboard@sha256:<hex>. The tag defaults to latest.
+func ParseReference(ref string) (Reference, error) {
+	ref = strings.TrimPrefix(ref, Scheme)
+	r := Reference{}
+	if idx := strings.Index(ref, "@"); idx >= 0 {
+		ref, r.Digest = ref[:idx], ref[idx+1:]
+	}
+	if idx := strings.LastIndex(ref, ":"); idx > strings.LastIndex(ref, "/") {
+		ref, r.Tag = ref[:idx], ref[idx+1:]
+	}
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %v: expected <registry>/<repository>[:<tag>|@<digest>]", ref)
+	}
+	r.Registry, r.Repository = parts[0], parts[1]
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// String returns the reference without the oci:// scheme
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Descriptor describes a blob of an artifact
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest lists the blobs of an artifact
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Client pulls artifacts from registries. The registries are accessed anonymously unless Username is set.
+type Client struct {
+	// Context cancels the requests in flight once it is done, context.Background() if not set
+	Context    context.Context
+	HTTPClient *http.Client
+	Username   string
+	Password   string
+	// PlainHTTP accesses the registries over http instead of https
+	PlainHTTP bool
+}
+
+// Manifest returns the manifest of ref along with its digest. The manifest is verified against the digest of ref, if set.
+func (c *Client) Manifest(ref Reference) (*Manifest, string, error) {
+	reference := ref.Digest
+	if reference == "" {
+		reference = ref.Tag
+	}
+	data, err := c.get(ref, "manifests/"+reference, strings.Join([]string{ManifestMediaType, DockerManifestMediaType}, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	if ref.Digest != "" {
+		if err := Verify(data, ref.Digest); err != nil {
+			return nil, "", fmt.Errorf("manifest of %v: %v", ref, err)
+		}
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest of %v: %v", ref, err)
+	}
+	return manifest, fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// Blob returns the blob digest of the repository of ref, after verifying its content against digest
+func (c *Client) Blob(ref Reference, digest string) ([]byte, error) {
+	data, err := c.get(ref, "blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(data, digest); err != nil {
+		return nil, fmt.Errorf("blob of %v: %v", ref, err)
+	}
+	return data, nil
+}
+
+// Verify checks that data matches digest, e.g. sha256:<hex>
+func Verify(data []byte, digest string) error {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid digest %v", digest)
+	}
+	if parts[0] != "sha256" {
+		return fmt.Errorf("unsupported digest algorithm %v", parts[0])
+	}
+	if actual := fmt.Sprintf("%x", sha256.Sum256(data)); actual != strings.ToLower(parts[1]) {
+		return fmt.Errorf("digest mismatch: expected %v, got sha256:%v", digest, actual)
+	}
+	return nil
+}
+
+// CredentialsFromDockerConfig returns the username and the password for registry in the content of a
+// .dockerconfigjson or .dockercfg key of a pull Secret, and false if it has none.
+func CredentialsFromDockerConfig(data []byte, registry string) (string, string, bool) {
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	config := struct {
+		Auths map[string]authEntry `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", false
+	}
+	if config.Auths == nil {
+		// .dockercfg holds the auths at the top level
+		if err := json.Unmarshal(data, &config.Auths); err != nil {
+			return "", "", false
+		}
+	}
+	for server, entry := range config.Auths {
+		host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+		host = strings.SplitN(host, "/", 2)[0]
+		if host != registry && !(registry == dockerHub && host == "index.docker.io") {
+			continue
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				continue
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			return parts[0], parts[1], true
+		}
+		return entry.Username, entry.Password, true
+	}
+	return "", "", false
+}
+
+// PullLayer returns the first layer of ref with mediaType
+func (c *Client) PullLayer(ref Reference, mediaType string) ([]byte, error) {
+	manifest, _, err := c.Manifest(ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == mediaType {
+			return c.Blob(ref, layer.Digest)
+		}
+	}
+	return nil, fmt.Errorf("%v has no layer of media type %v", ref, mediaType)
+}
+
+// get returns the content of the path of the repository of ref, authenticating as requested by the registry
+func (c *Client) get(ref Reference, path string, accept string) ([]byte, error) {
+	registry := ref.Registry
+	if registry == dockerHub {
+		registry = dockerHubRegistry
+	}
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	target := fmt.Sprintf("%v://%v/v2/%v/%v", scheme, registry, ref.Repository, path)
+
+	resp, err := c.do(target, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := c.authorize(challenge, ref)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(target, accept, authorization); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("couldn't get %v: %v", target, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *Client) do(target string, accept string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(c.context())
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+func (c *Client) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// authorize returns the Authorization header answering the WWW-Authenticate challenge of a registry.
+// Bearer challenges are answered with a token pulling the repository of ref.
+func (c *Client) authorize(challenge string, ref Reference) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.Username == "" {
+			return "", fmt.Errorf("%v requires credentials", ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(c.Username, c.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid realm in the challenge of %v: %v", ref.Registry, challenge)
+		}
+		query := realm.Query()
+		if service, ok := params["service"]; ok {
+			query.Set("service", service)
+		}
+		scope := params["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%v:pull", ref.Repository)
+		}
+		query.Set("scope", scope)
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(c.context())
+		if c.Username != "" {
+			req.SetBasicAuth(c.Username, c.Password)
+		}
+		httpClient := c.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("couldn't get a token from %v: %v", realm.Host, resp.Status)
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("invalid token from %v: %v", realm.Host, err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge from %v: %v", ref.Registry, challenge)
+	}
+}
+
+// parseChallenge returns the scheme and the parameters of a WWW-Authenticate header,
+// e.g. Bearer realm="https://quay.io/v2/auth",service="quay.io"
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = rest[eq+1:]
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[strings.ToLower(key)] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return parts[0], params
+}
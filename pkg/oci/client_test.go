@@ -0,0 +1,128 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	type testCase struct {
+		Ref      string
+		Expected Reference
+	}
+
+	testCases := []testCase{
+		{
+			Ref:      "oci://quay.io/opendatahub/charts/odh-dashboard:1.0.0",
+			Expected: Reference{Registry: "quay.io", Repository: "opendatahub/charts/odh-dashboard", Tag: "1.0.0"},
+		},
+		{
+			Ref:      "localhost:5000/odh-dashboard",
+			Expected: Reference{Registry: "localhost:5000", Repository: "odh-dashboard", Tag: "latest"},
+		},
+		{
+			Ref:      "quay.io/opendatahub/odh-dashboard@sha256:abc",
+			Expected: Reference{Registry: "quay.io", Repository: "opendatahub/odh-dashboard", Digest: "sha256:abc"},
+		},
+	}
+	for _, c := range testCases {
+		ref, err := ParseReference(c.Ref)
+		if err != nil {
+			t.Errorf("%v: %v", c.Ref, err)
+			continue
+		}
+		if ref != c.Expected {
+			t.Errorf("%v: got %+v, want %+v", c.Ref, ref, c.Expected)
+		}
+	}
+	if _, err := ParseReference("odh-dashboard"); err == nil {
+		t.Errorf("Expected a reference without registry to be invalid")
+	}
+}
+
+func TestPullLayer(t *testing.T) {
+	const token = "pull-token"
+	chartDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("chart")))
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:charts/dashboard:pull" {
+				http.Error(w, "invalid scope", http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": token})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="registry"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/charts/dashboard/manifests/1.0.0":
+			json.NewEncoder(w).Encode(Manifest{
+				SchemaVersion: 2,
+				Config:        Descriptor{MediaType: "application/vnd.cncf.helm.config.v1+json", Digest: "sha256:config"},
+				Layers:        []Descriptor{{MediaType: "application/vnd.cncf.helm.chart.content.v1.tar+gzip", Digest: chartDigest}, {MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: "sha256:0123"}},
+			})
+		case "/v2/charts/dashboard/blobs/" + chartDigest:
+			w.Write([]byte("chart"))
+		case "/v2/charts/dashboard/blobs/sha256:0123":
+			w.Write([]byte("tampered"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/charts/dashboard:1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{PlainHTTP: true}
+	data, err := client.PullLayer(ref, "application/vnd.cncf.helm.chart.content.v1.tar+gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "chart" {
+		t.Errorf("Unexpected layer %q", data)
+	}
+	if _, err := client.PullLayer(ref, "application/vnd.oci.image.layer.v1.tar+gzip"); err == nil {
+		t.Errorf("Expected pulling a layer not matching its digest to fail")
+	}
+	if _, err := client.PullLayer(ref, "application/vnd.oci.image.config.v1+json"); err == nil {
+		t.Errorf("Expected pulling a missing layer to fail")
+	}
+}
+
+func TestCredentialsFromDockerConfig(t *testing.T) {
+	dockerConfigJSON := []byte(`{"auths":{"quay.io":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("robot:secret")) + `"},` +
+		`"https://index.docker.io/v1/":{"username":"user","password":"pass"}}}`)
+	dockerCfg := []byte(`{"registry.example.com:5000":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("admin:admin")) + `"}}`)
+
+	type testCase struct {
+		Config   []byte
+		Registry string
+		Username string
+		Password string
+		Found    bool
+	}
+	testCases := []testCase{
+		{Config: dockerConfigJSON, Registry: "quay.io", Username: "robot", Password: "secret", Found: true},
+		{Config: dockerConfigJSON, Registry: "docker.io", Username: "user", Password: "pass", Found: true},
+		{Config: dockerConfigJSON, Registry: "ghcr.io"},
+		{Config: dockerCfg, Registry: "registry.example.com:5000", Username: "admin", Password: "admin", Found: true},
+	}
+	for _, c := range testCases {
+		username, password, found := CredentialsFromDockerConfig(c.Config, c.Registry)
+		if username != c.Username || password != c.Password || found != c.Found {
+			t.Errorf("%v: got (%q, %q, %v), want (%q, %q, %v)", c.Registry, username, password, found, c.Username, c.Password, c.Found)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package operatorconfig holds the cluster-wide settings the operator runs with: the flags of the operator,
+// overridden by the OperatorConfig singleton. The OperatorConfig is watched, so that the operator reconfigures
+// itself when it changes instead of being restarted with other flags.
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Defaults are the settings of the flags of the operator, used when the OperatorConfig does not set them
+var Defaults = ocv1alpha1.OperatorConfigSpec{
+	ApplicationsNamespace: "opendatahub",
+	PodSecurity:           ocv1alpha1.PodSecurityBaseline,
+	ServiceMesh:           ocv1alpha1.Unmanaged,
+	Monitoring:            ocv1alpha1.Monitoring{ManagementState: ocv1alpha1.Managed},
+	NetworkPolicy:         ocv1alpha1.NetworkPolicyManaged,
+}
+
+// ChangedFunc returns true if a subscriber is affected by the change of the settings from previous to next
+type ChangedFunc func(previous, next ocv1alpha1.OperatorConfigSpec) bool
+
+type subscriber struct {
+	changed ChangedFunc
+	events  chan event.GenericEvent
+}
+
+var (
+	mu sync.RWMutex
+	// override is the spec of the OperatorConfig, nil when there is none
+	override    *ocv1alpha1.OperatorConfigSpec
+	subscribers []subscriber
+)
+
+// Current returns the settings the operator runs with
+func Current() ocv1alpha1.OperatorConfigSpec {
+	mu.RLock()
+	defer mu.RUnlock()
+	if override == nil {
+		return Effective(ocv1alpha1.OperatorConfigSpec{})
+	}
+	return Effective(*override)
+}
+
+// Effective returns spec with the settings it does not set taken from Defaults
+func Effective(spec ocv1alpha1.OperatorConfigSpec) ocv1alpha1.OperatorConfigSpec {
+	if spec.ApplicationsNamespace == "" {
+		spec.ApplicationsNamespace = Defaults.ApplicationsNamespace
+	}
+	if spec.PodSecurity == "" {
+		spec.PodSecurity = Defaults.PodSecurity
+	}
+	if spec.ServiceMesh == "" {
+		spec.ServiceMesh = Defaults.ServiceMesh
+	}
+	if spec.Monitoring.ManagementState == "" {
+		spec.Monitoring.ManagementState = Defaults.Monitoring.ManagementState
+	}
+	if spec.Monitoring.Namespace == "" {
+		spec.Monitoring.Namespace = Defaults.Monitoring.Namespace
+	}
+	if spec.TrustedCABundle.ConfigMap == "" {
+		spec.TrustedCABundle.ConfigMap = Defaults.TrustedCABundle.ConfigMap
+	}
+	if spec.NetworkPolicy == "" {
+		spec.NetworkPolicy = Defaults.NetworkPolicy
+	}
+	return spec
+}
+
+// Validate returns an error describing the first invalid setting of spec
+func Validate(spec ocv1alpha1.OperatorConfigSpec) error {
+	if spec.ApplicationsNamespace != "" {
+		if errs := validation.IsDNS1123Label(spec.ApplicationsNamespace); len(errs) > 0 {
+			return fmt.Errorf("applicationsNamespace is not a valid namespace name: %v", strings.Join(errs, ", "))
+		}
+	}
+	switch spec.PodSecurity {
+	case "", ocv1alpha1.PodSecurityPrivileged, ocv1alpha1.PodSecurityBaseline, ocv1alpha1.PodSecurityRestricted:
+	default:
+		return fmt.Errorf("podSecurity must be privileged, baseline or restricted, got %q", spec.PodSecurity)
+	}
+	switch spec.ServiceMesh {
+	case "", ocv1alpha1.Managed, ocv1alpha1.Removed, ocv1alpha1.Unmanaged:
+	default:
+		return fmt.Errorf("serviceMesh must be Managed, Removed or Unmanaged, got %q", spec.ServiceMesh)
+	}
+	switch spec.Monitoring.ManagementState {
+	case "", ocv1alpha1.Managed, ocv1alpha1.Removed:
+	default:
+		return fmt.Errorf("monitoring.managementState must be Managed or Removed, got %q", spec.Monitoring.ManagementState)
+	}
+	if spec.Monitoring.Namespace != "" {
+		if errs := validation.IsDNS1123Label(spec.Monitoring.Namespace); len(errs) > 0 {
+			return fmt.Errorf("monitoring.namespace is not a valid namespace name: %v", strings.Join(errs, ", "))
+		}
+	}
+	if cm := spec.TrustedCABundle.ConfigMap; cm != "" {
+		if parts := strings.SplitN(cm, "/", 2); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("trustedCABundle.configMap must be of the form <namespace>/<name>, got %q", cm)
+		}
+	}
+	switch spec.NetworkPolicy {
+	case "", ocv1alpha1.NetworkPolicyManaged, ocv1alpha1.NetworkPolicyUnmanaged:
+	default:
+		return fmt.Errorf("networkPolicy must be Managed or Unmanaged, got %q", spec.NetworkPolicy)
+	}
+	if err := validatePriorityClass("priorityClasses.controlPlane", spec.PriorityClasses.ControlPlane); err != nil {
+		return err
+	}
+	if err := validatePriorityClass("priorityClasses.workloads", spec.PriorityClasses.Workloads); err != nil {
+		return err
+	}
+	controlPlane, workloads := spec.PriorityClasses.ControlPlane, spec.PriorityClasses.Workloads
+	if controlPlane.ManagementState == ocv1alpha1.Managed && workloads.ManagementState == ocv1alpha1.Managed {
+		if controlPlane.Name == workloads.Name {
+			return fmt.Errorf("priorityClasses.controlPlane and priorityClasses.workloads must not both create PriorityClass %v",
+				controlPlane.Name)
+		}
+		if controlPlane.Value <= workloads.Value {
+			return fmt.Errorf("priorityClasses.controlPlane.value must be greater than priorityClasses.workloads.value, got %v and %v",
+				controlPlane.Value, workloads.Value)
+		}
+	}
+	return nil
+}
+
+// MaxPriorityClassValue is the highest value of the PriorityClasses not reserved to the system
+const MaxPriorityClassValue = 1000000000
+
+// validatePriorityClass returns an error describing the first invalid setting of the PriorityClass at field
+func validatePriorityClass(field string, class ocv1alpha1.PriorityClass) error {
+	if class.Name != "" {
+		if errs := validation.IsDNS1123Subdomain(class.Name); len(errs) > 0 {
+			return fmt.Errorf("%v.name is not a valid PriorityClass name: %v", field, strings.Join(errs, ", "))
+		}
+	}
+	switch class.ManagementState {
+	case "", ocv1alpha1.Unmanaged:
+		return nil
+	case ocv1alpha1.Managed:
+	default:
+		return fmt.Errorf("%v.managementState must be Managed or Unmanaged, got %q", field, class.ManagementState)
+	}
+	if class.Name == "" {
+		return fmt.Errorf("%v.name must be set for the operator to create the PriorityClass", field)
+	}
+	if strings.HasPrefix(class.Name, "system-") {
+		return fmt.Errorf("%v.name must not start with system-, the prefix is reserved, got %q", field, class.Name)
+	}
+	if class.Value > MaxPriorityClassValue {
+		return fmt.Errorf("%v.value must be at most %v, got %v", field, MaxPriorityClassValue, class.Value)
+	}
+	return nil
+}
+
+// Set makes the operator run with the settings of spec, or with Defaults when spec is nil, and notifies the
+// subscribers affected by the change. obj is the OperatorConfig the notifications are sent for.
+// It returns true if the settings changed.
+func Set(spec *ocv1alpha1.OperatorConfigSpec, obj *ocv1alpha1.OperatorConfig) bool {
+	mu.Lock()
+	previous := Effective(ocv1alpha1.OperatorConfigSpec{})
+	if override != nil {
+		previous = Effective(*override)
+	}
+	override = nil
+	next := Effective(ocv1alpha1.OperatorConfigSpec{})
+	if spec != nil {
+		override = spec.DeepCopy()
+		next = Effective(*spec)
+	}
+	notified := []chan event.GenericEvent{}
+	for _, s := range subscribers {
+		if s.changed(previous, next) {
+			notified = append(notified, s.events)
+		}
+	}
+	mu.Unlock()
+
+	if obj == nil {
+		obj = &ocv1alpha1.OperatorConfig{}
+		obj.Name = ocv1alpha1.SingletonName
+	}
+	for _, events := range notified {
+		// A pending notification is enough, the subscribers read the current settings once notified
+		select {
+		case events <- event.GenericEvent{Meta: obj, Object: obj}:
+		default:
+		}
+	}
+	return previous != next
+}
+
+// Subscribe returns the channel notified when the settings change in a way changed reports, to use as the
+// source of a watch of a controller
+func Subscribe(changed ChangedFunc) <-chan event.GenericEvent {
+	mu.Lock()
+	defer mu.Unlock()
+	events := make(chan event.GenericEvent, 1)
+	subscribers = append(subscribers, subscriber{changed: changed, events: events})
+	return events
+}
+
+// Load reads the OperatorConfig with reader, so that the controllers start with its settings instead of
+// reconciling with Defaults until it is reconciled. The Defaults are kept when it is missing or invalid.
+func Load(reader client.Reader) {
+	instance := &ocv1alpha1.OperatorConfig{}
+	err := reader.Get(context.TODO(), client.ObjectKey{Name: ocv1alpha1.SingletonName}, instance)
+	if err != nil {
+		if !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			log.Warnf("Failed to read OperatorConfig %v, running with the flags until it is reconciled: %v.",
+				ocv1alpha1.SingletonName, err)
+		}
+		return
+	}
+	if err := Validate(instance.Spec); err != nil {
+		log.Warnf("Ignoring the invalid OperatorConfig %v: %v.", ocv1alpha1.SingletonName, err)
+		return
+	}
+	log.Infof("Running with the settings of OperatorConfig %v.", ocv1alpha1.SingletonName)
+	Set(&instance.Spec, instance)
+}
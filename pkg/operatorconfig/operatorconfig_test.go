@@ -0,0 +1,155 @@
+package operatorconfig
+
+import (
+	"testing"
+
+	ocv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/operatorconfig/v1alpha1"
+)
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		Name  string
+		Spec  ocv1alpha1.OperatorConfigSpec
+		Valid bool
+	}
+	testCases := []testCase{
+		{
+			Name:  "defaults",
+			Spec:  ocv1alpha1.OperatorConfigSpec{},
+			Valid: true,
+		},
+		{
+			Name: "all settings",
+			Spec: ocv1alpha1.OperatorConfigSpec{
+				ApplicationsNamespace: "odh-applications",
+				PodSecurity:           ocv1alpha1.PodSecurityRestricted,
+				ServiceMesh:           ocv1alpha1.Managed,
+				Monitoring:            ocv1alpha1.Monitoring{ManagementState: ocv1alpha1.Removed, Namespace: "prometheus"},
+				TrustedCABundle:       ocv1alpha1.TrustedCABundle{ConfigMap: "openshift-config/custom-ca"},
+				NetworkPolicy:         ocv1alpha1.NetworkPolicyUnmanaged,
+				PriorityClasses: ocv1alpha1.PriorityClasses{
+					ControlPlane: ocv1alpha1.PriorityClass{Name: "odh-control-plane", ManagementState: ocv1alpha1.Managed, Value: 1000000},
+					Workloads:    ocv1alpha1.PriorityClass{Name: "odh-workloads", ManagementState: ocv1alpha1.Managed, Value: 1000},
+				},
+			},
+			Valid: true,
+		},
+		{
+			Name: "existing priority classes",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				ControlPlane: ocv1alpha1.PriorityClass{Name: "high-priority"},
+				Workloads:    ocv1alpha1.PriorityClass{Name: "low-priority"},
+			}},
+			Valid: true,
+		},
+		{
+			Name: "invalid applications namespace",
+			Spec: ocv1alpha1.OperatorConfigSpec{ApplicationsNamespace: "Open Data Hub"},
+		},
+		{
+			Name: "invalid pod security level",
+			Spec: ocv1alpha1.OperatorConfigSpec{PodSecurity: "Restricted"},
+		},
+		{
+			Name: "invalid service mesh state",
+			Spec: ocv1alpha1.OperatorConfigSpec{ServiceMesh: "Enabled"},
+		},
+		{
+			Name: "invalid monitoring state",
+			Spec: ocv1alpha1.OperatorConfigSpec{Monitoring: ocv1alpha1.Monitoring{ManagementState: "Unmanaged"}},
+		},
+		{
+			Name: "trusted CA bundle without namespace",
+			Spec: ocv1alpha1.OperatorConfigSpec{TrustedCABundle: ocv1alpha1.TrustedCABundle{ConfigMap: "custom-ca"}},
+		},
+		{
+			Name: "invalid network policy mode",
+			Spec: ocv1alpha1.OperatorConfigSpec{NetworkPolicy: "Removed"},
+		},
+		{
+			Name: "invalid priority class name",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				Workloads: ocv1alpha1.PriorityClass{Name: "Low Priority"},
+			}},
+		},
+		{
+			Name: "managed priority class without name",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				ControlPlane: ocv1alpha1.PriorityClass{ManagementState: ocv1alpha1.Managed, Value: 1000},
+			}},
+		},
+		{
+			Name: "managed system priority class",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				ControlPlane: ocv1alpha1.PriorityClass{Name: "system-cluster-critical", ManagementState: ocv1alpha1.Managed},
+			}},
+		},
+		{
+			Name: "priority class value too high",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				ControlPlane: ocv1alpha1.PriorityClass{Name: "odh-control-plane", ManagementState: ocv1alpha1.Managed, Value: 2000000000},
+			}},
+		},
+		{
+			Name: "workloads prioritized over the control plane",
+			Spec: ocv1alpha1.OperatorConfigSpec{PriorityClasses: ocv1alpha1.PriorityClasses{
+				ControlPlane: ocv1alpha1.PriorityClass{Name: "odh-control-plane", ManagementState: ocv1alpha1.Managed, Value: 1000},
+				Workloads:    ocv1alpha1.PriorityClass{Name: "odh-workloads", ManagementState: ocv1alpha1.Managed, Value: 1000},
+			}},
+		},
+	}
+	for _, c := range testCases {
+		if err := Validate(c.Spec); (err == nil) != c.Valid {
+			t.Errorf("%v: Validate() = %v, expected valid: %v", c.Name, err, c.Valid)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	defer Set(nil, nil)
+	bundles := Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.TrustedCABundle != next.TrustedCABundle
+	})
+	policies := Subscribe(func(previous, next ocv1alpha1.OperatorConfigSpec) bool {
+		return previous.NetworkPolicy != next.NetworkPolicy
+	})
+
+	spec := &ocv1alpha1.OperatorConfigSpec{NetworkPolicy: ocv1alpha1.NetworkPolicyUnmanaged}
+	if !Set(spec, nil) {
+		t.Errorf("Expected the settings to change")
+	}
+	current := Current()
+	if current.NetworkPolicy != ocv1alpha1.NetworkPolicyUnmanaged || current.ApplicationsNamespace != Defaults.ApplicationsNamespace {
+		t.Errorf("Expected the network policy of the spec and the default applications namespace, got %+v", current)
+	}
+	select {
+	case e := <-policies:
+		if e.Meta.GetName() != ocv1alpha1.SingletonName {
+			t.Errorf("Expected a notification for OperatorConfig %v, got %v", ocv1alpha1.SingletonName, e.Meta.GetName())
+		}
+	default:
+		t.Errorf("Expected the subscriber of the network policy to be notified")
+	}
+	select {
+	case <-bundles:
+		t.Errorf("Expected the subscriber of the trusted CA bundle not to be notified")
+	default:
+	}
+
+	// the spec is copied, and setting the same settings again notifies no one
+	spec.NetworkPolicy = ocv1alpha1.NetworkPolicyManaged
+	if Set(&ocv1alpha1.OperatorConfigSpec{NetworkPolicy: ocv1alpha1.NetworkPolicyUnmanaged}, nil) {
+		t.Errorf("Expected the settings not to change")
+	}
+	if len(policies) != 0 {
+		t.Errorf("Expected no notification when the settings do not change")
+	}
+
+	// the defaults are restored once the OperatorConfig is deleted
+	if !Set(nil, nil) || Current() != Defaults {
+		t.Errorf("Expected the defaults to be restored, got %+v", Current())
+	}
+	if len(policies) != 1 {
+		t.Errorf("Expected the subscriber of the network policy to be notified")
+	}
+}
@@ -0,0 +1,33 @@
+// Package profiling serves the net/http/pprof endpoints of the operator, to capture CPU and heap profiles
+// of the manifest renders. They are only served on the loopback interface, e.g. through kubectl port-forward.
+package profiling
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NewHandler returns the handler of the pprof endpoints under /debug/pprof/
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve serves the pprof endpoints on port of localhost in the background
+func Serve(port int) {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	go func() {
+		log.Infof("Serving pprof endpoints on %v.", addr)
+		if err := http.ListenAndServe(addr, NewHandler()); err != nil {
+			log.Errorf("pprof server exited. Error: %v.", err)
+		}
+	}()
+}
@@ -0,0 +1,31 @@
+package profiling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	type testCase struct {
+		Path           string
+		ExpectedStatus int
+	}
+
+	testCases := []testCase{
+		{Path: "/debug/pprof/", ExpectedStatus: http.StatusOK},
+		{Path: "/debug/pprof/heap", ExpectedStatus: http.StatusOK},
+		{Path: "/debug/pprof/goroutine?debug=1", ExpectedStatus: http.StatusOK},
+		{Path: "/debug/pprof/unknown", ExpectedStatus: http.StatusNotFound},
+		{Path: "/metrics", ExpectedStatus: http.StatusNotFound},
+	}
+
+	handler := NewHandler()
+	for _, c := range testCases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.Path, nil))
+		if rec.Code != c.ExpectedStatus {
+			t.Errorf("%v: expected status %v, got %v", c.Path, c.ExpectedStatus, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package reconcilecontext creates the contexts of the reconciliations of the operator, passed down to the KfApp
+// plugins downloading the manifests and applying them. Every context expires after Timeout, and they are all
+// cancelled once the operator shuts down, so that the downloads and the applies in flight do not outlive their
+// reconciliation.
+package reconcilecontext
+
+import (
+	"context"
+	"time"
+)
+
+// StatusTimeout is how long the outcome of a reconciliation that timed out may take to be recorded
+const StatusTimeout = 30 * time.Second
+
+var (
+	// Timeout is how long a reconciliation of a KfDef may download and apply its manifests, 0 for no limit
+	Timeout = 30 * time.Minute
+
+	// base is cancelled once the operator shuts down
+	base, cancelBase = context.WithCancel(context.Background())
+)
+
+// CancelOnStop cancels the contexts of the reconciliations once stop is closed, when the operator shuts down
+func CancelOnStop(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		cancelBase()
+	}()
+}
+
+// New returns the context of a reconciliation, expiring after Timeout
+func New() (context.Context, context.CancelFunc) {
+	if Timeout > 0 {
+		return context.WithTimeout(base, Timeout)
+	}
+	return context.WithCancel(base)
+}
+
+// ForStatus returns the context recording the outcome of the reconciliation of ctx: ctx itself, or once it expired,
+// a context expiring after StatusTimeout for the failure of the reconciliation to be recorded still
+func ForStatus(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Err() != context.DeadlineExceeded {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(base, StatusTimeout)
+}
@@ -0,0 +1,33 @@
+package reconcilecontext
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	defer func(timeout time.Duration) { Timeout = timeout }(Timeout)
+	Timeout = 50 * time.Millisecond
+
+	ctx, cancel := New()
+	defer cancel()
+	status, cancelStatus := ForStatus(ctx)
+	if status != ctx {
+		t.Errorf("Expected the status to be recorded with the context of the reconciliation")
+	}
+	cancelStatus()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("Expected the context to expire after %v", Timeout)
+	}
+
+	status, cancelStatus = ForStatus(ctx)
+	defer cancelStatus()
+	if status.Err() != nil {
+		t.Errorf("Expected the status to be recorded once the reconciliation timed out, got %v", status.Err())
+	}
+	if deadline, ok := status.Deadline(); !ok || time.Until(deadline) > StatusTimeout {
+		t.Errorf("Expected the status to be recorded within %v", StatusTimeout)
+	}
+}
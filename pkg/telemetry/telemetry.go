@@ -0,0 +1,225 @@
+// Package telemetry periodically reports usage data of the DataScienceClusters opting in to it: the version of the
+// operator, the enabled components and the size bucket of the cluster, along with a random ID of the installation.
+// The data is exposed as metrics of the operator, and sent to Endpoint unless the DataScienceCluster only wants the
+// metrics.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var (
+	// Endpoint is the URL the usage reports are posted to as JSON. The reports are only exposed as metrics when it
+	// is empty.
+	Endpoint = ""
+	// Interval is the time between two usage reports
+	Interval = 24 * time.Hour
+	// OperatorVersion is the version of the operator reported
+	OperatorVersion = ""
+	// IDSecret is the Secret of the namespace of the operator holding the ID of the installation reported
+	IDSecret = "odh-telemetry-id"
+)
+
+// idKey is the key of the ID of the installation in IDSecret
+const idKey = "id"
+
+// clusterSizes are the upper bounds of the number of nodes of the size buckets of the clusters
+var clusterSizes = []struct {
+	maxNodes int
+	bucket   string
+}{
+	{3, "1-3"},
+	{10, "4-10"},
+	{50, "11-50"},
+	{200, "51-200"},
+}
+
+// Report is the usage data sent for a DataScienceCluster
+type Report struct {
+	// ClusterID is a random ID generated once the installation first reports its usage, and kept in IDSecret. It
+	// tells the reports of an installation apart from the others, and is derived from nothing known of the cluster.
+	ClusterID       string   `json:"clusterId"`
+	OperatorVersion string   `json:"operatorVersion"`
+	Components      []string `json:"components"`
+	ClusterSize     string   `json:"clusterSize"`
+}
+
+// AddToManager adds the usage reporter to the manager. It reports the usage once started, then every Interval.
+func AddToManager(mgr manager.Manager) error {
+	// The ID of the installation is not reported when the operator runs outside of the cluster
+	namespace, _ := k8sutil.GetOperatorNamespace()
+	reporter := &reporter{
+		reader:    mgr.GetAPIReader(),
+		writer:    mgr.GetClient(),
+		namespace: namespace,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+		for {
+			if err := reporter.report(); err != nil {
+				log.Warnf("Failed to report the usage. Error: %v.", err)
+			}
+			select {
+			case <-stop:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}))
+}
+
+// reporter reports the usage of the DataScienceClusters read from the API server
+type reporter struct {
+	reader client.Reader
+	// writer creates IDSecret in namespace, the namespace of the operator
+	writer    client.Writer
+	namespace string
+	client    *http.Client
+}
+
+// report exposes and sends the usage data of every DataScienceCluster opting in to the telemetry
+func (r *reporter) report() error {
+	instances := &dscv1alpha1.DataScienceClusterList{}
+	if err := r.reader.List(context.TODO(), instances); err != nil {
+		return fmt.Errorf("failed to list the DataScienceClusters: %v", err)
+	}
+	metrics.ResetUsage()
+	var clusterID, clusterSize string
+	for _, instance := range instances.Items {
+		if !instance.Spec.Telemetry.Enabled {
+			continue
+		}
+		// The cluster is only looked up when a DataScienceCluster opts in
+		if clusterID == "" {
+			var err error
+			if clusterID, clusterSize, err = r.cluster(); err != nil {
+				return err
+			}
+		}
+		report := newReport(&instance, clusterID, clusterSize)
+		metrics.UsageInfo.WithLabelValues(instance.Namespace, instance.Name, report.ClusterID, report.OperatorVersion,
+			report.ClusterSize).Set(1)
+		for _, status := range instance.Status.Components {
+			value := 0.0
+			if status.Enabled {
+				value = 1
+			}
+			metrics.UsageComponentEnabled.WithLabelValues(instance.Namespace, instance.Name, status.Name).Set(value)
+		}
+		if instance.Spec.Telemetry.MetricsOnly || Endpoint == "" {
+			continue
+		}
+		if err := r.send(report); err != nil {
+			log.Warnf("Failed to send the usage of DataScienceCluster %v/%v. Error: %v.", instance.Namespace, instance.Name, err)
+		}
+	}
+	return nil
+}
+
+// cluster returns the ID of the installation and the size bucket of the cluster
+func (r *reporter) cluster() (string, string, error) {
+	id, err := r.installationID()
+	if err != nil {
+		return "", "", err
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.reader.List(context.TODO(), nodes); err != nil {
+		return "", "", fmt.Errorf("failed to list the nodes: %v", err)
+	}
+	return id, clusterSize(len(nodes.Items)), nil
+}
+
+// installationID returns the ID of the installation kept in IDSecret, generating it on the first report
+func (r *reporter) installationID() (string, error) {
+	if r.namespace == "" {
+		return "", fmt.Errorf("the operator has no namespace to keep the ID of the installation in")
+	}
+	key := client.ObjectKey{Namespace: r.namespace, Name: IDSecret}
+	secret := &corev1.Secret{}
+	err := r.reader.Get(context.TODO(), key, secret)
+	if err == nil && len(secret.Data[idKey]) > 0 {
+		return string(secret.Data[idKey]), nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get the Secret %v: %v", key, err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	secret.Data = map[string][]byte{idKey: []byte(hex.EncodeToString(id))}
+	if err == nil {
+		// the Secret lost its ID
+		err = r.writer.Update(context.TODO(), secret)
+	} else {
+		secret.ObjectMeta = metav1.ObjectMeta{Name: IDSecret, Namespace: r.namespace, Labels: kfutils.WithManagedLabel(nil)}
+		err = r.writer.Create(context.TODO(), secret)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write the Secret %v: %v", key, err)
+	}
+	return string(secret.Data[idKey]), nil
+}
+
+// send posts the report to Endpoint
+func (r *reporter) send(report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
+
+// newReport returns the usage report of the DataScienceCluster, listing the components enabled in its status
+func newReport(instance *dscv1alpha1.DataScienceCluster, clusterID, clusterSize string) *Report {
+	report := &Report{
+		ClusterID:       clusterID,
+		OperatorVersion: OperatorVersion,
+		Components:      []string{},
+		ClusterSize:     clusterSize,
+	}
+	for _, status := range instance.Status.Components {
+		if status.Enabled {
+			report.Components = append(report.Components, status.Name)
+		}
+	}
+	return report
+}
+
+// clusterSize returns the size bucket of a cluster with the number of nodes
+func clusterSize(nodes int) string {
+	for _, size := range clusterSizes {
+		if nodes <= size.maxNodes {
+			return size.bucket
+		}
+	}
+	return "201+"
+}
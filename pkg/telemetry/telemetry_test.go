@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	dscv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datasciencecluster/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterSize(t *testing.T) {
+	for nodes, expected := range map[int]string{1: "1-3", 3: "1-3", 4: "4-10", 50: "11-50", 120: "51-200", 500: "201+"} {
+		if size := clusterSize(nodes); size != expected {
+			t.Errorf("Expected %v nodes to be in bucket %v, got %v", nodes, expected, size)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	reports := []Report{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Report{}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("Failed to parse the report: %v", err)
+		}
+		reports = append(reports, report)
+	}))
+	defer server.Close()
+	Endpoint = server.URL
+	OperatorVersion = "1.1.0"
+	defer func() { Endpoint, OperatorVersion = "", "" }()
+
+	status := dscv1alpha1.DataScienceClusterStatus{
+		Components: []dscv1alpha1.ComponentStatus{
+			{Name: "dashboard", Enabled: true},
+			{Name: "workbenches", Enabled: false},
+			{Name: "modelserving", Enabled: true},
+		},
+	}
+	kubeclient := fake.NewFakeClient(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}},
+		&dscv1alpha1.DataScienceCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "reported", Namespace: "opendatahub"},
+			Spec:       dscv1alpha1.DataScienceClusterSpec{Telemetry: dscv1alpha1.Telemetry{Enabled: true}},
+			Status:     status,
+		},
+		&dscv1alpha1.DataScienceCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics-only", Namespace: "opendatahub"},
+			Spec: dscv1alpha1.DataScienceClusterSpec{
+				Telemetry: dscv1alpha1.Telemetry{Enabled: true, MetricsOnly: true},
+			},
+			Status: status,
+		},
+		&dscv1alpha1.DataScienceCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "opted-out", Namespace: "opendatahub"},
+			Status:     status,
+		},
+	)
+	reporter := &reporter{reader: kubeclient, writer: kubeclient, namespace: "opendatahub-operator", client: server.Client()}
+	if err := reporter.report(); err != nil {
+		t.Fatalf("Failed to report the usage: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := kubeclient.Get(context.TODO(), types.NamespacedName{Namespace: "opendatahub-operator", Name: IDSecret}, secret); err != nil {
+		t.Fatalf("Expected the ID of the installation to be kept in a Secret: %v", err)
+	}
+	id := string(secret.Data[idKey])
+	if len(id) != 32 {
+		t.Errorf("Expected a random ID of 16 bytes, got %q", id)
+	}
+	expected := []Report{{
+		ClusterID:       id,
+		OperatorVersion: "1.1.0",
+		Components:      []string{"dashboard", "modelserving"},
+		ClusterSize:     "1-3",
+	}}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("Expected the reports %+v, got %+v", expected, reports)
+	}
+	// the next reports are sent with the same ID
+	if err := reporter.report(); err != nil {
+		t.Fatalf("Failed to report the usage: %v", err)
+	}
+	if len(reports) != 2 || reports[1].ClusterID != id {
+		t.Errorf("Expected the ID %v to be reported again, got %+v", id, reports)
+	}
+	if n := testutil.CollectAndCount(metrics.UsageInfo); n != 2 {
+		t.Errorf("Expected the usage of the 2 DataScienceClusters opting in, got %v series", n)
+	}
+	enabled := testutil.ToFloat64(metrics.UsageComponentEnabled.WithLabelValues("opendatahub", "metrics-only", "modelserving"))
+	if enabled != 1 {
+		t.Errorf("Expected modelserving to be reported as enabled, got %v", enabled)
+	}
+}
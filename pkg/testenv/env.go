@@ -0,0 +1,173 @@
+//go:build integration
+// +build integration
+
+package testenv
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	apis "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// kubebuilderAssetsEnvVar is the directory of the kube-apiserver and etcd binaries of the control plane
+	kubebuilderAssetsEnvVar = "KUBEBUILDER_ASSETS"
+	// defaultKubebuilderAssets is where envtest looks the binaries up when KUBEBUILDER_ASSETS is not set
+	defaultKubebuilderAssets = "/usr/local/kubebuilder/bin"
+	// useExistingClusterEnvVar makes envtest run the tests against the cluster of the kubeconfig instead
+	useExistingClusterEnvVar = "USE_EXISTING_CLUSTER"
+
+	// maxReconciles is how many times Reconcile reconciles a request that keeps being requeued
+	maxReconciles = 10
+)
+
+// Environment is a control plane started with envtest, with the CRDs of the operator installed
+type Environment struct {
+	// Config of the control plane. It is also written to the kubeconfig KUBECONFIG points at while the
+	// environment runs, for the kfctl packages loading their own config.
+	Config *rest.Config
+	// Scheme holds the Kubernetes types and the types of the operator
+	Scheme *k8sruntime.Scheme
+	// Client reads from and writes to the control plane directly, without a cache
+	Client client.Client
+
+	env        *envtest.Environment
+	dir        string
+	kubeconfig *string
+}
+
+// Available returns true if the control plane can be started, the tests requiring it are skipped otherwise
+func Available() bool {
+	if os.Getenv(useExistingClusterEnvVar) == "true" {
+		return true
+	}
+	assets := os.Getenv(kubebuilderAssetsEnvVar)
+	if assets == "" {
+		assets = defaultKubebuilderAssets
+	}
+	for _, binary := range []string{"kube-apiserver", "etcd"} {
+		if _, err := os.Stat(filepath.Join(assets, binary)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Start starts the control plane and installs the CRDs of deploy/crds. Stop it once the tests are done.
+func Start() (*Environment, error) {
+	_, file, _, _ := runtime.Caller(0)
+	e := &Environment{
+		env: &envtest.Environment{
+			CRDDirectoryPaths: []string{filepath.Join(filepath.Dir(file), "..", "..", "deploy", "crds")},
+		},
+		Scheme: k8sruntime.NewScheme(),
+	}
+	cfg, err := e.env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the control plane: %v", err)
+	}
+	e.Config = cfg
+	if err := e.init(); err != nil {
+		e.Stop()
+		return nil, err
+	}
+	return e, nil
+}
+
+// init sets up the scheme, the client and the kubeconfig of the started control plane
+func (e *Environment) init() error {
+	if err := clientgoscheme.AddToScheme(e.Scheme); err != nil {
+		return err
+	}
+	if err := apis.AddToScheme(e.Scheme); err != nil {
+		return err
+	}
+	c, err := client.New(e.Config, client.Options{Scheme: e.Scheme})
+	if err != nil {
+		return err
+	}
+	e.Client = c
+
+	// The kubeconfig of an existing cluster is used as it is
+	if os.Getenv(useExistingClusterEnvVar) == "true" {
+		return nil
+	}
+	e.dir, err = ioutil.TempDir("", "testenv")
+	if err != nil {
+		return err
+	}
+	kubeconfig := filepath.Join(e.dir, "kubeconfig")
+	if err := writeKubeconfig(e.Config, kubeconfig); err != nil {
+		return err
+	}
+	if previous, ok := os.LookupEnv("KUBECONFIG"); ok {
+		e.kubeconfig = &previous
+	}
+	return os.Setenv("KUBECONFIG", kubeconfig)
+}
+
+// Stop stops the control plane and restores KUBECONFIG
+func (e *Environment) Stop() error {
+	if e.dir != "" {
+		if e.kubeconfig != nil {
+			os.Setenv("KUBECONFIG", *e.kubeconfig)
+		} else {
+			os.Unsetenv("KUBECONFIG")
+		}
+		os.RemoveAll(e.dir)
+	}
+	return e.env.Stop()
+}
+
+// CreateNamespace creates the namespace the resources of a test are deployed in
+func (e *Environment) CreateNamespace(name string) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	return e.Client.Create(context.TODO(), ns)
+}
+
+// Reconcile reconciles the request with r until it is not requeued right away, the way the controller would
+func Reconcile(r reconcile.Reconciler, key types.NamespacedName) (reconcile.Result, error) {
+	for i := 0; i < maxReconciles; i++ {
+		result, err := r.Reconcile(reconcile.Request{NamespacedName: key})
+		if err != nil || !result.Requeue {
+			return result, err
+		}
+	}
+	return reconcile.Result{}, fmt.Errorf("%v still requeued after %d reconciles", key, maxReconciles)
+}
+
+// writeKubeconfig writes the kubeconfig of cfg to file
+func writeKubeconfig(cfg *rest.Config, file string) error {
+	server := cfg.Host
+	if !strings.Contains(server, "://") {
+		// the control plane serves on an insecure port
+		server = "http://" + server
+	}
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["envtest"] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: cfg.CAData}
+	kubeconfig.AuthInfos["envtest"] = &clientcmdapi.AuthInfo{
+		Token:                 cfg.BearerToken,
+		ClientCertificateData: cfg.CertData,
+		ClientKeyData:         cfg.KeyData,
+	}
+	kubeconfig.Contexts["envtest"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest"}
+	kubeconfig.CurrentContext = "envtest"
+	return clientcmd.WriteToFile(*kubeconfig, file)
+}
@@ -0,0 +1,152 @@
+// Package testenv is the harness of the integration tests of the controllers: a control plane started with envtest
+// with the CRDs of the operator installed, and a server of fixture manifests the KfDef instances of the tests are
+// deployed from, so that they are rendered, applied and pruned without a real cluster nor network access.
+//
+// The control plane is only built with the integration build tag, and requires the kube-apiserver and etcd
+// binaries of KUBEBUILDER_ASSETS, see Available:
+//
+//	KUBEBUILDER_ASSETS=/usr/local/kubebuilder/bin go test -tags integration ./pkg/...
+package testenv
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// tarballSuffix is the suffix of the paths the fixtures are served at
+const tarballSuffix = ".tar.gz"
+
+// ManifestServer serves the directories of a fixtures directory as gzipped tarballs at /<directory>.tar.gz, laid
+// out like the GitHub tarballs of odh-manifests with the files under a single top directory. The tarballs are
+// built on every request, so that a test can change the fixtures between two reconciles.
+type ManifestServer struct {
+	*httptest.Server
+	dir string
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// NewManifestServer starts serving the fixtures of dir. Close it once the tests are done.
+func NewManifestServer(dir string) *ManifestServer {
+	s := &ManifestServer{dir: dir, requests: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// URI returns the URI of the repo of the fixtures directory name, to use as the URI of a repo of a KfDef
+func (s *ManifestServer) URI(name string) string {
+	return s.URL + "/" + name + tarballSuffix
+}
+
+// Requests returns how many times the tarball of the fixtures directory name was fetched
+func (s *ManifestServer) Requests(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[name]
+}
+
+func (s *ManifestServer) serve(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	if req.Method != http.MethodGet || !strings.HasSuffix(name, tarballSuffix) || strings.Contains(name, "/") {
+		http.NotFound(w, req)
+		return
+	}
+	name = strings.TrimSuffix(name, tarballSuffix)
+	dir := path.Join(s.dir, name)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		http.NotFound(w, req)
+		return
+	}
+	data, err := Tarball(dir, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.requests[name]++
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Write(data)
+}
+
+// Tarball returns the files of dir as a gzipped tarball, under the top directory prefix
+func Tarball(dir string, prefix string) ([]byte, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if info.IsDir() {
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteManifests writes the files, by path relative to the fixtures directory, replacing the fixtures directories
+// they are in, e.g. to change the manifests of an application between two reconciles
+func WriteManifests(dir string, files map[string]string) error {
+	replaced := map[string]bool{}
+	for name := range files {
+		top := strings.SplitN(filepath.ToSlash(filepath.Clean(name)), "/", 2)[0]
+		if !replaced[top] {
+			if err := os.RemoveAll(path.Join(dir, top)); err != nil {
+				return err
+			}
+			replaced[top] = true
+		}
+	}
+	for name, content := range files {
+		file := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package testenv
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+)
+
+func TestManifestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = WriteManifests(path.Join(dir, "fixtures"), map[string]string{
+		"odh-manifests/odh-test/kustomization.yaml": "resources:\n- configmap.yaml\n",
+		"odh-manifests/odh-test/configmap.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: odh-test\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewManifestServer(path.Join(dir, "fixtures"))
+	defer s.Close()
+
+	c := &kfconfig.KfConfig{
+		Spec: kfconfig.KfConfigSpec{
+			AppDir: path.Join(dir, "app"),
+			Repos:  []kfconfig.Repo{{Name: "manifests", URI: s.URI("odh-manifests")}},
+		},
+	}
+	if err := c.SyncCache(); err != nil {
+		t.Fatalf("SyncCache() failed: %v", err)
+	}
+	cache, ok := c.GetRepoCache("manifests")
+	if !ok {
+		t.Fatalf("expected the manifests repo to be cached, got %+v", c.Status.Caches)
+	}
+	data, err := ioutil.ReadFile(path.Join(cache.LocalPath, "odh-test", "configmap.yaml"))
+	if err != nil || string(data) != "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: odh-test\n" {
+		t.Errorf("expected the fixtures to be served, got %q, %v", data, err)
+	}
+	if requests := s.Requests("odh-manifests"); requests != 1 {
+		t.Errorf("expected the tarball to be fetched once, got %v", requests)
+	}
+
+	// the fixtures are replaced along with the directory they are in
+	if err := WriteManifests(path.Join(dir, "fixtures"), map[string]string{
+		"odh-manifests/odh-test/kustomization.yaml": "resources: []\n",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(dir, "fixtures", "odh-manifests", "odh-test", "configmap.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected the previous fixtures to be removed, got %v", err)
+	}
+
+	for _, uri := range []string{s.URL + "/missing.tar.gz", s.URL + "/odh-manifests"} {
+		resp, err := http.Get(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected %v to be not found, got %v", uri, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Package tracing records the spans of the reconciliations of the KfDefs and exports them to an OpenTelemetry
+// collector with the OTLP/HTTP protocol, in its JSON encoding. Tracing is disabled unless Endpoint is set, the spans
+// are then nil and their methods no-ops.
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// spanKindInternal and the status codes are the values of the OTLP enums
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+	// maxQueuedSpans bounds the spans waiting to be exported, the other ones are dropped
+	maxQueuedSpans = 4096
+)
+
+var (
+	// Endpoint is the base URL of the OTLP/HTTP receiver of the collector, e.g. http://otel-collector:4318.
+	// The spans are sent to its /v1/traces path.
+	Endpoint = ""
+	// ServiceName is the service.name resource attribute of the spans
+	ServiceName = "opendatahub-operator"
+	// ExportInterval is the time between two exports of the spans
+	ExportInterval = 5 * time.Second
+
+	exporter = &spanExporter{client: &http.Client{Timeout: 10 * time.Second}}
+
+	activeMu sync.Mutex
+	active   = map[string]*Span{}
+)
+
+// Span is a timed operation of a reconciliation. A nil Span is a disabled one.
+type Span struct {
+	traceID    string
+	spanID     string
+	parentID   string
+	name       string
+	start      time.Time
+	attributes map[string]string
+}
+
+// Start starts a span named name, child of parent, or the root of a new trace when parent is nil. attributes are
+// pairs of keys and values. It returns nil when tracing is disabled.
+func Start(parent *Span, name string, attributes ...string) *Span {
+	if Endpoint == "" {
+		return nil
+	}
+	span := &Span{
+		spanID:     randomID(8),
+		name:       name,
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomID(16)
+	}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		span.attributes[attributes[i]] = attributes[i+1]
+	}
+	return span
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attributes[key] = value
+}
+
+// End ends the span, with an error status if err is not nil, and queues it for export
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	exporter.queue(s.record(time.Now(), err))
+}
+
+// Key returns the key of the active span of the reconciliation of a KfDef
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// SetActive makes span the active span of key, the parent of the spans started by the code that does not receive it,
+// e.g. the KfApp plugins. A nil span clears it.
+func SetActive(key string, span *Span) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if span == nil {
+		delete(active, key)
+		return
+	}
+	active[key] = span
+}
+
+// Active returns the active span of key, or nil if there is none
+func Active(key string) *Span {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	return active[key]
+}
+
+// Flush exports the queued spans
+func Flush() {
+	exporter.flush()
+}
+
+// randomID returns n random bytes, hex-encoded
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Warnf("Failed to generate a span ID: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// keyValue, anyValue, spanStatus and otlpSpan are the JSON encoding of the OTLP messages
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            spanStatus `json:"status"`
+}
+
+// record returns the OTLP span of s ended at end
+func (s *Span) record(end time.Time, err error) otlpSpan {
+	span := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:        attributes(s.attributes),
+		Status:            spanStatus{Code: statusCodeOk},
+	}
+	if err != nil {
+		span.Status = spanStatus{Code: statusCodeError, Message: err.Error()}
+	}
+	return span
+}
+
+// attributes returns the OTLP attributes of m
+func attributes(m map[string]string) []keyValue {
+	kvs := []keyValue{}
+	for k, v := range m {
+		kvs = append(kvs, keyValue{Key: k, Value: anyValue{StringValue: v}})
+	}
+	return kvs
+}
+
+// spanExporter sends the ended spans to Endpoint every ExportInterval
+type spanExporter struct {
+	client  *http.Client
+	once    sync.Once
+	mu      sync.Mutex
+	spans   []otlpSpan
+	dropped int
+}
+
+// queue queues span for the next export, starting the export loop on the first call
+func (e *spanExporter) queue(span otlpSpan) {
+	e.once.Do(func() {
+		go func() {
+			for range time.Tick(ExportInterval) {
+				e.flush()
+			}
+		}()
+	})
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= maxQueuedSpans {
+		e.dropped++
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+// flush exports the queued spans. They are dropped if the collector cannot be reached.
+func (e *spanExporter) flush() {
+	e.mu.Lock()
+	spans, dropped := e.spans, e.dropped
+	e.spans, e.dropped = nil, 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		log.Warnf("Dropped %v spans, the collector %v is too slow", dropped, Endpoint)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	if err := e.export(spans); err != nil {
+		log.Warnf("Failed to export %v spans to %v: %v", len(spans), Endpoint, err)
+	}
+}
+
+// export sends the spans to the OTLP/HTTP receiver of Endpoint
+func (e *spanExporter) export(spans []otlpSpan) error {
+	body, err := json.Marshal(exportRequest(spans))
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(Endpoint, "/") + "/v1/traces"
+	resp, err := e.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
+
+// exportRequest returns the ExportTraceServiceRequest of the spans
+func exportRequest(spans []otlpSpan) map[string]interface{} {
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []keyValue{{Key: "service.name", Value: anyValue{StringValue: ServiceName}}},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/kubeflow/kfctl/v3/pkg/tracing"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisabled(t *testing.T) {
+	Endpoint = ""
+	span := Start(nil, "Reconcile")
+	if span != nil {
+		t.Fatalf("Expected no span when tracing is disabled, got %+v", span)
+	}
+	// The methods of the disabled spans are no-ops
+	span.SetAttribute("kfdef.name", "odh")
+	span.End(nil)
+}
+
+func TestExport(t *testing.T) {
+	requests := []map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %v %v", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		request := map[string]interface{}{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("Failed to parse the request: %v", err)
+		}
+		requests = append(requests, request)
+	}))
+	defer server.Close()
+	Endpoint = server.URL
+	defer func() { Endpoint = "" }()
+
+	root := Start(nil, "Reconcile", "kfdef.name", "odh")
+	SetActive(Key("opendatahub", "odh"), root)
+	child := Start(Active(Key("opendatahub", "odh")), "Render")
+	child.End(errors.New("render failed"))
+	SetActive(Key("opendatahub", "odh"), nil)
+	root.End(nil)
+	if Active(Key("opendatahub", "odh")) != nil {
+		t.Errorf("Expected no active span")
+	}
+	Flush()
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected a single export, got %v", len(requests))
+	}
+	resourceSpans := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %v", spans)
+	}
+	render, reconcile := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	if render["traceId"] != reconcile["traceId"] || render["parentSpanId"] != reconcile["spanId"] {
+		t.Errorf("Expected Render to be a child of Reconcile, got %+v and %+v", render, reconcile)
+	}
+	if _, found := reconcile["parentSpanId"]; found {
+		t.Errorf("Expected Reconcile to be a root span, got %+v", reconcile)
+	}
+	status := render["status"].(map[string]interface{})
+	if status["code"] != float64(statusCodeError) || status["message"] != "render failed" {
+		t.Errorf("Expected the error status of Render, got %+v", status)
+	}
+	attributes := reconcile["attributes"].([]interface{})
+	if len(attributes) != 1 || attributes[0].(map[string]interface{})["key"] != "kfdef.name" {
+		t.Errorf("Expected the kfdef.name attribute, got %+v", attributes)
+	}
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The waves the classes of resources of the manifests are applied in, the lower first. A resource annotated with
+// kfctl.kubeflow.io/apply-wave is applied in the wave it names instead, e.g. 25 to apply it between the
+// configuration and the workloads.
+const (
+	CRDWave            = 0
+	NamespaceWave      = 10
+	RBACWave           = 20
+	ConfigWave         = 30
+	WorkloadWave       = 40
+	WebhookWave        = 50
+	CustomResourceWave = 60
+)
+
+// CRDEstablishTimeout is how long the CRDs of a wave have to be established in before the next wave is applied
+var CRDEstablishTimeout = time.Minute
+
+// kindWaves are the waves of the kinds of Kubernetes applied before or after the workloads
+var kindWaves = map[string]int{
+	"CustomResourceDefinition":       CRDWave,
+	"Namespace":                      NamespaceWave,
+	"ServiceAccount":                 RBACWave,
+	"ClusterRole":                    RBACWave,
+	"ClusterRoleBinding":             RBACWave,
+	"Role":                           RBACWave,
+	"RoleBinding":                    RBACWave,
+	"ResourceQuota":                  ConfigWave,
+	"LimitRange":                     ConfigWave,
+	"PodSecurityPolicy":              ConfigWave,
+	"Secret":                         ConfigWave,
+	"ConfigMap":                      ConfigWave,
+	"StorageClass":                   ConfigWave,
+	"PersistentVolume":               ConfigWave,
+	"PersistentVolumeClaim":          ConfigWave,
+	"MutatingWebhookConfiguration":   WebhookWave,
+	"ValidatingWebhookConfiguration": WebhookWave,
+	"APIService":                     WebhookWave,
+}
+
+// applyWave is the resources of the manifests applied together
+type applyWave struct {
+	wave      int
+	resources []*unstructured.Unstructured
+}
+
+// ResourceWave returns the wave a resource is applied in: the one of its kfctl.kubeflow.io/apply-wave
+// annotation, or the one of its class. The kinds of the Kubernetes and OpenShift API groups that are neither
+// cluster configuration nor webhooks are workloads, and the kinds of the other API groups are custom resources.
+func ResourceWave(obj *unstructured.Unstructured) (int, error) {
+	if value, ok := obj.GetAnnotations()[strings.Join([]string{KfDefAnnotation, ApplyWave}, "/")]; ok {
+		wave, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0, fmt.Errorf("invalid apply wave %q of %v %v: %v", value, obj.GetKind(), obj.GetName(), err)
+		}
+		return wave, nil
+	}
+	group := obj.GroupVersionKind().Group
+	builtin := !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io") ||
+		strings.HasSuffix(group, ".openshift.io")
+	if wave, ok := kindWaves[obj.GetKind()]; ok && builtin {
+		return wave, nil
+	}
+	if builtin {
+		return WorkloadWave, nil
+	}
+	return CustomResourceWave, nil
+}
+
+// groupWaves groups the resources by wave, keeping their order within every wave
+func groupWaves(resources []*unstructured.Unstructured) ([]applyWave, error) {
+	byWave := map[int][]*unstructured.Unstructured{}
+	for _, obj := range resources {
+		wave, err := ResourceWave(obj)
+		if err != nil {
+			return nil, err
+		}
+		byWave[wave] = append(byWave[wave], obj)
+	}
+	waves := []applyWave{}
+	for wave, objs := range byWave {
+		waves = append(waves, applyWave{wave: wave, resources: objs})
+	}
+	sort.Slice(waves, func(i, j int) bool { return waves[i].wave < waves[j].wave })
+	return waves, nil
+}
+
+// waitForCRDs waits for the CRDs among the applied resources to be established, so that their custom resources can
+// be applied, and returns true if there was one. It gives up once ctx is done.
+func waitForCRDs(ctx context.Context, kubeclient client.Client, resources []*unstructured.Unstructured) (bool, error) {
+	found := false
+	for _, obj := range resources {
+		if obj.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		found = true
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(obj.GroupVersionKind())
+		err := wait.PollImmediate(time.Second, CRDEstablishTimeout, func() (bool, error) {
+			if err := kubeclient.Get(ctx, client.ObjectKey{Name: obj.GetName()}, crd); err != nil {
+				return false, err
+			}
+			return isEstablished(crd), nil
+		})
+		if err == wait.ErrWaitTimeout {
+			return found, fmt.Errorf("CRD %v is not established after %v", obj.GetName(), CRDEstablishTimeout)
+		}
+		if err != nil {
+			return found, fmt.Errorf("failed to get CRD %v: %v", obj.GetName(), err)
+		}
+	}
+	return found, nil
+}
+
+// isEstablished returns true if the CRD has the Established condition
+func isEstablished(crd *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Established" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_GroupWaves(t *testing.T) {
+	resource := func(apiVersion string, kind string, name string, wave string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetName(name)
+		if wave != "" {
+			obj.SetAnnotations(map[string]string{KfDefAnnotation + "/" + ApplyWave: wave})
+		}
+		return obj
+	}
+	resources := []*unstructured.Unstructured{
+		resource("v1", "Namespace", "odh", ""),
+		resource("v1", "ServiceAccount", "dashboard", ""),
+		resource("apiextensions.k8s.io/v1", "CustomResourceDefinition", "notebooks.kubeflow.org", ""),
+		resource("v1", "ConfigMap", "dashboard-config", ""),
+		resource("rbac.authorization.k8s.io/v1", "ClusterRole", "dashboard", ""),
+		resource("apps/v1", "Deployment", "dashboard", ""),
+		resource("route.openshift.io/v1", "Route", "dashboard", ""),
+		resource("kubeflow.org/v1", "Notebook", "jupyter", ""),
+		resource("monitoring.coreos.com/v1", "ServiceMonitor", "dashboard", ""),
+		resource("admissionregistration.k8s.io/v1", "MutatingWebhookConfiguration", "dashboard", ""),
+		resource("v1", "Secret", "dashboard-oauth", "45"),
+		resource("example.com/v1", "ClusterRole", "not-rbac", ""),
+	}
+	waves, err := groupWaves(resources)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := map[int][]string{}
+	order := []int{}
+	for _, w := range waves {
+		order = append(order, w.wave)
+		for _, obj := range w.resources {
+			got[w.wave] = append(got[w.wave], obj.GetKind()+"/"+obj.GetName())
+		}
+	}
+	expected := map[int][]string{
+		CRDWave:            {"CustomResourceDefinition/notebooks.kubeflow.org"},
+		NamespaceWave:      {"Namespace/odh"},
+		RBACWave:           {"ServiceAccount/dashboard", "ClusterRole/dashboard"},
+		ConfigWave:         {"ConfigMap/dashboard-config"},
+		WorkloadWave:       {"Deployment/dashboard", "Route/dashboard"},
+		45:                 {"Secret/dashboard-oauth"},
+		WebhookWave:        {"MutatingWebhookConfiguration/dashboard"},
+		CustomResourceWave: {"Notebook/jupyter", "ServiceMonitor/dashboard", "ClusterRole/not-rbac"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the waves %v, got %v", expected, got)
+	}
+	if !reflect.DeepEqual(order, []int{CRDWave, NamespaceWave, RBACWave, ConfigWave, WorkloadWave, 45, WebhookWave, CustomResourceWave}) {
+		t.Errorf("Expected the waves in ascending order, got %v", order)
+	}
+
+	if _, err := groupWaves([]*unstructured.Unstructured{resource("v1", "Secret", "invalid", "first")}); err == nil {
+		t.Errorf("Expected an error for an invalid apply wave")
+	}
+}
+
+func Test_IsEstablished(t *testing.T) {
+	crd := func(conditions ...interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"conditions": conditions},
+		}}
+	}
+	testCases := []struct {
+		crd         *unstructured.Unstructured
+		established bool
+	}{
+		{crd: crd(), established: false},
+		{crd: crd(map[string]interface{}{"type": "NamesAccepted", "status": "True"}), established: false},
+		{crd: crd(map[string]interface{}{"type": "Established", "status": "False"}), established: false},
+		{
+			crd: crd(map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+				map[string]interface{}{"type": "Established", "status": "True"}),
+			established: true,
+		},
+	}
+	for _, test := range testCases {
+		if established := isEstablished(test.crd); established != test.established {
+			t.Errorf("Expected established %v for %v, got %v", test.established, test.crd.Object, established)
+		}
+	}
+}
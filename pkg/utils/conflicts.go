@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ConflictFail reports the fields of the manifests managed by other field managers in a CONFLICT KfError,
+	// without applying their resources
+	ConflictFail = "fail"
+	// ConflictForce takes over the fields of the manifests managed by other field managers
+	ConflictForce = "force"
+	// ConflictIgnoreFields leaves the IgnoredFields managed by other field managers to them and applies the rest of
+	// the resources, the conflicts on other fields are reported as with ConflictFail
+	ConflictIgnoreFields = "ignore-fields"
+)
+
+var (
+	// ConflictPolicy is what the manifests are applied with when fields are managed by other field managers,
+	// ConflictFail, ConflictForce or ConflictIgnoreFields
+	ConflictPolicy = ConflictFail
+	// IgnoredFields are the paths of the fields left to other field managers with ConflictIgnoreFields, as reported
+	// by the API server, e.g. .spec.replicas or .spec.template.spec.containers[name="manager"].resources. The fields
+	// under them are ignored too.
+	IgnoredFields = []string{}
+)
+
+// ValidateConflictPolicy returns an error if policy is not a valid ConflictPolicy, or if ConflictIgnoreFields is
+// set without fields to ignore
+func ValidateConflictPolicy(policy string, fields []string) error {
+	switch policy {
+	case ConflictFail, ConflictForce:
+		return nil
+	case ConflictIgnoreFields:
+		if len(fields) == 0 {
+			return fmt.Errorf("the %v conflict policy requires fields to ignore", policy)
+		}
+		for _, field := range fields {
+			if _, err := splitFieldPath(normalizeFieldPath(field)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid conflict policy %q, must be %v, %v or %v", policy, ConflictFail, ConflictForce,
+		ConflictIgnoreFields)
+}
+
+// removeIgnoredFields removes the conflicting fields at paths from obj, and returns true if they all are
+// IgnoredFields. obj may be partially changed otherwise.
+func removeIgnoredFields(obj map[string]interface{}, paths []string) bool {
+	if len(paths) == 0 {
+		return false
+	}
+	for _, path := range paths {
+		if !isIgnoredField(path) {
+			return false
+		}
+		segments, err := splitFieldPath(path)
+		if err != nil || !strings.HasPrefix(segments[0], ".") {
+			return false
+		}
+		if _, ok := removeField(obj, segments); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isIgnoredField returns true if path is one of the IgnoredFields, or a field under one of them
+func isIgnoredField(path string) bool {
+	for _, field := range IgnoredFields {
+		field = normalizeFieldPath(field)
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeFieldPath adds the leading dot of the paths reported by the API server to field
+func normalizeFieldPath(field string) string {
+	field = strings.TrimSpace(field)
+	if strings.HasPrefix(field, ".") || strings.HasPrefix(field, "[") {
+		return field
+	}
+	return "." + field
+}
+
+// splitFieldPath splits the path of a field reported by the API server into the names of the fields, prefixed with
+// a dot, and the selectors of the list items, in brackets
+func splitFieldPath(path string) ([]string, error) {
+	segments := []string{}
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			end := strings.IndexAny(path[i+1:], ".[")
+			if end < 0 {
+				end = len(path) - i - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid field path %q", path)
+			}
+			segments = append(segments, path[i:i+1+end])
+			i += 1 + end
+		case '[':
+			end, quoted := -1, false
+			for j := i + 1; j < len(path) && end < 0; j++ {
+				switch {
+				case path[j] == '\\' && quoted:
+					j++
+				case path[j] == '"':
+					quoted = !quoted
+				case path[j] == ']' && !quoted:
+					end = j
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field path %q", path)
+			}
+			segments = append(segments, path[i:end+1])
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid field path %q", path)
+	}
+	return segments, nil
+}
+
+// removeField removes the field at the path split into segments from obj, and returns obj, or the list replacing
+// it, and false if the field is not found
+func removeField(obj interface{}, segments []string) (interface{}, bool) {
+	segment, last := segments[0], len(segments) == 1
+	if strings.HasPrefix(segment, ".") {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return obj, false
+		}
+		name := segment[1:]
+		value, found := m[name]
+		if !found {
+			return obj, false
+		}
+		if last {
+			delete(m, name)
+			return m, true
+		}
+		value, ok = removeField(value, segments[1:])
+		m[name] = value
+		return m, ok
+	}
+
+	list, ok := obj.([]interface{})
+	if !ok {
+		return obj, false
+	}
+	for i, item := range list {
+		if !matchesSelector(item, i, segment[1:len(segment)-1]) {
+			continue
+		}
+		if last {
+			return append(append([]interface{}{}, list[:i]...), list[i+1:]...), true
+		}
+		item, ok = removeField(item, segments[1:])
+		list[i] = item
+		return list, ok
+	}
+	return obj, false
+}
+
+// matchesSelector returns true if the item at index of a list matches the selector of a field path: an index, a
+// value of a set, e.g. ="a", or the keys of an associative list, e.g. name="a",protocol="TCP"
+func matchesSelector(item interface{}, index int, selector string) bool {
+	if i, err := strconv.Atoi(selector); err == nil {
+		return i == index
+	}
+	if strings.HasPrefix(selector, "=") {
+		return jsonEqual(item, selector[1:])
+	}
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, pair := range splitSelector(selector) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || !jsonEqual(m[parts[0]], parts[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSelector splits the keys of a selector separated by commas outside of the JSON strings
+func splitSelector(selector string) []string {
+	pairs := []string{}
+	start, quoted := 0, false
+	for i := 0; i < len(selector); i++ {
+		switch {
+		case selector[i] == '\\' && quoted:
+			i++
+		case selector[i] == '"':
+			quoted = !quoted
+		case selector[i] == ',' && !quoted:
+			pairs = append(pairs, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(pairs, selector[start:])
+}
+
+// jsonEqual returns true if value is encoded as the JSON encoded want
+func jsonEqual(value interface{}, want string) bool {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(want), &decoded); err != nil {
+		return false
+	}
+	a, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(decoded)
+	return err == nil && string(a) == string(b)
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+func Test_RemoveIgnoredFields(t *testing.T) {
+	deployment := `
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: manager
+        image: manager:v1
+        resources:
+          limits:
+            cpu: "1"
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+        - containerPort: 8443
+          protocol: TCP
+      - name: proxy
+        image: proxy:v1
+`
+	type testCase struct {
+		name     string
+		ignored  []string
+		paths    []string
+		removed  bool
+		expected string
+	}
+	testCases := []testCase{
+		{
+			name:    "ignored field",
+			ignored: []string{"spec.replicas"},
+			paths:   []string{".spec.replicas"},
+			removed: true,
+			expected: `
+spec:
+  template:
+    spec:
+      containers:
+      - name: manager
+        image: manager:v1
+        resources:
+          limits:
+            cpu: "1"
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+        - containerPort: 8443
+          protocol: TCP
+      - name: proxy
+        image: proxy:v1
+`,
+		},
+		{
+			name:    "fields under an ignored field",
+			ignored: []string{`.spec.template.spec.containers[name="manager"]`},
+			paths: []string{
+				`.spec.template.spec.containers[name="manager"].resources.limits.cpu`,
+				`.spec.template.spec.containers[name="manager"].ports[containerPort=8443,protocol="TCP"]`,
+			},
+			removed: true,
+			expected: `
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: manager
+        image: manager:v1
+        resources:
+          limits: {}
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+      - name: proxy
+        image: proxy:v1
+`,
+		},
+		{
+			name:    "list item",
+			ignored: []string{`.spec.template.spec.containers[name="proxy"]`},
+			paths:   []string{`.spec.template.spec.containers[name="proxy"]`},
+			removed: true,
+			expected: `
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: manager
+        image: manager:v1
+        resources:
+          limits:
+            cpu: "1"
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+        - containerPort: 8443
+          protocol: TCP
+`,
+		},
+		{
+			name:    "field not ignored",
+			ignored: []string{".spec.replicas"},
+			paths:   []string{".spec.replicas", `.spec.template.spec.containers[name="manager"].image`},
+			removed: false,
+		},
+		{
+			name:    "prefix of another field",
+			ignored: []string{".spec.replica"},
+			paths:   []string{".spec.replicas"},
+			removed: false,
+		},
+		{
+			name:    "missing field",
+			ignored: []string{".spec.paused"},
+			paths:   []string{".spec.paused"},
+			removed: false,
+		},
+	}
+
+	defer func(fields []string) { IgnoredFields = fields }(IgnoredFields)
+	for _, test := range testCases {
+		IgnoredFields = test.ignored
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(deployment), &obj); err != nil {
+			t.Fatal(err)
+		}
+		removed := removeIgnoredFields(obj, test.paths)
+		if removed != test.removed {
+			t.Errorf("%v: expect removed %v, got %v", test.name, test.removed, removed)
+			continue
+		}
+		if !removed {
+			continue
+		}
+		expected := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(test.expected), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obj, expected) {
+			t.Errorf("%v: expect %v, got %v", test.name, expected, obj)
+		}
+	}
+}
+
+func Test_ValidateConflictPolicy(t *testing.T) {
+	type testCase struct {
+		policy string
+		fields []string
+		valid  bool
+	}
+	testCases := []testCase{
+		{policy: ConflictFail, valid: true},
+		{policy: ConflictForce, valid: true},
+		{policy: ConflictIgnoreFields, fields: []string{"spec.replicas"}, valid: true},
+		{policy: ConflictIgnoreFields, valid: false},
+		{policy: ConflictIgnoreFields, fields: []string{`.spec.containers[name="a"`}, valid: false},
+		{policy: "merge", valid: false},
+	}
+	for _, test := range testCases {
+		if err := ValidateConflictPolicy(test.policy, test.fields); (err == nil) != test.valid {
+			t.Errorf("validate policy %v with fields %v; expect valid %v, got %v", test.policy, test.fields, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package utils
+
+// DependencyCycle returns the names of items depending on each other, the first and the last one of the cycle being
+// the same, or nil if there is none. The items are visited in the order of names, dependencies maps every item to
+// the items it depends on.
+func DependencyCycle(names []string, dependencies map[string][]string) []string {
+	// Depth-first search of the cycles
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) []string
+	visit = func(name string, path []string) []string {
+		switch state[name] {
+		case visiting:
+			return append(path, name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range dependencies[name] {
+			if cycle := visit(dependency, append(path, name)); cycle != nil {
+				return cycle
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, name := range names {
+		if cycle := visit(name, nil); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyCycle(t *testing.T) {
+	type testCase struct {
+		Name         string
+		Names        []string
+		Dependencies map[string][]string
+		Cycle        []string
+	}
+	testCases := []testCase{
+		{
+			Name:         "no dependencies",
+			Names:        []string{"odh-common", "odh-dashboard"},
+			Dependencies: map[string][]string{},
+		},
+		{
+			Name:  "shared dependency",
+			Names: []string{"odh-common", "odh-dashboard", "notebooks"},
+			Dependencies: map[string][]string{
+				"odh-dashboard": {"odh-common"},
+				"notebooks":     {"odh-common", "odh-dashboard"},
+			},
+		},
+		{
+			Name:         "self",
+			Names:        []string{"odh-common", "odh-dashboard"},
+			Dependencies: map[string][]string{"odh-dashboard": {"odh-dashboard"}},
+			Cycle:        []string{"odh-dashboard", "odh-dashboard"},
+		},
+		{
+			Name:  "through other items",
+			Names: []string{"kserve", "odh-model-controller", "odh-common"},
+			Dependencies: map[string][]string{
+				"kserve":               {"odh-model-controller"},
+				"odh-model-controller": {"odh-common"},
+				"odh-common":           {"odh-model-controller"},
+			},
+			Cycle: []string{"kserve", "odh-model-controller", "odh-common", "odh-model-controller"},
+		},
+		{
+			Name:         "unknown dependency",
+			Names:        []string{"odh-dashboard"},
+			Dependencies: map[string][]string{"odh-dashboard": {"odh-common"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if cycle := DependencyCycle(tc.Names, tc.Dependencies); !reflect.DeepEqual(cycle, tc.Cycle) {
+				t.Errorf("Expected cycle %v, got %v", tc.Cycle, cycle)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// UpdateFailurePercent is the percentage of the creates, updates and patches of the apply clients failing with an
+	// injected ServiceUnavailable error, to test how the operator retries and degrades when the API server is flaky.
+	// It is only meant for testing.
+	UpdateFailurePercent int
+	// ListDelay delays the lists of the apply clients, to test the operator against a slow API server. It is only
+	// meant for testing.
+	ListDelay time.Duration
+)
+
+// ValidateFaults returns an error if the faults to inject are out of range
+func ValidateFaults(updateFailurePercent int, listDelay time.Duration) error {
+	if updateFailurePercent < 0 || updateFailurePercent > 100 {
+		return fmt.Errorf("the percentage of failed updates must be between 0 and 100, got %v", updateFailurePercent)
+	}
+	if listDelay < 0 {
+		return fmt.Errorf("the delay of the lists must not be negative, got %v", listDelay)
+	}
+	return nil
+}
+
+// FaultsEnabled returns true if faults are injected in the apply clients
+func FaultsEnabled() bool {
+	return UpdateFailurePercent > 0 || ListDelay > 0
+}
+
+// WithFaults returns a client injecting the faults of UpdateFailurePercent and ListDelay in the calls to c, or c
+// itself when no fault is injected
+func WithFaults(c client.Client) client.Client {
+	if !FaultsEnabled() {
+		return c
+	}
+	return &faultyClient{
+		Client: c,
+		fail: func() bool {
+			return rand.Intn(100) < UpdateFailurePercent
+		},
+		listDelay: ListDelay,
+	}
+}
+
+// faultyClient fails the writes for which fail returns true and delays the lists by listDelay
+type faultyClient struct {
+	client.Client
+	fail      func() bool
+	listDelay time.Duration
+}
+
+// injectedFault returns the error of a write failed on purpose
+func injectedFault(verb string, obj runtime.Object) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	return k8serrors.NewServiceUnavailable(fmt.Sprintf("injected fault: %v %v failed", verb, kind))
+}
+
+func (c *faultyClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if c.fail() {
+		return injectedFault("create", obj)
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *faultyClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if c.fail() {
+		return injectedFault("update", obj)
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *faultyClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.fail() {
+		return injectedFault("patch", obj)
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *faultyClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if c.listDelay > 0 {
+		select {
+		case <-time.After(c.listDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateFaults(t *testing.T) {
+	type testCase struct {
+		Percent int
+		Delay   time.Duration
+		Valid   bool
+	}
+	testCases := []testCase{
+		{Percent: 0, Delay: 0, Valid: true},
+		{Percent: 100, Delay: time.Second, Valid: true},
+		{Percent: 101, Delay: 0, Valid: false},
+		{Percent: -1, Delay: 0, Valid: false},
+		{Percent: 10, Delay: -time.Second, Valid: false},
+	}
+	for _, c := range testCases {
+		if err := ValidateFaults(c.Percent, c.Delay); (err == nil) != c.Valid {
+			t.Errorf("ValidateFaults(%v, %v) = %v, expected valid: %v", c.Percent, c.Delay, err, c.Valid)
+		}
+	}
+}
+
+func TestWithFaults(t *testing.T) {
+	kubeclient := fake.NewFakeClient()
+	if WithFaults(kubeclient) != kubeclient {
+		t.Errorf("Expected no fault to be injected by default")
+	}
+
+	failing := &faultyClient{Client: kubeclient, fail: func() bool { return true }, listDelay: 50 * time.Millisecond}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "odh-test", Namespace: "odh"}}
+	if err := failing.Create(context.TODO(), cm); !k8serrors.IsServiceUnavailable(err) {
+		t.Errorf("Expected the create to fail with an injected fault, got %v", err)
+	}
+	if err := failing.Update(context.TODO(), cm); !k8serrors.IsServiceUnavailable(err) {
+		t.Errorf("Expected the update to fail with an injected fault, got %v", err)
+	}
+	start := time.Now()
+	if err := failing.List(context.TODO(), &corev1.ConfigMapList{}); err != nil {
+		t.Errorf("Expected the list to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the list to be delayed, it took %v", elapsed)
+	}
+
+	passing := &faultyClient{Client: kubeclient, fail: func() bool { return false }}
+	if err := passing.Create(context.TODO(), cm); err != nil {
+		t.Errorf("Expected the create to succeed, got %v", err)
+	}
+}
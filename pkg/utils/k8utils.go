@@ -25,30 +25,32 @@ import (
 	configtypes "github.com/kubeflow/kfctl/v3/config"
 	kfapis "github.com/kubeflow/kfctl/v3/pkg/apis"
 	kftypes "github.com/kubeflow/kfctl/v3/pkg/apis/apps"
+	"github.com/kubeflow/kfctl/v3/pkg/audit"
+	"github.com/kubeflow/kfctl/v3/pkg/tracing"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	errutil "k8s.io/apimachinery/pkg/util/errors"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	kubectlapply "k8s.io/kubernetes/pkg/kubectl/cmd/apply"
-	kubectldelete "k8s.io/kubernetes/pkg/kubectl/cmd/delete"
 	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
 	"math/rand"
 	netUrl "net/url"
-	"os"
 	"path"
 	"regexp"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"strconv"
 	"strings"
 	"time"
 	// Auth plugins
@@ -67,8 +69,108 @@ const (
 	SetAnnotation              = "set-kubeflow-annotation"
 	KfDefInstance              = "kfdef-instance"
 	InstallByOperator          = "install-by-operator"
+	// DeleteClusterScoped is the annotation of a KfDef allowing the operator to delete its cluster-scoped resources
+	DeleteClusterScoped = "delete-cluster-scoped-resources"
+	// IgnoreDrift is the annotation of a deployed resource opting out of drift remediation
+	IgnoreDrift = "ignore-drift"
+	// DryRun is the annotation of a KfDef rendering and validating its manifests instead of applying them
+	DryRun = "dry-run"
+	// Paused is the annotation of a KfDef stopping the reconciliation of its applications
+	Paused = "paused"
+	// PausedApplications is the annotation of a KfDef listing the applications, separated by commas, whose
+	// reconciliation is stopped
+	PausedApplications = "paused-applications"
+	// RelatedImages is the annotation of a KfDef naming the <namespace>/<name> ConfigMap its image overrides are read from
+	RelatedImages = "related-images"
+	// TrustedCABundle is the annotation of a KfDef naming the <namespace>/<name> ConfigMap holding the CA bundle
+	// mounted in its Deployments instead of the OpenShift cluster-wide trusted CA bundle
+	TrustedCABundle = "trusted-ca-bundle"
+	// ServingCertSecret is the annotation of a Service requesting a serving certificate stored in the secret it names
+	ServingCertSecret = "serving-cert-secret"
+	// Auth is the annotation of a Route of the manifests opting out of the authentication layer of the KfDef
+	Auth = "auth"
+	// Expose is the annotation of a Service of the manifests exposing its port, named by the annotation, with a Route
+	// on OpenShift and an Ingress on Kubernetes
+	Expose = "expose"
+	// SCC is the annotation of a ServiceAccount of the manifests naming the SecurityContextConstraints profile, anyuid
+	// or nonroot, granted to it on OpenShift
+	SCC = "scc"
+	// Backup is the annotation of a workload of the manifests running a database, naming its engine (mysql, mariadb
+	// or postgresql) for the database to be dumped before its volumes are backed up by Velero
+	Backup = "backup"
+	// FIPS is the annotation of a resource of the manifests set to incompatible when its application cannot run on a
+	// FIPS-enabled cluster, the application is then refused on such clusters
+	FIPS = "fips"
+	// Architectures is the annotation of a workload of the manifests listing, separated by commas, the CPU
+	// architectures its images are built for, e.g. amd64,arm64. Its pods are only scheduled onto nodes of these.
+	Architectures = "architectures"
+	// AllowDestructiveCRDUpgrades is the annotation of a KfDef listing the CRDs, separated by commas, its manifests
+	// may upgrade even though the versions or the fields of their stored custom resources are removed
+	AllowDestructiveCRDUpgrades = "allow-destructive-crd-upgrades"
+	// ApplyWave is the annotation of a resource of the manifests naming the wave it is applied in, see ResourceWave
+	ApplyWave = "apply-wave"
+	// Hook is the annotation of a Job of the manifests run before (pre-apply) or after (post-apply) the other
+	// resources of its application are applied, e.g. to migrate the schema of a database
+	Hook = "hook"
+	// HookRetries is the annotation of a hook Job setting how many times it is run again once it failed
+	HookRetries = "hook-retries"
+	// HookTimeout is the annotation of a hook Job setting how long it may run, e.g. 5m
+	HookTimeout = "hook-timeout"
+	// CollectDiagnostics is the annotation of a KfDef requesting the operator to collect its diagnostics. The
+	// operator collects them again every time the value of the annotation changes, e.g. to the current time.
+	CollectDiagnostics = "collect-diagnostics"
 )
 
+// DefaultFieldManager is the name of the field manager the manifests are applied with by default
+const DefaultFieldManager = "kfctl"
+
+// FieldManager is the name of the field manager used to apply the manifests
+var FieldManager = DefaultFieldManager
+
+// LegacyFieldManagers are the field managers the manifests were applied with before FieldManager.
+// Their fields are taken over by FieldManager rather than reported as conflicts.
+var LegacyFieldManagers = []string{"application/apply-patch+yaml", "before-first-apply"}
+
+// IgnoresDrift returns true if the resource is annotated with kfctl.kubeflow.io/ignore-drift=true,
+// in which case changes made to it on the cluster are not reverted by the operator.
+func IgnoresDrift(obj metav1.Object) bool {
+	ignore, err := strconv.ParseBool(obj.GetAnnotations()[strings.Join([]string{KfDefAnnotation, IgnoreDrift}, "/")])
+	return err == nil && ignore
+}
+
+// IsDryRun returns true if the KfDef is annotated with kfctl.kubeflow.io/dry-run=true, in which case
+// its manifests are validated against the cluster and the changes they would make are reported instead of applied.
+func IsDryRun(obj metav1.Object) bool {
+	dryRun, err := strconv.ParseBool(obj.GetAnnotations()[strings.Join([]string{KfDefAnnotation, DryRun}, "/")])
+	return err == nil && dryRun
+}
+
+// DiagnosticsConfigMapName returns the name of the ConfigMap the diagnostics of the KfDef are collected into
+func DiagnosticsConfigMapName(kfdef string) string {
+	return kfdef + "-diagnostics"
+}
+
+// IsPaused returns true if the KfDef is annotated with kfctl.kubeflow.io/paused=true, in which case
+// its applications are neither applied nor restored by the operator until the annotation is removed.
+func IsPaused(obj metav1.Object) bool {
+	paused, err := strconv.ParseBool(obj.GetAnnotations()[strings.Join([]string{KfDefAnnotation, Paused}, "/")])
+	return err == nil && paused
+}
+
+// IsApplicationPaused returns true if the KfDef is paused, or if app is listed in its
+// kfctl.kubeflow.io/paused-applications annotation.
+func IsApplicationPaused(obj metav1.Object, app string) bool {
+	if IsPaused(obj) {
+		return true
+	}
+	for _, name := range strings.Split(obj.GetAnnotations()[strings.Join([]string{KfDefAnnotation, PausedApplications}, "/")], ",") {
+		if app != "" && strings.TrimSpace(name) == app {
+			return true
+		}
+	}
+	return false
+}
+
 func generateRandStr(length int) string {
 	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	b := make([]byte, length)
@@ -256,9 +358,11 @@ type Apply struct {
 	matchVersionKubeConfigFlags *cmdutil.MatchVersionFlags
 	factory                     cmdutil.Factory
 	clientset                   *kubernetes.Clientset
-	options                     *kubectlapply.ApplyOptions
-	tmpfile                     *os.File
-	stdin                       *os.File
+	restConfig                  *rest.Config
+	// defaultNamespace is the namespace of the namespaced resources applied without one
+	defaultNamespace string
+	// connect replaces the clients of the API server returned by newClient when set, for testing
+	connect func() (meta.RESTMapper, client.Client, error)
 }
 
 func NewApply(namespace string, restConfig *rest.Config) (*Apply, error) {
@@ -274,9 +378,20 @@ func NewApply(namespace string, restConfig *rest.Config) (*Apply, error) {
 	}
 	apply := &Apply{
 		matchVersionKubeConfigFlags: cmdutil.NewMatchVersionFlags(configFlags),
+		defaultNamespace:            namespace,
 	}
 	apply.factory = cmdutil.NewFactory(apply.matchVersionKubeConfigFlags)
-	clientset, err := apply.factory.KubernetesClientSet()
+	config, err := apply.factory.ToRESTConfig()
+	if err != nil {
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("could not get rest config: %v", err),
+		}
+	}
+	// The apply clients share a rate limiter, for the full reconciles not to throttle the other clients of the
+	// API server
+	apply.restConfig = RateLimited(config)
+	clientset, err := kubernetes.NewForConfig(apply.restConfig)
 	if err != nil {
 		return nil, &kfapis.KfError{
 			Code:    int(kfapis.INTERNAL_ERROR),
@@ -289,11 +404,6 @@ func NewApply(namespace string, restConfig *rest.Config) (*Apply, error) {
 		return nil, err
 	}
 
-	// Change behaviour of error from exit to panic.
-	cmdutil.BehaviorOnFatal(func(s string, i int) {
-		panic(fmt.Errorf("Encountered error: %s. Exiting with code: %d.", s, i))
-	})
-
 	return apply, nil
 }
 
@@ -305,111 +415,270 @@ func (a *Apply) IfNamespaceExist(name string) bool {
 	return true
 }
 
+// Apply server-side applies every resource of the manifests. The resources with fields managed by other
+// field managers, such as the replicas of a Deployment scaled by a HorizontalPodAutoscaler, are not applied at all:
+// their conflicting fields are reported in a CONFLICT KfError once the other resources are applied.
 func (a *Apply) Apply(data []byte) error {
-	a.tmpfile = a.tempFile(data)
-	a.stdin = os.Stdin
-	os.Stdin = a.tmpfile
-	defer a.cleanup()
-	ioStreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
-	a.options = kubectlapply.NewApplyOptions(ioStreams)
-	a.options.DeleteFlags = a.deleteFlags("that contains the configuration to apply")
-	// This is a required field for server-side apply
-	a.options.FieldManager =  "application/apply-patch+yaml"
-	a.options.ServerSideApply = true
-	// This is required to apply aggregated cluster roles :
-	// https://kubernetes.io/docs/reference/access-authn-authz/rbac/#aggregated-clusterroles
-	a.options.ForceConflicts = true
-	initializeErr := a.init()
-	if initializeErr != nil {
-		return &kfapis.KfError{
+	return a.ApplyTraced(context.Background(), data, nil)
+}
+
+// ApplyTraced applies the manifests like Apply with ctx, recording the application of every resource in a child
+// span of parent. See ApplyBatch.
+func (a *Apply) ApplyTraced(ctx context.Context, data []byte, parent *tracing.Span) error {
+	batch, err := NewApplyBatch(data)
+	if err != nil {
+		return err
+	}
+	return a.ApplyBatch(ctx, batch, parent)
+}
+
+// ApplyBatch is the resources of manifests applied across the attempts of an Apply. Every attempt only applies the
+// resources the previous attempts did not: the ones that failed and the ones of the waves following them.
+type ApplyBatch struct {
+	waves []applyWave
+	// applied holds the resources that were applied successfully
+	applied map[*unstructured.Unstructured]bool
+	total   int
+}
+
+// NewApplyBatch returns the batch of the resources of the manifests data, grouped by wave
+func NewApplyBatch(data []byte) (*ApplyBatch, error) {
+	documents, err := SplitYAML(data)
+	if err != nil {
+		return nil, &kfapis.KfError{
 			Code:    int(kfapis.INTERNAL_ERROR),
-			Message: fmt.Sprintf("could not initialize : %v", initializeErr),
+			Message: fmt.Sprintf("error splitting yaml: %v", err),
 		}
 	}
-	var err error
-	func() {
-		defer func() {
-			if temp := recover(); temp != nil {
-				err = temp.(error)
+	resources := []*unstructured.Unstructured{}
+	for _, res := range documents {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(res, &obj.Object); err != nil {
+			return nil, &kfapis.KfError{
+				Code:    int(kfapis.INTERNAL_ERROR),
+				Message: fmt.Sprintf("error parsing yaml: %v", err),
 			}
-		}()
-		err = a.run()
-	}()
+		}
+		if obj.GetKind() != "" {
+			resources = append(resources, obj)
+		}
+	}
+	waves, err := groupWaves(resources)
 	if err != nil {
-		return err
+		return nil, &kfapis.KfError{
+			Code:    int(kfapis.INVALID_ARGUMENT),
+			Message: err.Error(),
+		}
 	}
-	return nil
+	return &ApplyBatch{waves: waves, applied: map[*unstructured.Unstructured]bool{}, total: len(resources)}, nil
 }
 
-func (a *Apply) run() error {
-	resourcesErr := a.options.Run()
-	if resourcesErr != nil {
+// Pending returns the number of resources of the batch that were not applied yet
+func (b *ApplyBatch) Pending() int {
+	return b.total - len(b.applied)
+}
+
+// ApplyBatch applies the resources of batch that were not applied yet with ctx. The resources are applied wave by wave, see
+// ResourceWave: every resource of a wave is applied even though some of them fail, the waves following a failed
+// one are not applied, and the ones following CRDs wait for them to be established. The errors of the resources that
+// failed are aggregated in the error returned, for the resources to be applied again with the batch.
+func (a *Apply) ApplyBatch(ctx context.Context, batch *ApplyBatch, parent *tracing.Span) error {
+	mapper, kubeclient, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	conflicts := []string{}
+	for _, wave := range batch.waves {
+		for _, pristine := range wave.resources {
+			if batch.applied[pristine] {
+				continue
+			}
+			// The resources are updated with the responses of the API server, the ones applied again start over
+			obj := pristine.DeepCopy()
+			span := tracing.Start(parent, "Apply "+obj.GetKind(), "kind", obj.GetKind(), "name", obj.GetName())
+			err := a.applyResource(ctx, kubeclient, mapper, obj)
+			span.SetAttribute("namespace", obj.GetNamespace())
+			span.End(err)
+			switch {
+			case err == nil:
+				batch.applied[pristine] = true
+			case kfapis.IsConflict(err):
+				conflicts = append(conflicts, err.(*kfapis.KfError).Message)
+			default:
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			log.Infof("Not applying the waves following wave %v, which failed", wave.wave)
+			break
+		}
+		crds, err := waitForCRDs(ctx, kubeclient, wave.resources)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		// Discover the kinds of the CRDs for their resources to be mapped
+		if crds {
+			if mapper, kubeclient, err = a.newClient(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(errs) > 0 {
 		return &kfapis.KfError{
-			Code:    int(kfapis.INTERNAL_ERROR),
-			Message: fmt.Sprintf("Apply.Run : %v", resourcesErr),
+			Code: int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("Apply.Run : %d of %d resources not applied: %v", batch.Pending(), batch.total,
+				errutil.NewAggregate(errs)),
+		}
+	}
+	if len(conflicts) > 0 {
+		return &kfapis.KfError{
+			Code:    int(kfapis.CONFLICT),
+			Message: fmt.Sprintf("fields managed by other field managers were not applied: %v", strings.Join(conflicts, "; ")),
 		}
 	}
 	return nil
 }
 
-func (a *Apply) cleanup() error {
-	os.Stdin = a.stdin
-	if a.tmpfile != nil {
-		if err := a.tmpfile.Close(); err != nil {
-			return err
+// newClient returns a client mapping the kinds served by the API server when it is called, recording the changes it
+// makes when audited and injecting the faults set for testing
+func (a *Apply) newClient() (meta.RESTMapper, client.Client, error) {
+	if a.connect != nil {
+		return a.connect()
+	}
+	mapper, err := apiutil.NewDiscoveryRESTMapper(a.restConfig)
+	if err != nil {
+		return nil, nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("could not get rest mapper: %v", err),
 		}
-		return os.Remove(a.tmpfile.Name())
 	}
-	return nil
+	kubeclient, err := client.New(a.restConfig, client.Options{Mapper: mapper})
+	if err != nil {
+		return nil, nil, &kfapis.KfError{
+			Code:    int(kfapis.INTERNAL_ERROR),
+			Message: fmt.Sprintf("could not get client: %v", err),
+		}
+	}
+	return mapper, WithFaults(audit.WithAudit(kubeclient)), nil
 }
 
-func (a *Apply) init() error {
-	var err error
-	var o = a.options
-	var f = a.factory
-	// allow for a success message operation to be specified at print time
-	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
-		o.PrintFlags.NamePrintFlags.Operation = operation
-		if o.DryRun {
-			err = o.PrintFlags.Complete("%s (dry run)")
-			if err != nil {
-				return nil, err
-			}
+func (a *Apply) applyResource(ctx context.Context, kubeclient client.Client, mapper meta.RESTMapper, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("unable to map %v %v: %v", gvk.Kind, obj.GetName(), err)
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() == "" {
+		obj.SetNamespace(a.defaultNamespace)
+	}
+	// The rules of aggregated cluster roles are managed by the aggregation controller:
+	// https://kubernetes.io/docs/reference/access-authn-authz/rbac/#aggregated-clusterroles
+	if gvk.Kind == "ClusterRole" {
+		if _, found := obj.Object["aggregationRule"]; found {
+			unstructured.RemoveNestedField(obj.Object, "rules")
 		}
-		if o.ServerDryRun {
-			err = o.PrintFlags.Complete("%s (server dry run)")
-			if err != nil {
-				return nil, err
-			}
+	}
+	if err := ServerSideApply(ctx, kubeclient, obj); err != nil {
+		if kfapis.IsConflict(err) {
+			return err
 		}
-		return o.PrintFlags.ToPrinter()
+		return fmt.Errorf("failed to apply %v %v: %v", gvk.Kind, obj.GetName(), err)
 	}
-	o.DiscoveryClient, err = f.ToDiscoveryClient()
-	if err != nil {
+	log.Infof("%v %v applied", strings.ToLower(gvk.Kind), obj.GetName())
+	return nil
+}
+
+// ServerSideApply applies obj under the FieldManager field manager. Fields managed by one of the
+// LegacyFieldManagers are taken over. The fields managed by any other field manager are handled according to the
+// ConflictPolicy: they are taken over with ConflictForce, left to the other field managers when they are all
+// IgnoredFields with ConflictIgnoreFields, and a CONFLICT KfError is returned otherwise.
+// obj is updated with the response of the API server.
+func ServerSideApply(ctx context.Context, kubeclient client.Client, obj *unstructured.Unstructured, opts ...client.PatchOption) error {
+	desired := obj.DeepCopy()
+	opts = append([]client.PatchOption{client.FieldOwner(FieldManager)}, opts...)
+	err := kubeclient.Patch(ctx, obj, client.Apply, opts...)
+	if !k8serrors.IsConflict(err) {
 		return err
 	}
-	dynamicClient, err := f.DynamicClient()
-	if err != nil {
-		return err
+	managers, fields := conflictingFields(err)
+	switch {
+	case len(managers) > 0 && isLegacyFieldManagers(managers):
+		log.Infof("Taking over the fields of %v %v from %v", obj.GetKind(), obj.GetName(), strings.Join(managers, ", "))
+		desired.DeepCopyInto(obj)
+		return kubeclient.Patch(ctx, obj, client.Apply, append(opts, client.ForceOwnership)...)
+	case len(managers) > 0 && ConflictPolicy == ConflictForce:
+		log.Infof("Forcing the fields of %v %v managed by %v: %v", obj.GetKind(), obj.GetName(),
+			strings.Join(managers, ", "), strings.Join(fields, ", "))
+		desired.DeepCopyInto(obj)
+		return kubeclient.Patch(ctx, obj, client.Apply, append(opts, client.ForceOwnership)...)
+	case len(managers) > 0 && ConflictPolicy == ConflictIgnoreFields:
+		if paths := conflictingPaths(err); removeIgnoredFields(desired.Object, paths) {
+			log.Infof("Leaving the fields %v of %v %v to %v", strings.Join(paths, ", "), obj.GetKind(), obj.GetName(),
+				strings.Join(managers, ", "))
+			desired.DeepCopyInto(obj)
+			return kubeclient.Patch(ctx, obj, client.Apply, opts...)
+		}
+	}
+	if len(fields) == 0 {
+		fields = []string{err.Error()}
+	}
+	return &kfapis.KfError{
+		Code:    int(kfapis.CONFLICT),
+		Message: fmt.Sprintf("%v %v: %v", obj.GetKind(), obj.GetName(), strings.Join(fields, ", ")),
 	}
-	o.DeleteOptions = o.DeleteFlags.ToOptions(dynamicClient, o.IOStreams)
-	o.OpenAPIPatch = true
-	o.OpenAPISchema, _ = f.OpenAPISchema()
-	o.Validator, err = f.Validator(false)
-	o.Builder = f.NewBuilder()
-	o.Mapper, err = f.ToRESTMapper()
-	if err != nil {
-		return err
+}
+
+// conflictingFields returns the field managers and the fields reported by a server-side apply conflict error
+func conflictingFields(err error) ([]string, []string) {
+	managers := []string{}
+	fields := []string{}
+	status, ok := err.(k8serrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return managers, fields
 	}
-	o.DynamicClient, err = f.DynamicClient()
-	if err != nil {
-		return err
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%v (%v)", cause.Field, cause.Message))
+		// The message looks like: conflict with "kube-controller-manager" using apps/v1 at 2020-06-17T21:34:40Z
+		if parts := strings.SplitN(cause.Message, "\"", 3); len(parts) == 3 {
+			managers = append(managers, parts[1])
+		}
 	}
-	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
-	if err != nil {
-		return err
+	return managers, fields
+}
+
+// conflictingPaths returns the paths of the fields reported by a server-side apply conflict error, e.g.
+// .spec.replicas
+func conflictingPaths(err error) []string {
+	paths := []string{}
+	status, ok := err.(k8serrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return paths
 	}
-	return nil
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			paths = append(paths, cause.Field)
+		}
+	}
+	return paths
+}
+
+// isLegacyFieldManagers returns true if every field manager is one of the LegacyFieldManagers
+func isLegacyFieldManagers(managers []string) bool {
+	for _, manager := range managers {
+		legacy := false
+		for _, l := range LegacyFieldManagers {
+			legacy = legacy || manager == l
+		}
+		if !legacy {
+			return false
+		}
+	}
+	return true
 }
 
 func (a *Apply) patchNamespaceWithLabel(namespace string, labelKey string,
@@ -487,55 +756,10 @@ func (a *Apply) namespace(namespace string) error {
 	return nil
 }
 
-func (a *Apply) tempFile(data []byte) *os.File {
-	tmpfile, err := ioutil.TempFile("/tmp", "kout")
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := tmpfile.Write(data); err != nil {
-		log.Fatal(err)
-	}
-	if _, err := tmpfile.Seek(0, 0); err != nil {
-		log.Fatal(err)
-	}
-	return tmpfile
-}
-
-func (a *Apply) deleteFlags(usage string) *kubectldelete.DeleteFlags {
-	cascade := true
-	gracePeriod := -1
-	// setup command defaults
-	all := false
-	force := false
-	ignoreNotFound := false
-	now := false
-	output := ""
-	labelSelector := ""
-	fieldSelector := ""
-	timeout := time.Duration(0)
-	wait := true
-	filenames := []string{a.tmpfile.Name()}
-	recursive := false
-	return &kubectldelete.DeleteFlags{
-		FileNameFlags:  &genericclioptions.FileNameFlags{Usage: usage, Filenames: &filenames, Recursive: &recursive},
-		LabelSelector:  &labelSelector,
-		FieldSelector:  &fieldSelector,
-		Cascade:        &cascade,
-		GracePeriod:    &gracePeriod,
-		All:            &all,
-		Force:          &force,
-		IgnoreNotFound: &ignoreNotFound,
-		Now:            &now,
-		Timeout:        &timeout,
-		Wait:           &wait,
-		Output:         &output,
-	}
-}
-
 // DeleteResource removes resource. Prior to that it checks whether the resource is created through the kubeflow operator.
 // always removes the resource if it is not created by the Kubeflow operator, otherwise checks the annotation to
-// be sure the resource is part of the deployment and then remove.
-func DeleteResource(resourceBytes []byte, kubeclient client.Client, timeout time.Duration, byOperator bool) error {
+// be sure the resource is part of the deployment of the KfDef kfdefCr (<name>.<namespace>) and then remove.
+func DeleteResource(resourceBytes []byte, kubeclient client.Client, timeout time.Duration, byOperator bool, kfdefCr string) error {
 
 	// Convert to unstructured in order to access object metadata
 	resourceMap := map[string]interface{}{}
@@ -569,8 +793,7 @@ func DeleteResource(resourceBytes []byte, kubeclient client.Client, timeout time
 	if byOperator {
 		anns := unstructuredObject.GetAnnotations()
 		kfdefAnn := strings.Join([]string{KfDefAnnotation, KfDefInstance}, "/")
-		_, found := anns[kfdefAnn]
-		if !found {
+		if owner, found := anns[kfdefAnn]; !found || owner != kfdefCr {
 			return nil
 		}
 	}
@@ -622,3 +845,30 @@ func SplitYAML(resources []byte) ([][]byte, error) {
 	}
 	return res, nil
 }
+
+// SecretEnv returns an environment variable set from a key of a Secret
+func SecretEnv(name string, secret string, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: secret},
+			Key:                  key,
+		}},
+	}
+}
+
+// JobFinished returns whether the Job completed or failed, and the message of its failure
+func JobFinished(job *batchv1.Job) (bool, bool, string) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != v1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, false, ""
+		case batchv1.JobFailed:
+			return true, true, c.Message
+		}
+	}
+	return false, false, ""
+}
@@ -1,7 +1,19 @@
 package utils
 
 import (
+	"context"
+	"reflect"
 	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func Test_IsRemoteFile(t *testing.T) {
@@ -67,3 +79,241 @@ func TestSplitYAML(t *testing.T) {
 		})
 	}
 }
+
+func Test_ConflictingFields(t *testing.T) {
+	conflict := func(managers ...string) error {
+		causes := []metav1.StatusCause{}
+		for _, m := range managers {
+			causes = append(causes, metav1.StatusCause{
+				Type:    metav1.CauseTypeFieldManagerConflict,
+				Message: `conflict with "` + m + `" using apps/v1 at 2020-06-17T21:34:40Z`,
+				Field:   ".spec.replicas",
+			})
+		}
+		err := k8serrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "odh-dashboard", nil)
+		err.ErrStatus.Details.Causes = causes
+		return err
+	}
+
+	type testCase struct {
+		err    error
+		legacy bool
+	}
+
+	testCases := []testCase{
+		{
+			err:    conflict("application/apply-patch+yaml"),
+			legacy: true,
+		},
+		{
+			err:    conflict("application/apply-patch+yaml", "kube-controller-manager"),
+			legacy: false,
+		},
+		{
+			err:    conflict(),
+			legacy: false,
+		},
+	}
+
+	for _, test := range testCases {
+		managers, fields := conflictingFields(test.err)
+		if len(managers) != len(fields) {
+			t.Errorf("expect one manager per field, got managers %v and fields %v", managers, fields)
+		}
+		legacy := len(managers) > 0 && isLegacyFieldManagers(managers)
+		if legacy != test.legacy {
+			t.Errorf("check if %v are legacy field managers; expect %v, got %v", managers, test.legacy, legacy)
+		}
+	}
+}
+
+func Test_IsApplicationPaused(t *testing.T) {
+	type testCase struct {
+		annotations map[string]string
+		app         string
+		paused      bool
+	}
+
+	testCases := []testCase{
+		{
+			annotations: map[string]string{"kfctl.kubeflow.io/paused": "true"},
+			app:         "odh-dashboard",
+			paused:      true,
+		},
+		{
+			annotations: map[string]string{"kfctl.kubeflow.io/paused": "false"},
+			app:         "odh-dashboard",
+			paused:      false,
+		},
+		{
+			annotations: map[string]string{"kfctl.kubeflow.io/paused-applications": "odh-notebook-controller, odh-dashboard"},
+			app:         "odh-dashboard",
+			paused:      true,
+		},
+		{
+			annotations: map[string]string{"kfctl.kubeflow.io/paused-applications": "odh-dashboard-extensions"},
+			app:         "odh-dashboard",
+			paused:      false,
+		},
+		{
+			annotations: nil,
+			app:         "odh-dashboard",
+			paused:      false,
+		},
+	}
+
+	for _, test := range testCases {
+		obj := &metav1.ObjectMeta{Annotations: test.annotations}
+		if paused := IsApplicationPaused(obj, test.app); paused != test.paused {
+			t.Errorf("check if %v is paused by %v; expect %v, got %v", test.app, test.annotations, test.paused, paused)
+		}
+	}
+}
+
+func TestNewApplyBatch(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: odh
+`)
+	batch, err := NewApplyBatch(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if batch.Pending() != 3 {
+		t.Errorf("Expected 3 resources pending, got %v", batch.Pending())
+	}
+	if kind := batch.waves[0].resources[0].GetKind(); kind != "Namespace" {
+		t.Errorf("Expected the Namespace to be applied first, got %v", kind)
+	}
+	batch.applied[batch.waves[0].resources[0]] = true
+	if batch.Pending() != 2 {
+		t.Errorf("Expected 2 resources pending once the Namespace is applied, got %v", batch.Pending())
+	}
+
+	invalid := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-config
+  annotations:
+    kfctl.kubeflow.io/apply-wave: first
+`)
+	if _, err := NewApplyBatch(invalid); err == nil {
+		t.Errorf("Expected an error for an invalid apply wave")
+	}
+}
+
+// failingPatches is a client recording the resources it applies and failing the patches of the resources named in
+// fail
+type failingPatches struct {
+	client.Client
+	fail    map[string]bool
+	patched []string
+}
+
+func (c *failingPatches) Patch(_ context.Context, obj runtime.Object, _ client.Patch, _ ...client.PatchOption) error {
+	name := obj.(*unstructured.Unstructured).GetName()
+	c.patched = append(c.patched, name)
+	if c.fail[name] {
+		return k8serrors.NewServiceUnavailable("patch of " + name + " failed")
+	}
+	return nil
+}
+
+func TestApplyBatch(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: odh-dashboard-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: odh-dashboard
+`)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	kubeclient := &failingPatches{fail: map[string]bool{"odh-dashboard-config": true}}
+	apply := &Apply{
+		defaultNamespace: "odh",
+		connect: func() (meta.RESTMapper, client.Client, error) {
+			return mapper, kubeclient, nil
+		},
+	}
+	batch, err := NewApplyBatch(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the Deployment of the wave following the failed ConfigMap is not applied
+	if err := apply.ApplyBatch(context.TODO(), batch, nil); err == nil {
+		t.Fatalf("Expected the failed ConfigMap to be reported")
+	}
+	if !reflect.DeepEqual(kubeclient.patched, []string{"odh-config", "odh-dashboard-config"}) {
+		t.Errorf("Expected the ConfigMaps to be applied, got %v", kubeclient.patched)
+	}
+	if batch.Pending() != 2 {
+		t.Errorf("Expected the failed ConfigMap and the Deployment to be pending, got %v resources pending", batch.Pending())
+	}
+
+	kubeclient.fail, kubeclient.patched = map[string]bool{}, nil
+	if err := apply.ApplyBatch(context.TODO(), batch, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(kubeclient.patched, []string{"odh-dashboard-config", "odh-dashboard"}) {
+		t.Errorf("Expected only the failed ConfigMap and the Deployment to be applied, got %v", kubeclient.patched)
+	}
+	if batch.Pending() != 0 {
+		t.Errorf("Expected every resource to be applied, got %v resources pending", batch.Pending())
+	}
+}
+
+func TestJobFinished(t *testing.T) {
+	for _, c := range []struct {
+		Name       string
+		Conditions []batchv1.JobCondition
+		Finished   bool
+		Failed     bool
+		Message    string
+	}{
+		{Name: "running"},
+		{
+			Name:       "complete",
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+			Finished:   true,
+		},
+		{
+			Name:       "failed",
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Message: "BackoffLimitExceeded"}},
+			Finished:   true,
+			Failed:     true,
+			Message:    "BackoffLimitExceeded",
+		},
+		{
+			Name:       "not-failed",
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionFalse}},
+		},
+	} {
+		job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: c.Conditions}}
+		finished, failed, message := JobFinished(job)
+		if finished != c.Finished || failed != c.Failed || message != c.Message {
+			t.Errorf("Case %v: expected %v, %v, %q; got %v, %v, %q", c.Name, c.Finished, c.Failed, c.Message, finished, failed, message)
+		}
+	}
+}
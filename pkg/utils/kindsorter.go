@@ -22,6 +22,7 @@ var InstallOrder SortOrder = []string{
 	"PersistentVolume",
 	"PersistentVolumeClaim",
 	"ServiceAccount",
+	"SecurityContextConstraints",
 	"CustomResourceDefinition",
 	"ClusterRole",
 	"ClusterRoleBinding",
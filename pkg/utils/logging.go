@@ -3,9 +3,21 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/go-logr/logr"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// JSONLogFormat logs every entry as a JSON object
+	JSONLogFormat = "json"
+	// TextLogFormat logs every entry as key=value pairs
+	TextLogFormat = "text"
+)
+
 // PrettyPrint returns a pretty format output of any value.
 func PrettyPrint(value interface{}) string {
 	if s, ok := value.(string); ok {
@@ -18,3 +30,108 @@ func PrettyPrint(value interface{}) string {
 	}
 	return string(valueJson)
 }
+
+// ConfigureLogging sets the format and the level of the standard logrus logger.
+// format is one of json or text, level is any level understood by logrus.ParseLevel.
+func ConfigureLogging(format string, level string) error {
+	switch format {
+	case JSONLogFormat:
+		log.SetFormatter(&log.JSONFormatter{})
+	case TextLogFormat:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %q, must be one of %v or %v", format, JSONLogFormat, TextLogFormat)
+	}
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(lvl)
+	return nil
+}
+
+// ToggleDebugOnSignal switches the level of the standard logrus logger between debug and its
+// current level every time the process receives sig, so that a running process can be debugged.
+func ToggleDebugOnSignal(sig os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig)
+	go func() {
+		previous := log.GetLevel()
+		for range c {
+			if log.GetLevel() == log.DebugLevel {
+				log.SetLevel(previous)
+			} else {
+				previous = log.GetLevel()
+				log.SetLevel(log.DebugLevel)
+			}
+			log.Infof("Received %v, log level set to %v.", sig, log.GetLevel())
+		}
+	}()
+}
+
+// NewLogrLogger returns a logr.Logger writing to entry, so that libraries logging through logr
+// (e.g. controller-runtime) use the same logger, format and level as the rest of the operator.
+// Info logs of verbosity 0 are logged at info level, more verbose ones at debug level.
+func NewLogrLogger(entry *log.Entry) logr.Logger {
+	return &logrusLogger{entry: entry}
+}
+
+type logrusLogger struct {
+	entry     *log.Entry
+	name      string
+	verbosity int
+}
+
+// blank assignment to verify that logrusLogger implements logr.Logger
+var _ logr.Logger = &logrusLogger{}
+
+func (l *logrusLogger) level() log.Level {
+	if l.verbosity > 0 {
+		return log.DebugLevel
+	}
+	return log.InfoLevel
+}
+
+func (l *logrusLogger) Enabled() bool {
+	return l.entry.Logger.IsLevelEnabled(l.level())
+}
+
+func (l *logrusLogger) Info(msg string, keysAndValues ...interface{}) {
+	if !l.Enabled() {
+		return
+	}
+	l.entry.WithFields(toFields(keysAndValues)).Log(l.level(), msg)
+}
+
+func (l *logrusLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.entry.WithFields(toFields(keysAndValues)).WithError(err).Error(msg)
+}
+
+func (l *logrusLogger) V(level int) logr.InfoLogger {
+	return &logrusLogger{entry: l.entry, name: l.name, verbosity: l.verbosity + level}
+}
+
+func (l *logrusLogger) WithName(name string) logr.Logger {
+	if l.name != "" {
+		name = strings.Join([]string{l.name, name}, ".")
+	}
+	return &logrusLogger{entry: l.entry.WithField("logger", name), name: name, verbosity: l.verbosity}
+}
+
+func (l *logrusLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &logrusLogger{entry: l.entry.WithFields(toFields(keysAndValues)), name: l.name, verbosity: l.verbosity}
+}
+
+// toFields converts logr key/value pairs to logrus fields
+func toFields(keysAndValues []interface{}) log.Fields {
+	fields := log.Fields{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprintf("%v", keysAndValues[i])
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = nil
+		}
+	}
+	return fields
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func Test_NewLogrLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = buf
+	logger.SetFormatter(&log.JSONFormatter{})
+	logger.SetLevel(log.InfoLevel)
+
+	l := NewLogrLogger(log.NewEntry(logger)).WithName("controller").WithValues("request", "kubeflow")
+	l.V(1).Info("debug message")
+	if buf.Len() != 0 {
+		t.Errorf("Expected verbose logs to be disabled at info level; got %v", buf.String())
+	}
+
+	l.Error(fmt.Errorf("failed"), "error message", "kind", "KfDef")
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log entry %v: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":   "error",
+		"msg":     "error message",
+		"error":   "failed",
+		"logger":  "controller",
+		"request": "kubeflow",
+		"kind":    "KfDef",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("Expected %v=%v in log entry; got %v", k, v, entry[k])
+		}
+	}
+}
+
+func Test_ConfigureLogging(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	defer log.SetFormatter(log.StandardLogger().Formatter)
+
+	if err := ConfigureLogging("xml", "info"); err == nil {
+		t.Errorf("Expected an error for an unknown log format")
+	}
+	if err := ConfigureLogging(JSONLogFormat, "verbose"); err == nil {
+		t.Errorf("Expected an error for an unknown log level")
+	}
+	if err := ConfigureLogging(JSONLogFormat, "debug"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if log.GetLevel() != log.DebugLevel {
+		t.Errorf("Expected log level debug; got %v", log.GetLevel())
+	}
+}
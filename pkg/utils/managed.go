@@ -0,0 +1,54 @@
+package utils
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// ManagedLabel is the label of every resource the operator creates, whether deployed by a KfDef or by one of its
+	// other controllers, selecting them in the watches of its controllers and in the cache of the managed resources
+	ManagedLabel = "opendatahub.io/managed-by"
+	// ManagedValue is the value of ManagedLabel
+	ManagedValue = "opendatahub-operator"
+)
+
+// ManagedSelector returns the label selector of the resources created by the operator
+func ManagedSelector() string {
+	return ManagedLabel + "=" + ManagedValue
+}
+
+// WithManagedLabel returns a copy of labels along with the label of the resources created by the operator. The labels
+// selecting pods must not be passed through it, since the selectors of the workloads cannot be changed.
+func WithManagedLabel(labels map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[ManagedLabel] = ManagedValue
+	return result
+}
+
+// IsManaged returns true for the resources created by the operator
+func IsManaged(object metav1.Object) bool {
+	return object != nil && object.GetLabels()[ManagedLabel] == ManagedValue
+}
+
+// ManagedPredicate filters out the events of the resources not created by the operator, so that the changes to
+// the unrelated resources of the watched namespaces do not requeue the owners. An update removing the label is
+// still let through, for the owner to label the resource again.
+var ManagedPredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return IsManaged(e.Meta)
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return IsManaged(e.Meta)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return IsManaged(e.MetaOld) || IsManaged(e.MetaNew)
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return IsManaged(e.Meta)
+	},
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestWithManagedLabel(t *testing.T) {
+	labels := map[string]string{"app": "fake"}
+	managed := WithManagedLabel(labels)
+	if managed[ManagedLabel] != ManagedValue || managed["app"] != "fake" {
+		t.Errorf("WithManagedLabel(%v) = %v, expected app and %v labels", labels, managed, ManagedLabel)
+	}
+	if _, found := labels[ManagedLabel]; found {
+		t.Errorf("WithManagedLabel changed the labels it was passed: %v", labels)
+	}
+}
+
+func TestManagedPredicate(t *testing.T) {
+	managed := &metav1.ObjectMeta{Labels: WithManagedLabel(nil)}
+	unrelated := &metav1.ObjectMeta{Labels: map[string]string{"app": "fake"}}
+	if !ManagedPredicate.Create(event.CreateEvent{Meta: managed}) {
+		t.Errorf("Create event of a managed resource filtered out")
+	}
+	if ManagedPredicate.Create(event.CreateEvent{Meta: unrelated}) {
+		t.Errorf("Create event of an unrelated resource let through")
+	}
+	if ManagedPredicate.Update(event.UpdateEvent{MetaOld: unrelated, MetaNew: unrelated}) {
+		t.Errorf("Update event of an unrelated resource let through")
+	}
+	if !ManagedPredicate.Update(event.UpdateEvent{MetaOld: managed, MetaNew: unrelated}) {
+		t.Errorf("Update event removing the label filtered out")
+	}
+	if ManagedPredicate.Delete(event.DeleteEvent{Meta: unrelated}) {
+		t.Errorf("Delete event of an unrelated resource let through")
+	}
+}
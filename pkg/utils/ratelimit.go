@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var (
+	// APIQPS is the number of requests per second the clients of the operator send to the API server. The apply
+	// clients share a single rate limiter, so that a full reconcile of every application stays under it.
+	APIQPS float32 = 20
+	// APIBurst is the number of requests the clients of the operator send to the API server above APIQPS in bursts
+	APIBurst = 30
+
+	// applyRateLimiterOnce creates applyRateLimiter from the settings the operator is started with
+	applyRateLimiterOnce sync.Once
+	// applyRateLimiter is the rate limiter shared by the apply clients
+	applyRateLimiter flowcontrol.RateLimiter
+)
+
+// ValidateRateLimits returns an error if the rate limits of the clients are out of range
+func ValidateRateLimits(qps float32, burst int) error {
+	if qps <= 0 || burst <= 0 {
+		return fmt.Errorf("the QPS and the burst of the clients must be positive, got %v and %v", qps, burst)
+	}
+	if float32(burst) < qps {
+		return fmt.Errorf("the burst of the clients must be at least their QPS, got %v and %v", burst, qps)
+	}
+	return nil
+}
+
+// RateLimited returns a copy of config whose clients share the rate limiter of the apply clients, or nil when
+// config is nil
+func RateLimited(config *rest.Config) *rest.Config {
+	if config == nil {
+		return nil
+	}
+	applyRateLimiterOnce.Do(func() {
+		applyRateLimiter = flowcontrol.NewTokenBucketRateLimiter(APIQPS, APIBurst)
+	})
+	limited := rest.CopyConfig(config)
+	limited.QPS, limited.Burst = APIQPS, APIBurst
+	limited.RateLimiter = applyRateLimiter
+	return limited
+}
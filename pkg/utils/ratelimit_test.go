@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestValidateRateLimits(t *testing.T) {
+	type testCase struct {
+		QPS   float32
+		Burst int
+		Valid bool
+	}
+	testCases := []testCase{
+		{QPS: 20, Burst: 30, Valid: true},
+		{QPS: 0.5, Burst: 1, Valid: true},
+		{QPS: 20, Burst: 20, Valid: true},
+		{QPS: 0, Burst: 30, Valid: false},
+		{QPS: -1, Burst: 30, Valid: false},
+		{QPS: 20, Burst: 0, Valid: false},
+		{QPS: 20, Burst: 10, Valid: false},
+	}
+	for _, c := range testCases {
+		if err := ValidateRateLimits(c.QPS, c.Burst); (err == nil) != c.Valid {
+			t.Errorf("ValidateRateLimits(%v, %v) = %v, expected valid: %v", c.QPS, c.Burst, err, c.Valid)
+		}
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	if RateLimited(nil) != nil {
+		t.Errorf("Expected no config to be rate limited")
+	}
+	config := &rest.Config{Host: "https://api.example.com:6443"}
+	first, second := RateLimited(config), RateLimited(&rest.Config{Host: "https://api.example.com:6443"})
+	if config.RateLimiter != nil || config.QPS != 0 {
+		t.Errorf("Expected the config to be copied, got %+v", config)
+	}
+	if first.Host != config.Host || first.QPS != APIQPS || first.Burst != APIBurst {
+		t.Errorf("Unexpected rate limited config %+v", first)
+	}
+	if first.RateLimiter == nil || first.RateLimiter != second.RateLimiter {
+		t.Errorf("Expected the rate limited configs to share a rate limiter")
+	}
+}
@@ -0,0 +1,350 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	kfutils "github.com/kubeflow/kfctl/v3/pkg/utils"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	crdclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var (
+	// ManageCertificates generates the serving certificate of the webhook server and its CA, injects the CA into
+	// the webhook configurations and the CRDs converted by the webhook server, and rotates them before they expire.
+	// The certificate is provided by the OpenShift service CA or cert-manager otherwise.
+	ManageCertificates = false
+	// ServiceName is the name of the Service of the webhook server, in the namespace of the operator
+	ServiceName = "kubeflow-operator-webhook"
+	// CertificateSecret is the name of the Secret the certificate and the CA are kept in, shared by the replicas
+	CertificateSecret = "kubeflow-operator-webhook-cert"
+	// CertificateValidity is how long the certificate and the CA are valid for
+	CertificateValidity = 365 * 24 * time.Hour
+	// CertificateRotateBefore is how long before their expiry the certificate and the CA are generated again
+	CertificateRotateBefore = 90 * 24 * time.Hour
+	// certificateCheckInterval is the time between two checks of the certificate
+	certificateCheckInterval = time.Hour
+)
+
+const (
+	// caBundleKey holds the CA bundle injected in the webhook configurations: the current CA, and the previous one
+	// until it expires, so that the replicas still serving the previous certificate are trusted
+	caBundleKey = "ca-bundle.crt"
+)
+
+// certificateManager keeps the serving certificate of the webhook server of a replica of the operator up to date
+type certificateManager struct {
+	clientset kubernetes.Interface
+	crdClient crdclientset.CustomResourceDefinitionsGetter
+	namespace string
+	certDir   string
+}
+
+// NeedLeaderElection returns false, every replica serves the webhooks
+func (m *certificateManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Start checks the certificate every certificateCheckInterval until stop is closed
+func (m *certificateManager) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		if err := m.sync(); err != nil {
+			log.Warnf("Failed to rotate the webhook certificate. Error: %v.", err)
+		}
+	}
+}
+
+// addCertificateManager writes the certificate of the webhook server to certDir before the server is started, and
+// adds the rotation of the certificate to the manager
+func addCertificateManager(mgr manager.Manager, certDir string) error {
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return fmt.Errorf("failed to get the namespace of the webhook server: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	crdClient, err := crdclientset.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	m := &certificateManager{clientset: clientset, crdClient: crdClient, namespace: namespace, certDir: certDir}
+	// The replicas starting together may conflict when creating the Secret
+	if err := backoff.Retry(m.sync, kfutils.NewDefaultBackoff()); err != nil {
+		return fmt.Errorf("failed to provision the webhook certificate: %v", err)
+	}
+	return mgr.Add(m)
+}
+
+// sync generates the certificate when it is missing or about to expire, writes it for the webhook server and
+// injects its CA bundle
+func (m *certificateManager) sync() error {
+	secret, err := m.ensureSecret(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := writeCertificate(m.certDir, secret); err != nil {
+		return err
+	}
+	return m.injectCABundle(secret.Data[caBundleKey])
+}
+
+// ensureSecret returns the Secret of the certificate, generating it once it is due for rotation. Another replica
+// rotating it at the same time makes the update conflict, and the certificate is read again on the next try.
+func (m *certificateManager) ensureSecret(now time.Time) (*corev1.Secret, error) {
+	secrets := m.clientset.CoreV1().Secrets(m.namespace)
+	secret, err := secrets.Get(CertificateSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		data, err := newCertificate(ServiceName, m.namespace, nil, now)
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: CertificateSecret, Namespace: m.namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		}
+		log.Infof("Generating the webhook certificate %v/%v.", m.namespace, CertificateSecret)
+		return secrets.Create(secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	reason := rotationReason(secret.Data, ServiceName, m.namespace, now)
+	if reason == "" {
+		return secret, nil
+	}
+	data, err := newCertificate(ServiceName, m.namespace, secret.Data[corev1.ServiceAccountRootCAKey], now)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Rotating the webhook certificate %v/%v, %v.", m.namespace, CertificateSecret, reason)
+	secret.Data = data
+	return secrets.Update(secret)
+}
+
+// rotationReason returns why the certificate of data must be generated again, or an empty string if it is valid
+// for the Service for more than CertificateRotateBefore
+func rotationReason(data map[string][]byte, service string, namespace string, now time.Time) string {
+	cert, err := parseCertificate(data[corev1.TLSCertKey])
+	if err != nil {
+		return err.Error()
+	}
+	if _, err := parseCertificate(data[corev1.ServiceAccountRootCAKey]); err != nil {
+		return "its CA " + err.Error()
+	}
+	if err := cert.VerifyHostname(serviceHost(service, namespace)); err != nil {
+		return err.Error()
+	}
+	if expiry := cert.NotAfter; now.Add(CertificateRotateBefore).After(expiry) {
+		return "it expires on " + expiry.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// newCertificate returns the data of the Secret of a new serving certificate of the Service, signed by a new CA.
+// The CA bundle trusts previousCA too until it expires.
+func newCertificate(service string, namespace string, previousCA []byte, now time.Time) (map[string][]byte, error) {
+	notBefore := now.Add(-time.Hour)
+	notAfter := now.Add(CertificateValidity)
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%v-ca@%v", service, now.Unix())},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if caTemplate.SerialNumber, err = serialNumber(); err != nil {
+		return nil, err
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	host := serviceHost(service, namespace)
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: host},
+		DNSNames:    []string{service, service + "." + namespace, host, host + ".cluster.local"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if template.SerialNumber, err = serialNumber(); err != nil {
+		return nil, err
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	bundle := append([]byte{}, caPEM...)
+	if previous, err := parseCertificate(previousCA); err == nil && now.Before(previous.NotAfter) {
+		bundle = append(bundle, previousCA...)
+	}
+	return map[string][]byte{
+		corev1.TLSCertKey:              pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		corev1.TLSPrivateKeyKey:        pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		corev1.ServiceAccountRootCAKey: caPEM,
+		caBundleKey:                    bundle,
+	}, nil
+}
+
+// serviceHost returns the host name of the Service
+func serviceHost(service string, namespace string) string {
+	return fmt.Sprintf("%v.%v.svc", service, namespace)
+}
+
+// serialNumber returns a random serial number of a certificate
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// parseCertificate parses the first certificate of PEM encoded data
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("has no PEM encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// writeCertificate writes the certificate and the key of the Secret to the files the webhook server watches, when
+// they changed. The key is written first, the server reloading both files on every write.
+func writeCertificate(dir string, secret *corev1.Secret) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		file := filepath.Join(dir, key)
+		if current, err := ioutil.ReadFile(file); err == nil && bytes.Equal(current, secret.Data[key]) {
+			continue
+		}
+		if err := ioutil.WriteFile(file, secret.Data[key], 0600); err != nil {
+			return fmt.Errorf("failed to write the webhook certificate: %v", err)
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets the CA bundle of the webhooks and the conversion webhooks of the CRDs calling the Service of
+// the webhook server
+func (m *certificateManager) injectCABundle(bundle []byte) error {
+	isService := func(name string, namespace string) bool {
+		return name == ServiceName && namespace == m.namespace
+	}
+
+	admission := m.clientset.AdmissionregistrationV1beta1()
+	validating, err := admission.ValidatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the ValidatingWebhookConfigurations: %v", err)
+	}
+	for i := range validating.Items {
+		config := &validating.Items[i]
+		changed := false
+		for j := range config.Webhooks {
+			client := &config.Webhooks[j].ClientConfig
+			if client.Service != nil && isService(client.Service.Name, client.Service.Namespace) &&
+				!bytes.Equal(client.CABundle, bundle) {
+				client.CABundle, changed = bundle, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := admission.ValidatingWebhookConfigurations().Update(config); err != nil {
+			return fmt.Errorf("failed to inject the CA bundle into ValidatingWebhookConfiguration %v: %v", config.Name, err)
+		}
+		log.Infof("Injected the webhook CA bundle into ValidatingWebhookConfiguration %v.", config.Name)
+	}
+
+	mutating, err := admission.MutatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the MutatingWebhookConfigurations: %v", err)
+	}
+	for i := range mutating.Items {
+		config := &mutating.Items[i]
+		changed := false
+		for j := range config.Webhooks {
+			client := &config.Webhooks[j].ClientConfig
+			if client.Service != nil && isService(client.Service.Name, client.Service.Namespace) &&
+				!bytes.Equal(client.CABundle, bundle) {
+				client.CABundle, changed = bundle, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := admission.MutatingWebhookConfigurations().Update(config); err != nil {
+			return fmt.Errorf("failed to inject the CA bundle into MutatingWebhookConfiguration %v: %v", config.Name, err)
+		}
+		log.Infof("Injected the webhook CA bundle into MutatingWebhookConfiguration %v.", config.Name)
+	}
+
+	crds, err := m.crdClient.CustomResourceDefinitions().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the CRDs: %v", err)
+	}
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		if crd.Spec.Conversion == nil || crd.Spec.Conversion.WebhookClientConfig == nil {
+			continue
+		}
+		client := crd.Spec.Conversion.WebhookClientConfig
+		if client.Service == nil || !isService(client.Service.Name, client.Service.Namespace) ||
+			bytes.Equal(client.CABundle, bundle) {
+			continue
+		}
+		client.CABundle = bundle
+		if _, err := m.crdClient.CustomResourceDefinitions().Update(crd); err != nil {
+			return fmt.Errorf("failed to inject the CA bundle into CRD %v: %v", crd.Name, err)
+		}
+		log.Infof("Injected the webhook CA bundle into CRD %v.", crd.Name)
+	}
+	return nil
+}
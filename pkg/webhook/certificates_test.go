@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	crdfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCertificateRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	service := &admissionv1beta1.ServiceReference{Name: ServiceName, Namespace: "operators"}
+	other := &admissionv1beta1.ServiceReference{Name: "other-webhook", Namespace: "operators"}
+	clientset := fake.NewSimpleClientset(&admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubeflow-operator-kfdef-validator"},
+		Webhooks: []admissionv1beta1.ValidatingWebhook{
+			{Name: "validate.kfdef.apps.kubeflow.org", ClientConfig: admissionv1beta1.WebhookClientConfig{Service: service}},
+			{Name: "other", ClientConfig: admissionv1beta1.WebhookClientConfig{Service: other}},
+		},
+	})
+	crdClient := crdfake.NewSimpleClientset(&apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "kfdefs.kfdef.apps.kubeflow.org"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{Conversion: &apiextensionsv1beta1.CustomResourceConversion{
+			Strategy: apiextensionsv1beta1.WebhookConverter,
+			WebhookClientConfig: &apiextensionsv1beta1.WebhookClientConfig{
+				Service: &apiextensionsv1beta1.ServiceReference{Name: ServiceName, Namespace: "operators"},
+			},
+		}},
+	}).ApiextensionsV1beta1()
+	m := &certificateManager{clientset: clientset, crdClient: crdClient, namespace: "operators", certDir: dir}
+
+	now := time.Now()
+	first, err := m.ensureSecret(now)
+	if err != nil {
+		t.Fatalf("Failed to generate the certificate: %v", err)
+	}
+	if err := writeCertificate(dir, first); err != nil {
+		t.Fatalf("Failed to write the certificate: %v", err)
+	}
+	if err := m.injectCABundle(first.Data[caBundleKey]); err != nil {
+		t.Fatalf("Failed to inject the CA bundle: %v", err)
+	}
+	verify(t, dir, first.Data[caBundleKey], now)
+
+	config, _ := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("kubeflow-operator-kfdef-validator", metav1.GetOptions{})
+	if !bytes.Equal(config.Webhooks[0].ClientConfig.CABundle, first.Data[caBundleKey]) {
+		t.Errorf("Expected the CA bundle to be injected into the webhook of the operator")
+	}
+	if len(config.Webhooks[1].ClientConfig.CABundle) > 0 {
+		t.Errorf("Expected the webhook of another Service to be left as it is")
+	}
+	crd, _ := crdClient.CustomResourceDefinitions().Get("kfdefs.kfdef.apps.kubeflow.org", metav1.GetOptions{})
+	if !bytes.Equal(crd.Spec.Conversion.WebhookClientConfig.CABundle, first.Data[caBundleKey]) {
+		t.Errorf("Expected the CA bundle to be injected into the conversion webhook of the CRD")
+	}
+
+	unchanged, err := m.ensureSecret(now.Add(CertificateValidity - CertificateRotateBefore - time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unchanged.Data[corev1.TLSCertKey], first.Data[corev1.TLSCertKey]) {
+		t.Errorf("Expected the certificate to be kept until it is due for rotation")
+	}
+
+	rotation := now.Add(CertificateValidity - CertificateRotateBefore + time.Hour)
+	rotated, err := m.ensureSecret(rotation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rotated.Data[corev1.TLSCertKey], first.Data[corev1.TLSCertKey]) {
+		t.Fatalf("Expected the certificate to be rotated before it expires")
+	}
+	// The replicas serving the previous certificate are trusted until it expires
+	verify(t, dir, rotated.Data[caBundleKey], rotation)
+	if err := writeCertificate(dir, rotated); err != nil {
+		t.Fatal(err)
+	}
+	verify(t, dir, rotated.Data[caBundleKey], rotation)
+}
+
+func TestRotationReason(t *testing.T) {
+	now := time.Now()
+	data, err := newCertificate(ServiceName, "operators", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		Name      string
+		Data      map[string][]byte
+		Namespace string
+		Rotated   bool
+	}
+	testCases := []testCase{
+		{Name: "valid", Data: data, Namespace: "operators"},
+		{Name: "other namespace", Data: data, Namespace: "opendatahub", Rotated: true},
+		{Name: "missing", Data: map[string][]byte{}, Namespace: "operators", Rotated: true},
+		{Name: "missing CA", Data: map[string][]byte{corev1.TLSCertKey: data[corev1.TLSCertKey]}, Namespace: "operators", Rotated: true},
+	}
+	for _, c := range testCases {
+		if reason := rotationReason(c.Data, ServiceName, c.Namespace, now); (reason != "") != c.Rotated {
+			t.Errorf("%v: expected rotation %v, got %q", c.Name, c.Rotated, reason)
+		}
+	}
+}
+
+// verify checks the certificate written to dir is trusted by the CA bundle for the host of the Service at the time
+func verify(t *testing.T, dir string, bundle []byte, at time.Time) {
+	cert, err := tls.LoadX509KeyPair(dir+"/tls.crt", dir+"/tls.key")
+	if err != nil {
+		t.Fatalf("Failed to load the certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		t.Fatalf("Failed to parse the CA bundle")
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: serviceHost(ServiceName, "operators"), CurrentTime: at}); err != nil {
+		t.Errorf("Expected the certificate to be trusted: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package kfdef
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfapp/kustomize"
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DefaultPath is the path the KfDef defaulting webhook is served on
+const DefaultPath = "/mutate-kfdef"
+
+// DefaultRepoName is the repo the applications refer to when their repoRef has no name
+const DefaultRepoName = "manifests"
+
+var (
+	// DefaultManifestsURI is the URI of the manifests released along with the operator. The repo named
+	// DefaultRepoName is added to the specs referring to it without declaring it, and gets this URI when it has none.
+	DefaultManifestsURI = ""
+	// DefaultOpenShiftOverlays are the overlays of the kustomize applications setting none on OpenShift
+	DefaultOpenShiftOverlays = []string{}
+	// DefaultKubernetesOverlays are the overlays of the kustomize applications setting none on other clusters
+	DefaultKubernetesOverlays = []string{}
+)
+
+// kfDefDefaulter fills in the fields left empty in KfDef specs and rewrites their deprecated fields
+type kfDefDefaulter struct {
+	decoder   *admission.Decoder
+	openShift bool
+}
+
+// NewDefaultingWebhook returns the admission webhook defaulting KfDef create and update requests.
+// openShift selects the default overlays of the cluster.
+func NewDefaultingWebhook(openShift bool) *admission.Webhook {
+	return &admission.Webhook{Handler: &kfDefDefaulter{openShift: openShift}}
+}
+
+// blank assignment to verify that kfDefDefaulter implements admission.DecoderInjector
+var _ admission.DecoderInjector = &kfDefDefaulter{}
+
+// InjectDecoder injects the decoder into the defaulter
+func (d *kfDefDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// Handle decodes the KfDef from the admission request and patches its spec with the defaults
+func (d *kfDefDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	instance := &kfdefv1.KfDef{}
+	if err := d.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	if !DefaultSpec(&instance.Spec, d.openShift) {
+		return admission.Allowed("")
+	}
+	log.Infof("Defaulting the spec of KfDef %v/%v.", req.Namespace, req.Name)
+	defaulted, err := json.Marshal(instance)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// DefaultSpec fills in the fields left empty in the spec:
+//   - the repoRef without a name refers to DefaultRepoName, and the repo is added with DefaultManifestsURI
+//   - the kustomize applications without overlays get the overlays of the cluster, once the deprecated
+//     overlay parameters are moved to their overlays
+//   - the applications without a deletion policy are deleted along with the KfDef
+//
+// It returns true if the spec was changed. Defaulting a defaulted spec leaves it as it is.
+func DefaultSpec(spec *kfdefv1.KfDefSpec, openShift bool) bool {
+	changed := false
+	overlays := DefaultKubernetesOverlays
+	if openShift {
+		overlays = DefaultOpenShiftOverlays
+	}
+
+	usesDefaultRepo := false
+	for i := range spec.Applications {
+		app := &spec.Applications[i]
+		for _, repoRef := range applicationRepoRefs(app) {
+			if repoRef.Name == "" {
+				repoRef.Name = DefaultRepoName
+				changed = true
+			}
+			usesDefaultRepo = usesDefaultRepo || repoRef.Name == DefaultRepoName
+		}
+		if app.KustomizeConfig != nil {
+			if moveOverlayParameters(app.KustomizeConfig) {
+				changed = true
+			}
+			if len(app.KustomizeConfig.Overlays) == 0 && len(overlays) > 0 {
+				app.KustomizeConfig.Overlays = append([]string{}, overlays...)
+				changed = true
+			}
+		}
+		if app.DeletionPolicy == "" {
+			app.DeletionPolicy = kfdefv1.DeletionPolicyDelete
+			changed = true
+		}
+	}
+
+	if DefaultManifestsURI == "" {
+		return changed
+	}
+	declared := false
+	for i := range spec.Repos {
+		if spec.Repos[i].Name != DefaultRepoName {
+			continue
+		}
+		declared = true
+		if spec.Repos[i].URI == "" {
+			spec.Repos[i].URI = DefaultManifestsURI
+			changed = true
+		}
+	}
+	if usesDefaultRepo && !declared {
+		spec.Repos = append(spec.Repos, kfdefv1.Repo{Name: DefaultRepoName, URI: DefaultManifestsURI})
+		changed = true
+	}
+	return changed
+}
+
+// applicationRepoRefs returns the repoRefs of the application
+func applicationRepoRefs(app *kfdefv1.Application) []*kfdefv1.RepoRef {
+	repoRefs := []*kfdefv1.RepoRef{}
+	if app.KustomizeConfig != nil && app.KustomizeConfig.RepoRef != nil {
+		repoRefs = append(repoRefs, app.KustomizeConfig.RepoRef)
+	}
+	if app.HelmConfig != nil && app.HelmConfig.RepoRef != nil {
+		repoRefs = append(repoRefs, app.HelmConfig.RepoRef)
+	}
+	return repoRefs
+}
+
+// moveOverlayParameters moves the values of the deprecated overlay parameters to the overlays of the
+// kustomize config. It returns true if there was any.
+func moveOverlayParameters(config *kfdefv1.KustomizeConfig) bool {
+	params := []kfdefv1.NameValue{}
+	moved := false
+	for _, param := range config.Parameters {
+		if param.Name != kustomize.OverlayParamName {
+			params = append(params, param)
+			continue
+		}
+		moved = true
+		if param.Value != "" && !contains(config.Overlays, param.Value) {
+			config.Overlays = append(config.Overlays, param.Value)
+		}
+	}
+	if moved {
+		config.Parameters = params
+	}
+	return moved
+}
+
+// contains returns true if values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package kfdef
+
+import (
+	"reflect"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+)
+
+func withOverlays(app kfdefv1.Application, overlays ...string) kfdefv1.Application {
+	app.KustomizeConfig.Overlays = overlays
+	return app
+}
+
+func withParameters(app kfdefv1.Application, params ...kfdefv1.NameValue) kfdefv1.Application {
+	app.KustomizeConfig.Parameters = params
+	return app
+}
+
+func TestDefaultSpec(t *testing.T) {
+	type testCase struct {
+		Name      string
+		OpenShift bool
+		Spec      kfdefv1.KfDefSpec
+		Expected  kfdefv1.KfDefSpec
+	}
+
+	DefaultManifestsURI = "https://github.com/opendatahub-io/odh-manifests/tarball/v1.1.0"
+	DefaultOpenShiftOverlays = []string{"openshift"}
+	DefaultKubernetesOverlays = []string{}
+	defer func() {
+		DefaultManifestsURI = ""
+		DefaultOpenShiftOverlays = []string{}
+	}()
+	manifests := kfdefv1.Repo{Name: "manifests", URI: DefaultManifestsURI}
+	custom := kfdefv1.Repo{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}
+
+	testCases := []testCase{
+		{
+			Name:      "minimal",
+			OpenShift: true,
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-dashboard", "")},
+			},
+			Expected: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withDeletionPolicy(withOverlays(app("odh-dashboard", "manifests"), "openshift"), kfdefv1.DeletionPolicyDelete),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+		},
+		{
+			Name: "kubernetes",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-dashboard", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests"}},
+			},
+			Expected: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withDeletionPolicy(app("odh-dashboard", "manifests"), kfdefv1.DeletionPolicyDelete)},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+		},
+		{
+			Name:      "deprecated-overlay-parameters",
+			OpenShift: true,
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withParameters(app("odh-dashboard", "manifests"),
+						kfdefv1.NameValue{Name: "overlay", Value: "authentication"},
+						kfdefv1.NameValue{Name: "namespace", Value: "opendatahub"}),
+				},
+				Repos: []kfdefv1.Repo{custom},
+			},
+			Expected: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withDeletionPolicy(withParameters(withOverlays(app("odh-dashboard", "manifests"), "authentication"),
+						kfdefv1.NameValue{Name: "namespace", Value: "opendatahub"}), kfdefv1.DeletionPolicyDelete),
+				},
+				Repos: []kfdefv1.Repo{custom},
+			},
+		},
+		{
+			Name:      "other-repo",
+			OpenShift: true,
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withDeletionPolicy(withOverlays(app("odh-dashboard", "odh"), "dev"), kfdefv1.DeletionPolicyRetain),
+				},
+				Repos: []kfdefv1.Repo{{Name: "odh", URI: custom.URI}},
+			},
+			Expected: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withDeletionPolicy(withOverlays(app("odh-dashboard", "odh"), "dev"), kfdefv1.DeletionPolicyRetain),
+				},
+				Repos: []kfdefv1.Repo{{Name: "odh", URI: custom.URI}},
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		spec := c.Spec.DeepCopy()
+		changed := DefaultSpec(spec, c.OpenShift)
+		if !reflect.DeepEqual(*spec, c.Expected) {
+			t.Errorf("%v: expected the spec to be defaulted to %+v, got %+v", c.Name, c.Expected, *spec)
+		}
+		if changed != !reflect.DeepEqual(c.Spec, c.Expected) {
+			t.Errorf("%v: expected DefaultSpec to report the changes, got %v", c.Name, changed)
+		}
+		if DefaultSpec(spec, c.OpenShift) {
+			t.Errorf("%v: expected defaulting a defaulted spec to leave it as it is", c.Name)
+		}
+	}
+}
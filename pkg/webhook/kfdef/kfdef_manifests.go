@@ -0,0 +1,138 @@
+package kfdef
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"sigs.k8s.io/kustomize/v3/pkg/pgmconfig"
+)
+
+var (
+	// KnownApplications are the names the applications of the KfDefs may have, any name when empty
+	KnownApplications = []string{}
+	// ManifestsCacheDir is the directory the validating webhook fetches the repos of the KfDefs to
+	ManifestsCacheDir = path.Join(os.TempDir(), "kfdef-webhook")
+	// ManifestsCacheTTL is how long the repos fetched by the validating webhook are used before being fetched again
+	ManifestsCacheTTL = 10 * time.Minute
+)
+
+// fetchedRepo is a repo fetched by the validating webhook
+type fetchedRepo struct {
+	localPath string
+	fetched   time.Time
+}
+
+// manifestsCache holds the repos fetched by the validating webhook, by namespace and repo, for the KfDefs
+// referring to the same repos not to fetch them on every request
+type manifestsCache struct {
+	mu    sync.Mutex
+	repos map[string]fetchedRepo
+}
+
+func newManifestsCache() *manifestsCache {
+	return &manifestsCache{repos: map[string]fetchedRepo{}}
+}
+
+// resolve returns the local path of repo r of a KfDef of namespace, fetching it with ctx unless it was fetched
+// within ManifestsCacheTTL. The repos are fetched one at a time.
+func (c *manifestsCache) resolve(ctx context.Context, namespace string, r kfdefv1.Repo) (string, error) {
+	spec, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(namespace + "/" + string(spec)))
+	key := hex.EncodeToString(sum[:8])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if repo, ok := c.repos[key]; ok && time.Since(repo.fetched) < ManifestsCacheTTL {
+		return repo.localPath, nil
+	}
+	config := &kfconfig.KfConfig{
+		Spec: kfconfig.KfConfigSpec{
+			AppDir: path.Join(ManifestsCacheDir, key),
+			Repos: []kfconfig.Repo{{
+				Name:       r.Name,
+				URI:        r.URI,
+				Ref:        r.Ref,
+				SecretName: r.SecretName,
+			}},
+		},
+	}
+	config.Namespace = namespace
+	if r.Verify != nil {
+		config.Spec.Repos[0].Verify = &kfconfig.Verification{
+			PublicKey: r.Verify.PublicKey,
+			Identity:  r.Verify.Identity,
+			Issuer:    r.Verify.Issuer,
+		}
+	}
+	if err := config.SyncCacheContext(ctx); err != nil {
+		return "", err
+	}
+	cache, ok := config.GetRepoCache(r.Name)
+	if !ok {
+		return "", fmt.Errorf("repo %q was not fetched", r.Name)
+	}
+	c.repos[key] = fetchedRepo{localPath: cache.LocalPath, fetched: time.Now()}
+	return cache.LocalPath, nil
+}
+
+// validateManifests checks that the path of every kustomize application of the spec of a KfDef of namespace is a
+// kustomization of its repo, fetching the repos with ctx. The spec is valid according to ValidateSpec.
+// It returns one message per problem found.
+func (c *manifestsCache) validateManifests(ctx context.Context, namespace string, spec *kfdefv1.KfDefSpec) []string {
+	errs := []string{}
+	repos := map[string]int{}
+	for i, r := range spec.Repos {
+		repos[r.Name] = i
+	}
+	localPaths := map[string]string{}
+	for i, app := range spec.Applications {
+		if app.KustomizeConfig == nil || app.KustomizeConfig.RepoRef == nil {
+			continue
+		}
+		repoRef := app.KustomizeConfig.RepoRef
+		localPath, fetched := localPaths[repoRef.Name]
+		if !fetched {
+			idx := repos[repoRef.Name]
+			var err error
+			if localPath, err = c.resolve(ctx, namespace, spec.Repos[idx]); err != nil {
+				errs = append(errs, fmt.Sprintf("spec.repos[%d]: repo %q could not be fetched: %v", idx, repoRef.Name, err))
+			}
+			localPaths[repoRef.Name] = localPath
+		}
+		if localPath == "" {
+			continue
+		}
+		if !isKustomization(localPath, repoRef.Path) {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q refers to path %q, which is not a kustomization of repo %q",
+				i, app.Name, repoRef.Path, repoRef.Name))
+		}
+	}
+	return errs
+}
+
+// isKustomization returns true if dir of the repo fetched to localPath is a directory holding a kustomization
+func isKustomization(localPath string, dir string) bool {
+	appPath := path.Join(localPath, dir)
+	if appPath != localPath && !strings.HasPrefix(appPath, localPath+"/") {
+		return false
+	}
+	for _, name := range pgmconfig.KustomizationFileNames {
+		if fi, err := os.Stat(path.Join(appPath, name)); err == nil && !fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
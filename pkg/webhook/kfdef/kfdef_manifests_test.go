@@ -0,0 +1,101 @@
+package kfdef
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/testenv"
+)
+
+func TestValidateManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kfdef-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cacheDir string) { ManifestsCacheDir = cacheDir }(ManifestsCacheDir)
+	ManifestsCacheDir = path.Join(dir, "cache")
+
+	err = testenv.WriteManifests(path.Join(dir, "fixtures"), map[string]string{
+		"odh-manifests/odh-common/kustomization.yaml":         "resources: []\n",
+		"odh-manifests/odh-dashboard/base/kustomization.yaml": "resources: []\n",
+		"odh-manifests/odh-dashboard/README.md":               "odh-dashboard\n",
+		"odh-manifests/odh-notebook-controller/kustomization": "resources: []\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testenv.NewManifestServer(path.Join(dir, "fixtures"))
+	defer s.Close()
+
+	withPath := func(app kfdefv1.Application, p string) kfdefv1.Application {
+		app.KustomizeConfig.RepoRef.Path = p
+		return app
+	}
+	manifests := kfdefv1.Repo{Name: "manifests", URI: s.URI("odh-manifests")}
+	testCases := []struct {
+		Name     string
+		Spec     kfdefv1.KfDefSpec
+		Messages []string
+	}{
+		{
+			Name: "kustomizations",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					app("odh-common", "manifests"),
+					withPath(app("odh-dashboard", "manifests"), "odh-dashboard/base"),
+					{Name: "jupyterhub", HelmConfig: &kfdefv1.HelmConfig{Repo: "https://charts.example.com", Chart: "jupyterhub"}},
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+		},
+		{
+			Name: "unknown-paths",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					app("odh-dashbaord", "manifests"),
+					app("odh-dashboard", "manifests"),
+					app("odh-notebook-controller", "manifests"),
+					withPath(app("odh-common", "manifests"), "../odh-common"),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+			Messages: []string{
+				`application "odh-dashbaord" refers to path "odh-dashbaord"`,
+				`application "odh-dashboard" refers to path "odh-dashboard"`,
+				`application "odh-notebook-controller" refers to path "odh-notebook-controller"`,
+				`application "odh-common" refers to path "../odh-common"`,
+			},
+		},
+		{
+			Name: "missing-repo",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-dashboard", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: s.URI("missing")}},
+			},
+			Messages: []string{`spec.repos[0]: repo "manifests" could not be fetched`},
+		},
+	}
+
+	c := newManifestsCache()
+	for _, tc := range testCases {
+		errs := c.validateManifests(context.TODO(), "odh", &tc.Spec)
+		if len(errs) != len(tc.Messages) {
+			t.Errorf("Case %v: expected %v errors, got %v", tc.Name, len(tc.Messages), errs)
+			continue
+		}
+		for i, msg := range tc.Messages {
+			if !strings.Contains(errs[i], msg) {
+				t.Errorf("Case %v: expected %q in %q", tc.Name, msg, errs[i])
+			}
+		}
+	}
+	if requests := s.Requests("odh-manifests"); requests != 1 {
+		t.Errorf("Expected the repo to be fetched once within %v, got %v", ManifestsCacheTTL, requests)
+	}
+}
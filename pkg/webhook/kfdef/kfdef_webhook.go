@@ -0,0 +1,376 @@
+package kfdef
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	gogetter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/go-getter/helper/url"
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+	"github.com/kubeflow/kfctl/v3/pkg/cosign"
+	"github.com/kubeflow/kfctl/v3/pkg/kfconfig"
+	"github.com/kubeflow/kfctl/v3/pkg/oci"
+	"github.com/kubeflow/kfctl/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path the KfDef validating webhook is served on
+const ValidatePath = "/validate-kfdef"
+
+// kfDefValidator rejects KfDef specs that the kustomize plugin would not be able to apply
+type kfDefValidator struct {
+	decoder   *admission.Decoder
+	manifests *manifestsCache
+}
+
+// NewValidatingWebhook returns the admission webhook validating KfDef create and update requests
+func NewValidatingWebhook() *admission.Webhook {
+	return &admission.Webhook{Handler: &kfDefValidator{manifests: newManifestsCache()}}
+}
+
+// blank assignment to verify that kfDefValidator implements admission.DecoderInjector
+var _ admission.DecoderInjector = &kfDefValidator{}
+
+// InjectDecoder injects the decoder into the validator
+func (v *kfDefValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle decodes the KfDef from the admission request and validates its spec, then the paths of its kustomize
+// applications against the manifests of their repos fetched with ctx
+func (v *kfDefValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	instance := &kfdefv1.KfDef{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Do not block the removal of the finalizer on a KfDef that is being deleted.
+	if instance.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	if errs := ValidateSpec(&instance.Spec); len(errs) > 0 {
+		log.Infof("Rejecting KfDef %v/%v: %v.", req.Namespace, req.Name, strings.Join(errs, "; "))
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	if errs := v.manifests.validateManifests(ctx, req.Namespace, &instance.Spec); len(errs) > 0 {
+		log.Infof("Rejecting KfDef %v/%v: %v.", req.Namespace, req.Name, strings.Join(errs, "; "))
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+// ValidateSpec checks that every application in the spec is named once, after one of the KnownApplications if they
+// are set, has a known deletion policy and refers
+// either to a repo that is declared in the spec with a URI go-getter can resolve, or to a chart of a chart repository.
+// The repos verifying their signatures must set a public key or a keyless identity, the applications must
+// depend on applications of the spec without depending on each other, override Deployments and StatefulSets only,
+// and autoscale Deployments whose replicas they do not override.
+// The pod security levels must be privileged, baseline or restricted, and the IP family policy one of the policies of
+// the Services.
+// It returns one message per problem found.
+func ValidateSpec(spec *kfdefv1.KfDefSpec) []string {
+	errs := []string{}
+
+	repos := map[string]bool{}
+	for i, r := range spec.Repos {
+		if r.Name == "" {
+			errs = append(errs, fmt.Sprintf("spec.repos[%d].name must be set", i))
+			continue
+		}
+		if repos[r.Name] {
+			errs = append(errs, fmt.Sprintf("spec.repos[%d]: duplicate repo %q", i, r.Name))
+			continue
+		}
+		repos[r.Name] = true
+		if err := validateRepoURI(r.URI); err != nil {
+			errs = append(errs, fmt.Sprintf("spec.repos[%d]: repo %q has an unresolvable uri %q: %v", i, r.Name, r.URI, err))
+		}
+		if r.Verify != nil {
+			if err := validateVerification(r); err != nil {
+				errs = append(errs, fmt.Sprintf("spec.repos[%d]: repo %q has an invalid verify: %v", i, r.Name, err))
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range KnownApplications {
+		known[name] = true
+	}
+	apps := map[string]bool{}
+	for i, app := range spec.Applications {
+		if app.Name == "" {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d].name must be set", i))
+			continue
+		}
+		if apps[app.Name] {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: duplicate application %q", i, app.Name))
+			continue
+		}
+		apps[app.Name] = true
+		if len(KnownApplications) > 0 && !known[app.Name] {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: unknown application %q, must be one of %v",
+				i, app.Name, strings.Join(KnownApplications, ", ")))
+		}
+
+		switch app.DeletionPolicy {
+		case "", kfdefv1.DeletionPolicyDelete, kfdefv1.DeletionPolicyRetain:
+		default:
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q has an unknown deletionPolicy %q, must be %v or %v",
+				i, app.Name, app.DeletionPolicy, kfdefv1.DeletionPolicyDelete, kfdefv1.DeletionPolicyRetain))
+		}
+		if !validPodSecurity(app.PodSecurity) {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q has an unknown podSecurity %q, must be %v, %v or %v",
+				i, app.Name, app.PodSecurity, kfdefv1.PodSecurityPrivileged, kfdefv1.PodSecurityBaseline, kfdefv1.PodSecurityRestricted))
+		}
+		errs = append(errs, validateOverrides(i, app)...)
+		errs = append(errs, validateAutoscaling(i, app)...)
+		var repoRef *kfdefv1.RepoRef
+		switch {
+		case app.HelmConfig != nil && app.KustomizeConfig != nil:
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q has both kustomizeConfig and helmConfig", i, app.Name))
+			continue
+		case app.HelmConfig != nil:
+			if app.HelmConfig.RepoRef == nil {
+				if app.HelmConfig.Repo == "" || app.HelmConfig.Chart == "" {
+					errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q has neither helmConfig.repoRef nor helmConfig.repo and helmConfig.chart",
+						i, app.Name))
+				}
+				continue
+			}
+			repoRef = app.HelmConfig.RepoRef
+		case app.KustomizeConfig == nil || app.KustomizeConfig.RepoRef == nil:
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q has no kustomizeConfig.repoRef", i, app.Name))
+			continue
+		default:
+			repoRef = app.KustomizeConfig.RepoRef
+		}
+		if !repos[repoRef.Name] {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q refers to unknown repo %q",
+				i, app.Name, repoRef.Name))
+		}
+	}
+
+	for i, app := range spec.Applications {
+		for _, dependency := range app.DependsOn {
+			if !apps[dependency] {
+				errs = append(errs, fmt.Sprintf("spec.applications[%d]: application %q depends on unknown application %q",
+					i, app.Name, dependency))
+			}
+		}
+	}
+	if cycle := dependencyCycle(spec.Applications); len(cycle) > 0 {
+		errs = append(errs, fmt.Sprintf("spec.applications: applications depend on each other: %v", strings.Join(cycle, " -> ")))
+	}
+
+	if !validPodSecurity(spec.PodSecurity) {
+		errs = append(errs, fmt.Sprintf("spec.podSecurity: unknown level %q, must be %v, %v or %v",
+			spec.PodSecurity, kfdefv1.PodSecurityPrivileged, kfdefv1.PodSecurityBaseline, kfdefv1.PodSecurityRestricted))
+	}
+
+	switch spec.IPFamilyPolicy {
+	case "", kfdefv1.IPFamilyPolicySingleStack, kfdefv1.IPFamilyPolicyPreferDualStack, kfdefv1.IPFamilyPolicyRequireDualStack:
+	default:
+		errs = append(errs, fmt.Sprintf("spec.ipFamilyPolicy: unknown policy %q, must be %v, %v or %v", spec.IPFamilyPolicy,
+			kfdefv1.IPFamilyPolicySingleStack, kfdefv1.IPFamilyPolicyPreferDualStack, kfdefv1.IPFamilyPolicyRequireDualStack))
+	}
+
+	images := map[string]bool{}
+	for i, image := range spec.Images {
+		if image.Name == "" {
+			errs = append(errs, fmt.Sprintf("spec.images[%d].name must be set", i))
+			continue
+		}
+		if images[image.Name] {
+			errs = append(errs, fmt.Sprintf("spec.images[%d]: duplicate image %q", i, image.Name))
+			continue
+		}
+		images[image.Name] = true
+		if image.Digest != "" && !digestRegexp.MatchString(image.Digest) {
+			errs = append(errs, fmt.Sprintf("spec.images[%d]: image %q has an invalid digest %q, must be of the form <algorithm>:<hex>",
+				i, image.Name, image.Digest))
+		}
+	}
+	return errs
+}
+
+// validPodSecurity returns true if level is empty or a level of the Pod Security Standards
+func validPodSecurity(level kfdefv1.PodSecurityLevel) bool {
+	switch level {
+	case "", kfdefv1.PodSecurityPrivileged, kfdefv1.PodSecurityBaseline, kfdefv1.PodSecurityRestricted:
+		return true
+	}
+	return false
+}
+
+// validateAutoscaling checks that the autoscaling of the application scales between a positive minimum and a maximum
+// at least as large, on positive utilizations and named metrics with quantities as targets, and that the replicas of
+// the Deployments it scales are not overridden
+func validateAutoscaling(i int, app kfdefv1.Application) []string {
+	errs := []string{}
+	a := app.Autoscaling
+	if a == nil {
+		return errs
+	}
+	field := fmt.Sprintf("spec.applications[%d].autoscaling", i)
+	if a.MaxReplicas < 1 {
+		errs = append(errs, fmt.Sprintf("%v.maxReplicas must be at least 1", field))
+	}
+	if a.MinReplicas != nil && (*a.MinReplicas < 1 || *a.MinReplicas > a.MaxReplicas) {
+		errs = append(errs, fmt.Sprintf("%v.minReplicas must be between 1 and maxReplicas", field))
+	}
+	if a.TargetCPUUtilization != nil && *a.TargetCPUUtilization < 1 {
+		errs = append(errs, fmt.Sprintf("%v.targetCPUUtilization must be positive", field))
+	}
+	if a.TargetMemoryUtilization != nil && *a.TargetMemoryUtilization < 1 {
+		errs = append(errs, fmt.Sprintf("%v.targetMemoryUtilization must be positive", field))
+	}
+	metrics := map[string]bool{}
+	for j, m := range a.Metrics {
+		if m.Name == "" {
+			errs = append(errs, fmt.Sprintf("%v.metrics[%d].name must be set", field, j))
+			continue
+		}
+		if metrics[m.Name] {
+			errs = append(errs, fmt.Sprintf("%v.metrics[%d]: duplicate metric %q", field, j, m.Name))
+		}
+		metrics[m.Name] = true
+		if _, err := resource.ParseQuantity(m.TargetAverageValue); err != nil {
+			errs = append(errs, fmt.Sprintf("%v.metrics[%d].targetAverageValue %q is not a quantity", field, j, m.TargetAverageValue))
+		}
+	}
+	scaled := map[string]bool{}
+	for j, name := range a.Deployments {
+		if name == "" {
+			errs = append(errs, fmt.Sprintf("%v.deployments[%d] must be set", field, j))
+		}
+		scaled[name] = true
+	}
+	for j, o := range app.Overrides {
+		if (o.Kind == "" || o.Kind == "Deployment") && o.Replicas != nil && (len(a.Deployments) == 0 || scaled[o.Name]) {
+			errs = append(errs, fmt.Sprintf("spec.applications[%d].overrides[%d]: the replicas of Deployment %q are set by the autoscaling",
+				i, j, o.Name))
+		}
+	}
+	return errs
+}
+
+// validateOverrides checks that the overrides of the application are for named Deployments and StatefulSets, once each,
+// with a non-negative number of replicas and named containers
+func validateOverrides(i int, app kfdefv1.Application) []string {
+	errs := []string{}
+	workloads := map[string]bool{}
+	for j, o := range app.Overrides {
+		field := fmt.Sprintf("spec.applications[%d].overrides[%d]", i, j)
+		kind := o.Kind
+		if kind == "" {
+			kind = "Deployment"
+		}
+		if kind != "Deployment" && kind != "StatefulSet" {
+			errs = append(errs, fmt.Sprintf("%v: unknown kind %q, must be Deployment or StatefulSet", field, o.Kind))
+			continue
+		}
+		if o.Name == "" {
+			errs = append(errs, fmt.Sprintf("%v.name must be set", field))
+			continue
+		}
+		if workloads[kind+"/"+o.Name] {
+			errs = append(errs, fmt.Sprintf("%v: duplicate override of %v %q", field, kind, o.Name))
+			continue
+		}
+		workloads[kind+"/"+o.Name] = true
+		if o.Replicas != nil && *o.Replicas < 0 {
+			errs = append(errs, fmt.Sprintf("%v.replicas must not be negative", field))
+		}
+		for k, c := range o.Containers {
+			if c.Name == "" {
+				errs = append(errs, fmt.Sprintf("%v.containers[%d].name must be set", field, k))
+			}
+		}
+	}
+	return errs
+}
+
+// dependencyCycle returns the names of applications depending on each other through dependsOn, the first and the
+// last one being the same, or nil if there is none.
+func dependencyCycle(applications []kfdefv1.Application) []string {
+	names := []string{}
+	dependencies := map[string][]string{}
+	for _, app := range applications {
+		if _, ok := dependencies[app.Name]; !ok {
+			names = append(names, app.Name)
+			dependencies[app.Name] = app.DependsOn
+		}
+	}
+	return utils.DependencyCycle(names, dependencies)
+}
+
+// digestRegexp matches the image digests, e.g. sha256:<hex>
+var digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// validateVerification checks that the signatures of repo r can be verified with either a public key or
+// a keyless identity. The signatures of git repos can't be verified.
+func validateVerification(r kfdefv1.Repo) error {
+	if _, _, ok := kfconfig.ParseGitURI(r.URI); ok {
+		return fmt.Errorf("only the signatures of tarballs and OCI artifacts can be verified")
+	}
+	if r.Verify.PublicKey != "" {
+		if r.Verify.Identity != "" || r.Verify.Issuer != "" {
+			return fmt.Errorf("publicKey can't be set along with identity and issuer")
+		}
+		_, err := cosign.NewKeyVerifier([]byte(r.Verify.PublicKey))
+		return err
+	}
+	if r.Verify.Identity == "" || r.Verify.Issuer == "" {
+		return fmt.Errorf("either publicKey or both identity and issuer must be set")
+	}
+	return nil
+}
+
+// validateRepoURI checks that uri can be resolved by one of the go-getter getters
+func validateRepoURI(uri string) error {
+	if uri == "" {
+		return fmt.Errorf("uri must be set")
+	}
+	if strings.HasPrefix(uri, kfconfig.ConfigMapScheme+"://") {
+		_, _, _, err := kfconfig.ParseConfigMapURI(uri)
+		return err
+	}
+	if kfconfig.IsOCIURI(uri) {
+		_, err := oci.ParseReference(uri)
+		return err
+	}
+	if _, _, ok := kfconfig.ParseGitURI(uri); ok {
+		return nil
+	}
+	detected, err := gogetter.Detect(uri, "/", gogetter.Detectors)
+	if err != nil {
+		return err
+	}
+	scheme := ""
+	if idx := strings.Index(detected, "::"); idx > 0 {
+		scheme = detected[:idx]
+		detected = detected[idx+2:]
+	}
+	u, err := url.Parse(detected)
+	if err != nil {
+		return err
+	}
+	if scheme == "" {
+		scheme = u.Scheme
+	}
+	if _, ok := gogetter.Getters[scheme]; !ok {
+		return fmt.Errorf("unsupported scheme %q", scheme)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package kfdef
+
+import (
+	"strings"
+	"testing"
+
+	kfdefv1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/kfdef/v1"
+)
+
+func app(name string, repo string) kfdefv1.Application {
+	return kfdefv1.Application{
+		Name: name,
+		KustomizeConfig: &kfdefv1.KustomizeConfig{
+			RepoRef: &kfdefv1.RepoRef{Name: repo, Path: name},
+		},
+	}
+}
+
+func withDeletionPolicy(app kfdefv1.Application, policy kfdefv1.DeletionPolicy) kfdefv1.Application {
+	app.DeletionPolicy = policy
+	return app
+}
+
+const publicKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEV92snPs2ocpjQ0ekLKQDIMr13RGw
+r4w1hr6kdpIM6QuEeq9E7c/uZ5y7Zv71fG77Zo53j5yoh5Sdi0S3WV4HFQ==
+-----END PUBLIC KEY-----
+`
+
+func withDependencies(app kfdefv1.Application, dependencies ...string) kfdefv1.Application {
+	app.DependsOn = dependencies
+	return app
+}
+
+func withPodSecurity(app kfdefv1.Application, level kfdefv1.PodSecurityLevel) kfdefv1.Application {
+	app.PodSecurity = level
+	return app
+}
+
+func withOverrides(app kfdefv1.Application, overrides ...kfdefv1.WorkloadOverride) kfdefv1.Application {
+	app.Overrides = overrides
+	return app
+}
+
+func withAutoscaling(app kfdefv1.Application, autoscaling kfdefv1.Autoscaling) kfdefv1.Application {
+	app.Autoscaling = &autoscaling
+	return app
+}
+
+func TestValidateSpec(t *testing.T) {
+	replicas, negative := int32(1), int32(-1)
+	type testCase struct {
+		Name    string
+		Spec    kfdefv1.KfDefSpec
+		NumErrs int
+	}
+
+	manifests := kfdefv1.Repo{
+		Name: "manifests",
+		URI:  "https://github.com/opendatahub-io/odh-manifests/tarball/master",
+	}
+
+	testCases := []testCase{
+		{
+			Name: "valid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-dashboard", "manifests")},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "duplicate-application",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "unknown-repo",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "other")},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "missing-kustomize-config",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{{Name: "odh-common"}},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "retained-application",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withDeletionPolicy(app("odh-common", "manifests"), kfdefv1.DeletionPolicyRetain)},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "unknown-deletion-policy",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withDeletionPolicy(app("odh-common", "manifests"), "Orphan")},
+				Repos:        []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "pod-security",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withPodSecurity(app("odh-common", "manifests"), kfdefv1.PodSecurityPrivileged)},
+				Repos:        []kfdefv1.Repo{manifests},
+				PodSecurity:  kfdefv1.PodSecurityRestricted,
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "unknown-pod-security",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withPodSecurity(app("odh-common", "manifests"), "Restricted")},
+				Repos:        []kfdefv1.Repo{manifests},
+				PodSecurity:  "strict",
+			},
+			NumErrs: 2,
+		},
+		{
+			Name: "dual-stack",
+			Spec: kfdefv1.KfDefSpec{
+				Applications:   []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:          []kfdefv1.Repo{manifests},
+				IPFamilyPolicy: kfdefv1.IPFamilyPolicyPreferDualStack,
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "unknown-ip-family-policy",
+			Spec: kfdefv1.KfDefSpec{
+				Applications:   []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:          []kfdefv1.Repo{manifests},
+				IPFamilyPolicy: "DualStack",
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "unresolvable-uri",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "ftp://example.com/manifests.tar.gz"}},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "local-path",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "file:///opt/manifests"}},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "configmap",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "configmap://opendatahub/odh-manifests?key=manifests.tar.gz"}},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "image-overrides",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{manifests},
+				Images: []kfdefv1.Image{
+					{Name: "quay.io/opendatahub/odh-dashboard", NewName: "registry.example.com/odh-dashboard"},
+					{Name: "quay.io/opendatahub/notebooks", Digest: "sha256:" + strings.Repeat("a", 64)},
+				},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "invalid-image-overrides",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{manifests},
+				Images: []kfdefv1.Image{
+					{NewName: "registry.example.com/odh-dashboard"},
+					{Name: "quay.io/opendatahub/notebooks", Digest: "latest"},
+					{Name: "quay.io/opendatahub/notebooks"},
+				},
+			},
+			NumErrs: 3,
+		},
+		{
+			Name: "configmap-without-namespace",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "configmap://odh-manifests"}},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "git-ssh",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos: []kfdefv1.Repo{{Name: "manifests", URI: "git@github.com:opendatahub-io/odh-manifests.git",
+					Ref: "v1.1.0", SecretName: "odh-manifests-credentials"}},
+			},
+		},
+		{
+			Name: "oci-artifact",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos: []kfdefv1.Repo{{Name: "manifests", URI: "oci://registry.example.com/opendatahub/odh-manifests",
+					Ref: "sha256:0123456789abcdef", SecretName: "registry-pull-secret"}},
+			},
+		},
+		{
+			Name: "oci-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "oci://odh-manifests"}},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "verified-repos",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-dashboard", "dashboard")},
+				Repos: []kfdefv1.Repo{
+					{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master",
+						Verify: &kfdefv1.Verification{PublicKey: publicKey}},
+					{Name: "dashboard", URI: "oci://quay.io/opendatahub/odh-dashboard-manifests:v1.1.0",
+						Verify: &kfdefv1.Verification{Identity: "release@opendatahub.io", Issuer: "https://github.com/login/oauth"}},
+				},
+			},
+		},
+		{
+			Name: "verified-repos-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-dashboard", "dashboard"), app("odh-notebooks", "notebooks")},
+				Repos: []kfdefv1.Repo{
+					{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master",
+						Verify: &kfdefv1.Verification{PublicKey: "not a key"}},
+					{Name: "dashboard", URI: "oci://quay.io/opendatahub/odh-dashboard-manifests:v1.1.0",
+						Verify: &kfdefv1.Verification{Identity: "release@opendatahub.io"}},
+					{Name: "notebooks", URI: "git::https://github.com/opendatahub-io/odh-manifests?ref=v1.1.0",
+						Verify: &kfdefv1.Verification{PublicKey: publicKey}},
+				},
+			},
+			NumErrs: 3,
+		},
+		{
+			Name: "dependencies",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{withDependencies(app("kserve", "manifests"), "istio"), app("istio", "manifests")},
+				Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}},
+			},
+		},
+		{
+			Name: "dependencies-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withDependencies(app("kserve", "manifests"), "istio", "knative"),
+					withDependencies(app("istio", "manifests"), "odh-common"),
+					withDependencies(app("odh-common", "manifests"), "kserve"),
+				},
+				Repos: []kfdefv1.Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}},
+			},
+			NumErrs: 2,
+		},
+		{
+			Name: "overrides",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withOverrides(app("odh-dashboard", "manifests"),
+						kfdefv1.WorkloadOverride{Name: "odh-dashboard", Replicas: &replicas,
+							Containers: []kfdefv1.ContainerOverride{{Name: "odh-dashboard"}}},
+						kfdefv1.WorkloadOverride{Kind: "StatefulSet", Name: "odh-dashboard"}),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+		},
+		{
+			Name: "overrides-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withOverrides(app("odh-dashboard", "manifests"),
+						kfdefv1.WorkloadOverride{Kind: "Service", Name: "odh-dashboard"},
+						kfdefv1.WorkloadOverride{Kind: "Deployment"},
+						kfdefv1.WorkloadOverride{Name: "odh-dashboard", Replicas: &negative},
+						kfdefv1.WorkloadOverride{Kind: "Deployment", Name: "odh-dashboard"},
+						kfdefv1.WorkloadOverride{Kind: "StatefulSet", Name: "odh-dashboard",
+							Containers: []kfdefv1.ContainerOverride{{}}}),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 5,
+		},
+		{
+			Name: "autoscaling",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withAutoscaling(withOverrides(app("odh-dashboard", "manifests"),
+						kfdefv1.WorkloadOverride{Name: "odh-dashboard-db", Replicas: &replicas}),
+						kfdefv1.Autoscaling{Deployments: []string{"odh-dashboard"}, MinReplicas: &replicas, MaxReplicas: 3,
+							Metrics: []kfdefv1.AutoscalingMetric{{Name: "requests_per_second", TargetAverageValue: "100"}}}),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+		},
+		{
+			Name: "autoscaling-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					withAutoscaling(withOverrides(app("odh-dashboard", "manifests"),
+						kfdefv1.WorkloadOverride{Name: "odh-dashboard", Replicas: &replicas}),
+						kfdefv1.Autoscaling{MinReplicas: &replicas, TargetCPUUtilization: &negative,
+							Metrics: []kfdefv1.AutoscalingMetric{{Name: "requests_per_second", TargetAverageValue: "fast"}, {}}}),
+				},
+				Repos: []kfdefv1.Repo{manifests},
+			},
+			NumErrs: 6,
+		},
+		{
+			Name: "helm-charts",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					{Name: "dashboard", HelmConfig: &kfdefv1.HelmConfig{Repo: "oci://quay.io/opendatahub/charts", Chart: "odh-dashboard", Version: "1.0.0"}},
+					{Name: "notebooks", HelmConfig: &kfdefv1.HelmConfig{RepoRef: &kfdefv1.RepoRef{Name: "manifests", Path: "charts/notebooks"}}},
+				},
+				Repos: []kfdefv1.Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}},
+			},
+		},
+		{
+			Name: "helm-invalid",
+			Spec: kfdefv1.KfDefSpec{
+				Applications: []kfdefv1.Application{
+					{Name: "dashboard", HelmConfig: &kfdefv1.HelmConfig{Repo: "https://charts.example.com"}},
+					{Name: "notebooks", HelmConfig: &kfdefv1.HelmConfig{RepoRef: &kfdefv1.RepoRef{Name: "charts"}}},
+				},
+			},
+			NumErrs: 2,
+		},
+	}
+
+	for _, c := range testCases {
+		errs := ValidateSpec(&c.Spec)
+		if len(errs) != c.NumErrs {
+			t.Errorf("Case %v: expected %v errors; got %v: %v", c.Name, c.NumErrs, len(errs), errs)
+		}
+	}
+}
+
+func TestValidateSpecKnownApplications(t *testing.T) {
+	defer func(known []string) { KnownApplications = known }(KnownApplications)
+	KnownApplications = []string{"odh-common", "odh-dashboard"}
+
+	spec := kfdefv1.KfDefSpec{
+		Applications: []kfdefv1.Application{app("odh-common", "manifests"), app("odh-dashbaord", "manifests")},
+		Repos:        []kfdefv1.Repo{{Name: "manifests", URI: "https://github.com/opendatahub-io/odh-manifests/tarball/master"}},
+	}
+	errs := ValidateSpec(&spec)
+	if len(errs) != 1 || !strings.Contains(errs[0], `unknown application "odh-dashbaord"`) {
+		t.Errorf("Expected the unknown application to be rejected, got %v", errs)
+	}
+}
@@ -0,0 +1,74 @@
+package kueue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubeflow/kfctl/v3/pkg/controller/datasciencecluster"
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// QueueNamePath is the path the queue name webhook is served on
+const QueueNamePath = "/mutate-queue-name"
+
+// QueueNameLabel is the label of the workloads Kueue admits from the LocalQueue it names
+const QueueNameLabel = "kueue.x-k8s.io/queue-name"
+
+// queueNamer labels the RayClusters, the RayJobs and the pods of the pipelines created in the data science
+// projects with the LocalQueue of their namespace
+type queueNamer struct {
+	client client.Client
+}
+
+// NewQueueNameWebhook returns the admission webhook labeling the workloads created in the namespaces annotated
+// with a LocalQueue by the DataScienceCluster controller
+func NewQueueNameWebhook(c client.Client) *admission.Webhook {
+	return &admission.Webhook{Handler: &queueNamer{client: c}}
+}
+
+// Handle patches the labels of the workload with the LocalQueue of its namespace, unless it names one already
+func (q *queueNamer) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create {
+		return admission.Allowed("")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	ns := &corev1.Namespace{}
+	if err := q.client.Get(ctx, client.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !SetQueueName(obj, ns.Annotations[datasciencecluster.LocalQueueAnnotation]) {
+		return admission.Allowed("")
+	}
+	log.Infof("Queueing %v %v/%v in LocalQueue %v.", req.Kind.Kind, req.Namespace, obj.GetName(),
+		ns.Annotations[datasciencecluster.LocalQueueAnnotation])
+	labeled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, labeled)
+}
+
+// SetQueueName labels obj with the LocalQueue. It returns false, leaving obj as it is, if the queue is empty or
+// obj names a LocalQueue already.
+func SetQueueName(obj *unstructured.Unstructured, queue string) bool {
+	labels := obj.GetLabels()
+	if queue == "" || labels[QueueNameLabel] != "" {
+		return false
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[QueueNameLabel] = queue
+	obj.SetLabels(labels)
+	return true
+}
@@ -0,0 +1,53 @@
+package kueue
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSetQueueName(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Labels   map[string]string
+		Queue    string
+		Changed  bool
+		Expected map[string]string
+	}
+	testCases := []testCase{
+		{
+			Name:     "unlabeled",
+			Queue:    "default",
+			Changed:  true,
+			Expected: map[string]string{QueueNameLabel: "default"},
+		},
+		{
+			Name:     "other labels",
+			Labels:   map[string]string{"tekton.dev/pipelineRun": "train"},
+			Queue:    "default",
+			Changed:  true,
+			Expected: map[string]string{"tekton.dev/pipelineRun": "train", QueueNameLabel: "default"},
+		},
+		{
+			Name:     "queued",
+			Labels:   map[string]string{QueueNameLabel: "team-queue"},
+			Queue:    "default",
+			Expected: map[string]string{QueueNameLabel: "team-queue"},
+		},
+		{
+			Name: "namespace without queue",
+		},
+	}
+
+	for _, c := range testCases {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetLabels(c.Labels)
+		if changed := SetQueueName(obj, c.Queue); changed != c.Changed {
+			t.Errorf("Case %v: expected changed to be %v, got %v", c.Name, c.Changed, changed)
+		}
+		if labels := obj.GetLabels(); !reflect.DeepEqual(labels, c.Expected) {
+			t.Errorf("Case %v: expected labels %v, got %v", c.Name, c.Expected, labels)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package notebook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	dspv1alpha1 "github.com/kubeflow/kfctl/v3/pkg/apis/apps/datascienceproject/v1alpha1"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/datascienceproject"
+	"github.com/kubeflow/kfctl/v3/pkg/controller/notebook"
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// QuotaPath is the path the notebook quota webhook is served on
+const QuotaPath = "/validate-notebook-quota"
+
+// quotaValidator rejects the notebooks of the data science projects whose pod would exceed the quota of their
+// project, instead of leaving them pending until their pod is admitted by the ResourceQuota
+type quotaValidator struct {
+	client client.Client
+}
+
+// NewQuotaWebhook returns the admission webhook validating the notebooks created, started or resized in the
+// namespaces of the DataScienceProjects against the quota of their project
+func NewQuotaWebhook(c client.Client) *admission.Webhook {
+	return &admission.Webhook{Handler: &quotaValidator{client: c}}
+}
+
+// Handle denies the notebook if the resources its pod requests, beyond the ones of its previous version, exceed
+// what is left of the quota of its project. The notebooks of the other namespaces are allowed.
+func (v *quotaValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	requested, err := QuotaRequests(obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(requested) == 0 {
+		return admission.Allowed("")
+	}
+	project := &dspv1alpha1.DataScienceProject{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: req.Namespace}, project); err != nil {
+		if errors.IsNotFound(err) {
+			return admission.Allowed("")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	released := corev1.ResourceList{}
+	if req.Operation == admissionv1beta1.Update {
+		old := &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if released, err = QuotaRequests(old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	quota := &corev1.ResourceQuota{}
+	err = v.client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: datascienceproject.ResourceQuotaName}, quota)
+	if err != nil && !errors.IsNotFound(err) {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := CheckQuota(project.Name, datascienceproject.Quota(project), quota.Status.Used, requested, released); err != nil {
+		log.Infof("Denying %v %v/%v: %v.", req.Kind.Kind, req.Namespace, obj.GetName(), err)
+		return admission.Denied(fmt.Sprintf("%v %v %v", req.Kind.Kind, obj.GetName(), err))
+	}
+	return admission.Allowed("")
+}
+
+// QuotaRequests returns the resources the pod of the notebook obj is charged to the ResourceQuota of its
+// namespace, by their names in the quota. The containers that do not request cpu or memory are charged the
+// defaults of the LimitRange of the projects, and the stopped notebooks, which have no pod, are charged nothing.
+func QuotaRequests(obj *unstructured.Unstructured) (corev1.ResourceList, error) {
+	charged := corev1.ResourceList{}
+	if _, ok := obj.GetAnnotations()[notebook.StopAnnotation]; ok {
+		return charged, nil
+	}
+	template, found, err := unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+	if err != nil || !found {
+		return charged, err
+	}
+	podSpec := &corev1.PodSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, podSpec); err != nil {
+		return nil, fmt.Errorf("invalid pod template: %v", err)
+	}
+
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range podSpec.Containers {
+		addResources(requests, containerRequests(c))
+		addResources(limits, c.Resources.Limits)
+	}
+	// The init containers run one at a time before the containers, the pod is charged the greater of the sum of
+	// its containers and of every init container
+	for _, c := range podSpec.InitContainers {
+		maxResources(requests, containerRequests(c))
+		maxResources(limits, c.Resources.Limits)
+	}
+	for name, q := range requests {
+		charged[corev1.ResourceName("requests."+string(name))] = q
+		if name == corev1.ResourceCPU || name == corev1.ResourceMemory {
+			charged[name] = q
+		}
+	}
+	for name, q := range limits {
+		charged[corev1.ResourceName("limits."+string(name))] = q
+	}
+	return charged, nil
+}
+
+// containerRequests returns the requests of container c once admitted: the limits it does not request are
+// requested, and the defaults of the LimitRange of the projects for the cpu and the memory it still does not
+func containerRequests(c corev1.Container) corev1.ResourceList {
+	requests := c.Resources.Requests.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
+	}
+	for name, q := range c.Resources.Limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = q.DeepCopy()
+		}
+	}
+	for name, q := range datascienceproject.DefaultContainerRequests() {
+		if _, ok := requests[name]; !ok {
+			requests[name] = q
+		}
+	}
+	return requests
+}
+
+// addResources adds the quantities of added to total
+func addResources(total corev1.ResourceList, added corev1.ResourceList) {
+	for name, q := range added {
+		sum := total[name]
+		sum.Add(q)
+		total[name] = sum
+	}
+}
+
+// maxResources raises the quantities of total to the ones of other that are greater
+func maxResources(total corev1.ResourceList, other corev1.ResourceList) {
+	for name, q := range other {
+		if current, ok := total[name]; !ok || q.Cmp(current) > 0 {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
+
+// CheckQuota returns an error describing the resources of the quota hard of project exceeded by charging
+// requested, once released is freed, to the quota with used charged already. The resources requested no more
+// than released are not checked, so that the notebooks are still updated once the quota is lowered.
+func CheckQuota(project string, hard, used, requested, released corev1.ResourceList) error {
+	exceeded := []string{}
+	for name, q := range requested {
+		limit, ok := hard[name]
+		if !ok || q.Cmp(released[name]) <= 0 {
+			continue
+		}
+		available := limit.DeepCopy()
+		available.Sub(used[name])
+		available.Add(released[name])
+		if q.Cmp(available) <= 0 {
+			continue
+		}
+		if available.Sign() < 0 {
+			available = resource.Quantity{}
+		}
+		exceeded = append(exceeded, fmt.Sprintf("%v requests %v while %v of the quota of %v is left", name,
+			q.String(), available.String(), limit.String()))
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	sort.Strings(exceeded)
+	return fmt.Errorf("exceeds the quota of data science project %v: %v", project, strings.Join(exceeded, ", "))
+}
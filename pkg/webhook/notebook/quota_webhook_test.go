@@ -0,0 +1,155 @@
+package notebook
+
+import (
+	"testing"
+
+	"github.com/kubeflow/kfctl/v3/pkg/controller/notebook"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestQuotaRequests(t *testing.T) {
+	type testCase struct {
+		Name        string
+		Annotations map[string]string
+		PodSpec     map[string]interface{}
+		Expected    map[string]string
+	}
+	gpuContainer := map[string]interface{}{
+		"name": "notebook",
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "2", "memory": "8Gi"},
+			"limits":   map[string]interface{}{"memory": "16Gi", "nvidia.com/gpu": "1"},
+		},
+	}
+	testCases := []testCase{
+		{
+			Name:    "notebook with a GPU",
+			PodSpec: map[string]interface{}{"containers": []interface{}{gpuContainer}},
+			Expected: map[string]string{
+				"cpu":                     "2",
+				"requests.cpu":            "2",
+				"memory":                  "8Gi",
+				"requests.memory":         "8Gi",
+				"requests.nvidia.com/gpu": "1",
+				"limits.memory":           "16Gi",
+				"limits.nvidia.com/gpu":   "1",
+			},
+		},
+		{
+			Name: "sidecar without requests and init container",
+			PodSpec: map[string]interface{}{
+				"containers": []interface{}{gpuContainer, map[string]interface{}{"name": "oauth-proxy"}},
+				"initContainers": []interface{}{map[string]interface{}{
+					"name": "fetch",
+					"resources": map[string]interface{}{
+						"requests": map[string]interface{}{"cpu": "4"},
+					},
+				}},
+			},
+			Expected: map[string]string{
+				"cpu":                     "4",
+				"requests.cpu":            "4",
+				"memory":                  "8448Mi",
+				"requests.memory":         "8448Mi",
+				"requests.nvidia.com/gpu": "1",
+				"limits.memory":           "16Gi",
+				"limits.nvidia.com/gpu":   "1",
+			},
+		},
+		{
+			Name:        "stopped notebook",
+			Annotations: map[string]string{notebook.StopAnnotation: "2024-01-01T00:00:00Z"},
+			PodSpec:     map[string]interface{}{"containers": []interface{}{gpuContainer}},
+			Expected:    map[string]string{},
+		},
+		{
+			Name:     "notebook without pod template",
+			Expected: map[string]string{},
+		},
+	}
+
+	for _, c := range testCases {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetAnnotations(c.Annotations)
+		if c.PodSpec != nil {
+			obj.Object["spec"] = map[string]interface{}{"template": map[string]interface{}{"spec": c.PodSpec}}
+		}
+		charged, err := QuotaRequests(obj)
+		if err != nil {
+			t.Errorf("Case %v: QuotaRequests() failed: %v", c.Name, err)
+			continue
+		}
+		if len(charged) != len(c.Expected) {
+			t.Errorf("Case %v: expected %v, got %v", c.Name, c.Expected, charged)
+			continue
+		}
+		for name, expected := range c.Expected {
+			if q, ok := charged[corev1.ResourceName(name)]; !ok || q.Cmp(resource.MustParse(expected)) != 0 {
+				t.Errorf("Case %v: expected %v to be charged %v, got %v", c.Name, name, expected, charged)
+			}
+		}
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	list := func(quantities map[string]string) corev1.ResourceList {
+		resources := corev1.ResourceList{}
+		for name, q := range quantities {
+			resources[corev1.ResourceName(name)] = resource.MustParse(q)
+		}
+		return resources
+	}
+	hard := list(map[string]string{"requests.memory": "32Gi", "requests.nvidia.com/gpu": "2", "persistentvolumeclaims": "10"})
+	type testCase struct {
+		Name      string
+		Used      map[string]string
+		Requested map[string]string
+		Released  map[string]string
+		Valid     bool
+	}
+	testCases := []testCase{
+		{
+			Name:      "within the quota",
+			Used:      map[string]string{"requests.memory": "16Gi", "requests.nvidia.com/gpu": "1"},
+			Requested: map[string]string{"requests.memory": "16Gi", "requests.nvidia.com/gpu": "1", "requests.cpu": "64"},
+			Valid:     true,
+		},
+		{
+			Name:      "no GPU left",
+			Used:      map[string]string{"requests.memory": "8Gi", "requests.nvidia.com/gpu": "2"},
+			Requested: map[string]string{"requests.memory": "8Gi", "requests.nvidia.com/gpu": "1"},
+		},
+		{
+			Name:      "not enough memory",
+			Requested: map[string]string{"requests.memory": "64Gi"},
+		},
+		{
+			Name:      "resized within the quota",
+			Used:      map[string]string{"requests.memory": "24Gi"},
+			Requested: map[string]string{"requests.memory": "24Gi"},
+			Released:  map[string]string{"requests.memory": "16Gi"},
+			Valid:     true,
+		},
+		{
+			Name:      "unchanged over the lowered quota",
+			Used:      map[string]string{"requests.nvidia.com/gpu": "4"},
+			Requested: map[string]string{"requests.nvidia.com/gpu": "4"},
+			Released:  map[string]string{"requests.nvidia.com/gpu": "4"},
+			Valid:     true,
+		},
+	}
+	for _, c := range testCases {
+		err := CheckQuota("fraud-detection", hard, list(c.Used), list(c.Requested), list(c.Released))
+		if (err == nil) != c.Valid {
+			t.Errorf("Case %v: CheckQuota() = %v, expected valid: %v", c.Name, err, c.Valid)
+		}
+	}
+	err := CheckQuota("fraud-detection", hard, list(map[string]string{"requests.nvidia.com/gpu": "2"}),
+		list(map[string]string{"requests.nvidia.com/gpu": "1"}), corev1.ResourceList{})
+	expected := "exceeds the quota of data science project fraud-detection: requests.nvidia.com/gpu requests 1 while 0 of the quota of 2 is left"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,64 @@
+package priorityclass
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubeflow/kfctl/v3/pkg/operatorconfig"
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PriorityClassPath is the path the priority class webhook is served on
+const PriorityClassPath = "/mutate-priority-class"
+
+// priorityClassAssigner assigns the PriorityClass of the user workloads to the notebooks created in the data
+// science projects
+type priorityClassAssigner struct{}
+
+// NewPriorityClassWebhook returns the admission webhook assigning the PriorityClass of the user workloads of the
+// operator settings to the pods of the notebooks
+func NewPriorityClassWebhook() *admission.Webhook {
+	return &admission.Webhook{Handler: &priorityClassAssigner{}}
+}
+
+// Handle patches the pod template of the notebook with the PriorityClass of the user workloads, unless it names
+// one already
+func (p *priorityClassAssigner) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create {
+		return admission.Allowed("")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	name := operatorconfig.Current().PriorityClasses.Workloads.Name
+	if !SetPriorityClass(obj, name) {
+		return admission.Allowed("")
+	}
+	log.Infof("Assigning PriorityClass %v to %v %v/%v.", name, req.Kind.Kind, req.Namespace, obj.GetName())
+	assigned, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, assigned)
+}
+
+// SetPriorityClass sets the PriorityClass name in the pod template of the notebook obj. It returns false, leaving
+// obj as it is, if name is empty or the pod template names a PriorityClass already.
+func SetPriorityClass(obj *unstructured.Unstructured, name string) bool {
+	if name == "" {
+		return false
+	}
+	if current, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "priorityClassName"); current != "" {
+		return false
+	}
+	if err := unstructured.SetNestedField(obj.Object, name, "spec", "template", "spec", "priorityClassName"); err != nil {
+		return false
+	}
+	return true
+}
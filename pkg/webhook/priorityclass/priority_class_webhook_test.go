@@ -0,0 +1,58 @@
+package priorityclass
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSetPriorityClass(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Spec     map[string]interface{}
+		Class    string
+		Changed  bool
+		Expected string
+	}
+	testCases := []testCase{
+		{
+			Name:     "notebook",
+			Spec:     map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{}}},
+			Class:    "odh-workloads",
+			Changed:  true,
+			Expected: "odh-workloads",
+		},
+		{
+			Name:     "notebook without pod template",
+			Class:    "odh-workloads",
+			Changed:  true,
+			Expected: "odh-workloads",
+		},
+		{
+			Name: "notebook naming a PriorityClass",
+			Spec: map[string]interface{}{"template": map[string]interface{}{
+				"spec": map[string]interface{}{"priorityClassName": "high-priority"},
+			}},
+			Class:    "odh-workloads",
+			Expected: "high-priority",
+		},
+		{
+			Name: "no PriorityClass of the workloads",
+			Spec: map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{}}},
+		},
+	}
+
+	for _, c := range testCases {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		if c.Spec != nil {
+			obj.Object["spec"] = c.Spec
+		}
+		if changed := SetPriorityClass(obj, c.Class); changed != c.Changed {
+			t.Errorf("Case %v: expected changed to be %v, got %v", c.Name, c.Changed, changed)
+		}
+		class, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "priorityClassName")
+		if class != c.Expected {
+			t.Errorf("Case %v: expected PriorityClass %q, got %q", c.Name, c.Expected, class)
+		}
+	}
+}
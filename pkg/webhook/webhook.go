@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/kubeflow/kfctl/v3/pkg/webhook/kfdef"
+	"github.com/kubeflow/kfctl/v3/pkg/webhook/kueue"
+	"github.com/kubeflow/kfctl/v3/pkg/webhook/notebook"
+	"github.com/kubeflow/kfctl/v3/pkg/webhook/priorityclass"
+)
+
+// ConvertPath is the path the conversion webhook of the CRDs is served on
+const ConvertPath = "/convert"
+
+// AddToManager registers all admission and conversion webhooks with the webhook server of the Manager. The
+// certificate of the server is provisioned first when ManageCertificates is set.
+func AddToManager(m manager.Manager, host string, port int, certDir string) error {
+	if ManageCertificates {
+		if err := addCertificateManager(m, certDir); err != nil {
+			return err
+		}
+	}
+	server := m.GetWebhookServer()
+	server.Host = host
+	server.Port = port
+	server.CertDir = certDir
+	server.Register(kfdef.ValidatePath, kfdef.NewValidatingWebhook())
+	server.Register(kfdef.DefaultPath, kfdef.NewDefaultingWebhook(isOpenShift(m.GetRESTMapper())))
+	server.Register(kueue.QueueNamePath, kueue.NewQueueNameWebhook(m.GetClient()))
+	server.Register(priorityclass.PriorityClassPath, priorityclass.NewPriorityClassWebhook())
+	server.Register(notebook.QuotaPath, notebook.NewQuotaWebhook(m.GetClient()))
+	server.Register(ConvertPath, &conversion.Webhook{})
+	return nil
+}
+
+// isOpenShift returns true if the cluster serves the OpenShift routes
+func isOpenShift(mapper meta.RESTMapper) bool {
+	_, err := mapper.RESTMapping(schema.GroupKind{Group: "route.openshift.io", Kind: "Route"})
+	return err == nil
+}